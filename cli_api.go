@@ -0,0 +1,94 @@
+// Package main provides the run-script-service daemon executable.
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"run-script-service/client"
+	"run-script-service/service"
+)
+
+// apiTimeout bounds the CLI's requests to the daemon's API
+const apiTimeout = 5 * time.Second
+
+// apiClient returns a client for the daemon's API, reaching it the same way as followDialer
+// and authenticating with RSS_API_TOKEN when set
+func apiClient(settings *service.Settings) (*client.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	baseURL := webURL(settings)
+	if path, ok := service.UnixSocketPath(settings.BindAddress); ok {
+		transport.DialContext = unixDialer(path)
+		baseURL = "http://localhost"
+	} else {
+		config, err := daemonTLSConfig(settings)
+		if err != nil {
+			return nil, err
+		}
+		transport.TLSClientConfig = config
+	}
+
+	options := []client.Option{client.WithHTTPClient(&http.Client{Transport: transport, Timeout: apiTimeout})}
+	if token := os.Getenv(apiTokenEnv); token != "" {
+		options = append(options, client.WithToken(token))
+	}
+	return client.New(baseURL, options...), nil
+}
+
+// unixDialer returns a dial function connecting to the unix socket at path whatever the address
+func unixDialer(path string) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, _, _ string) (net.Conn, error) {
+		var d net.Dialer
+		return d.DialContext(ctx, "unix", path)
+	}
+}
+
+// daemonTLSConfig trusts the system CAs and the daemon's own certificate, so self-signed
+// certificates work, and presents a client certificate when RSS_CLIENT_CERT and RSS_CLIENT_KEY
+// are set. It returns nil when the daemon does not serve TLS.
+func daemonTLSConfig(settings *service.Settings) (*tls.Config, error) {
+	if settings.TLS == nil {
+		return nil, nil
+	}
+	roots, err := x509.SystemCertPool()
+	if err != nil {
+		roots = x509.NewCertPool()
+	}
+	certFile, _, _ := settings.TLS.Files(settings.DataDir)
+	if data, err := os.ReadFile(certFile); err == nil {
+		roots.AppendCertsFromPEM(data)
+	}
+	config := &tls.Config{MinVersion: tls.VersionTLS12, RootCAs: roots}
+	if clientCert := os.Getenv(clientCertEnv); clientCert != "" {
+		cert, err := tls.LoadX509KeyPair(clientCert, os.Getenv(clientKeyEnv))
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate from %s and %s: %v", clientCertEnv, clientKeyEnv, err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
+
+// printAPIStatus prints the uptime and script counts reported by the running daemon
+func printAPIStatus(settings *service.Settings) {
+	c, err := apiClient(settings)
+	if err != nil {
+		fmt.Printf("API: %v\n", err)
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), apiTimeout)
+	defer cancel()
+	status, err := c.Status(ctx)
+	if err != nil {
+		fmt.Printf("API: unreachable (%v)\n", err)
+		return
+	}
+	fmt.Printf("Uptime: %s\n", status.Uptime)
+	fmt.Printf("Scripts: %d running, %d total\n", status.RunningScripts, status.TotalScripts)
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"run-script-service/service"
+)
+
+func TestAPIClient_UnixSocket(t *testing.T) {
+	settings := service.DefaultSettings(t.TempDir())
+	socket := filepath.Join(settings.DataDir, "rss.sock")
+	settings.BindAddress = "unix:" + socket
+	t.Setenv(apiTokenEnv, "secret")
+
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/status" || r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"success":true,"data":{"status":"running","uptime":"5m","runningScripts":1,"totalScripts":2}}`))
+	})}
+	go server.Serve(listener)
+	defer server.Close()
+
+	c, err := apiClient(settings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	status, err := c.Status(context.Background())
+	if err != nil {
+		t.Fatalf("Status failed: %v", err)
+	}
+	if status.Uptime != "5m" || status.RunningScripts != 1 || status.TotalScripts != 2 {
+		t.Errorf("Unexpected status %+v", status)
+	}
+}
+
+func TestDaemonTLSConfig(t *testing.T) {
+	settings := service.DefaultSettings(t.TempDir())
+	if config, err := daemonTLSConfig(settings); err != nil || config != nil {
+		t.Errorf("Expected no TLS config without TLS, got %v, %v", config, err)
+	}
+
+	settings.TLS = &service.TLSConfig{}
+	certFile, keyFile, _ := settings.TLS.Files(settings.DataDir)
+	if err := service.GenerateSelfSignedCert(certFile, keyFile, nil); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(clientCertEnv, certFile)
+	t.Setenv(clientKeyEnv, keyFile)
+	if _, err := apiClient(settings); err != nil {
+		t.Errorf("Expected a TLS client, got %v", err)
+	}
+	t.Setenv(clientKeyEnv, filepath.Join(settings.DataDir, "missing.pem"))
+	if _, err := apiClient(settings); err == nil {
+		t.Error("Expected an error for a missing client key")
+	}
+}
@@ -0,0 +1,101 @@
+// Package main provides the run-script-service daemon executable.
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+	"time"
+
+	"run-script-service/service"
+)
+
+// auditLog opens the audit log in the data directory
+func auditLog() *service.AuditLog {
+	return service.NewAuditLog(service.AuditLogPath(appSettings.DataDir))
+}
+
+// cliActor returns the OS user running the CLI
+func cliActor() string {
+	if current, err := user.Current(); err == nil && current.Username != "" {
+		return current.Username
+	}
+	if name := os.Getenv("USER"); name != "" {
+		return name
+	}
+	return "unknown"
+}
+
+// recordAudit records a change made by a CLI command. before and after are the states
+// compared for the diff, nil when the target did not exist before or after.
+func recordAudit(action, target, detail string, before, after interface{}) {
+	err := auditLog().Record(service.AuditEntry{
+		Actor:  cliActor(),
+		Source: service.AuditSourceCLI,
+		Action: action,
+		Target: target,
+		Detail: detail,
+		Diff:   service.AuditDiff(before, after),
+	})
+	if err != nil {
+		fmt.Printf("Warning: failed to record the change in the audit log: %v\n", err)
+	}
+}
+
+// handleAuditCommand shows who changed what, newest first.
+// Usage: audit [--actor=<name>] [--action=<action>] [--target=<name>] [--since=<time>] [--until=<time>] [--limit=<n>] [--diff]
+func handleAuditCommand(args []string, _ string) (CommandResult, error) {
+	flags, positional, err := parseCommandFlags(args, "diff")
+	if err != nil {
+		return CommandResult{shouldRunService: false}, err
+	}
+	if len(positional) > 0 {
+		return CommandResult{shouldRunService: false}, fmt.Errorf("usage: ./run-script-service audit [--actor=<name>] " +
+			"[--action=<action>] [--target=<name>] [--since=<time>] [--until=<time>] [--limit=<n>] [--diff]")
+	}
+
+	filter := service.AuditFilter{Actor: flags["actor"], Action: flags["action"], Target: flags["target"], Limit: 50}
+	if value, ok := flags["limit"]; ok {
+		if filter.Limit, err = strconv.Atoi(value); err != nil || filter.Limit < 0 {
+			return CommandResult{shouldRunService: false}, fmt.Errorf("invalid limit: %s", value)
+		}
+	}
+	now := time.Now()
+	if value, ok := flags["since"]; ok {
+		if filter.Since, err = service.ParseLogTime(value, now); err != nil {
+			return CommandResult{shouldRunService: false}, err
+		}
+	}
+	if value, ok := flags["until"]; ok {
+		if filter.Until, err = service.ParseLogTime(value, now); err != nil {
+			return CommandResult{shouldRunService: false}, err
+		}
+	}
+
+	entries, err := auditLog().Query(filter)
+	if err != nil {
+		return CommandResult{shouldRunService: false}, err
+	}
+	if len(entries) == 0 {
+		fmt.Println("No audit entries")
+		return CommandResult{shouldRunService: false}, nil
+	}
+
+	fmt.Printf("%-19s %-12s %-20s %-15s %-20s %s\n", "TIME", "ACTOR", "FROM", "ACTION", "TARGET", "DETAIL")
+	for _, entry := range entries {
+		from := entry.Source
+		if entry.RemoteIP != "" {
+			from += " " + entry.RemoteIP
+		}
+		fmt.Printf("%-19s %-12s %-20s %-15s %-20s %s\n", entry.Time.Local().Format("2006-01-02 15:04:05"),
+			entry.Actor, from, entry.Action, entry.Target, entry.Detail)
+		if flags["diff"] == "true" {
+			for _, line := range entry.Diff {
+				fmt.Printf("    %s %s\n", line.Op, strings.TrimRight(line.Text, " "))
+			}
+		}
+	}
+	return CommandResult{shouldRunService: false}, nil
+}
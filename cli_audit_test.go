@@ -0,0 +1,86 @@
+// Package main provides tests for the audit CLI command
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"run-script-service/service"
+)
+
+func TestCLIChangesAreAudited(t *testing.T) {
+	useTempDataDir(t)
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "service_config.json")
+	scriptPath := filepath.Join(tempDir, "backup.sh")
+	if err := os.WriteFile(scriptPath, []byte("#!/bin/bash\necho ok\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	commands := [][]string{
+		{"add-script", "--name=backup", "--path=" + scriptPath, "--interval=1m"},
+		{"disable-script", "backup"},
+		{"set-web-port", "9090"},
+		{"remove-script", "backup"},
+	}
+	for _, command := range commands {
+		if _, err := handleCommand(append([]string{"run-script-service"}, command...), configPath); err != nil {
+			t.Fatalf("%v: expected no error, got: %v", command, err)
+		}
+	}
+
+	entries, err := auditLog().Query(service.AuditFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{service.AuditScriptDelete, service.AuditConfigUpdate, service.AuditScriptDisable, service.AuditScriptAdd}
+	if len(entries) != len(expected) {
+		t.Fatalf("Expected %d entries, got %+v", len(expected), entries)
+	}
+	for i, action := range expected {
+		if entries[i].Action != action || entries[i].Source != service.AuditSourceCLI || entries[i].Actor != cliActor() {
+			t.Errorf("Entry %d: unexpected %+v", i, entries[i])
+		}
+	}
+
+	disable := entries[2]
+	if len(disable.Diff) != 2 || !strings.Contains(disable.Diff[1].Text, `"enabled": false`) {
+		t.Errorf("Expected the enabled flag in the diff, got %+v", disable.Diff)
+	}
+	port := entries[1]
+	if port.Detail != "web port" || len(port.Diff) != 2 || !strings.Contains(port.Diff[1].Text, "9090") {
+		t.Errorf("Expected the port change in the diff, got %+v", port)
+	}
+}
+
+func TestHandleAuditCommand(t *testing.T) {
+	useTempDataDir(t)
+	recordAudit(service.AuditLogsClear, "backup", "", nil, nil)
+
+	valid := [][]string{
+		{"audit"},
+		{"audit", "--actor=" + cliActor(), "--action=logs.", "--since=1h", "--diff"},
+	}
+	for _, args := range valid {
+		if _, err := handleCommand(append([]string{"run-script-service"}, args...), ""); err != nil {
+			t.Errorf("%v: expected no error, got: %v", args, err)
+		}
+	}
+
+	invalid := []struct {
+		args   []string
+		errMsg string
+	}{
+		{[]string{"audit", "backup"}, "usage"},
+		{[]string{"audit", "--limit=-1"}, "invalid limit"},
+		{[]string{"audit", "--since=yesterday"}, "invalid"},
+	}
+	for _, tt := range invalid {
+		_, err := handleCommand(append([]string{"run-script-service"}, tt.args...), "")
+		if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+			t.Errorf("%v: expected error containing %q, got %v", tt.args, tt.errMsg, err)
+		}
+	}
+}
@@ -0,0 +1,209 @@
+// Package main provides the run-script-service daemon executable.
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"run-script-service/service"
+)
+
+// apiTokenEnv holds the API token the CLI sends to an authenticated daemon
+const apiTokenEnv = "RSS_API_TOKEN"
+
+// Client certificate and key the CLI presents to a daemon that requires mutual TLS
+const (
+	clientCertEnv = "RSS_CLIENT_CERT"
+	clientKeyEnv  = "RSS_CLIENT_KEY"
+)
+
+// authStore opens the credential store in the data directory
+func authStore() *service.AuthStore {
+	return service.NewAuthStore(service.AuthStorePath(appSettings.DataDir))
+}
+
+// handleUserCommand manages the users that can log in to the web interface and API.
+// Usage: user <add <name> [--role=<role>]|role <name> <role>|remove <name>|list>
+func handleUserCommand(args []string, _ string) (CommandResult, error) {
+	usage := fmt.Errorf("usage: ./run-script-service user <add <name> [--role=<role>]|role <name> <role>|remove <name>|list>")
+	flags, args, err := parseCommandFlags(args)
+	if err != nil {
+		return CommandResult{shouldRunService: false}, err
+	}
+	if len(args) < 1 {
+		return CommandResult{shouldRunService: false}, usage
+	}
+
+	store := authStore()
+	switch args[0] {
+	case "add":
+		if len(args) != 2 {
+			return CommandResult{shouldRunService: false}, usage
+		}
+		return handleUserAdd(store, args[1], flags["role"], os.Stdin, os.Stderr)
+	case "role":
+		if len(args) != 3 {
+			return CommandResult{shouldRunService: false}, usage
+		}
+		if err := store.SetUserRole(args[1], args[2]); err != nil {
+			return CommandResult{shouldRunService: false}, err
+		}
+		recordAudit(service.AuditUserChange, args[1], "role "+args[2], nil, nil)
+		fmt.Printf("User %s is now %s\n", args[1], args[2])
+		return CommandResult{shouldRunService: false}, nil
+	case "remove":
+		if len(args) != 2 {
+			return CommandResult{shouldRunService: false}, usage
+		}
+		if err := store.RemoveUser(args[1]); err != nil {
+			return CommandResult{shouldRunService: false}, err
+		}
+		recordAudit(service.AuditUserChange, args[1], "removed", nil, nil)
+		fmt.Printf("Removed user %s\n", args[1])
+		return CommandResult{shouldRunService: false}, nil
+	case "list":
+		users, err := store.Users()
+		if err != nil {
+			return CommandResult{shouldRunService: false}, err
+		}
+		if len(users) == 0 {
+			fmt.Println("No users")
+		}
+		for _, user := range users {
+			fmt.Printf("%-20s %-9s created %s\n", user.Name, user.Role, user.Created.Local().Format("2006-01-02 15:04:05"))
+		}
+		return CommandResult{shouldRunService: false}, nil
+	default:
+		return CommandResult{shouldRunService: false},
+			fmt.Errorf("unknown user subcommand: %s\navailable subcommands: add, role, remove, list", args[0])
+	}
+}
+
+// handleUserAdd creates a user or resets its password. The password is read from the
+// terminal without echo, or from the first line of stdin when it is not a terminal.
+// New users are admins unless role is set.
+func handleUserAdd(store *service.AuthStore, name, role string, in *os.File, prompt io.Writer) (CommandResult, error) {
+	if role != "" {
+		if err := service.ValidateRole(role); err != nil {
+			return CommandResult{shouldRunService: false}, err
+		}
+	}
+	password, err := readPassword(in, prompt, "Password: ")
+	if err != nil {
+		return CommandResult{shouldRunService: false}, err
+	}
+	if isTerminal(in) {
+		confirm, err := readPassword(in, prompt, "Repeat password: ")
+		if err != nil {
+			return CommandResult{shouldRunService: false}, err
+		}
+		if confirm != password {
+			return CommandResult{shouldRunService: false}, fmt.Errorf("passwords do not match")
+		}
+	}
+
+	created, err := store.AddUser(name, password, role)
+	if err != nil {
+		return CommandResult{shouldRunService: false}, err
+	}
+	if created {
+		recordAudit(service.AuditUserChange, name, "added", nil, nil)
+		fmt.Printf("Added user %s\n", name)
+	} else {
+		recordAudit(service.AuditUserChange, name, "password changed", nil, nil)
+		fmt.Printf("Changed the password of user %s\n", name)
+	}
+	return CommandResult{shouldRunService: false}, nil
+}
+
+// readPassword reads one line, turning terminal echo off while it is typed
+func readPassword(in *os.File, prompt io.Writer, label string) (string, error) {
+	if isTerminal(in) {
+		fmt.Fprint(prompt, label)
+		if err := setTerminalEcho(in, false); err == nil {
+			defer func() {
+				_ = setTerminalEcho(in, true)
+				fmt.Fprintln(prompt)
+			}()
+		}
+	}
+
+	line, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return "", fmt.Errorf("failed to read password: %v", err)
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// setTerminalEcho turns echo of a terminal on or off
+func setTerminalEcho(tty *os.File, on bool) error {
+	mode := "-echo"
+	if on {
+		mode = "echo"
+	}
+	cmd := exec.Command("stty", mode)
+	cmd.Stdin = tty
+	return cmd.Run()
+}
+
+// handleTokenCommand manages API tokens for scripts and the CLI.
+// Usage: token <create <name> [--role=<role>]|revoke <id|name>|list>
+func handleTokenCommand(args []string, _ string) (CommandResult, error) {
+	usage := fmt.Errorf("usage: ./run-script-service token <create <name> [--role=<role>]|revoke <id|name>|list>")
+	flags, args, err := parseCommandFlags(args)
+	if err != nil {
+		return CommandResult{shouldRunService: false}, err
+	}
+	if len(args) < 1 {
+		return CommandResult{shouldRunService: false}, usage
+	}
+
+	store := authStore()
+	switch args[0] {
+	case "create":
+		if len(args) != 2 {
+			return CommandResult{shouldRunService: false}, usage
+		}
+		role := flags["role"]
+		if role == "" {
+			role = service.RoleAdmin
+		}
+		token, info, err := store.CreateToken(args[1], role)
+		if err != nil {
+			return CommandResult{shouldRunService: false}, err
+		}
+		recordAudit(service.AuditTokenChange, info.Name, fmt.Sprintf("created as %s with role %s", info.ID, info.Role), nil, nil)
+		fmt.Printf("Created %s token %s (id %s). It is shown only once:\n\n%s\n\n", info.Role, info.Name, info.ID, token)
+		fmt.Printf("Send it as \"Authorization: Bearer <token>\", or set %s for the CLI.\n", apiTokenEnv)
+		return CommandResult{shouldRunService: false}, nil
+	case "revoke":
+		if len(args) != 2 {
+			return CommandResult{shouldRunService: false}, usage
+		}
+		if err := store.RevokeToken(args[1]); err != nil {
+			return CommandResult{shouldRunService: false}, err
+		}
+		recordAudit(service.AuditTokenChange, args[1], "revoked", nil, nil)
+		fmt.Printf("Revoked token %s\n", args[1])
+		return CommandResult{shouldRunService: false}, nil
+	case "list":
+		tokens, err := store.Tokens()
+		if err != nil {
+			return CommandResult{shouldRunService: false}, err
+		}
+		if len(tokens) == 0 {
+			fmt.Println("No tokens")
+		}
+		for _, token := range tokens {
+			fmt.Printf("%-10s %-20s %-9s created %s\n", token.ID, token.Name, token.Role, token.Created.Local().Format("2006-01-02 15:04:05"))
+		}
+		return CommandResult{shouldRunService: false}, nil
+	default:
+		return CommandResult{shouldRunService: false},
+			fmt.Errorf("unknown token subcommand: %s\navailable subcommands: create, revoke, list", args[0])
+	}
+}
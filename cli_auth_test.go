@@ -0,0 +1,112 @@
+// Package main provides tests for the user and token CLI commands
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"run-script-service/service"
+)
+
+// useTempDataDir points the data directory at a temporary directory for one test
+func useTempDataDir(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	previous := appSettings.DataDir
+	appSettings.DataDir = dir
+	t.Cleanup(func() { appSettings.DataDir = previous })
+	return dir
+}
+
+func TestHandleUserAdd_FromStdin(t *testing.T) {
+	useTempDataDir(t)
+
+	input := filepath.Join(t.TempDir(), "password")
+	if err := os.WriteFile(input, []byte("password1\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	in, err := os.Open(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer in.Close()
+
+	if _, err := handleUserAdd(authStore(), "alice", "", in, os.Stderr); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if !authStore().CheckPassword("alice", "password1") {
+		t.Error("Expected the password from stdin to be set")
+	}
+
+	if _, err := handleCommand([]string{"run-script-service", "user", "role", "alice", "operator"}, ""); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if role, _ := authStore().UserRole("alice"); role != service.RoleOperator {
+		t.Errorf("Expected alice to be an operator, got %s", role)
+	}
+
+	if _, err := handleCommand([]string{"run-script-service", "user", "remove", "alice"}, ""); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if authStore().HasUser("alice") {
+		t.Error("Expected alice to be removed")
+	}
+}
+
+func TestHandleTokenCommand(t *testing.T) {
+	useTempDataDir(t)
+
+	if _, err := handleCommand([]string{"run-script-service", "token", "create", "ci", "--role=operator"}, ""); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	tokens, err := authStore().Tokens()
+	if err != nil || len(tokens) != 1 || tokens[0].Name != "ci" || tokens[0].Role != service.RoleOperator {
+		t.Fatalf("Expected the ci token, got %+v, %v", tokens, err)
+	}
+
+	if _, err := handleCommand([]string{"run-script-service", "token", "revoke", "ci"}, ""); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if has, _ := authStore().HasCredentials(); has {
+		t.Error("Expected the token to be revoked")
+	}
+}
+
+func TestHandleUserAndTokenCommand_Usage(t *testing.T) {
+	useTempDataDir(t)
+
+	tests := []struct {
+		args   []string
+		errMsg string
+	}{
+		{[]string{"user"}, "usage"},
+		{[]string{"user", "add"}, "usage"},
+		{[]string{"user", "rename", "a"}, "unknown user subcommand"},
+		{[]string{"user", "remove", "bob"}, "not found"},
+		{[]string{"token", "create"}, "usage"},
+		{[]string{"token", "rotate"}, "unknown token subcommand"},
+		{[]string{"token", "revoke", "missing"}, "not found"},
+		{[]string{"token", "create", "bad name"}, "invalid token name"},
+		{[]string{"token", "create", "ci", "--role=root"}, "invalid role"},
+		{[]string{"user", "add", "bob", "--role=root"}, "invalid role"},
+		{[]string{"user", "role", "bob"}, "usage"},
+	}
+	for _, tt := range tests {
+		_, err := handleCommand(append([]string{"run-script-service"}, tt.args...), "")
+		if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+			t.Errorf("%v: expected error containing %q, got %v", tt.args, tt.errMsg, err)
+		}
+	}
+}
+
+func TestAuthStorePath(t *testing.T) {
+	dir := useTempDataDir(t)
+	if _, _, err := authStore().CreateToken("ci", service.RoleAdmin); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, service.AuthFileName)); err != nil {
+		t.Errorf("Expected the store in the data directory, got %v", err)
+	}
+}
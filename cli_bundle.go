@@ -0,0 +1,158 @@
+// Package main provides the run-script-service daemon executable.
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	"run-script-service/service"
+)
+
+// parseCommandFlags splits args into --key=value flags and positional arguments.
+// Names listed in booleans may be given without a value.
+func parseCommandFlags(args []string, booleans ...string) (map[string]string, []string, error) {
+	isBoolean := make(map[string]bool)
+	for _, name := range booleans {
+		isBoolean[name] = true
+	}
+
+	flags := make(map[string]string)
+	var positional []string
+	for _, arg := range args {
+		if !strings.HasPrefix(arg, "--") {
+			positional = append(positional, arg)
+			continue
+		}
+		parts := strings.SplitN(arg[2:], "=", 2)
+		switch {
+		case len(parts) == 2:
+			flags[parts[0]] = parts[1]
+		case isBoolean[parts[0]]:
+			flags[parts[0]] = "true"
+		default:
+			return nil, nil, fmt.Errorf("invalid flag format: %s (expected --key=value)", arg)
+		}
+	}
+	return flags, positional, nil
+}
+
+// handleExport writes selected scripts and their files to a bundle.
+// Usage: export [--scripts=a,b] [--format=json|tar.gz] [--output=<file>]
+func handleExport(args []string, configPath string) (CommandResult, error) {
+	flags, positional, err := parseCommandFlags(args)
+	if err != nil {
+		return CommandResult{shouldRunService: false}, err
+	}
+	if len(positional) > 0 {
+		return CommandResult{shouldRunService: false},
+			fmt.Errorf("usage: ./run-script-service export [--scripts=a,b] [--format=json|tar.gz] [--output=<file>]")
+	}
+
+	output := flags["output"]
+	formatName := flags["format"]
+	if formatName == "" {
+		formatName = "json"
+		if strings.HasSuffix(output, ".tar.gz") || strings.HasSuffix(output, ".tgz") {
+			formatName = "tar.gz"
+		}
+	}
+	format, err := service.ParseBundleFormat(formatName)
+	if err != nil {
+		return CommandResult{shouldRunService: false}, err
+	}
+
+	var names []string
+	if scripts := flags["scripts"]; scripts != "" {
+		names = strings.Split(scripts, ",")
+	}
+
+	var config service.ServiceConfig
+	if err := service.LoadServiceConfig(configPath, &config); err != nil {
+		return CommandResult{shouldRunService: false}, fmt.Errorf("failed to load config: %v", err)
+	}
+
+	bundle, err := service.ExportBundle(&config, "", names)
+	if err != nil {
+		return CommandResult{shouldRunService: false}, err
+	}
+
+	var buf bytes.Buffer
+	if err := service.EncodeBundle(&buf, bundle, format); err != nil {
+		return CommandResult{shouldRunService: false}, fmt.Errorf("failed to encode bundle: %v", err)
+	}
+
+	if output == "" || output == "-" {
+		os.Stdout.Write(buf.Bytes())
+		return CommandResult{shouldRunService: false}, nil
+	}
+	if err := os.WriteFile(output, buf.Bytes(), 0644); err != nil {
+		return CommandResult{shouldRunService: false}, fmt.Errorf("failed to write bundle: %v", err)
+	}
+	fmt.Printf("Exported %d script(s) to %s\n", len(bundle.Scripts), output)
+	return CommandResult{shouldRunService: false}, nil
+}
+
+// handleImport imports a bundle into the configuration.
+// Usage: import <file> [--conflict=skip|rename|overwrite] [--dry-run]
+func handleImport(args []string, configPath string) (CommandResult, error) {
+	flags, positional, err := parseCommandFlags(args, "dry-run")
+	if err != nil {
+		return CommandResult{shouldRunService: false}, err
+	}
+	if len(positional) != 1 {
+		return CommandResult{shouldRunService: false},
+			fmt.Errorf("usage: ./run-script-service import <file> [--conflict=skip|rename|overwrite] [--dry-run]")
+	}
+
+	policy := service.ConflictSkip
+	if name, ok := flags["conflict"]; ok {
+		if policy, err = service.ParseConflictPolicy(name); err != nil {
+			return CommandResult{shouldRunService: false}, err
+		}
+	}
+
+	data, err := os.ReadFile(positional[0])
+	if err != nil {
+		return CommandResult{shouldRunService: false}, fmt.Errorf("failed to read bundle: %v", err)
+	}
+	bundle, err := service.DecodeBundle(data)
+	if err != nil {
+		return CommandResult{shouldRunService: false}, err
+	}
+
+	var config service.ServiceConfig
+	if err := service.LoadServiceConfig(configPath, &config); err != nil {
+		return CommandResult{shouldRunService: false}, fmt.Errorf("failed to load config: %v", err)
+	}
+
+	before := service.AuditSnapshot(&config)
+	opts := service.ImportOptions{Conflict: policy, DryRun: flags["dry-run"] == "true"}
+	result, err := service.ImportBundle(&config, bundle, opts)
+	if err != nil {
+		return CommandResult{shouldRunService: false}, fmt.Errorf("import failed: %v", err)
+	}
+
+	if opts.DryRun {
+		fmt.Println("Dry run, no changes made:")
+	}
+	fmt.Printf("%-10s %-20s %-20s %s\n", "ACTION", "SCRIPT", "PATH", "REASON")
+	for _, action := range result.Actions {
+		name := action.Name
+		if name != action.OriginalName {
+			name = fmt.Sprintf("%s (was %s)", action.Name, action.OriginalName)
+		}
+		fmt.Printf("%-10s %-20s %-20s %s\n", action.Action, name, action.Path, action.Reason)
+	}
+
+	if opts.DryRun || !result.Changed() {
+		return CommandResult{shouldRunService: false}, nil
+	}
+	if err := service.SaveServiceConfig(configPath, &config); err != nil {
+		return CommandResult{shouldRunService: false}, fmt.Errorf("failed to save config: %v", err)
+	}
+	recordAudit(service.AuditConfigImport, positional[0], result.Summary(), before, service.AuditSnapshot(&config))
+	notifyDaemonReload()
+	return CommandResult{shouldRunService: false}, nil
+}
@@ -0,0 +1,88 @@
+// Package main provides tests for script import/export CLI commands
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"run-script-service/service"
+)
+
+func TestParseCommandFlags(t *testing.T) {
+	flags, positional, err := parseCommandFlags([]string{"bundle.json", "--conflict=rename", "--dry-run"}, "dry-run")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if !reflect.DeepEqual(flags, map[string]string{"conflict": "rename", "dry-run": "true"}) {
+		t.Errorf("Unexpected flags: %v", flags)
+	}
+	if !reflect.DeepEqual(positional, []string{"bundle.json"}) {
+		t.Errorf("Unexpected positional args: %v", positional)
+	}
+
+	if _, _, err := parseCommandFlags([]string{"--dry-run"}); err == nil {
+		t.Error("Expected error for boolean flag that is not allowed")
+	}
+}
+
+func TestExportImportCommands(t *testing.T) {
+	dir := t.TempDir()
+	scriptPath := filepath.Join(dir, "backup.sh")
+	if err := os.WriteFile(scriptPath, []byte("#!/bin/bash\necho backup\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	sourceConfig := filepath.Join(dir, "source.json")
+	config := &service.ServiceConfig{Scripts: []service.ScriptConfig{
+		{Name: "backup", Path: scriptPath, Interval: 60, Enabled: true, MaxLogLines: 100},
+	}}
+	if err := service.SaveServiceConfig(sourceConfig, config); err != nil {
+		t.Fatalf("Failed to save config: %v", err)
+	}
+
+	bundlePath := filepath.Join(dir, "bundle.tar.gz")
+	if _, err := handleExport([]string{"--output=" + bundlePath}, sourceConfig); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+
+	// Import writes script files into the working directory
+	workDir := t.TempDir()
+	previous, _ := os.Getwd()
+	if err := os.Chdir(workDir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(previous)
+
+	targetConfig := filepath.Join(workDir, "target.json")
+
+	if _, err := handleImport([]string{bundlePath, "--dry-run"}, targetConfig); err != nil {
+		t.Fatalf("Dry run failed: %v", err)
+	}
+	if _, err := os.Stat(targetConfig); !os.IsNotExist(err) {
+		t.Error("Dry run must not write the config")
+	}
+
+	if _, err := handleImport([]string{bundlePath}, targetConfig); err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+
+	var imported service.ServiceConfig
+	if err := service.LoadServiceConfig(targetConfig, &imported); err != nil {
+		t.Fatalf("Failed to load imported config: %v", err)
+	}
+	if len(imported.Scripts) != 1 || imported.Scripts[0].Path != "./backup.sh" {
+		t.Errorf("Unexpected imported config: %+v", imported.Scripts)
+	}
+	if _, err := os.Stat(filepath.Join(workDir, "backup.sh")); err != nil {
+		t.Errorf("Expected script file to be imported: %v", err)
+	}
+
+	if _, err := handleImport([]string{}, targetConfig); err == nil {
+		t.Error("Expected usage error without a bundle file")
+	}
+	if _, err := handleImport([]string{bundlePath, "--conflict=merge"}, targetConfig); err == nil {
+		t.Error("Expected error for unknown conflict policy")
+	}
+}
@@ -0,0 +1,152 @@
+// Package main provides the run-script-service daemon executable.
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"syscall"
+
+	"run-script-service/service"
+)
+
+// handleConfigCommand handles config subcommands (history/show/diff/rollback)
+func handleConfigCommand(args []string, configPath string) (CommandResult, error) {
+	usage := fmt.Errorf("usage: ./run-script-service config <history|show <version>|diff <a> <b>|rollback <version>>")
+	if len(args) < 1 {
+		return CommandResult{shouldRunService: false}, usage
+	}
+
+	history := service.NewConfigHistory(configPath, configHistoryLimit(configPath))
+
+	switch args[0] {
+	case "history":
+		return handleConfigHistory(history)
+	case "show":
+		if len(args) != 2 {
+			return CommandResult{shouldRunService: false}, usage
+		}
+		return handleConfigShow(history, args[1])
+	case "diff":
+		if len(args) != 3 {
+			return CommandResult{shouldRunService: false}, usage
+		}
+		return handleConfigDiff(history, args[1], args[2])
+	case "rollback":
+		if len(args) != 2 {
+			return CommandResult{shouldRunService: false}, usage
+		}
+		return handleConfigRollback(history, args[1])
+	default:
+		return CommandResult{shouldRunService: false},
+			fmt.Errorf("unknown config subcommand: %s\navailable subcommands: history, show, diff, rollback", args[0])
+	}
+}
+
+// configHistoryLimit returns the history limit configured in the config file
+func configHistoryLimit(configPath string) int {
+	var config service.ServiceConfig
+	if err := service.LoadServiceConfig(configPath, &config); err != nil {
+		return 0
+	}
+	return config.ConfigHistoryLimit
+}
+
+// parseVersion parses a config version number argument
+func parseVersion(value string) (int, error) {
+	version, err := strconv.Atoi(value)
+	if err != nil || version < 1 {
+		return 0, fmt.Errorf("invalid version: %s", value)
+	}
+	return version, nil
+}
+
+// handleConfigHistory lists recorded configuration versions
+func handleConfigHistory(history *service.ConfigHistory) (CommandResult, error) {
+	versions, err := history.List()
+	if err != nil {
+		return CommandResult{shouldRunService: false}, fmt.Errorf("failed to read config history: %v", err)
+	}
+
+	if len(versions) == 0 {
+		fmt.Println("No configuration history recorded")
+		return CommandResult{shouldRunService: false}, nil
+	}
+
+	fmt.Printf("%-8s %-20s %-10s %-8s %s\n", "VERSION", "TIMESTAMP", "ORIGIN", "SIZE", "CHECKSUM")
+	for _, v := range versions {
+		fmt.Printf("%-8d %-20s %-10s %-8d %s\n",
+			v.Version, v.Timestamp.Format("2006-01-02 15:04:05"), v.Origin, v.Size, v.Checksum[:12])
+	}
+	return CommandResult{shouldRunService: false}, nil
+}
+
+// handleConfigShow prints the content of a recorded version
+func handleConfigShow(history *service.ConfigHistory, versionArg string) (CommandResult, error) {
+	version, err := parseVersion(versionArg)
+	if err != nil {
+		return CommandResult{shouldRunService: false}, err
+	}
+
+	data, _, err := history.Get(version)
+	if err != nil {
+		return CommandResult{shouldRunService: false}, err
+	}
+	fmt.Println(string(data))
+	return CommandResult{shouldRunService: false}, nil
+}
+
+// handleConfigDiff prints the differences between two recorded versions
+func handleConfigDiff(history *service.ConfigHistory, fromArg, toArg string) (CommandResult, error) {
+	from, err := parseVersion(fromArg)
+	if err != nil {
+		return CommandResult{shouldRunService: false}, err
+	}
+	to, err := parseVersion(toArg)
+	if err != nil {
+		return CommandResult{shouldRunService: false}, err
+	}
+
+	diff, err := history.Diff(from, to)
+	if err != nil {
+		return CommandResult{shouldRunService: false}, err
+	}
+
+	if !service.HasChanges(diff) {
+		fmt.Printf("Versions %d and %d are identical\n", from, to)
+		return CommandResult{shouldRunService: false}, nil
+	}
+	fmt.Print(service.FormatDiff(fmt.Sprintf("v%d", from), fmt.Sprintf("v%d", to), diff, 3))
+	return CommandResult{shouldRunService: false}, nil
+}
+
+// handleConfigRollback restores a recorded version and asks a running daemon to reload it
+func handleConfigRollback(history *service.ConfigHistory, versionArg string) (CommandResult, error) {
+	version, err := parseVersion(versionArg)
+	if err != nil {
+		return CommandResult{shouldRunService: false}, err
+	}
+
+	before, _ := os.ReadFile(history.ConfigPath())
+	restored, err := history.Rollback(version)
+	if err != nil {
+		return CommandResult{shouldRunService: false}, fmt.Errorf("rollback failed: %v", err)
+	}
+	after, _ := os.ReadFile(history.ConfigPath())
+	recordAudit(service.AuditConfigRollback, fmt.Sprintf("version %d", version), "", before, after)
+	fmt.Printf("Configuration rolled back to version %d (recorded as version %d)\n", version, restored.Version)
+
+	notifyDaemonReload()
+	return CommandResult{shouldRunService: false}, nil
+}
+
+// notifyDaemonReload asks a running daemon to pick up the configuration file again
+func notifyDaemonReload() {
+	if pid, pidErr := readPidFile(); pidErr == nil && isProcessRunning(pid) {
+		if sigErr := syscall.Kill(pid, syscall.SIGHUP); sigErr != nil {
+			fmt.Printf("Warning: failed to notify running service (PID: %d): %v\n", pid, sigErr)
+		} else {
+			fmt.Printf("Running service (PID: %d) notified to reload configuration\n", pid)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+// Package main provides tests for config history CLI commands
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"run-script-service/service"
+)
+
+func TestConfigHistoryCommands(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "service_config.json")
+
+	config := &service.ServiceConfig{WebPort: 8080}
+	if err := service.SaveServiceConfig(configPath, config); err != nil {
+		t.Fatalf("Failed to save config: %v", err)
+	}
+	if _, err := handleSetWebPort("9090", configPath); err != nil {
+		t.Fatalf("Failed to set web port: %v", err)
+	}
+
+	t.Run("history", func(t *testing.T) {
+		if _, err := handleConfigCommand([]string{"history"}, configPath); err != nil {
+			t.Errorf("Expected no error, got: %v", err)
+		}
+	})
+
+	t.Run("diff", func(t *testing.T) {
+		if _, err := handleConfigCommand([]string{"diff", "1", "2"}, configPath); err != nil {
+			t.Errorf("Expected no error, got: %v", err)
+		}
+		if _, err := handleConfigCommand([]string{"diff", "1", "x"}, configPath); err == nil {
+			t.Error("Expected error for invalid version")
+		}
+	})
+
+	t.Run("rollback", func(t *testing.T) {
+		if _, err := handleConfigCommand([]string{"rollback", "1"}, configPath); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+
+		var loaded service.ServiceConfig
+		if err := service.LoadServiceConfig(configPath, &loaded); err != nil {
+			t.Fatalf("Failed to load config: %v", err)
+		}
+		if loaded.WebPort != 8080 {
+			t.Errorf("Expected web port 8080 after rollback, got %d", loaded.WebPort)
+		}
+	})
+
+	t.Run("usage errors", func(t *testing.T) {
+		for _, args := range [][]string{{}, {"rollback"}, {"unknown"}} {
+			if _, err := handleConfigCommand(args, configPath); err == nil {
+				t.Errorf("Expected error for args %v", args)
+			}
+		}
+	})
+}
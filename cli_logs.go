@@ -0,0 +1,254 @@
+// Package main provides the run-script-service daemon executable.
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"run-script-service/service"
+)
+
+// ANSI colors of followed run statuses
+const (
+	colorReset  = "\033[0m"
+	colorRed    = "\033[31m"
+	colorGreen  = "\033[32m"
+	colorYellow = "\033[33m"
+)
+
+// handleMigrateLogs converts script logs in the old text format to the current record format.
+// Usage: migrate-logs [--dry-run]
+func handleMigrateLogs(args []string, _ string) (CommandResult, error) {
+	flags, positional, err := parseCommandFlags(args, "dry-run")
+	if err != nil {
+		return CommandResult{shouldRunService: false}, err
+	}
+	if len(positional) > 0 {
+		return CommandResult{shouldRunService: false},
+			fmt.Errorf("usage: ./run-script-service migrate-logs [--dry-run]")
+	}
+	dryRun := flags["dry-run"] == "true"
+
+	migrations, err := service.MigrateLogs(appSettings.LogDir, dryRun)
+	if err != nil {
+		return CommandResult{shouldRunService: false}, fmt.Errorf("log migration failed: %v", err)
+	}
+
+	if len(migrations) == 0 {
+		fmt.Printf("No log files found in %s\n", appSettings.LogDir)
+		return CommandResult{shouldRunService: false}, nil
+	}
+	if dryRun {
+		fmt.Println("Dry run, no changes made:")
+	}
+
+	migrated := 0
+	fmt.Printf("%-20s %-10s %s\n", "SCRIPT", "ENTRIES", "STATUS")
+	for _, migration := range migrations {
+		status := "up to date"
+		if migration.Migrated {
+			migrated++
+			status = "converted"
+			if dryRun {
+				status = "would convert"
+			}
+		}
+		fmt.Printf("%-20s %-10d %s\n", migration.Script, migration.Entries, status)
+	}
+	if migrated > 0 && !dryRun {
+		fmt.Printf("Converted %d log file(s), originals kept with %s suffix\n", migrated, service.LegacyBackupSuffix)
+	}
+	return CommandResult{shouldRunService: false}, nil
+}
+
+// handleFollowLogs prints runs and their output live from the running daemon until Ctrl-C.
+// Usage: logs --follow [--script=<name>] [--exit-code=<code>] [--no-color]
+func handleFollowLogs(flags map[string]string, query *service.LogQuery) (CommandResult, error) {
+	follower := &logFollower{
+		out:      os.Stdout,
+		script:   query.ScriptName,
+		exitCode: query.ExitCode,
+		color:    flags["no-color"] != "true" && os.Getenv("NO_COLOR") == "" && isTerminal(os.Stdout),
+		pending:  make(map[string][]string),
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	dialer, err := followDialer(appSettings)
+	if err != nil {
+		return CommandResult{shouldRunService: false}, err
+	}
+	url := followURL(appSettings)
+	fmt.Printf("Following logs from %s (Ctrl-C to stop)\n", webURL(appSettings))
+	return CommandResult{shouldRunService: false}, followLogs(ctx, dialer, url, follower)
+}
+
+// followURL returns the WebSocket endpoint of the daemon's web interface. Connections
+// to a unix socket use a placeholder host, followDialer routes them to the socket.
+func followURL(settings *service.Settings) string {
+	if _, ok := service.UnixSocketPath(settings.BindAddress); ok {
+		return "ws://localhost/ws"
+	}
+	return "ws" + strings.TrimPrefix(webURL(settings), "http") + "/ws"
+}
+
+// followDialer connects to the daemon's unix socket, or over TLS as configured by daemonTLSConfig
+func followDialer(settings *service.Settings) (*websocket.Dialer, error) {
+	dialer := *websocket.DefaultDialer
+	if path, ok := service.UnixSocketPath(settings.BindAddress); ok {
+		dialer.NetDialContext = unixDialer(path)
+		return &dialer, nil
+	}
+	config, err := daemonTLSConfig(settings)
+	if err != nil {
+		return nil, err
+	}
+	dialer.TLSClientConfig = config
+	return &dialer, nil
+}
+
+// isTerminal reports whether f is attached to a terminal
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// followLogs streams daemon events from url into the follower until ctx is done
+func followLogs(ctx context.Context, dialer *websocket.Dialer, url string, follower *logFollower) error {
+	header := http.Header{}
+	if token := os.Getenv(apiTokenEnv); token != "" {
+		header.Set("Authorization", "Bearer "+token)
+	}
+	conn, _, err := dialer.DialContext(ctx, url, header)
+	if err != nil {
+		return fmt.Errorf("failed to connect to the daemon at %s (is it running with the web interface?): %v", url, err)
+	}
+	defer conn.Close()
+
+	go func() {
+		<-ctx.Done()
+		_ = conn.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(time.Second))
+		conn.Close()
+	}()
+
+	for {
+		_, message, err := conn.ReadMessage()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("connection to the daemon lost: %v", err)
+		}
+		follower.handle(message)
+	}
+}
+
+// followMessage is the part of a daemon WebSocket message the follower uses
+type followMessage struct {
+	Type      string    `json:"type"`
+	Timestamp time.Time `json:"timestamp"`
+	Data      struct {
+		ScriptName string `json:"script_name"`
+		Status     string `json:"status"`
+		ExitCode   int    `json:"exit_code"`
+		Duration   int64  `json:"duration"`
+		Stream     string `json:"stream"`
+		Line       string `json:"line"`
+	} `json:"data"`
+}
+
+// logFollower prints live runs and output lines matching the filters
+type logFollower struct {
+	out      io.Writer
+	script   string
+	exitCode *int
+	color    bool
+	pending  map[string][]string // output held back until the run's exit code is known
+}
+
+// handle prints one WebSocket frame, which may hold several newline-separated messages
+func (f *logFollower) handle(frame []byte) {
+	for _, raw := range bytes.Split(frame, []byte{'\n'}) {
+		var msg followMessage
+		if err := json.Unmarshal(raw, &msg); err != nil {
+			continue
+		}
+		if f.script != "" && msg.Data.ScriptName != f.script {
+			continue
+		}
+		switch msg.Type {
+		case "script_status":
+			f.status(&msg)
+		case "script_output":
+			f.output(&msg)
+		}
+	}
+}
+
+// status prints a run starting or finishing
+func (f *logFollower) status(msg *followMessage) {
+	name := msg.Data.ScriptName
+	stamp := msg.Timestamp.Local().Format("2006-01-02 15:04:05")
+
+	switch msg.Data.Status {
+	case "starting":
+		delete(f.pending, name)
+		if f.exitCode == nil {
+			fmt.Fprintf(f.out, "[%s] %s %s\n", stamp, name, f.paint(colorYellow, "started"))
+		}
+	case "completed", service.StatusWarning, "failed":
+		lines := f.pending[name]
+		delete(f.pending, name)
+		if f.exitCode != nil && msg.Data.ExitCode != *f.exitCode {
+			return
+		}
+		for _, line := range lines {
+			fmt.Fprintln(f.out, line)
+		}
+		color := colorGreen
+		switch msg.Data.Status {
+		case service.StatusWarning:
+			color = colorYellow
+		case "failed":
+			color = colorRed
+		}
+		fmt.Fprintf(f.out, "[%s] %s %s (exit: %d, duration: %dms)\n",
+			stamp, name, f.paint(color, msg.Data.Status), msg.Data.ExitCode, msg.Data.Duration)
+	}
+}
+
+// output prints a line of a running script, or holds it back while filtering by exit code
+func (f *logFollower) output(msg *followMessage) {
+	label := "STDOUT:"
+	if msg.Data.Stream == service.StreamStderr {
+		label = f.paint(colorRed, "STDERR:")
+	}
+	line := fmt.Sprintf("  %s %s %s", msg.Data.ScriptName, label, msg.Data.Line)
+
+	if f.exitCode != nil {
+		f.pending[msg.Data.ScriptName] = append(f.pending[msg.Data.ScriptName], line)
+		return
+	}
+	fmt.Fprintln(f.out, line)
+}
+
+// paint wraps text in an ANSI color when colors are enabled
+func (f *logFollower) paint(color, text string) string {
+	if !f.color {
+		return text
+	}
+	return color + text + colorReset
+}
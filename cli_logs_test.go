@@ -0,0 +1,219 @@
+// Package main provides tests for log maintenance CLI commands
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"run-script-service/service"
+)
+
+func TestMigrateLogsCommand(t *testing.T) {
+	dir := t.TempDir()
+	previous := appSettings.LogDir
+	appSettings.LogDir = dir
+	defer func() { appSettings.LogDir = previous }()
+
+	legacy := "[2025-08-02 11:26:16] Exit code: 1\nSTDOUT: hello\n" +
+		"--------------------------------------------------\n"
+	logPath := service.LogFilePath(dir, "backup")
+	if err := os.WriteFile(logPath, []byte(legacy), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := handleCommand([]string{"run-script-service", "migrate-logs", "--dry-run"}, ""); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if data, _ := os.ReadFile(logPath); string(data) != legacy {
+		t.Error("Expected dry run to leave the log untouched")
+	}
+
+	result, err := handleCommand([]string{"run-script-service", "migrate-logs"}, "")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if result.shouldRunService {
+		t.Error("Expected shouldRunService to be false for migrate-logs command")
+	}
+
+	entries, isLegacy, err := service.ReadLogFile(logPath, "backup")
+	if err != nil || isLegacy || len(entries) != 1 || entries[0].ExitCode != 1 || entries[0].Stdout != "hello" {
+		t.Errorf("Expected one migrated record, got %+v (legacy %v, err %v)", entries, isLegacy, err)
+	}
+
+	if _, err := handleCommand([]string{"run-script-service", "migrate-logs", "extra"}, ""); err == nil {
+		t.Error("Expected usage error for positional argument")
+	}
+}
+
+// followFrames are daemon messages for a failed run of fetch and a successful run of report
+var followFrames = []string{
+	`{"type":"script_status","timestamp":"2025-03-01T12:00:00Z","data":{"script_name":"fetch","status":"starting"}}`,
+	`{"type":"script_output","timestamp":"2025-03-01T12:00:01Z","data":{"script_name":"fetch","stream":"stderr","line":"connection refused"}}` + "\n" +
+		`{"type":"system_metrics","timestamp":"2025-03-01T12:00:01Z","data":{"cpu":1}}`,
+	`{"type":"script_status","timestamp":"2025-03-01T12:00:00Z","data":{"script_name":"report","status":"starting"}}`,
+	`{"type":"script_output","timestamp":"2025-03-01T12:00:01Z","data":{"script_name":"report","stream":"stdout","line":"sent"}}`,
+	`{"type":"script_status","timestamp":"2025-03-01T12:00:02Z","data":{"script_name":"report","status":"completed","exit_code":0,"duration":40}}`,
+	`{"type":"script_status","timestamp":"2025-03-01T12:00:02Z","data":{"script_name":"fetch","status":"failed","exit_code":7,"duration":2000}}`,
+}
+
+func TestLogFollower(t *testing.T) {
+	seven := 7
+	tests := []struct {
+		name     string
+		follower logFollower
+		expected []string
+	}{
+		{
+			name:     "everything",
+			follower: logFollower{},
+			expected: []string{"fetch started", "fetch STDERR: connection refused", "report started", "report STDOUT: sent",
+				"report completed (exit: 0, duration: 40ms)", "fetch failed (exit: 7, duration: 2000ms)"},
+		},
+		{
+			name:     "script",
+			follower: logFollower{script: "report"},
+			expected: []string{"report started", "report STDOUT: sent", "report completed (exit: 0, duration: 40ms)"},
+		},
+		{
+			name:     "exit code holds output until the run ends",
+			follower: logFollower{exitCode: &seven},
+			expected: []string{"fetch STDERR: connection refused", "fetch failed (exit: 7, duration: 2000ms)"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			follower := tt.follower
+			follower.out = &out
+			follower.pending = make(map[string][]string)
+			for _, frame := range followFrames {
+				follower.handle([]byte(frame))
+			}
+
+			lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+			if len(lines) != len(tt.expected) {
+				t.Fatalf("Expected %d lines, got:\n%s", len(tt.expected), out.String())
+			}
+			for i, want := range tt.expected {
+				if !strings.Contains(lines[i], want) {
+					t.Errorf("Line %d: expected %q, got %q", i, want, lines[i])
+				}
+			}
+		})
+	}
+}
+
+func TestLogFollower_Color(t *testing.T) {
+	var out bytes.Buffer
+	follower := &logFollower{out: &out, color: true, pending: make(map[string][]string)}
+	follower.handle([]byte(followFrames[len(followFrames)-1]))
+
+	if !strings.Contains(out.String(), colorRed+"failed"+colorReset) {
+		t.Errorf("Expected a red failed status, got %q", out.String())
+	}
+
+	out.Reset()
+	follower.handle([]byte(`{"type":"script_status","timestamp":"2025-03-01T12:00:02Z","data":{"script_name":"sync","status":"warning","exit_code":1,"duration":5}}`))
+	if !strings.Contains(out.String(), colorYellow+"warning"+colorReset+" (exit: 1, duration: 5ms)") {
+		t.Errorf("Expected a yellow warning status, got %q", out.String())
+	}
+}
+
+func TestFollowLogs(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for _, frame := range followFrames {
+			_ = conn.WriteMessage(websocket.TextMessage, []byte(frame))
+		}
+		// Stay connected until the client hangs up
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}))
+	defer server.Close()
+
+	var out bytes.Buffer
+	follower := &logFollower{out: &out, script: "report", pending: make(map[string][]string)}
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+
+	if err := followLogs(ctx, websocket.DefaultDialer, "ws"+strings.TrimPrefix(server.URL, "http"), follower); err != nil {
+		t.Fatalf("Expected a clean stop, got: %v", err)
+	}
+	if !strings.Contains(out.String(), "report completed") {
+		t.Errorf("Expected followed runs, got:\n%s", out.String())
+	}
+
+	if err := followLogs(context.Background(), websocket.DefaultDialer, "ws://127.0.0.1:1/ws", follower); err == nil {
+		t.Error("Expected error when the daemon is not reachable")
+	}
+}
+
+func TestFollowURL(t *testing.T) {
+	settings := service.DefaultSettings(t.TempDir())
+	settings.Port = 9090
+	if got := followURL(settings); got != "ws://localhost:9090/ws" {
+		t.Errorf("Expected ws://localhost:9090/ws, got %s", got)
+	}
+
+	settings.TLS = &service.TLSConfig{}
+	if got := followURL(settings); got != "wss://localhost:9090/ws" {
+		t.Errorf("Expected wss://localhost:9090/ws, got %s", got)
+	}
+
+	settings.BindAddress = "unix:/run/rss.sock"
+	if got := followURL(settings); got != "ws://localhost/ws" {
+		t.Errorf("Expected ws://localhost/ws, got %s", got)
+	}
+}
+
+func TestFollowDialer(t *testing.T) {
+	settings := service.DefaultSettings(t.TempDir())
+	dialer, err := followDialer(settings)
+	if err != nil || dialer.TLSClientConfig != nil || dialer.NetDialContext != nil {
+		t.Errorf("Expected a plain dialer, got %+v, %v", dialer, err)
+	}
+
+	settings.BindAddress = "unix:/run/rss.sock"
+	if dialer, err = followDialer(settings); err != nil || dialer.NetDialContext == nil {
+		t.Errorf("Expected a unix socket dialer, got %v", err)
+	}
+
+	settings.BindAddress = ""
+	settings.TLS = &service.TLSConfig{}
+	certFile, keyFile, _ := settings.TLS.Files(settings.DataDir)
+	if err := service.GenerateSelfSignedCert(certFile, keyFile, nil); err != nil {
+		t.Fatal(err)
+	}
+	if dialer, err = followDialer(settings); err != nil || dialer.TLSClientConfig == nil || dialer.TLSClientConfig.RootCAs == nil {
+		t.Errorf("Expected a dialer trusting the daemon certificate, got %v", err)
+	}
+
+	t.Setenv(clientCertEnv, certFile)
+	t.Setenv(clientKeyEnv, keyFile)
+	if dialer, err = followDialer(settings); err != nil || len(dialer.TLSClientConfig.Certificates) != 1 {
+		t.Errorf("Expected the client certificate to be presented, got %v", err)
+	}
+	t.Setenv(clientKeyEnv, filepath.Join(settings.DataDir, "missing.pem"))
+	if _, err = followDialer(settings); err == nil {
+		t.Error("Expected an error for a missing client key")
+	}
+}
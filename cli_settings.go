@@ -0,0 +1,39 @@
+// Package main provides the run-script-service daemon executable.
+package main
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+
+	"run-script-service/service"
+)
+
+// handleShowEffectiveConfig prints every layered setting with the source it came from
+func handleShowEffectiveConfig(settings *service.Settings) (CommandResult, error) {
+	fmt.Printf("%-13s %-45s %-8s %s\n", "SETTING", "VALUE", "SOURCE", "OVERRIDE")
+	for _, v := range settings.Values() {
+		value := v.Value
+		if value == "" {
+			value = "(all interfaces)"
+		}
+		fmt.Printf("%-13s %-45s %-8s %s / %s\n", v.Key, value, v.Source, v.Env, v.Flag)
+	}
+	return CommandResult{shouldRunService: false}, nil
+}
+
+// webURL returns the address the web interface is reachable at, unix:<path> for a socket
+func webURL(settings *service.Settings) string {
+	if path, ok := service.UnixSocketPath(settings.BindAddress); ok {
+		return "unix:" + path
+	}
+	host := settings.BindAddress
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host = "localhost"
+	}
+	scheme := "http"
+	if settings.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + net.JoinHostPort(host, strconv.Itoa(settings.Port))
+}
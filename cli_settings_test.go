@@ -0,0 +1,38 @@
+// Package main provides tests for layered settings CLI output
+package main
+
+import (
+	"testing"
+
+	"run-script-service/service"
+)
+
+func TestShowEffectiveConfig(t *testing.T) {
+	settings := service.DefaultSettings(t.TempDir())
+	if _, err := handleShowEffectiveConfig(settings); err != nil {
+		t.Errorf("Expected no error, got: %v", err)
+	}
+}
+
+func TestWebURL(t *testing.T) {
+	settings := service.DefaultSettings(t.TempDir())
+	if url := webURL(settings); url != "http://localhost:8080" {
+		t.Errorf("Expected http://localhost:8080, got %s", url)
+	}
+
+	settings.BindAddress = "::1"
+	settings.Port = 9090
+	if url := webURL(settings); url != "http://[::1]:9090" {
+		t.Errorf("Expected http://[::1]:9090, got %s", url)
+	}
+
+	settings.TLS = &service.TLSConfig{SelfSigned: true}
+	if url := webURL(settings); url != "https://[::1]:9090" {
+		t.Errorf("Expected https://[::1]:9090, got %s", url)
+	}
+
+	settings.BindAddress = "unix:/run/rss.sock"
+	if url := webURL(settings); url != "unix:/run/rss.sock" {
+		t.Errorf("Expected unix:/run/rss.sock, got %s", url)
+	}
+}
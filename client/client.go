@@ -0,0 +1,258 @@
+// Package client is a typed Go client for the run-script-service HTTP API.
+// The types mirror the schemas published at /api/v1/openapi.json.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"run-script-service/service"
+)
+
+// Client calls the HTTP API of one run-script-service daemon
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	token      string
+	username   string
+	password   string
+}
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithHTTPClient sends requests with httpClient, e.g. one dialing a unix socket or trusting a private CA
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithToken authenticates requests with an API token sent as a bearer token
+func WithToken(token string) Option {
+	return func(c *Client) {
+		c.token = token
+	}
+}
+
+// WithBasicAuth authenticates requests with a user name and password
+func WithBasicAuth(username, password string) Option {
+	return func(c *Client) {
+		c.username, c.password = username, password
+	}
+}
+
+// New creates a client for the daemon at baseURL, such as http://localhost:8080
+func New(baseURL string, options ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		httpClient: http.DefaultClient,
+	}
+	for _, option := range options {
+		option(c)
+	}
+	return c
+}
+
+// Error is returned when the daemon answers with an error status
+type Error struct {
+	StatusCode int
+	Message    string
+	Code       string                // such as not_found or validation_failed, empty for non-API responses
+	Details    []service.ConfigIssue // the invalid request fields of validation_failed
+	RequestID  string                // the X-Request-ID of the request, for matching server logs
+	RetryAfter time.Duration         // how long to wait before retrying a rate_limited request
+}
+
+// Error implements the error interface
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s (HTTP %d)", e.Message, e.StatusCode)
+}
+
+// IsStatus reports whether err is an Error with the given HTTP status
+func IsStatus(err error, status int) bool {
+	var apiErr *Error
+	return errors.As(err, &apiErr) && apiErr.StatusCode == status
+}
+
+// IsCode reports whether err is an Error with the given error code
+func IsCode(err error, code string) bool {
+	var apiErr *Error
+	return errors.As(err, &apiErr) && apiErr.Code == code
+}
+
+// envelope is the response format of the JSON endpoints
+type envelope struct {
+	Success   bool                  `json:"success"`
+	Data      json.RawMessage       `json:"data,omitempty"`
+	Error     string                `json:"error,omitempty"`
+	Code      string                `json:"code,omitempty"`
+	Details   []service.ConfigIssue `json:"details,omitempty"`
+	RequestID string                `json:"request_id,omitempty"`
+}
+
+// call sends in as a JSON request to path and decodes the data of the response into out,
+// either may be nil
+func (c *Client) call(ctx context.Context, method, path string, query url.Values, in, out interface{}) error {
+	if in == nil {
+		return c.send(ctx, method, path, query, nil, "", out)
+	}
+	data, err := json.Marshal(in)
+	if err != nil {
+		return fmt.Errorf("failed to encode request: %v", err)
+	}
+	return c.send(ctx, method, path, query, bytes.NewReader(data), "application/json", out)
+}
+
+// send sends body to path and decodes the data of the JSON response into out, which may be nil
+func (c *Client) send(ctx context.Context, method, path string, query url.Values, body io.Reader, contentType string, out interface{}) error {
+	data, err := c.do(ctx, method, path, query, body, contentType)
+	if err != nil || out == nil {
+		return err
+	}
+	var response envelope
+	if err := json.Unmarshal(data, &response); err != nil {
+		return fmt.Errorf("failed to decode response of %s %s: %v", method, path, err)
+	}
+	if len(response.Data) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(response.Data, out); err != nil {
+		return fmt.Errorf("failed to decode response of %s %s: %v", method, path, err)
+	}
+	return nil
+}
+
+// do sends a request and returns the body of a successful response
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body io.Reader, contentType string) ([]byte, error) {
+	target := c.baseURL + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	switch {
+	case c.token != "":
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	case c.username != "":
+		req.SetBasicAuth(c.username, c.password)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response of %s %s: %v", method, path, err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		apiErr := &Error{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(data)), RequestID: resp.Header.Get("X-Request-ID")}
+		var response envelope
+		if json.Unmarshal(data, &response) == nil && response.Error != "" {
+			apiErr.Message, apiErr.Code, apiErr.Details = response.Error, response.Code, response.Details
+			if response.RequestID != "" {
+				apiErr.RequestID = response.RequestID
+			}
+		}
+		if apiErr.Message == "" {
+			apiErr.Message = http.StatusText(resp.StatusCode)
+		}
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+			apiErr.RetryAfter = time.Duration(seconds) * time.Second
+		}
+		return nil, apiErr
+	}
+	return data, nil
+}
+
+// escapePath escapes each segment of a slash-separated path
+func escapePath(path string) string {
+	segments := strings.Split(strings.TrimPrefix(path, "/"), "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// Principal is the authenticated caller of a request
+type Principal struct {
+	Name   string `json:"name"`
+	Method string `json:"method"` // token, basic, session or certificate
+	Role   string `json:"role"`
+}
+
+// LoginRequest is the body of POST /api/v1/auth/login
+type LoginRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// Status describes the daemon
+type Status struct {
+	Status         string `json:"status"`
+	Uptime         string `json:"uptime"`
+	RunningScripts int    `json:"runningScripts"`
+	TotalScripts   int    `json:"totalScripts"`
+}
+
+// Status returns the state of the daemon and its script counts
+func (c *Client) Status(ctx context.Context) (*Status, error) {
+	var status Status
+	if err := c.call(ctx, http.MethodGet, "/api/v1/status", nil, nil, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// Login checks a user's password. The session cookie is kept only when the HTTP client has a cookie jar.
+func (c *Client) Login(ctx context.Context, username, password string) (*Principal, error) {
+	var principal Principal
+	if err := c.call(ctx, http.MethodPost, "/api/v1/auth/login", nil, LoginRequest{Username: username, Password: password}, &principal); err != nil {
+		return nil, err
+	}
+	return &principal, nil
+}
+
+// Logout ends the session of the HTTP client's cookie jar, if any
+func (c *Client) Logout(ctx context.Context) error {
+	return c.call(ctx, http.MethodPost, "/api/v1/auth/logout", nil, nil, nil)
+}
+
+// Me returns the authenticated caller, or nil when the daemon does not require authentication
+func (c *Client) Me(ctx context.Context) (*Principal, error) {
+	var principal *Principal
+	if err := c.call(ctx, http.MethodGet, "/api/v1/auth/me", nil, nil, &principal); err != nil {
+		return nil, err
+	}
+	return principal, nil
+}
+
+// OpenAPI returns the OpenAPI document of the daemon
+func (c *Client) OpenAPI(ctx context.Context) (map[string]interface{}, error) {
+	data, err := c.do(ctx, http.MethodGet, "/api/v1/openapi.json", nil, nil, "")
+	if err != nil {
+		return nil, err
+	}
+	var spec map[string]interface{}
+	if err := json.Unmarshal(data, &spec); err != nil {
+		return nil, fmt.Errorf("failed to decode OpenAPI document: %v", err)
+	}
+	return spec, nil
+}
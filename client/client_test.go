@@ -0,0 +1,173 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"run-script-service/service"
+)
+
+// recorded is a request received by a test server
+type recorded struct {
+	method      string
+	path        string // escaped
+	query       string
+	body        string
+	contentType string
+	auth        string
+}
+
+// newTestClient returns a client for a server answering every request with status and body,
+// and the request it received last
+func newTestClient(t *testing.T, status int, body string, options ...Option) (*Client, *recorded) {
+	t.Helper()
+	last := &recorded{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		*last = recorded{
+			method:      r.Method,
+			path:        r.URL.EscapedPath(),
+			query:       r.URL.RawQuery,
+			body:        string(data),
+			contentType: r.Header.Get("Content-Type"),
+			auth:        r.Header.Get("Authorization"),
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		io.WriteString(w, body)
+	}))
+	t.Cleanup(server.Close)
+	return New(server.URL+"/", options...), last
+}
+
+func TestClient_Status(t *testing.T) {
+	c, last := newTestClient(t, http.StatusOK,
+		`{"success":true,"data":{"status":"running","uptime":"1h","runningScripts":2,"totalScripts":3}}`,
+		WithToken("secret"))
+
+	status, err := c.Status(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Status != "running" || status.RunningScripts != 2 || status.TotalScripts != 3 {
+		t.Errorf("Unexpected status %+v", status)
+	}
+	if last.method != "GET" || last.path != "/api/v1/status" || last.auth != "Bearer secret" {
+		t.Errorf("Unexpected request %+v", last)
+	}
+}
+
+func TestClient_BasicAuth(t *testing.T) {
+	c, last := newTestClient(t, http.StatusOK, `{"success":true,"data":null}`, WithBasicAuth("alice", "password1"))
+
+	principal, err := c.Me(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if principal != nil {
+		t.Errorf("Expected no principal when authentication is off, got %+v", principal)
+	}
+	if last.auth != "Basic YWxpY2U6cGFzc3dvcmQx" {
+		t.Errorf("Expected basic credentials, got %q", last.auth)
+	}
+}
+
+func TestClient_Error(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		message string
+		code    string
+	}{
+		{"api error", http.StatusNotFound, `{"success":false,"error":"Script 'x' not found","code":"not_found","request_id":"r1"}`, "Script 'x' not found", "not_found"},
+		{"plain text", http.StatusBadGateway, "upstream down\n", "upstream down", ""},
+		{"empty body", http.StatusServiceUnavailable, "", "Service Unavailable", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := newTestClient(t, tt.status, tt.body)
+			_, err := c.GetScript(context.Background(), "x")
+			apiErr, ok := err.(*Error)
+			if !ok {
+				t.Fatalf("Expected an *Error, got %v", err)
+			}
+			if apiErr.StatusCode != tt.status || apiErr.Message != tt.message || apiErr.Code != tt.code {
+				t.Errorf("Unexpected error %+v", apiErr)
+			}
+			if tt.code != "" && (!IsCode(err, tt.code) || apiErr.RequestID != "r1") {
+				t.Errorf("Expected code %s and request ID r1, got %+v", tt.code, apiErr)
+			}
+			if !IsStatus(err, tt.status) || IsStatus(err, http.StatusOK) {
+				t.Error("Expected IsStatus to match the status only")
+			}
+		})
+	}
+}
+
+func TestClient_ValidationError(t *testing.T) {
+	c, _ := newTestClient(t, http.StatusBadRequest, `{"success":false,"error":"Script name is required","code":"validation_failed",`+
+		`"details":[{"path":"$.name","message":"name is required"}],"request_id":"r2"}`)
+
+	_, err := c.CreateScript(context.Background(), service.ScriptConfig{Path: "./backup.sh"})
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || !IsCode(err, "validation_failed") {
+		t.Fatalf("Expected a validation error, got %v", err)
+	}
+	if len(apiErr.Details) != 1 || apiErr.Details[0].Path != "$.name" {
+		t.Errorf("Expected the name field in the details, got %+v", apiErr.Details)
+	}
+}
+
+func TestClient_RateLimited(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "12")
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusTooManyRequests)
+		io.WriteString(w, `{"success":false,"error":"Too many run requests","code":"rate_limited","request_id":"r3"}`)
+	}))
+	defer server.Close()
+
+	_, err := New(server.URL).Status(context.Background())
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || !IsCode(err, "rate_limited") {
+		t.Fatalf("Expected a rate_limited error, got %v", err)
+	}
+	if apiErr.RetryAfter != 12*time.Second {
+		t.Errorf("Expected Retry-After of 12s, got %s", apiErr.RetryAfter)
+	}
+}
+
+func TestClient_Login(t *testing.T) {
+	c, last := newTestClient(t, http.StatusOK, `{"success":true,"data":{"name":"alice","method":"session","role":""}}`)
+
+	principal, err := c.Login(context.Background(), "alice", "password1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if principal.Name != "alice" || principal.Method != "session" {
+		t.Errorf("Unexpected principal %+v", principal)
+	}
+	if last.body != `{"username":"alice","password":"password1"}` || last.contentType != "application/json" {
+		t.Errorf("Unexpected request %+v", last)
+	}
+}
+
+func TestEscapePath(t *testing.T) {
+	tests := map[string]string{
+		"backup":              "backup",
+		"nightly backup":      "nightly%20backup",
+		"scripts/a b.sh":      "scripts/a%20b.sh",
+		"/scripts/report?.sh": "scripts/report%3F.sh",
+	}
+	for path, expected := range tests {
+		if got := escapePath(path); got != expected {
+			t.Errorf("escapePath(%q) = %q, expected %q", path, got, expected)
+		}
+	}
+}
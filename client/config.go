@@ -0,0 +1,167 @@
+// Package client is a typed Go client for the run-script-service HTTP API.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"run-script-service/service"
+)
+
+// Config holds the settings shown by the web interface. Only WebPort comes from the
+// configuration file, the other fields are fixed display defaults.
+type Config struct {
+	WebPort      int    `json:"webPort"`
+	Interval     string `json:"interval"`
+	LogRetention int    `json:"logRetention"`
+	AutoRefresh  bool   `json:"autoRefresh"`
+}
+
+// ConfigUpdateRequest is the body of PUT /api/v1/config
+type ConfigUpdateRequest struct {
+	WebPort int `json:"webPort"`
+}
+
+// ConfigUpdate is the reply of PUT /api/v1/config
+type ConfigUpdate struct {
+	Message string                `json:"message"`
+	Config  service.ServiceConfig `json:"config"`
+}
+
+// ConfigValidation is the result of validating a configuration document
+type ConfigValidation struct {
+	Valid  bool                  `json:"valid"`
+	Issues []service.ConfigIssue `json:"issues"`
+}
+
+// ConfigVersionContent is a recorded configuration version and its content
+type ConfigVersionContent struct {
+	Version service.ConfigVersion `json:"version"`
+	Content string                `json:"content"`
+}
+
+// ConfigDiff is a line diff between two recorded versions
+type ConfigDiff struct {
+	From    int                `json:"from"`
+	To      int                `json:"to"`
+	Changed bool               `json:"changed"`
+	Lines   []service.DiffLine `json:"lines"`
+	Unified string             `json:"unified"`
+}
+
+// ConfigRollback is the reply of restoring a recorded version
+type ConfigRollback struct {
+	Message  string                `json:"message"`
+	Restored service.ConfigVersion `json:"restored"`
+}
+
+// Config returns the settings shown by the web interface
+func (c *Client) Config(ctx context.Context) (*Config, error) {
+	var config Config
+	if err := c.call(ctx, http.MethodGet, "/api/v1/config", nil, nil, &config); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+// SetWebPort changes the port of the web interface in the configuration file
+func (c *Client) SetWebPort(ctx context.Context, port int) (*ConfigUpdate, error) {
+	var update ConfigUpdate
+	if err := c.call(ctx, http.MethodPut, "/api/v1/config", nil, ConfigUpdateRequest{WebPort: port}, &update); err != nil {
+		return nil, err
+	}
+	return &update, nil
+}
+
+// ValidateConfig validates a configuration document, or the daemon's config file when content is empty
+func (c *Client) ValidateConfig(ctx context.Context, content []byte, checkFiles bool) (*ConfigValidation, error) {
+	query := url.Values{"check_files": {strconv.FormatBool(checkFiles)}}
+	var validation ConfigValidation
+	if err := c.send(ctx, http.MethodPost, "/api/v1/config/validate", query, bytes.NewReader(content), "application/json", &validation); err != nil {
+		return nil, err
+	}
+	return &validation, nil
+}
+
+// ConfigSchema returns the JSON Schema of the configuration file
+func (c *Client) ConfigSchema(ctx context.Context) (map[string]interface{}, error) {
+	data, err := c.do(ctx, http.MethodGet, "/api/v1/config/schema", nil, nil, "")
+	if err != nil {
+		return nil, err
+	}
+	var schema map[string]interface{}
+	if err := json.Unmarshal(data, &schema); err != nil {
+		return nil, fmt.Errorf("failed to decode configuration schema: %v", err)
+	}
+	return schema, nil
+}
+
+// ConfigHistory lists the recorded configuration versions, oldest first
+func (c *Client) ConfigHistory(ctx context.Context) ([]service.ConfigVersion, error) {
+	versions := []service.ConfigVersion{}
+	if err := c.call(ctx, http.MethodGet, "/api/v1/config/history", nil, nil, &versions); err != nil {
+		return nil, err
+	}
+	return versions, nil
+}
+
+// ConfigVersion returns a recorded configuration version
+func (c *Client) ConfigVersion(ctx context.Context, version int) (*ConfigVersionContent, error) {
+	var content ConfigVersionContent
+	if err := c.call(ctx, http.MethodGet, "/api/v1/config/history/"+strconv.Itoa(version), nil, nil, &content); err != nil {
+		return nil, err
+	}
+	return &content, nil
+}
+
+// DiffConfigVersions returns a line diff between two recorded versions
+func (c *Client) DiffConfigVersions(ctx context.Context, from, to int) (*ConfigDiff, error) {
+	query := url.Values{"from": {strconv.Itoa(from)}, "to": {strconv.Itoa(to)}}
+	var diff ConfigDiff
+	if err := c.call(ctx, http.MethodGet, "/api/v1/config/history/diff", query, nil, &diff); err != nil {
+		return nil, err
+	}
+	return &diff, nil
+}
+
+// RollbackConfig restores a recorded version and reloads it into the daemon
+func (c *Client) RollbackConfig(ctx context.Context, version int) (*ConfigRollback, error) {
+	var rollback ConfigRollback
+	path := "/api/v1/config/history/" + strconv.Itoa(version) + "/rollback"
+	if err := c.call(ctx, http.MethodPost, path, nil, nil, &rollback); err != nil {
+		return nil, err
+	}
+	return &rollback, nil
+}
+
+// Export returns a bundle of the named scripts, or of all scripts when none are named,
+// in the json or tar.gz format
+func (c *Client) Export(ctx context.Context, format string, scripts ...string) ([]byte, error) {
+	query := url.Values{"format": {format}}
+	if len(scripts) > 0 {
+		query.Set("scripts", strings.Join(scripts, ","))
+	}
+	return c.do(ctx, http.MethodGet, "/api/v1/export", query, nil, "")
+}
+
+// Import adds the scripts of a bundle, resolving name conflicts with skip, rename or overwrite
+func (c *Client) Import(ctx context.Context, bundle []byte, conflict string, dryRun bool) (*service.ImportResult, error) {
+	query := url.Values{}
+	if conflict != "" {
+		query.Set("conflict", conflict)
+	}
+	if dryRun {
+		query.Set("dry_run", "true")
+	}
+	var result service.ImportResult
+	if err := c.send(ctx, http.MethodPost, "/api/v1/import", query, bytes.NewReader(bundle), "application/octet-stream", &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
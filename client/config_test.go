@@ -0,0 +1,84 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+func TestClient_SetWebPort(t *testing.T) {
+	c, last := newTestClient(t, http.StatusOK,
+		`{"success":true,"data":{"message":"Configuration updated","config":{"scripts":[],"web_port":9090}}}`)
+
+	update, err := c.SetWebPort(context.Background(), 9090)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if update.Config.WebPort != 9090 {
+		t.Errorf("Unexpected update %+v", update)
+	}
+	if last.method != "PUT" || last.path != "/api/v1/config" || last.body != `{"webPort":9090}` {
+		t.Errorf("Unexpected request %+v", last)
+	}
+}
+
+func TestClient_ValidateConfig(t *testing.T) {
+	c, last := newTestClient(t, http.StatusOK,
+		`{"success":true,"data":{"valid":false,"issues":[{"path":"scripts[0].interval","message":"must be positive"}]}}`)
+
+	validation, err := c.ValidateConfig(context.Background(), []byte(`{"scripts":[]}`), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if validation.Valid || len(validation.Issues) != 1 || validation.Issues[0].Path != "scripts[0].interval" {
+		t.Errorf("Unexpected validation %+v", validation)
+	}
+	if last.query != "check_files=true" || last.body != `{"scripts":[]}` || last.contentType != "application/json" {
+		t.Errorf("Unexpected request %+v", last)
+	}
+}
+
+func TestClient_DiffConfigVersions(t *testing.T) {
+	c, last := newTestClient(t, http.StatusOK,
+		`{"success":true,"data":{"from":1,"to":2,"changed":true,"lines":[],"unified":"-a\n+b\n"}}`)
+
+	diff, err := c.DiffConfigVersions(context.Background(), 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !diff.Changed || diff.Unified != "-a\n+b\n" {
+		t.Errorf("Unexpected diff %+v", diff)
+	}
+	if last.path != "/api/v1/config/history/diff" || last.query != "from=1&to=2" {
+		t.Errorf("Unexpected request %s?%s", last.path, last.query)
+	}
+}
+
+func TestClient_Import(t *testing.T) {
+	c, last := newTestClient(t, http.StatusOK, `{"success":true,"data":{"dry_run":true}}`)
+
+	if _, err := c.Import(context.Background(), []byte("bundle"), "rename", true); err != nil {
+		t.Fatal(err)
+	}
+	if last.method != "POST" || last.query != "conflict=rename&dry_run=true" || last.body != "bundle" {
+		t.Errorf("Unexpected request %+v", last)
+	}
+	if last.contentType != "application/octet-stream" {
+		t.Errorf("Expected a raw bundle, got %s", last.contentType)
+	}
+}
+
+func TestClient_Export(t *testing.T) {
+	c, last := newTestClient(t, http.StatusOK, `{"version":1,"scripts":[]}`)
+
+	data, err := c.Export(context.Background(), "json", "backup", "report")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"version":1,"scripts":[]}` {
+		t.Errorf("Expected the raw bundle, got %s", data)
+	}
+	if last.query != "format=json&scripts=backup%2Creport" {
+		t.Errorf("Unexpected query %s", last.query)
+	}
+}
@@ -0,0 +1,78 @@
+// Package client is a typed Go client for the run-script-service HTTP API.
+package client
+
+import (
+	"context"
+	"net/http"
+
+	"run-script-service/service"
+)
+
+// FileWriteRequest is the body of PUT /api/v1/files/{path}
+type FileWriteRequest struct {
+	Path    string `json:"path,omitempty"`
+	Content string `json:"content,omitempty"`
+}
+
+// FileWrite is the reply of writing a file
+type FileWrite struct {
+	Message string `json:"message"`
+	Path    string `json:"path"`
+}
+
+// ScriptValidationRequest is the body of POST /api/v1/files/validate
+type ScriptValidationRequest struct {
+	Content string `json:"content"`
+}
+
+// ScriptValidation is the result of checking a script for problems
+type ScriptValidation struct {
+	Valid  bool     `json:"valid"`
+	Issues []string `json:"issues,omitempty"`
+}
+
+// FileInfo describes one entry of a directory listing
+type FileInfo struct {
+	Name    string `json:"name"`
+	Size    int64  `json:"size"`
+	Mode    string `json:"mode"`
+	IsDir   bool   `json:"is_dir"`
+	ModTime int64  `json:"mod_time"` // Unix seconds
+}
+
+// ReadFile returns a file in the daemon's directory
+func (c *Client) ReadFile(ctx context.Context, path string) (*service.FileContent, error) {
+	var content service.FileContent
+	if err := c.call(ctx, http.MethodGet, "/api/v1/files/"+escapePath(path), nil, nil, &content); err != nil {
+		return nil, err
+	}
+	return &content, nil
+}
+
+// WriteFile replaces the content of a file in the daemon's directory
+func (c *Client) WriteFile(ctx context.Context, path, content string) (*FileWrite, error) {
+	var write FileWrite
+	request := FileWriteRequest{Path: path, Content: content}
+	if err := c.call(ctx, http.MethodPut, "/api/v1/files/"+escapePath(path), nil, request, &write); err != nil {
+		return nil, err
+	}
+	return &write, nil
+}
+
+// ValidateScript checks shell script content for unmatched quotes and dangerous commands
+func (c *Client) ValidateScript(ctx context.Context, content string) (*ScriptValidation, error) {
+	var validation ScriptValidation
+	if err := c.call(ctx, http.MethodPost, "/api/v1/files/validate", nil, ScriptValidationRequest{Content: content}, &validation); err != nil {
+		return nil, err
+	}
+	return &validation, nil
+}
+
+// ListFiles lists a directory in the daemon's directory
+func (c *Client) ListFiles(ctx context.Context, dir string) ([]FileInfo, error) {
+	files := []FileInfo{}
+	if err := c.call(ctx, http.MethodGet, "/api/v1/files-list/"+escapePath(dir), nil, nil, &files); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
@@ -0,0 +1,53 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+func TestClient_ReadFile(t *testing.T) {
+	c, last := newTestClient(t, http.StatusOK, `{"success":true,"data":{"path":"scripts/a b.sh","content":"echo hi\n"}}`)
+
+	file, err := c.ReadFile(context.Background(), "scripts/a b.sh")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if file.Content != "echo hi\n" {
+		t.Errorf("Unexpected file %+v", file)
+	}
+	if last.path != "/api/v1/files/scripts/a%20b.sh" {
+		t.Errorf("Unexpected path %s", last.path)
+	}
+}
+
+func TestClient_WriteFile(t *testing.T) {
+	c, last := newTestClient(t, http.StatusOK, `{"success":true,"data":{"message":"File saved","path":"run.sh"}}`)
+
+	write, err := c.WriteFile(context.Background(), "run.sh", "echo hi\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if write.Path != "run.sh" {
+		t.Errorf("Unexpected reply %+v", write)
+	}
+	if last.method != "PUT" || last.body != `{"path":"run.sh","content":"echo hi\n"}` {
+		t.Errorf("Unexpected request %+v", last)
+	}
+}
+
+func TestClient_ListFiles(t *testing.T) {
+	c, last := newTestClient(t, http.StatusOK,
+		`{"success":true,"data":[{"name":"run.sh","size":8,"mode":"-rwxr-xr-x","is_dir":false,"mod_time":1700000000}]}`)
+
+	files, err := c.ListFiles(context.Background(), "scripts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files[0].Name != "run.sh" || files[0].ModTime != 1700000000 {
+		t.Errorf("Unexpected files %+v", files)
+	}
+	if last.path != "/api/v1/files-list/scripts" {
+		t.Errorf("Unexpected path %s", last.path)
+	}
+}
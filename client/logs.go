@@ -0,0 +1,194 @@
+// Package client is a typed Go client for the run-script-service HTTP API.
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"run-script-service/service"
+)
+
+// LogEntry is one recorded run
+type LogEntry struct {
+	Timestamp string `json:"timestamp"` // RFC 3339
+	Message   string `json:"message"`
+	Level     string `json:"level"` // info, warning or error
+	Script    string `json:"script,omitempty"`
+	ExitCode  int    `json:"exit_code"`
+	Duration  int64  `json:"duration_ms"`
+	Stdout    string `json:"stdout,omitempty"`
+	Stderr    string `json:"stderr,omitempty"`
+	Trigger   string `json:"trigger,omitempty"`
+	RunID     string `json:"run_id,omitempty"`
+
+	Result        map[string]interface{} `json:"result,omitempty"`
+	Outcome       string                 `json:"outcome,omitempty"`
+	OutcomeReason string                 `json:"outcome_reason,omitempty"`
+	Artifacts     int                    `json:"artifacts,omitempty"`
+}
+
+// LogSearchResult is one page of search results, newest run first
+type LogSearchResult struct {
+	Entries    []LogEntry `json:"entries"`
+	NextCursor string     `json:"next_cursor,omitempty"`
+}
+
+// ScriptLog is the raw log file of a script
+type ScriptLog struct {
+	Script  string `json:"script"`
+	Content string `json:"content"`
+	Message string `json:"message,omitempty"` // set when there is no log file
+}
+
+// LogSearch selects runs for SearchLogs and ExportLogs; zero fields match everything
+type LogSearch struct {
+	Text        string // substring of the output
+	Regex       string
+	IgnoreCase  bool
+	Stream      string // stdout or stderr
+	Script      string
+	ExitCode    *int
+	Trigger     string
+	MinDuration int64  // milliseconds
+	MaxDuration int64  // milliseconds
+	Since       string // RFC 3339 time, date (2006-01-02) or age such as 12h or 7d
+	Until       string
+	Limit       int
+	Cursor      string // NextCursor of the previous page
+}
+
+// values returns the query parameters of the search
+func (s *LogSearch) values() url.Values {
+	values := url.Values{}
+	if s == nil {
+		return values
+	}
+	set := func(name, value string) {
+		if value != "" {
+			values.Set(name, value)
+		}
+	}
+	set("q", s.Text)
+	set("regex", s.Regex)
+	set("stream", s.Stream)
+	set("script", s.Script)
+	set("trigger", s.Trigger)
+	set("since", s.Since)
+	set("until", s.Until)
+	set("cursor", s.Cursor)
+	if s.IgnoreCase {
+		values.Set("ignore_case", "true")
+	}
+	if s.ExitCode != nil {
+		values.Set("exit_code", strconv.Itoa(*s.ExitCode))
+	}
+	if s.MinDuration > 0 {
+		values.Set("min_duration", strconv.FormatInt(s.MinDuration, 10))
+	}
+	if s.MaxDuration > 0 {
+		values.Set("max_duration", strconv.FormatInt(s.MaxDuration, 10))
+	}
+	if s.Limit > 0 {
+		values.Set("limit", strconv.Itoa(s.Limit))
+	}
+	return values
+}
+
+// AuditQuery selects audit entries; zero fields match everything
+type AuditQuery struct {
+	Actor  string
+	Action string // an action, or a prefix such as "script."
+	Target string
+	Since  string
+	Until  string
+	Limit  int // the daemon returns 100 entries when zero
+}
+
+// Logs returns the most recent runs of script, or of all scripts when script is empty, oldest first
+func (c *Client) Logs(ctx context.Context, script string, limit int) ([]LogEntry, error) {
+	query := url.Values{}
+	if script != "" {
+		query.Set("script", script)
+	}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	entries := []LogEntry{}
+	if err := c.call(ctx, http.MethodGet, "/api/v1/logs", query, nil, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// SearchLogs searches run output across the whole log history
+func (c *Client) SearchLogs(ctx context.Context, search *LogSearch) (*LogSearchResult, error) {
+	var result LogSearchResult
+	if err := c.call(ctx, http.MethodGet, "/api/v1/logs/search", search.values(), nil, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// ExportLogs returns the matching runs as csv, ndjson or junit
+func (c *Client) ExportLogs(ctx context.Context, format string, search *LogSearch) ([]byte, error) {
+	query := search.values()
+	query.Set("format", format)
+	return c.do(ctx, http.MethodGet, "/api/v1/logs/export", query, nil, "")
+}
+
+// ScriptLog returns the raw log file of a script
+func (c *Client) ScriptLog(ctx context.Context, script string) (*ScriptLog, error) {
+	var log ScriptLog
+	if err := c.call(ctx, http.MethodGet, "/api/v1/logs/"+escapePath(script), nil, nil, &log); err != nil {
+		return nil, err
+	}
+	return &log, nil
+}
+
+// ClearLogs removes the recorded runs of a script
+func (c *Client) ClearLogs(ctx context.Context, script string) (*ScriptAction, error) {
+	return c.scriptAction(ctx, http.MethodDelete, "/api/v1/logs/"+escapePath(script))
+}
+
+// Metrics returns the run metrics in the Prometheus text format
+func (c *Client) Metrics(ctx context.Context) (string, error) {
+	data, err := c.do(ctx, http.MethodGet, "/api/v1/metrics", nil, nil, "")
+	return string(data), err
+}
+
+// Audit returns the matching audit entries, newest first
+func (c *Client) Audit(ctx context.Context, query AuditQuery) ([]service.AuditEntry, error) {
+	values := url.Values{}
+	for name, value := range map[string]string{
+		"actor": query.Actor, "action": query.Action, "target": query.Target,
+		"since": query.Since, "until": query.Until,
+	} {
+		if value != "" {
+			values.Set(name, value)
+		}
+	}
+	if query.Limit > 0 {
+		values.Set("limit", strconv.Itoa(query.Limit))
+	}
+	entries := []service.AuditEntry{}
+	if err := c.call(ctx, http.MethodGet, "/api/v1/audit", values, nil, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// Artifacts returns the manifest of the files kept with a run
+func (c *Client) Artifacts(ctx context.Context, runID string) (*service.ArtifactManifest, error) {
+	var manifest service.ArtifactManifest
+	if err := c.call(ctx, http.MethodGet, "/api/v1/runs/"+escapePath(runID)+"/artifacts", nil, nil, &manifest); err != nil {
+		return nil, err
+	}
+	return &manifest, nil
+}
+
+// DownloadArtifact returns the content of one artifact of a run
+func (c *Client) DownloadArtifact(ctx context.Context, runID, path string) ([]byte, error) {
+	return c.do(ctx, http.MethodGet, "/api/v1/runs/"+escapePath(runID)+"/artifacts/"+escapePath(path), nil, nil, "")
+}
@@ -0,0 +1,97 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestLogSearch_Values(t *testing.T) {
+	exitCode := 0
+	search := &LogSearch{
+		Text:        "error",
+		IgnoreCase:  true,
+		Stream:      "stderr",
+		ExitCode:    &exitCode,
+		MinDuration: 500,
+		Since:       "7d",
+		Limit:       20,
+	}
+
+	expected := url.Values{
+		"q":            {"error"},
+		"ignore_case":  {"true"},
+		"stream":       {"stderr"},
+		"exit_code":    {"0"},
+		"min_duration": {"500"},
+		"since":        {"7d"},
+		"limit":        {"20"},
+	}
+	if got := search.values(); got.Encode() != expected.Encode() {
+		t.Errorf("Expected %s, got %s", expected.Encode(), got.Encode())
+	}
+
+	var empty *LogSearch
+	if got := empty.values(); len(got) != 0 {
+		t.Errorf("Expected no parameters for a nil search, got %v", got)
+	}
+}
+
+func TestClient_Logs(t *testing.T) {
+	c, last := newTestClient(t, http.StatusOK, `{"success":true,"data":[`+
+		`{"timestamp":"2026-01-02T03:04:05Z","script":"backup","exit_code":1,"stdout":"","stderr":"boom","duration":12}]}`)
+
+	entries, err := c.Logs(context.Background(), "backup", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Script != "backup" || entries[0].ExitCode != 1 || entries[0].Stderr != "boom" {
+		t.Errorf("Unexpected entries %+v", entries)
+	}
+	if last.path != "/api/v1/logs" || last.query != "limit=5&script=backup" {
+		t.Errorf("Unexpected request %s?%s", last.path, last.query)
+	}
+}
+
+func TestClient_ExportLogs(t *testing.T) {
+	c, last := newTestClient(t, http.StatusOK, "script,exit_code\nbackup,0\n")
+
+	data, err := c.ExportLogs(context.Background(), "csv", &LogSearch{Script: "backup"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "script,exit_code\nbackup,0\n" {
+		t.Errorf("Expected the raw export, got %q", data)
+	}
+	if last.path != "/api/v1/logs/export" || last.query != "format=csv&script=backup" {
+		t.Errorf("Unexpected request %s?%s", last.path, last.query)
+	}
+}
+
+func TestClient_Audit(t *testing.T) {
+	c, last := newTestClient(t, http.StatusOK, `{"success":true,"data":[{"actor":"alice","action":"script.create","target":"backup"}]}`)
+
+	entries, err := c.Audit(context.Background(), AuditQuery{Actor: "alice", Action: "script.", Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Actor != "alice" || entries[0].Target != "backup" {
+		t.Errorf("Unexpected entries %+v", entries)
+	}
+	if last.path != "/api/v1/audit" || last.query != "action=script.&actor=alice&limit=10" {
+		t.Errorf("Unexpected request %s?%s", last.path, last.query)
+	}
+}
+
+func TestClient_DownloadArtifact(t *testing.T) {
+	c, last := newTestClient(t, http.StatusOK, "report")
+
+	data, err := c.DownloadArtifact(context.Background(), "run-1", "out/report.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "report" || last.path != "/api/v1/runs/run-1/artifacts/out/report.txt" {
+		t.Errorf("Unexpected download %q from %s", data, last.path)
+	}
+}
@@ -0,0 +1,45 @@
+// Package client is a typed Go client for the run-script-service HTTP API.
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+
+	"run-script-service/service"
+)
+
+// RunScript queues a run of a script. With a positive wait it waits up to that long (at
+// most 5 minutes) for the run to finish; a failed run is returned as an Error with the
+// script_failed code. The run is returned in whatever state it reached.
+func (c *Client) RunScript(ctx context.Context, name string, wait time.Duration) (*service.RunStatus, error) {
+	var query url.Values
+	if wait > 0 {
+		query = url.Values{"wait": {wait.String()}}
+	}
+	var run service.RunStatus
+	if err := c.call(ctx, http.MethodPost, scriptPath(name, "run"), query, nil, &run); err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
+
+// Run returns the state of a manual run by its ID
+func (c *Client) Run(ctx context.Context, id string) (*service.RunStatus, error) {
+	var run service.RunStatus
+	if err := c.call(ctx, http.MethodGet, "/api/v1/runs/"+escapePath(id), nil, nil, &run); err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
+
+// Queue returns the manual runs in flight and waiting for a worker, the run limits and the
+// runs of all scripts waiting for a slot
+func (c *Client) Queue(ctx context.Context) (*service.QueueStatus, error) {
+	var queue service.QueueStatus
+	if err := c.call(ctx, http.MethodGet, "/api/v1/queue", nil, nil, &queue); err != nil {
+		return nil, err
+	}
+	return &queue, nil
+}
@@ -0,0 +1,63 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestClient_RunScript(t *testing.T) {
+	c, last := newTestClient(t, http.StatusAccepted,
+		`{"success":true,"data":{"id":"0123456789abcdef","script":"backup","state":"queued","position":2,"queued_at":"2026-10-18T10:00:00Z"}}`)
+
+	run, err := c.RunScript(context.Background(), "nightly backup", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run.ID != "0123456789abcdef" || run.State != "queued" || run.Position != 2 {
+		t.Errorf("Unexpected run %+v", run)
+	}
+	if last.method != "POST" || last.path != "/api/v1/scripts/nightly%20backup/run" || last.query != "" {
+		t.Errorf("Unexpected request %+v", last)
+	}
+
+	c, last = newTestClient(t, http.StatusOK, `{"success":true,"data":{"id":"0123456789abcdef","script":"backup","state":"succeeded"}}`)
+	if run, err = c.RunScript(context.Background(), "backup", 30*time.Second); err != nil || run.State != "succeeded" {
+		t.Errorf("Expected a finished run, got %+v %v", run, err)
+	}
+	if last.query != "wait=30s" {
+		t.Errorf("Expected the wait parameter, got %q", last.query)
+	}
+
+	c, _ = newTestClient(t, http.StatusUnprocessableEntity, `{"success":false,"error":"Run 0123456789abcdef of script backup failed","code":"script_failed"}`)
+	if _, err = c.RunScript(context.Background(), "backup", time.Minute); !IsCode(err, "script_failed") {
+		t.Errorf("Expected a script_failed error, got %v", err)
+	}
+}
+
+func TestClient_RunAndQueue(t *testing.T) {
+	c, last := newTestClient(t, http.StatusOK, `{"success":true,"data":{"id":"0123456789abcdef","script":"backup","state":"failed","error":"script exited with code 1"}}`)
+	run, err := c.Run(context.Background(), "0123456789abcdef")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run.State != "failed" || run.Error != "script exited with code 1" || last.path != "/api/v1/runs/0123456789abcdef" {
+		t.Errorf("Unexpected run %+v from %s", run, last.path)
+	}
+
+	c, last = newTestClient(t, http.StatusOK, `{"success":true,"data":{"workers":4,"capacity":100,"depth":1,`+
+		`"running":[{"id":"a","script":"backup","state":"running"}],"pending":[{"id":"b","script":"report","state":"queued","position":1}],`+
+		`"max_concurrent_runs":2,"active":2,"pools":[{"name":"db","size":1,"in_use":1}],`+
+		`"waiting":[{"run_id":"c","script":"sync","trigger":"schedule","priority":5,"pools":["db"],"position":1}]}}`)
+	queue, err := c.Queue(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if queue.Depth != 1 || len(queue.Running) != 1 || queue.Pending[0].Position != 1 || last.path != "/api/v1/queue" {
+		t.Errorf("Unexpected queue %+v from %s", queue, last.path)
+	}
+	if queue.MaxConcurrentRuns != 2 || queue.Pools[0].InUse != 1 || len(queue.Waiting) != 1 || queue.Waiting[0].Priority != 5 {
+		t.Errorf("Expected the run limits and waiting runs, got %+v", queue)
+	}
+}
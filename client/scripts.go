@@ -0,0 +1,126 @@
+// Package client is a typed Go client for the run-script-service HTTP API.
+package client
+
+import (
+	"context"
+	"net/http"
+
+	"run-script-service/service"
+)
+
+// Script is a configured script and whether it is scheduled
+type Script struct {
+	Name        string   `json:"name"`
+	Path        string   `json:"path"`
+	Interval    int      `json:"interval"` // seconds between runs
+	Enabled     bool     `json:"enabled"`
+	MaxLogLines int      `json:"max_log_lines"`
+	Timeout     int      `json:"timeout"` // seconds, 0 means no limit
+	Tags        []string `json:"tags,omitempty"`
+	Pools       []string `json:"pools,omitempty"`    // resource pools a run holds a slot of
+	Priority    int      `json:"priority,omitempty"` // runs waiting for a slot start highest priority first
+	Running     bool     `json:"running"`
+}
+
+// ScriptUpdate is the reply of PUT /api/v1/scripts/{name}
+type ScriptUpdate struct {
+	Message     string `json:"message"`
+	Script      string `json:"script"`
+	Name        string `json:"name"`
+	Path        string `json:"path"`
+	Interval    int    `json:"interval"`
+	Enabled     bool   `json:"enabled"`
+	MaxLogLines int    `json:"max_log_lines"`
+	Timeout     int    `json:"timeout"`
+}
+
+// ScriptAction is the reply of deleting a script or clearing its logs
+type ScriptAction struct {
+	Message string `json:"message"`
+	Script  string `json:"script"`
+}
+
+// ScriptToggle is the reply of enabling or disabling a script
+type ScriptToggle struct {
+	Message string `json:"message"`
+	Script  string `json:"script"`
+	Enabled bool   `json:"enabled"`
+}
+
+// scriptPath returns the API path of a script, with an optional action
+func scriptPath(name, action string) string {
+	path := "/api/v1/scripts/" + escapePath(name)
+	if action != "" {
+		path += "/" + action
+	}
+	return path
+}
+
+// ListScripts returns the configured scripts
+func (c *Client) ListScripts(ctx context.Context) ([]Script, error) {
+	scripts := []Script{}
+	if err := c.call(ctx, http.MethodGet, "/api/v1/scripts", nil, nil, &scripts); err != nil {
+		return nil, err
+	}
+	return scripts, nil
+}
+
+// GetScript returns one script
+func (c *Client) GetScript(ctx context.Context, name string) (*Script, error) {
+	var script Script
+	if err := c.call(ctx, http.MethodGet, scriptPath(name, ""), nil, nil, &script); err != nil {
+		return nil, err
+	}
+	return &script, nil
+}
+
+// CreateScript adds a script, the daemon fills in the default interval and log limit
+func (c *Client) CreateScript(ctx context.Context, config service.ScriptConfig) (*Script, error) {
+	var script Script
+	if err := c.call(ctx, http.MethodPost, "/api/v1/scripts", nil, config, &script); err != nil {
+		return nil, err
+	}
+	return &script, nil
+}
+
+// UpdateScript replaces the configuration of the script name
+func (c *Client) UpdateScript(ctx context.Context, name string, config service.ScriptConfig) (*ScriptUpdate, error) {
+	var update ScriptUpdate
+	if err := c.call(ctx, http.MethodPut, scriptPath(name, ""), nil, config, &update); err != nil {
+		return nil, err
+	}
+	return &update, nil
+}
+
+// DeleteScript removes a script
+func (c *Client) DeleteScript(ctx context.Context, name string) (*ScriptAction, error) {
+	return c.scriptAction(ctx, http.MethodDelete, scriptPath(name, ""))
+}
+
+// EnableScript enables a script and starts scheduling it
+func (c *Client) EnableScript(ctx context.Context, name string) (*ScriptToggle, error) {
+	return c.scriptToggle(ctx, scriptPath(name, "enable"))
+}
+
+// DisableScript disables a script and stops scheduling it
+func (c *Client) DisableScript(ctx context.Context, name string) (*ScriptToggle, error) {
+	return c.scriptToggle(ctx, scriptPath(name, "disable"))
+}
+
+// scriptAction sends a request answered with a ScriptAction
+func (c *Client) scriptAction(ctx context.Context, method, path string) (*ScriptAction, error) {
+	var action ScriptAction
+	if err := c.call(ctx, method, path, nil, nil, &action); err != nil {
+		return nil, err
+	}
+	return &action, nil
+}
+
+// scriptToggle sends a request answered with a ScriptToggle
+func (c *Client) scriptToggle(ctx context.Context, path string) (*ScriptToggle, error) {
+	var toggle ScriptToggle
+	if err := c.call(ctx, http.MethodPost, path, nil, nil, &toggle); err != nil {
+		return nil, err
+	}
+	return &toggle, nil
+}
@@ -0,0 +1,90 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"run-script-service/service"
+)
+
+func TestClient_ListScripts(t *testing.T) {
+	c, last := newTestClient(t, http.StatusOK, `{"success":true,"data":[`+
+		`{"name":"backup","path":"./backup.sh","interval":3600,"enabled":true,"max_log_lines":100,"timeout":0,"tags":["nightly"],"running":true}]}`)
+
+	scripts, err := c.ListScripts(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(scripts) != 1 || scripts[0].Name != "backup" || !scripts[0].Running || scripts[0].Tags[0] != "nightly" {
+		t.Errorf("Unexpected scripts %+v", scripts)
+	}
+	if last.method != "GET" || last.path != "/api/v1/scripts" {
+		t.Errorf("Unexpected request %+v", last)
+	}
+
+	// A daemon without scripts answers with null
+	c, _ = newTestClient(t, http.StatusOK, `{"success":true,"data":null}`)
+	scripts, err = c.ListScripts(context.Background())
+	if err != nil || len(scripts) != 0 {
+		t.Errorf("Expected an empty list, got %v %v", scripts, err)
+	}
+}
+
+func TestClient_ScriptRequests(t *testing.T) {
+	tests := []struct {
+		name   string
+		call   func(c *Client) error
+		method string
+		path   string
+		body   string
+	}{
+		{"create", func(c *Client) error {
+			_, err := c.CreateScript(context.Background(), service.ScriptConfig{Name: "backup", Path: "./backup.sh"})
+			return err
+		}, "POST", "/api/v1/scripts", `"name":"backup"`},
+		{"update", func(c *Client) error {
+			_, err := c.UpdateScript(context.Background(), "nightly backup", service.ScriptConfig{Path: "./backup.sh", Interval: 60})
+			return err
+		}, "PUT", "/api/v1/scripts/nightly%20backup", `"interval":60`},
+		{"delete", func(c *Client) error {
+			_, err := c.DeleteScript(context.Background(), "backup")
+			return err
+		}, "DELETE", "/api/v1/scripts/backup", ""},
+		{"enable", func(c *Client) error {
+			_, err := c.EnableScript(context.Background(), "backup")
+			return err
+		}, "POST", "/api/v1/scripts/backup/enable", ""},
+		{"disable", func(c *Client) error {
+			_, err := c.DisableScript(context.Background(), "backup")
+			return err
+		}, "POST", "/api/v1/scripts/backup/disable", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, last := newTestClient(t, http.StatusOK, `{"success":true,"data":{"message":"ok","script":"backup"}}`)
+			if err := tt.call(c); err != nil {
+				t.Fatal(err)
+			}
+			if last.method != tt.method || last.path != tt.path {
+				t.Errorf("Expected %s %s, got %s %s", tt.method, tt.path, last.method, last.path)
+			}
+			if tt.body != "" && !strings.Contains(last.body, tt.body) {
+				t.Errorf("Expected the body to contain %s, got %s", tt.body, last.body)
+			}
+		})
+	}
+}
+
+func TestClient_EnableScript(t *testing.T) {
+	c, _ := newTestClient(t, http.StatusOK, `{"success":true,"data":{"message":"Script backup enabled successfully","script":"backup","enabled":true}}`)
+
+	toggle, err := c.EnableScript(context.Background(), "backup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !toggle.Enabled || toggle.Script != "backup" {
+		t.Errorf("Unexpected reply %+v", toggle)
+	}
+}
@@ -2,12 +2,15 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
 	"os/exec"
 	"os/signal"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 	"syscall"
@@ -21,12 +24,17 @@ import (
 type CommandResult struct {
 	shouldRunService bool
 	webMode          bool
+	strictConfig     bool
 }
 
-// handleCommand processes command line arguments and returns appropriate action
-func handleCommand(args []string, scriptPath, logPath, configPath string, maxLines int) (CommandResult, error) {
-	svc := service.NewService(scriptPath, logPath, configPath, maxLines)
+// webShutdownTimeout is how long the web server may take to answer requests in flight on shutdown
+const webShutdownTimeout = 10 * time.Second
+
+// appSettings holds the effective service settings (defaults, config file, RSS_* env vars and flags)
+var appSettings = service.DefaultSettings(service.ExecutableDir())
 
+// handleCommand processes command line arguments and returns appropriate action
+func handleCommand(args []string, configPath string) (CommandResult, error) {
 	if len(args) < 2 {
 		return CommandResult{shouldRunService: true, webMode: true}, nil
 	}
@@ -37,25 +45,23 @@ func handleCommand(args []string, scriptPath, logPath, configPath string, maxLin
 	case "run":
 		// Always enable web mode by default
 		result := CommandResult{shouldRunService: true, webMode: true}
+		result.strictConfig = hasFlag(args[2:], "--strict")
 		return result, nil
 	case "set-interval":
-		if len(args) != 3 {
-			return CommandResult{shouldRunService: false},
-				fmt.Errorf("usage: ./run-script-service set-interval <interval>\nexamples: 30s, 5m, 1h, 3600")
-		}
-		interval, err := parseInterval(args[2])
-		if err != nil {
-			return CommandResult{shouldRunService: false},
-				fmt.Errorf("invalid interval: %v", err)
-		}
-		if err := svc.SetInterval(interval); err != nil {
-			return CommandResult{shouldRunService: false},
-				fmt.Errorf("error setting interval: %v", err)
-		}
-		return CommandResult{shouldRunService: false}, nil
+		return handleSetInterval(args[2:], configPath)
 	case "show-config":
-		svc.ShowConfig()
-		return CommandResult{shouldRunService: false}, nil
+		if hasFlag(args[2:], "--effective") {
+			return handleShowEffectiveConfig(appSettings)
+		}
+		return handleShowConfig(configPath)
+	case "validate-config":
+		return handleValidateConfig(args[2:], configPath)
+	case "config":
+		return handleConfigCommand(args[2:], configPath)
+	case "export":
+		return handleExport(args[2:], configPath)
+	case "import":
+		return handleImport(args[2:], configPath)
 	case "add-script":
 		return handleAddScript(args[2:], configPath)
 	case "list-scripts":
@@ -88,126 +94,87 @@ func handleCommand(args []string, scriptPath, logPath, configPath string, maxLin
 		return handleLogs(args[2:], configPath)
 	case "clear-logs":
 		return handleClearLogs(args[2:], configPath)
+	case "migrate-logs":
+		return handleMigrateLogs(args[2:], configPath)
 	case "set-web-port":
 		if len(args) != 3 {
 			return CommandResult{shouldRunService: false},
 				fmt.Errorf("usage: ./run-script-service set-web-port <port>")
 		}
 		return handleSetWebPort(args[2], configPath)
+	case "user":
+		return handleUserCommand(args[2:], configPath)
+	case "token":
+		return handleTokenCommand(args[2:], configPath)
+	case "audit":
+		return handleAuditCommand(args[2:], configPath)
 	case "daemon":
 		if len(args) < 3 {
 			return CommandResult{shouldRunService: false},
 				fmt.Errorf("usage: ./run-script-service daemon <start|stop|status|restart|logs>")
 		}
-		return handleDaemonCommand(args[2], configPath)
+		return handleDaemonCommand(args[2], args[3:], configPath)
 	default:
-		availableCommands := "run, set-interval, show-config, add-script, " +
-			"list-scripts, enable-script, disable-script, remove-script, run-script, logs, clear-logs, set-web-port, daemon"
+		availableCommands := "run, set-interval, show-config, validate-config, config, export, import, add-script, " +
+			"list-scripts, enable-script, disable-script, remove-script, run-script, logs, clear-logs, migrate-logs, set-web-port, user, token, audit, daemon"
 		return CommandResult{shouldRunService: false},
 			fmt.Errorf("unknown command: %s\navailable commands: %s", command, availableCommands)
 	}
 }
 
-func main() {
-	// Get paths relative to executable
-	dir, err := os.Executable()
-	if err != nil {
-		dir, _ = os.Getwd()
-	} else {
-		dir = filepath.Dir(dir)
-	}
-
-	scriptPath := filepath.Join(dir, "run.sh")
-	logPath := filepath.Join(dir, "run.log")
-	configPath := filepath.Join(dir, "service_config.json")
-	maxLines := 100
+// migratingCommands run scripts or change the config. A legacy config is converted before
+// them; other commands read it as it is and leave the file alone.
+var migratingCommands = map[string]bool{
+	"run": true, "set-interval": true, "import": true, "add-script": true, "enable-script": true,
+	"disable-script": true, "remove-script": true, "run-script": true, "set-web-port": true,
+}
 
-	result, err := handleCommand(os.Args, scriptPath, logPath, configPath, maxLines)
-	if err != nil {
-		fmt.Printf("%v\n", err)
-		os.Exit(1)
+// migrateLegacyConfig converts a legacy single-script config once before the commands in
+// migratingCommands, keeping the original as a backup
+func migrateLegacyConfig(command, configPath string) error {
+	if !migratingCommands[command] {
+		return nil
 	}
-
-	if result.shouldRunService {
-		if result.webMode {
-			runMultiScriptServiceWithWeb(configPath)
-		} else {
-			runMultiScriptService(configPath)
-		}
+	migrated, err := service.MigrateLegacyConfig(configPath)
+	if migrated {
+		fmt.Printf("Migrated legacy config %s to the multi-script format (backup: %s%s)\n",
+			configPath, configPath, service.LegacyBackupSuffix)
 	}
+	return err
 }
 
-func runService(svc *service.Service) {
-	// Set up signal handling
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGTERM, syscall.SIGINT)
-
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-
-	// Start service in a goroutine
-	done := make(chan bool)
-	go func() {
-		svc.Start(ctx)
-		done <- true
-	}()
-
-	// Wait for signal or service completion
-	select {
-	case <-sigChan:
-		fmt.Println("Received shutdown signal")
-		svc.Stop()
-		cancel()
-		<-done // Wait for service to finish
-	case <-done:
-		// Service finished naturally
-	}
-}
+func main() {
+	// Get paths relative to executable
+	dir := service.ExecutableDir()
 
-func runMultiScriptService(configPath string) {
-	// Load service configuration
-	var config service.ServiceConfig
-	err := service.LoadServiceConfig(configPath, &config)
+	// Resolve settings and strip global flags such as --config or --port
+	settings, args, err := service.ResolveSettings(dir, os.Args, os.Getenv)
 	if err != nil {
-		fmt.Printf("Failed to load config: %v\n", err)
+		fmt.Printf("%v\n", err)
 		os.Exit(1)
 	}
+	appSettings = settings
+	level, _ := service.ParseLogLevel(settings.LogLevel)
+	service.SetLogLevel(level)
 
-	// Set default web port if not configured
-	if config.WebPort == 0 {
-		config.WebPort = 8080
-	}
+	configPath := settings.ConfigPath
 
-	// Create script manager
-	manager := service.NewScriptManager(&config)
-
-	// Set up signal handling
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGTERM, syscall.SIGINT)
-
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+	if len(args) > 1 {
+		if err := migrateLegacyConfig(args[1], configPath); err != nil {
+			fmt.Printf("Failed to migrate legacy config: %v\n", err)
+			os.Exit(1)
+		}
+	}
 
-	// Start all enabled scripts
-	err = manager.StartAllEnabled(ctx)
+	result, err := handleCommand(args, configPath)
 	if err != nil {
-		fmt.Printf("Failed to start scripts: %v\n", err)
-		cancel()
+		fmt.Printf("%v\n", err)
 		os.Exit(1)
 	}
 
-	fmt.Println("Multi-script service started")
-	fmt.Printf("Running scripts: %v\n", manager.GetRunningScripts())
-
-	// Wait for shutdown signal
-	<-sigChan
-	fmt.Println("Received shutdown signal")
-
-	// Stop all scripts
-	manager.StopAll()
-	cancel()
-
-	fmt.Println("Service stopped")
+	if result.shouldRunService {
+		runScriptService(configPath, result.strictConfig, result.webMode)
+	}
 }
 
 func parseInterval(intervalStr string) (int, error) {
@@ -260,6 +227,63 @@ func parseInterval(intervalStr string) (int, error) {
 	}
 }
 
+// hasFlag reports whether a boolean flag such as --strict is present in args
+func hasFlag(args []string, flag string) bool {
+	for _, arg := range args {
+		if arg == flag {
+			return true
+		}
+	}
+	return false
+}
+
+// loadServiceConfig loads the configuration, failing on any problem when strict is set
+func loadServiceConfig(configPath string, config *service.ServiceConfig, strict bool) error {
+	if strict {
+		return service.LoadServiceConfigStrict(configPath, config)
+	}
+	return service.LoadServiceConfig(configPath, config)
+}
+
+// handleValidateConfig checks a configuration file and reports every problem found
+func handleValidateConfig(args []string, configPath string) (CommandResult, error) {
+	opts := service.ValidationOptions{CheckFiles: true}
+	for _, arg := range args {
+		switch {
+		case arg == "--no-file-check":
+			opts.CheckFiles = false
+		case strings.HasPrefix(arg, "--"):
+			return CommandResult{shouldRunService: false},
+				fmt.Errorf("usage: ./run-script-service validate-config [config-file] [--no-file-check]")
+		default:
+			configPath = arg
+		}
+	}
+
+	if _, err := os.Stat(configPath); os.IsNotExist(err) {
+		return CommandResult{shouldRunService: false}, fmt.Errorf("config file not found: %s", configPath)
+	}
+
+	issues, err := service.ValidateServiceConfigFile(configPath, opts)
+	if err != nil {
+		return CommandResult{shouldRunService: false}, err
+	}
+
+	if service.IsLegacyConfigFile(configPath) {
+		fmt.Printf("Configuration %s uses the legacy single-script format; it is converted when the service starts or the config is changed\n", configPath)
+	}
+	if len(issues) == 0 {
+		fmt.Printf("Configuration %s is valid\n", configPath)
+		return CommandResult{shouldRunService: false}, nil
+	}
+
+	fmt.Printf("Configuration %s has %d problem(s):\n", configPath, len(issues))
+	for _, issue := range issues {
+		fmt.Printf("  %s: %s\n", issue.Path, issue.Message)
+	}
+	return CommandResult{shouldRunService: false}, fmt.Errorf("configuration is invalid")
+}
+
 // parseScriptFlags parses command line flags for script management
 func parseScriptFlags(args []string) (map[string]string, error) {
 	flags := make(map[string]string)
@@ -352,6 +376,7 @@ func handleAddScript(args []string, configPath string) (CommandResult, error) {
 		return CommandResult{shouldRunService: false}, fmt.Errorf("failed to save config: %v", err)
 	}
 
+	recordAudit(service.AuditScriptAdd, newScript.Name, "", nil, newScript)
 	fmt.Printf("Script '%s' added successfully\n", flags["name"])
 	return CommandResult{shouldRunService: false}, nil
 }
@@ -398,28 +423,30 @@ func handleScriptToggle(scriptName, configPath string, enable bool) (CommandResu
 		return CommandResult{shouldRunService: false}, fmt.Errorf("failed to load config: %v", err)
 	}
 
-	found := false
+	found := -1
 	for i, script := range config.Scripts {
 		if script.Name == scriptName {
-			config.Scripts[i].Enabled = enable
-			found = true
+			found = i
 			break
 		}
 	}
 
-	if !found {
+	if found < 0 {
 		return CommandResult{shouldRunService: false}, fmt.Errorf("script '%s' not found", scriptName)
 	}
+	before := config.Scripts[found]
+	config.Scripts[found].Enabled = enable
 
 	err = service.SaveServiceConfig(configPath, &config)
 	if err != nil {
 		return CommandResult{shouldRunService: false}, fmt.Errorf("failed to save config: %v", err)
 	}
 
-	action := "disabled"
+	action, auditAction := "disabled", service.AuditScriptDisable
 	if enable {
-		action = "enabled"
+		action, auditAction = "enabled", service.AuditScriptEnable
 	}
+	recordAudit(auditAction, scriptName, "", before, config.Scripts[found])
 	fmt.Printf("Script '%s' %s\n", scriptName, action)
 	return CommandResult{shouldRunService: false}, nil
 }
@@ -442,17 +469,17 @@ func handleRemoveScript(scriptName, configPath string) (CommandResult, error) {
 		return CommandResult{shouldRunService: false}, fmt.Errorf("failed to load config: %v", err)
 	}
 
-	found := false
+	var removed *service.ScriptConfig
 	newScripts := make([]service.ScriptConfig, 0, len(config.Scripts))
-	for _, script := range config.Scripts {
+	for i, script := range config.Scripts {
 		if script.Name != scriptName {
 			newScripts = append(newScripts, script)
 		} else {
-			found = true
+			removed = &config.Scripts[i]
 		}
 	}
 
-	if !found {
+	if removed == nil {
 		return CommandResult{shouldRunService: false}, fmt.Errorf("script '%s' not found", scriptName)
 	}
 
@@ -463,6 +490,7 @@ func handleRemoveScript(scriptName, configPath string) (CommandResult, error) {
 		return CommandResult{shouldRunService: false}, fmt.Errorf("failed to save config: %v", err)
 	}
 
+	recordAudit(service.AuditScriptDelete, scriptName, "", *removed, nil)
 	fmt.Printf("Script '%s' removed\n", scriptName)
 	return CommandResult{shouldRunService: false}, nil
 }
@@ -487,12 +515,18 @@ func handleRunScript(scriptName, configPath string) (CommandResult, error) {
 		return CommandResult{shouldRunService: false}, fmt.Errorf("script '%s' not found", scriptName)
 	}
 
-	// Create a temporary script runner and execute once
-	logPath := fmt.Sprintf("%s.log", scriptName)
-	runner := service.NewScriptRunner(*scriptConfig, logPath)
+	// Create a temporary script runner and execute once, recording the run and its artifacts
+	// with the daemon's
+	runner := service.NewScriptRunnerWithLogManager(*scriptConfig, service.NewLogManager(appSettings.LogDir))
+	runner.SetArtifactStore(service.NewArtifactStore(service.ArtifactDir(appSettings.DataDir)), config.ArtifactPolicyFor(scriptConfig))
 
 	ctx := context.Background()
 	err = runner.RunOnce(ctx)
+	detail := "completed"
+	if err != nil {
+		detail = err.Error()
+	}
+	recordAudit(service.AuditScriptRun, scriptName, detail, nil, nil)
 	if err != nil {
 		fmt.Printf("Script '%s' execution failed: %v\n", scriptName, err)
 	} else {
@@ -502,52 +536,46 @@ func handleRunScript(scriptName, configPath string) (CommandResult, error) {
 	return CommandResult{shouldRunService: false}, nil
 }
 
-// handleLogs displays logs for scripts
+// handleLogs displays logs for scripts.
+// With --grep, --regex or --cursor the whole history is searched, newest run first.
 func handleLogs(args []string, _ string) (CommandResult, error) {
-	flags, err := parseLogFlags(args)
+	flags, err := parseLogFlags(args, "ignore-case", "follow", "no-color")
 	if err != nil {
 		return CommandResult{shouldRunService: false}, err
 	}
 
-	// Determine logs directory path
-	dir, err := os.Executable()
-	if err != nil {
-		dir, _ = os.Getwd()
-	} else {
-		dir = filepath.Dir(dir)
-	}
-	logsDir := filepath.Join(dir, "logs")
+	logsDir := appSettings.LogDir
 
 	// Create log manager
 	logManager := service.NewLogManager(logsDir)
 
 	// Build query
-	query := &service.LogQuery{}
-
-	if scriptName, ok := flags["script"]; ok {
-		query.ScriptName = scriptName
+	query, err := buildLogQuery(flags, time.Now())
+	if err != nil {
+		return CommandResult{shouldRunService: false}, err
 	}
 
-	if exitCode, ok := flags["exit-code"]; ok {
-		code, parseErr := strconv.Atoi(exitCode)
-		if parseErr != nil {
-			return CommandResult{shouldRunService: false}, fmt.Errorf("invalid exit-code: %v", parseErr)
-		}
-		query.ExitCode = &code
+	if flags["follow"] == "true" {
+		return handleFollowLogs(flags, query)
 	}
 
-	if limit, ok := flags["limit"]; ok {
-		limitNum, parseErr := strconv.Atoi(limit)
-		if parseErr != nil {
-			return CommandResult{shouldRunService: false}, fmt.Errorf("invalid limit: %v", parseErr)
-		}
-		query.Limit = limitNum
+	if format, ok := flags["format"]; ok && format != "text" {
+		return CommandResult{shouldRunService: false}, exportLogs(logManager, query, format, flags["output"])
 	}
 
-	// Query logs
-	entries, err := logManager.QueryLogs(query)
-	if err != nil {
-		return CommandResult{shouldRunService: false}, fmt.Errorf("failed to query logs: %v", err)
+	var entries []service.LogEntry
+	nextCursor := ""
+	if query.Text != "" || query.Regex != "" || query.Cursor != "" {
+		result, searchErr := logManager.Search(query)
+		if searchErr != nil {
+			return CommandResult{shouldRunService: false}, fmt.Errorf("failed to search logs: %v", searchErr)
+		}
+		entries, nextCursor = result.Entries, result.NextCursor
+	} else {
+		entries, err = logManager.QueryLogs(query)
+		if err != nil {
+			return CommandResult{shouldRunService: false}, fmt.Errorf("failed to query logs: %v", err)
+		}
 	}
 
 	// Display logs
@@ -567,13 +595,106 @@ func handleLogs(args []string, _ string) (CommandResult, error) {
 			if entry.Stderr != "" {
 				fmt.Printf("  STDERR: %s\n", entry.Stderr)
 			}
+			if entry.Error != "" {
+				fmt.Printf("  ERROR: %s\n", entry.Error)
+			}
+			if len(entry.Result) > 0 {
+				result, _ := json.Marshal(entry.Result)
+				fmt.Printf("  RESULT: %s (%s)\n", result, entry.Outcome)
+			}
+			if entry.Artifacts > 0 {
+				fmt.Printf("  ARTIFACTS: %d files, run %s\n", entry.Artifacts, entry.RunID)
+			}
+			if entry.OutcomeReason != "" {
+				fmt.Printf("  %s: %s\n", strings.ToUpper(entry.RunOutcome()), entry.OutcomeReason)
+			}
 			fmt.Println()
 		}
 	}
+	if nextCursor != "" {
+		fmt.Printf("More results: repeat with --cursor=%s\n", nextCursor)
+	}
 
 	return CommandResult{shouldRunService: false}, nil
 }
 
+// exportLogs writes every run matching the query in an export format to output, or stdout
+func exportLogs(logManager *service.LogManager, query *service.LogQuery, format, output string) error {
+	if _, _, err := service.ExportContentType(format); err != nil {
+		return err
+	}
+	entries, err := logManager.Export(query)
+	if err != nil {
+		return fmt.Errorf("failed to export logs: %v", err)
+	}
+
+	if output == "" {
+		return service.WriteLogExport(os.Stdout, format, entries)
+	}
+	var buf bytes.Buffer
+	if err := service.WriteLogExport(&buf, format, entries); err != nil {
+		return err
+	}
+	if err := os.WriteFile(output, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write export: %v", err)
+	}
+	fmt.Printf("Exported %d log entries to %s\n", len(entries), output)
+	return nil
+}
+
+// buildLogQuery converts logs command flags into a LogQuery
+func buildLogQuery(flags map[string]string, now time.Time) (*service.LogQuery, error) {
+	query := &service.LogQuery{
+		ScriptName: flags["script"],
+		Text:       flags["grep"],
+		Regex:      flags["regex"],
+		IgnoreCase: flags["ignore-case"] == "true",
+		Stream:     flags["stream"],
+		Trigger:    flags["trigger"],
+		Cursor:     flags["cursor"],
+	}
+
+	if exitCode, ok := flags["exit-code"]; ok {
+		code, parseErr := strconv.Atoi(exitCode)
+		if parseErr != nil {
+			return nil, fmt.Errorf("invalid exit-code: %v", parseErr)
+		}
+		query.ExitCode = &code
+	}
+
+	if limit, ok := flags["limit"]; ok {
+		limitNum, parseErr := strconv.Atoi(limit)
+		if parseErr != nil {
+			return nil, fmt.Errorf("invalid limit: %v", parseErr)
+		}
+		query.Limit = limitNum
+	}
+
+	for name, target := range map[string]*int64{"min-duration": &query.MinDuration, "max-duration": &query.MaxDuration} {
+		if value, ok := flags[name]; ok {
+			ms, parseErr := strconv.ParseInt(value, 10, 64)
+			if parseErr != nil {
+				return nil, fmt.Errorf("invalid %s: %v", name, parseErr)
+			}
+			*target = ms
+		}
+	}
+
+	var err error
+	if since, ok := flags["since"]; ok {
+		if query.StartTime, err = service.ParseLogTime(since, now); err != nil {
+			return nil, err
+		}
+	}
+	if until, ok := flags["until"]; ok {
+		if query.EndTime, err = service.ParseLogTime(until, now); err != nil {
+			return nil, err
+		}
+	}
+
+	return query, nil
+}
+
 // handleClearLogs clears logs for a specific script
 func handleClearLogs(args []string, _ string) (CommandResult, error) {
 	flags, err := parseLogFlags(args)
@@ -587,17 +708,10 @@ func handleClearLogs(args []string, _ string) (CommandResult, error) {
 			fmt.Errorf("usage: ./run-script-service clear-logs --script=<script-name>")
 	}
 
-	// Determine logs directory path
-	dir, err := os.Executable()
-	if err != nil {
-		dir, _ = os.Getwd()
-	} else {
-		dir = filepath.Dir(dir)
-	}
-	logsDir := filepath.Join(dir, "logs")
+	logsDir := appSettings.LogDir
 
 	// Clear the specific log file
-	logFile := filepath.Join(logsDir, fmt.Sprintf("%s.log", scriptName))
+	logFile := service.LogFilePath(logsDir, scriptName)
 
 	if _, statErr := os.Stat(logFile); os.IsNotExist(statErr) {
 		fmt.Printf("No log file found for script '%s'\n", scriptName)
@@ -609,12 +723,13 @@ func handleClearLogs(args []string, _ string) (CommandResult, error) {
 		return CommandResult{shouldRunService: false}, fmt.Errorf("failed to clear logs: %v", err)
 	}
 
+	recordAudit(service.AuditLogsClear, scriptName, "", nil, nil)
 	fmt.Printf("Logs cleared for script '%s'\n", scriptName)
 	return CommandResult{shouldRunService: false}, nil
 }
 
-// parseLogFlags parses log command flags
-func parseLogFlags(args []string) (map[string]string, error) {
+// parseLogFlags parses log command flags, names in booleans may be given without a value
+func parseLogFlags(args []string, booleans ...string) (map[string]string, error) {
 	flags := make(map[string]string)
 
 	for _, arg := range args {
@@ -627,6 +742,8 @@ func parseLogFlags(args []string) (map[string]string, error) {
 			parts := strings.SplitN(arg[2:], "=", 2)
 			if len(parts) == 2 {
 				flags[parts[0]] = parts[1]
+			} else if slices.Contains(booleans, parts[0]) {
+				flags[parts[0]] = "true"
 			} else {
 				return nil, fmt.Errorf("invalid flag format: %s (expected --key=value)", arg)
 			}
@@ -638,6 +755,94 @@ func parseLogFlags(args []string) (map[string]string, error) {
 	return flags, nil
 }
 
+// handleSetInterval changes the interval of a chosen script, or the default script.
+// Usage: set-interval <interval> [--script=<name>]
+func handleSetInterval(args []string, configPath string) (CommandResult, error) {
+	flags, positional, err := parseCommandFlags(args)
+	if err != nil {
+		return CommandResult{shouldRunService: false}, err
+	}
+	if len(positional) != 1 {
+		return CommandResult{shouldRunService: false},
+			fmt.Errorf("usage: ./run-script-service set-interval <interval> [--script=<name>]\nexamples: 30s, 5m, 1h, 3600")
+	}
+	interval, err := parseInterval(positional[0])
+	if err != nil {
+		return CommandResult{shouldRunService: false},
+			fmt.Errorf("invalid interval: %v", err)
+	}
+
+	var config service.ServiceConfig
+	if err := service.LoadServiceConfig(configPath, &config); err != nil {
+		return CommandResult{shouldRunService: false}, fmt.Errorf("failed to load config: %v", err)
+	}
+
+	// A fresh install has no scripts yet, set up the default run.sh like the single-script service did
+	if len(config.Scripts) == 0 && flags["script"] == "" {
+		config.Scripts = append(config.Scripts, service.DefaultScriptConfig(interval))
+		if config.WebPort == 0 {
+			config.WebPort = service.DefaultWebPort
+		}
+	}
+
+	before := service.AuditSnapshot(&config)
+	manager := service.NewScriptManagerWithPath(&config, configPath)
+	name, err := manager.ResolveScriptName(flags["script"])
+	if err != nil {
+		return CommandResult{shouldRunService: false}, err
+	}
+	if err := manager.SetScriptInterval(name, interval); err != nil {
+		return CommandResult{shouldRunService: false},
+			fmt.Errorf("error setting interval: %v", err)
+	}
+	if err := service.SaveServiceConfig(configPath, &config); err != nil {
+		return CommandResult{shouldRunService: false}, fmt.Errorf("failed to save config: %v", err)
+	}
+
+	recordAudit(service.AuditConfigUpdate, name, "interval", before, service.AuditSnapshot(&config))
+	fmt.Printf("Interval for script '%s' set to %d seconds\n", name, interval)
+	notifyDaemonReload()
+	return CommandResult{shouldRunService: false}, nil
+}
+
+// handleShowConfig displays the current configuration
+func handleShowConfig(configPath string) (CommandResult, error) {
+	var config service.ServiceConfig
+	if err := service.LoadServiceConfig(configPath, &config); err != nil {
+		return CommandResult{shouldRunService: false}, fmt.Errorf("failed to load config: %v", err)
+	}
+
+	fmt.Printf("Current configuration:\n")
+	fmt.Printf("  Config: %s\n", configPath)
+	fmt.Printf("  Web: %s\n", webURL(appSettings))
+	fmt.Printf("  Logs: %s\n", appSettings.LogDir)
+	if len(config.Scripts) == 0 {
+		fmt.Println("  Scripts: none configured")
+		return CommandResult{shouldRunService: false}, nil
+	}
+	fmt.Println("  Scripts:")
+	for _, script := range config.Scripts {
+		status := "disabled"
+		if script.Enabled {
+			status = "enabled"
+		}
+		fmt.Printf("    %s: every %d seconds (%s), %s, %s\n",
+			script.Name, script.Interval, formatDuration(script.Interval), status, script.Path)
+	}
+	return CommandResult{shouldRunService: false}, nil
+}
+
+// formatDuration formats seconds into a human-readable duration string
+func formatDuration(seconds int) string {
+	if seconds < 60 {
+		return fmt.Sprintf("%ds", seconds)
+	} else if seconds < 3600 {
+		return fmt.Sprintf("%dm", seconds/60)
+	} else {
+		return fmt.Sprintf("%dh", seconds/3600)
+	}
+}
+
 // handleSetWebPort sets the web server port
 func handleSetWebPort(portStr, configPath string) (CommandResult, error) {
 	port, err := strconv.Atoi(portStr)
@@ -657,6 +862,7 @@ func handleSetWebPort(portStr, configPath string) (CommandResult, error) {
 	}
 
 	// Update web port
+	before := service.AuditSnapshot(&config)
 	config.WebPort = port
 
 	// Save configuration
@@ -665,15 +871,16 @@ func handleSetWebPort(portStr, configPath string) (CommandResult, error) {
 		return CommandResult{shouldRunService: false}, fmt.Errorf("failed to save config: %v", err)
 	}
 
+	recordAudit(service.AuditConfigUpdate, "configuration", "web port", before, service.AuditSnapshot(&config))
 	fmt.Printf("Web port set to %d\n", port)
 	return CommandResult{shouldRunService: false}, nil
 }
 
-// runMultiScriptServiceWithWeb runs the service with web interface
-func runMultiScriptServiceWithWeb(configPath string) {
+// runScriptService runs all enabled scripts under one ScriptManager, with the web interface when webMode is set
+func runScriptService(configPath string, strict, webMode bool) {
 	// Load service configuration
 	var config service.ServiceConfig
-	err := service.LoadServiceConfig(configPath, &config)
+	err := loadServiceConfig(configPath, &config, strict)
 	if err != nil {
 		fmt.Printf("Failed to load config: %v\n", err)
 		os.Exit(1)
@@ -681,35 +888,23 @@ func runMultiScriptServiceWithWeb(configPath string) {
 
 	// Set default web port if not configured
 	if config.WebPort == 0 {
-		config.WebPort = 8080
+		config.WebPort = service.DefaultWebPort
 	}
 
-	// Get current directory for file operations
-	dir, err := os.Executable()
-	if err != nil {
-		dir, _ = os.Getwd()
-	} else {
-		dir = filepath.Dir(dir)
+	// Create the log directory before any script writes to it
+	if err := os.MkdirAll(appSettings.LogDir, 0755); err != nil {
+		fmt.Printf("Failed to create log directory: %v\n", err)
+		os.Exit(1)
 	}
 
-	// Create file manager for secure file operations
-	fileManager := service.NewFileManager(dir)
-
 	// Create script manager
 	scriptManager := service.NewScriptManagerWithPath(&config, configPath)
-
-	// Create system monitor
-	systemMonitor := service.NewSystemMonitor()
-
-	// Create web server (simplified, no LogManager dependency)
-	webServer := web.NewWebServer(nil, config.WebPort)
-	webServer.SetScriptManager(scriptManager)
-	webServer.SetFileManager(fileManager)
-	webServer.SetSystemMonitor(systemMonitor)
+	scriptManager.SetLogDir(appSettings.LogDir)
+	scriptManager.SetArtifactDir(service.ArtifactDir(appSettings.DataDir))
 
 	// Set up signal handling
 	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGTERM, syscall.SIGINT)
+	signal.Notify(sigChan, syscall.SIGTERM, syscall.SIGINT, syscall.SIGHUP)
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -722,13 +917,99 @@ func runMultiScriptServiceWithWeb(configPath string) {
 		os.Exit(1)
 	}
 
-	fmt.Println("Multi-script service with web interface started")
+	// Enforce log retention in the background
+	scriptManager.StartLogCompactor(ctx, service.DefaultLogCompactionInterval)
+
+	var webServer *web.WebServer
+	if webMode {
+		webServer = startWebInterface(ctx, cancel, scriptManager)
+		fmt.Println("Multi-script service with web interface started")
+		fmt.Printf("Web interface available at %s\n", webURL(appSettings))
+	} else {
+		fmt.Println("Multi-script service started")
+	}
 	fmt.Printf("Running scripts: %v\n", scriptManager.GetRunningScripts())
-	fmt.Printf("Web interface available at http://localhost:%d\n", config.WebPort)
+
+	// Wait for shutdown signal, reloading the config on SIGHUP
+	waitForShutdown(ctx, sigChan, scriptManager)
+	fmt.Println("Received shutdown signal")
+
+	summary := shutdownService(sigChan, scriptManager, webServer)
+	cancel()
+
+	fmt.Printf("Service stopped: %s\n", summary)
+}
+
+// shutdownService stops the service in order: new runs are refused and runs in flight may
+// finish until shutdown_timeout, or until a second signal. Then WebSocket clients get close
+// frames, the web server answers the requests in flight and the log sinks are flushed.
+func shutdownService(sigChan <-chan os.Signal, scriptManager *service.ScriptManager, webServer *web.WebServer) service.ShutdownSummary {
+	timeout := scriptManager.GetConfig().ShutdownDuration()
+	drainCtx, cancelDrain := context.WithTimeout(context.Background(), timeout)
+	defer cancelDrain()
+	go func() {
+		select {
+		case <-sigChan:
+			fmt.Println("Received second signal, killing running scripts")
+			cancelDrain()
+		case <-drainCtx.Done():
+		}
+	}()
+
+	fmt.Printf("Waiting up to %s for running scripts to finish\n", timeout)
+	summary := scriptManager.Shutdown(drainCtx)
+
+	if webServer != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), webShutdownTimeout)
+		if err := webServer.Shutdown(ctx); err != nil {
+			fmt.Printf("%v\n", err)
+		}
+		cancel()
+	}
+
+	scriptManager.CloseLogSinks()
+	_ = os.Stdout.Sync()
+	return summary
+}
+
+// startWebInterface starts the web server and system metrics broadcasting in the background.
+// It returns nil when the web server could not be configured.
+func startWebInterface(ctx context.Context, cancel context.CancelFunc, scriptManager *service.ScriptManager) *web.WebServer {
+	// Create web server, it bridges the script manager's events to WebSocket clients
+	webServer := web.NewWebServer(appSettings.Port)
+	webServer.SetBindAddress(appSettings.BindAddress)
+	if err := configureTLS(webServer, appSettings); err != nil {
+		fmt.Printf("Web server failed: %v\n", err)
+		cancel()
+		return nil
+	}
+	webServer.SetScriptManager(scriptManager)
+	// Keep credentials, TLS keys and the audit log out of reach of the file API
+	fileManager := service.NewFileManager(service.ExecutableDir())
+	fileManager.Protect(appSettings.SecretPaths(service.ExecutableDir())...)
+	webServer.SetFileManager(fileManager)
+	webServer.SetSystemMonitor(service.NewSystemMonitor())
+
+	// Record who changes what through the API
+	webServer.SetAuditLog(auditLog())
+
+	// Limit each client's API requests and the size of request bodies
+	webServer.SetRateLimits(scriptManager.GetConfig().RateLimit)
+
+	// Require credentials on the API and WebSocket once a user or token exists
+	store := authStore()
+	webServer.SetAuth(store, scriptManager.GetConfig().Auth)
+	if hasCredentials, err := store.HasCredentials(); err != nil {
+		service.Warnf("Credential store unavailable: %v", err)
+	} else if !hasCredentials {
+		service.Warnf("The web API is unauthenticated; create a user (user add) or an API token (token create) to protect it")
+	}
+
+	// Rate limits and authentication follow reloads; TLS and listening need a restart
+	scriptManager.OnReload(webServer.ApplyConfig)
 
 	// Start system metrics broadcasting (every 30 seconds)
-	err = webServer.StartSystemMetricsBroadcasting(ctx, 30*time.Second)
-	if err != nil {
+	if err := webServer.StartSystemMetricsBroadcasting(ctx, 30*time.Second); err != nil {
 		fmt.Printf("Failed to start system metrics broadcasting: %v\n", err)
 	} else {
 		fmt.Println("System metrics broadcasting started")
@@ -741,27 +1022,56 @@ func runMultiScriptServiceWithWeb(configPath string) {
 			cancel()
 		}
 	}()
+	return webServer
+}
 
-	// Wait for shutdown signal
-	<-sigChan
-	fmt.Println("Received shutdown signal")
+// configureTLS serves HTTPS when the config file has a tls section, generating a
+// self-signed certificate on first start when enabled
+func configureTLS(webServer *web.WebServer, settings *service.Settings) error {
+	if settings.TLS == nil {
+		return nil
+	}
+	if _, ok := service.UnixSocketPath(settings.BindAddress); ok {
+		service.Warnf("Ignoring tls, the web interface listens on a unix socket")
+		return nil
+	}
 
-	// Stop all scripts and web server
-	scriptManager.StopAll()
-	cancel()
+	hosts := []string{settings.BindAddress}
+	if hostname, err := os.Hostname(); err == nil {
+		hosts = append(hosts, hostname)
+	}
+	reloader, err := service.NewTLSReloader(settings.TLS, settings.DataDir, hosts)
+	if err != nil {
+		return err
+	}
+	webServer.SetTLSConfig(reloader.ServerConfig())
+	return nil
+}
 
-	fmt.Println("Service stopped")
+// waitForShutdown blocks until SIGTERM, SIGINT or ctx is done, reloading the configuration on SIGHUP
+func waitForShutdown(ctx context.Context, sigChan <-chan os.Signal, scriptManager *service.ScriptManager) {
+	for {
+		var sig os.Signal
+		select {
+		case sig = <-sigChan:
+		case <-ctx.Done():
+			return
+		}
+		if sig != syscall.SIGHUP {
+			return
+		}
+		fmt.Println("Received SIGHUP, reloading configuration")
+		if err := scriptManager.ReloadConfig(ctx); err != nil {
+			fmt.Printf("Failed to reload config, keeping current settings: %v\n", err)
+			continue
+		}
+		fmt.Printf("Configuration reloaded, running scripts: %v\n", scriptManager.GetRunningScripts())
+	}
 }
 
 // PID file management functions
 func getPidFilePath() string {
-	dir, err := os.Executable()
-	if err != nil {
-		dir, _ = os.Getwd()
-	} else {
-		dir = filepath.Dir(dir)
-	}
-	return filepath.Join(dir, "run-script-service.pid")
+	return appSettings.PidFilePath()
 }
 
 func writePidFile(pid int) error {
@@ -793,16 +1103,16 @@ func isProcessRunning(pid int) bool {
 }
 
 // handleDaemonCommand handles daemon subcommands (start/stop/status/restart/logs)
-func handleDaemonCommand(subCommand, configPath string) (CommandResult, error) {
+func handleDaemonCommand(subCommand string, args []string, configPath string) (CommandResult, error) {
 	switch subCommand {
 	case "start":
-		return handleDaemonStart(configPath)
+		return handleDaemonStart(configPath, hasFlag(args, "--strict"))
 	case "stop":
 		return handleDaemonStop()
 	case "status":
 		return handleDaemonStatus()
 	case "restart":
-		return handleDaemonRestart(configPath)
+		return handleDaemonRestart(configPath, hasFlag(args, "--strict"))
 	case "logs":
 		return handleDaemonLogs()
 	default:
@@ -812,13 +1122,21 @@ func handleDaemonCommand(subCommand, configPath string) (CommandResult, error) {
 }
 
 // handleDaemonStart starts the service as a background daemon
-func handleDaemonStart(configPath string) (CommandResult, error) {
+func handleDaemonStart(configPath string, strict bool) (CommandResult, error) {
 	// Check if already running
 	if pid, err := readPidFile(); err == nil && isProcessRunning(pid) {
 		return CommandResult{shouldRunService: false},
 			fmt.Errorf("service is already running (PID: %d)", pid)
 	}
 
+	// Refuse to start in the background with a config that would fail strict loading
+	if strict {
+		var config service.ServiceConfig
+		if err := service.LoadServiceConfigStrict(configPath, &config); err != nil {
+			return CommandResult{shouldRunService: false}, err
+		}
+	}
+
 	// Get executable path
 	execPath, err := os.Executable()
 	if err != nil {
@@ -836,7 +1154,11 @@ func handleDaemonStart(configPath string) (CommandResult, error) {
 	}
 
 	// Create log file for daemon output
-	logFile := filepath.Join(workDir, "daemon.log")
+	if err := os.MkdirAll(appSettings.DataDir, 0755); err != nil {
+		return CommandResult{shouldRunService: false},
+			fmt.Errorf("failed to create data directory: %v", err)
+	}
+	logFile := appSettings.DaemonLogPath()
 	file, err := os.OpenFile(logFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
 	if err != nil {
 		return CommandResult{shouldRunService: false},
@@ -844,8 +1166,12 @@ func handleDaemonStart(configPath string) (CommandResult, error) {
 	}
 	defer file.Close()
 
-	// Start the daemon process
-	cmd := exec.Command(execPath, "run")
+	// Start the daemon process, passing on global flags so it resolves the same settings
+	runArgs := append(append([]string{}, appSettings.Flags...), "run")
+	if strict {
+		runArgs = append(runArgs, "--strict")
+	}
+	cmd := exec.Command(execPath, runArgs...)
 	cmd.Dir = workDir
 	cmd.Stdout = file
 	cmd.Stderr = file
@@ -868,7 +1194,7 @@ func handleDaemonStart(configPath string) (CommandResult, error) {
 	}
 
 	fmt.Printf("Service started successfully (PID: %d)\n", cmd.Process.Pid)
-	fmt.Printf("Web interface available at http://localhost:8080\n")
+	fmt.Printf("Web interface available at %s\n", webURL(appSettings))
 	fmt.Printf("Logs: %s\n", logFile)
 
 	return CommandResult{shouldRunService: false}, nil
@@ -900,8 +1226,11 @@ func handleDaemonStop() (CommandResult, error) {
 		return CommandResult{shouldRunService: false}, fmt.Errorf("failed to stop service: %v", err)
 	}
 
-	// Wait a bit for graceful shutdown
-	time.Sleep(2 * time.Second)
+	// Wait for running scripts to drain and the web server to shut down
+	deadline := time.Now().Add(daemonStopTimeout())
+	for isProcessRunning(pid) && time.Now().Before(deadline) {
+		time.Sleep(200 * time.Millisecond)
+	}
 
 	// Check if still running, force kill if necessary
 	if isProcessRunning(pid) {
@@ -920,6 +1249,15 @@ func handleDaemonStop() (CommandResult, error) {
 	return CommandResult{shouldRunService: false}, nil
 }
 
+// daemonStopTimeout is how long the daemon may take to shut down before it is killed: the
+// configured shutdown_timeout plus time for killed runs, the web server and the log sinks
+func daemonStopTimeout() time.Duration {
+	var config service.ServiceConfig
+	// A config that cannot be read keeps the default timeout
+	_ = service.LoadServiceConfig(appSettings.ConfigPath, &config)
+	return config.ShutdownDuration() + webShutdownTimeout + 5*time.Second
+}
+
 // handleDaemonStatus shows the status of the daemon
 func handleDaemonStatus() (CommandResult, error) {
 	pid, err := readPidFile()
@@ -933,7 +1271,8 @@ func handleDaemonStatus() (CommandResult, error) {
 
 	if isProcessRunning(pid) {
 		fmt.Printf("Service is running (PID: %d)\n", pid)
-		fmt.Println("Web interface: http://localhost:8080")
+		fmt.Printf("Web interface: %s\n", webURL(appSettings))
+		printAPIStatus(appSettings)
 	} else {
 		fmt.Println("Service is not running (stale PID file)")
 		removePidFile()
@@ -943,7 +1282,7 @@ func handleDaemonStatus() (CommandResult, error) {
 }
 
 // handleDaemonRestart restarts the daemon
-func handleDaemonRestart(configPath string) (CommandResult, error) {
+func handleDaemonRestart(configPath string, strict bool) (CommandResult, error) {
 	// Stop if running
 	_, err := handleDaemonStop()
 	if err != nil && !strings.Contains(err.Error(), "not running") {
@@ -954,19 +1293,12 @@ func handleDaemonRestart(configPath string) (CommandResult, error) {
 	time.Sleep(1 * time.Second)
 
 	// Start again
-	return handleDaemonStart(configPath)
+	return handleDaemonStart(configPath, strict)
 }
 
 // handleDaemonLogs shows the daemon service logs
 func handleDaemonLogs() (CommandResult, error) {
-	dir, err := os.Executable()
-	if err != nil {
-		dir, _ = os.Getwd()
-	} else {
-		dir = filepath.Dir(dir)
-	}
-
-	logFile := filepath.Join(dir, "daemon.log")
+	logFile := appSettings.DaemonLogPath()
 
 	// Check if log file exists
 	if _, err := os.Stat(logFile); os.IsNotExist(err) {
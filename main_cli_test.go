@@ -4,7 +4,9 @@ package main
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"run-script-service/service"
 )
@@ -13,7 +15,6 @@ func TestCLICommands(t *testing.T) {
 	// Create temporary directory for test files
 	tempDir := t.TempDir()
 	scriptPath := filepath.Join(tempDir, "test.sh")
-	logPath := filepath.Join(tempDir, "test.log")
 	configPath := filepath.Join(tempDir, "service_config.json")
 
 	// Create a simple test script
@@ -25,7 +26,7 @@ func TestCLICommands(t *testing.T) {
 	// Test add-script command
 	t.Run("add-script command", func(t *testing.T) {
 		args := []string{"run-script-service", "add-script", "--name=test", "--path=" + scriptPath, "--interval=30s"}
-		result, err := handleCommand(args, scriptPath, logPath, configPath, 100)
+		result, err := handleCommand(args, configPath)
 
 		if err != nil {
 			t.Errorf("Expected no error, got: %v", err)
@@ -83,7 +84,7 @@ func TestCLICommands(t *testing.T) {
 		}
 
 		args := []string{"run-script-service", "list-scripts"}
-		result, err := handleCommand(args, scriptPath, logPath, configPath, 100)
+		result, err := handleCommand(args, configPath)
 
 		if err != nil {
 			t.Errorf("Expected no error, got: %v", err)
@@ -96,7 +97,7 @@ func TestCLICommands(t *testing.T) {
 
 	t.Run("enable-script command", func(t *testing.T) {
 		args := []string{"run-script-service", "enable-script", "test2"}
-		result, err := handleCommand(args, scriptPath, logPath, configPath, 100)
+		result, err := handleCommand(args, configPath)
 
 		if err != nil {
 			t.Errorf("Expected no error, got: %v", err)
@@ -127,7 +128,7 @@ func TestCLICommands(t *testing.T) {
 
 	t.Run("disable-script command", func(t *testing.T) {
 		args := []string{"run-script-service", "disable-script", "test1"}
-		result, err := handleCommand(args, scriptPath, logPath, configPath, 100)
+		result, err := handleCommand(args, configPath)
 
 		if err != nil {
 			t.Errorf("Expected no error, got: %v", err)
@@ -158,7 +159,7 @@ func TestCLICommands(t *testing.T) {
 
 	t.Run("remove-script command", func(t *testing.T) {
 		args := []string{"run-script-service", "remove-script", "test2"}
-		result, err := handleCommand(args, scriptPath, logPath, configPath, 100)
+		result, err := handleCommand(args, configPath)
 
 		if err != nil {
 			t.Errorf("Expected no error, got: %v", err)
@@ -184,7 +185,7 @@ func TestCLICommands(t *testing.T) {
 
 	t.Run("run-script command", func(t *testing.T) {
 		args := []string{"run-script-service", "run-script", "test1"}
-		result, err := handleCommand(args, scriptPath, logPath, configPath, 100)
+		result, err := handleCommand(args, configPath)
 
 		if err != nil {
 			t.Errorf("Expected no error, got: %v", err)
@@ -197,7 +198,7 @@ func TestCLICommands(t *testing.T) {
 
 	t.Run("logs command - all scripts", func(t *testing.T) {
 		args := []string{"run-script-service", "logs", "--all"}
-		result, err := handleCommand(args, scriptPath, logPath, configPath, 100)
+		result, err := handleCommand(args, configPath)
 
 		if err != nil {
 			t.Errorf("Expected no error, got: %v", err)
@@ -210,7 +211,7 @@ func TestCLICommands(t *testing.T) {
 
 	t.Run("logs command - specific script", func(t *testing.T) {
 		args := []string{"run-script-service", "logs", "--script=test1"}
-		result, err := handleCommand(args, scriptPath, logPath, configPath, 100)
+		result, err := handleCommand(args, configPath)
 
 		if err != nil {
 			t.Errorf("Expected no error, got: %v", err)
@@ -223,7 +224,7 @@ func TestCLICommands(t *testing.T) {
 
 	t.Run("logs command - with filters", func(t *testing.T) {
 		args := []string{"run-script-service", "logs", "--script=test1", "--exit-code=0", "--limit=10"}
-		result, err := handleCommand(args, scriptPath, logPath, configPath, 100)
+		result, err := handleCommand(args, configPath)
 
 		if err != nil {
 			t.Errorf("Expected no error, got: %v", err)
@@ -236,7 +237,7 @@ func TestCLICommands(t *testing.T) {
 
 	t.Run("clear-logs command - specific script", func(t *testing.T) {
 		args := []string{"run-script-service", "clear-logs", "--script=test1"}
-		result, err := handleCommand(args, scriptPath, logPath, configPath, 100)
+		result, err := handleCommand(args, configPath)
 
 		if err != nil {
 			t.Errorf("Expected no error, got: %v", err)
@@ -249,7 +250,7 @@ func TestCLICommands(t *testing.T) {
 
 	t.Run("clear-logs command - missing script", func(t *testing.T) {
 		args := []string{"run-script-service", "clear-logs"}
-		_, err := handleCommand(args, scriptPath, logPath, configPath, 100)
+		_, err := handleCommand(args, configPath)
 
 		if err == nil {
 			t.Error("Expected error for clear-logs command without --script flag")
@@ -318,3 +319,245 @@ func TestParseScriptFlags(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateConfigCommand(t *testing.T) {
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "service_config.json")
+
+	t.Run("valid config", func(t *testing.T) {
+		content := `{"scripts": [], "web_port": 8080}`
+		if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		result, err := handleValidateConfig(nil, configPath)
+		if err != nil {
+			t.Errorf("Expected no error, got: %v", err)
+		}
+		if result.shouldRunService {
+			t.Error("Expected shouldRunService to be false")
+		}
+	})
+
+	t.Run("invalid config", func(t *testing.T) {
+		content := `{"scripts": [{"name": "a", "path": "./missing.sh", "enabeld": true}], "web_port": 8080}`
+		if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := handleValidateConfig(nil, configPath); err == nil {
+			t.Error("Expected error for invalid config")
+		}
+
+		// File checks can be skipped, but the unknown field is still reported
+		if _, err := handleValidateConfig([]string{"--no-file-check"}, configPath); err == nil {
+			t.Error("Expected error for unknown field")
+		}
+	})
+
+	t.Run("explicit path", func(t *testing.T) {
+		otherPath := filepath.Join(tempDir, "other.json")
+		if err := os.WriteFile(otherPath, []byte(`{"interval": 60}`), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := handleValidateConfig([]string{otherPath}, configPath); err != nil {
+			t.Errorf("Expected legacy config to be valid, got: %v", err)
+		}
+	})
+
+	t.Run("legacy config left alone", func(t *testing.T) {
+		legacyPath := filepath.Join(tempDir, "legacy.json")
+		if err := os.WriteFile(legacyPath, []byte(`{"interval": -5}`), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := migrateLegacyConfig("validate-config", legacyPath); err != nil {
+			t.Fatalf("Expected validate-config not to migrate, got: %v", err)
+		}
+		if _, err := handleValidateConfig([]string{legacyPath}, configPath); err == nil {
+			t.Error("Expected the negative legacy interval to be reported")
+		}
+		if data, _ := os.ReadFile(legacyPath); string(data) != `{"interval": -5}` {
+			t.Errorf("Expected the legacy file to be unchanged, got %s", data)
+		}
+		if err := migrateLegacyConfig("run", legacyPath); err == nil {
+			t.Error("Expected an invalid legacy config not to be migrated")
+		}
+	})
+}
+
+func TestMigrateLegacyConfigCommands(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "service_config.json")
+	if err := os.WriteFile(configPath, []byte(`{"interval": 60}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, command := range []string{"validate-config", "show-config", "list-scripts", "logs"} {
+		if err := migrateLegacyConfig(command, configPath); err != nil || !service.IsLegacyConfigFile(configPath) {
+			t.Errorf("Expected %s to leave the legacy config alone (error %v)", command, err)
+		}
+	}
+	if err := migrateLegacyConfig("enable-script", configPath); err != nil || service.IsLegacyConfigFile(configPath) {
+		t.Errorf("Expected enable-script to migrate the legacy config (error %v)", err)
+	}
+	if _, err := os.Stat(configPath + service.LegacyBackupSuffix); err != nil {
+		t.Errorf("Expected a backup of the legacy config: %v", err)
+	}
+}
+
+func TestSetIntervalCommand(t *testing.T) {
+	t.Run("fresh config gets the default script", func(t *testing.T) {
+		configPath := filepath.Join(t.TempDir(), "service_config.json")
+
+		if _, err := handleSetInterval([]string{"30m"}, configPath); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+
+		var config service.ServiceConfig
+		if err := service.LoadServiceConfig(configPath, &config); err != nil {
+			t.Fatalf("Failed to load config: %v", err)
+		}
+		if len(config.Scripts) != 1 || config.Scripts[0].Name != service.DefaultScriptName || config.Scripts[0].Interval != 1800 {
+			t.Errorf("Unexpected scripts: %+v", config.Scripts)
+		}
+	})
+
+	t.Run("multi-script config needs a script", func(t *testing.T) {
+		configPath := filepath.Join(t.TempDir(), "service_config.json")
+		config := &service.ServiceConfig{
+			Scripts: []service.ScriptConfig{
+				{Name: "a", Path: "./a.sh", Interval: 60},
+				{Name: "b", Path: "./b.sh", Interval: 60},
+			},
+			WebPort: 8080,
+		}
+		if err := service.SaveServiceConfig(configPath, config); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := handleSetInterval([]string{"5m"}, configPath); err == nil {
+			t.Error("Expected error when the script is ambiguous")
+		}
+		if _, err := handleSetInterval([]string{"5m", "--script=b"}, configPath); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+
+		var loaded service.ServiceConfig
+		if err := service.LoadServiceConfig(configPath, &loaded); err != nil {
+			t.Fatal(err)
+		}
+		if loaded.Scripts[0].Interval != 60 || loaded.Scripts[1].Interval != 300 {
+			t.Errorf("Expected only script b to change, got %+v", loaded.Scripts)
+		}
+	})
+}
+
+func TestBuildLogQuery(t *testing.T) {
+	now := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+	flags, err := parseLogFlags([]string{"--grep=refused", "--ignore-case", "--stream=stderr", "--since=7d",
+		"--min-duration=100", "--trigger=manual", "--limit=5"}, "ignore-case")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	query, err := buildLogQuery(flags, now)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if query.Text != "refused" || !query.IgnoreCase || query.Stream != "stderr" || query.MinDuration != 100 ||
+		query.Trigger != "manual" || query.Limit != 5 || !query.StartTime.Equal(now.AddDate(0, 0, -7)) {
+		t.Errorf("Unexpected query: %+v", query)
+	}
+
+	for _, bad := range []map[string]string{{"since": "soon"}, {"max-duration": "x"}, {"exit-code": "zero"}} {
+		if _, err := buildLogQuery(bad, now); err == nil {
+			t.Errorf("Expected error for %v", bad)
+		}
+	}
+
+	if _, err := parseLogFlags([]string{"--ignore-case"}); err == nil {
+		t.Error("Expected error for boolean flag that is not allowed")
+	}
+}
+
+func TestLogsCommandGrep(t *testing.T) {
+	dir := t.TempDir()
+	previous := appSettings.LogDir
+	appSettings.LogDir = dir
+	defer func() { appSettings.LogDir = previous }()
+
+	if err := service.WriteLogFile(service.LogFilePath(dir, "fetch"), []service.LogEntry{
+		{Timestamp: time.Now(), ScriptName: "fetch", ExitCode: 1, Stderr: "connection refused"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := handleCommand([]string{"run-script-service", "logs", "--grep=refused"}, ""); err != nil {
+		t.Errorf("Expected no error, got: %v", err)
+	}
+	if _, err := handleCommand([]string{"run-script-service", "logs", "--regex=("}, ""); err == nil {
+		t.Error("Expected error for invalid regex")
+	}
+}
+
+func TestLogsCommandExport(t *testing.T) {
+	dir := t.TempDir()
+	previous := appSettings.LogDir
+	appSettings.LogDir = dir
+	defer func() { appSettings.LogDir = previous }()
+
+	if err := service.WriteLogFile(service.LogFilePath(dir, "fetch"), []service.LogEntry{
+		{Timestamp: time.Now().Add(-time.Minute), ScriptName: "fetch", Stdout: "ok"},
+		{Timestamp: time.Now(), ScriptName: "fetch", ExitCode: 1, Stderr: "connection refused"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	output := filepath.Join(dir, "report.xml")
+	if _, err := handleCommand([]string{"run-script-service", "logs", "--format=junit", "--output=" + output}, ""); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	data, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatalf("Expected export file: %v", err)
+	}
+	if !strings.Contains(string(data), `<testsuite name="fetch" tests="2" failures="1"`) {
+		t.Errorf("Unexpected JUnit export:\n%s", data)
+	}
+
+	if _, err := handleCommand([]string{"run-script-service", "logs", "--format=xlsx"}, ""); err == nil {
+		t.Error("Expected error for unknown format")
+	}
+}
+
+func TestRunScriptCommandCollectsArtifacts(t *testing.T) {
+	dir := t.TempDir()
+	previousLogDir, previousDataDir := appSettings.LogDir, appSettings.DataDir
+	appSettings.LogDir, appSettings.DataDir = filepath.Join(dir, "logs"), dir
+	defer func() { appSettings.LogDir, appSettings.DataDir = previousLogDir, previousDataDir }()
+
+	scriptPath := filepath.Join(dir, "report.sh")
+	if err := os.WriteFile(scriptPath, []byte("#!/bin/bash\nmkdir -p out\necho '<html/>' > out/report.html\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	configPath := filepath.Join(dir, "service_config.json")
+	config := &service.ServiceConfig{Scripts: []service.ScriptConfig{{
+		Name: "report", Path: scriptPath, Interval: 60, MaxLogLines: 10, Artifacts: []string{"out/*.html"},
+	}}}
+	if err := service.SaveServiceConfig(configPath, config); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := handleRunScript("report", configPath); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	entries := service.NewLogManager(appSettings.LogDir).GetLogger("report").GetEntries()
+	if len(entries) != 1 || entries[0].Artifacts != 1 {
+		t.Fatalf("Expected one run with one artifact, got %+v", entries)
+	}
+	manifest, err := service.NewArtifactStore(service.ArtifactDir(dir)).Manifest(entries[0].RunID)
+	if err != nil || len(manifest.Files) != 1 || manifest.Files[0].Path != "out/report.html" {
+		t.Errorf("Expected out/report.html in the manifest, got %+v (err %v)", manifest, err)
+	}
+}
@@ -1,9 +1,15 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+
+	"run-script-service/service"
+	"run-script-service/web"
 )
 
 func TestParseInterval(t *testing.T) {
@@ -167,6 +173,19 @@ func TestHandleCommand(t *testing.T) {
 			expectRun: false,
 			expectErr: false,
 		},
+		{
+			name:      "run with strict flag",
+			args:      []string{"run-script-service", "run", "--strict"},
+			expectRun: true,
+			expectErr: false,
+		},
+		{
+			name:       "validate-config missing file",
+			args:       []string{"run-script-service", "validate-config"},
+			expectRun:  false,
+			expectErr:  true,
+			errMessage: "config file not found",
+		},
 	}
 
 	for _, tt := range tests {
@@ -175,13 +194,12 @@ func TestHandleCommand(t *testing.T) {
 			// Create a temporary directory for test files
 			tempDir := t.TempDir()
 			scriptPath := tempDir + "/run.sh"
-			logPath := tempDir + "/run.log"
 			configPath := tempDir + "/service_config.json"
 
 			// Create a dummy script
 			os.WriteFile(scriptPath, []byte("#!/bin/bash\necho 'test'"), 0755)
 
-			result, err := handleCommand(tt.args, scriptPath, logPath, configPath, 100)
+			result, err := handleCommand(tt.args, configPath)
 
 			if tt.expectErr {
 				if err == nil {
@@ -244,3 +262,39 @@ func TestWebServerWithFileManager(t *testing.T) {
 		}
 	})
 }
+
+func TestConfigureTLS(t *testing.T) {
+	settings := service.DefaultSettings(t.TempDir())
+	if err := configureTLS(web.NewWebServer(0), settings); err != nil {
+		t.Errorf("Expected plain HTTP without tls, got %v", err)
+	}
+
+	settings.TLS = &service.TLSConfig{}
+	if err := configureTLS(web.NewWebServer(0), settings); err == nil {
+		t.Error("Expected an error when the certificate files are missing")
+	}
+
+	settings.TLS.SelfSigned = true
+	if err := configureTLS(web.NewWebServer(0), settings); err != nil {
+		t.Fatalf("Expected a self-signed certificate to be generated, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(settings.DataDir, service.DefaultTLSCertFile)); err != nil {
+		t.Errorf("Expected the certificate in the data directory: %v", err)
+	}
+}
+
+func TestShutdownService(t *testing.T) {
+	manager := service.NewScriptManager(&service.ServiceConfig{
+		ShutdownTimeout: 1,
+		Scripts:         []service.ScriptConfig{{Name: "backup", Path: "./backup.sh", Interval: 60, MaxLogLines: 10}},
+	})
+	sigChan := make(chan os.Signal, 1)
+
+	summary := shutdownService(sigChan, manager, web.NewWebServer(0))
+	if len(summary.Finished) != 0 || len(summary.Killed) != 0 {
+		t.Errorf("Expected nothing in flight, got %+v", summary)
+	}
+	if err := manager.RunScriptOnce(context.Background(), "backup"); !errors.Is(err, service.ErrShuttingDown) {
+		t.Errorf("Expected runs to be refused after shutdown, got %v", err)
+	}
+}
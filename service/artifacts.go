@@ -0,0 +1,398 @@
+// Package service provides core functionality for the run-script-service daemon.
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Artifact limits used when a policy leaves them unset
+const (
+	DefaultArtifactKeepRuns     = 10
+	DefaultArtifactMaxFileBytes = 10 << 20
+	DefaultArtifactMaxRunBytes  = 100 << 20
+	DefaultArtifactMaxFiles     = 100
+)
+
+// artifactManifestName is the file describing the artifacts of a run, next to the copies
+const artifactManifestName = "manifest.json"
+
+// artifactFilesDir holds the copied files inside a run's artifact directory
+const artifactFilesDir = "files"
+
+// ArtifactDir returns the directory run artifacts are kept in under the data directory
+func ArtifactDir(dataDir string) string {
+	return filepath.Join(dataDir, "artifacts")
+}
+
+// runIDRegex matches the run IDs generated by NewRunID
+var runIDRegex = regexp.MustCompile(`^[0-9a-f]{16}$`)
+
+// ArtifactPolicy limits the artifacts kept for a script. Zero fields use the defaults.
+type ArtifactPolicy struct {
+	KeepRuns     int   `json:"keep_runs,omitempty"`      // most recent runs whose artifacts are kept
+	KeepDays     int   `json:"keep_days,omitempty"`      // artifacts older than this are removed, 0 means no age limit
+	MaxFileBytes int64 `json:"max_file_bytes,omitempty"` // larger files are skipped
+	MaxRunBytes  int64 `json:"max_run_bytes,omitempty"`  // files beyond this total are skipped
+	MaxFiles     int   `json:"max_files,omitempty"`      // files beyond this count are skipped
+}
+
+// withDefaults fills the unset limits of a policy
+func (p ArtifactPolicy) withDefaults() ArtifactPolicy {
+	if p.KeepRuns == 0 {
+		p.KeepRuns = DefaultArtifactKeepRuns
+	}
+	if p.MaxFileBytes == 0 {
+		p.MaxFileBytes = DefaultArtifactMaxFileBytes
+	}
+	if p.MaxRunBytes == 0 {
+		p.MaxRunBytes = DefaultArtifactMaxRunBytes
+	}
+	if p.MaxFiles == 0 {
+		p.MaxFiles = DefaultArtifactMaxFiles
+	}
+	return p
+}
+
+// ArtifactPolicyFor returns the effective artifact policy of a script: artifact_policy
+// overridden field by field by the script's own artifact_policy, with defaults for the rest
+func (c *ServiceConfig) ArtifactPolicyFor(script *ScriptConfig) ArtifactPolicy {
+	var policy ArtifactPolicy
+	if c.ArtifactPolicy != nil {
+		policy = *c.ArtifactPolicy
+	}
+	if override := script.ArtifactPolicy; override != nil {
+		if override.KeepRuns != 0 {
+			policy.KeepRuns = override.KeepRuns
+		}
+		if override.KeepDays != 0 {
+			policy.KeepDays = override.KeepDays
+		}
+		if override.MaxFileBytes != 0 {
+			policy.MaxFileBytes = override.MaxFileBytes
+		}
+		if override.MaxRunBytes != 0 {
+			policy.MaxRunBytes = override.MaxRunBytes
+		}
+		if override.MaxFiles != 0 {
+			policy.MaxFiles = override.MaxFiles
+		}
+	}
+	return policy.withDefaults()
+}
+
+// Artifact is one file kept with a run
+type Artifact struct {
+	Path string `json:"path"` // slash-separated, relative to the script's working directory
+	Size int64  `json:"size"`
+}
+
+// SkippedArtifact is a matching file that was not kept
+type SkippedArtifact struct {
+	Path   string `json:"path"`
+	Reason string `json:"reason"`
+}
+
+// ArtifactManifest lists the artifacts of a run
+type ArtifactManifest struct {
+	RunID      string            `json:"run_id"`
+	ScriptName string            `json:"script_name"`
+	Timestamp  time.Time         `json:"timestamp"`
+	Files      []Artifact        `json:"files"`
+	Skipped    []SkippedArtifact `json:"skipped,omitempty"` // over a size quota or unreadable
+	Bytes      int64             `json:"bytes"`
+}
+
+// ArtifactStore keeps the artifacts of each run in <dir>/<run id>
+type ArtifactStore struct {
+	dir   string
+	mutex sync.Mutex
+}
+
+// NewArtifactStore creates a store rooted at dir
+func NewArtifactStore(dir string) *ArtifactStore {
+	return &ArtifactStore{dir: dir}
+}
+
+// GetBaseDir returns the directory the store is rooted at
+func (s *ArtifactStore) GetBaseDir() string {
+	return s.dir
+}
+
+// ValidateArtifactPattern checks that a pattern is a valid glob inside the working directory
+func ValidateArtifactPattern(pattern string) error {
+	if pattern == "" {
+		return fmt.Errorf("pattern cannot be empty")
+	}
+	if path.IsAbs(pattern) || filepath.IsAbs(pattern) {
+		return fmt.Errorf("pattern must be relative to the script's directory")
+	}
+	for _, segment := range strings.Split(pattern, "/") {
+		if segment == ".." {
+			return fmt.Errorf("pattern cannot leave the script's directory")
+		}
+		if _, err := path.Match(segment, ""); err != nil {
+			return fmt.Errorf("invalid pattern: %v", err)
+		}
+	}
+	return nil
+}
+
+// Collect copies the files matching patterns in workDir into the run's artifact directory.
+// Files over the policy's quotas are skipped. It returns nil when nothing matched.
+func (s *ArtifactStore) Collect(entry *LogEntry, workDir string, patterns []string, policy ArtifactPolicy) (*ArtifactManifest, error) {
+	if !runIDRegex.MatchString(entry.RunID) {
+		return nil, fmt.Errorf("invalid run ID '%s'", entry.RunID)
+	}
+	matches, err := matchArtifacts(workDir, patterns, s.dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(matches) == 0 {
+		return nil, nil
+	}
+
+	policy = policy.withDefaults()
+	manifest := &ArtifactManifest{
+		RunID:      entry.RunID,
+		ScriptName: entry.ScriptName,
+		Timestamp:  entry.Timestamp,
+		Files:      make([]Artifact, 0, len(matches)),
+	}
+	runDir := filepath.Join(s.dir, entry.RunID)
+	for _, rel := range matches {
+		info, err := os.Stat(filepath.Join(workDir, filepath.FromSlash(rel)))
+		switch {
+		case err != nil:
+			manifest.Skipped = append(manifest.Skipped, SkippedArtifact{Path: rel, Reason: err.Error()})
+			continue
+		case len(manifest.Files) >= policy.MaxFiles:
+			manifest.Skipped = append(manifest.Skipped, SkippedArtifact{Path: rel, Reason: fmt.Sprintf("more than %d files", policy.MaxFiles)})
+			continue
+		case info.Size() > policy.MaxFileBytes:
+			manifest.Skipped = append(manifest.Skipped, SkippedArtifact{Path: rel, Reason: fmt.Sprintf("larger than %d bytes", policy.MaxFileBytes)})
+			continue
+		case manifest.Bytes+info.Size() > policy.MaxRunBytes:
+			manifest.Skipped = append(manifest.Skipped, SkippedArtifact{Path: rel, Reason: fmt.Sprintf("run total over %d bytes", policy.MaxRunBytes)})
+			continue
+		}
+
+		dest := filepath.Join(runDir, artifactFilesDir, filepath.FromSlash(rel))
+		size, err := copyArtifact(filepath.Join(workDir, filepath.FromSlash(rel)), dest, policy.MaxFileBytes)
+		if err != nil {
+			manifest.Skipped = append(manifest.Skipped, SkippedArtifact{Path: rel, Reason: err.Error()})
+			continue
+		}
+		manifest.Files = append(manifest.Files, Artifact{Path: rel, Size: size})
+		manifest.Bytes += size
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode artifact manifest: %v", err)
+	}
+	if err := os.MkdirAll(runDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create artifact directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(runDir, artifactManifestName), data, 0644); err != nil {
+		return nil, fmt.Errorf("failed to write artifact manifest: %v", err)
+	}
+	return manifest, nil
+}
+
+// copyArtifact copies one regular file, creating parent directories, and returns its size.
+// A file that grew past limit while being copied is removed.
+func copyArtifact(src, dest string, limit int64) (int64, error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return 0, err
+	}
+	defer in.Close()
+
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return 0, err
+	}
+	out, err := os.Create(dest)
+	if err != nil {
+		return 0, err
+	}
+	size, err := io.Copy(out, io.LimitReader(in, limit+1))
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && size > limit {
+		err = fmt.Errorf("larger than %d bytes", limit)
+	}
+	if err != nil {
+		os.Remove(dest)
+		return 0, err
+	}
+	return size, nil
+}
+
+// matchArtifacts returns the regular files under workDir matching any pattern, as sorted
+// slash-separated relative paths. A "**" segment matches any number of directories.
+// The exclude directory, where artifacts are stored, is never searched.
+func matchArtifacts(workDir string, patterns []string, exclude string) ([]string, error) {
+	exclude, _ = filepath.Abs(exclude)
+	found := make(map[string]bool)
+	for _, pattern := range patterns {
+		if err := ValidateArtifactPattern(pattern); err != nil {
+			return nil, fmt.Errorf("artifact pattern '%s': %v", pattern, err)
+		}
+		segments := strings.Split(path.Clean(pattern), "/")
+
+		// Walk only below the directories named literally at the start of the pattern
+		base := 0
+		for base < len(segments)-1 && !hasGlobMeta(segments[base]) {
+			base++
+		}
+		root := filepath.Join(workDir, filepath.FromSlash(strings.Join(segments[:base], "/")))
+
+		err := filepath.WalkDir(root, func(file string, d fs.DirEntry, err error) error {
+			if err != nil {
+				if file == root {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if d.IsDir() {
+				if abs, _ := filepath.Abs(file); abs == exclude {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !d.Type().IsRegular() {
+				return nil
+			}
+			rel, relErr := filepath.Rel(workDir, file)
+			if relErr != nil {
+				return nil
+			}
+			rel = filepath.ToSlash(rel)
+			if matchSegments(segments, strings.Split(rel, "/")) {
+				found[rel] = true
+			}
+			return nil
+		})
+		if err != nil && err != filepath.SkipDir {
+			return nil, err
+		}
+	}
+
+	matches := make([]string, 0, len(found))
+	for rel := range found {
+		matches = append(matches, rel)
+	}
+	sort.Strings(matches)
+	return matches, nil
+}
+
+// hasGlobMeta reports whether a pattern segment contains glob syntax
+func hasGlobMeta(segment string) bool {
+	return strings.ContainsAny(segment, `*?[\`)
+}
+
+// matchSegments matches a path against a pattern segment by segment, "**" matching zero or more segments
+func matchSegments(pattern, name []string) bool {
+	if len(pattern) == 0 {
+		return len(name) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(name); i++ {
+			if matchSegments(pattern[1:], name[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(name) == 0 {
+		return false
+	}
+	ok, _ := path.Match(pattern[0], name[0])
+	return ok && matchSegments(pattern[1:], name[1:])
+}
+
+// Manifest returns the artifacts of a run, or os.ErrNotExist when the run kept none
+func (s *ArtifactStore) Manifest(runID string) (*ArtifactManifest, error) {
+	if !runIDRegex.MatchString(runID) {
+		return nil, fmt.Errorf("invalid run ID '%s'", runID)
+	}
+	data, err := os.ReadFile(filepath.Join(s.dir, runID, artifactManifestName))
+	if err != nil {
+		return nil, err
+	}
+	var manifest ArtifactManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("invalid artifact manifest of run %s: %v", runID, err)
+	}
+	return &manifest, nil
+}
+
+// Path returns the location on disk of an artifact listed in a run's manifest.
+// Paths not in the manifest return os.ErrNotExist.
+func (s *ArtifactStore) Path(runID, artifactPath string) (string, error) {
+	manifest, err := s.Manifest(runID)
+	if err != nil {
+		return "", err
+	}
+	artifactPath = strings.TrimPrefix(artifactPath, "/")
+	for _, file := range manifest.Files {
+		if file.Path == artifactPath {
+			return filepath.Join(s.dir, runID, artifactFilesDir, filepath.FromSlash(file.Path)), nil
+		}
+	}
+	return "", os.ErrNotExist
+}
+
+// Prune removes the artifacts of a script's runs beyond the policy's run count and age
+func (s *ArtifactStore) Prune(scriptName string, policy ArtifactPolicy, now time.Time) (int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	dirs, err := os.ReadDir(s.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to read artifact directory: %v", err)
+	}
+	var runs []*ArtifactManifest
+	for _, dir := range dirs {
+		if !dir.IsDir() {
+			continue
+		}
+		manifest, err := s.Manifest(dir.Name())
+		if err != nil || manifest.ScriptName != scriptName {
+			continue
+		}
+		runs = append(runs, manifest)
+	}
+	sort.Slice(runs, func(i, j int) bool {
+		return runs[i].Timestamp.After(runs[j].Timestamp)
+	})
+
+	policy = policy.withDefaults()
+	removed := 0
+	for i, run := range runs {
+		expired := policy.KeepDays > 0 && run.Timestamp.Before(now.AddDate(0, 0, -policy.KeepDays))
+		if i < policy.KeepRuns && !expired {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(s.dir, run.RunID)); err != nil {
+			return removed, fmt.Errorf("failed to remove artifacts of run %s: %v", run.RunID, err)
+		}
+		removed++
+	}
+	return removed, nil
+}
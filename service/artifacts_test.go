@@ -0,0 +1,197 @@
+package service
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeArtifactFiles creates files with the given contents below dir
+func writeArtifactFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		file := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestMatchArtifacts(t *testing.T) {
+	workDir := t.TempDir()
+	writeArtifactFiles(t, workDir, map[string]string{
+		"report.html":            "r",
+		"notes.txt":              "n",
+		"out/summary.json":       "s",
+		"out/2025/03/daily.json": "d",
+		"artifacts/old.json":     "stored",
+	})
+
+	tests := []struct {
+		patterns []string
+		expected []string
+	}{
+		{[]string{"*.html"}, []string{"report.html"}},
+		{[]string{"out/*.json"}, []string{"out/summary.json"}},
+		{[]string{"out/**/*.json"}, []string{"out/2025/03/daily.json", "out/summary.json"}},
+		{[]string{"**/*.json"}, []string{"out/2025/03/daily.json", "out/summary.json"}},
+		{[]string{"*.txt", "*.txt", "missing/*"}, []string{"notes.txt"}},
+	}
+	for _, tt := range tests {
+		got, err := matchArtifacts(workDir, tt.patterns, filepath.Join(workDir, "artifacts"))
+		if err != nil {
+			t.Fatalf("%v: unexpected error: %v", tt.patterns, err)
+		}
+		if !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("%v: expected %v, got %v", tt.patterns, tt.expected, got)
+		}
+	}
+}
+
+func TestValidateArtifactPattern(t *testing.T) {
+	for _, pattern := range []string{"", "/etc/*", "../secrets/*", "out/[", "out/../../x"} {
+		if err := ValidateArtifactPattern(pattern); err == nil {
+			t.Errorf("Expected %q to be rejected", pattern)
+		}
+	}
+	for _, pattern := range []string{"report.html", "out/**/*.json", "logs/*.txt"} {
+		if err := ValidateArtifactPattern(pattern); err != nil {
+			t.Errorf("Expected %q to be valid, got %v", pattern, err)
+		}
+	}
+}
+
+func TestArtifactStore_CollectWithQuotas(t *testing.T) {
+	workDir := t.TempDir()
+	writeArtifactFiles(t, workDir, map[string]string{
+		"a.txt":   "aaaa",
+		"b.txt":   "bbbb",
+		"c.txt":   "cccc",
+		"big.txt": strings.Repeat("x", 100),
+	})
+	store := NewArtifactStore(t.TempDir())
+	entry := &LogEntry{RunID: "0123456789abcdef", ScriptName: "report", Timestamp: time.Now()}
+
+	manifest, err := store.Collect(entry, workDir, []string{"*.txt"}, ArtifactPolicy{MaxFileBytes: 10, MaxRunBytes: 8})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	expected := []Artifact{{Path: "a.txt", Size: 4}, {Path: "b.txt", Size: 4}}
+	if !reflect.DeepEqual(manifest.Files, expected) || manifest.Bytes != 8 {
+		t.Errorf("Expected %v, got %+v", expected, manifest)
+	}
+	if len(manifest.Skipped) != 2 || manifest.Skipped[0].Path != "big.txt" || manifest.Skipped[1].Path != "c.txt" {
+		t.Errorf("Expected big.txt and c.txt to be skipped, got %+v", manifest.Skipped)
+	}
+
+	stored, err := store.Manifest(entry.RunID)
+	if err != nil || !reflect.DeepEqual(stored.Files, expected) || stored.ScriptName != "report" {
+		t.Errorf("Expected the manifest to be stored, got %+v (err %v)", stored, err)
+	}
+	file, err := store.Path(entry.RunID, "/b.txt")
+	if err != nil {
+		t.Fatalf("Expected b.txt to be found, got: %v", err)
+	}
+	if data, _ := os.ReadFile(file); string(data) != "bbbb" {
+		t.Errorf("Expected the copied contents, got %q", data)
+	}
+	for _, name := range []string{"c.txt", "../manifest.json"} {
+		if _, err := store.Path(entry.RunID, name); !os.IsNotExist(err) {
+			t.Errorf("Expected %s not to be served, got %v", name, err)
+		}
+	}
+	if _, err := store.Manifest("../etc"); err == nil || os.IsNotExist(err) {
+		t.Errorf("Expected an invalid run ID error, got %v", err)
+	}
+
+	none, err := store.Collect(&LogEntry{RunID: "fedcba9876543210"}, workDir, []string{"*.pdf"}, ArtifactPolicy{})
+	if err != nil || none != nil {
+		t.Errorf("Expected no manifest without matches, got %+v (err %v)", none, err)
+	}
+}
+
+func TestArtifactStore_Prune(t *testing.T) {
+	workDir := t.TempDir()
+	writeArtifactFiles(t, workDir, map[string]string{"report.html": "r"})
+	store := NewArtifactStore(t.TempDir())
+
+	now := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+	runs := []struct {
+		id     string
+		script string
+		age    time.Duration
+	}{
+		{"0000000000000001", "report", 0},
+		{"0000000000000002", "report", time.Hour},
+		{"0000000000000003", "report", 2 * time.Hour},
+		{"0000000000000004", "report", 5 * 24 * time.Hour},
+		{"0000000000000005", "other", 30 * 24 * time.Hour},
+	}
+	for _, run := range runs {
+		entry := &LogEntry{RunID: run.id, ScriptName: run.script, Timestamp: now.Add(-run.age)}
+		if _, err := store.Collect(entry, workDir, []string{"*.html"}, ArtifactPolicy{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	removed, err := store.Prune("report", ArtifactPolicy{KeepRuns: 3, KeepDays: 1}, now)
+	if err != nil || removed != 1 {
+		t.Fatalf("Expected one run removed by count, got %d (err %v)", removed, err)
+	}
+	removed, err = store.Prune("report", ArtifactPolicy{KeepRuns: 2}, now)
+	if err != nil || removed != 1 {
+		t.Fatalf("Expected one more run removed, got %d (err %v)", removed, err)
+	}
+	for _, run := range runs {
+		_, err := store.Manifest(run.id)
+		kept := run.id == "0000000000000001" || run.id == "0000000000000002" || run.script == "other"
+		if kept != (err == nil) {
+			t.Errorf("Run %s: expected kept=%v, got err %v", run.id, kept, err)
+		}
+	}
+}
+
+func TestServiceConfig_ArtifactPolicyFor(t *testing.T) {
+	config := &ServiceConfig{ArtifactPolicy: &ArtifactPolicy{KeepRuns: 5, MaxRunBytes: 1000}}
+	script := &ScriptConfig{ArtifactPolicy: &ArtifactPolicy{KeepRuns: 2, KeepDays: 7}}
+
+	expected := ArtifactPolicy{KeepRuns: 2, KeepDays: 7, MaxFileBytes: DefaultArtifactMaxFileBytes, MaxRunBytes: 1000, MaxFiles: DefaultArtifactMaxFiles}
+	if got := config.ArtifactPolicyFor(script); got != expected {
+		t.Errorf("Expected %+v, got %+v", expected, got)
+	}
+}
+
+func TestScriptManager_RunCollectsArtifacts(t *testing.T) {
+	tmpDir := t.TempDir()
+	scriptPath := filepath.Join(tmpDir, "report.sh")
+	script := "#!/bin/bash\nmkdir -p out\necho '<html/>' > out/report.html\n"
+	if err := os.WriteFile(scriptPath, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	config := &ServiceConfig{Scripts: []ScriptConfig{{
+		Name: "report", Path: scriptPath, Interval: 60, MaxLogLines: 10, Artifacts: []string{"out/*.html"},
+	}}}
+	manager := NewScriptManager(config)
+	manager.SetLogDir(filepath.Join(tmpDir, "logs"))
+	manager.SetArtifactDir(filepath.Join(tmpDir, "artifacts"))
+
+	if err := manager.RunScriptOnce(context.Background(), "report"); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	entries := manager.GetLogManager().GetLogger("report").GetEntries()
+	if len(entries) != 1 || entries[0].Artifacts != 1 {
+		t.Fatalf("Expected one run with one artifact, got %+v", entries)
+	}
+	manifest, err := manager.GetArtifactStore().Manifest(entries[0].RunID)
+	if err != nil || len(manifest.Files) != 1 || manifest.Files[0].Path != "out/report.html" {
+		t.Errorf("Expected out/report.html in the manifest, got %+v (err %v)", manifest, err)
+	}
+}
@@ -0,0 +1,206 @@
+// Package service provides core functionality for the run-script-service daemon.
+package service
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AuditFileName is the audit log in the data directory
+const AuditFileName = "audit.log"
+
+// Actions recorded in the audit log
+const (
+	AuditScriptAdd      = "script.add"
+	AuditScriptUpdate   = "script.update"
+	AuditScriptDelete   = "script.delete"
+	AuditScriptEnable   = "script.enable"
+	AuditScriptDisable  = "script.disable"
+	AuditScriptRun      = "script.run"
+	AuditFileWrite      = "file.write"
+	AuditConfigUpdate   = "config.update"
+	AuditConfigRollback = "config.rollback"
+	AuditConfigImport   = "config.import"
+	AuditLogsClear      = "logs.clear"
+	AuditUserChange     = "user.change"
+	AuditTokenChange    = "token.change"
+)
+
+// Sources of audited changes
+const (
+	AuditSourceAPI = "api"
+	AuditSourceCLI = "cli"
+)
+
+// AuditEntry records who changed what and when
+type AuditEntry struct {
+	Time     time.Time  `json:"time"`
+	Actor    string     `json:"actor"`               // user or token name, the OS user for the CLI
+	Source   string     `json:"source"`              // AuditSourceAPI or AuditSourceCLI
+	RemoteIP string     `json:"remote_ip,omitempty"` // client address of API requests
+	Action   string     `json:"action"`
+	Target   string     `json:"target"`           // script name, file path or version
+	Detail   string     `json:"detail,omitempty"` // e.g. the outcome of a manual run
+	Diff     []DiffLine `json:"diff,omitempty"`   // changed lines between before and after
+}
+
+// AuditFilter selects audit entries; zero fields match everything
+type AuditFilter struct {
+	Actor  string
+	Action string // an action, or a prefix such as "script."
+	Target string
+	Since  time.Time
+	Until  time.Time
+	Limit  int // most recent entries returned, 0 means all
+}
+
+// AuditLog appends entries to a JSON lines file that is never rewritten.
+// The daemon and the CLI can write to it at the same time.
+type AuditLog struct {
+	path  string
+	mutex sync.Mutex
+}
+
+// NewAuditLog opens the audit log at path
+func NewAuditLog(path string) *AuditLog {
+	return &AuditLog{path: path}
+}
+
+// AuditLogPath returns the location of the audit log in a data directory
+func AuditLogPath(dataDir string) string {
+	return filepath.Join(dataDir, AuditFileName)
+}
+
+// AuditDiff returns the changed lines between two states. Strings are compared as text,
+// other values as indented JSON; nil stands for a state that does not exist.
+func AuditDiff(before, after interface{}) []DiffLine {
+	var changed []DiffLine
+	for _, line := range DiffLines(auditText(before), auditText(after)) {
+		if line.Op != " " {
+			changed = append(changed, line)
+		}
+	}
+	return changed
+}
+
+// AuditSnapshot copies a value that is changed in place, such as the configuration, as indented JSON
+func AuditSnapshot(value interface{}) []byte {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+// auditText renders a state for AuditDiff
+func auditText(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	}
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(data)
+}
+
+// Record appends an entry, setting its time when it is zero
+func (a *AuditLog) Record(entry AuditEntry) error {
+	if entry.Time.IsZero() {
+		entry.Time = time.Now().UTC()
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit entry: %v", err)
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if err := os.MkdirAll(filepath.Dir(a.path), 0755); err != nil {
+		return fmt.Errorf("failed to create data directory: %v", err)
+	}
+	file, err := os.OpenFile(a.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %v", err)
+	}
+	defer file.Close()
+	// One write per entry, so appends of concurrent processes do not interleave
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write audit log: %v", err)
+	}
+	return nil
+}
+
+// Query returns the entries matching filter, newest first
+func (a *AuditLog) Query(filter AuditFilter) ([]AuditEntry, error) {
+	file, err := os.Open(a.path)
+	if os.IsNotExist(err) {
+		return []AuditEntry{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %v", err)
+	}
+	defer file.Close()
+
+	entries := []AuditEntry{}
+	reader := bufio.NewReader(file)
+	for lineNo := 1; ; lineNo++ {
+		line, err := reader.ReadBytes('\n')
+		if len(strings.TrimSpace(string(line))) > 0 {
+			var entry AuditEntry
+			if jsonErr := json.Unmarshal(line, &entry); jsonErr != nil {
+				Warnf("Skipping invalid audit log line %d: %v", lineNo, jsonErr)
+			} else if filter.matches(&entry) {
+				entries = append(entries, entry)
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read audit log: %v", err)
+		}
+	}
+
+	// Reverse into newest first, then keep the most recent
+	for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+		entries[i], entries[j] = entries[j], entries[i]
+	}
+	if filter.Limit > 0 && len(entries) > filter.Limit {
+		entries = entries[:filter.Limit]
+	}
+	return entries, nil
+}
+
+// matches reports whether an entry passes the filter
+func (f *AuditFilter) matches(entry *AuditEntry) bool {
+	if f.Actor != "" && entry.Actor != f.Actor {
+		return false
+	}
+	if f.Action != "" && entry.Action != f.Action &&
+		!(strings.HasSuffix(f.Action, ".") && strings.HasPrefix(entry.Action, f.Action)) {
+		return false
+	}
+	if f.Target != "" && entry.Target != f.Target {
+		return false
+	}
+	if !f.Since.IsZero() && entry.Time.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && entry.Time.After(f.Until) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,128 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAuditLog_RecordAndQuery(t *testing.T) {
+	path := filepath.Join(t.TempDir(), AuditFileName)
+	audit := NewAuditLog(path)
+
+	if entries, err := audit.Query(AuditFilter{}); err != nil || len(entries) != 0 {
+		t.Fatalf("Expected no entries without a file, got %v, %v", entries, err)
+	}
+
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	records := []AuditEntry{
+		{Time: start, Actor: "alice", Source: AuditSourceAPI, RemoteIP: "10.0.0.5", Action: AuditScriptAdd, Target: "backup"},
+		{Time: start.Add(time.Hour), Actor: "bob", Source: AuditSourceCLI, Action: AuditScriptDisable, Target: "backup"},
+		{Time: start.Add(2 * time.Hour), Actor: "alice", Source: AuditSourceAPI, Action: AuditFileWrite, Target: "backup.sh"},
+	}
+	for _, entry := range records {
+		if err := audit.Record(entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Expected mode 0600, got %v", info.Mode().Perm())
+	}
+
+	tests := []struct {
+		name     string
+		filter   AuditFilter
+		expected []string
+	}{
+		{"all, newest first", AuditFilter{}, []string{AuditFileWrite, AuditScriptDisable, AuditScriptAdd}},
+		{"actor", AuditFilter{Actor: "alice"}, []string{AuditFileWrite, AuditScriptAdd}},
+		{"action prefix", AuditFilter{Action: "script."}, []string{AuditScriptDisable, AuditScriptAdd}},
+		{"exact action", AuditFilter{Action: AuditScriptAdd}, []string{AuditScriptAdd}},
+		{"action is not a prefix without a dot", AuditFilter{Action: "script"}, nil},
+		{"target", AuditFilter{Target: "backup"}, []string{AuditScriptDisable, AuditScriptAdd}},
+		{"time range", AuditFilter{Since: start.Add(30 * time.Minute), Until: start.Add(90 * time.Minute)}, []string{AuditScriptDisable}},
+		{"limit keeps the newest", AuditFilter{Limit: 1}, []string{AuditFileWrite}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := audit.Query(tt.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != len(tt.expected) {
+				t.Fatalf("Expected %d entries, got %+v", len(tt.expected), entries)
+			}
+			for i, action := range tt.expected {
+				if entries[i].Action != action {
+					t.Errorf("Entry %d: expected %s, got %s", i, action, entries[i].Action)
+				}
+			}
+		})
+	}
+}
+
+func TestAuditLog_AppendsOnly(t *testing.T) {
+	path := filepath.Join(t.TempDir(), AuditFileName)
+	if err := NewAuditLog(path).Record(AuditEntry{Actor: "alice", Action: AuditLogsClear, Target: "a"}); err != nil {
+		t.Fatal(err)
+	}
+	before, _ := os.ReadFile(path)
+
+	// A second writer, like the CLI next to the daemon, and a corrupt line in between
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.WriteString("not json\n")
+	file.Close()
+	if err := NewAuditLog(path).Record(AuditEntry{Actor: "bob", Action: AuditLogsClear, Target: "b"}); err != nil {
+		t.Fatal(err)
+	}
+
+	after, _ := os.ReadFile(path)
+	if string(after[:len(before)]) != string(before) {
+		t.Error("Expected existing entries to be left untouched")
+	}
+	entries, err := NewAuditLog(path).Query(AuditFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].Actor != "bob" || entries[1].Time.IsZero() {
+		t.Errorf("Expected both entries with their time, got %+v", entries)
+	}
+}
+
+func TestAuditDiff(t *testing.T) {
+	before := ScriptConfig{Name: "backup", Path: "./backup.sh", Interval: 60, Enabled: true}
+	after := before
+	after.Interval = 120
+
+	diff := AuditDiff(before, after)
+	if len(diff) != 2 || diff[0] != (DiffLine{Op: "-", Text: `  "interval": 60,`}) ||
+		diff[1] != (DiffLine{Op: "+", Text: `  "interval": 120,`}) {
+		t.Errorf("Expected only the interval to change, got %+v", diff)
+	}
+
+	if diff := AuditDiff(nil, "echo hi\n"); len(diff) != 1 || diff[0].Op != "+" || diff[0].Text != "echo hi" {
+		t.Errorf("Expected a new file to be all additions, got %+v", diff)
+	}
+	if diff := AuditDiff(before, nil); len(diff) == 0 || diff[0].Op != "-" {
+		t.Errorf("Expected a removal to be all deletions, got %+v", diff)
+	}
+	if diff := AuditDiff(nil, nil); diff != nil {
+		t.Errorf("Expected no diff, got %+v", diff)
+	}
+
+	config := &ServiceConfig{WebPort: 8080}
+	snapshot := AuditSnapshot(config)
+	config.WebPort = 9090
+	if diff := AuditDiff(snapshot, AuditSnapshot(config)); len(diff) != 2 {
+		t.Errorf("Expected the snapshot to keep the old port, got %+v", diff)
+	}
+}
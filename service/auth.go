@@ -0,0 +1,417 @@
+// Package service provides core functionality for the run-script-service daemon.
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// AuthFileName is the credential store in the data directory
+const AuthFileName = "auth.json"
+
+// Authentication methods of the web API
+const (
+	AuthMethodToken       = "token"       // Authorization: Bearer <api token>
+	AuthMethodBasic       = "basic"       // HTTP basic with a user's password
+	AuthMethodSession     = "session"     // cookie from POST /api/v1/auth/login, used by the web interface
+	AuthMethodCertificate = "certificate" // TLS client certificate whose common name is a user
+)
+
+// DefaultSessionTTL is how long a web interface session lasts without auth.session_ttl
+const DefaultSessionTTL = 12 * time.Hour
+
+// tokenPrefix starts every API token, so leaked tokens are easy to recognise
+const tokenPrefix = "rss_"
+
+// bcryptCost is the work factor of password hashes
+var bcryptCost = bcrypt.DefaultCost
+
+// userNameRegex matches valid user and token names
+var userNameRegex = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.@-]*$`)
+
+// AuthConfig configures authentication of the web API and WebSocket
+type AuthConfig struct {
+	Methods        []string    `json:"methods,omitempty"`         // accepted methods, all when empty
+	SessionTTL     int         `json:"session_ttl,omitempty"`     // minutes a login session lasts, 0 means 12 hours
+	AllowedOrigins []string    `json:"allowed_origins,omitempty"` // origins besides the server's own that may open the WebSocket
+	Grants         []AuthGrant `json:"grants,omitempty"`          // higher roles on selected scripts
+}
+
+// MethodEnabled reports whether an authentication method is accepted
+func (c *AuthConfig) MethodEnabled(method string) bool {
+	if c == nil || len(c.Methods) == 0 {
+		return true
+	}
+	for _, m := range c.Methods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
+// SessionDuration returns how long a login session lasts
+func (c *AuthConfig) SessionDuration() time.Duration {
+	if c == nil || c.SessionTTL == 0 {
+		return DefaultSessionTTL
+	}
+	return time.Duration(c.SessionTTL) * time.Minute
+}
+
+// AuthUser is a user of the web interface with a bcrypt password hash
+type AuthUser struct {
+	Name         string    `json:"name"`
+	PasswordHash string    `json:"password_hash"`
+	Role         string    `json:"role,omitempty"` // empty for users created before roles, meaning admin
+	Created      time.Time `json:"created"`
+}
+
+// AuthToken is an API token; only the SHA-256 of the token is stored
+type AuthToken struct {
+	ID      string    `json:"id"` // public part of the token, used to revoke it
+	Name    string    `json:"name"`
+	Hash    string    `json:"hash"`
+	Role    string    `json:"role,omitempty"` // empty for tokens created before roles, meaning admin
+	Created time.Time `json:"created"`
+}
+
+// authData is the content of the credential store
+type authData struct {
+	Users  []AuthUser  `json:"users"`
+	Tokens []AuthToken `json:"tokens"`
+}
+
+// AuthStore keeps users and API tokens in a JSON file readable only by its owner.
+// Changes made by other processes, such as the CLI, are picked up on the next lookup.
+type AuthStore struct {
+	path    string
+	data    authData
+	modTime time.Time
+	size    int64
+	mutex   sync.Mutex
+}
+
+// NewAuthStore opens the credential store at path; a missing file is an empty store
+func NewAuthStore(path string) *AuthStore {
+	return &AuthStore{path: path}
+}
+
+// AuthStorePath returns the location of the credential store in a data directory
+func AuthStorePath(dataDir string) string {
+	return filepath.Join(dataDir, AuthFileName)
+}
+
+// refresh reloads the file when it changed since it was last read. Callers hold the mutex.
+func (s *AuthStore) refresh() error {
+	info, err := os.Stat(s.path)
+	if os.IsNotExist(err) {
+		s.data, s.modTime, s.size = authData{}, time.Time{}, 0
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read credential store: %v", err)
+	}
+	if info.ModTime().Equal(s.modTime) && info.Size() == s.size {
+		return nil
+	}
+
+	content, err := os.ReadFile(s.path)
+	if err != nil {
+		return fmt.Errorf("failed to read credential store: %v", err)
+	}
+	var data authData
+	if err := json.Unmarshal(content, &data); err != nil {
+		return fmt.Errorf("invalid credential store %s: %v", s.path, err)
+	}
+	s.data, s.modTime, s.size = data, info.ModTime(), info.Size()
+	return nil
+}
+
+// save writes the store atomically. Callers hold the mutex.
+func (s *AuthStore) save() error {
+	content, err := json.MarshalIndent(s.data, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode credential store: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("failed to create data directory: %v", err)
+	}
+	if err := WriteFileAtomic(s.path, content, 0600); err != nil {
+		return fmt.Errorf("failed to write credential store: %v", err)
+	}
+	s.modTime = time.Time{} // force a reload so the cached stat matches the new file
+	return s.refresh()
+}
+
+// HasCredentials reports whether any user or token exists. The web API requires
+// authentication as soon as it does.
+func (s *AuthStore) HasCredentials() (bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if err := s.refresh(); err != nil {
+		return false, err
+	}
+	return len(s.data.Users) > 0 || len(s.data.Tokens) > 0, nil
+}
+
+// AddUser creates a user or, when it exists, replaces its password.
+// An empty role keeps the role of an existing user and makes new users admins.
+func (s *AuthStore) AddUser(name, password, role string) (created bool, err error) {
+	if !userNameRegex.MatchString(name) {
+		return false, fmt.Errorf("invalid user name '%s'", name)
+	}
+	if role != "" {
+		if err := ValidateRole(role); err != nil {
+			return false, err
+		}
+	}
+	if len(password) < 8 {
+		return false, fmt.Errorf("password must be at least 8 characters")
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcryptCost)
+	if err != nil {
+		return false, fmt.Errorf("failed to hash password: %v", err)
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if err := s.refresh(); err != nil {
+		return false, err
+	}
+	for i := range s.data.Users {
+		if s.data.Users[i].Name == name {
+			s.data.Users[i].PasswordHash = string(hash)
+			if role != "" {
+				s.data.Users[i].Role = role
+			}
+			return false, s.save()
+		}
+	}
+	if role == "" {
+		role = RoleAdmin
+	}
+	s.data.Users = append(s.data.Users, AuthUser{Name: name, PasswordHash: string(hash), Role: role, Created: time.Now().UTC()})
+	return true, s.save()
+}
+
+// SetUserRole changes the role of a user
+func (s *AuthStore) SetUserRole(name, role string) error {
+	if err := ValidateRole(role); err != nil {
+		return err
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if err := s.refresh(); err != nil {
+		return err
+	}
+	for i := range s.data.Users {
+		if s.data.Users[i].Name == name {
+			s.data.Users[i].Role = role
+			return s.save()
+		}
+	}
+	return fmt.Errorf("user '%s' not found", name)
+}
+
+// RemoveUser deletes a user
+func (s *AuthStore) RemoveUser(name string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if err := s.refresh(); err != nil {
+		return err
+	}
+	for i := range s.data.Users {
+		if s.data.Users[i].Name == name {
+			s.data.Users = append(s.data.Users[:i], s.data.Users[i+1:]...)
+			return s.save()
+		}
+	}
+	return fmt.Errorf("user '%s' not found", name)
+}
+
+// Users returns the users without their password hashes
+func (s *AuthStore) Users() ([]AuthUser, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if err := s.refresh(); err != nil {
+		return nil, err
+	}
+	users := make([]AuthUser, len(s.data.Users))
+	for i, user := range s.data.Users {
+		users[i] = AuthUser{Name: user.Name, Role: roleOrAdmin(user.Role), Created: user.Created}
+	}
+	return users, nil
+}
+
+// UserRole returns the role of a user, and false when the user does not exist
+func (s *AuthStore) UserRole(name string) (string, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if err := s.refresh(); err != nil {
+		return "", false
+	}
+	for _, user := range s.data.Users {
+		if user.Name == name {
+			return roleOrAdmin(user.Role), true
+		}
+	}
+	return "", false
+}
+
+// HasUser reports whether a user exists
+func (s *AuthStore) HasUser(name string) bool {
+	_, ok := s.UserRole(name)
+	return ok
+}
+
+// CheckPassword reports whether password is the password of user name
+func (s *AuthStore) CheckPassword(name, password string) bool {
+	s.mutex.Lock()
+	hash := ""
+	if err := s.refresh(); err == nil {
+		for _, user := range s.data.Users {
+			if user.Name == name {
+				hash = user.PasswordHash
+			}
+		}
+	}
+	s.mutex.Unlock()
+
+	if hash == "" {
+		return false
+	}
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
+// CreateToken issues a new API token with a role. The token is only returned here; the store keeps its hash.
+func (s *AuthStore) CreateToken(name, role string) (token string, info AuthToken, err error) {
+	if !userNameRegex.MatchString(name) {
+		return "", AuthToken{}, fmt.Errorf("invalid token name '%s'", name)
+	}
+	if err := ValidateRole(role); err != nil {
+		return "", AuthToken{}, err
+	}
+	id, err := randomHex(4)
+	if err != nil {
+		return "", AuthToken{}, err
+	}
+	secret, err := randomHex(20)
+	if err != nil {
+		return "", AuthToken{}, err
+	}
+	token = tokenPrefix + id + "_" + secret
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if err := s.refresh(); err != nil {
+		return "", AuthToken{}, err
+	}
+	for _, existing := range s.data.Tokens {
+		if existing.Name == name {
+			return "", AuthToken{}, fmt.Errorf("token '%s' already exists", name)
+		}
+	}
+	info = AuthToken{ID: id, Name: name, Hash: hashToken(token), Role: role, Created: time.Now().UTC()}
+	s.data.Tokens = append(s.data.Tokens, info)
+	if err := s.save(); err != nil {
+		return "", AuthToken{}, err
+	}
+	info.Hash = ""
+	return token, info, nil
+}
+
+// RevokeToken deletes a token by ID or name
+func (s *AuthStore) RevokeToken(idOrName string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if err := s.refresh(); err != nil {
+		return err
+	}
+	for i, token := range s.data.Tokens {
+		if token.ID == idOrName || token.Name == idOrName {
+			s.data.Tokens = append(s.data.Tokens[:i], s.data.Tokens[i+1:]...)
+			return s.save()
+		}
+	}
+	return fmt.Errorf("token '%s' not found", idOrName)
+}
+
+// Tokens returns the tokens without their hashes
+func (s *AuthStore) Tokens() ([]AuthToken, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if err := s.refresh(); err != nil {
+		return nil, err
+	}
+	tokens := make([]AuthToken, len(s.data.Tokens))
+	for i, token := range s.data.Tokens {
+		tokens[i] = AuthToken{ID: token.ID, Name: token.Name, Role: roleOrAdmin(token.Role), Created: token.Created}
+	}
+	return tokens, nil
+}
+
+// VerifyToken returns the stored token a presented token belongs to
+func (s *AuthStore) VerifyToken(token string) (AuthToken, bool) {
+	rest, ok := strings.CutPrefix(token, tokenPrefix)
+	if !ok {
+		return AuthToken{}, false
+	}
+	id, _, ok := strings.Cut(rest, "_")
+	if !ok {
+		return AuthToken{}, false
+	}
+	hash := hashToken(token)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if err := s.refresh(); err != nil {
+		return AuthToken{}, false
+	}
+	for _, stored := range s.data.Tokens {
+		if stored.ID == id && subtle.ConstantTimeCompare([]byte(stored.Hash), []byte(hash)) == 1 {
+			stored.Role = roleOrAdmin(stored.Role)
+			return stored, true
+		}
+	}
+	return AuthToken{}, false
+}
+
+// roleOrAdmin returns the role of a stored user or token; records from before roles are admins
+func roleOrAdmin(role string) string {
+	if role == "" {
+		return RoleAdmin
+	}
+	return role
+}
+
+// hashToken returns the hex SHA-256 of a token. Tokens are random, so a fast hash is enough.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// randomHex returns n random bytes as hex
+func randomHex(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate random bytes: %v", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// NewSessionID returns a random session identifier for a login cookie
+func NewSessionID() (string, error) {
+	return randomHex(32)
+}
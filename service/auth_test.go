@@ -0,0 +1,234 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func init() {
+	bcryptCost = bcrypt.MinCost
+}
+
+func TestAuthStore_Users(t *testing.T) {
+	path := filepath.Join(t.TempDir(), AuthFileName)
+	store := NewAuthStore(path)
+
+	if has, err := store.HasCredentials(); err != nil || has {
+		t.Fatalf("Expected an empty store without a file, got %v, %v", has, err)
+	}
+
+	if _, err := store.AddUser("alice", "short", ""); err == nil {
+		t.Error("Expected short passwords to be rejected")
+	}
+	if _, err := store.AddUser("bad name", "password1", ""); err == nil {
+		t.Error("Expected invalid user names to be rejected")
+	}
+
+	created, err := store.AddUser("alice", "password1", "")
+	if err != nil || !created {
+		t.Fatalf("Expected alice to be created, got %v, %v", created, err)
+	}
+	if !store.CheckPassword("alice", "password1") {
+		t.Error("Expected the password to match")
+	}
+	if store.CheckPassword("alice", "password2") || store.CheckPassword("bob", "password1") {
+		t.Error("Expected wrong credentials not to match")
+	}
+
+	created, err = store.AddUser("alice", "password2", "")
+	if err != nil || created {
+		t.Fatalf("Expected the password to be changed, got %v, %v", created, err)
+	}
+	if !store.CheckPassword("alice", "password2") {
+		t.Error("Expected the new password to match")
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Expected mode 0600, got %v", info.Mode().Perm())
+	}
+	content, _ := os.ReadFile(path)
+	if strings.Contains(string(content), "password2") {
+		t.Error("Expected the password not to be stored in plain text")
+	}
+
+	users, err := store.Users()
+	if err != nil || len(users) != 1 || users[0].Name != "alice" || users[0].PasswordHash != "" {
+		t.Errorf("Expected alice without a hash, got %+v, %v", users, err)
+	}
+
+	if err := store.RemoveUser("alice"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.RemoveUser("alice"); err == nil {
+		t.Error("Expected removing a missing user to fail")
+	}
+	if store.HasUser("alice") {
+		t.Error("Expected alice to be removed")
+	}
+}
+
+func TestAuthStore_Roles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), AuthFileName)
+	store := NewAuthStore(path)
+
+	if _, err := store.AddUser("alice", "password1", ""); err != nil {
+		t.Fatal(err)
+	}
+	if role, _ := store.UserRole("alice"); role != RoleAdmin {
+		t.Errorf("Expected new users to be admins by default, got %s", role)
+	}
+	if _, err := store.AddUser("bob", "password1", RoleViewer); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.AddUser("bob", "password2", ""); err != nil {
+		t.Fatal(err)
+	}
+	if role, _ := store.UserRole("bob"); role != RoleViewer {
+		t.Errorf("Expected a password change to keep the role, got %s", role)
+	}
+	if _, err := store.AddUser("carol", "password1", "root"); err == nil {
+		t.Error("Expected an invalid role to be rejected")
+	}
+
+	if err := store.SetUserRole("bob", RoleOperator); err != nil {
+		t.Fatal(err)
+	}
+	if role, _ := store.UserRole("bob"); role != RoleOperator {
+		t.Errorf("Expected bob to be an operator, got %s", role)
+	}
+	if err := store.SetUserRole("dave", RoleOperator); err == nil {
+		t.Error("Expected changing the role of a missing user to fail")
+	}
+
+	if _, _, err := store.CreateToken("ci", "root"); err == nil {
+		t.Error("Expected an invalid token role to be rejected")
+	}
+	token, _, err := store.CreateToken("ci", RoleOperator)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored, _ := store.VerifyToken(token); stored.Role != RoleOperator {
+		t.Errorf("Expected an operator token, got %+v", stored)
+	}
+}
+
+func TestAuthStore_LegacyRecordsAreAdmins(t *testing.T) {
+	path := filepath.Join(t.TempDir(), AuthFileName)
+	token := tokenPrefix + "0a1b2c3d_secret"
+	legacy := `{"users": [{"name": "alice", "password_hash": "x"}],
+		"tokens": [{"id": "0a1b2c3d", "name": "ci", "hash": "` + hashToken(token) + `"}]}`
+	if err := os.WriteFile(path, []byte(legacy), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	store := NewAuthStore(path)
+	if role, ok := store.UserRole("alice"); !ok || role != RoleAdmin {
+		t.Errorf("Expected alice to be an admin, got %s, %v", role, ok)
+	}
+	if stored, ok := store.VerifyToken(token); !ok || stored.Role != RoleAdmin {
+		t.Errorf("Expected an admin token, got %+v, %v", stored, ok)
+	}
+}
+
+func TestAuthStore_Tokens(t *testing.T) {
+	store := NewAuthStore(filepath.Join(t.TempDir(), AuthFileName))
+
+	token, info, err := store.CreateToken("ci", RoleAdmin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(token, tokenPrefix+info.ID+"_") || info.Hash != "" {
+		t.Errorf("Unexpected token %s with info %+v", token, info)
+	}
+	if _, _, err := store.CreateToken("ci", RoleAdmin); err == nil {
+		t.Error("Expected duplicate token names to be rejected")
+	}
+
+	stored, ok := store.VerifyToken(token)
+	if !ok || stored.Name != "ci" {
+		t.Errorf("Expected the token to verify, got %+v, %v", stored, ok)
+	}
+	for _, invalid := range []string{"", token + "x", "rss_" + info.ID, strings.TrimPrefix(token, tokenPrefix)} {
+		if _, ok := store.VerifyToken(invalid); ok {
+			t.Errorf("Expected %q not to verify", invalid)
+		}
+	}
+
+	tokens, err := store.Tokens()
+	if err != nil || len(tokens) != 1 || tokens[0].Hash != "" {
+		t.Errorf("Expected one token without a hash, got %+v, %v", tokens, err)
+	}
+
+	if err := store.RevokeToken(info.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := store.VerifyToken(token); ok {
+		t.Error("Expected a revoked token not to verify")
+	}
+	if err := store.RevokeToken("ci"); err == nil {
+		t.Error("Expected revoking a missing token to fail")
+	}
+}
+
+func TestAuthStore_ReloadsExternalChanges(t *testing.T) {
+	path := filepath.Join(t.TempDir(), AuthFileName)
+	daemon := NewAuthStore(path)
+	if has, _ := daemon.HasCredentials(); has {
+		t.Fatal("Expected no credentials")
+	}
+
+	cli := NewAuthStore(path)
+	token, _, err := cli.CreateToken("deploy", RoleAdmin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := daemon.VerifyToken(token); !ok {
+		t.Error("Expected a token created by another process to verify")
+	}
+
+	// Make sure the modification time differs even on coarse file systems
+	time.Sleep(10 * time.Millisecond)
+	if err := cli.RevokeToken("deploy"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := daemon.VerifyToken(token); ok {
+		t.Error("Expected a token revoked by another process to be rejected")
+	}
+}
+
+func TestAuthStore_InvalidFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), AuthFileName)
+	if err := os.WriteFile(path, []byte("{"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	store := NewAuthStore(path)
+	if _, err := store.HasCredentials(); err == nil {
+		t.Error("Expected an invalid store to be reported")
+	}
+	if store.CheckPassword("alice", "password1") {
+		t.Error("Expected no password to match an unreadable store")
+	}
+}
+
+func TestAuthConfig(t *testing.T) {
+	var config *AuthConfig
+	if !config.MethodEnabled(AuthMethodBasic) || config.SessionDuration() != DefaultSessionTTL {
+		t.Error("Expected a nil config to accept all methods with the default session length")
+	}
+	config = &AuthConfig{Methods: []string{AuthMethodToken}, SessionTTL: 30}
+	if !config.MethodEnabled(AuthMethodToken) || config.MethodEnabled(AuthMethodSession) {
+		t.Error("Expected only token authentication to be enabled")
+	}
+	if config.SessionDuration() != 30*time.Minute {
+		t.Errorf("Expected 30 minutes, got %v", config.SessionDuration())
+	}
+}
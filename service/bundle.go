@@ -0,0 +1,477 @@
+// Package service provides core functionality for the run-script-service daemon.
+package service
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// BundleVersion is the current script bundle format version
+const BundleVersion = 1
+
+// bundleManifestName is the manifest entry inside tar.gz bundles
+const bundleManifestName = "bundle.json"
+
+// BundleFormat selects how a bundle is encoded
+type BundleFormat string
+
+// Supported bundle encodings
+const (
+	BundleJSON  BundleFormat = "json"
+	BundleTarGz BundleFormat = "tar.gz"
+)
+
+// ParseBundleFormat converts a format name into a BundleFormat
+func ParseBundleFormat(name string) (BundleFormat, error) {
+	switch strings.ToLower(name) {
+	case "json":
+		return BundleJSON, nil
+	case "tar.gz", "tgz", "tar":
+		return BundleTarGz, nil
+	default:
+		return "", fmt.Errorf("unknown bundle format %q (expected json or tar.gz)", name)
+	}
+}
+
+// BundleScript is one exported script: its configuration and script file
+type BundleScript struct {
+	Config   ScriptConfig `json:"config"`
+	FileName string       `json:"file_name"`
+	Mode     uint32       `json:"mode"`
+	Content  string       `json:"content,omitempty"` // omitted in tar.gz manifests, the file is a separate entry
+}
+
+// Bundle is a portable set of script definitions
+type Bundle struct {
+	Version   int            `json:"version"`
+	CreatedAt time.Time      `json:"created_at"`
+	Source    string         `json:"source,omitempty"` // host the bundle was exported from
+	Scripts   []BundleScript `json:"scripts"`
+}
+
+// ConflictPolicy decides what happens when an imported script clashes with an existing one
+type ConflictPolicy string
+
+// Supported conflict policies
+const (
+	ConflictSkip      ConflictPolicy = "skip"
+	ConflictRename    ConflictPolicy = "rename"
+	ConflictOverwrite ConflictPolicy = "overwrite"
+)
+
+// ParseConflictPolicy converts a policy name into a ConflictPolicy
+func ParseConflictPolicy(name string) (ConflictPolicy, error) {
+	switch ConflictPolicy(strings.ToLower(name)) {
+	case ConflictSkip, ConflictRename, ConflictOverwrite:
+		return ConflictPolicy(strings.ToLower(name)), nil
+	default:
+		return "", fmt.Errorf("unknown conflict policy %q (expected skip, rename or overwrite)", name)
+	}
+}
+
+// ImportOptions controls how a bundle is imported
+type ImportOptions struct {
+	Conflict ConflictPolicy // defaults to skip
+	DryRun   bool           // plan only, change nothing
+	BaseDir  string         // directory script files are written to, defaults to the working directory
+}
+
+// Import actions reported per script
+const (
+	ImportCreate    = "create"
+	ImportSkip      = "skip"
+	ImportRename    = "rename"
+	ImportOverwrite = "overwrite"
+)
+
+// ImportAction describes what happened (or would happen) to one bundled script
+type ImportAction struct {
+	Name         string `json:"name"`
+	OriginalName string `json:"original_name"`
+	Action       string `json:"action"`
+	Path         string `json:"path"`
+	Reason       string `json:"reason,omitempty"`
+}
+
+// ImportResult summarizes an import
+type ImportResult struct {
+	DryRun  bool           `json:"dry_run"`
+	Actions []ImportAction `json:"actions"`
+}
+
+// Changed reports whether the import added or replaced any script
+func (r *ImportResult) Changed() bool {
+	for _, action := range r.Actions {
+		if action.Action != ImportSkip {
+			return true
+		}
+	}
+	return false
+}
+
+// Summary lists the changed scripts with their actions, e.g. "create backup, rename report as report-2"
+func (r *ImportResult) Summary() string {
+	var parts []string
+	for _, action := range r.Actions {
+		switch {
+		case action.Action == ImportSkip:
+		case action.Name != action.OriginalName:
+			parts = append(parts, fmt.Sprintf("%s %s as %s", action.Action, action.OriginalName, action.Name))
+		default:
+			parts = append(parts, action.Action+" "+action.Name)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// resolveScriptPath resolves a configured script path against baseDir
+func resolveScriptPath(baseDir, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(baseDir, path)
+}
+
+// workingDir returns dir, or the process working directory when dir is empty
+func workingDir(dir string) (string, error) {
+	if dir != "" {
+		return dir, nil
+	}
+	return os.Getwd()
+}
+
+// ExportBundle bundles the named scripts (all scripts when names is empty) with their file contents.
+// Relative script paths are resolved against baseDir, or the working directory when it is empty.
+func ExportBundle(config *ServiceConfig, baseDir string, names []string) (*Bundle, error) {
+	baseDir, err := workingDir(baseDir)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get working directory: %v", err)
+	}
+
+	selected := make(map[string]bool)
+	for _, name := range names {
+		selected[name] = true
+	}
+
+	hostname, _ := os.Hostname()
+	bundle := &Bundle{
+		Version:   BundleVersion,
+		CreatedAt: time.Now().UTC(),
+		Source:    hostname,
+		Scripts:   make([]BundleScript, 0),
+	}
+
+	for _, script := range config.Scripts {
+		if len(selected) > 0 && !selected[script.Name] {
+			continue
+		}
+		delete(selected, script.Name)
+
+		path := resolveScriptPath(baseDir, script.Path)
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read script file for %s: %v", script.Name, err)
+		}
+		mode := uint32(0755)
+		if info, statErr := os.Stat(path); statErr == nil {
+			mode = uint32(info.Mode().Perm())
+		}
+
+		bundle.Scripts = append(bundle.Scripts, BundleScript{
+			Config:   script,
+			FileName: filepath.Base(script.Path),
+			Mode:     mode,
+			Content:  string(content),
+		})
+	}
+
+	for name := range selected {
+		return nil, fmt.Errorf("script %s %w", name, ErrScriptNotFound)
+	}
+	return bundle, nil
+}
+
+// EncodeBundle writes the bundle in the requested format
+func EncodeBundle(w io.Writer, bundle *Bundle, format BundleFormat) error {
+	switch format {
+	case BundleJSON:
+		data, err := json.MarshalIndent(bundle, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(append(data, '\n'))
+		return err
+	case BundleTarGz:
+		return encodeTarGz(w, bundle)
+	default:
+		return fmt.Errorf("unknown bundle format %q", format)
+	}
+}
+
+// tarEntry is a named file with its mode and content
+type tarEntry struct {
+	name string
+	mode uint32
+	data []byte
+}
+
+// encodeTarGz writes a manifest plus one tar entry per script file
+func encodeTarGz(w io.Writer, bundle *Bundle) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	manifest := *bundle
+	manifest.Scripts = make([]BundleScript, len(bundle.Scripts))
+	for i, script := range bundle.Scripts {
+		script.Content = ""
+		manifest.Scripts[i] = script
+	}
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	entries := []tarEntry{{bundleManifestName, 0644, data}}
+	for _, script := range bundle.Scripts {
+		entries = append(entries, tarEntry{bundleEntryName(script), script.Mode, []byte(script.Content)})
+	}
+
+	for _, entry := range entries {
+		header := &tar.Header{
+			Name:    entry.name,
+			Mode:    int64(entry.mode),
+			Size:    int64(len(entry.data)),
+			ModTime: bundle.CreatedAt,
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if _, err := tw.Write(entry.data); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// bundleEntryName returns the tar entry holding a script's file
+func bundleEntryName(script BundleScript) string {
+	return fmt.Sprintf("scripts/%s/%s", script.Config.Name, script.FileName)
+}
+
+// DecodeBundle parses a JSON or tar.gz bundle, detecting the format from its content
+func DecodeBundle(data []byte) (*Bundle, error) {
+	var bundle *Bundle
+	var err error
+	if len(data) >= 2 && data[0] == 0x1f && data[1] == 0x8b {
+		bundle, err = decodeTarGz(data)
+	} else {
+		bundle = &Bundle{}
+		if jsonErr := json.Unmarshal(data, bundle); jsonErr != nil {
+			err = fmt.Errorf("invalid bundle: %v", jsonErr)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if bundle.Version != BundleVersion {
+		return nil, fmt.Errorf("unsupported bundle version %d (expected %d)", bundle.Version, BundleVersion)
+	}
+	return bundle, nil
+}
+
+// decodeTarGz reads a tar.gz bundle and fills in script contents from their entries
+func decodeTarGz(data []byte) (*Bundle, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("invalid bundle archive: %v", err)
+	}
+	defer gz.Close()
+
+	var manifest []byte
+	files := make(map[string][]byte)
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid bundle archive: %v", err)
+		}
+		content, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid bundle archive: %v", err)
+		}
+		if header.Name == bundleManifestName {
+			manifest = content
+		} else {
+			files[header.Name] = content
+		}
+	}
+
+	if manifest == nil {
+		return nil, fmt.Errorf("invalid bundle archive: missing %s", bundleManifestName)
+	}
+
+	var bundle Bundle
+	if err := json.Unmarshal(manifest, &bundle); err != nil {
+		return nil, fmt.Errorf("invalid bundle manifest: %v", err)
+	}
+	for i, script := range bundle.Scripts {
+		content, ok := files[bundleEntryName(script)]
+		if !ok {
+			return nil, fmt.Errorf("invalid bundle archive: missing file for script %s", script.Config.Name)
+		}
+		bundle.Scripts[i].Content = string(content)
+	}
+	return &bundle, nil
+}
+
+// ImportBundle merges the bundle's scripts into config and writes their files under opts.BaseDir.
+// With DryRun set only the plan is returned; neither config nor files are touched.
+// The caller is responsible for saving config afterwards.
+func ImportBundle(config *ServiceConfig, bundle *Bundle, opts ImportOptions) (*ImportResult, error) {
+	if opts.Conflict == "" {
+		opts.Conflict = ConflictSkip
+	}
+	baseDir, err := workingDir(opts.BaseDir)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get working directory: %v", err)
+	}
+
+	// Validate everything up front so a bad entry leaves nothing half imported
+	for i, script := range bundle.Scripts {
+		if err := script.Config.ValidateWithOptions(false); err != nil {
+			return nil, fmt.Errorf("invalid script %d in bundle: %v", i, err)
+		}
+		if !isPlainFileName(script.FileName) {
+			return nil, fmt.Errorf("invalid file name for script %s: %q", script.Config.Name, script.FileName)
+		}
+	}
+
+	result := &ImportResult{DryRun: opts.DryRun, Actions: make([]ImportAction, 0, len(bundle.Scripts))}
+	scripts := append([]ScriptConfig{}, config.Scripts...)
+	var files []tarEntry
+	claimed := make(map[string]bool) // file names taken by earlier entries of this bundle
+
+	for _, script := range bundle.Scripts {
+		imported := script.Config
+		fileName := script.FileName
+		action := ImportAction{OriginalName: imported.Name, Action: ImportCreate}
+
+		existing := indexOfScript(scripts, imported.Name)
+		fileClash := claimed[fileName] || fileConflicts(filepath.Join(baseDir, fileName), script.Content)
+		if existing >= 0 || fileClash {
+			action.Reason = conflictReason(existing >= 0, fileClash)
+			switch opts.Conflict {
+			case ConflictSkip:
+				action.Action = ImportSkip
+			case ConflictOverwrite:
+				action.Action = ImportOverwrite
+			case ConflictRename:
+				action.Action = ImportRename
+				imported.Name, fileName = uniqueScriptName(scripts, claimed, baseDir, imported.Name, fileName)
+				existing = -1
+			}
+		}
+
+		imported.Path = "./" + fileName
+		action.Name = imported.Name
+		action.Path = imported.Path
+		result.Actions = append(result.Actions, action)
+
+		if action.Action == ImportSkip {
+			continue
+		}
+		if existing >= 0 {
+			scripts[existing] = imported
+		} else {
+			scripts = append(scripts, imported)
+		}
+		claimed[fileName] = true
+		files = append(files, tarEntry{filepath.Join(baseDir, fileName), script.Mode, []byte(script.Content)})
+	}
+
+	if opts.DryRun {
+		return result, nil
+	}
+
+	for _, file := range files {
+		mode := os.FileMode(file.mode).Perm()
+		if mode == 0 {
+			mode = 0755
+		}
+		if err := WriteFileAtomic(file.name, file.data, mode); err != nil {
+			return nil, fmt.Errorf("failed to write script file %s: %v", file.name, err)
+		}
+	}
+	config.Scripts = scripts
+	return result, nil
+}
+
+// indexOfScript returns the index of the named script or -1
+func indexOfScript(scripts []ScriptConfig, name string) int {
+	for i, script := range scripts {
+		if script.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// fileConflicts reports whether path exists with content different from content
+func fileConflicts(path, content string) bool {
+	existing, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	return string(existing) != content
+}
+
+// conflictReason describes why an imported script conflicts
+func conflictReason(nameClash, fileClash bool) string {
+	switch {
+	case nameClash && fileClash:
+		return "script name and file already exist"
+	case nameClash:
+		return "script name already exists"
+	default:
+		return "script file already exists with different content"
+	}
+}
+
+// uniqueScriptName finds the first "<name>-N" whose name and file are both free
+func uniqueScriptName(scripts []ScriptConfig, claimed map[string]bool, baseDir, name, fileName string) (string, string) {
+	ext := filepath.Ext(fileName)
+	stem := strings.TrimSuffix(fileName, ext)
+	for n := 2; ; n++ {
+		candidate := fmt.Sprintf("%s-%d", name, n)
+		candidateFile := fmt.Sprintf("%s-%d%s", stem, n, ext)
+		if indexOfScript(scripts, candidate) >= 0 || claimed[candidateFile] {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(baseDir, candidateFile)); err == nil {
+			continue
+		}
+		return candidate, candidateFile
+	}
+}
+
+// isPlainFileName reports whether name is a single path element safe to write
+func isPlainFileName(name string) bool {
+	return name != "" && name != "." && name != ".." &&
+		!strings.ContainsAny(name, `/\`) && filepath.Base(name) == name
+}
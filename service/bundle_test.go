@@ -0,0 +1,186 @@
+package service
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeScript creates an executable script file and returns its path
+func writeScript(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0755); err != nil {
+		t.Fatalf("failed to write script: %v", err)
+	}
+	return path
+}
+
+func exportTestBundle(t *testing.T) *Bundle {
+	t.Helper()
+	srcDir := t.TempDir()
+	writeScript(t, srcDir, "backup.sh", "#!/bin/bash\necho backup\n")
+	writeScript(t, srcDir, "cleanup.sh", "#!/bin/bash\necho cleanup\n")
+
+	config := &ServiceConfig{Scripts: []ScriptConfig{
+		{Name: "backup", Path: "./backup.sh", Interval: 3600, Enabled: true, MaxLogLines: 100},
+		{Name: "cleanup", Path: "./cleanup.sh", Interval: 60, MaxLogLines: 50, Timeout: 30},
+	}}
+
+	bundle, err := ExportBundle(config, srcDir, nil)
+	if err != nil {
+		t.Fatalf("unexpected export error: %v", err)
+	}
+	return bundle
+}
+
+func TestExportBundle(t *testing.T) {
+	bundle := exportTestBundle(t)
+
+	if bundle.Version != BundleVersion || len(bundle.Scripts) != 2 {
+		t.Fatalf("unexpected bundle: %+v", bundle)
+	}
+	if bundle.Scripts[0].FileName != "backup.sh" || !strings.Contains(bundle.Scripts[0].Content, "echo backup") {
+		t.Errorf("unexpected script entry: %+v", bundle.Scripts[0])
+	}
+	if bundle.Scripts[1].Config.Timeout != 30 {
+		t.Errorf("expected config to be carried over, got %+v", bundle.Scripts[1].Config)
+	}
+
+	config := &ServiceConfig{Scripts: []ScriptConfig{{Name: "backup", Path: "./backup.sh"}}}
+	if _, err := ExportBundle(config, t.TempDir(), []string{"missing"}); err == nil {
+		t.Error("expected error for unknown script")
+	}
+}
+
+func TestBundleEncodeDecodeRoundTrip(t *testing.T) {
+	bundle := exportTestBundle(t)
+
+	for _, format := range []BundleFormat{BundleJSON, BundleTarGz} {
+		t.Run(string(format), func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := EncodeBundle(&buf, bundle, format); err != nil {
+				t.Fatalf("unexpected encode error: %v", err)
+			}
+
+			decoded, err := DecodeBundle(buf.Bytes())
+			if err != nil {
+				t.Fatalf("unexpected decode error: %v", err)
+			}
+			if len(decoded.Scripts) != 2 {
+				t.Fatalf("expected 2 scripts, got %d", len(decoded.Scripts))
+			}
+			for i, script := range decoded.Scripts {
+				if script.Content != bundle.Scripts[i].Content || !reflect.DeepEqual(script.Config, bundle.Scripts[i].Config) {
+					t.Errorf("script %d did not round-trip: %+v", i, script)
+				}
+			}
+		})
+	}
+
+	if _, err := DecodeBundle([]byte(`{"version": 99, "scripts": []}`)); err == nil {
+		t.Error("expected error for unsupported version")
+	}
+	if _, err := DecodeBundle([]byte("not a bundle")); err == nil {
+		t.Error("expected error for garbage input")
+	}
+}
+
+func TestImportBundle_ConflictPolicies(t *testing.T) {
+	tests := []struct {
+		policy        ConflictPolicy
+		expectAction  string
+		expectName    string
+		expectScripts int
+		expectContent string
+	}{
+		{ConflictSkip, ImportSkip, "backup", 1, "#!/bin/bash\necho local\n"},
+		{ConflictRename, ImportRename, "backup-2", 2, "#!/bin/bash\necho local\n"},
+		{ConflictOverwrite, ImportOverwrite, "backup", 1, "#!/bin/bash\necho backup\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			bundle := exportTestBundle(t)
+			bundle.Scripts = bundle.Scripts[:1]
+
+			destDir := t.TempDir()
+			writeScript(t, destDir, "backup.sh", "#!/bin/bash\necho local\n")
+			config := &ServiceConfig{Scripts: []ScriptConfig{{Name: "backup", Path: "./backup.sh", Interval: 10}}}
+
+			result, err := ImportBundle(config, bundle, ImportOptions{Conflict: tt.policy, BaseDir: destDir})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			action := result.Actions[0]
+			if action.Action != tt.expectAction || action.Name != tt.expectName || action.Reason == "" {
+				t.Errorf("unexpected action: %+v", action)
+			}
+			if len(config.Scripts) != tt.expectScripts {
+				t.Errorf("expected %d scripts, got %d", tt.expectScripts, len(config.Scripts))
+			}
+
+			content, _ := os.ReadFile(filepath.Join(destDir, "backup.sh"))
+			if string(content) != tt.expectContent {
+				t.Errorf("unexpected backup.sh content: %q", content)
+			}
+			if tt.policy == ConflictRename {
+				if _, err := os.Stat(filepath.Join(destDir, "backup-2.sh")); err != nil {
+					t.Errorf("expected renamed script file: %v", err)
+				}
+				if config.Scripts[1].Path != "./backup-2.sh" {
+					t.Errorf("expected renamed path, got %s", config.Scripts[1].Path)
+				}
+			}
+		})
+	}
+}
+
+func TestImportBundle_DryRun(t *testing.T) {
+	bundle := exportTestBundle(t)
+	destDir := t.TempDir()
+	config := &ServiceConfig{}
+
+	result, err := ImportBundle(config, bundle, ImportOptions{DryRun: true, BaseDir: destDir})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !result.DryRun || len(result.Actions) != 2 || result.Actions[0].Action != ImportCreate {
+		t.Errorf("unexpected plan: %+v", result)
+	}
+	if len(config.Scripts) != 0 {
+		t.Error("dry run must not change the config")
+	}
+	if entries, _ := os.ReadDir(destDir); len(entries) != 0 {
+		t.Error("dry run must not write files")
+	}
+}
+
+func TestImportBundle_RejectsUnsafeFileNames(t *testing.T) {
+	bundle := exportTestBundle(t)
+	bundle.Scripts[1].FileName = "../escape.sh"
+	destDir := t.TempDir()
+	config := &ServiceConfig{}
+
+	if _, err := ImportBundle(config, bundle, ImportOptions{BaseDir: destDir}); err == nil {
+		t.Fatal("expected error for path traversal")
+	}
+	if len(config.Scripts) != 0 {
+		t.Error("a rejected bundle must not be partially imported")
+	}
+}
+
+func TestImportResult_Summary(t *testing.T) {
+	result := ImportResult{Actions: []ImportAction{
+		{Name: "backup", OriginalName: "backup", Action: ImportCreate},
+		{Name: "report", OriginalName: "report", Action: ImportSkip},
+		{Name: "sync-2", OriginalName: "sync", Action: ImportRename},
+	}}
+	if got := result.Summary(); got != "create backup, rename sync as sync-2" {
+		t.Errorf("Unexpected summary %q", got)
+	}
+}
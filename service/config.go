@@ -17,16 +17,48 @@ type ScriptConfig struct {
 	Enabled     bool   `json:"enabled"`
 	MaxLogLines int    `json:"max_log_lines"`
 	Timeout     int    `json:"timeout"` // seconds, 0 means no limit
+
+	Tags []string `json:"tags,omitempty"` // labels that auth grants can select scripts by
+
+	Retention *RetentionPolicy    `json:"retention,omitempty"` // overrides log_retention for this script
+	LogSinks  []string            `json:"log_sinks,omitempty"` // names of log_sinks to forward runs to, default sinks when empty
+	Output    *OutputParserConfig `json:"output,omitempty"`    // extracts a result map from stdout
+
+	SuccessExitCodes []int         `json:"success_exit_codes,omitempty"` // exit codes of successful runs, default [0]
+	WarningExitCodes []int         `json:"warning_exit_codes,omitempty"` // exit codes of runs that completed with a warning
+	OutcomeRules     []OutcomeRule `json:"outcome_rules,omitempty"`      // output conditions that downgrade a run
+
+	Artifacts      []string        `json:"artifacts,omitempty"`       // globs in the script's directory kept with each run
+	ArtifactPolicy *ArtifactPolicy `json:"artifact_policy,omitempty"` // overrides artifact_policy for this script
+
+	Pools    []string `json:"pools,omitempty"`    // resource_pools a run holds a slot of while it executes
+	Priority int      `json:"priority,omitempty"` // runs waiting for a slot start highest priority first
 }
 
 // ServiceConfig represents the overall service configuration
 type ServiceConfig struct {
-	Scripts []ScriptConfig `json:"scripts"`
-	WebPort int            `json:"web_port"`
+	Scripts            []ScriptConfig   `json:"scripts"`
+	WebPort            int              `json:"web_port"`
+	BindAddress        string           `json:"bind_address,omitempty"`         // empty listens on all interfaces, unix:<path> on a socket
+	LogDir             string           `json:"log_dir,omitempty"`              // relative to the config file
+	DataDir            string           `json:"data_dir,omitempty"`             // relative to the config file
+	LogLevel           string           `json:"log_level,omitempty"`            // debug, info, warn or error
+	ConfigHistoryLimit int              `json:"config_history_limit,omitempty"` // versions kept, 0 means default
+	LogRetention       *RetentionPolicy `json:"log_retention,omitempty"`        // default retention for all script logs
+	LogSinks           []LogSinkConfig  `json:"log_sinks,omitempty"`            // syslog and journald forwarding
+	ArtifactPolicy     *ArtifactPolicy  `json:"artifact_policy,omitempty"`      // default artifact retention and quotas
+	Auth               *AuthConfig      `json:"auth,omitempty"`                 // web API authentication
+	TLS                *TLSConfig       `json:"tls,omitempty"`                  // serve the web interface over HTTPS
+	ShutdownTimeout    int              `json:"shutdown_timeout,omitempty"`     // seconds runs in flight may finish on shutdown, 0 means 30
+	RunWorkers         int              `json:"run_workers,omitempty"`          // manual runs executing at once, 0 means 4
+	RunQueueSize       int              `json:"run_queue_size,omitempty"`       // manual runs waiting for a worker, 0 means 100
+	MaxConcurrentRuns  int              `json:"max_concurrent_runs,omitempty"`  // runs of all scripts executing at once, 0 means no limit
+	ResourcePools      map[string]int   `json:"resource_pools,omitempty"`       // named limits scripts declare in pools, e.g. "db": 2
+	RateLimit          *RateLimitConfig `json:"rate_limit,omitempty"`           // API requests per client and request body sizes
 }
 
-// Config is a legacy struct for backward compatibility
-type Config struct {
+// LegacyConfig is the old single-script format, only read to migrate it
+type LegacyConfig struct {
 	Interval int `json:"interval"`
 }
 
@@ -87,40 +119,6 @@ func (sc *ScriptConfig) ValidateWithOptions(checkFileExists bool) error {
 	return nil
 }
 
-// LoadConfig loads configuration from the specified file path
-func LoadConfig(configPath string, config *Config) error {
-	if _, err := os.Stat(configPath); os.IsNotExist(err) {
-		return nil // Keep default config
-	}
-
-	data, err := os.ReadFile(configPath)
-	if err != nil {
-		log.Printf("Error reading config: %v", err)
-		return nil // Keep default config, don't fail
-	}
-
-	if err := json.Unmarshal(data, config); err != nil {
-		log.Printf("Error parsing config: %v", err)
-		return nil // Keep default config, don't fail
-	}
-
-	return nil
-}
-
-// SaveConfig saves configuration to the specified file path
-func SaveConfig(configPath string, config *Config) error {
-	data, err := json.MarshalIndent(config, "", "  ")
-	if err != nil {
-		return fmt.Errorf("error marshaling config: %v", err)
-	}
-
-	if err := os.WriteFile(configPath, data, 0600); err != nil {
-		return fmt.Errorf("error writing config: %v", err)
-	}
-
-	return nil
-}
-
 // LoadServiceConfig loads the new multi-script configuration with backward compatibility
 func LoadServiceConfig(configPath string, config *ServiceConfig) error {
 	if _, err := os.Stat(configPath); os.IsNotExist(err) {
@@ -157,40 +155,47 @@ func LoadServiceConfig(configPath string, config *ServiceConfig) error {
 	}
 
 	// Try to parse as legacy format for backward compatibility
-	var legacyConfig Config
+	var legacyConfig LegacyConfig
 	if err := json.Unmarshal(data, &legacyConfig); err != nil {
 		log.Printf("Error parsing config: %v", err)
 		return nil // Keep default config, don't fail
 	}
 
-	// Convert legacy config to new format
-	config.Scripts = []ScriptConfig{
-		{
-			Name:        "main",
-			Path:        "./run.sh", // default script path
-			Interval:    legacyConfig.Interval,
-			Enabled:     true,
-			MaxLogLines: 100, // default
-			Timeout:     0,   // no timeout
-		},
-	}
-	if config.WebPort == 0 {
-		config.WebPort = 8080 // default
-	}
-
+	*config = convertLegacyConfig(legacyConfig)
 	return nil
 }
 
-// SaveServiceConfig saves the service configuration to file
+// SaveServiceConfig saves the service configuration to file on behalf of the CLI
 func SaveServiceConfig(configPath string, config *ServiceConfig) error {
+	return SaveServiceConfigWithOrigin(configPath, config, OriginCLI)
+}
+
+// SaveServiceConfigWithOrigin atomically saves the service configuration and records it in the history
+func SaveServiceConfigWithOrigin(configPath string, config *ServiceConfig, origin ConfigOrigin) error {
 	data, err := json.MarshalIndent(config, "", "  ")
 	if err != nil {
 		return fmt.Errorf("error marshaling config: %v", err)
 	}
 
-	if err := os.WriteFile(configPath, data, 0600); err != nil {
+	history := NewConfigHistory(configPath, config.ConfigHistoryLimit)
+
+	// Keep the pre-existing file as the first version so it can be rolled back to
+	if versions, listErr := history.List(); listErr == nil && len(versions) == 0 {
+		if previous, readErr := os.ReadFile(configPath); readErr == nil {
+			if _, recordErr := history.Record(previous, OriginInitial); recordErr != nil {
+				log.Printf("Failed to record config history: %v", recordErr)
+			}
+		}
+	}
+
+	if err := WriteFileAtomic(configPath, data, 0600); err != nil {
 		return fmt.Errorf("error writing config: %v", err)
 	}
 
+	// History is best effort, the config itself has been saved
+	if _, err := history.Record(data, origin); err != nil {
+		log.Printf("Failed to record config history: %v", err)
+	}
+
 	return nil
 }
@@ -0,0 +1,288 @@
+// Package service provides core functionality for the run-script-service daemon.
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// ConfigOrigin identifies what caused a configuration version to be written
+type ConfigOrigin string
+
+// Configuration origins recorded in the history
+const (
+	OriginInitial  ConfigOrigin = "initial"  // content found on disk before the first recorded save
+	OriginCLI      ConfigOrigin = "cli"      // written by a CLI command
+	OriginAPI      ConfigOrigin = "api"      // written through the web API
+	OriginReload   ConfigOrigin = "reload"   // picked up by the daemon when reloading the file
+	OriginRollback ConfigOrigin = "rollback" // restored from an earlier version
+	OriginMigrate  ConfigOrigin = "migrate"  // converted from the legacy single-script format
+)
+
+// ErrConfigVersionNotFound is wrapped by errors about versions missing from the history
+var ErrConfigVersionNotFound = errors.New("not found")
+
+// DefaultConfigHistoryLimit is the number of versions kept when config_history_limit is not set
+const DefaultConfigHistoryLimit = 20
+
+// ConfigVersion describes one entry of the configuration history
+type ConfigVersion struct {
+	Version   int          `json:"version"`
+	Timestamp time.Time    `json:"timestamp"`
+	Origin    ConfigOrigin `json:"origin"`
+	Checksum  string       `json:"checksum"`
+	Size      int          `json:"size"`
+}
+
+// ConfigHistory keeps versioned copies of the configuration file next to it
+type ConfigHistory struct {
+	configPath string
+	dir        string
+	limit      int
+	mutex      sync.Mutex
+}
+
+// configHistoryIndex is the on-disk index of recorded versions
+type configHistoryIndex struct {
+	NextVersion int             `json:"next_version"`
+	Versions    []ConfigVersion `json:"versions"`
+}
+
+// configHistoryLocks serializes history updates per config path across instances
+var configHistoryLocks sync.Map
+
+// NewConfigHistory creates a history for the given config file keeping at most limit versions
+func NewConfigHistory(configPath string, limit int) *ConfigHistory {
+	if limit <= 0 {
+		limit = DefaultConfigHistoryLimit
+	}
+	return &ConfigHistory{
+		configPath: configPath,
+		dir:        configPath + ".history",
+		limit:      limit,
+	}
+}
+
+// ConfigPath returns the configuration file the history belongs to
+func (h *ConfigHistory) ConfigPath() string {
+	return h.configPath
+}
+
+// lock acquires the process-wide lock for this config path
+func (h *ConfigHistory) lock() func() {
+	value, _ := configHistoryLocks.LoadOrStore(h.configPath, &sync.Mutex{})
+	mutex := value.(*sync.Mutex)
+	mutex.Lock()
+	return mutex.Unlock
+}
+
+// Record stores data as a new version unless it matches the latest recorded version
+func (h *ConfigHistory) Record(data []byte, origin ConfigOrigin) (*ConfigVersion, error) {
+	unlock := h.lock()
+	defer unlock()
+	return h.record(data, origin)
+}
+
+// record stores a version; the caller must hold the lock
+func (h *ConfigHistory) record(data []byte, origin ConfigOrigin) (*ConfigVersion, error) {
+	index, err := h.loadIndex()
+	if err != nil {
+		return nil, err
+	}
+
+	checksum := checksumOf(data)
+	if n := len(index.Versions); n > 0 && index.Versions[n-1].Checksum == checksum {
+		latest := index.Versions[n-1]
+		return &latest, nil
+	}
+
+	if err := os.MkdirAll(h.dir, 0750); err != nil {
+		return nil, fmt.Errorf("failed to create history directory: %v", err)
+	}
+
+	version := ConfigVersion{
+		Version:   index.NextVersion,
+		Timestamp: time.Now(),
+		Origin:    origin,
+		Checksum:  checksum,
+		Size:      len(data),
+	}
+	if err := WriteFileAtomic(h.versionPath(version.Version), data, 0600); err != nil {
+		return nil, fmt.Errorf("failed to write history version: %v", err)
+	}
+
+	index.NextVersion++
+	index.Versions = append(index.Versions, version)
+
+	// Prune the oldest versions beyond the limit
+	for len(index.Versions) > h.limit {
+		_ = os.Remove(h.versionPath(index.Versions[0].Version))
+		index.Versions = index.Versions[1:]
+	}
+
+	if err := h.saveIndex(index); err != nil {
+		return nil, err
+	}
+	return &version, nil
+}
+
+// List returns all recorded versions, oldest first
+func (h *ConfigHistory) List() ([]ConfigVersion, error) {
+	unlock := h.lock()
+	defer unlock()
+
+	index, err := h.loadIndex()
+	if err != nil {
+		return nil, err
+	}
+	return index.Versions, nil
+}
+
+// Get returns the content and metadata of a recorded version
+func (h *ConfigHistory) Get(version int) ([]byte, *ConfigVersion, error) {
+	unlock := h.lock()
+	defer unlock()
+	return h.get(version)
+}
+
+// get reads a version; the caller must hold the lock
+func (h *ConfigHistory) get(version int) ([]byte, *ConfigVersion, error) {
+	index, err := h.loadIndex()
+	if err != nil {
+		return nil, nil, err
+	}
+	for i := range index.Versions {
+		if index.Versions[i].Version == version {
+			data, err := os.ReadFile(h.versionPath(version))
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to read config version %d: %v", version, err)
+			}
+			return data, &index.Versions[i], nil
+		}
+	}
+	return nil, nil, fmt.Errorf("config version %d %w", version, ErrConfigVersionNotFound)
+}
+
+// Diff returns a line diff between two recorded versions
+func (h *ConfigHistory) Diff(from, to int) ([]DiffLine, error) {
+	unlock := h.lock()
+	defer unlock()
+
+	before, _, err := h.get(from)
+	if err != nil {
+		return nil, err
+	}
+	after, _, err := h.get(to)
+	if err != nil {
+		return nil, err
+	}
+	return DiffLines(string(before), string(after)), nil
+}
+
+// Rollback restores a recorded version to the config file and records it as a new version
+func (h *ConfigHistory) Rollback(version int) (*ConfigVersion, error) {
+	unlock := h.lock()
+	defer unlock()
+
+	data, _, err := h.get(version)
+	if err != nil {
+		return nil, err
+	}
+
+	// Refuse to restore something the service could not load
+	if issues := ValidateServiceConfigData(data, ValidationOptions{}); len(issues) > 0 {
+		return nil, &ConfigValidationError{ConfigPath: h.versionPath(version), Issues: issues}
+	}
+
+	if err := WriteFileAtomic(h.configPath, data, 0600); err != nil {
+		return nil, fmt.Errorf("error writing config: %v", err)
+	}
+	return h.record(data, OriginRollback)
+}
+
+// loadIndex reads the history index, returning an empty index if none exists
+func (h *ConfigHistory) loadIndex() (*configHistoryIndex, error) {
+	index := &configHistoryIndex{NextVersion: 1, Versions: make([]ConfigVersion, 0)}
+
+	data, err := os.ReadFile(filepath.Join(h.dir, "index.json"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return index, nil
+		}
+		return nil, fmt.Errorf("failed to read history index: %v", err)
+	}
+	if err := json.Unmarshal(data, index); err != nil {
+		return nil, fmt.Errorf("failed to parse history index: %v", err)
+	}
+	return index, nil
+}
+
+// saveIndex writes the history index atomically
+func (h *ConfigHistory) saveIndex(index *configHistoryIndex) error {
+	data, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal history index: %v", err)
+	}
+	return WriteFileAtomic(filepath.Join(h.dir, "index.json"), data, 0600)
+}
+
+// versionPath returns the file that stores a version's content
+func (h *ConfigHistory) versionPath(version int) string {
+	return filepath.Join(h.dir, fmt.Sprintf("v%06d.json", version))
+}
+
+// checksumOf returns the hex SHA-256 of data
+func checksumOf(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// atomicTempPrefix returns the path prefix of the temporary files WriteFileAtomic writes path with
+func atomicTempPrefix(path string) string {
+	return filepath.Join(filepath.Dir(path), "."+filepath.Base(path)+".tmp-")
+}
+
+// WriteFileAtomic writes data to a temporary file in the same directory and renames it into place,
+// so readers never observe a partially written file
+func WriteFileAtomic(path string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, filepath.Base(atomicTempPrefix(path))+"*")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+
+	cleanup := func() {
+		tmp.Close()
+		os.Remove(tmpName)
+	}
+
+	if _, err := tmp.Write(data); err != nil {
+		cleanup()
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		cleanup()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		cleanup()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+	if err := os.Rename(tmpName, path); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,167 @@
+package service
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestConfigHistory_RecordAndList(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	history := NewConfigHistory(configPath, 3)
+
+	v1, err := history.Record([]byte(`{"scripts": [], "web_port": 8080}`), OriginCLI)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if v1.Version != 1 || v1.Origin != OriginCLI {
+		t.Errorf("unexpected first version: %+v", v1)
+	}
+
+	// Identical content is not recorded twice
+	same, err := history.Record([]byte(`{"scripts": [], "web_port": 8080}`), OriginAPI)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if same.Version != 1 {
+		t.Errorf("expected duplicate content to return version 1, got %d", same.Version)
+	}
+
+	for _, port := range []int{8081, 8082, 8083} {
+		data, _ := json.Marshal(ServiceConfig{WebPort: port})
+		if _, err := history.Record(data, OriginAPI); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	versions, err := history.List()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(versions) != 3 {
+		t.Fatalf("expected history to be pruned to 3 versions, got %d", len(versions))
+	}
+	if versions[0].Version != 2 || versions[2].Version != 4 {
+		t.Errorf("expected versions 2..4, got %d..%d", versions[0].Version, versions[2].Version)
+	}
+	if _, _, err := history.Get(1); err == nil {
+		t.Error("expected pruned version 1 to be gone")
+	}
+}
+
+func TestConfigHistory_DiffAndRollback(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.json")
+
+	config := &ServiceConfig{
+		Scripts: []ScriptConfig{{Name: "a", Path: "./a.sh", Interval: 60, Enabled: true, MaxLogLines: 100}},
+		WebPort: 8080,
+	}
+	if err := SaveServiceConfig(configPath, config); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	config.Scripts[0].Interval = 120
+	if err := SaveServiceConfigWithOrigin(configPath, config, OriginAPI); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	history := NewConfigHistory(configPath, 0)
+	versions, err := history.List()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(versions) != 2 || versions[0].Origin != OriginCLI || versions[1].Origin != OriginAPI {
+		t.Fatalf("unexpected versions: %+v", versions)
+	}
+
+	diff, err := history.Diff(1, 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var removed, added int
+	for _, line := range diff {
+		switch line.Op {
+		case "-":
+			removed++
+		case "+":
+			added++
+		}
+	}
+	if removed != 1 || added != 1 {
+		t.Errorf("expected one changed line, got -%d +%d", removed, added)
+	}
+
+	restored, err := history.Rollback(1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if restored.Version != 3 || restored.Origin != OriginRollback {
+		t.Errorf("unexpected restored version: %+v", restored)
+	}
+
+	var loaded ServiceConfig
+	if err := LoadServiceConfig(configPath, &loaded); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if loaded.Scripts[0].Interval != 60 {
+		t.Errorf("expected interval 60 after rollback, got %d", loaded.Scripts[0].Interval)
+	}
+
+	if _, err := history.Rollback(42); err == nil {
+		t.Error("expected error rolling back to unknown version")
+	}
+}
+
+func TestSaveServiceConfig_RecordsExistingFileAsInitial(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(configPath, []byte(`{"scripts": [], "web_port": 9000}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := SaveServiceConfig(configPath, &ServiceConfig{WebPort: 9001}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	versions, err := NewConfigHistory(configPath, 0).List()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(versions) != 2 || versions[0].Origin != OriginInitial {
+		t.Errorf("expected initial snapshot followed by the save, got %+v", versions)
+	}
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "file.json")
+
+	if err := WriteFileAtomic(path, []byte("first"), 0600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := WriteFileAtomic(path, []byte("second"), 0600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "second" {
+		t.Errorf("expected second, got %q", data)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("expected mode 0600, got %v", info.Mode().Perm())
+	}
+
+	// No temporary files are left behind
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("expected only the target file, got %d entries", len(entries))
+	}
+}
@@ -0,0 +1,91 @@
+// Package service provides core functionality for the run-script-service daemon.
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// DefaultScriptName is the script a legacy single-script config is converted to
+const DefaultScriptName = "main"
+
+// DefaultScriptPath is the script file legacy single-script configs ran
+const DefaultScriptPath = "./run.sh"
+
+// LegacyBackupSuffix is appended to the config path to keep the original legacy file
+const LegacyBackupSuffix = ".legacy.bak"
+
+// convertLegacyConfig converts the old {"interval": N} format into a single-script ServiceConfig
+func convertLegacyConfig(legacy LegacyConfig) ServiceConfig {
+	return ServiceConfig{
+		Scripts: []ScriptConfig{DefaultScriptConfig(legacy.Interval)},
+		WebPort: DefaultWebPort,
+	}
+}
+
+// DefaultScriptConfig returns the configuration of the default run.sh script
+func DefaultScriptConfig(interval int) ScriptConfig {
+	return ScriptConfig{
+		Name:        DefaultScriptName,
+		Path:        DefaultScriptPath,
+		Interval:    interval,
+		Enabled:     true,
+		MaxLogLines: 100,
+		Timeout:     0,
+	}
+}
+
+// MigrateLegacyConfig rewrites a legacy single-script config file in the multi-script format.
+// The original file is kept next to it with LegacyBackupSuffix. It returns false when the
+// file is missing or already uses the current format, and an error when the legacy file
+// is invalid.
+func MigrateLegacyConfig(configPath string) (bool, error) {
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("error reading config: %v", err)
+	}
+
+	if !isLegacyConfigData(data) {
+		return false, nil
+	}
+	// A broken legacy file is reported rather than converted
+	if issues := ValidateServiceConfigData(data, ValidationOptions{}); len(issues) > 0 {
+		return false, &ConfigValidationError{ConfigPath: configPath, Issues: issues}
+	}
+
+	var legacy LegacyConfig
+	if err := json.Unmarshal(data, &legacy); err != nil {
+		return false, fmt.Errorf("error parsing legacy config: %v", err)
+	}
+
+	// Never replace an earlier backup, it holds the original file
+	backupPath := configPath + LegacyBackupSuffix
+	if _, err := os.Stat(backupPath); os.IsNotExist(err) {
+		if err := WriteFileAtomic(backupPath, data, 0600); err != nil {
+			return false, fmt.Errorf("error writing legacy config backup: %v", err)
+		}
+	}
+
+	config := convertLegacyConfig(legacy)
+	if err := SaveServiceConfigWithOrigin(configPath, &config, OriginMigrate); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// IsLegacyConfigFile reports whether the config file at configPath uses the legacy
+// single-script format
+func IsLegacyConfigFile(configPath string) bool {
+	data, err := os.ReadFile(configPath)
+	return err == nil && isLegacyConfigData(data)
+}
+
+// isLegacyConfigData reports whether raw configuration JSON uses the legacy format
+func isLegacyConfigData(data []byte) bool {
+	var rawMap map[string]interface{}
+	return json.Unmarshal(data, &rawMap) == nil && isLegacyConfig(rawMap)
+}
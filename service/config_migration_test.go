@@ -0,0 +1,93 @@
+package service
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMigrateLegacyConfig(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "service_config.json")
+	legacy := `{"interval": 1800}`
+	if err := os.WriteFile(configPath, []byte(legacy), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	migrated, err := MigrateLegacyConfig(configPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !migrated {
+		t.Fatal("expected legacy config to be migrated")
+	}
+
+	backup, err := os.ReadFile(configPath + LegacyBackupSuffix)
+	if err != nil || string(backup) != legacy {
+		t.Errorf("expected backup with original content, got %q (%v)", backup, err)
+	}
+
+	issues, err := ValidateServiceConfigFile(configPath, ValidationOptions{})
+	if err != nil || len(issues) != 0 {
+		t.Errorf("expected migrated config to be valid, got %v (%v)", issues, err)
+	}
+
+	var config ServiceConfig
+	if err := LoadServiceConfig(configPath, &config); err != nil {
+		t.Fatal(err)
+	}
+	if len(config.Scripts) != 1 || config.Scripts[0].Name != DefaultScriptName || config.Scripts[0].Interval != 1800 {
+		t.Errorf("unexpected migrated scripts: %+v", config.Scripts)
+	}
+
+	versions, err := NewConfigHistory(configPath, 0).List()
+	if err != nil || len(versions) == 0 || versions[len(versions)-1].Origin != OriginMigrate {
+		t.Errorf("expected migration to be recorded in history, got %+v (%v)", versions, err)
+	}
+
+	// Migration happens once
+	migrated, err = MigrateLegacyConfig(configPath)
+	if err != nil || migrated {
+		t.Errorf("expected second migration to be a no-op, got %v (%v)", migrated, err)
+	}
+}
+
+func TestMigrateLegacyConfig_NoOp(t *testing.T) {
+	dir := t.TempDir()
+
+	migrated, err := MigrateLegacyConfig(filepath.Join(dir, "missing.json"))
+	if err != nil || migrated {
+		t.Errorf("expected missing file to be ignored, got %v (%v)", migrated, err)
+	}
+
+	current := filepath.Join(dir, "current.json")
+	if err := os.WriteFile(current, []byte(`{"scripts": [], "web_port": 8080}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	migrated, err = MigrateLegacyConfig(current)
+	if err != nil || migrated {
+		t.Errorf("expected current format to be left alone, got %v (%v)", migrated, err)
+	}
+	if _, err := os.Stat(current + LegacyBackupSuffix); !os.IsNotExist(err) {
+		t.Error("expected no backup for a current-format config")
+	}
+}
+
+func TestMigrateLegacyConfig_Invalid(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "service_config.json")
+	if err := os.WriteFile(configPath, []byte(`{"interval": -60}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	migrated, err := MigrateLegacyConfig(configPath)
+	var validationErr *ConfigValidationError
+	if migrated || !errors.As(err, &validationErr) || validationErr.Issues[0].Path != "$.interval" {
+		t.Fatalf("expected the negative interval to be reported, got %v (%v)", migrated, err)
+	}
+	if !IsLegacyConfigFile(configPath) {
+		t.Error("expected the invalid legacy file to be left alone")
+	}
+	if _, err := os.Stat(configPath + LegacyBackupSuffix); !os.IsNotExist(err) {
+		t.Error("expected no backup when nothing was migrated")
+	}
+}
@@ -0,0 +1,130 @@
+// Package service provides core functionality for the run-script-service daemon.
+package service
+
+import (
+	"reflect"
+)
+
+// ConfigSchemaID is the identifier published in the configuration JSON Schema
+const ConfigSchemaID = "https://run-script-service/schemas/service_config.json"
+
+// schemaConstraints holds extra JSON Schema keywords keyed by "<Type>.<json field>"
+var schemaConstraints = map[string]map[string]interface{}{
+	"ServiceConfig.web_port":                   {"minimum": 0, "maximum": 65535},
+	"ServiceConfig.config_history_limit":       {"minimum": 0},
+	"ServiceConfig.log_level":                  {"enum": []string{"debug", "info", "warn", "error"}},
+	"ServiceConfig.shutdown_timeout":           {"minimum": 0, "description": "seconds runs in flight may finish on shutdown, 0 means 30"},
+	"ServiceConfig.run_workers":                {"minimum": 0, "description": "manual runs executing at once, 0 means 4"},
+	"ServiceConfig.run_queue_size":             {"minimum": 0, "description": "manual runs waiting for a worker, 0 means 100"},
+	"ServiceConfig.max_concurrent_runs":        {"minimum": 0, "description": "runs of all scripts executing at once, 0 means no limit"},
+	"ServiceConfig.resource_pools":             {"additionalProperties": map[string]interface{}{"type": "integer", "minimum": 1}, "description": "named limits of runs holding a slot at once"},
+	"RateLimitConfig.requests_per_minute":      {"minimum": 0, "description": "reads per client, 0 means 300"},
+	"RateLimitConfig.writes_per_minute":        {"minimum": 0, "description": "writes per client, 0 means 60"},
+	"RateLimitConfig.runs_per_minute":          {"minimum": 0, "description": "manual runs per client, 0 means 20"},
+	"RateLimitConfig.auth_failures_per_minute": {"minimum": 0, "description": "failed authentications per address, 0 means 10"},
+	"RateLimitConfig.max_body_bytes":           {"minimum": 0, "description": "request bodies, 0 means 1 MiB"},
+	"RateLimitConfig.max_upload_bytes":         {"minimum": 0, "description": "file uploads and imports, 0 means 10 MiB"},
+	"ScriptConfig.name":                        {"minLength": 1},
+	"ScriptConfig.path":                        {"minLength": 1},
+	"ScriptConfig.interval":                    {"minimum": 0, "description": "seconds between runs"},
+	"ScriptConfig.max_log_lines":               {"minimum": 0},
+	"ScriptConfig.timeout":                     {"minimum": 0, "description": "seconds, 0 means no limit"},
+	"RetentionPolicy.keep_runs":                {"minimum": 0, "description": "runs kept, 0 falls back to max_log_lines"},
+	"RetentionPolicy.keep_days":                {"minimum": 0, "description": "days runs are kept, 0 means no age limit"},
+	"RetentionPolicy.max_bytes":                {"minimum": 0, "description": "bytes on disk per script, 0 means no limit"},
+	"LogSinkConfig.name":                       {"minLength": 1},
+	"LogSinkConfig.type":                       {"enum": []string{LogSinkSyslog, LogSinkJournald}},
+	"LogSinkConfig.network":                    {"enum": []string{"unixgram", "unix", "udp", "tcp"}},
+	"OutputParserConfig.format":                {"enum": []string{OutputFormatJSON, OutputFormatKeyValue, OutputFormatRegex}},
+	"ScriptConfig.success_exit_codes":          {"description": "exit codes of successful runs, default [0]"},
+	"ScriptConfig.warning_exit_codes":          {"description": "exit codes of runs that completed with a warning"},
+	"ScriptConfig.priority":                    {"description": "runs waiting for a slot start highest priority first"},
+	"ArtifactPolicy.keep_runs":                 {"minimum": 0, "description": "runs whose artifacts are kept, default 10"},
+	"ArtifactPolicy.keep_days":                 {"minimum": 0, "description": "days artifacts are kept, 0 means no age limit"},
+	"ArtifactPolicy.max_file_bytes":            {"minimum": 0, "description": "larger files are skipped, default 10 MiB"},
+	"ArtifactPolicy.max_run_bytes":             {"minimum": 0, "description": "total bytes kept per run, default 100 MiB"},
+	"ArtifactPolicy.max_files":                 {"minimum": 0, "description": "files kept per run, default 100"},
+	"AuthConfig.methods":                       {"items": map[string]interface{}{"type": "string", "enum": []string{AuthMethodToken, AuthMethodBasic, AuthMethodSession, AuthMethodCertificate}}},
+	"AuthConfig.session_ttl":                   {"minimum": 0, "description": "minutes a login session lasts, 0 means 12 hours"},
+	"AuthGrant.role":                           {"enum": roles},
+	"TLSConfig.client_auth":                    {"enum": []string{ClientAuthRequire, ClientAuthOptional}},
+	"OutcomeRule.if":                           {"enum": []string{RuleStderrNotEmpty, RuleStdoutMatches, RuleStderrMatches}},
+	"OutcomeRule.outcome":                      {"enum": []string{OutcomeWarning, OutcomeFailure}},
+}
+
+// schemaRequired lists required properties per struct type
+var schemaRequired = map[string][]string{
+	"ScriptConfig":       {"name", "path"},
+	"LogSinkConfig":      {"name", "type"},
+	"OutputParserConfig": {"format"},
+	"ResultCondition":    {"field", "equals"},
+	"OutcomeRule":        {"if", "outcome"},
+	"AuthGrant":          {"role"},
+}
+
+// ConfigSchema returns a JSON Schema (draft 2020-12) describing service_config.json.
+// It is derived from the ServiceConfig struct so it stays in sync with the code.
+func ConfigSchema() map[string]interface{} {
+	schema := schemaForType(reflect.TypeOf(ServiceConfig{}))
+	schema["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	schema["$id"] = ConfigSchemaID
+	schema["title"] = "run-script-service configuration"
+	return schema
+}
+
+// schemaForType builds the schema fragment for a Go type
+func schemaForType(t reflect.Type) map[string]interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		properties := make(map[string]interface{})
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if field.PkgPath != "" {
+				continue
+			}
+			name := jsonFieldName(field)
+			if name == "-" {
+				continue
+			}
+			prop := schemaForType(field.Type)
+			for key, value := range schemaConstraints[t.Name()+"."+name] {
+				prop[key] = value
+			}
+			properties[name] = prop
+		}
+		schema := map[string]interface{}{
+			"type":                 "object",
+			"properties":           properties,
+			"additionalProperties": false,
+		}
+		if required, ok := schemaRequired[t.Name()]; ok {
+			schema["required"] = required
+		}
+		return schema
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{
+			"type":  "array",
+			"items": schemaForType(t.Elem()),
+		}
+	case reflect.Map:
+		return map[string]interface{}{
+			"type":                 "object",
+			"additionalProperties": schemaForType(t.Elem()),
+		}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	default:
+		return map[string]interface{}{}
+	}
+}
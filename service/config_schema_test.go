@@ -0,0 +1,41 @@
+package service
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestConfigSchema(t *testing.T) {
+	schema := ConfigSchema()
+
+	if schema["$id"] != ConfigSchemaID {
+		t.Errorf("expected $id %s, got %v", ConfigSchemaID, schema["$id"])
+	}
+	if schema["additionalProperties"] != false {
+		t.Error("expected top-level schema to reject unknown properties")
+	}
+
+	properties := schema["properties"].(map[string]interface{})
+	webPort := properties["web_port"].(map[string]interface{})
+	if webPort["type"] != "integer" || webPort["maximum"] != 65535 {
+		t.Errorf("unexpected web_port schema: %v", webPort)
+	}
+
+	scripts := properties["scripts"].(map[string]interface{})
+	items := scripts["items"].(map[string]interface{})
+	required := items["required"].([]string)
+	if len(required) != 2 || required[0] != "name" || required[1] != "path" {
+		t.Errorf("unexpected required script fields: %v", required)
+	}
+
+	scriptProps := items["properties"].(map[string]interface{})
+	for _, field := range []string{"name", "path", "interval", "enabled", "max_log_lines", "timeout"} {
+		if _, ok := scriptProps[field]; !ok {
+			t.Errorf("expected script property %s in schema", field)
+		}
+	}
+
+	if _, err := json.Marshal(schema); err != nil {
+		t.Errorf("schema should be serializable: %v", err)
+	}
+}
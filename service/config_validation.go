@@ -0,0 +1,614 @@
+// Package service provides core functionality for the run-script-service daemon.
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ConfigIssue describes a single problem found while validating a configuration
+type ConfigIssue struct {
+	Path    string `json:"path"` // JSON path such as $.scripts[0].name
+	Message string `json:"message"`
+}
+
+// ValidationOptions controls which checks ValidateServiceConfigData performs
+type ValidationOptions struct {
+	CheckFiles bool // verify that script files exist and are executable
+}
+
+// ConfigValidationError is returned by strict loading when the configuration has problems
+type ConfigValidationError struct {
+	ConfigPath string
+	Issues     []ConfigIssue
+}
+
+// Error implements the error interface
+func (e *ConfigValidationError) Error() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "invalid configuration %s: %d problem(s)", e.ConfigPath, len(e.Issues))
+	for _, issue := range e.Issues {
+		fmt.Fprintf(&sb, "\n  %s: %s", issue.Path, issue.Message)
+	}
+	return sb.String()
+}
+
+// ValidateServiceConfigFile validates the configuration file at configPath.
+// A missing file is valid because the service starts with defaults.
+func ValidateServiceConfigFile(configPath string, opts ValidationOptions) ([]ConfigIssue, error) {
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error reading config: %v", err)
+	}
+	return ValidateServiceConfigData(data, opts), nil
+}
+
+// ValidateServiceConfigData validates raw configuration JSON and reports every problem found
+func ValidateServiceConfigData(data []byte, opts ValidationOptions) []ConfigIssue {
+	issues := make([]ConfigIssue, 0)
+
+	var raw interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return append(issues, ConfigIssue{Path: "$", Message: describeJSONError(data, err)})
+	}
+
+	rawMap, ok := raw.(map[string]interface{})
+	if !ok {
+		return append(issues, ConfigIssue{Path: "$", Message: "configuration must be a JSON object"})
+	}
+
+	// Legacy single-script files only carry an interval
+	if isLegacyConfig(rawMap) {
+		issues = append(issues, checkUnknownFields(rawMap, reflect.TypeOf(LegacyConfig{}), "$")...)
+		var legacy LegacyConfig
+		if err := json.Unmarshal(data, &legacy); err != nil {
+			return append(issues, DecodeIssue(err))
+		}
+		if legacy.Interval < 0 {
+			issues = append(issues, ConfigIssue{Path: "$.interval", Message: "interval cannot be negative"})
+		}
+		return issues
+	}
+
+	issues = append(issues, checkUnknownFields(rawMap, reflect.TypeOf(ServiceConfig{}), "$")...)
+
+	var config ServiceConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		// Type mismatches stop further semantic checks
+		return append(issues, DecodeIssue(err))
+	}
+
+	return append(issues, validateServiceConfig(&config, opts)...)
+}
+
+// validateServiceConfig runs semantic checks on a decoded configuration
+func validateServiceConfig(config *ServiceConfig, opts ValidationOptions) []ConfigIssue {
+	var issues []ConfigIssue
+
+	if config.WebPort < 0 || config.WebPort > 65535 {
+		issues = append(issues, ConfigIssue{
+			Path:    "$.web_port",
+			Message: fmt.Sprintf("port %d must be between 1 and 65535 (0 selects the default)", config.WebPort),
+		})
+	}
+
+	if config.LogLevel != "" {
+		if _, err := ParseLogLevel(config.LogLevel); err != nil {
+			issues = append(issues, ConfigIssue{Path: "$.log_level", Message: err.Error()})
+		}
+	}
+
+	if config.ConfigHistoryLimit < 0 {
+		issues = append(issues, ConfigIssue{Path: "$.config_history_limit", Message: "config_history_limit cannot be negative"})
+	}
+	if config.ShutdownTimeout < 0 {
+		issues = append(issues, ConfigIssue{Path: "$.shutdown_timeout", Message: "shutdown_timeout cannot be negative"})
+	}
+	if config.RunWorkers < 0 {
+		issues = append(issues, ConfigIssue{Path: "$.run_workers", Message: "run_workers cannot be negative"})
+	}
+	if config.RunQueueSize < 0 {
+		issues = append(issues, ConfigIssue{Path: "$.run_queue_size", Message: "run_queue_size cannot be negative"})
+	}
+	if config.MaxConcurrentRuns < 0 {
+		issues = append(issues, ConfigIssue{Path: "$.max_concurrent_runs", Message: "max_concurrent_runs cannot be negative"})
+	}
+	pools := make([]string, 0, len(config.ResourcePools))
+	for name := range config.ResourcePools {
+		pools = append(pools, name)
+	}
+	sort.Strings(pools)
+	for _, name := range pools {
+		if name == "" {
+			issues = append(issues, ConfigIssue{Path: "$.resource_pools", Message: "resource pool name cannot be empty"})
+		} else if config.ResourcePools[name] < 1 {
+			issues = append(issues, ConfigIssue{
+				Path:    "$.resource_pools." + name,
+				Message: fmt.Sprintf("resource pool '%s' needs a size of at least 1", name),
+			})
+		}
+	}
+
+	issues = append(issues, validateRetention(config.LogRetention, "$.log_retention")...)
+	issues = append(issues, validateArtifactPolicy(config.ArtifactPolicy, "$.artifact_policy")...)
+	issues = append(issues, validateAuth(config.Auth)...)
+	issues = append(issues, validateTLS(config.TLS, config.BindAddress)...)
+	issues = append(issues, validateRateLimit(config.RateLimit)...)
+
+	sinks := make(map[string]bool)
+	for i, sink := range config.LogSinks {
+		prefix := fmt.Sprintf("$.log_sinks[%d]", i)
+		switch {
+		case sink.Name == "":
+			issues = append(issues, ConfigIssue{Path: prefix + ".name", Message: "log sink name cannot be empty"})
+		case sinks[sink.Name]:
+			issues = append(issues, ConfigIssue{Path: prefix + ".name", Message: fmt.Sprintf("duplicate log sink name '%s'", sink.Name)})
+		default:
+			sinks[sink.Name] = true
+			if _, err := NewLogSink(sink); err != nil {
+				issues = append(issues, ConfigIssue{Path: prefix, Message: err.Error()})
+			}
+		}
+	}
+
+	seen := make(map[string]int)
+	for i := range config.Scripts {
+		script := &config.Scripts[i]
+		prefix := fmt.Sprintf("$.scripts[%d]", i)
+
+		if script.Name == "" {
+			issues = append(issues, ConfigIssue{Path: prefix + ".name", Message: "script name cannot be empty"})
+		} else if first, dup := seen[script.Name]; dup {
+			issues = append(issues, ConfigIssue{
+				Path:    prefix + ".name",
+				Message: fmt.Sprintf("duplicate script name '%s' (first defined at $.scripts[%d])", script.Name, first),
+			})
+		} else {
+			seen[script.Name] = i
+		}
+
+		if script.Path == "" {
+			issues = append(issues, ConfigIssue{Path: prefix + ".path", Message: "script path cannot be empty"})
+		} else if opts.CheckFiles {
+			if msg := checkScriptFile(script.Path); msg != "" {
+				issues = append(issues, ConfigIssue{Path: prefix + ".path", Message: msg})
+			}
+		}
+
+		if script.Interval < 0 {
+			issues = append(issues, ConfigIssue{Path: prefix + ".interval", Message: "interval cannot be negative"})
+		}
+		if script.MaxLogLines < 0 {
+			issues = append(issues, ConfigIssue{Path: prefix + ".max_log_lines", Message: "max_log_lines cannot be negative"})
+		}
+		if script.Timeout < 0 {
+			issues = append(issues, ConfigIssue{Path: prefix + ".timeout", Message: "timeout cannot be negative"})
+		}
+		for j, tag := range script.Tags {
+			if tag == "" {
+				issues = append(issues, ConfigIssue{Path: fmt.Sprintf("%s.tags[%d]", prefix, j), Message: "tag cannot be empty"})
+			}
+		}
+		issues = append(issues, validateRetention(script.Retention, prefix+".retention")...)
+		if script.Output != nil {
+			if _, err := NewOutputParser(*script.Output); err != nil {
+				issues = append(issues, ConfigIssue{Path: prefix + ".output", Message: err.Error()})
+			}
+		}
+		issues = append(issues, validateOutcome(script, prefix)...)
+		for j, pattern := range script.Artifacts {
+			if err := ValidateArtifactPattern(pattern); err != nil {
+				issues = append(issues, ConfigIssue{Path: fmt.Sprintf("%s.artifacts[%d]", prefix, j), Message: err.Error()})
+			}
+		}
+		issues = append(issues, validateArtifactPolicy(script.ArtifactPolicy, prefix+".artifact_policy")...)
+		for j, name := range script.LogSinks {
+			if !sinks[name] {
+				issues = append(issues, ConfigIssue{
+					Path:    fmt.Sprintf("%s.log_sinks[%d]", prefix, j),
+					Message: fmt.Sprintf("unknown log sink '%s'", name),
+				})
+			}
+		}
+		listed := make(map[string]bool, len(script.Pools))
+		for j, name := range script.Pools {
+			if _, ok := config.ResourcePools[name]; !ok {
+				issues = append(issues, ConfigIssue{
+					Path:    fmt.Sprintf("%s.pools[%d]", prefix, j),
+					Message: fmt.Sprintf("unknown resource pool '%s'", name),
+				})
+			} else if listed[name] {
+				issues = append(issues, ConfigIssue{
+					Path:    fmt.Sprintf("%s.pools[%d]", prefix, j),
+					Message: fmt.Sprintf("resource pool '%s' listed twice", name),
+				})
+			}
+			listed[name] = true
+		}
+	}
+
+	return issues
+}
+
+// ValidateScript checks script as it would be stored in config, replacing the script of the
+// same name or appended. Issue paths are relative to the script, such as $.interval.
+func ValidateScript(config *ServiceConfig, script ScriptConfig) []ConfigIssue {
+	candidate := *config
+	candidate.Scripts = append([]ScriptConfig(nil), config.Scripts...)
+	index := indexOfScript(candidate.Scripts, script.Name)
+	if index < 0 {
+		index = len(candidate.Scripts)
+		candidate.Scripts = append(candidate.Scripts, script)
+	} else {
+		candidate.Scripts[index] = script
+	}
+
+	prefix := fmt.Sprintf("$.scripts[%d]", index)
+	var issues []ConfigIssue
+	for _, issue := range validateServiceConfig(&candidate, ValidationOptions{}) {
+		if rest, ok := strings.CutPrefix(issue.Path, prefix); ok && (rest == "" || rest[0] == '.' || rest[0] == '[') {
+			issues = append(issues, ConfigIssue{Path: "$" + rest, Message: issue.Message})
+		}
+	}
+	return issues
+}
+
+// validateOutcome checks the success exit codes, warning exit codes and outcome rules of a script
+func validateOutcome(script *ScriptConfig, prefix string) []ConfigIssue {
+	var issues []ConfigIssue
+	success := script.SuccessExitCodes
+	if len(success) == 0 {
+		success = []int{0}
+	}
+	for j, code := range script.SuccessExitCodes {
+		if code < 0 || code > 255 {
+			issues = append(issues, ConfigIssue{
+				Path:    fmt.Sprintf("%s.success_exit_codes[%d]", prefix, j),
+				Message: fmt.Sprintf("exit code %d is out of range (0-255)", code),
+			})
+		}
+	}
+	for j, code := range script.WarningExitCodes {
+		path := fmt.Sprintf("%s.warning_exit_codes[%d]", prefix, j)
+		if code < 0 || code > 255 {
+			issues = append(issues, ConfigIssue{Path: path, Message: fmt.Sprintf("exit code %d is out of range (0-255)", code)})
+		} else if containsExitCode(success, code) {
+			issues = append(issues, ConfigIssue{Path: path, Message: fmt.Sprintf("exit code %d is also a success exit code", code)})
+		}
+	}
+	for j, rule := range script.OutcomeRules {
+		if _, err := compileOutcomeRule(rule); err != nil {
+			issues = append(issues, ConfigIssue{Path: fmt.Sprintf("%s.outcome_rules[%d]", prefix, j), Message: err.Error()})
+		}
+	}
+	return issues
+}
+
+// validateAuth checks the authentication methods, session lifetime, WebSocket origins and grants
+func validateAuth(auth *AuthConfig) []ConfigIssue {
+	if auth == nil {
+		return nil
+	}
+
+	var issues []ConfigIssue
+	for i, method := range auth.Methods {
+		switch method {
+		case AuthMethodToken, AuthMethodBasic, AuthMethodSession, AuthMethodCertificate:
+		default:
+			issues = append(issues, ConfigIssue{
+				Path:    fmt.Sprintf("$.auth.methods[%d]", i),
+				Message: fmt.Sprintf("invalid authentication method '%s' (expected token, basic, session or certificate)", method),
+			})
+		}
+	}
+	if auth.SessionTTL < 0 {
+		issues = append(issues, ConfigIssue{Path: "$.auth.session_ttl", Message: "session_ttl cannot be negative"})
+	}
+	for i, origin := range auth.AllowedOrigins {
+		if u, err := url.Parse(origin); err != nil || u.Scheme == "" || u.Host == "" || (u.Path != "" && u.Path != "/") {
+			issues = append(issues, ConfigIssue{
+				Path:    fmt.Sprintf("$.auth.allowed_origins[%d]", i),
+				Message: fmt.Sprintf("invalid origin '%s' (expected scheme://host[:port])", origin),
+			})
+		}
+	}
+	for i, grant := range auth.Grants {
+		prefix := fmt.Sprintf("$.auth.grants[%d]", i)
+		if (grant.User == "") == (grant.Token == "") {
+			issues = append(issues, ConfigIssue{Path: prefix, Message: "grant needs exactly one of user or token"})
+		}
+		if err := ValidateRole(grant.Role); err != nil {
+			issues = append(issues, ConfigIssue{Path: prefix + ".role", Message: err.Error()})
+		}
+		if len(grant.Scripts) == 0 && len(grant.Tags) == 0 {
+			issues = append(issues, ConfigIssue{Path: prefix, Message: "grant needs scripts or tags"})
+		}
+	}
+	return issues
+}
+
+// validateTLS checks that certificate files are configured and the client certificate mode
+func validateTLS(config *TLSConfig, bindAddress string) []ConfigIssue {
+	if config == nil {
+		return nil
+	}
+
+	var issues []ConfigIssue
+	if !config.SelfSigned && (config.CertFile == "") != (config.KeyFile == "") {
+		issues = append(issues, ConfigIssue{Path: "$.tls", Message: "cert_file and key_file must be set together"})
+	}
+	switch config.ClientAuth {
+	case "", ClientAuthRequire, ClientAuthOptional:
+	default:
+		issues = append(issues, ConfigIssue{
+			Path:    "$.tls.client_auth",
+			Message: fmt.Sprintf("invalid client_auth '%s' (expected require or optional)", config.ClientAuth),
+		})
+	}
+	if config.ClientAuth != "" && config.ClientCAFile == "" {
+		issues = append(issues, ConfigIssue{Path: "$.tls.client_auth", Message: "client_auth needs client_ca_file"})
+	}
+	if _, ok := UnixSocketPath(bindAddress); ok {
+		issues = append(issues, ConfigIssue{Path: "$.tls", Message: "TLS is not used on a unix socket bind address"})
+	}
+	return issues
+}
+
+// validateRateLimit checks that the request limits are not negative
+func validateRateLimit(config *RateLimitConfig) []ConfigIssue {
+	if config == nil {
+		return nil
+	}
+
+	var issues []ConfigIssue
+	limits := []struct {
+		field string
+		value int64
+	}{
+		{"requests_per_minute", int64(config.RequestsPerMinute)},
+		{"writes_per_minute", int64(config.WritesPerMinute)},
+		{"runs_per_minute", int64(config.RunsPerMinute)},
+		{"auth_failures_per_minute", int64(config.AuthFailuresPerMinute)},
+		{"max_body_bytes", config.MaxBodyBytes},
+		{"max_upload_bytes", config.MaxUploadBytes},
+	}
+	for _, limit := range limits {
+		if limit.value < 0 {
+			issues = append(issues, ConfigIssue{Path: "$.rate_limit." + limit.field, Message: limit.field + " cannot be negative"})
+		}
+	}
+	return issues
+}
+
+// validateArtifactPolicy checks that an artifact policy has no negative limits
+func validateArtifactPolicy(policy *ArtifactPolicy, prefix string) []ConfigIssue {
+	if policy == nil {
+		return nil
+	}
+
+	var issues []ConfigIssue
+	limits := []struct {
+		field string
+		value int64
+	}{
+		{"keep_runs", int64(policy.KeepRuns)},
+		{"keep_days", int64(policy.KeepDays)},
+		{"max_file_bytes", policy.MaxFileBytes},
+		{"max_run_bytes", policy.MaxRunBytes},
+		{"max_files", int64(policy.MaxFiles)},
+	}
+	for _, limit := range limits {
+		if limit.value < 0 {
+			issues = append(issues, ConfigIssue{Path: prefix + "." + limit.field, Message: limit.field + " cannot be negative"})
+		}
+	}
+	return issues
+}
+
+// validateRetention checks that a retention policy has no negative limits
+func validateRetention(policy *RetentionPolicy, prefix string) []ConfigIssue {
+	if policy == nil {
+		return nil
+	}
+
+	var issues []ConfigIssue
+	if policy.KeepRuns < 0 {
+		issues = append(issues, ConfigIssue{Path: prefix + ".keep_runs", Message: "keep_runs cannot be negative"})
+	}
+	if policy.KeepDays < 0 {
+		issues = append(issues, ConfigIssue{Path: prefix + ".keep_days", Message: "keep_days cannot be negative"})
+	}
+	if policy.MaxBytes < 0 {
+		issues = append(issues, ConfigIssue{Path: prefix + ".max_bytes", Message: "max_bytes cannot be negative"})
+	}
+	return issues
+}
+
+// checkScriptFile returns a description of why the script file is unusable, or an empty string
+func checkScriptFile(path string) string {
+	scriptPath := path
+	if !filepath.IsAbs(scriptPath) {
+		workDir, err := os.Getwd()
+		if err != nil {
+			return fmt.Sprintf("unable to get working directory: %v", err)
+		}
+		scriptPath = filepath.Join(workDir, path)
+	}
+
+	info, err := os.Stat(scriptPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Sprintf("script file does not exist: %s", path)
+		}
+		return fmt.Sprintf("unable to access script file %s: %v", path, err)
+	}
+	if info.IsDir() {
+		return fmt.Sprintf("script path is a directory, not a file: %s", path)
+	}
+	if info.Mode()&0111 == 0 {
+		return fmt.Sprintf("script file is not executable: %s (mode: %v)", path, info.Mode())
+	}
+	return ""
+}
+
+// isLegacyConfig reports whether a raw config uses the old {"interval": N} format
+func isLegacyConfig(rawMap map[string]interface{}) bool {
+	_, hasScripts := rawMap["scripts"]
+	_, hasWebPort := rawMap["web_port"]
+	_, hasInterval := rawMap["interval"]
+	return !hasScripts && !hasWebPort && hasInterval
+}
+
+// checkUnknownFields walks raw JSON against a struct type and reports keys without a matching json tag
+func checkUnknownFields(raw interface{}, t reflect.Type, path string) []ConfigIssue {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	var issues []ConfigIssue
+	switch t.Kind() {
+	case reflect.Struct:
+		obj, ok := raw.(map[string]interface{})
+		if !ok {
+			return nil // type mismatches are reported by the decoder
+		}
+		fields := jsonFields(t)
+		keys := make([]string, 0, len(obj))
+		for key := range obj {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			fieldType, known := fields[key]
+			if !known {
+				issues = append(issues, ConfigIssue{
+					Path:    path + "." + key,
+					Message: fmt.Sprintf("unknown field '%s'", key),
+				})
+				continue
+			}
+			issues = append(issues, checkUnknownFields(obj[key], fieldType, path+"."+key)...)
+		}
+	case reflect.Slice:
+		items, ok := raw.([]interface{})
+		if !ok {
+			return nil
+		}
+		for i, item := range items {
+			issues = append(issues, checkUnknownFields(item, t.Elem(), fmt.Sprintf("%s[%d]", path, i))...)
+		}
+	case reflect.Map:
+		obj, ok := raw.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		keys := make([]string, 0, len(obj))
+		for key := range obj {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			issues = append(issues, checkUnknownFields(obj[key], t.Elem(), path+"."+key)...)
+		}
+	}
+	return issues
+}
+
+// jsonFields maps the JSON names of a struct's exported fields to their types
+func jsonFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		name := jsonFieldName(field)
+		if name == "-" {
+			continue
+		}
+		fields[name] = field.Type
+	}
+	return fields
+}
+
+// jsonFieldName returns the name encoding/json uses for a struct field
+func jsonFieldName(field reflect.StructField) string {
+	tag := field.Tag.Get("json")
+	if tag == "" {
+		return field.Name
+	}
+	name := strings.Split(tag, ",")[0]
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
+
+// DecodeIssue converts a JSON decoding error into a ConfigIssue, with the path of the field
+// for type mismatches and $ otherwise
+func DecodeIssue(err error) ConfigIssue {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		return ConfigIssue{
+			Path:    jsonPathFromField(typeErr.Field),
+			Message: fmt.Sprintf("expected %s, got %s", typeErr.Type.String(), typeErr.Value),
+		}
+	}
+	return ConfigIssue{Path: "$", Message: err.Error()}
+}
+
+// jsonPathFromField converts a decoder field path like "scripts.0.interval" into "$.scripts[0].interval"
+func jsonPathFromField(field string) string {
+	var sb strings.Builder
+	sb.WriteString("$")
+	for _, part := range strings.Split(field, ".") {
+		if _, err := strconv.Atoi(part); err == nil {
+			fmt.Fprintf(&sb, "[%s]", part)
+		} else {
+			sb.WriteString("." + part)
+		}
+	}
+	return sb.String()
+}
+
+// describeJSONError adds line and column information to JSON syntax errors
+func describeJSONError(data []byte, err error) string {
+	var syntaxErr *json.SyntaxError
+	if !errors.As(err, &syntaxErr) {
+		return fmt.Sprintf("invalid JSON: %v", err)
+	}
+	line, col := 1, 1
+	for i := int64(0); i < syntaxErr.Offset-1 && i < int64(len(data)); i++ {
+		if data[i] == '\n' {
+			line++
+			col = 1
+		} else {
+			col++
+		}
+	}
+	return fmt.Sprintf("invalid JSON at line %d, column %d: %v", line, col, err)
+}
+
+// LoadServiceConfigStrict loads the configuration and fails if any validation problem is found,
+// instead of falling back to defaults like LoadServiceConfig does
+func LoadServiceConfigStrict(configPath string, config *ServiceConfig) error {
+	issues, err := ValidateServiceConfigFile(configPath, ValidationOptions{CheckFiles: true})
+	if err != nil {
+		return err
+	}
+	if len(issues) > 0 {
+		return &ConfigValidationError{ConfigPath: configPath, Issues: issues}
+	}
+	return LoadServiceConfig(configPath, config)
+}
@@ -0,0 +1,255 @@
+package service
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidateServiceConfigData(t *testing.T) {
+	tests := []struct {
+		name          string
+		content       string
+		expectedPaths []string
+	}{
+		{
+			name: "valid config",
+			content: `{
+				"scripts": [{"name": "a", "path": "./a.sh", "interval": 60, "enabled": true, "max_log_lines": 100, "timeout": 0}],
+				"web_port": 8080
+			}`,
+			expectedPaths: nil,
+		},
+		{
+			name:          "legacy config",
+			content:       `{"interval": 1800}`,
+			expectedPaths: nil,
+		},
+		{
+			name:          "invalid json",
+			content:       `{"scripts": [}`,
+			expectedPaths: []string{"$"},
+		},
+		{
+			name: "unknown fields",
+			content: `{
+				"scripts": [{"name": "a", "path": "./a.sh", "intervall": 60}],
+				"web_prot": 8080
+			}`,
+			expectedPaths: []string{"$.scripts[0].intervall", "$.web_prot"},
+		},
+		{
+			name: "duplicate names",
+			content: `{
+				"scripts": [
+					{"name": "a", "path": "./a.sh"},
+					{"name": "a", "path": "./b.sh"}
+				]
+			}`,
+			expectedPaths: []string{"$.scripts[1].name"},
+		},
+		{
+			name:          "bad port",
+			content:       `{"scripts": [], "web_port": 70000}`,
+			expectedPaths: []string{"$.web_port"},
+		},
+		{
+			name:          "bad log level",
+			content:       `{"scripts": [], "log_level": "verbose"}`,
+			expectedPaths: []string{"$.log_level"},
+		},
+		{
+			name:          "negative retention",
+			content:       `{"scripts": [{"name": "a", "path": "./a.sh", "retention": {"keep_days": -1}}], "log_retention": {"max_bytes": -5, "keep_runs": 10}}`,
+			expectedPaths: []string{"$.log_retention.max_bytes", "$.scripts[0].retention.keep_days"},
+		},
+		{
+			name: "bad log sinks",
+			content: `{"scripts": [{"name": "a", "path": "./a.sh", "log_sinks": ["central", "missing"]}],
+				"log_sinks": [{"name": "central", "type": "syslog", "network": "udp", "address": "logs:514"},
+					{"name": "central", "type": "journald"}, {"name": "x", "type": "syslog", "network": "tcp"},
+					{"name": "y", "type": "gelf"}]}`,
+			expectedPaths: []string{"$.log_sinks[1].name", "$.log_sinks[2]", "$.log_sinks[3]", "$.scripts[0].log_sinks[1]"},
+		},
+		{
+			name: "bad success criteria",
+			content: `{"scripts": [{"name": "a", "path": "./a.sh", "success_exit_codes": [0, 300], "warning_exit_codes": [0, 2],
+				"outcome_rules": [{"if": "stderr_not_empty", "outcome": "warning"}, {"if": "stdout_matches", "pattern": "(", "outcome": "failure"}]}]}`,
+			expectedPaths: []string{"$.scripts[0].success_exit_codes[1]", "$.scripts[0].warning_exit_codes[0]", "$.scripts[0].outcome_rules[1]"},
+		},
+		{
+			name: "bad artifacts",
+			content: `{"scripts": [{"name": "a", "path": "./a.sh", "artifacts": ["out/*.html", "../x", ""],
+				"artifact_policy": {"max_run_bytes": -1}}], "artifact_policy": {"keep_runs": -2}}`,
+			expectedPaths: []string{"$.artifact_policy.keep_runs", "$.scripts[0].artifacts[1]", "$.scripts[0].artifacts[2]", "$.scripts[0].artifact_policy.max_run_bytes"},
+		},
+		{
+			name: "bad auth",
+			content: `{"scripts": [], "auth": {"methods": ["token", "oauth"], "session_ttl": -1,
+				"allowed_origins": ["https://ops.example.com", "ops.example.com", "https://ops.example.com/ui"]}}`,
+			expectedPaths: []string{"$.auth.methods[1]", "$.auth.session_ttl", "$.auth.allowed_origins[1]", "$.auth.allowed_origins[2]"},
+		},
+		{
+			name:          "negative shutdown timeout",
+			content:       `{"scripts": [], "shutdown_timeout": -5}`,
+			expectedPaths: []string{"$.shutdown_timeout"},
+		},
+		{
+			name:          "negative run queue",
+			content:       `{"scripts": [], "run_workers": -1, "run_queue_size": -1}`,
+			expectedPaths: []string{"$.run_workers", "$.run_queue_size"},
+		},
+		{
+			name: "bad run limits",
+			content: `{"max_concurrent_runs": -1, "resource_pools": {"db": 2, "api": 0},
+				"scripts": [{"name": "a", "path": "./a.sh", "pools": ["db", "cache"]},
+					{"name": "b", "path": "./b.sh", "pools": ["db", "db"]}]}`,
+			expectedPaths: []string{"$.max_concurrent_runs", "$.resource_pools.api", "$.scripts[0].pools[1]", "$.scripts[1].pools[1]"},
+		},
+		{
+			name:          "negative rate limits",
+			content:       `{"scripts": [], "rate_limit": {"runs_per_minute": -1, "auth_failures_per_minute": -1, "max_body_bytes": -1}}`,
+			expectedPaths: []string{"$.rate_limit.runs_per_minute", "$.rate_limit.auth_failures_per_minute", "$.rate_limit.max_body_bytes"},
+		},
+		{
+			name: "bad tls",
+			content: `{"scripts": [], "bind_address": "unix:/run/rss.sock",
+				"tls": {"cert_file": "cert.pem", "client_auth": "sometimes"}}`,
+			expectedPaths: []string{"$.tls", "$.tls.client_auth", "$.tls.client_auth", "$.tls"},
+		},
+		{
+			name: "bad grants",
+			content: `{"scripts": [{"name": "a", "path": "./a.sh", "tags": ["reports", ""]}],
+				"auth": {"grants": [{"user": "bob", "role": "editor", "tags": ["reports"]}, {"user": "bob", "token": "ci", "role": "root"},
+					{"token": "ci", "role": "operator"}]}}`,
+			expectedPaths: []string{"$.auth.grants[1]", "$.auth.grants[1].role", "$.auth.grants[1]", "$.auth.grants[2]", "$.scripts[0].tags[1]"},
+		},
+		{
+			name:          "wrong type",
+			content:       `{"scripts": [], "web_port": "8080"}`,
+			expectedPaths: []string{"$.web_port"},
+		},
+		{
+			name: "multiple problems in one script",
+			content: `{
+				"scripts": [{"name": "", "path": "", "interval": -1, "timeout": -5}]
+			}`,
+			expectedPaths: []string{"$.scripts[0].name", "$.scripts[0].path", "$.scripts[0].interval", "$.scripts[0].timeout"},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			issues := ValidateServiceConfigData([]byte(tt.content), ValidationOptions{})
+
+			if len(issues) != len(tt.expectedPaths) {
+				t.Fatalf("expected %d issues, got %d: %+v", len(tt.expectedPaths), len(issues), issues)
+			}
+			for i, path := range tt.expectedPaths {
+				if issues[i].Path != path {
+					t.Errorf("issue %d: expected path %s, got %s (%s)", i, path, issues[i].Path, issues[i].Message)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateServiceConfigData_CheckFiles(t *testing.T) {
+	tempDir := t.TempDir()
+
+	executable := filepath.Join(tempDir, "ok.sh")
+	if err := os.WriteFile(executable, []byte("#!/bin/bash\necho ok\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	notExecutable := filepath.Join(tempDir, "noexec.sh")
+	if err := os.WriteFile(notExecutable, []byte("#!/bin/bash\necho ok\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	content := `{"scripts": [
+		{"name": "ok", "path": "` + executable + `"},
+		{"name": "noexec", "path": "` + notExecutable + `"},
+		{"name": "missing", "path": "` + filepath.Join(tempDir, "missing.sh") + `"}
+	]}`
+
+	issues := ValidateServiceConfigData([]byte(content), ValidationOptions{CheckFiles: true})
+	if len(issues) != 2 {
+		t.Fatalf("expected 2 issues, got %d: %+v", len(issues), issues)
+	}
+	if issues[0].Path != "$.scripts[1].path" || !strings.Contains(issues[0].Message, "not executable") {
+		t.Errorf("unexpected issue: %+v", issues[0])
+	}
+	if issues[1].Path != "$.scripts[2].path" || !strings.Contains(issues[1].Message, "does not exist") {
+		t.Errorf("unexpected issue: %+v", issues[1])
+	}
+}
+
+func TestLoadServiceConfigStrict(t *testing.T) {
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "config.json")
+
+	// Missing file keeps defaults
+	config := ServiceConfig{WebPort: 8080}
+	if err := LoadServiceConfigStrict(configPath, &config); err != nil {
+		t.Fatalf("unexpected error for missing file: %v", err)
+	}
+
+	// Lenient loading silently keeps defaults, strict loading fails
+	if err := os.WriteFile(configPath, []byte(`{"scripts": [{"name": "a", "path": "./a.sh", "enabeld": true}]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	err := LoadServiceConfigStrict(configPath, &config)
+	var validationErr *ConfigValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("expected ConfigValidationError, got %v", err)
+	}
+	if len(validationErr.Issues) != 2 {
+		t.Errorf("expected unknown field and missing file issues, got %+v", validationErr.Issues)
+	}
+	if !strings.Contains(err.Error(), "$.scripts[0].enabeld") {
+		t.Errorf("expected error message to include JSON path, got %q", err.Error())
+	}
+}
+
+func TestJSONPathFromField(t *testing.T) {
+	tests := map[string]string{
+		"web_port":           "$.web_port",
+		"scripts.0.interval": "$.scripts[0].interval",
+		"scripts.interval":   "$.scripts.interval",
+	}
+	for field, expected := range tests {
+		if got := jsonPathFromField(field); got != expected {
+			t.Errorf("jsonPathFromField(%q) = %q, expected %q", field, got, expected)
+		}
+	}
+}
+
+func TestValidateScript(t *testing.T) {
+	config := &ServiceConfig{Scripts: []ScriptConfig{{Name: "backup", Path: "./backup.sh", Interval: 60}}}
+
+	issues := ValidateScript(config, ScriptConfig{Name: "report", Path: "./report.sh", Interval: -1, Tags: []string{""}, LogSinks: []string{"syslog"}})
+	paths := make(map[string]bool)
+	for _, issue := range issues {
+		paths[issue.Path] = true
+	}
+	for _, path := range []string{"$.interval", "$.tags[0]", "$.log_sinks[0]"} {
+		if !paths[path] {
+			t.Errorf("Expected an issue at %s, got %v", path, issues)
+		}
+	}
+	if len(issues) != 3 {
+		t.Errorf("Expected issues of the new script only, got %v", issues)
+	}
+
+	// Replacing a script does not report its name as a duplicate
+	if issues := ValidateScript(config, ScriptConfig{Name: "backup", Path: "./other.sh"}); len(issues) != 0 {
+		t.Errorf("Expected a valid update, got %v", issues)
+	}
+	if len(config.Scripts) != 1 || config.Scripts[0].Path != "./backup.sh" {
+		t.Errorf("Expected the configuration to be unchanged, got %+v", config.Scripts)
+	}
+}
@@ -5,13 +5,21 @@ import (
 	"time"
 )
 
+// StatusOutput is the status of events carrying one line of live script output
+const StatusOutput = "output"
+
+// StatusWarning is the status of runs that finished with OutcomeWarning
+const StatusWarning = "warning"
+
 // ScriptStatusEvent represents a script status change event
 type ScriptStatusEvent struct {
 	ScriptName string    `json:"script_name"`
-	Status     string    `json:"status"` // "starting", "running", "completed", "failed"
+	Status     string    `json:"status"` // "starting", "running", "completed", StatusWarning, "failed" or StatusOutput
 	ExitCode   int       `json:"exit_code"`
 	Duration   int64     `json:"duration"` // Duration in milliseconds
 	Timestamp  time.Time `json:"timestamp"`
+	Stream     string    `json:"stream,omitempty"` // StreamStdout or StreamStderr of an output event
+	Line       string    `json:"line,omitempty"`   // output line without its newline
 }
 
 // NewScriptStatusEvent creates a new script status event
@@ -25,6 +33,17 @@ func NewScriptStatusEvent(scriptName, status string, exitCode int, duration int6
 	}
 }
 
+// NewScriptOutputEvent creates an event for one line a running script wrote
+func NewScriptOutputEvent(scriptName, stream, line string) *ScriptStatusEvent {
+	return &ScriptStatusEvent{
+		ScriptName: scriptName,
+		Status:     StatusOutput,
+		Timestamp:  time.Now(),
+		Stream:     stream,
+		Line:       line,
+	}
+}
+
 // ToJSON converts the event to a JSON-compatible map
 func (e *ScriptStatusEvent) ToJSON() map[string]interface{} {
 	return map[string]interface{}{
@@ -22,11 +22,15 @@ type ExecutionResult struct {
 	Timestamp time.Time
 }
 
+// OutputHandler receives each line a script writes while it runs
+type OutputHandler func(stream, line string)
+
 // Executor handles script execution and logging
 type Executor struct {
-	scriptPath string
-	logPath    string
-	maxLines   int
+	scriptPath    string
+	logPath       string
+	maxLines      int
+	outputHandler OutputHandler
 }
 
 // NewExecutor creates a new script executor
@@ -38,6 +42,11 @@ func NewExecutor(scriptPath, logPath string, maxLines int) *Executor {
 	}
 }
 
+// SetOutputHandler sets a handler called with every output line as the script runs
+func (e *Executor) SetOutputHandler(handler OutputHandler) {
+	e.outputHandler = handler
+}
+
 // ExecuteScript executes the configured script and logs the results
 func (e *Executor) ExecuteScript(args ...string) *ExecutionResult {
 	// Use context with timeout for backward compatibility
@@ -59,6 +68,11 @@ func (e *Executor) ExecuteScriptWithContext(ctx context.Context, args ...string)
 	cmd.SysProcAttr = &syscall.SysProcAttr{
 		Setpgid: true,
 	}
+	// A cancelled run kills the whole group, so children holding the output pipes end too
+	// and the output written so far is kept
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
 
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
@@ -99,10 +113,19 @@ func (e *Executor) ExecuteScriptWithContext(ctx context.Context, args ...string)
 		}
 	}()
 
-	stdoutBytes, _ := io.ReadAll(stdout)
-	stderrBytes, _ := io.ReadAll(stderr)
+	// Read both pipes at once so a script filling one of them cannot block
+	var stdoutBuf, stderrBuf strings.Builder
+	done := make(chan struct{})
+	go func() {
+		e.readOutput(stderr, StreamStderr, &stderrBuf)
+		close(done)
+	}()
+	e.readOutput(stdout, StreamStdout, &stdoutBuf)
+	<-done
 
 	err = cmd.Wait()
+	result.Stdout = strings.TrimSpace(stdoutBuf.String())
+	result.Stderr = strings.TrimSpace(stderrBuf.String())
 	result.ExitCode = 0
 	if err != nil {
 		if exitError, ok := err.(*exec.ExitError); ok {
@@ -114,21 +137,20 @@ func (e *Executor) ExecuteScriptWithContext(ctx context.Context, args ...string)
 		}
 	}
 
-	result.Stdout = strings.TrimSpace(string(stdoutBytes))
-	result.Stderr = strings.TrimSpace(string(stderrBytes))
-
 	// Write to log only if logPath is specified
 	if e.logPath != "" {
-		logEntry := fmt.Sprintf("[%s] Exit code: %d\n", timestamp.Format("2006-01-02 15:04:05"), result.ExitCode)
-		if result.Stdout != "" {
-			logEntry += fmt.Sprintf("STDOUT: %s\n", result.Stdout)
-		}
-		if result.Stderr != "" {
-			logEntry += fmt.Sprintf("STDERR: %s\n", result.Stderr)
+		record, err := MarshalLogRecord(&LogEntry{
+			Timestamp:  timestamp,
+			ScriptName: e.scriptName(),
+			ExitCode:   result.ExitCode,
+			Stdout:     result.Stdout,
+			Stderr:     result.Stderr,
+			Duration:   time.Since(timestamp).Milliseconds(),
+		})
+		if err == nil {
+			err = e.WriteLog(string(record))
 		}
-		logEntry += strings.Repeat("-", 50) + "\n"
-
-		if err := e.WriteLog(logEntry); err != nil {
+		if err != nil {
 			fmt.Printf("Error writing to log: %v\n", err)
 		}
 
@@ -140,18 +162,50 @@ func (e *Executor) ExecuteScriptWithContext(ctx context.Context, args ...string)
 	return result
 }
 
+// readOutput copies a pipe into buf, passing complete lines to the output handler
+func (e *Executor) readOutput(r io.Reader, stream string, buf *strings.Builder) {
+	if e.outputHandler == nil {
+		_, _ = io.Copy(buf, r)
+		return
+	}
+
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadString('\n')
+		buf.WriteString(line)
+		if line != "" {
+			e.outputHandler(stream, strings.TrimRight(line, "\r\n"))
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
 // logError logs an error message
 func (e *Executor) logError(timestamp time.Time, message string) {
 	if e.logPath != "" {
-		errorMsg := fmt.Sprintf("[%s] ERROR: %s\n%s\n",
-			timestamp.Format("2006-01-02 15:04:05"), message, strings.Repeat("-", 50))
-		if err := e.WriteLog(errorMsg); err != nil {
+		record, err := MarshalLogRecord(&LogEntry{
+			Timestamp:  timestamp,
+			ScriptName: e.scriptName(),
+			ExitCode:   -1,
+			Error:      message,
+		})
+		if err == nil {
+			err = e.WriteLog(string(record))
+		}
+		if err != nil {
 			fmt.Printf("Error writing error to log: %v\n", err)
 		}
 	}
 	fmt.Printf("Error executing script: %s\n", message)
 }
 
+// scriptName returns the script name the log file is named after
+func (e *Executor) scriptName() string {
+	return strings.TrimSuffix(filepath.Base(e.logPath), LogFileExt)
+}
+
 // WriteLog writes content to the log file
 func (e *Executor) WriteLog(content string) error {
 	file, err := os.OpenFile(e.logPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
@@ -4,6 +4,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
 )
 
@@ -184,12 +185,45 @@ func TestExecutor_LogError(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	logContent := string(data)
-	if !strings.Contains(logContent, "ERROR:") {
-		t.Error("expected error message in log")
+	entry, err := ParseLogRecord([]byte(strings.TrimSpace(string(data))))
+	if err != nil {
+		t.Fatalf("expected a log record, got error: %v", err)
+	}
+
+	if entry.Error == "" || entry.ExitCode != -1 {
+		t.Errorf("expected error record with exit code -1, got %+v", entry)
 	}
 
-	if !strings.Contains(logContent, "----") {
-		t.Error("expected error separator in log")
+	if entry.ScriptName != "test" || entry.Version != LogFormatVersion {
+		t.Errorf("expected versioned record for script test, got %+v", entry)
+	}
+}
+
+func TestExecutor_OutputHandler(t *testing.T) {
+	tempDir := t.TempDir()
+	scriptPath := filepath.Join(tempDir, "test_script.sh")
+	script := "#!/bin/bash\necho first\necho oops >&2\nprintf 'last without newline'"
+	if err := os.WriteFile(scriptPath, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	lines := make(map[string][]string)
+	executor := NewExecutor(scriptPath, "", 0)
+	executor.SetOutputHandler(func(stream, line string) {
+		mu.Lock()
+		defer mu.Unlock()
+		lines[stream] = append(lines[stream], line)
+	})
+
+	result := executor.ExecuteScript()
+	if result.Stdout != "first\nlast without newline" || result.Stderr != "oops" {
+		t.Errorf("Unexpected captured output: %q / %q", result.Stdout, result.Stderr)
+	}
+	if strings.Join(lines[StreamStdout], "|") != "first|last without newline" {
+		t.Errorf("Unexpected stdout lines: %q", lines[StreamStdout])
+	}
+	if strings.Join(lines[StreamStderr], "|") != "oops" {
+		t.Errorf("Unexpected stderr lines: %q", lines[StreamStderr])
 	}
 }
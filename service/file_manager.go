@@ -2,6 +2,7 @@
 package service
 
 import (
+	"errors"
 	"fmt"
 	"io/fs"
 	"os"
@@ -9,11 +10,15 @@ import (
 	"strings"
 )
 
+// ErrPathNotAllowed rejects paths outside the directories the FileManager serves
+var ErrPathNotAllowed = errors.New("access denied: path not allowed")
+
 // FileManager handles secure file operations
 type FileManager struct {
-	allowedPaths []string
-	deniedPaths  []string
-	baseDir      string
+	allowedPaths   []string
+	deniedPaths    []string
+	protectedPaths []string // absolute, never served even inside the allowed directories
+	baseDir        string
 }
 
 // FileContent represents file content with metadata
@@ -49,6 +54,34 @@ func NewFileManager(baseDir string) *FileManager {
 	}
 }
 
+// Protect denies access to paths and everything below them, even inside the allowed
+// directories. Relative paths are resolved against the base directory.
+func (fm *FileManager) Protect(paths ...string) {
+	for _, path := range paths {
+		if path == "" {
+			continue
+		}
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(fm.baseDir, path)
+		}
+		if abs, err := filepath.Abs(path); err == nil {
+			fm.protectedPaths = append(fm.protectedPaths, abs)
+		}
+	}
+}
+
+// isProtected reports whether absPath is a protected path, lies below one or is a temporary
+// file WriteFileAtomic writes one with
+func (fm *FileManager) isProtected(absPath string) bool {
+	for _, protected := range fm.protectedPaths {
+		if absPath == protected || strings.HasPrefix(absPath, protected+string(filepath.Separator)) ||
+			strings.HasPrefix(absPath, atomicTempPrefix(protected)) {
+			return true
+		}
+	}
+	return false
+}
+
 // IsPathAllowed checks if a file path is allowed for access
 func (fm *FileManager) IsPathAllowed(path string) bool {
 	// Clean and resolve the path
@@ -92,6 +125,9 @@ func (fm *FileManager) IsPathAllowed(path string) bool {
 	if !strings.HasPrefix(absRequestPath, absBaseDir) {
 		return false // Outside base directory
 	}
+	if fm.isProtected(absRequestPath) {
+		return false // Credentials, keys and the audit log
+	}
 
 	// For relative paths, check if they're within allowed directories
 	for _, allowed := range fm.allowedPaths {
@@ -126,7 +162,7 @@ func (fm *FileManager) IsPathAllowed(path string) bool {
 // ReadFile reads a file's content safely
 func (fm *FileManager) ReadFile(path string) (*FileContent, error) {
 	if !fm.IsPathAllowed(path) {
-		return nil, fmt.Errorf("access denied: path not allowed")
+		return nil, ErrPathNotAllowed
 	}
 
 	// Resolve relative path
@@ -158,7 +194,7 @@ func (fm *FileManager) ReadFile(path string) (*FileContent, error) {
 // WriteFile writes content to a file safely
 func (fm *FileManager) WriteFile(path string, content string) error {
 	if !fm.IsPathAllowed(path) {
-		return fmt.Errorf("access denied: path not allowed")
+		return ErrPathNotAllowed
 	}
 
 	// Resolve relative path
@@ -220,7 +256,7 @@ func (fm *FileManager) ValidateScriptSyntax(content string) []string {
 // ListFiles lists files in a directory
 func (fm *FileManager) ListFiles(dirPath string) ([]fs.FileInfo, error) {
 	if !fm.IsPathAllowed(dirPath) {
-		return nil, fmt.Errorf("access denied: path not allowed")
+		return nil, ErrPathNotAllowed
 	}
 
 	// Resolve relative path
@@ -236,6 +272,9 @@ func (fm *FileManager) ListFiles(dirPath string) ([]fs.FileInfo, error) {
 
 	var fileInfos []fs.FileInfo
 	for _, entry := range entries {
+		if absEntry, err := filepath.Abs(filepath.Join(fullPath, entry.Name())); err != nil || fm.isProtected(absEntry) {
+			continue // Hide protected files from listings
+		}
 		info, err := entry.Info()
 		if err != nil {
 			continue // Skip files we can't get info for
@@ -1,6 +1,7 @@
 package service
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
 	"strings"
@@ -347,3 +348,38 @@ func TestFileManager_ListFiles(t *testing.T) {
 		}
 	})
 }
+
+func TestFileManager_Protect(t *testing.T) {
+	tempDir := t.TempDir()
+	for _, name := range []string{"run.sh", "auth.json", ".auth.json.tmp-1234", "tls/key.pem", "data/state.json"} {
+		path := filepath.Join(tempDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("secret"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	fm := NewFileManager(tempDir)
+	fm.Protect(filepath.Join(tempDir, "auth.json"), "tls", filepath.Join(tempDir, "data"))
+
+	for _, path := range []string{"auth.json", "./auth.json", ".auth.json.tmp-1234", "tls", "tls/key.pem", "data/state.json"} {
+		if _, err := fm.ReadFile(path); !errors.Is(err, ErrPathNotAllowed) {
+			t.Errorf("Expected %s to be denied, got %v", path, err)
+		}
+	}
+	if err := fm.WriteFile("tls/key.pem", "forged"); !errors.Is(err, ErrPathNotAllowed) {
+		t.Errorf("Expected writing a protected file to be denied, got %v", err)
+	}
+	if _, err := fm.ReadFile("run.sh"); err != nil {
+		t.Errorf("Expected other files to stay readable, got %v", err)
+	}
+
+	files, err := fm.ListFiles(".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files[0].Name() != "run.sh" {
+		t.Errorf("Expected protected files hidden from the listing, got %d files", len(files))
+	}
+}
@@ -0,0 +1,228 @@
+// Package service provides core functionality for the run-script-service daemon.
+package service
+
+import (
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Log export formats
+const (
+	ExportCSV    = "csv"
+	ExportNDJSON = "ndjson" // one versioned log record per line, as stored on disk
+	ExportJUnit  = "junit"  // one testcase per run, grouped into a testsuite per script
+)
+
+// exportCSVHeader is the first row of a CSV export
+var exportCSVHeader = []string{"timestamp", "script", "trigger", "exit_code", "duration_ms", "stdout", "stderr", "error", "run_id"}
+
+// ExportContentType returns the MIME type and file extension of an export format
+func ExportContentType(format string) (contentType, ext string, err error) {
+	switch format {
+	case ExportCSV:
+		return "text/csv; charset=utf-8", ".csv", nil
+	case ExportNDJSON:
+		return "application/x-ndjson", ".ndjson", nil
+	case ExportJUnit:
+		return "application/xml; charset=utf-8", ".xml", nil
+	default:
+		return "", "", fmt.Errorf("invalid export format '%s' (expected csv, ndjson or junit)", format)
+	}
+}
+
+// Export returns every run in the whole history matching the query, oldest first.
+// A limit keeps the most recent runs; the cursor is ignored.
+func (lm *LogManager) Export(query *LogQuery) ([]LogEntry, error) {
+	matcher, err := compileLogQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	matches, err := lm.searchAll(matcher)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return newerRun(&matches[j], &matches[i])
+	})
+	if query.Limit > 0 && len(matches) > query.Limit {
+		matches = matches[len(matches)-query.Limit:]
+	}
+	return matches, nil
+}
+
+// WriteLogExport writes entries to w in the given export format
+func WriteLogExport(w io.Writer, format string, entries []LogEntry) error {
+	switch format {
+	case ExportCSV:
+		return writeCSVExport(w, entries)
+	case ExportNDJSON:
+		return writeNDJSONExport(w, entries)
+	case ExportJUnit:
+		return writeJUnitExport(w, entries)
+	default:
+		_, _, err := ExportContentType(format)
+		return err
+	}
+}
+
+// writeCSVExport writes a header row and one row per run
+func writeCSVExport(w io.Writer, entries []LogEntry) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(exportCSVHeader); err != nil {
+		return fmt.Errorf("failed to write CSV export: %v", err)
+	}
+	for i := range entries {
+		entry := &entries[i]
+		row := []string{
+			entry.Timestamp.Format(time.RFC3339Nano),
+			entry.ScriptName,
+			entry.Trigger,
+			strconv.Itoa(entry.ExitCode),
+			strconv.FormatInt(entry.Duration, 10),
+			entry.Stdout,
+			entry.Stderr,
+			entry.Error,
+			entry.RunID,
+		}
+		if err := writer.Write(row); err != nil {
+			return fmt.Errorf("failed to write CSV export: %v", err)
+		}
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("failed to write CSV export: %v", err)
+	}
+	return nil
+}
+
+// writeNDJSONExport writes one log record per run
+func writeNDJSONExport(w io.Writer, entries []LogEntry) error {
+	for i := range entries {
+		record, err := MarshalLogRecord(&entries[i])
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(record); err != nil {
+			return fmt.Errorf("failed to write NDJSON export: %v", err)
+		}
+	}
+	return nil
+}
+
+// junitTestSuites is the root element of a JUnit XML report
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+// junitTestSuite holds the runs of one script
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Time      string          `xml:"time,attr"`
+	Timestamp string          `xml:"timestamp,attr,omitempty"`
+	Cases     []junitTestCase `xml:"testcase"`
+}
+
+// junitTestCase is one run
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+	SystemErr string        `xml:"system-err,omitempty"`
+}
+
+// junitFailure marks a run that failed or could not start
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// junitSeconds formats a duration in milliseconds as JUnit seconds
+func junitSeconds(ms int64) string {
+	return strconv.FormatFloat(float64(ms)/1000, 'f', 3, 64)
+}
+
+// writeJUnitExport writes a JUnit report with a testsuite per script and a testcase per run.
+// Failed runs carry their stderr as the failure text; runs with a warning pass.
+func writeJUnitExport(w io.Writer, entries []LogEntry) error {
+	report := junitTestSuites{}
+	suites := make(map[string]int)
+	durations := make(map[string]int64)
+	var total int64
+
+	for i := range entries {
+		entry := &entries[i]
+		pos, exists := suites[entry.ScriptName]
+		if !exists {
+			pos = len(report.Suites)
+			suites[entry.ScriptName] = pos
+			report.Suites = append(report.Suites, junitTestSuite{
+				Name:      entry.ScriptName,
+				Timestamp: entry.Timestamp.Format("2006-01-02T15:04:05"),
+			})
+		}
+		suite := &report.Suites[pos]
+
+		testCase := junitTestCase{
+			Name:      entry.Timestamp.Format(time.RFC3339),
+			Classname: entry.ScriptName,
+			Time:      junitSeconds(entry.Duration),
+			SystemOut: entry.Stdout,
+		}
+		if entry.Failed() {
+			failure := &junitFailure{
+				Message: fmt.Sprintf("exit code %d", entry.ExitCode),
+				Type:    "ExitCode",
+				Text:    entry.Stderr,
+			}
+			switch {
+			case entry.Error != "":
+				failure.Message = entry.Error
+				failure.Type = "StartError"
+			case entry.OutcomeReason != "" && entry.OutcomeReason != exitCodeReason(entry.ExitCode):
+				failure.Message = entry.OutcomeReason
+				failure.Type = "Outcome"
+			}
+			testCase.Failure = failure
+			suite.Failures++
+			report.Failures++
+		} else {
+			testCase.SystemErr = entry.Stderr
+		}
+		suite.Cases = append(suite.Cases, testCase)
+		suite.Tests++
+		report.Tests++
+		durations[entry.ScriptName] += entry.Duration
+		total += entry.Duration
+	}
+	for i := range report.Suites {
+		report.Suites[i].Time = junitSeconds(durations[report.Suites[i].Name])
+	}
+	report.Time = junitSeconds(total)
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return fmt.Errorf("failed to write JUnit export: %v", err)
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(&report); err != nil {
+		return fmt.Errorf("failed to write JUnit export: %v", err)
+	}
+	if _, err := io.WriteString(w, "\n"); err != nil {
+		return fmt.Errorf("failed to write JUnit export: %v", err)
+	}
+	return nil
+}
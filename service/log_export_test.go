@@ -0,0 +1,149 @@
+package service
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+)
+
+// exportFixture returns a successful and a failed run
+func exportFixture() []LogEntry {
+	base := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	return []LogEntry{
+		{Timestamp: base, ScriptName: "fetch", Stdout: "fetched, 3 items", Duration: 1500, Trigger: TriggerSchedule},
+		{Timestamp: base.Add(time.Hour), ScriptName: "fetch", ExitCode: 2, Stderr: "connection refused", Duration: 250, Trigger: TriggerManual},
+	}
+}
+
+func TestWriteLogExport_CSV(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteLogExport(&buf, ExportCSV, exportFixture()); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("Export is not valid CSV: %v", err)
+	}
+	if len(rows) != 3 {
+		t.Fatalf("Expected header and 2 rows, got %d rows", len(rows))
+	}
+	if strings.Join(rows[0], ",") != "timestamp,script,trigger,exit_code,duration_ms,stdout,stderr,error,run_id" {
+		t.Errorf("Unexpected header: %v", rows[0])
+	}
+	if rows[1][5] != "fetched, 3 items" || rows[2][3] != "2" || rows[2][6] != "connection refused" {
+		t.Errorf("Unexpected rows: %v", rows[1:])
+	}
+}
+
+func TestWriteLogExport_NDJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteLogExport(&buf, ExportNDJSON, exportFixture()); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	entries, _, err := readLogRecords(&buf, "fetch")
+	if err != nil {
+		t.Fatalf("Failed to read export: %v", err)
+	}
+	if len(entries) != 2 || entries[1].ExitCode != 2 || entries[0].Version != LogFormatVersion {
+		t.Errorf("Unexpected records: %+v", entries)
+	}
+}
+
+func TestWriteLogExport_JUnit(t *testing.T) {
+	entries := append(exportFixture(), LogEntry{
+		Timestamp: time.Date(2025, 3, 1, 14, 0, 0, 0, time.UTC), ScriptName: "report", ExitCode: -1, Error: "exec: not found",
+	})
+
+	var buf bytes.Buffer
+	if err := WriteLogExport(&buf, ExportJUnit, entries); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	var report junitTestSuites
+	if err := xml.Unmarshal(buf.Bytes(), &report); err != nil {
+		t.Fatalf("Export is not valid XML: %v", err)
+	}
+	if report.Tests != 3 || report.Failures != 2 || len(report.Suites) != 2 {
+		t.Fatalf("Unexpected totals: tests=%d failures=%d suites=%d", report.Tests, report.Failures, len(report.Suites))
+	}
+
+	fetch := report.Suites[0]
+	if fetch.Name != "fetch" || fetch.Tests != 2 || fetch.Failures != 1 || fetch.Time != "1.750" {
+		t.Errorf("Unexpected fetch suite: %+v", fetch)
+	}
+	if fetch.Cases[0].Failure != nil || fetch.Cases[0].SystemOut != "fetched, 3 items" {
+		t.Errorf("Expected a passing testcase with stdout, got %+v", fetch.Cases[0])
+	}
+	failure := fetch.Cases[1].Failure
+	if failure == nil || failure.Message != "exit code 2" || failure.Text != "connection refused" {
+		t.Errorf("Expected the stderr as failure text, got %+v", failure)
+	}
+
+	if failure := report.Suites[1].Cases[0].Failure; failure == nil || failure.Type != "StartError" {
+		t.Errorf("Expected a start error failure, got %+v", failure)
+	}
+}
+
+func TestWriteLogExport_JUnitOutcomes(t *testing.T) {
+	base := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	entries := []LogEntry{
+		{Timestamp: base, ScriptName: "sync", ExitCode: 1, Outcome: OutcomeWarning, OutcomeReason: "exited with warning code 1"},
+		{Timestamp: base.Add(time.Hour), ScriptName: "sync", Stdout: "FATAL", Outcome: OutcomeFailure, OutcomeReason: `stdout matches "FATAL"`},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteLogExport(&buf, ExportJUnit, entries); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	var report junitTestSuites
+	if err := xml.Unmarshal(buf.Bytes(), &report); err != nil {
+		t.Fatalf("Export is not valid XML: %v", err)
+	}
+	cases := report.Suites[0].Cases
+	if cases[0].Failure != nil {
+		t.Errorf("Expected a run with a warning to pass, got %+v", cases[0].Failure)
+	}
+	if failure := cases[1].Failure; failure == nil || failure.Type != "Outcome" || failure.Message != `stdout matches "FATAL"` {
+		t.Errorf("Expected the outcome reason as failure, got %+v", failure)
+	}
+}
+
+func TestWriteLogExport_InvalidFormat(t *testing.T) {
+	if err := WriteLogExport(&bytes.Buffer{}, "xlsx", nil); err == nil {
+		t.Error("Expected an error for an unknown format")
+	}
+	if _, _, err := ExportContentType("xlsx"); err == nil {
+		t.Error("Expected an error for an unknown format")
+	}
+}
+
+func TestLogManager_Export(t *testing.T) {
+	lm, base := searchFixture(t)
+
+	entries, err := lm.Export(&LogQuery{Text: "refused", IgnoreCase: true})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("Expected 3 runs, got %d", len(entries))
+	}
+	// Oldest first, across scripts
+	for i, offset := range []time.Duration{time.Hour, 2 * time.Hour, 4 * time.Hour} {
+		if !entries[i].Timestamp.Equal(base.Add(offset)) {
+			t.Errorf("Entry %d: expected %v, got %v", i, base.Add(offset), entries[i].Timestamp)
+		}
+	}
+
+	entries, err = lm.Export(&LogQuery{Limit: 2})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(entries) != 2 || !entries[1].Timestamp.Equal(base.Add(4*time.Hour)) {
+		t.Errorf("Expected the 2 most recent runs, got %+v", entries)
+	}
+}
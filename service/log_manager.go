@@ -2,14 +2,10 @@
 package service
 
 import (
-	"bufio"
-	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
-	"regexp"
-	"strconv"
-	"strings"
+	"sort"
 	"sync"
 	"time"
 )
@@ -27,19 +23,62 @@ type ScriptLogger struct {
 	logPath    string
 	maxLines   int
 	entries    []LogEntry
+	complete   bool         // entries hold the whole history, rotated segments included
+	index      *logIndex    // search index over the whole history, built on first search
+	stamp      logFileStamp // the log file the entries match, see reloadIfChanged
 	mutex      sync.RWMutex
 }
 
-// LogEntry represents a single log entry
+// LogEntry represents a single script run, stored as one JSONL record per line
 type LogEntry struct {
-	Timestamp  time.Time `json:"timestamp"`
-	ScriptName string    `json:"script_name"`
-	ExitCode   int       `json:"exit_code"`
-	Stdout     string    `json:"stdout"`
-	Stderr     string    `json:"stderr"`
-	Duration   int64     `json:"duration_ms"`
+	Version     int       `json:"v"` // LogFormatVersion the record was written with
+	Timestamp   time.Time `json:"timestamp"`
+	ScriptName  string    `json:"script_name"`
+	ExitCode    int       `json:"exit_code"`
+	Stdout      string    `json:"stdout"`
+	Stderr      string    `json:"stderr"`
+	Duration    int64     `json:"duration_ms"`
+	Error       string    `json:"error,omitempty"`       // set when the script could not be run
+	Trigger     string    `json:"trigger,omitempty"`     // TriggerSchedule or TriggerManual
+	RunID       string    `json:"run_id,omitempty"`      // unique per run, shared with log sinks
+	Interrupted string    `json:"interrupted,omitempty"` // why the run was killed before the script exited
+
+	Result        map[string]interface{} `json:"result,omitempty"`         // fields extracted by the script's output parser
+	Outcome       string                 `json:"outcome,omitempty"`        // OutcomeSuccess, OutcomeWarning or OutcomeFailure, empty in older records
+	OutcomeReason string                 `json:"outcome_reason,omitempty"` // why the run did not succeed
+	Artifacts     int                    `json:"artifacts,omitempty"`      // files kept in the run's artifact directory
 }
 
+// Run outcomes recorded in LogEntry.Outcome
+const (
+	OutcomeSuccess = "success"
+	OutcomeWarning = "warning" // the run completed but needs attention
+	OutcomeFailure = "failure"
+)
+
+// RunOutcome returns the outcome of the run. Records without an outcome
+// succeeded when the script ran and exited with code 0, and failed otherwise.
+func (e *LogEntry) RunOutcome() string {
+	if e.Outcome != "" {
+		return e.Outcome
+	}
+	if e.ExitCode == 0 && e.Error == "" && e.Interrupted == "" {
+		return OutcomeSuccess
+	}
+	return OutcomeFailure
+}
+
+// Failed reports whether the run failed
+func (e *LogEntry) Failed() bool {
+	return e.RunOutcome() == OutcomeFailure
+}
+
+// Run triggers recorded in LogEntry.Trigger
+const (
+	TriggerSchedule = "schedule" // started by the script's interval
+	TriggerManual   = "manual"   // started on request from the CLI or API
+)
+
 // LogQuery defines criteria for querying logs
 type LogQuery struct {
 	ScriptName string    `json:"script_name,omitempty"`
@@ -47,6 +86,15 @@ type LogQuery struct {
 	EndTime    time.Time `json:"end_time,omitempty"`
 	ExitCode   *int      `json:"exit_code,omitempty"`
 	Limit      int       `json:"limit,omitempty"`
+
+	Text        string `json:"text,omitempty"`  // substring of the run's output
+	Regex       string `json:"regex,omitempty"` // regular expression over the run's output
+	IgnoreCase  bool   `json:"ignore_case,omitempty"`
+	Stream      string `json:"stream,omitempty"` // StreamStdout or StreamStderr, empty searches both
+	MinDuration int64  `json:"min_duration_ms,omitempty"`
+	MaxDuration int64  `json:"max_duration_ms,omitempty"`
+	Trigger     string `json:"trigger,omitempty"`
+	Cursor      string `json:"cursor,omitempty"` // NextCursor of the previous search page
 }
 
 // NewLogManager creates a new LogManager instance
@@ -57,6 +105,11 @@ func NewLogManager(baseDir string) *LogManager {
 	}
 }
 
+// GetBaseDir returns the directory log files are stored in
+func (lm *LogManager) GetBaseDir() string {
+	return lm.baseDir
+}
+
 // GetLogger returns a logger for the specified script, creating one if it doesn't exist
 func (lm *LogManager) GetLogger(scriptName string) *ScriptLogger {
 	lm.mutex.Lock()
@@ -71,9 +124,24 @@ func (lm *LogManager) GetLogger(scriptName string) *ScriptLogger {
 	return logger
 }
 
+// discoverLoggers loads loggers for log files on disk that have not been loaded yet.
+// The caller must hold the write lock.
+func (lm *LogManager) discoverLoggers() {
+	names, err := ListLogScripts(lm.baseDir)
+	if err != nil {
+		Warnf("Failed to discover log files: %v", err)
+		return
+	}
+	for _, name := range names {
+		if _, exists := lm.loggers[name]; !exists {
+			lm.loggers[name] = NewScriptLogger(name, lm.baseDir, 100)
+		}
+	}
+}
+
 // NewScriptLogger creates a new ScriptLogger instance
 func NewScriptLogger(scriptName, baseDir string, maxLines int) *ScriptLogger {
-	logPath := filepath.Join(baseDir, fmt.Sprintf("%s.log", scriptName))
+	logPath := LogFilePath(baseDir, scriptName)
 
 	logger := &ScriptLogger{
 		scriptName: scriptName,
@@ -83,7 +151,9 @@ func NewScriptLogger(scriptName, baseDir string, maxLines int) *ScriptLogger {
 	}
 
 	// Ensure log directory exists
-	_ = os.MkdirAll(baseDir, 0750) // Ignore error - logger will still work, file ops may fail later
+	if baseDir != "" {
+		_ = os.MkdirAll(baseDir, 0750) // Ignore error - logger will still work, file ops may fail later
+	}
 
 	// Load existing log file if it exists
 	logger.LoadExistingLogs()
@@ -96,16 +166,29 @@ func (sl *ScriptLogger) AddEntry(entry *LogEntry) error {
 	sl.mutex.Lock()
 	defer sl.mutex.Unlock()
 
+	entry.Version = LogFormatVersion
+	sl.reloadIfChanged()
+
 	// Add entry to in-memory storage
 	sl.entries = append(sl.entries, *entry)
 
 	// Maintain maxLines limit
 	if len(sl.entries) > sl.maxLines {
 		sl.entries = sl.entries[len(sl.entries)-sl.maxLines:]
+		sl.complete = false
 	}
 
 	// Write to file
-	return sl.writeToFile(entry)
+	err := sl.writeToFile(entry)
+	sl.stamp = statLogFile(sl.logPath)
+	if err != nil {
+		sl.index = nil // the file may no longer match the index
+		return err
+	}
+	if sl.index != nil {
+		sl.index.appendRun(entry)
+	}
+	return nil
 }
 
 // writeToFile writes a log entry to the log file
@@ -116,12 +199,12 @@ func (sl *ScriptLogger) writeToFile(entry *LogEntry) error {
 	}
 	defer file.Close()
 
-	data, err := json.Marshal(entry)
+	data, err := MarshalLogRecord(entry)
 	if err != nil {
-		return fmt.Errorf("failed to marshal log entry: %w", err)
+		return err
 	}
 
-	_, err = file.Write(append(data, '\n'))
+	_, err = file.Write(data)
 	if err != nil {
 		return fmt.Errorf("failed to write log entry: %w", err)
 	}
@@ -131,6 +214,7 @@ func (sl *ScriptLogger) writeToFile(entry *LogEntry) error {
 
 // GetEntries returns all log entries for this script
 func (sl *ScriptLogger) GetEntries() []LogEntry {
+	sl.refresh()
 	sl.mutex.RLock()
 	defer sl.mutex.RUnlock()
 
@@ -140,125 +224,156 @@ func (sl *ScriptLogger) GetEntries() []LogEntry {
 	return entries
 }
 
-// QueryLogs queries logs across all managed scripts
+// QueryLogs queries logs across all scripts with a log file or a loaded logger.
+// Results are ordered oldest first.
 func (lm *LogManager) QueryLogs(query *LogQuery) ([]LogEntry, error) {
-	lm.mutex.RLock()
-	defer lm.mutex.RUnlock()
-
-	var results []LogEntry
-
-	// If specific script is requested
-	if query.ScriptName != "" {
-		if logger, exists := lm.loggers[query.ScriptName]; exists {
-			entries := logger.GetEntries()
-			results = append(results, entries...)
-		}
-	} else {
-		// Query all scripts
-		for _, logger := range lm.loggers {
-			entries := logger.GetEntries()
-			results = append(results, entries...)
-		}
+	matcher, err := compileLogQuery(query)
+	if err != nil {
+		return nil, err
 	}
 
-	// Apply filters
-	filtered := make([]LogEntry, 0)
-	for i := range results {
-		if lm.matchesQuery(&results[i], query) {
-			filtered = append(filtered, results[i])
-		}
+	// Each script contributes its last Limit matches, the newest Limit of those are returned
+	var results []LogEntry
+	for _, logger := range lm.queriedLoggers(query.ScriptName) {
+		results = append(results, logger.recent(matcher, query.Limit)...)
 	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Timestamp.Before(results[j].Timestamp)
+	})
 
 	// Apply limit
-	if query.Limit > 0 && len(filtered) > query.Limit {
-		filtered = filtered[len(filtered)-query.Limit:]
+	if query.Limit > 0 && len(results) > query.Limit {
+		results = results[len(results)-query.Limit:]
+	}
+	if results == nil {
+		results = make([]LogEntry, 0)
 	}
 
-	return filtered, nil
+	return results, nil
 }
 
-// matchesQuery checks if a log entry matches the query criteria
-func (lm *LogManager) matchesQuery(entry *LogEntry, query *LogQuery) bool {
-	// Check time range
-	if !query.StartTime.IsZero() && entry.Timestamp.Before(query.StartTime) {
-		return false
-	}
-	if !query.EndTime.IsZero() && entry.Timestamp.After(query.EndTime) {
-		return false
-	}
+// queriedLoggers returns the logger of scriptName, or of every script when it is empty.
+// Reading their files happens after the manager's lock is released, so runs finishing
+// meanwhile can still get their loggers.
+func (lm *LogManager) queriedLoggers(scriptName string) []*ScriptLogger {
+	lm.mutex.Lock()
+	defer lm.mutex.Unlock()
 
-	// Check exit code
-	if query.ExitCode != nil && entry.ExitCode != *query.ExitCode {
-		return false
+	lm.discoverLoggers()
+	var loggers []*ScriptLogger
+	for name, logger := range lm.loggers {
+		if scriptName == "" || name == scriptName {
+			loggers = append(loggers, logger)
+		}
 	}
-
-	return true
+	return loggers
 }
 
-// LoadExistingLogs loads log entries from existing log file
+// LoadExistingLogs loads the most recent entries from the log file and rotated segments
 func (sl *ScriptLogger) LoadExistingLogs() {
-	if _, err := os.Stat(sl.logPath); os.IsNotExist(err) {
-		return // No existing log file
+	sl.stamp = statLogFile(sl.logPath) // before reading, so a write meanwhile is noticed later
+	entries, err := readRecentHistory(filepath.Dir(sl.logPath), sl.scriptName, sl.maxLines+1)
+	if err != nil {
+		Warnf("%v", err) // Continue without loading
+		return
+	}
+	sl.entries = append(sl.entries, entries...)
+	sl.complete = len(sl.entries) <= sl.maxLines
+
+	// Maintain maxLines limit
+	if len(sl.entries) > sl.maxLines {
+		sl.entries = sl.entries[len(sl.entries)-sl.maxLines:]
 	}
+}
 
-	file, err := os.Open(sl.logPath)
+// logFileStamp identifies the state of a log file by its size and modification time
+type logFileStamp struct {
+	size    int64 // -1 when the file does not exist
+	modTime time.Time
+}
+
+// statLogFile returns the stamp of the log file at path
+func statLogFile(path string) logFileStamp {
+	info, err := os.Stat(path)
 	if err != nil {
-		return // Can't open file, continue without loading
+		return logFileStamp{size: -1}
 	}
-	defer file.Close()
+	return logFileStamp{size: info.Size(), modTime: info.ModTime()}
+}
+
+// refresh reloads the entries when the log file changed behind the logger
+func (sl *ScriptLogger) refresh() {
+	sl.mutex.Lock()
+	defer sl.mutex.Unlock()
+	sl.reloadIfChanged()
+}
 
-	scanner := bufio.NewScanner(file)
-	var currentEntry *LogEntry
-	var stdoutLines []string
+// reloadIfChanged reloads the entries and drops the search index when another process, such
+// as a run started from the CLI, wrote to the log file since the logger last read or wrote
+// it. The caller holds the write lock.
+func (sl *ScriptLogger) reloadIfChanged() {
+	current := statLogFile(sl.logPath)
+	if current.size == sl.stamp.size && current.modTime.Equal(sl.stamp.modTime) {
+		return
+	}
+	sl.entries = make([]LogEntry, 0)
+	sl.index = nil
+	sl.LoadExistingLogs()
+}
 
-	// Regex to match timestamp and exit code line: [2025-08-02 11:26:16] Exit code: 0
-	timestampRegex := regexp.MustCompile(`^\[([^\]]+)\] Exit code: (\d+)$`)
+// recent returns the last limit runs of the script matching matcher, oldest first; limit <= 0
+// returns every match. Runs are read newest first from memory, or from disk when the memory
+// does not hold them all, and only until limit runs match or the runs are older than the
+// query's start time.
+func (sl *ScriptLogger) recent(matcher *logMatcher, limit int) []LogEntry {
+	sl.refresh()
+	sl.mutex.RLock()
+	defer sl.mutex.RUnlock()
 
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
+	var matched []LogEntry // newest first
+	visit := func(entry *LogEntry) bool {
+		if matcher.matches(entry) {
+			matched = append(matched, *entry)
+		}
+		return (limit <= 0 || len(matched) < limit) && !matcher.olderThanStart(entry)
+	}
 
-		if line == "--------------------------------------------------" {
-			// End of entry
-			if currentEntry != nil {
-				currentEntry.Stdout = strings.Join(stdoutLines, "\n")
-				sl.entries = append(sl.entries, *currentEntry)
-				currentEntry = nil
-				stdoutLines = nil
-			}
-		} else if matches := timestampRegex.FindStringSubmatch(line); matches != nil {
-			// Start of new entry
-			timestamp, _ := time.Parse("2006-01-02 15:04:05", matches[1])
-			exitCode, _ := strconv.Atoi(matches[2])
-
-			currentEntry = &LogEntry{
-				Timestamp:  timestamp,
-				ScriptName: sl.scriptName,
-				ExitCode:   exitCode,
-				Stdout:     "",
-				Stderr:     "",
-				Duration:   0, // Can't determine from existing logs
+	scanned := false
+	if !sl.complete {
+		if err := scanHistory(filepath.Dir(sl.logPath), sl.scriptName, visit); err != nil {
+			Warnf("%v", err) // Fall back to the entries in memory
+			matched = nil
+		} else {
+			scanned = true
+		}
+	}
+	if !scanned {
+		for i := len(sl.entries) - 1; i >= 0; i-- {
+			if !visit(&sl.entries[i]) {
+				break
 			}
-			stdoutLines = make([]string, 0)
-		} else if currentEntry != nil && strings.HasPrefix(line, "STDOUT: ") {
-			// STDOUT line
-			stdoutContent := strings.TrimPrefix(line, "STDOUT: ")
-			stdoutLines = append(stdoutLines, stdoutContent)
-		} else if currentEntry != nil && line != "" && !strings.HasPrefix(line, "STDERR: ") {
-			// Continuation of stdout (multi-line output)
-			stdoutLines = append(stdoutLines, line)
 		}
 	}
 
-	// Handle last entry if file doesn't end with separator
-	if currentEntry != nil {
-		currentEntry.Stdout = strings.Join(stdoutLines, "\n")
-		sl.entries = append(sl.entries, *currentEntry)
+	for i, j := 0, len(matched)-1; i < j; i, j = i+1, j-1 {
+		matched[i], matched[j] = matched[j], matched[i]
 	}
+	return matched
+}
 
-	// Maintain maxLines limit
-	if len(sl.entries) > sl.maxLines {
-		sl.entries = sl.entries[len(sl.entries)-sl.maxLines:]
+// Compact rotates and trims a script's log files under policy and reloads its entries
+func (lm *LogManager) Compact(scriptName string, policy RetentionPolicy) (CompactionResult, error) {
+	logger := lm.GetLogger(scriptName)
+	logger.mutex.Lock()
+	defer logger.mutex.Unlock()
+
+	result, err := compactLogFiles(lm.baseDir, scriptName, policy, time.Now())
+	if result.Rotated || result.Removed > 0 {
+		logger.entries = make([]LogEntry, 0)
+		logger.index = nil
+		logger.LoadExistingLogs()
 	}
+	return result, err
 }
 
 // ClearLogs clears all log entries for a specific script
@@ -266,6 +381,8 @@ func (lm *LogManager) ClearLogs(scriptName string) error {
 	lm.mutex.Lock()
 	defer lm.mutex.Unlock()
 
+	lm.discoverLoggers()
+
 	if logger, exists := lm.loggers[scriptName]; exists {
 		return logger.ClearEntries()
 	}
@@ -280,11 +397,15 @@ func (sl *ScriptLogger) ClearEntries() error {
 
 	// Clear in-memory entries
 	sl.entries = make([]LogEntry, 0)
+	sl.complete = true
+	sl.index = nil
 
-	// Clear the log file
-	if err := os.Truncate(sl.logPath, 0); err != nil {
+	// Clear the log file and its rotated segments
+	err := os.Truncate(sl.logPath, 0)
+	sl.stamp = statLogFile(sl.logPath)
+	if err != nil {
 		return fmt.Errorf("failed to clear log file: %v", err)
 	}
 
-	return nil
+	return removeLogSegments(filepath.Dir(sl.logPath), sl.scriptName)
 }
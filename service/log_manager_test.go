@@ -138,3 +138,71 @@ func TestLogQuery_Empty(t *testing.T) {
 		t.Errorf("Expected default Limit 0, got %d", query.Limit)
 	}
 }
+
+func TestLogManager_QueryLogs_DiscoversFiles(t *testing.T) {
+	dir := t.TempDir()
+	older := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	if err := WriteLogFile(LogFilePath(dir, "b"), []LogEntry{{Timestamp: older.Add(time.Hour), ScriptName: "b", ExitCode: 1}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteLogFile(LogFilePath(dir, "a"), []LogEntry{{Timestamp: older, ScriptName: "a"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	lm := NewLogManager(dir)
+	entries, err := lm.QueryLogs(&LogQuery{})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(entries) != 2 || entries[0].ScriptName != "a" || entries[1].ScriptName != "b" {
+		t.Fatalf("Expected entries of a and b oldest first, got %+v", entries)
+	}
+
+	entries, _ = NewLogManager(dir).QueryLogs(&LogQuery{ScriptName: "b"})
+	if len(entries) != 1 || entries[0].ExitCode != 1 {
+		t.Errorf("Expected the single run of b, got %+v", entries)
+	}
+
+	if err := lm.ClearLogs("a"); err != nil {
+		t.Errorf("Expected discovered log to be clearable, got: %v", err)
+	}
+}
+
+func TestLogManager_SeesRunsLoggedByOtherProcesses(t *testing.T) {
+	dir := t.TempDir()
+	base := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	daemon := NewLogManager(dir)
+	if err := daemon.GetLogger("backup").AddEntry(&LogEntry{Timestamp: base, ScriptName: "backup", Stdout: "first run"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := daemon.Search(&LogQuery{Text: "run"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// A CLI run writes the same log through its own LogManager
+	cli := NewLogManager(dir)
+	if err := cli.GetLogger("backup").AddEntry(&LogEntry{Timestamp: base.Add(time.Hour), ScriptName: "backup", Stdout: "cli run"}); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := daemon.QueryLogs(&LogQuery{ScriptName: "backup"})
+	if err != nil || len(entries) != 2 || entries[1].Stdout != "cli run" {
+		t.Errorf("Expected the CLI run in the daemon's query, got %+v (%v)", entries, err)
+	}
+	result, err := daemon.Search(&LogQuery{Text: "cli run"})
+	if err != nil || len(result.Entries) != 1 {
+		t.Errorf("Expected the CLI run in the daemon's search, got %+v (%v)", result, err)
+	}
+
+	// The daemon's next run follows the CLI run in memory and in the index
+	if err := daemon.GetLogger("backup").AddEntry(&LogEntry{Timestamp: base.Add(2 * time.Hour), ScriptName: "backup", Stdout: "third run"}); err != nil {
+		t.Fatal(err)
+	}
+	if entries := daemon.GetLogger("backup").GetEntries(); len(entries) != 3 {
+		t.Errorf("Expected 3 runs in memory, got %+v", entries)
+	}
+	result, err = daemon.Search(&LogQuery{Text: "run"})
+	if err != nil || len(result.Entries) != 3 || result.Entries[0].Stdout != "third run" {
+		t.Errorf("Expected all 3 runs found, got %+v (%v)", result, err)
+	}
+}
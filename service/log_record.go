@@ -0,0 +1,296 @@
+// Package service provides core functionality for the run-script-service daemon.
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LogFormatVersion is the version of the log record written by this build.
+// Records without a version were written before versioning and read as version 1.
+const LogFormatVersion = 1
+
+// LogFileExt is the extension of per-script log files
+const LogFileExt = ".log"
+
+// maxLogRecordSize bounds a single JSONL record, stdout of one run included
+const maxLogRecordSize = 16 * 1024 * 1024
+
+// legacySeparator ends every entry in the old text log format
+var legacySeparator = strings.Repeat("-", 50)
+
+// legacyTimestampLayout is the local-time timestamp used by the old text log format
+const legacyTimestampLayout = "2006-01-02 15:04:05"
+
+var (
+	// [2025-08-02 11:26:16] Exit code: 0
+	legacyExitRegex = regexp.MustCompile(`^\[([^\]]+)\] Exit code: (-?\d+)$`)
+	// [2025-08-02 11:26:16] ERROR: Error starting command: ...
+	legacyErrorRegex = regexp.MustCompile(`^\[([^\]]+)\] ERROR: (.*)$`)
+)
+
+// LogMigration describes the conversion of one script log file
+type LogMigration struct {
+	Script   string `json:"script"`
+	Path     string `json:"path"`
+	Entries  int    `json:"entries"`
+	Migrated bool   `json:"migrated"` // false when the file already used the current format
+}
+
+// MarshalLogRecord encodes an entry as one versioned JSONL record, newline included
+func MarshalLogRecord(entry *LogEntry) ([]byte, error) {
+	record := *entry
+	record.Version = LogFormatVersion
+	data, err := json.Marshal(&record)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal log entry: %w", err)
+	}
+	return append(data, '\n'), nil
+}
+
+// ParseLogRecord decodes one JSONL record
+func ParseLogRecord(line []byte) (LogEntry, error) {
+	var entry LogEntry
+	if err := json.Unmarshal(line, &entry); err != nil {
+		return LogEntry{}, fmt.Errorf("invalid log record: %v", err)
+	}
+	if entry.Version > LogFormatVersion {
+		return LogEntry{}, fmt.Errorf("unsupported log record version %d", entry.Version)
+	}
+	if entry.Version == 0 {
+		entry.Version = LogFormatVersion
+	}
+	return entry, nil
+}
+
+// LogFilePath returns the log file of a script in dir
+func LogFilePath(dir, scriptName string) string {
+	return filepath.Join(dir, scriptName+LogFileExt)
+}
+
+// ReadLogFile reads all entries of a script log file in order. Entries in the old
+// text format are converted on the fly; legacy reports whether any were found.
+// A missing file has no entries.
+func ReadLogFile(path, scriptName string) (entries []LogEntry, legacy bool, err error) {
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, false, nil
+		}
+		return nil, false, fmt.Errorf("failed to open log file: %v", err)
+	}
+	defer file.Close()
+
+	entries, legacy, err = readLogRecords(file, scriptName)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read log file %s: %v", path, err)
+	}
+	return entries, legacy, nil
+}
+
+// readLogRecords parses JSONL records and old text entries from r
+func readLogRecords(r io.Reader, scriptName string) ([]LogEntry, bool, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLogRecordSize)
+
+	parser := legacyLogParser{scriptName: scriptName}
+	entries := make([]LogEntry, 0)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if trimmed := bytes.TrimSpace(line); len(trimmed) > 0 && trimmed[0] == '{' {
+			entry, err := ParseLogRecord(trimmed)
+			if err != nil {
+				Debugf("Skipping log record of %s: %v", scriptName, err)
+				continue
+			}
+			if entry.ScriptName == "" {
+				entry.ScriptName = scriptName
+			}
+			entries = append(entries, entry)
+			continue
+		}
+		if entry := parser.feed(string(line)); entry != nil {
+			entries = append(entries, *entry)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, false, err
+	}
+	if entry := parser.flush(); entry != nil {
+		entries = append(entries, *entry)
+	}
+
+	return entries, parser.found, nil
+}
+
+// legacyLogParser assembles entries of the old text format line by line
+type legacyLogParser struct {
+	scriptName string
+	current    *LogEntry
+	stdout     []string
+	stderr     []string
+	inStderr   bool
+	found      bool
+}
+
+// feed consumes one line and returns an entry once it is complete
+func (p *legacyLogParser) feed(line string) *LogEntry {
+	trimmed := strings.TrimSpace(line)
+
+	if trimmed == legacySeparator {
+		return p.flush()
+	}
+
+	if matches := legacyExitRegex.FindStringSubmatch(trimmed); matches != nil {
+		done := p.flush()
+		exitCode, _ := strconv.Atoi(matches[2])
+		p.start(matches[1]).ExitCode = exitCode
+		return done
+	}
+
+	if matches := legacyErrorRegex.FindStringSubmatch(trimmed); matches != nil {
+		done := p.flush()
+		entry := p.start(matches[1])
+		entry.ExitCode = -1
+		entry.Error = matches[2]
+		return done
+	}
+
+	if p.current == nil {
+		return nil
+	}
+	switch {
+	case strings.HasPrefix(trimmed, "STDOUT: "):
+		p.inStderr = false
+		p.stdout = append(p.stdout, strings.TrimPrefix(trimmed, "STDOUT: "))
+	case strings.HasPrefix(trimmed, "STDERR: "):
+		p.inStderr = true
+		p.stderr = append(p.stderr, strings.TrimPrefix(trimmed, "STDERR: "))
+	case p.inStderr:
+		// Continuation of multi-line output
+		p.stderr = append(p.stderr, line)
+	default:
+		p.stdout = append(p.stdout, line)
+	}
+	return nil
+}
+
+// start begins a new entry at the given legacy timestamp
+func (p *legacyLogParser) start(timestamp string) *LogEntry {
+	parsed, _ := time.ParseInLocation(legacyTimestampLayout, timestamp, time.Local)
+	p.current = &LogEntry{
+		Version:    LogFormatVersion,
+		Timestamp:  parsed,
+		ScriptName: p.scriptName,
+		Duration:   0, // Not recorded by the old format
+	}
+	p.stdout = nil
+	p.stderr = nil
+	p.inStderr = false
+	p.found = true
+	return p.current
+}
+
+// flush returns the entry in progress, if any
+func (p *legacyLogParser) flush() *LogEntry {
+	entry := p.current
+	if entry == nil {
+		return nil
+	}
+	entry.Stdout = strings.TrimSpace(strings.Join(p.stdout, "\n"))
+	entry.Stderr = strings.TrimSpace(strings.Join(p.stderr, "\n"))
+	p.current = nil
+	return entry
+}
+
+// WriteLogFile atomically replaces a script log file with the given entries
+func WriteLogFile(path string, entries []LogEntry) error {
+	var buf bytes.Buffer
+	for i := range entries {
+		record, err := MarshalLogRecord(&entries[i])
+		if err != nil {
+			return err
+		}
+		buf.Write(record)
+	}
+	return WriteFileAtomic(path, buf.Bytes(), 0600)
+}
+
+// MigrateLogFile rewrites a script log file that contains old text entries in the
+// current record format. The original is kept next to it with LegacyBackupSuffix.
+func MigrateLogFile(path, scriptName string, dryRun bool) (LogMigration, error) {
+	migration := LogMigration{Script: scriptName, Path: path}
+
+	entries, legacy, err := ReadLogFile(path, scriptName)
+	if err != nil {
+		return migration, err
+	}
+	migration.Entries = len(entries)
+	migration.Migrated = legacy
+	if !legacy || dryRun {
+		return migration, nil
+	}
+
+	// Never replace an earlier backup, it holds the original file
+	backupPath := path + LegacyBackupSuffix
+	if _, statErr := os.Stat(backupPath); os.IsNotExist(statErr) {
+		data, readErr := os.ReadFile(path)
+		if readErr != nil {
+			return migration, fmt.Errorf("failed to read log file: %v", readErr)
+		}
+		if err := WriteFileAtomic(backupPath, data, 0600); err != nil {
+			return migration, fmt.Errorf("failed to back up log file: %v", err)
+		}
+	}
+
+	if err := WriteLogFile(path, entries); err != nil {
+		return migration, fmt.Errorf("failed to rewrite log file: %v", err)
+	}
+	return migration, nil
+}
+
+// MigrateLogs converts every script log file in dir to the current record format
+func MigrateLogs(dir string, dryRun bool) ([]LogMigration, error) {
+	names, err := ListLogScripts(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	migrations := make([]LogMigration, 0, len(names))
+	for _, name := range names {
+		migration, err := MigrateLogFile(LogFilePath(dir, name), name, dryRun)
+		if err != nil {
+			return migrations, err
+		}
+		migrations = append(migrations, migration)
+	}
+	return migrations, nil
+}
+
+// ListLogScripts returns the names of scripts that have a log file in dir, sorted
+func ListLogScripts(dir string) ([]string, error) {
+	matches, err := filepath.Glob(filepath.Join(dir, "*"+LogFileExt))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list log files: %v", err)
+	}
+
+	names := make([]string, 0, len(matches))
+	for _, match := range matches {
+		if info, statErr := os.Stat(match); statErr != nil || info.IsDir() {
+			continue
+		}
+		names = append(names, strings.TrimSuffix(filepath.Base(match), LogFileExt))
+	}
+	sort.Strings(names)
+	return names, nil
+}
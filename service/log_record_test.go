@@ -0,0 +1,130 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const legacyLogContent = `[2025-08-02 11:26:16] Exit code: 0
+STDOUT: first line
+second line
+--------------------------------------------------
+[2025-08-02 11:27:16] Exit code: 2
+STDERR: disk full
+--------------------------------------------------
+[2025-08-02 11:28:16] ERROR: Error starting command: permission denied
+--------------------------------------------------
+`
+
+func TestParseLogRecord(t *testing.T) {
+	entry, err := ParseLogRecord([]byte(`{"timestamp":"2025-08-02T11:26:16Z","script_name":"a","exit_code":1}`))
+	if err != nil {
+		t.Fatalf("Expected unversioned record to parse, got: %v", err)
+	}
+	if entry.Version != LogFormatVersion || entry.ExitCode != 1 {
+		t.Errorf("Unexpected entry: %+v", entry)
+	}
+
+	if _, err := ParseLogRecord([]byte(`{"v":99,"script_name":"a"}`)); err == nil {
+		t.Error("Expected error for unsupported record version")
+	}
+	if _, err := ParseLogRecord([]byte(`{"v":`)); err == nil {
+		t.Error("Expected error for malformed record")
+	}
+
+	data, err := MarshalLogRecord(&LogEntry{ScriptName: "a"})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if !strings.HasPrefix(string(data), `{"v":1,`) || !strings.HasSuffix(string(data), "\n") {
+		t.Errorf("Expected versioned record line, got %q", data)
+	}
+}
+
+func TestReadLogFile_MixedFormats(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "backup.log")
+	record, _ := MarshalLogRecord(&LogEntry{Timestamp: time.Now(), ExitCode: 0, Stdout: "new"})
+	if err := os.WriteFile(path, append([]byte(legacyLogContent), record...), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, legacy, err := ReadLogFile(path, "backup")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if !legacy {
+		t.Error("Expected old text entries to be reported")
+	}
+	if len(entries) != 4 {
+		t.Fatalf("Expected 4 entries, got %d: %+v", len(entries), entries)
+	}
+
+	if entries[0].Stdout != "first line\nsecond line" || entries[0].ExitCode != 0 {
+		t.Errorf("Unexpected first entry: %+v", entries[0])
+	}
+	expected := time.Date(2025, 8, 2, 11, 26, 16, 0, time.Local)
+	if !entries[0].Timestamp.Equal(expected) {
+		t.Errorf("Expected timestamp %v, got %v", expected, entries[0].Timestamp)
+	}
+	if entries[1].Stderr != "disk full" || entries[1].ExitCode != 2 {
+		t.Errorf("Unexpected second entry: %+v", entries[1])
+	}
+	if entries[2].ExitCode != -1 || !strings.Contains(entries[2].Error, "permission denied") {
+		t.Errorf("Unexpected error entry: %+v", entries[2])
+	}
+	if entries[3].Stdout != "new" || entries[3].ScriptName != "backup" {
+		t.Errorf("Unexpected record entry: %+v", entries[3])
+	}
+
+	if entries, _, err := ReadLogFile(filepath.Join(t.TempDir(), "missing.log"), "missing"); err != nil || len(entries) != 0 {
+		t.Errorf("Expected no entries for missing file, got %v, %v", entries, err)
+	}
+}
+
+func TestMigrateLogs(t *testing.T) {
+	dir := t.TempDir()
+	legacyPath := LogFilePath(dir, "old")
+	if err := os.WriteFile(legacyPath, []byte(legacyLogContent), 0600); err != nil {
+		t.Fatal(err)
+	}
+	current := NewScriptLogger("current", dir, 100)
+	if err := current.AddEntry(&LogEntry{Timestamp: time.Now(), ScriptName: "current"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Dry run reports without touching the file
+	migrations, err := MigrateLogs(dir, true)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(migrations) != 2 || migrations[0].Script != "current" || migrations[0].Migrated ||
+		migrations[1].Script != "old" || !migrations[1].Migrated || migrations[1].Entries != 3 {
+		t.Fatalf("Unexpected dry run result: %+v", migrations)
+	}
+	if data, _ := os.ReadFile(legacyPath); string(data) != legacyLogContent {
+		t.Error("Expected dry run to leave the log file unchanged")
+	}
+
+	if _, err := MigrateLogs(dir, false); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if data, _ := os.ReadFile(legacyPath + LegacyBackupSuffix); string(data) != legacyLogContent {
+		t.Error("Expected original log to be kept as backup")
+	}
+	entries, legacy, err := ReadLogFile(legacyPath, "old")
+	if err != nil || legacy || len(entries) != 3 {
+		t.Fatalf("Expected 3 migrated records, got %d (legacy %v, err %v)", len(entries), legacy, err)
+	}
+	if entries[1].Stderr != "disk full" || entries[1].Version != LogFormatVersion {
+		t.Errorf("Unexpected migrated entry: %+v", entries[1])
+	}
+
+	// Migrating again is a no-op
+	migrations, err = MigrateLogs(dir, false)
+	if err != nil || migrations[1].Migrated {
+		t.Errorf("Expected second migration to do nothing, got %+v, %v", migrations, err)
+	}
+}
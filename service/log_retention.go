@@ -0,0 +1,375 @@
+// Package service provides core functionality for the run-script-service daemon.
+package service
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LogSegmentBytes is the size at which the active log file is rotated into a gzipped segment
+const LogSegmentBytes = 1 << 20
+
+// LogSegmentExt is the extension of rotated log segments, <name>.log.<seq>.gz
+const LogSegmentExt = ".gz"
+
+// DefaultLogCompactionInterval is how often the daemon enforces log retention
+const DefaultLogCompactionInterval = 10 * time.Minute
+
+// RetentionPolicy limits the run history kept for a script. Zero fields are unlimited.
+type RetentionPolicy struct {
+	KeepRuns int   `json:"keep_runs,omitempty"` // most recent runs kept
+	KeepDays int   `json:"keep_days,omitempty"` // runs older than this are dropped
+	MaxBytes int64 `json:"max_bytes,omitempty"` // cap on the script's log files on disk
+}
+
+// IsZero reports whether the policy keeps everything
+func (p RetentionPolicy) IsZero() bool {
+	return p.KeepRuns == 0 && p.KeepDays == 0 && p.MaxBytes == 0
+}
+
+// segmentBytes returns the active file size that triggers a rotation
+func (p RetentionPolicy) segmentBytes() int64 {
+	// Rotate often enough that the byte cap can be met by dropping whole segments
+	if p.MaxBytes > 0 && p.MaxBytes/4 < LogSegmentBytes {
+		return p.MaxBytes / 4
+	}
+	return LogSegmentBytes
+}
+
+// RetentionFor returns the effective retention of a script: log_retention overridden
+// field by field by the script's own retention. Without a run limit the script's
+// max_log_lines runs are kept.
+func (c *ServiceConfig) RetentionFor(script *ScriptConfig) RetentionPolicy {
+	var policy RetentionPolicy
+	if c.LogRetention != nil {
+		policy = *c.LogRetention
+	}
+	if override := script.Retention; override != nil {
+		if override.KeepRuns != 0 {
+			policy.KeepRuns = override.KeepRuns
+		}
+		if override.KeepDays != 0 {
+			policy.KeepDays = override.KeepDays
+		}
+		if override.MaxBytes != 0 {
+			policy.MaxBytes = override.MaxBytes
+		}
+	}
+	if policy.KeepRuns == 0 {
+		policy.KeepRuns = script.MaxLogLines
+	}
+	return policy
+}
+
+// CompactionResult describes one pass of the compactor over a script's logs
+type CompactionResult struct {
+	Script  string `json:"script"`
+	Rotated bool   `json:"rotated"` // the active file was moved into a new segment
+	Removed int    `json:"removed"` // runs dropped by the retention policy
+	Bytes   int64  `json:"bytes"`   // size of the script's log files afterwards
+}
+
+// logSegmentPath returns the path of rotated segment seq of a script
+func logSegmentPath(dir, scriptName string, seq int) string {
+	return fmt.Sprintf("%s.%d%s", LogFilePath(dir, scriptName), seq, LogSegmentExt)
+}
+
+// listLogSegments returns the sequence numbers of a script's rotated segments, oldest first
+func listLogSegments(dir, scriptName string) ([]int, error) {
+	prefix := LogFilePath(dir, scriptName) + "."
+	matches, err := filepath.Glob(prefix + "*" + LogSegmentExt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list log segments: %v", err)
+	}
+
+	seqs := make([]int, 0, len(matches))
+	for _, match := range matches {
+		seq, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(match, prefix), LogSegmentExt))
+		if err != nil || seq <= 0 {
+			continue
+		}
+		seqs = append(seqs, seq)
+	}
+	sort.Ints(seqs)
+	return seqs, nil
+}
+
+// readLogSegment reads the entries of a gzipped segment
+func readLogSegment(path, scriptName string) ([]LogEntry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open log segment: %v", err)
+	}
+	defer file.Close()
+
+	reader, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read log segment %s: %v", path, err)
+	}
+	defer reader.Close()
+
+	entries, _, err := readLogRecords(reader, scriptName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read log segment %s: %v", path, err)
+	}
+	return entries, nil
+}
+
+// writeLogSegment atomically writes entries to a gzipped segment
+func writeLogSegment(path string, entries []LogEntry) error {
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	for i := range entries {
+		record, err := MarshalLogRecord(&entries[i])
+		if err != nil {
+			return err
+		}
+		if _, err := writer.Write(record); err != nil {
+			return fmt.Errorf("failed to compress log segment: %v", err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("failed to compress log segment: %v", err)
+	}
+	return WriteFileAtomic(path, buf.Bytes(), 0600)
+}
+
+// ReadLogHistory returns every run of a script from its rotated segments and active log, oldest first
+func ReadLogHistory(dir, scriptName string) ([]LogEntry, error) {
+	return readRecentHistory(dir, scriptName, 0)
+}
+
+// scanHistory passes the runs of a script to visit newest first, reading the active log and
+// then the segments newest first, until visit returns false. Segments past that are not read.
+func scanHistory(dir, scriptName string, visit func(entry *LogEntry) bool) error {
+	entries, _, err := ReadLogFile(LogFilePath(dir, scriptName), scriptName)
+	if err != nil {
+		return err
+	}
+	seqs, err := listLogSegments(dir, scriptName)
+	if err != nil {
+		return err
+	}
+
+	for i := len(seqs); ; i-- {
+		for j := len(entries) - 1; j >= 0; j-- {
+			if !visit(&entries[j]) {
+				return nil
+			}
+		}
+		if i == 0 {
+			return nil
+		}
+		if entries, err = readLogSegment(logSegmentPath(dir, scriptName, seqs[i-1]), scriptName); err != nil {
+			return err
+		}
+	}
+}
+
+// readRecentHistory returns at least the last n runs of a script, reading segments newest
+// first until enough are found. n <= 0 reads everything.
+func readRecentHistory(dir, scriptName string, n int) ([]LogEntry, error) {
+	entries, _, err := ReadLogFile(LogFilePath(dir, scriptName), scriptName)
+	if err != nil {
+		return nil, err
+	}
+	seqs, err := listLogSegments(dir, scriptName)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := len(seqs) - 1; i >= 0 && (n <= 0 || len(entries) < n); i-- {
+		segment, err := readLogSegment(logSegmentPath(dir, scriptName, seqs[i]), scriptName)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(segment, entries...)
+	}
+	return entries, nil
+}
+
+// logFileSet is one file holding part of a script's history
+type logFileSet struct {
+	path    string
+	active  bool
+	entries []LogEntry
+}
+
+// compactLogFiles rotates a script's active log when it is large enough and applies the
+// retention policy across segments and the active log. The caller must keep others from
+// writing the active log meanwhile.
+func compactLogFiles(dir, scriptName string, policy RetentionPolicy, now time.Time) (CompactionResult, error) {
+	result := CompactionResult{Script: scriptName}
+	activePath := LogFilePath(dir, scriptName)
+
+	seqs, err := listLogSegments(dir, scriptName)
+	if err != nil {
+		return result, err
+	}
+
+	// Rotate the active file into a new segment
+	if info, statErr := os.Stat(activePath); statErr == nil && info.Size() >= policy.segmentBytes() {
+		entries, _, readErr := ReadLogFile(activePath, scriptName)
+		if readErr != nil {
+			return result, readErr
+		}
+		next := 1
+		if len(seqs) > 0 {
+			next = seqs[len(seqs)-1] + 1
+		}
+		if err := writeLogSegment(logSegmentPath(dir, scriptName, next), entries); err != nil {
+			return result, fmt.Errorf("failed to rotate log file: %v", err)
+		}
+		if err := os.Truncate(activePath, 0); err != nil {
+			return result, fmt.Errorf("failed to rotate log file: %v", err)
+		}
+		seqs = append(seqs, next)
+		result.Rotated = true
+	}
+
+	// Load the whole history, oldest file first
+	files := make([]*logFileSet, 0, len(seqs)+1)
+	total := 0
+	for _, seq := range seqs {
+		path := logSegmentPath(dir, scriptName, seq)
+		entries, err := readLogSegment(path, scriptName)
+		if err != nil {
+			return result, err
+		}
+		files = append(files, &logFileSet{path: path, entries: entries})
+		total += len(entries)
+	}
+	activeEntries, _, err := ReadLogFile(activePath, scriptName)
+	if err != nil {
+		return result, err
+	}
+	files = append(files, &logFileSet{path: activePath, active: true, entries: activeEntries})
+	total += len(activeEntries)
+
+	// Runs beyond the count limit or older than the age limit are dropped from the front
+	drop := 0
+	if policy.KeepRuns > 0 && total > policy.KeepRuns {
+		drop = total - policy.KeepRuns
+	}
+	if policy.KeepDays > 0 {
+		cutoff := now.Add(-time.Duration(policy.KeepDays) * 24 * time.Hour)
+		expired := 0
+	counting:
+		for _, file := range files {
+			for i := range file.entries {
+				if !file.entries[i].Timestamp.Before(cutoff) {
+					break counting
+				}
+				expired++
+			}
+		}
+		if expired > drop {
+			drop = expired
+		}
+	}
+	for _, file := range files {
+		if drop == 0 {
+			break
+		}
+		n := drop
+		if n > len(file.entries) {
+			n = len(file.entries)
+		}
+		if err := file.trim(n); err != nil {
+			return result, err
+		}
+		result.Removed += n
+		drop -= n
+	}
+
+	// Enforce the byte cap by dropping whole segments, then the oldest active runs
+	if policy.MaxBytes > 0 {
+		for _, file := range files {
+			if logFilesSize(files) <= policy.MaxBytes {
+				break
+			}
+			n := len(file.entries)
+			if file.active {
+				n = file.excessRuns(logFilesSize(files) - policy.MaxBytes)
+			}
+			if err := file.trim(n); err != nil {
+				return result, err
+			}
+			result.Removed += n
+		}
+	}
+
+	result.Bytes = logFilesSize(files)
+	return result, nil
+}
+
+// trim drops the first n entries of the file, removing emptied segments
+func (f *logFileSet) trim(n int) error {
+	if n <= 0 {
+		return nil
+	}
+	f.entries = f.entries[n:]
+
+	switch {
+	case f.active:
+		if err := WriteLogFile(f.path, f.entries); err != nil {
+			return fmt.Errorf("failed to trim log file: %v", err)
+		}
+	case len(f.entries) == 0:
+		if err := os.Remove(f.path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove log segment: %v", err)
+		}
+	default:
+		if err := writeLogSegment(f.path, f.entries); err != nil {
+			return fmt.Errorf("failed to trim log segment: %v", err)
+		}
+	}
+	return nil
+}
+
+// excessRuns returns how many leading runs must go to shrink the file by at least excess bytes
+func (f *logFileSet) excessRuns(excess int64) int {
+	var freed int64
+	for i := range f.entries {
+		if freed >= excess {
+			return i
+		}
+		record, err := MarshalLogRecord(&f.entries[i])
+		if err == nil {
+			freed += int64(len(record))
+		}
+	}
+	return len(f.entries)
+}
+
+// logFilesSize returns the size on disk of the files
+func logFilesSize(files []*logFileSet) int64 {
+	var size int64
+	for _, file := range files {
+		if info, err := os.Stat(file.path); err == nil {
+			size += info.Size()
+		}
+	}
+	return size
+}
+
+// removeLogSegments deletes all rotated segments of a script
+func removeLogSegments(dir, scriptName string) error {
+	seqs, err := listLogSegments(dir, scriptName)
+	if err != nil {
+		return err
+	}
+	for _, seq := range seqs {
+		if err := os.Remove(logSegmentPath(dir, scriptName, seq)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove log segment: %v", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,189 @@
+package service
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+)
+
+// writeRuns writes n runs of a script one hour apart, ending at end
+func writeRuns(t *testing.T, path, name string, n int, end time.Time) {
+	t.Helper()
+	entries := make([]LogEntry, n)
+	for i := range entries {
+		entries[i] = LogEntry{Timestamp: end.Add(time.Duration(i-n+1) * time.Hour), ScriptName: name, ExitCode: i}
+	}
+	if err := WriteLogFile(path, entries); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestServiceConfig_RetentionFor(t *testing.T) {
+	config := &ServiceConfig{LogRetention: &RetentionPolicy{KeepDays: 7, MaxBytes: 1000}}
+
+	policy := config.RetentionFor(&ScriptConfig{MaxLogLines: 50, Retention: &RetentionPolicy{KeepDays: 1}})
+	if policy != (RetentionPolicy{KeepRuns: 50, KeepDays: 1, MaxBytes: 1000}) {
+		t.Errorf("Unexpected merged policy: %+v", policy)
+	}
+
+	policy = config.RetentionFor(&ScriptConfig{MaxLogLines: 50, Retention: &RetentionPolicy{KeepRuns: 5}})
+	if policy.KeepRuns != 5 || policy.KeepDays != 7 {
+		t.Errorf("Expected keep_runs override and global keep_days, got %+v", policy)
+	}
+
+	if !(&ServiceConfig{}).RetentionFor(&ScriptConfig{}).IsZero() {
+		t.Error("Expected no retention without any limits")
+	}
+}
+
+func TestCompactLogFiles_RotatesAndKeepsRuns(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	path := LogFilePath(dir, "job")
+	writeRuns(t, path, "job", 10, now)
+
+	// A tiny byte cap forces a rotation into a gzipped segment
+	result, err := compactLogFiles(dir, "job", RetentionPolicy{MaxBytes: 100000}, now)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if result.Rotated {
+		t.Fatal("Expected no rotation below the segment size")
+	}
+
+	result, err = compactLogFiles(dir, "job", RetentionPolicy{MaxBytes: 400}, now)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if !result.Rotated {
+		t.Fatal("Expected the active log to be rotated")
+	}
+	if _, err := os.Stat(logSegmentPath(dir, "job", 1)); err != nil {
+		t.Fatalf("Expected segment 1 to exist: %v", err)
+	}
+	if result.Bytes > 400 {
+		t.Errorf("Expected logs to fit in 400 bytes, got %d", result.Bytes)
+	}
+
+	// Segments are read back transparently
+	writeRuns(t, path, "job", 3, now)
+	history, err := ReadLogHistory(dir, "job")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(history) != 10-result.Removed+3 {
+		t.Errorf("Expected %d runs in history, got %d", 10-result.Removed+3, len(history))
+	}
+
+	// keep_runs drops the oldest runs across segments and the active file
+	result, err = compactLogFiles(dir, "job", RetentionPolicy{KeepRuns: 2}, now)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	history, _ = ReadLogHistory(dir, "job")
+	if len(history) != 2 || history[1].ExitCode != 2 {
+		t.Errorf("Expected the 2 most recent runs, got %+v", history)
+	}
+	if seqs, _ := listLogSegments(dir, "job"); len(seqs) != 0 {
+		t.Errorf("Expected emptied segments to be removed, got %v", seqs)
+	}
+}
+
+func TestCompactLogFiles_KeepDays(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	writeRuns(t, LogFilePath(dir, "job"), "job", 72, now) // three days of hourly runs
+
+	result, err := compactLogFiles(dir, "job", RetentionPolicy{KeepDays: 1}, now)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	history, _ := ReadLogHistory(dir, "job")
+	if result.Removed != 47 || len(history) != 25 {
+		t.Errorf("Expected runs of the last day to remain, removed %d, kept %d", result.Removed, len(history))
+	}
+}
+
+func TestScriptManager_CompactLogs(t *testing.T) {
+	dir := t.TempDir()
+	manager := NewScriptManager(&ServiceConfig{
+		Scripts:      []ScriptConfig{{Name: "job", Path: "./job.sh", MaxLogLines: 100, Retention: &RetentionPolicy{KeepRuns: 3}}},
+		LogRetention: &RetentionPolicy{KeepRuns: 1},
+	})
+	manager.SetLogDir(dir)
+	writeRuns(t, LogFilePath(dir, "job"), "job", 5, time.Now())
+	writeRuns(t, LogFilePath(dir, "removed"), "removed", 5, time.Now())
+
+	// The manager has already loaded the script, compaction must refresh it
+	if entries, _ := manager.GetLogManager().QueryLogs(&LogQuery{ScriptName: "job"}); len(entries) != 5 {
+		t.Fatalf("Expected 5 runs before compaction, got %d", len(entries))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	manager.StartLogCompactor(ctx, time.Hour)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		job, _ := manager.GetLogManager().QueryLogs(&LogQuery{ScriptName: "job"})
+		removed, _ := manager.GetLogManager().QueryLogs(&LogQuery{ScriptName: "removed"})
+		if len(job) == 3 && len(removed) == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected 3 runs of job and 1 of removed script, got %d and %d", len(job), len(removed))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestLogManager_QueryLogs_ReadsSegmentsOnlyAsNeeded(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now().Truncate(time.Second)
+	runs := func(from, n int) []LogEntry {
+		entries := make([]LogEntry, n)
+		for i := range entries {
+			entries[i] = LogEntry{Timestamp: now.Add(time.Duration(from+i-12) * time.Hour), ScriptName: "job", ExitCode: from + i}
+		}
+		return entries
+	}
+	if err := writeLogSegment(logSegmentPath(dir, "job", 1), runs(0, 4)); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeLogSegment(logSegmentPath(dir, "job", 2), runs(4, 4)); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteLogFile(LogFilePath(dir, "job"), runs(8, 4)); err != nil {
+		t.Fatal(err)
+	}
+	// Reading the oldest segment fails, so results that need it fall back to the runs in memory
+	if err := os.WriteFile(logSegmentPath(dir, "job", 1), []byte("not gzip"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	lm := NewLogManager(dir)
+	entries, err := lm.QueryLogs(&LogQuery{ScriptName: "job", Limit: 6})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 6 || entries[0].ExitCode != 6 || entries[5].ExitCode != 11 {
+		t.Errorf("Expected runs 6 to 11 from the newest segment and the active log, got %+v", entries)
+	}
+
+	entries, _ = lm.QueryLogs(&LogQuery{ScriptName: "job", StartTime: now.Add(-7 * time.Hour)})
+	if len(entries) != 7 || entries[0].ExitCode != 5 {
+		t.Errorf("Expected the runs since the start time without reading older segments, got %d runs", len(entries))
+	}
+
+	entries, _ = lm.QueryLogs(&LogQuery{ScriptName: "job"})
+	if len(entries) == 12 {
+		t.Error("Expected the full history to need the unreadable segment")
+	}
+
+	exitCode := 9
+	entries, _ = lm.QueryLogs(&LogQuery{ExitCode: &exitCode, Limit: 1})
+	if len(entries) != 1 || entries[0].ExitCode != 9 {
+		t.Errorf("Expected the newest matching run, got %+v", entries)
+	}
+}
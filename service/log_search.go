@@ -0,0 +1,433 @@
+// Package service provides core functionality for the run-script-service daemon.
+package service
+
+import (
+	"encoding/base64"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"regexp/syntax"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultSearchLimit is the page size of a search without a limit
+const DefaultSearchLimit = 50
+
+// Output streams a search can be restricted to
+const (
+	StreamStdout = "stdout"
+	StreamStderr = "stderr" // includes the error of runs that could not start
+)
+
+// LogSearchResult is one page of search results, newest run first
+type LogSearchResult struct {
+	Entries    []LogEntry `json:"entries"`
+	NextCursor string     `json:"next_cursor,omitempty"` // empty on the last page
+}
+
+// logMatcher is a LogQuery prepared for matching many entries
+type logMatcher struct {
+	query    *LogQuery
+	text     string
+	regex    *regexp.Regexp
+	literals []string // text every match must contain, used to narrow the index
+}
+
+// compileLogQuery validates a query and prepares it for matching
+func compileLogQuery(query *LogQuery) (*logMatcher, error) {
+	switch query.Stream {
+	case "", StreamStdout, StreamStderr:
+	default:
+		return nil, fmt.Errorf("invalid stream '%s' (expected stdout or stderr)", query.Stream)
+	}
+	if query.MinDuration < 0 || query.MaxDuration < 0 {
+		return nil, fmt.Errorf("duration bounds cannot be negative")
+	}
+
+	matcher := &logMatcher{query: query, text: query.Text}
+	if query.IgnoreCase {
+		matcher.text = strings.ToLower(query.Text)
+	}
+	if query.Text != "" {
+		matcher.literals = append(matcher.literals, query.Text)
+	}
+	if query.Regex != "" {
+		pattern := query.Regex
+		if query.IgnoreCase {
+			pattern = "(?i)" + pattern
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex: %v", err)
+		}
+		matcher.regex = re
+		matcher.literals = append(matcher.literals, requiredLiterals(pattern)...)
+	}
+	return matcher, nil
+}
+
+// olderThanStart reports whether an entry ran before the query's start time, so the runs
+// before it cannot match either
+func (m *logMatcher) olderThanStart(entry *LogEntry) bool {
+	return !m.query.StartTime.IsZero() && entry.Timestamp.Before(m.query.StartTime)
+}
+
+// matches reports whether an entry satisfies every criterion of the query
+func (m *logMatcher) matches(entry *LogEntry) bool {
+	query := m.query
+	if query.ScriptName != "" && entry.ScriptName != query.ScriptName {
+		return false
+	}
+	if !query.StartTime.IsZero() && entry.Timestamp.Before(query.StartTime) {
+		return false
+	}
+	if !query.EndTime.IsZero() && entry.Timestamp.After(query.EndTime) {
+		return false
+	}
+	if query.ExitCode != nil && entry.ExitCode != *query.ExitCode {
+		return false
+	}
+	if query.MinDuration > 0 && entry.Duration < query.MinDuration {
+		return false
+	}
+	if query.MaxDuration > 0 && entry.Duration > query.MaxDuration {
+		return false
+	}
+	if query.Trigger != "" && entry.Trigger != query.Trigger {
+		return false
+	}
+	if m.text == "" && m.regex == nil {
+		return true
+	}
+
+	for _, output := range m.outputs(entry) {
+		if m.text != "" {
+			if query.IgnoreCase {
+				output = strings.ToLower(output)
+			}
+			if !strings.Contains(output, m.text) {
+				continue
+			}
+		}
+		if m.regex != nil && !m.regex.MatchString(output) {
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+// outputs returns the texts of an entry the query searches
+func (m *logMatcher) outputs(entry *LogEntry) []string {
+	switch m.query.Stream {
+	case StreamStdout:
+		return []string{entry.Stdout}
+	case StreamStderr:
+		return []string{entry.Stderr, entry.Error}
+	default:
+		return []string{entry.Stdout, entry.Stderr, entry.Error}
+	}
+}
+
+// requiredLiterals returns literal strings any match of a regular expression must contain
+func requiredLiterals(pattern string) []string {
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return nil
+	}
+	re = re.Simplify()
+
+	var literals []string
+	switch re.Op {
+	case syntax.OpLiteral:
+		literals = append(literals, string(re.Rune))
+	case syntax.OpConcat:
+		for _, sub := range re.Sub {
+			if sub.Op == syntax.OpLiteral {
+				literals = append(literals, string(sub.Rune))
+			}
+		}
+	}
+	return literals
+}
+
+// trigram is three consecutive bytes of lower-cased output
+type trigram [3]byte
+
+// logRef locates a run in a script's log files: the segment sequence number, 0 for the
+// active log, and the run's position in that file
+type logRef struct {
+	seq, pos int32
+}
+
+// logIndex is a trigram index over the full run history of a script. It holds where each
+// run is stored rather than the run itself; candidates are read back from disk.
+type logIndex struct {
+	dir, scriptName string
+	runs            []logRef
+	active          int32               // runs indexed from the active log
+	grams           map[trigram][]int32 // positions in runs, ascending
+}
+
+// newLogIndex indexes a script's segments and active log, reading one file at a time
+func newLogIndex(dir, scriptName string) (*logIndex, error) {
+	index := &logIndex{dir: dir, scriptName: scriptName, grams: make(map[trigram][]int32)}
+	seqs, err := listLogSegments(dir, scriptName)
+	if err != nil {
+		return nil, err
+	}
+	for _, seq := range seqs {
+		entries, err := index.readFile(int32(seq))
+		if err != nil {
+			return nil, err
+		}
+		for i := range entries {
+			index.add(&entries[i], logRef{seq: int32(seq), pos: int32(i)})
+		}
+	}
+
+	entries, err := index.readFile(0)
+	if err != nil {
+		return nil, err
+	}
+	for i := range entries {
+		index.appendRun(&entries[i])
+	}
+	return index, nil
+}
+
+// appendRun indexes a run appended to the active log
+func (idx *logIndex) appendRun(entry *LogEntry) {
+	idx.add(entry, logRef{pos: idx.active})
+	idx.active++
+}
+
+// add indexes the output of a run stored at ref
+func (idx *logIndex) add(entry *LogEntry, ref logRef) {
+	pos := int32(len(idx.runs))
+	idx.runs = append(idx.runs, ref)
+
+	seen := make(map[trigram]bool)
+	for _, text := range []string{entry.Stdout, entry.Stderr, entry.Error} {
+		lower := strings.ToLower(text)
+		for i := 0; i+3 <= len(lower); i++ {
+			gram := trigram{lower[i], lower[i+1], lower[i+2]}
+			if !seen[gram] {
+				seen[gram] = true
+				idx.grams[gram] = append(idx.grams[gram], pos)
+			}
+		}
+	}
+}
+
+// readFile reads the runs of segment seq, or of the active log when seq is 0
+func (idx *logIndex) readFile(seq int32) ([]LogEntry, error) {
+	if seq == 0 {
+		entries, _, err := ReadLogFile(LogFilePath(idx.dir, idx.scriptName), idx.scriptName)
+		return entries, err
+	}
+	return readLogSegment(logSegmentPath(idx.dir, idx.scriptName, int(seq)), idx.scriptName)
+}
+
+// candidates returns the positions of entries that may contain every literal.
+// ok is false when no literal is long enough to use the index.
+func (idx *logIndex) candidates(literals []string) (positions []int32, ok bool) {
+	for _, literal := range literals {
+		lower := strings.ToLower(literal)
+		for i := 0; i+3 <= len(lower); i++ {
+			postings := idx.grams[trigram{lower[i], lower[i+1], lower[i+2]}]
+			if !ok {
+				positions, ok = postings, true
+			} else {
+				positions = intersectPostings(positions, postings)
+			}
+			if len(positions) == 0 {
+				return nil, true
+			}
+		}
+	}
+	return positions, ok
+}
+
+// intersectPostings returns the positions present in both ascending lists
+func intersectPostings(a, b []int32) []int32 {
+	result := make([]int32, 0, len(a))
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i] < b[j]:
+			i++
+		case a[i] > b[j]:
+			j++
+		default:
+			result = append(result, a[i])
+			i++
+			j++
+		}
+	}
+	return result
+}
+
+// search returns the runs matching m, reading the files holding index candidates
+func (idx *logIndex) search(m *logMatcher) ([]LogEntry, error) {
+	var matches []LogEntry
+	positions, ok := idx.candidates(m.literals)
+	if !ok {
+		err := scanHistory(idx.dir, idx.scriptName, func(entry *LogEntry) bool {
+			if m.matches(entry) {
+				matches = append(matches, *entry)
+			}
+			return true
+		})
+		return matches, err
+	}
+
+	// Positions are ascending, so the runs of each file are consecutive
+	var entries []LogEntry
+	loaded := int32(-1)
+	for _, pos := range positions {
+		ref := idx.runs[pos]
+		if ref.seq != loaded {
+			var err error
+			if entries, err = idx.readFile(ref.seq); err != nil {
+				return nil, err
+			}
+			loaded = ref.seq
+		}
+		if int(ref.pos) < len(entries) && m.matches(&entries[ref.pos]) {
+			matches = append(matches, entries[ref.pos])
+		}
+	}
+	return matches, nil
+}
+
+// search finds matching runs in the script's whole history, building the index on first use
+func (sl *ScriptLogger) search(m *logMatcher) ([]LogEntry, error) {
+	sl.mutex.Lock()
+	defer sl.mutex.Unlock()
+	sl.reloadIfChanged()
+
+	if sl.index == nil {
+		index, err := newLogIndex(filepath.Dir(sl.logPath), sl.scriptName)
+		if err != nil {
+			return nil, err
+		}
+		sl.index = index
+	}
+	return sl.index.search(m)
+}
+
+// Search finds runs matching the query across scripts, newest first, one page at a time.
+// Pass the returned NextCursor as query.Cursor to fetch the following page.
+func (lm *LogManager) Search(query *LogQuery) (*LogSearchResult, error) {
+	matcher, err := compileLogQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	after, err := parseLogCursor(query.Cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	matches, err := lm.searchAll(matcher)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return newerRun(&matches[i], &matches[j])
+	})
+
+	if after != nil {
+		start := sort.Search(len(matches), func(i int) bool {
+			return newerRun(after, &matches[i])
+		})
+		matches = matches[start:]
+	}
+
+	limit := query.Limit
+	if limit <= 0 {
+		limit = DefaultSearchLimit
+	}
+	if matches == nil {
+		matches = make([]LogEntry, 0)
+	}
+	result := &LogSearchResult{Entries: matches}
+	if len(matches) > limit {
+		result.Entries = matches[:limit]
+		result.NextCursor = formatLogCursor(&matches[limit-1])
+	}
+	return result, nil
+}
+
+// searchAll returns every matching run in the history of the queried scripts, unordered
+func (lm *LogManager) searchAll(matcher *logMatcher) ([]LogEntry, error) {
+	var matches []LogEntry
+	for _, logger := range lm.queriedLoggers(matcher.query.ScriptName) {
+		found, err := logger.search(matcher)
+		if err != nil {
+			return nil, err
+		}
+		matches = append(matches, found...)
+	}
+	return matches, nil
+}
+
+// newerRun orders runs newest first, then by script name and run ID
+func newerRun(a, b *LogEntry) bool {
+	if !a.Timestamp.Equal(b.Timestamp) {
+		return a.Timestamp.After(b.Timestamp)
+	}
+	if a.ScriptName != b.ScriptName {
+		return a.ScriptName < b.ScriptName
+	}
+	return a.RunID < b.RunID
+}
+
+// formatLogCursor encodes the position after entry
+func formatLogCursor(entry *LogEntry) string {
+	raw := strconv.FormatInt(entry.Timestamp.UnixNano(), 10) + "|" + entry.RunID + "|" + entry.ScriptName
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// parseLogCursor decodes a cursor into the last run of the previous page
+func parseLogCursor(cursor string) (*LogEntry, error) {
+	if cursor == "" {
+		return nil, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+	// Cursors are nanos|run ID|script; earlier ones had no run ID
+	parts := strings.SplitN(string(raw), "|", 3)
+	nanos, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil || len(parts) < 2 {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+	if len(parts) == 2 {
+		return &LogEntry{Timestamp: time.Unix(0, nanos), ScriptName: parts[1]}, nil
+	}
+	return &LogEntry{Timestamp: time.Unix(0, nanos), RunID: parts[1], ScriptName: parts[2]}, nil
+}
+
+// ParseLogTime parses a search time bound: an RFC 3339 timestamp, a date (2006-01-02, local
+// time) or an age such as 90m, 12h or 7d that is subtracted from now
+func ParseLogTime(value string, now time.Time) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return t, nil
+	}
+	if days, found := strings.CutSuffix(value, "d"); found {
+		if n, err := strconv.Atoi(days); err == nil && n >= 0 {
+			return now.AddDate(0, 0, -n), nil
+		}
+	}
+	if age, err := time.ParseDuration(value); err == nil && age >= 0 {
+		return now.Add(-age), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time '%s' (expected RFC 3339, YYYY-MM-DD or an age like 7d)", value)
+}
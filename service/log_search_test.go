@@ -0,0 +1,249 @@
+package service
+
+import (
+	"encoding/base64"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// searchFixture writes runs of two scripts and returns a LogManager over them
+func searchFixture(t *testing.T) (*LogManager, time.Time) {
+	t.Helper()
+	dir := t.TempDir()
+	base := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	if err := WriteLogFile(LogFilePath(dir, "fetch"), []LogEntry{
+		{Timestamp: base, ScriptName: "fetch", Stdout: "ok", Duration: 100, Trigger: TriggerSchedule},
+		{Timestamp: base.Add(2 * time.Hour), ScriptName: "fetch", ExitCode: 7, Stderr: "dial tcp: Connection refused", Duration: 3000, Trigger: TriggerSchedule},
+		{Timestamp: base.Add(4 * time.Hour), ScriptName: "fetch", ExitCode: 7, Stderr: "connection refused again", Duration: 2500, Trigger: TriggerManual},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteLogFile(LogFilePath(dir, "report"), []LogEntry{
+		{Timestamp: base.Add(time.Hour), ScriptName: "report", Stdout: "connection refused by upstream", Duration: 50, Trigger: TriggerSchedule},
+		{Timestamp: base.Add(3 * time.Hour), ScriptName: "report", Stdout: "report sent", Duration: 60, Trigger: TriggerSchedule},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return NewLogManager(dir), base
+}
+
+func TestLogManager_Search(t *testing.T) {
+	lm, base := searchFixture(t)
+
+	tests := []struct {
+		name     string
+		query    LogQuery
+		expected []time.Duration // offsets from base of the expected runs, newest first
+	}{
+		{"substring", LogQuery{Text: "connection refused"}, []time.Duration{4 * time.Hour, time.Hour}},
+		{"ignore case", LogQuery{Text: "connection refused", IgnoreCase: true}, []time.Duration{4 * time.Hour, 2 * time.Hour, time.Hour}},
+		{"regex", LogQuery{Regex: `refused (again|by)`}, []time.Duration{4 * time.Hour, time.Hour}},
+		{"stream", LogQuery{Text: "refused", Stream: StreamStdout}, []time.Duration{time.Hour}},
+		{"script", LogQuery{Text: "refused", ScriptName: "fetch", IgnoreCase: true}, []time.Duration{4 * time.Hour, 2 * time.Hour}},
+		{"duration", LogQuery{MinDuration: 1000, MaxDuration: 2800}, []time.Duration{4 * time.Hour}},
+		{"trigger", LogQuery{Trigger: TriggerManual}, []time.Duration{4 * time.Hour}},
+		{"time range", LogQuery{StartTime: base.Add(time.Hour), EndTime: base.Add(3 * time.Hour), Text: "re"},
+			[]time.Duration{3 * time.Hour, 2 * time.Hour, time.Hour}},
+		{"no match", LogQuery{Text: "timeout"}, []time.Duration{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := lm.Search(&tt.query)
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			got := make([]time.Duration, 0, len(result.Entries))
+			for _, entry := range result.Entries {
+				got = append(got, entry.Timestamp.Sub(base))
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Expected runs at %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestLogManager_Search_Pagination(t *testing.T) {
+	lm, _ := searchFixture(t)
+
+	var pages [][]string
+	cursor := ""
+	for i := 0; i < 5; i++ {
+		result, err := lm.Search(&LogQuery{Limit: 2, Cursor: cursor})
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		var page []string
+		for _, entry := range result.Entries {
+			page = append(page, fmt.Sprintf("%s@%d", entry.ScriptName, entry.Timestamp.Hour()))
+		}
+		pages = append(pages, page)
+		if cursor = result.NextCursor; cursor == "" {
+			break
+		}
+	}
+
+	expected := [][]string{{"fetch@16", "report@15"}, {"fetch@14", "report@13"}, {"fetch@12"}}
+	if !reflect.DeepEqual(pages, expected) {
+		t.Errorf("Expected pages %v, got %v", expected, pages)
+	}
+
+	if _, err := lm.Search(&LogQuery{Cursor: "not a cursor!"}); err == nil {
+		t.Error("Expected error for invalid cursor")
+	}
+	if _, err := lm.Search(&LogQuery{Regex: "("}); err == nil {
+		t.Error("Expected error for invalid regex")
+	}
+	if _, err := lm.Search(&LogQuery{Stream: "both"}); err == nil {
+		t.Error("Expected error for invalid stream")
+	}
+}
+
+func TestLogManager_Search_IndexFollowsNewRuns(t *testing.T) {
+	lm, base := searchFixture(t)
+
+	// Build the index, then record a run after it
+	if _, err := lm.Search(&LogQuery{Text: "sent"}); err != nil {
+		t.Fatal(err)
+	}
+	entry := &LogEntry{Timestamp: base.Add(5 * time.Hour), ScriptName: "report", Stdout: "second report sent"}
+	if err := lm.GetLogger("report").AddEntry(entry); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := lm.Search(&LogQuery{Text: "report sent"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Entries) != 2 || result.Entries[0].Stdout != "second report sent" {
+		t.Errorf("Expected the new run to be found first, got %+v", result.Entries)
+	}
+}
+
+func TestLogManager_Search_TiedRuns(t *testing.T) {
+	dir := t.TempDir()
+	at := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	if err := WriteLogFile(LogFilePath(dir, "fetch"), []LogEntry{
+		{Timestamp: at, ScriptName: "fetch", RunID: "b2", Stdout: "second"},
+		{Timestamp: at, ScriptName: "fetch", RunID: "a1", Stdout: "first"},
+		{Timestamp: at, ScriptName: "fetch", RunID: "c3", Stdout: "third"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	lm := NewLogManager(dir)
+
+	var runs []string
+	cursor := ""
+	for i := 0; i < 5; i++ {
+		result, err := lm.Search(&LogQuery{Limit: 1, Cursor: cursor})
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		for _, entry := range result.Entries {
+			runs = append(runs, entry.RunID)
+		}
+		if cursor = result.NextCursor; cursor == "" {
+			break
+		}
+	}
+	if expected := []string{"a1", "b2", "c3"}; !reflect.DeepEqual(runs, expected) {
+		t.Errorf("Expected runs tied on time and script to be paged as %v, got %v", expected, runs)
+	}
+
+	// Cursors without a run ID are still accepted
+	old := base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("%d|fetch", at.Add(time.Second).UnixNano())))
+	if result, err := lm.Search(&LogQuery{Cursor: old}); err != nil || len(result.Entries) != 3 {
+		t.Errorf("Expected an older cursor to be accepted, got %v (error %v)", result, err)
+	}
+}
+
+func TestLogManager_Search_ReadsRunsFromSegments(t *testing.T) {
+	dir := t.TempDir()
+	base := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	if err := writeLogSegment(logSegmentPath(dir, "fetch", 1), []LogEntry{
+		{Timestamp: base, ScriptName: "fetch", Stdout: "timeout contacting upstream"},
+		{Timestamp: base.Add(time.Hour), ScriptName: "fetch", Stdout: "ok"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteLogFile(LogFilePath(dir, "fetch"), []LogEntry{
+		{Timestamp: base.Add(2 * time.Hour), ScriptName: "fetch", Stdout: "timeout again"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	lm := NewLogManager(dir)
+
+	result, err := lm.Search(&LogQuery{Text: "timeout"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Entries) != 2 || result.Entries[0].Stdout != "timeout again" || result.Entries[1].Stdout != "timeout contacting upstream" {
+		t.Errorf("Expected runs from the active log and the segment, got %+v", result.Entries)
+	}
+
+	index := lm.GetLogger("fetch").index
+	expected := []logRef{{seq: 1, pos: 0}, {seq: 1, pos: 1}, {seq: 0, pos: 0}}
+	if !reflect.DeepEqual(index.runs, expected) {
+		t.Errorf("Expected the index to locate runs at %v, got %v", expected, index.runs)
+	}
+}
+
+func TestLogIndex_Candidates(t *testing.T) {
+	dir := t.TempDir()
+	if err := WriteLogFile(LogFilePath(dir, "fetch"), []LogEntry{{Stdout: "alpha beta"}, {Stderr: "Beta gamma"}, {Error: "delta"}}); err != nil {
+		t.Fatal(err)
+	}
+	index, err := newLogIndex(dir, "fetch")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if positions, ok := index.candidates([]string{"beta"}); !ok || !reflect.DeepEqual(positions, []int32{0, 1}) {
+		t.Errorf("Expected candidates [0 1], got %v (ok %v)", positions, ok)
+	}
+	if positions, ok := index.candidates([]string{"beta", "gam"}); !ok || !reflect.DeepEqual(positions, []int32{1}) {
+		t.Errorf("Expected candidates [1], got %v (ok %v)", positions, ok)
+	}
+	if _, ok := index.candidates([]string{"ab"}); ok {
+		t.Error("Expected short literals not to use the index")
+	}
+}
+
+func TestRequiredLiterals(t *testing.T) {
+	if got := requiredLiterals(`connection refused`); !reflect.DeepEqual(got, []string{"connection refused"}) {
+		t.Errorf("Unexpected literals: %v", got)
+	}
+	if got := requiredLiterals(`error: \d+ retries`); !reflect.DeepEqual(got, []string{"error: ", " retries"}) {
+		t.Errorf("Unexpected literals: %v", got)
+	}
+	if got := requiredLiterals(`foo|bar`); len(got) != 0 {
+		t.Errorf("Expected no required literals for alternation, got %v", got)
+	}
+}
+
+func TestParseLogTime(t *testing.T) {
+	now := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		value    string
+		expected time.Time
+	}{
+		{"2025-03-01T08:00:00Z", time.Date(2025, 3, 1, 8, 0, 0, 0, time.UTC)},
+		{"2025-03-01", time.Date(2025, 3, 1, 0, 0, 0, 0, time.Local)},
+		{"7d", now.AddDate(0, 0, -7)},
+		{"90m", now.Add(-90 * time.Minute)},
+	}
+	for _, tt := range tests {
+		got, err := ParseLogTime(tt.value, now)
+		if err != nil || !got.Equal(tt.expected) {
+			t.Errorf("ParseLogTime(%q) = %v, %v; expected %v", tt.value, got, err, tt.expected)
+		}
+	}
+
+	if _, err := ParseLogTime("last week", now); err == nil {
+		t.Error("Expected error for unparseable time")
+	}
+}
@@ -0,0 +1,388 @@
+// Package service provides core functionality for the run-script-service daemon.
+package service
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Log sink types
+const (
+	LogSinkSyslog   = "syslog"   // RFC 5424 over a unix socket, UDP or TCP
+	LogSinkJournald = "journald" // systemd journal native protocol
+)
+
+// Default log sink endpoints and identity
+const (
+	DefaultSyslogAddress   = "/dev/log"
+	DefaultJournalAddress  = "/run/systemd/journal/socket"
+	DefaultLogSinkTag      = "run-script-service"
+	DefaultSyslogFacility  = "daemon"
+	logSinkWriteTimeout    = 5 * time.Second
+	syslogMaxMessageBytes  = 8192      // what common syslog daemons accept by default
+	journalMaxFieldBytes   = 64 * 1024 // keeps a record well below the socket's datagram limit
+	syslogEnterpriseNumber = 32473     // RFC 5612 documentation number, used in structured data IDs
+)
+
+// syslogFacilities maps facility names to their RFC 5424 codes
+var syslogFacilities = map[string]int{
+	"kern": 0, "user": 1, "mail": 2, "daemon": 3, "auth": 4, "syslog": 5, "lpr": 6, "news": 7,
+	"uucp": 8, "cron": 9, "authpriv": 10, "ftp": 11,
+	"local0": 16, "local1": 17, "local2": 18, "local3": 19, "local4": 20, "local5": 21, "local6": 22, "local7": 23,
+}
+
+// syslog severities used for runs
+const (
+	severityError   = 3
+	severityWarning = 4
+	severityInfo    = 6
+)
+
+// LogSinkConfig forwards finished runs to an external log collector in addition to the log files
+type LogSinkConfig struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`               // LogSinkSyslog or LogSinkJournald
+	Network  string `json:"network,omitempty"`  // syslog only: unixgram (default), unix, udp or tcp
+	Address  string `json:"address,omitempty"`  // socket path or host:port, defaults to the local daemon
+	Facility string `json:"facility,omitempty"` // syslog only, default daemon
+	Tag      string `json:"tag,omitempty"`      // syslog app name and journal SYSLOG_IDENTIFIER
+	Default  bool   `json:"default,omitempty"`  // used by scripts that do not list log_sinks
+}
+
+// LogSink receives every finished run of the scripts it is configured for
+type LogSink interface {
+	Send(entry *LogEntry) error
+	Close() error
+}
+
+// NewLogSink creates a sink from its configuration. Connections are opened on first use.
+func NewLogSink(cfg LogSinkConfig) (LogSink, error) {
+	tag := cfg.Tag
+	if tag == "" {
+		tag = DefaultLogSinkTag
+	}
+
+	switch cfg.Type {
+	case LogSinkSyslog:
+		network, address := cfg.Network, cfg.Address
+		if network == "" {
+			network = "unixgram"
+		}
+		if address == "" {
+			if network != "unix" && network != "unixgram" {
+				return nil, fmt.Errorf("log sink '%s': address is required for network %s", cfg.Name, network)
+			}
+			address = DefaultSyslogAddress
+		}
+		if network != "unix" && network != "unixgram" && network != "udp" && network != "tcp" {
+			return nil, fmt.Errorf("log sink '%s': invalid network '%s' (expected unixgram, unix, udp or tcp)", cfg.Name, network)
+		}
+		facilityName := cfg.Facility
+		if facilityName == "" {
+			facilityName = DefaultSyslogFacility
+		}
+		facility, ok := syslogFacilities[facilityName]
+		if !ok {
+			return nil, fmt.Errorf("log sink '%s': unknown syslog facility '%s'", cfg.Name, facilityName)
+		}
+		hostname, _ := os.Hostname()
+		return &syslogSink{
+			conn:     sinkConn{network: network, address: address},
+			facility: facility,
+			tag:      tag,
+			hostname: hostname,
+		}, nil
+	case LogSinkJournald:
+		address := cfg.Address
+		if address == "" {
+			address = DefaultJournalAddress
+		}
+		return &journaldSink{conn: sinkConn{network: "unixgram", address: address}, tag: tag}, nil
+	default:
+		return nil, fmt.Errorf("log sink '%s': invalid type '%s' (expected syslog or journald)", cfg.Name, cfg.Type)
+	}
+}
+
+// sinkConn is a lazily dialed connection that is redialed once when a write fails
+type sinkConn struct {
+	network string
+	address string
+	conn    net.Conn
+	mutex   sync.Mutex
+}
+
+// write sends one message, dialing first if needed
+func (c *sinkConn) write(message []byte) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	var err error
+	for attempt := 0; attempt < 2; attempt++ {
+		if c.conn == nil {
+			c.conn, err = net.DialTimeout(c.network, c.address, logSinkWriteTimeout)
+			if err != nil {
+				return fmt.Errorf("failed to connect to %s %s: %v", c.network, c.address, err)
+			}
+		}
+		_ = c.conn.SetWriteDeadline(time.Now().Add(logSinkWriteTimeout))
+		if _, err = c.conn.Write(message); err == nil {
+			return nil
+		}
+		c.conn.Close()
+		c.conn = nil
+	}
+	return fmt.Errorf("failed to write to %s %s: %v", c.network, c.address, err)
+}
+
+// stream reports whether messages need framing on this connection
+func (c *sinkConn) stream() bool {
+	return c.network == "tcp" || c.network == "unix"
+}
+
+// close closes the connection if it is open
+func (c *sinkConn) close() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	c.conn = nil
+	return err
+}
+
+// runSummary is the one-line description of a run used as the log message
+func runSummary(entry *LogEntry) string {
+	if entry.Error != "" {
+		return fmt.Sprintf("%s failed to run: %s", entry.ScriptName, entry.Error)
+	}
+	return fmt.Sprintf("%s exited with code %d after %dms", entry.ScriptName, entry.ExitCode, entry.Duration)
+}
+
+// runSeverity returns the syslog severity of a run
+func runSeverity(entry *LogEntry) int {
+	switch entry.RunOutcome() {
+	case OutcomeFailure:
+		return severityError
+	case OutcomeWarning:
+		return severityWarning
+	default:
+		return severityInfo
+	}
+}
+
+// syslogSink writes RFC 5424 messages with the run in structured data
+type syslogSink struct {
+	conn     sinkConn
+	facility int
+	tag      string
+	hostname string
+}
+
+// Send implements LogSink
+func (s *syslogSink) Send(entry *LogEntry) error {
+	message := formatSyslogMessage(entry, s.facility, s.hostname, s.tag, os.Getpid())
+	if s.conn.stream() {
+		// RFC 6587 octet counting
+		message = append([]byte(strconv.Itoa(len(message))+" "), message...)
+	}
+	return s.conn.write(message)
+}
+
+// Close implements LogSink
+func (s *syslogSink) Close() error {
+	return s.conn.close()
+}
+
+// formatSyslogMessage renders a run as an RFC 5424 message:
+// <PRI>1 TIMESTAMP HOSTNAME APP-NAME PROCID MSGID [run@32473 ...] MSG
+func formatSyslogMessage(entry *LogEntry, facility int, hostname, tag string, pid int) []byte {
+	if hostname == "" {
+		hostname = "-"
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "<%d>1 %s %s %s %d run [run@%d",
+		facility*8+runSeverity(entry), entry.Timestamp.UTC().Format("2006-01-02T15:04:05.000000Z"),
+		hostname, tag, pid, syslogEnterpriseNumber)
+	for _, param := range runFields(entry) {
+		fmt.Fprintf(&buf, ` %s="%s"`, param.name, escapeSDValue(param.value))
+	}
+	buf.WriteString("] ")
+
+	buf.WriteString(runSummary(entry))
+	for _, output := range []struct{ label, text string }{{"STDOUT", entry.Stdout}, {"STDERR", entry.Stderr}} {
+		if output.text != "" {
+			fmt.Fprintf(&buf, "\n%s: %s", output.label, output.text)
+		}
+	}
+
+	message := buf.Bytes()
+	if len(message) > syslogMaxMessageBytes {
+		message = message[:syslogMaxMessageBytes]
+	}
+	return message
+}
+
+// escapeSDValue escapes a structured data parameter value
+func escapeSDValue(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`).Replace(value)
+}
+
+// runField is a structured field describing a run
+type runField struct {
+	name  string
+	value string
+}
+
+// runFields returns the structured fields of a run, empty ones left out
+func runFields(entry *LogEntry) []runField {
+	fields := []runField{
+		{"script", entry.ScriptName},
+		{"run_id", entry.RunID},
+		{"exit_code", strconv.Itoa(entry.ExitCode)},
+		{"duration_ms", strconv.FormatInt(entry.Duration, 10)},
+		{"trigger", entry.Trigger},
+		{"outcome", entry.Outcome},
+	}
+	kept := fields[:0]
+	for _, field := range fields {
+		if field.value != "" {
+			kept = append(kept, field)
+		}
+	}
+	return kept
+}
+
+// journaldSink writes records in the systemd journal native protocol
+type journaldSink struct {
+	conn sinkConn
+	tag  string
+}
+
+// Send implements LogSink
+func (s *journaldSink) Send(entry *LogEntry) error {
+	fields := map[string]string{
+		"MESSAGE":           runSummary(entry),
+		"PRIORITY":          strconv.Itoa(runSeverity(entry)),
+		"SYSLOG_IDENTIFIER": s.tag,
+		"STDOUT":            entry.Stdout,
+		"STDERR":            entry.Stderr,
+		"ERROR":             entry.Error,
+	}
+	for _, field := range runFields(entry) {
+		fields[strings.ToUpper(field.name)] = field.value
+	}
+	fields["SCRIPT_NAME"] = fields["SCRIPT"]
+	delete(fields, "SCRIPT")
+	return s.conn.write(formatJournalRecord(fields))
+}
+
+// Close implements LogSink
+func (s *journaldSink) Close() error {
+	return s.conn.close()
+}
+
+// formatJournalRecord encodes fields in the journal native protocol, sorted by name.
+// Values with a newline use the binary form: NAME\n, 64-bit little-endian length, value\n.
+func formatJournalRecord(fields map[string]string) []byte {
+	names := make([]string, 0, len(fields))
+	for name, value := range fields {
+		if value != "" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	for _, name := range names {
+		value := fields[name]
+		if len(value) > journalMaxFieldBytes {
+			value = value[:journalMaxFieldBytes]
+		}
+		if !strings.Contains(value, "\n") {
+			buf.WriteString(name + "=" + value + "\n")
+			continue
+		}
+		buf.WriteString(name + "\n")
+		_ = binary.Write(&buf, binary.LittleEndian, uint64(len(value)))
+		buf.WriteString(value + "\n")
+	}
+	return buf.Bytes()
+}
+
+// LogForwarder sends finished runs to the log sinks configured for each script
+type LogForwarder struct {
+	sinks    map[string]LogSink
+	defaults []string            // sinks of scripts without log_sinks
+	scripts  map[string][]string // sinks listed by each script
+}
+
+// NewLogForwarder creates the sinks of a configuration. Sinks that cannot be created are
+// skipped with a warning, so a bad sink never stops scripts from running.
+func NewLogForwarder(config *ServiceConfig) *LogForwarder {
+	forwarder := &LogForwarder{
+		sinks:   make(map[string]LogSink),
+		scripts: make(map[string][]string),
+	}
+	for _, cfg := range config.LogSinks {
+		sink, err := NewLogSink(cfg)
+		if err != nil {
+			Warnf("Log sink disabled: %v", err)
+			continue
+		}
+		forwarder.sinks[cfg.Name] = sink
+		if cfg.Default {
+			forwarder.defaults = append(forwarder.defaults, cfg.Name)
+		}
+	}
+	for _, script := range config.Scripts {
+		if len(script.LogSinks) > 0 {
+			forwarder.scripts[script.Name] = script.LogSinks
+		}
+	}
+	return forwarder
+}
+
+// Forward sends a run to the sinks of its script. Failures are logged, not returned.
+func (f *LogForwarder) Forward(entry *LogEntry) {
+	names, listed := f.scripts[entry.ScriptName]
+	if !listed {
+		names = f.defaults
+	}
+	for _, name := range names {
+		sink, ok := f.sinks[name]
+		if !ok {
+			continue
+		}
+		if err := sink.Send(entry); err != nil {
+			Warnf("Failed to forward run of %s to log sink '%s': %v", entry.ScriptName, name, err)
+		}
+	}
+}
+
+// Close closes every sink
+func (f *LogForwarder) Close() {
+	for _, sink := range f.sinks {
+		_ = sink.Close()
+	}
+}
+
+// NewRunID returns a random identifier for one run of a script
+func NewRunID() string {
+	var id [8]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	return hex.EncodeToString(id[:])
+}
@@ -0,0 +1,324 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// sinkEntry is a failed run with multi-line output
+func sinkEntry() *LogEntry {
+	return &LogEntry{
+		Timestamp:  time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC),
+		ScriptName: "fetch",
+		ExitCode:   7,
+		Stdout:     "fetching",
+		Stderr:     "dial tcp: connection refused\nretry failed",
+		Duration:   3000,
+		Trigger:    TriggerSchedule,
+		RunID:      "0123456789abcdef",
+	}
+}
+
+func TestFormatSyslogMessage(t *testing.T) {
+	message := string(formatSyslogMessage(sinkEntry(), syslogFacilities["local3"], "host1", "rss", 42))
+
+	expected := `<155>1 2025-03-01T12:00:00.000000Z host1 rss 42 run [run@32473 script="fetch" run_id="0123456789abcdef" ` +
+		`exit_code="7" duration_ms="3000" trigger="schedule"] fetch exited with code 7 after 3000ms` +
+		"\nSTDOUT: fetching\nSTDERR: dial tcp: connection refused\nretry failed"
+	if message != expected {
+		t.Errorf("Unexpected message:\n%s\nexpected:\n%s", message, expected)
+	}
+
+	if got := escapeSDValue(`a"b\c]`); got != `a\"b\\c\]` {
+		t.Errorf("Unexpected escaping: %s", got)
+	}
+}
+
+func TestSyslogSink_UDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	sink, err := NewLogSink(LogSinkConfig{Name: "central", Type: LogSinkSyslog, Network: "udp", Address: conn.LocalAddr().String()})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	defer sink.Close()
+	if err := sink.Send(sinkEntry()); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	buf := make([]byte, syslogMaxMessageBytes)
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("Expected a datagram: %v", err)
+	}
+	// daemon facility, error severity
+	if message := string(buf[:n]); !strings.HasPrefix(message, "<27>1 ") || !strings.Contains(message, `run_id="0123456789abcdef"`) {
+		t.Errorf("Unexpected message: %s", message)
+	}
+}
+
+func TestSyslogSink_TCP(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	received := make(chan []string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		reader := bufio.NewReader(conn)
+		var messages []string
+		for len(messages) < 2 {
+			// RFC 6587 octet counting: "<length> <message>"
+			length, err := reader.ReadString(' ')
+			if err != nil {
+				return
+			}
+			n, _ := strconv.Atoi(strings.TrimSpace(length))
+			message := make([]byte, n)
+			if _, err := io.ReadFull(reader, message); err != nil {
+				return
+			}
+			messages = append(messages, string(message))
+		}
+		received <- messages
+	}()
+
+	sink, err := NewLogSink(LogSinkConfig{Name: "central", Type: LogSinkSyslog, Network: "tcp", Address: listener.Addr().String()})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	defer sink.Close()
+
+	success := &LogEntry{Timestamp: time.Now(), ScriptName: "report", Duration: 5}
+	for _, entry := range []*LogEntry{sinkEntry(), success} {
+		if err := sink.Send(entry); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+	}
+
+	select {
+	case messages := <-received:
+		if !strings.Contains(messages[0], "STDERR: dial tcp: connection refused\nretry failed") {
+			t.Errorf("Expected multi-line output in one frame, got %q", messages[0])
+		}
+		if !strings.HasPrefix(messages[1], "<30>1 ") || !strings.HasSuffix(messages[1], "report exited with code 0 after 5ms") {
+			t.Errorf("Unexpected second message: %q", messages[1])
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected two framed messages")
+	}
+}
+
+func TestRunSeverity(t *testing.T) {
+	tests := []struct {
+		entry    LogEntry
+		severity int
+	}{
+		{LogEntry{}, severityInfo},
+		{LogEntry{ExitCode: 1, Outcome: OutcomeWarning}, severityWarning},
+		{LogEntry{ExitCode: 1}, severityError},
+		{LogEntry{Outcome: OutcomeFailure}, severityError},
+	}
+	for _, tt := range tests {
+		if got := runSeverity(&tt.entry); got != tt.severity {
+			t.Errorf("%+v: expected severity %d, got %d", tt.entry, tt.severity, got)
+		}
+	}
+}
+
+// listenUnixgram listens on a datagram socket in a temporary directory
+func listenUnixgram(t *testing.T) (*net.UnixConn, string) {
+	t.Helper()
+	dir, err := os.MkdirTemp("", "sink")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	path := filepath.Join(dir, "socket")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn, path
+}
+
+// readDatagram reads one datagram from conn
+func readDatagram(t *testing.T, conn *net.UnixConn) []byte {
+	t.Helper()
+	buf := make([]byte, 256*1024)
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatalf("Expected a datagram: %v", err)
+	}
+	return buf[:n]
+}
+
+func TestSyslogSink_Unixgram(t *testing.T) {
+	conn, path := listenUnixgram(t)
+
+	sink, err := NewLogSink(LogSinkConfig{Name: "local", Type: LogSinkSyslog, Address: path, Tag: "backups"})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	defer sink.Close()
+	if err := sink.Send(sinkEntry()); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if message := string(readDatagram(t, conn)); !strings.Contains(message, " backups ") {
+		t.Errorf("Expected the tag as app name, got %s", message)
+	}
+}
+
+// parseJournalRecord decodes the journal native protocol
+func parseJournalRecord(t *testing.T, data []byte) map[string]string {
+	t.Helper()
+	fields := make(map[string]string)
+	for len(data) > 0 {
+		end := bytes.IndexByte(data, '\n')
+		if end < 0 {
+			t.Fatalf("Truncated record: %q", data)
+		}
+		line := string(data[:end])
+		data = data[end+1:]
+		if name, value, found := strings.Cut(line, "="); found {
+			fields[name] = value
+			continue
+		}
+		size := binary.LittleEndian.Uint64(data[:8])
+		fields[line] = string(data[8 : 8+size])
+		data = data[8+size+1:]
+	}
+	return fields
+}
+
+func TestJournaldSink(t *testing.T) {
+	conn, path := listenUnixgram(t)
+
+	sink, err := NewLogSink(LogSinkConfig{Name: "journal", Type: LogSinkJournald, Address: path})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	defer sink.Close()
+	if err := sink.Send(sinkEntry()); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	fields := parseJournalRecord(t, readDatagram(t, conn))
+	expected := map[string]string{
+		"MESSAGE":           "fetch exited with code 7 after 3000ms",
+		"PRIORITY":          "3",
+		"SYSLOG_IDENTIFIER": DefaultLogSinkTag,
+		"SCRIPT_NAME":       "fetch",
+		"RUN_ID":            "0123456789abcdef",
+		"EXIT_CODE":         "7",
+		"DURATION_MS":       "3000",
+		"TRIGGER":           TriggerSchedule,
+		"STDOUT":            "fetching",
+		"STDERR":            "dial tcp: connection refused\nretry failed",
+	}
+	for name, want := range expected {
+		if fields[name] != want {
+			t.Errorf("%s: expected %q, got %q", name, want, fields[name])
+		}
+	}
+	if _, ok := fields["ERROR"]; ok {
+		t.Error("Expected empty fields to be left out")
+	}
+}
+
+func TestNewLogSink_Invalid(t *testing.T) {
+	configs := []LogSinkConfig{
+		{Name: "a", Type: "gelf"},
+		{Name: "b", Type: LogSinkSyslog, Network: "sctp", Address: "x:1"},
+		{Name: "c", Type: LogSinkSyslog, Network: "udp"},
+		{Name: "d", Type: LogSinkSyslog, Facility: "printer"},
+	}
+	for _, cfg := range configs {
+		if _, err := NewLogSink(cfg); err == nil {
+			t.Errorf("%s: expected an error", cfg.Name)
+		}
+	}
+}
+
+// recordingSink keeps the runs sent to it
+type recordingSink struct {
+	entries []LogEntry
+}
+
+func (s *recordingSink) Send(entry *LogEntry) error {
+	s.entries = append(s.entries, *entry)
+	return nil
+}
+
+func (s *recordingSink) Close() error { return nil }
+
+func TestLogForwarder_Routing(t *testing.T) {
+	central, audit := &recordingSink{}, &recordingSink{}
+	forwarder := &LogForwarder{
+		sinks:    map[string]LogSink{"central": central, "audit": audit},
+		defaults: []string{"central"},
+		scripts:  map[string][]string{"backup": {"audit"}},
+	}
+
+	forwarder.Forward(&LogEntry{ScriptName: "fetch"})
+	forwarder.Forward(&LogEntry{ScriptName: "backup"})
+
+	if len(central.entries) != 1 || central.entries[0].ScriptName != "fetch" {
+		t.Errorf("Expected only fetch in the default sink, got %+v", central.entries)
+	}
+	if len(audit.entries) != 1 || audit.entries[0].ScriptName != "backup" {
+		t.Errorf("Expected only backup in its own sink, got %+v", audit.entries)
+	}
+}
+
+func TestScriptManager_ForwardsRunsToSinks(t *testing.T) {
+	conn, path := listenUnixgram(t)
+	tmpDir := t.TempDir()
+	scriptPath := filepath.Join(tmpDir, "hello.sh")
+	if err := os.WriteFile(scriptPath, []byte("#!/bin/bash\necho hello\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	manager := NewScriptManager(&ServiceConfig{
+		Scripts:  []ScriptConfig{{Name: "hello", Path: scriptPath, Interval: 60, Enabled: true, MaxLogLines: 100}},
+		LogSinks: []LogSinkConfig{{Name: "journal", Type: LogSinkJournald, Address: path, Default: true}},
+	})
+	manager.SetLogDir(filepath.Join(tmpDir, "logs"))
+
+	if err := manager.RunScriptOnce(context.Background(), "hello"); err != nil {
+		t.Fatalf("Expected no error running script, got: %v", err)
+	}
+
+	fields := parseJournalRecord(t, readDatagram(t, conn))
+	entries := manager.GetLogManager().GetLogger("hello").GetEntries()
+	if len(entries) != 1 || entries[0].RunID == "" {
+		t.Fatalf("Expected one logged run with a run ID, got %+v", entries)
+	}
+	if fields["RUN_ID"] != entries[0].RunID || fields["STDOUT"] != "hello" {
+		t.Errorf("Expected the logged run in the journal, got %v", fields)
+	}
+}
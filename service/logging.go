@@ -0,0 +1,77 @@
+// Package service provides core functionality for the run-script-service daemon.
+package service
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync/atomic"
+)
+
+// LogLevel controls which service diagnostics are printed
+type LogLevel int32
+
+// Supported log levels, from most to least verbose
+const (
+	LevelDebug LogLevel = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+// currentLogLevel holds the active LogLevel
+var currentLogLevel int32 = int32(LevelInfo)
+
+// ParseLogLevel converts a level name (debug, info, warn, error) into a LogLevel
+func ParseLogLevel(name string) (LogLevel, error) {
+	switch strings.ToLower(name) {
+	case "debug":
+		return LevelDebug, nil
+	case "info":
+		return LevelInfo, nil
+	case "warn", "warning":
+		return LevelWarn, nil
+	case "error":
+		return LevelError, nil
+	default:
+		return LevelInfo, fmt.Errorf("unknown log level %q (expected debug, info, warn or error)", name)
+	}
+}
+
+// SetLogLevel sets the minimum level of diagnostics that are printed
+func SetLogLevel(level LogLevel) {
+	atomic.StoreInt32(&currentLogLevel, int32(level))
+}
+
+// GetLogLevel returns the active log level
+func GetLogLevel() LogLevel {
+	return LogLevel(atomic.LoadInt32(&currentLogLevel))
+}
+
+// logf prints a message if level is enabled
+func logf(level LogLevel, prefix, format string, args ...interface{}) {
+	if level < GetLogLevel() {
+		return
+	}
+	log.Printf(prefix+format, args...)
+}
+
+// Debugf logs a debug message
+func Debugf(format string, args ...interface{}) {
+	logf(LevelDebug, "DEBUG: ", format, args...)
+}
+
+// Infof logs an informational message
+func Infof(format string, args ...interface{}) {
+	logf(LevelInfo, "", format, args...)
+}
+
+// Warnf logs a warning
+func Warnf(format string, args ...interface{}) {
+	logf(LevelWarn, "WARN: ", format, args...)
+}
+
+// Errorf logs an error
+func Errorf(format string, args ...interface{}) {
+	logf(LevelError, "ERROR: ", format, args...)
+}
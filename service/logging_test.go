@@ -0,0 +1,52 @@
+package service
+
+import (
+	"bytes"
+	"log"
+	"strings"
+	"testing"
+)
+
+func TestParseLogLevel(t *testing.T) {
+	tests := map[string]LogLevel{
+		"debug":   LevelDebug,
+		"INFO":    LevelInfo,
+		"warning": LevelWarn,
+		"error":   LevelError,
+	}
+	for name, expected := range tests {
+		level, err := ParseLogLevel(name)
+		if err != nil || level != expected {
+			t.Errorf("ParseLogLevel(%q) = %v, %v; expected %v", name, level, err, expected)
+		}
+	}
+
+	if _, err := ParseLogLevel("trace"); err == nil {
+		t.Error("expected error for unknown level")
+	}
+}
+
+func TestLogLevelFiltering(t *testing.T) {
+	var buf bytes.Buffer
+	previousOutput := log.Writer()
+	previousLevel := GetLogLevel()
+	defer func() {
+		log.SetOutput(previousOutput)
+		SetLogLevel(previousLevel)
+	}()
+	log.SetOutput(&buf)
+
+	SetLogLevel(LevelWarn)
+	Debugf("debug message")
+	Infof("info message")
+	Warnf("warn message")
+	Errorf("error message")
+
+	output := buf.String()
+	if strings.Contains(output, "debug message") || strings.Contains(output, "info message") {
+		t.Errorf("expected debug and info messages to be filtered, got %q", output)
+	}
+	if !strings.Contains(output, "WARN: warn message") || !strings.Contains(output, "ERROR: error message") {
+		t.Errorf("expected warn and error messages, got %q", output)
+	}
+}
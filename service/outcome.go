@@ -0,0 +1,171 @@
+// Package service provides core functionality for the run-script-service daemon.
+package service
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// Conditions of an outcome rule
+const (
+	RuleStderrNotEmpty = "stderr_not_empty"
+	RuleStdoutMatches  = "stdout_matches" // pattern is a regular expression
+	RuleStderrMatches  = "stderr_matches" // pattern is a regular expression
+)
+
+// OutcomeRule downgrades a run whose output meets a condition
+type OutcomeRule struct {
+	If      string `json:"if"`                // RuleStderrNotEmpty, RuleStdoutMatches or RuleStderrMatches
+	Pattern string `json:"pattern,omitempty"` // matches rules only
+	Outcome string `json:"outcome"`           // OutcomeWarning or OutcomeFailure
+}
+
+// outcomeRule is a compiled OutcomeRule
+type outcomeRule struct {
+	OutcomeRule
+	pattern *regexp.Regexp
+}
+
+// OutcomeEvaluator decides the outcome of a run from its exit code, output and result
+type OutcomeEvaluator struct {
+	success []int
+	warning []int
+	rules   []outcomeRule
+}
+
+// NewOutcomeEvaluator compiles the success criteria of a script.
+// Without success_exit_codes only exit code 0 is a success.
+func NewOutcomeEvaluator(config ScriptConfig) (*OutcomeEvaluator, error) {
+	evaluator := &OutcomeEvaluator{success: config.SuccessExitCodes, warning: config.WarningExitCodes}
+	if len(evaluator.success) == 0 {
+		evaluator.success = []int{0}
+	}
+	for _, code := range append(append([]int(nil), config.SuccessExitCodes...), config.WarningExitCodes...) {
+		if code < 0 || code > 255 {
+			return nil, fmt.Errorf("exit code %d is out of range (0-255)", code)
+		}
+	}
+	for _, code := range config.WarningExitCodes {
+		if containsExitCode(evaluator.success, code) {
+			return nil, fmt.Errorf("exit code %d is both a success and a warning exit code", code)
+		}
+	}
+	for i, rule := range config.OutcomeRules {
+		compiled, err := compileOutcomeRule(rule)
+		if err != nil {
+			return nil, fmt.Errorf("outcome rule %d: %v", i, err)
+		}
+		evaluator.rules = append(evaluator.rules, compiled)
+	}
+	return evaluator, nil
+}
+
+// compileOutcomeRule validates a rule and compiles its pattern
+func compileOutcomeRule(rule OutcomeRule) (outcomeRule, error) {
+	compiled := outcomeRule{OutcomeRule: rule}
+	switch rule.Outcome {
+	case OutcomeWarning, OutcomeFailure:
+	default:
+		return compiled, fmt.Errorf("invalid outcome '%s' (expected warning or failure)", rule.Outcome)
+	}
+	switch rule.If {
+	case RuleStderrNotEmpty:
+		if rule.Pattern != "" {
+			return compiled, fmt.Errorf("pattern is only used by the stdout_matches and stderr_matches rules")
+		}
+	case RuleStdoutMatches, RuleStderrMatches:
+		if rule.Pattern == "" {
+			return compiled, fmt.Errorf("%s needs a pattern", rule.If)
+		}
+		re, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return compiled, fmt.Errorf("invalid pattern: %v", err)
+		}
+		compiled.pattern = re
+	default:
+		return compiled, fmt.Errorf("invalid condition '%s' (expected stderr_not_empty, stdout_matches or stderr_matches)", rule.If)
+	}
+	return compiled, nil
+}
+
+// Evaluate returns the outcome of a run and, unless it succeeded, the reason.
+// The exit code decides first; a failed result condition or a matching rule can only make it worse.
+func (e *OutcomeEvaluator) Evaluate(entry *LogEntry, resultOK bool) (outcome, reason string) {
+	switch {
+	case entry.Error != "":
+		return OutcomeFailure, "could not be run: " + entry.Error
+	case entry.Interrupted != "":
+		return OutcomeFailure, entry.Interrupted
+	case containsExitCode(e.success, entry.ExitCode):
+		outcome = OutcomeSuccess
+	case containsExitCode(e.warning, entry.ExitCode):
+		outcome, reason = OutcomeWarning, fmt.Sprintf("exited with warning code %d", entry.ExitCode)
+	default:
+		return OutcomeFailure, exitCodeReason(entry.ExitCode)
+	}
+
+	if !resultOK {
+		return OutcomeFailure, "result does not meet its success condition"
+	}
+	for _, rule := range e.rules {
+		if rule.matches(entry) && outcomeRank(rule.Outcome) > outcomeRank(outcome) {
+			outcome, reason = rule.Outcome, rule.describe()
+			if outcome == OutcomeFailure {
+				break
+			}
+		}
+	}
+	return outcome, reason
+}
+
+// matches reports whether the run's output meets the rule's condition
+func (r *outcomeRule) matches(entry *LogEntry) bool {
+	switch r.If {
+	case RuleStderrNotEmpty:
+		return entry.Stderr != ""
+	case RuleStdoutMatches:
+		return r.pattern.MatchString(entry.Stdout)
+	case RuleStderrMatches:
+		return r.pattern.MatchString(entry.Stderr)
+	}
+	return false
+}
+
+// describe explains why the rule applied
+func (r *outcomeRule) describe() string {
+	switch r.If {
+	case RuleStderrNotEmpty:
+		return "wrote to stderr"
+	case RuleStdoutMatches:
+		return fmt.Sprintf("stdout matches %q", r.Pattern)
+	default:
+		return fmt.Sprintf("stderr matches %q", r.Pattern)
+	}
+}
+
+// outcomeRank orders outcomes from best to worst
+func outcomeRank(outcome string) int {
+	switch outcome {
+	case OutcomeSuccess:
+		return 0
+	case OutcomeWarning:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// exitCodeReason is the reason of runs failed by their exit code
+func exitCodeReason(code int) string {
+	return fmt.Sprintf("exited with code %d", code)
+}
+
+// containsExitCode reports whether code is in codes
+func containsExitCode(codes []int, code int) bool {
+	for _, c := range codes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,148 @@
+package service
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestOutcomeEvaluator_Evaluate(t *testing.T) {
+	config := ScriptConfig{
+		SuccessExitCodes: []int{0, 1},
+		WarningExitCodes: []int{2},
+		OutcomeRules: []OutcomeRule{
+			{If: RuleStderrNotEmpty, Outcome: OutcomeWarning},
+			{If: RuleStdoutMatches, Pattern: `(?i)fatal`, Outcome: OutcomeFailure},
+		},
+	}
+	evaluator, err := NewOutcomeEvaluator(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		entry    LogEntry
+		resultOK bool
+		outcome  string
+		reason   string
+	}{
+		{"success code", LogEntry{ExitCode: 0}, true, OutcomeSuccess, ""},
+		{"nothing to do", LogEntry{ExitCode: 1}, true, OutcomeSuccess, ""},
+		{"warning code", LogEntry{ExitCode: 2}, true, OutcomeWarning, "exited with warning code 2"},
+		{"failure code", LogEntry{ExitCode: 3, Stdout: "FATAL"}, true, OutcomeFailure, "exited with code 3"},
+		{"could not run", LogEntry{ExitCode: -1, Error: "exec: not found"}, true, OutcomeFailure, "could not be run: exec: not found"},
+		{"result condition", LogEntry{ExitCode: 0}, false, OutcomeFailure, "result does not meet its success condition"},
+		{"stderr warns", LogEntry{Stderr: "deprecated flag"}, true, OutcomeWarning, "wrote to stderr"},
+		{"stdout fails", LogEntry{Stdout: "Fatal: disk full", Stderr: "x"}, true, OutcomeFailure, `stdout matches "(?i)fatal"`},
+		{"rules never improve", LogEntry{ExitCode: 2, Stderr: "x"}, true, OutcomeWarning, "exited with warning code 2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outcome, reason := evaluator.Evaluate(&tt.entry, tt.resultOK)
+			if outcome != tt.outcome || reason != tt.reason {
+				t.Errorf("Expected %s (%q), got %s (%q)", tt.outcome, tt.reason, outcome, reason)
+			}
+		})
+	}
+}
+
+func TestNewOutcomeEvaluator_Invalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		config ScriptConfig
+		errMsg string
+	}{
+		{"out of range", ScriptConfig{WarningExitCodes: []int{256}}, "out of range"},
+		{"overlap", ScriptConfig{WarningExitCodes: []int{0}}, "both a success and a warning"},
+		{"bad condition", ScriptConfig{OutcomeRules: []OutcomeRule{{If: "exit_code", Outcome: OutcomeFailure}}}, "invalid condition"},
+		{"bad outcome", ScriptConfig{OutcomeRules: []OutcomeRule{{If: RuleStderrNotEmpty, Outcome: OutcomeSuccess}}}, "invalid outcome"},
+		{"missing pattern", ScriptConfig{OutcomeRules: []OutcomeRule{{If: RuleStderrMatches, Outcome: OutcomeFailure}}}, "needs a pattern"},
+		{"bad pattern", ScriptConfig{OutcomeRules: []OutcomeRule{{If: RuleStdoutMatches, Pattern: "(", Outcome: OutcomeFailure}}}, "invalid pattern"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewOutcomeEvaluator(tt.config)
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("Expected error containing %q, got %v", tt.errMsg, err)
+			}
+		})
+	}
+}
+
+func TestLogEntry_RunOutcome(t *testing.T) {
+	tests := []struct {
+		entry    LogEntry
+		expected string
+	}{
+		{LogEntry{}, OutcomeSuccess},
+		{LogEntry{ExitCode: 1}, OutcomeFailure},
+		{LogEntry{Error: "exec: not found"}, OutcomeFailure},
+		{LogEntry{Outcome: OutcomeFailure}, OutcomeFailure},
+		{LogEntry{ExitCode: 1, Outcome: OutcomeSuccess}, OutcomeSuccess},
+		{LogEntry{ExitCode: 1, Outcome: OutcomeWarning}, OutcomeWarning},
+	}
+	for _, tt := range tests {
+		if got := tt.entry.RunOutcome(); got != tt.expected {
+			t.Errorf("%+v: expected %s, got %s", tt.entry, tt.expected, got)
+		}
+		if got := tt.entry.Failed(); got != (tt.expected == OutcomeFailure) {
+			t.Errorf("%+v: expected Failed() %v, got %v", tt.entry, tt.expected == OutcomeFailure, got)
+		}
+	}
+}
+
+func TestScriptRunner_WarningOutcome(t *testing.T) {
+	tmpDir := t.TempDir()
+	scriptPath := filepath.Join(tmpDir, "sync.sh")
+	if err := os.WriteFile(scriptPath, []byte("#!/bin/bash\necho 'nothing to do'\nexit 1\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	config := ScriptConfig{
+		Name: "sync", Path: scriptPath, Interval: 60, MaxLogLines: 10,
+		WarningExitCodes: []int{1},
+	}
+	logManager := NewLogManager(filepath.Join(tmpDir, "logs"))
+	broadcaster := NewEventBroadcaster()
+	events := make(chan *ScriptStatusEvent, 10)
+	defer broadcaster.Subscribe(events)()
+	metrics := NewRunMetrics()
+
+	runner := NewManagedScriptRunner(config, logManager, broadcaster)
+	runner.SetRunMetrics(metrics)
+	if err := runner.RunOnce(context.Background()); err != nil {
+		t.Errorf("Expected a warning not to fail the run, got %v", err)
+	}
+
+	entries := logManager.GetLogger("sync").GetEntries()
+	if len(entries) != 1 {
+		t.Fatalf("Expected one entry, got %d", len(entries))
+	}
+	if entries[0].Outcome != OutcomeWarning || entries[0].OutcomeReason != "exited with warning code 1" {
+		t.Errorf("Expected a warning outcome, got %+v", entries[0])
+	}
+
+	var last *ScriptStatusEvent
+	for len(events) > 0 {
+		last = <-events
+	}
+	if last == nil || last.Status != StatusWarning || last.ExitCode != 1 {
+		t.Errorf("Expected a warning event, got %+v", last)
+	}
+	if got := metrics.scripts["sync"]; got == nil || got.runs[OutcomeWarning] != 1 {
+		t.Errorf("Expected a warning run in the metrics, got %+v", got)
+	}
+
+	runner = NewManagedScriptRunner(ScriptConfig{
+		Name: "sync", Path: scriptPath, Interval: 60, MaxLogLines: 10,
+		OutcomeRules:     []OutcomeRule{{If: RuleStdoutMatches, Pattern: "nothing", Outcome: OutcomeFailure}},
+		SuccessExitCodes: []int{0, 1},
+	}, logManager, nil)
+	err := runner.RunOnce(context.Background())
+	if err == nil || err.Error() != `script stdout matches "nothing"` {
+		t.Errorf("Expected the stdout rule to fail the run, got %v", err)
+	}
+}
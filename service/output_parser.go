@@ -0,0 +1,168 @@
+// Package service provides core functionality for the run-script-service daemon.
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Output parser formats
+const (
+	OutputFormatJSON     = "json"  // the last non-empty stdout line is a JSON object
+	OutputFormatKeyValue = "kv"    // key=value lines, later lines win
+	OutputFormatRegex    = "regex" // named groups of the last match of pattern
+)
+
+// keyValueRegex matches one key=value line
+var keyValueRegex = regexp.MustCompile(`^\s*([A-Za-z_][A-Za-z0-9_.-]*)\s*=\s*(.*?)\s*$`)
+
+// OutputParserConfig extracts a result map from a script's stdout
+type OutputParserConfig struct {
+	Format  string           `json:"format"`            // OutputFormatJSON, OutputFormatKeyValue or OutputFormatRegex
+	Pattern string           `json:"pattern,omitempty"` // regular expression with named groups, regex format only
+	Metrics []string         `json:"metrics,omitempty"` // numeric result fields exported as metrics
+	Success *ResultCondition `json:"success,omitempty"` // required in addition to a successful exit code
+}
+
+// ResultCondition requires a result field to hold one of the listed values
+type ResultCondition struct {
+	Field  string   `json:"field"`
+	Equals []string `json:"equals"` // numbers are compared in their shortest form, e.g. "1200"
+}
+
+// OutputParser is a compiled OutputParserConfig
+type OutputParser struct {
+	config  OutputParserConfig
+	pattern *regexp.Regexp
+}
+
+// NewOutputParser validates and compiles a parser configuration
+func NewOutputParser(config OutputParserConfig) (*OutputParser, error) {
+	parser := &OutputParser{config: config}
+	switch config.Format {
+	case OutputFormatJSON, OutputFormatKeyValue:
+		if config.Pattern != "" {
+			return nil, fmt.Errorf("pattern is only used by the regex format")
+		}
+	case OutputFormatRegex:
+		re, err := regexp.Compile(config.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern: %v", err)
+		}
+		named := false
+		for _, name := range re.SubexpNames() {
+			named = named || name != ""
+		}
+		if !named {
+			return nil, fmt.Errorf("pattern has no named groups such as (?P<rows>\\d+)")
+		}
+		parser.pattern = re
+	default:
+		return nil, fmt.Errorf("invalid output format '%s' (expected json, kv or regex)", config.Format)
+	}
+
+	if config.Success != nil && config.Success.Field == "" {
+		return nil, fmt.Errorf("success condition needs a field")
+	}
+	return parser, nil
+}
+
+// Parse extracts the result fields of stdout. Numeric values become float64.
+func (p *OutputParser) Parse(stdout string) (map[string]interface{}, error) {
+	result := make(map[string]interface{})
+	switch p.config.Format {
+	case OutputFormatJSON:
+		line := lastLine(stdout)
+		if line == "" {
+			return nil, fmt.Errorf("no output to parse")
+		}
+		if err := json.Unmarshal([]byte(line), &result); err != nil {
+			return nil, fmt.Errorf("last output line is not a JSON object: %v", err)
+		}
+	case OutputFormatKeyValue:
+		for _, line := range strings.Split(stdout, "\n") {
+			if matches := keyValueRegex.FindStringSubmatch(line); matches != nil {
+				result[matches[1]] = parseResultValue(matches[2])
+			}
+		}
+	case OutputFormatRegex:
+		all := p.pattern.FindAllStringSubmatch(stdout, -1)
+		if len(all) == 0 {
+			return nil, fmt.Errorf("pattern did not match the output")
+		}
+		matches := all[len(all)-1]
+		for i, name := range p.pattern.SubexpNames() {
+			if name != "" {
+				result[name] = parseResultValue(matches[i])
+			}
+		}
+	}
+	if len(result) == 0 {
+		return nil, fmt.Errorf("no result fields found")
+	}
+	return result, nil
+}
+
+// Succeeded reports whether a result satisfies the success condition, if any
+func (p *OutputParser) Succeeded(result map[string]interface{}) bool {
+	condition := p.config.Success
+	if condition == nil {
+		return true
+	}
+	value, ok := result[condition.Field]
+	if !ok {
+		return false
+	}
+	formatted := formatResultValue(value)
+	for _, expected := range condition.Equals {
+		if formatted == expected {
+			return true
+		}
+	}
+	return false
+}
+
+// Metrics returns the numeric result fields configured as metrics
+func (p *OutputParser) Metrics(result map[string]interface{}) map[string]float64 {
+	metrics := make(map[string]float64)
+	for _, name := range p.config.Metrics {
+		if value, ok := result[name].(float64); ok {
+			metrics[name] = value
+		}
+	}
+	return metrics
+}
+
+// lastLine returns the last non-empty line of text
+func lastLine(text string) string {
+	lines := strings.Split(strings.TrimSpace(text), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
+
+// parseResultValue converts a text value to a number when it is one, unquoting quoted strings
+func parseResultValue(value string) interface{} {
+	if number, err := strconv.ParseFloat(value, 64); err == nil && !math.IsNaN(number) && !math.IsInf(number, 0) {
+		return number
+	}
+	if unquoted, err := strconv.Unquote(value); err == nil {
+		return unquoted
+	}
+	return value
+}
+
+// formatResultValue renders a result value for comparison with a condition
+func formatResultValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		data, _ := json.Marshal(v)
+		return string(data)
+	}
+}
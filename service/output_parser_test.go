@@ -0,0 +1,172 @@
+package service
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestOutputParser_Parse(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   OutputParserConfig
+		stdout   string
+		expected map[string]interface{}
+		wantErr  bool
+	}{
+		{
+			name:     "json last line",
+			config:   OutputParserConfig{Format: OutputFormatJSON},
+			stdout:   "starting\nloaded\n{\"rows\": 1200, \"status\": \"partial\"}\n\n",
+			expected: map[string]interface{}{"rows": float64(1200), "status": "partial"},
+		},
+		{
+			name:    "json last line is not an object",
+			config:  OutputParserConfig{Format: OutputFormatJSON},
+			stdout:  "{\"rows\": 1}\ndone",
+			wantErr: true,
+		},
+		{
+			name:     "key value lines",
+			config:   OutputParserConfig{Format: OutputFormatKeyValue},
+			stdout:   "rows=10\nnoise line\nstatus = \"ok then\"\nrows=12\nratio=0.5",
+			expected: map[string]interface{}{"rows": float64(12), "status": "ok then", "ratio": 0.5},
+		},
+		{
+			name:     "regex named groups of the last match",
+			config:   OutputParserConfig{Format: OutputFormatRegex, Pattern: `copied (?P<files>\d+) files \((?P<size>\w+)\)`},
+			stdout:   "copied 3 files (1MB)\ncopied 7 files (4MB)",
+			expected: map[string]interface{}{"files": float64(7), "size": "4MB"},
+		},
+		{
+			name:    "regex without match",
+			config:  OutputParserConfig{Format: OutputFormatRegex, Pattern: `rows=(?P<rows>\d+)`},
+			stdout:  "nothing",
+			wantErr: true,
+		},
+		{
+			name:     "NaN stays text",
+			config:   OutputParserConfig{Format: OutputFormatKeyValue},
+			stdout:   "value=NaN",
+			expected: map[string]interface{}{"value": "NaN"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser, err := NewOutputParser(tt.config)
+			if err != nil {
+				t.Fatalf("Expected a valid parser, got: %v", err)
+			}
+			result, err := parser.Parse(tt.stdout)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected an error, got %v", result)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestNewOutputParser_Invalid(t *testing.T) {
+	configs := []OutputParserConfig{
+		{Format: "yaml"},
+		{Format: OutputFormatRegex, Pattern: "("},
+		{Format: OutputFormatRegex, Pattern: `rows=(\d+)`},
+		{Format: OutputFormatJSON, Pattern: "x"},
+		{Format: OutputFormatJSON, Success: &ResultCondition{Equals: []string{"ok"}}},
+	}
+	for _, config := range configs {
+		if _, err := NewOutputParser(config); err == nil {
+			t.Errorf("Expected an error for %+v", config)
+		}
+	}
+}
+
+func TestOutputParser_SucceededAndMetrics(t *testing.T) {
+	parser, err := NewOutputParser(OutputParserConfig{
+		Format:  OutputFormatJSON,
+		Metrics: []string{"rows", "status", "missing"},
+		Success: &ResultCondition{Field: "status", Equals: []string{"ok", "complete"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !parser.Succeeded(map[string]interface{}{"status": "ok"}) {
+		t.Error("Expected status ok to succeed")
+	}
+	if parser.Succeeded(map[string]interface{}{"status": "partial"}) || parser.Succeeded(nil) {
+		t.Error("Expected partial and missing status to fail")
+	}
+
+	metrics := parser.Metrics(map[string]interface{}{"rows": float64(1200), "status": "ok"})
+	if !reflect.DeepEqual(metrics, map[string]float64{"rows": 1200}) {
+		t.Errorf("Expected only numeric metrics, got %v", metrics)
+	}
+
+	numeric, _ := NewOutputParser(OutputParserConfig{Format: OutputFormatJSON, Success: &ResultCondition{Field: "errors", Equals: []string{"0"}}})
+	if !numeric.Succeeded(map[string]interface{}{"errors": float64(0)}) {
+		t.Error("Expected numbers to compare in their shortest form")
+	}
+}
+
+func TestScriptRunner_ResultDecidesOutcome(t *testing.T) {
+	tmpDir := t.TempDir()
+	scriptPath := filepath.Join(tmpDir, "load.sh")
+	if err := os.WriteFile(scriptPath, []byte("#!/bin/bash\necho loading\necho '{\"rows\": 1200, \"status\": \"partial\"}'\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	config := ScriptConfig{
+		Name: "load", Path: scriptPath, Interval: 60, MaxLogLines: 10,
+		Output: &OutputParserConfig{
+			Format:  OutputFormatJSON,
+			Metrics: []string{"rows"},
+			Success: &ResultCondition{Field: "status", Equals: []string{"ok"}},
+		},
+	}
+	logManager := NewLogManager(filepath.Join(tmpDir, "logs"))
+	broadcaster := NewEventBroadcaster()
+	events := make(chan *ScriptStatusEvent, 10)
+	defer broadcaster.Subscribe(events)()
+	metrics := NewRunMetrics()
+
+	runner := NewManagedScriptRunner(config, logManager, broadcaster)
+	runner.SetRunMetrics(metrics)
+	if err := runner.RunOnce(context.Background()); err == nil {
+		t.Error("Expected the failed success condition to fail the run")
+	}
+
+	entries := logManager.GetLogger("load").GetEntries()
+	if len(entries) != 1 {
+		t.Fatalf("Expected one entry, got %d", len(entries))
+	}
+	entry := entries[0]
+	if entry.ExitCode != 0 || entry.Outcome != OutcomeFailure || !entry.Failed() {
+		t.Errorf("Expected a failed run with exit code 0, got %+v", entry)
+	}
+	if entry.Result["rows"] != float64(1200) || entry.Result["status"] != "partial" {
+		t.Errorf("Expected the parsed result, got %v", entry.Result)
+	}
+
+	var last *ScriptStatusEvent
+	for len(events) > 0 {
+		last = <-events
+	}
+	if last == nil || last.Status != "failed" {
+		t.Errorf("Expected a failed event, got %+v", last)
+	}
+	if got := metrics.scripts["load"]; got == nil || got.results["rows"] != 1200 || got.runs[OutcomeFailure] != 1 {
+		t.Errorf("Expected the run in the metrics, got %+v", got)
+	}
+}
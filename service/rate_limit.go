@@ -0,0 +1,163 @@
+// Package service provides core functionality for the run-script-service daemon.
+package service
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Defaults of the API request limits, used when the rate_limit settings are 0
+const (
+	DefaultRequestsPerMinute     = 300
+	DefaultWritesPerMinute       = 60
+	DefaultRunsPerMinute         = 20
+	DefaultAuthFailuresPerMinute = 10       // failed authentications per address and minute
+	DefaultMaxBodyBytes          = 1 << 20  // 1 MiB
+	DefaultMaxUploadBytes        = 10 << 20 // 10 MiB
+)
+
+// Classes of API requests, each limited by its own token bucket per client
+const (
+	RequestClassRead  = "read"  // GET and other safe methods
+	RequestClassWrite = "write" // requests that change configuration, files or logs
+	RequestClassRun   = "run"   // manual runs of scripts
+	RequestClassAuth  = "auth"  // failed authentications, limited per address before authenticating
+)
+
+// RateLimitConfig limits the API requests of each client, identified by its user or token
+// name once authenticated and by its address otherwise, and the size of request bodies
+type RateLimitConfig struct {
+	Disabled              bool  `json:"disabled,omitempty"`                 // turns rate limiting off, body sizes stay limited
+	RequestsPerMinute     int   `json:"requests_per_minute,omitempty"`      // reads per client, 0 means 300
+	WritesPerMinute       int   `json:"writes_per_minute,omitempty"`        // writes per client, 0 means 60
+	RunsPerMinute         int   `json:"runs_per_minute,omitempty"`          // manual runs per client, 0 means 20
+	AuthFailuresPerMinute int   `json:"auth_failures_per_minute,omitempty"` // failed logins per address, 0 means 10
+	MaxBodyBytes          int64 `json:"max_body_bytes,omitempty"`           // request bodies, 0 means 1 MiB
+	MaxUploadBytes        int64 `json:"max_upload_bytes,omitempty"`         // file uploads and imports, 0 means 10 MiB
+}
+
+// PerMinute returns how many requests of class a client may make per minute, 0 when rate
+// limiting is disabled
+func (c *RateLimitConfig) PerMinute(class string) int {
+	if c != nil && c.Disabled {
+		return 0
+	}
+	var configured, fallback int
+	switch class {
+	case RequestClassWrite:
+		fallback = DefaultWritesPerMinute
+		if c != nil {
+			configured = c.WritesPerMinute
+		}
+	case RequestClassRun:
+		fallback = DefaultRunsPerMinute
+		if c != nil {
+			configured = c.RunsPerMinute
+		}
+	case RequestClassAuth:
+		fallback = DefaultAuthFailuresPerMinute
+		if c != nil {
+			configured = c.AuthFailuresPerMinute
+		}
+	default:
+		fallback = DefaultRequestsPerMinute
+		if c != nil {
+			configured = c.RequestsPerMinute
+		}
+	}
+	if configured <= 0 {
+		return fallback
+	}
+	return configured
+}
+
+// BodyLimit returns the largest request body accepted, for uploads or other requests
+func (c *RateLimitConfig) BodyLimit(upload bool) int64 {
+	if upload {
+		if c == nil || c.MaxUploadBytes <= 0 {
+			return DefaultMaxUploadBytes
+		}
+		return c.MaxUploadBytes
+	}
+	if c == nil || c.MaxBodyBytes <= 0 {
+		return DefaultMaxBodyBytes
+	}
+	return c.MaxBodyBytes
+}
+
+// idleBucketSweep is how often buckets that refilled completely are forgotten
+const idleBucketSweep = time.Minute
+
+// tokenBucket holds the tokens of one client, refilled continuously
+type tokenBucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// RateLimiter keeps a token bucket per client. Each bucket holds up to a minute's worth
+// of requests and refills at the per-minute rate, so short bursts pass and sustained
+// traffic is held to the rate.
+type RateLimiter struct {
+	mutex     sync.Mutex
+	perMinute int
+	buckets   map[string]*tokenBucket
+	swept     time.Time
+	now       func() time.Time
+}
+
+// NewRateLimiter creates a limiter allowing perMinute requests per client
+func NewRateLimiter(perMinute int) *RateLimiter {
+	return &RateLimiter{perMinute: perMinute, buckets: make(map[string]*tokenBucket), now: time.Now}
+}
+
+// Allow takes a token from the bucket of client. When the bucket is empty it returns false
+// and how long until the next token.
+func (l *RateLimiter) Allow(client string) (bool, time.Duration) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	bucket, retry := l.refill(client)
+	if retry > 0 {
+		return false, retry
+	}
+	bucket.tokens--
+	return true, 0
+}
+
+// Return puts back a token taken by Allow, for requests that turned out not to count
+func (l *RateLimiter) Return(client string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	bucket, _ := l.refill(client)
+	bucket.tokens = math.Min(float64(l.perMinute), bucket.tokens+1)
+}
+
+// refill brings the bucket of client up to date and returns it with how long until it
+// holds a token. Callers hold the mutex.
+func (l *RateLimiter) refill(client string) (*tokenBucket, time.Duration) {
+	now := l.now()
+	capacity := float64(l.perMinute)
+	rate := capacity / time.Minute.Seconds() // tokens per second
+	if now.Sub(l.swept) >= idleBucketSweep {
+		for key, bucket := range l.buckets {
+			if bucket.tokens+now.Sub(bucket.updated).Seconds()*rate >= capacity {
+				delete(l.buckets, key)
+			}
+		}
+		l.swept = now
+	}
+
+	bucket, ok := l.buckets[client]
+	if !ok {
+		bucket = &tokenBucket{tokens: capacity, updated: now}
+		l.buckets[client] = bucket
+	}
+	bucket.tokens = math.Min(capacity, bucket.tokens+now.Sub(bucket.updated).Seconds()*rate)
+	bucket.updated = now
+	if bucket.tokens >= 1 {
+		return bucket, 0
+	}
+	return bucket, time.Duration(math.Ceil((1-bucket.tokens)/rate*1000)) * time.Millisecond
+}
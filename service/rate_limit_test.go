@@ -0,0 +1,90 @@
+package service
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRateLimitConfig_Defaults(t *testing.T) {
+	var none *RateLimitConfig
+	if none.PerMinute(RequestClassRead) != DefaultRequestsPerMinute || none.PerMinute(RequestClassRun) != DefaultRunsPerMinute {
+		t.Errorf("Expected the defaults without rate_limit")
+	}
+	if none.BodyLimit(false) != DefaultMaxBodyBytes || none.BodyLimit(true) != DefaultMaxUploadBytes {
+		t.Errorf("Expected the default body limits without rate_limit")
+	}
+
+	config := &RateLimitConfig{WritesPerMinute: 5, MaxUploadBytes: 100}
+	if config.PerMinute(RequestClassWrite) != 5 || config.PerMinute(RequestClassRead) != DefaultRequestsPerMinute {
+		t.Errorf("Expected the configured write limit, got %d", config.PerMinute(RequestClassWrite))
+	}
+	if config.BodyLimit(true) != 100 || config.BodyLimit(false) != DefaultMaxBodyBytes {
+		t.Errorf("Expected the configured upload limit, got %d", config.BodyLimit(true))
+	}
+
+	disabled := &RateLimitConfig{Disabled: true, MaxBodyBytes: 10}
+	if disabled.PerMinute(RequestClassRun) != 0 || disabled.BodyLimit(false) != 10 {
+		t.Errorf("Expected rate limiting off and the body limit kept")
+	}
+}
+
+func TestRateLimiter_Allow(t *testing.T) {
+	now := time.Date(2026, time.October, 18, 12, 0, 0, 0, time.UTC)
+	limiter := NewRateLimiter(6) // one token every 10 seconds
+	limiter.now = func() time.Time { return now }
+
+	for i := 0; i < 6; i++ {
+		if ok, _ := limiter.Allow("alice"); !ok {
+			t.Fatalf("Expected request %d of the burst to pass", i+1)
+		}
+	}
+	ok, retry := limiter.Allow("alice")
+	if ok || retry != 10*time.Second {
+		t.Errorf("Expected the 7th request refused for 10s, got %v, %s", ok, retry)
+	}
+	if ok, _ := limiter.Allow("bob"); !ok {
+		t.Error("Expected another client to have its own bucket")
+	}
+
+	now = now.Add(4 * time.Second)
+	if ok, retry := limiter.Allow("alice"); ok || retry != 6*time.Second {
+		t.Errorf("Expected 6s left until the next token, got %v, %s", ok, retry)
+	}
+	now = now.Add(6 * time.Second)
+	if ok, _ := limiter.Allow("alice"); !ok {
+		t.Error("Expected a refilled token to pass")
+	}
+}
+
+func TestRateLimiter_ForgetsIdleClients(t *testing.T) {
+	now := time.Date(2026, time.October, 18, 12, 0, 0, 0, time.UTC)
+	limiter := NewRateLimiter(60)
+	limiter.now = func() time.Time { return now }
+
+	limiter.Allow("alice")
+	limiter.Allow("bob")
+	now = now.Add(2 * time.Minute)
+	limiter.Allow("carol")
+	if len(limiter.buckets) != 1 {
+		t.Errorf("Expected only the active client kept, got %d buckets", len(limiter.buckets))
+	}
+}
+
+func TestRateLimiter_Return(t *testing.T) {
+	now := time.Date(2026, time.October, 18, 12, 0, 0, 0, time.UTC)
+	limiter := NewRateLimiter(2)
+	limiter.now = func() time.Time { return now }
+
+	for i := 0; i < 5; i++ {
+		if ok, _ := limiter.Allow("alice"); !ok {
+			t.Fatalf("Expected request %d to pass with its token returned", i+1)
+		}
+		limiter.Return("alice")
+	}
+	limiter.Return("alice")
+	limiter.Allow("alice")
+	limiter.Allow("alice")
+	if ok, _ := limiter.Allow("alice"); ok {
+		t.Error("Expected returned tokens not to exceed the capacity")
+	}
+}
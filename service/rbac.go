@@ -0,0 +1,85 @@
+// Package service provides core functionality for the run-script-service daemon.
+package service
+
+import "fmt"
+
+// Roles of users and API tokens, from least to most privileged. Each role can do
+// everything the roles before it can.
+const (
+	RoleViewer   = "viewer"   // scripts, logs, artifacts and metrics
+	RoleOperator = "operator" // run, enable and disable scripts
+	RoleEditor   = "editor"   // add, change and delete scripts and clear their logs
+	RoleAdmin    = "admin"    // configuration, script files, import and export
+)
+
+// roles lists the roles in order of privilege
+var roles = []string{RoleViewer, RoleOperator, RoleEditor, RoleAdmin}
+
+// AuthGrant gives a user or token a higher role on some scripts, selected by name or tag
+type AuthGrant struct {
+	User    string   `json:"user,omitempty"`
+	Token   string   `json:"token,omitempty"`
+	Role    string   `json:"role"`
+	Scripts []string `json:"scripts,omitempty"`
+	Tags    []string `json:"tags,omitempty"`
+}
+
+// ValidateRole checks that role is one of the known roles
+func ValidateRole(role string) error {
+	if roleRank(role) < 0 {
+		return fmt.Errorf("invalid role '%s' (expected viewer, operator, editor or admin)", role)
+	}
+	return nil
+}
+
+// RoleAllows reports whether role includes the permissions of required
+func RoleAllows(role, required string) bool {
+	return roleRank(role) >= roleRank(required)
+}
+
+// roleRank orders roles by privilege; unknown roles rank below viewer
+func roleRank(role string) int {
+	for i, r := range roles {
+		if r == role {
+			return i
+		}
+	}
+	return -1
+}
+
+// ScriptRole returns the role of a user (or, with token set, an API token) on a script:
+// its own role raised by every grant that names the script or one of its tags
+func (c *AuthConfig) ScriptRole(name string, token bool, role string, script ScriptConfig) string {
+	if c == nil {
+		return role
+	}
+	for _, grant := range c.Grants {
+		if !grant.appliesTo(name, token, script) {
+			continue
+		}
+		if roleRank(grant.Role) > roleRank(role) {
+			role = grant.Role
+		}
+	}
+	return role
+}
+
+// appliesTo reports whether a grant covers a principal and script
+func (g *AuthGrant) appliesTo(name string, token bool, script ScriptConfig) bool {
+	if token && g.Token != name || !token && g.User != name {
+		return false
+	}
+	for _, s := range g.Scripts {
+		if s == script.Name {
+			return true
+		}
+	}
+	for _, tag := range g.Tags {
+		for _, t := range script.Tags {
+			if t == tag {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,61 @@
+package service
+
+import "testing"
+
+func TestRoleAllows(t *testing.T) {
+	tests := []struct {
+		role, required string
+		expected       bool
+	}{
+		{RoleViewer, RoleViewer, true},
+		{RoleViewer, RoleOperator, false},
+		{RoleOperator, RoleOperator, true},
+		{RoleOperator, RoleEditor, false},
+		{RoleEditor, RoleOperator, true},
+		{RoleAdmin, RoleEditor, true},
+		{"superuser", RoleViewer, false},
+	}
+	for _, tt := range tests {
+		if got := RoleAllows(tt.role, tt.required); got != tt.expected {
+			t.Errorf("RoleAllows(%s, %s): expected %v, got %v", tt.role, tt.required, tt.expected, got)
+		}
+	}
+	if ValidateRole(RoleEditor) != nil || ValidateRole("root") == nil {
+		t.Error("Expected only the known roles to be valid")
+	}
+}
+
+func TestAuthConfig_ScriptRole(t *testing.T) {
+	config := &AuthConfig{Grants: []AuthGrant{
+		{User: "bob", Role: RoleEditor, Tags: []string{"reports"}},
+		{User: "bob", Role: RoleOperator, Scripts: []string{"backup"}},
+		{Token: "ci", Role: RoleAdmin, Scripts: []string{"deploy"}},
+		{User: "carol", Role: RoleViewer, Scripts: []string{"deploy"}},
+	}}
+	report := ScriptConfig{Name: "daily", Tags: []string{"reports", "nightly"}}
+
+	tests := []struct {
+		name     string
+		token    bool
+		role     string
+		script   ScriptConfig
+		expected string
+	}{
+		{"bob", false, RoleViewer, report, RoleEditor},
+		{"bob", false, RoleViewer, ScriptConfig{Name: "backup"}, RoleOperator},
+		{"bob", false, RoleViewer, ScriptConfig{Name: "deploy"}, RoleViewer},
+		{"ci", true, RoleOperator, ScriptConfig{Name: "deploy"}, RoleAdmin},
+		{"ci", false, RoleOperator, ScriptConfig{Name: "deploy"}, RoleOperator},
+		{"carol", false, RoleEditor, ScriptConfig{Name: "deploy"}, RoleEditor},
+	}
+	for _, tt := range tests {
+		if got := config.ScriptRole(tt.name, tt.token, tt.role, tt.script); got != tt.expected {
+			t.Errorf("%s on %s: expected %s, got %s", tt.name, tt.script.Name, tt.expected, got)
+		}
+	}
+
+	var none *AuthConfig
+	if got := none.ScriptRole("bob", false, RoleViewer, report); got != RoleViewer {
+		t.Errorf("Expected the own role without config, got %s", got)
+	}
+}
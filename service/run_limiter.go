@@ -0,0 +1,241 @@
+// Package service provides core functionality for the run-script-service daemon.
+package service
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// RunWaiting is the state of a manual run that has a worker but waits for a run slot
+const RunWaiting = "waiting"
+
+// WaitingRun is a run waiting for max_concurrent_runs or one of its resource pools
+type WaitingRun struct {
+	RunID    string    `json:"run_id"`
+	Script   string    `json:"script"`
+	Trigger  string    `json:"trigger"` // schedule or manual
+	Priority int       `json:"priority,omitempty"`
+	Pools    []string  `json:"pools,omitempty"`
+	Since    time.Time `json:"since"`
+	Position int       `json:"position"` // place among the waiting runs, 1 starts next
+}
+
+// PoolStatus describes the slots of a resource pool
+type PoolStatus struct {
+	Name  string `json:"name"`
+	Size  int    `json:"size"`
+	InUse int    `json:"in_use"`
+}
+
+// limiterWaiter is a run waiting in the limiter, ready is closed when it got its slots
+// or the limiter was closed
+type limiterWaiter struct {
+	run   WaitingRun
+	ready chan struct{}
+	err   error
+}
+
+// runLimiter admits the runs of all scripts up to max_concurrent_runs and the sizes of the
+// resource pools they declare. Waiting runs are admitted highest priority first, then in
+// arrival order; a run only blocked by a busy pool lets later runs of other pools start.
+type runLimiter struct {
+	mutex   sync.Mutex
+	max     int            // 0 means no global limit
+	pools   map[string]int // pool sizes, pools not configured are unlimited
+	active  int
+	inUse   map[string]int
+	waiting []*limiterWaiter
+	closed  bool
+	refused int
+}
+
+// newRunLimiter creates a limiter with the limits of config
+func newRunLimiter(config *ServiceConfig) *runLimiter {
+	l := &runLimiter{inUse: make(map[string]int)}
+	l.configure(config)
+	return l
+}
+
+// configure applies the limits of config; runs holding slots keep them, and waiting runs
+// that fit the new limits start
+func (l *runLimiter) configure(config *ServiceConfig) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.max = config.MaxConcurrentRuns
+	l.pools = make(map[string]int, len(config.ResourcePools))
+	for name, size := range config.ResourcePools {
+		l.pools[name] = size
+	}
+	l.admit()
+}
+
+// acquire waits until run may start and returns the function releasing its slots; waited is
+// called when the run has to wait. Waiting ends with ctx.Err() when ctx is done and with
+// ErrShuttingDown when the limiter is closed.
+func (l *runLimiter) acquire(ctx context.Context, run WaitingRun, waited func()) (func(), error) {
+	l.mutex.Lock()
+	if l.closed {
+		l.refused++
+		l.mutex.Unlock()
+		return nil, ErrShuttingDown
+	}
+	run.Since = time.Now()
+	run.Pools = distinctPools(run.Pools)
+	w := &limiterWaiter{run: run, ready: make(chan struct{})}
+	i := sort.Search(len(l.waiting), func(i int) bool { return l.waiting[i].run.Priority < run.Priority })
+	l.waiting = append(l.waiting, nil)
+	copy(l.waiting[i+1:], l.waiting[i:])
+	l.waiting[i] = w
+	l.admit()
+	l.mutex.Unlock()
+
+	select {
+	case <-w.ready:
+	default:
+		if waited != nil {
+			waited()
+		}
+		select {
+		case <-w.ready:
+		case <-ctx.Done():
+			l.mutex.Lock()
+			defer l.mutex.Unlock()
+			select {
+			case <-w.ready:
+				// Admitted meanwhile, hand the slots back
+				if w.err == nil {
+					l.releaseLocked(run.Pools)
+				}
+			default:
+				l.remove(w)
+				l.admit()
+			}
+			return nil, ctx.Err()
+		}
+	}
+	if w.err != nil {
+		return nil, w.err
+	}
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			l.mutex.Lock()
+			defer l.mutex.Unlock()
+			l.releaseLocked(run.Pools)
+		})
+	}, nil
+}
+
+// admit starts the waiting runs that fit the limits, l.mutex must be held
+func (l *runLimiter) admit() {
+	for i := 0; i < len(l.waiting); {
+		if l.closed || (l.max > 0 && l.active >= l.max) {
+			return
+		}
+		w := l.waiting[i]
+		if !l.poolsFree(w.run.Pools) {
+			i++
+			continue
+		}
+		l.active++
+		for _, pool := range w.run.Pools {
+			l.inUse[pool]++
+		}
+		l.waiting = append(l.waiting[:i], l.waiting[i+1:]...)
+		close(w.ready)
+	}
+}
+
+// distinctPools drops repeated pool names, a run takes one slot of each pool it lists
+func distinctPools(pools []string) []string {
+	seen := make(map[string]bool, len(pools))
+	distinct := make([]string, 0, len(pools))
+	for _, pool := range pools {
+		if !seen[pool] {
+			seen[pool] = true
+			distinct = append(distinct, pool)
+		}
+	}
+	return distinct
+}
+
+// poolsFree reports whether every pool in pools has a free slot, l.mutex must be held
+func (l *runLimiter) poolsFree(pools []string) bool {
+	for _, pool := range pools {
+		if size, ok := l.pools[pool]; ok && l.inUse[pool] >= size {
+			return false
+		}
+	}
+	return true
+}
+
+// releaseLocked frees the slots of a run holding pools, l.mutex must be held
+func (l *runLimiter) releaseLocked(pools []string) {
+	l.active--
+	for _, pool := range pools {
+		if l.inUse[pool]--; l.inUse[pool] <= 0 {
+			delete(l.inUse, pool)
+		}
+	}
+	l.admit()
+}
+
+// remove drops w from the waiting runs, l.mutex must be held
+func (l *runLimiter) remove(w *limiterWaiter) {
+	for i, waiting := range l.waiting {
+		if waiting == w {
+			l.waiting = append(l.waiting[:i], l.waiting[i+1:]...)
+			return
+		}
+	}
+}
+
+// close fails the waiting runs and refuses new ones with ErrShuttingDown
+func (l *runLimiter) close() {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.closed {
+		return
+	}
+	l.closed = true
+	for _, w := range l.waiting {
+		w.err = ErrShuttingDown
+		close(w.ready)
+	}
+	l.refused += len(l.waiting)
+	l.waiting = nil
+}
+
+// refusedRuns returns how many runs were failed or refused since the limiter was closed
+func (l *runLimiter) refusedRuns() int {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.refused
+}
+
+// waitingRuns returns the waiting runs in the order they are considered
+func (l *runLimiter) waitingRuns() []WaitingRun {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	runs := make([]WaitingRun, 0, len(l.waiting))
+	for i, w := range l.waiting {
+		run := w.run
+		run.Position = i + 1
+		runs = append(runs, run)
+	}
+	return runs
+}
+
+// usage returns the global limit, the runs holding a slot and the pools by name
+func (l *runLimiter) usage() (int, int, []PoolStatus) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	pools := make([]PoolStatus, 0, len(l.pools))
+	for name, size := range l.pools {
+		pools = append(pools, PoolStatus{Name: name, Size: size, InUse: l.inUse[name]})
+	}
+	sort.Slice(pools, func(i, j int) bool { return pools[i].Name < pools[j].Name })
+	return l.max, l.active, pools
+}
@@ -0,0 +1,238 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// acquireAsync acquires a slot for run in the background and sends the release function, or
+// nil when acquire failed, once it returns
+func acquireAsync(l *runLimiter, ctx context.Context, run WaitingRun) chan func() {
+	admitted := make(chan func(), 1)
+	go func() {
+		release, err := l.acquire(ctx, run, nil)
+		if err != nil {
+			release = nil
+		}
+		admitted <- release
+	}()
+	return admitted
+}
+
+// waitForWaiting waits up to 5 seconds until n runs wait in l
+func waitForWaiting(t *testing.T, l *runLimiter, n int) []WaitingRun {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(time.Millisecond) {
+		if runs := l.waitingRuns(); len(runs) == n {
+			return runs
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected %d waiting runs, got %+v", n, l.waitingRuns())
+		}
+	}
+}
+
+func TestRunLimiter_PriorityOrder(t *testing.T) {
+	l := newRunLimiter(&ServiceConfig{MaxConcurrentRuns: 1})
+	ctx := context.Background()
+
+	release, err := l.acquire(ctx, WaitingRun{RunID: "first", Script: "a"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	low := acquireAsync(l, ctx, WaitingRun{RunID: "low", Script: "b"})
+	waitForWaiting(t, l, 1)
+	high := acquireAsync(l, ctx, WaitingRun{RunID: "high", Script: "c", Priority: 10})
+	waiting := waitForWaiting(t, l, 2)
+	if waiting[0].RunID != "high" || waiting[0].Position != 1 || waiting[1].RunID != "low" || waiting[1].Position != 2 {
+		t.Errorf("Expected the high priority run first, got %+v", waiting)
+	}
+	if max, active, _ := l.usage(); max != 1 || active != 1 {
+		t.Errorf("Expected one of one slot in use, got %d of %d", active, max)
+	}
+
+	release()
+	releaseHigh := <-high
+	if releaseHigh == nil {
+		t.Fatal("Expected the high priority run to start")
+	}
+	select {
+	case <-low:
+		t.Fatal("Expected the low priority run to wait for a slot")
+	case <-time.After(20 * time.Millisecond):
+	}
+	releaseHigh()
+	if releaseLow := <-low; releaseLow == nil {
+		t.Error("Expected the low priority run to start last")
+	} else {
+		releaseLow()
+	}
+}
+
+func TestRunLimiter_Pools(t *testing.T) {
+	l := newRunLimiter(&ServiceConfig{ResourcePools: map[string]int{"db": 1}})
+	ctx := context.Background()
+
+	release, err := l.acquire(ctx, WaitingRun{RunID: "1", Script: "backup", Pools: []string{"db"}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := acquireAsync(l, ctx, WaitingRun{RunID: "2", Script: "report", Pools: []string{"db"}, Priority: 5})
+	waitForWaiting(t, l, 1)
+
+	// A run of another pool is not held up by the busy db pool
+	other, err := l.acquire(ctx, WaitingRun{RunID: "3", Script: "fetch", Pools: []string{"api"}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other()
+
+	if _, _, pools := l.usage(); len(pools) != 1 || pools[0] != (PoolStatus{Name: "db", Size: 1, InUse: 1}) {
+		t.Errorf("Expected the db pool full, got %+v", pools)
+	}
+	release()
+	if releaseDB := <-db; releaseDB == nil {
+		t.Error("Expected the waiting db run to start")
+	} else {
+		releaseDB()
+	}
+	if _, active, pools := l.usage(); active != 0 || pools[0].InUse != 0 {
+		t.Errorf("Expected all slots free, got %d active and %+v", active, pools)
+	}
+}
+
+func TestRunLimiter_RepeatedPool(t *testing.T) {
+	l := newRunLimiter(&ServiceConfig{ResourcePools: map[string]int{"db": 1}})
+	ctx := context.Background()
+
+	release, err := l.acquire(ctx, WaitingRun{RunID: "1", Script: "backup", Pools: []string{"db", "db"}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, pools := l.usage(); pools[0].InUse != 1 {
+		t.Errorf("Expected a repeated pool to take one slot, got %+v", pools)
+	}
+	second := acquireAsync(l, ctx, WaitingRun{RunID: "2", Script: "report", Pools: []string{"db"}})
+	waitForWaiting(t, l, 1)
+
+	release()
+	releaseSecond := <-second
+	if releaseSecond == nil {
+		t.Fatal("Expected the waiting db run to start")
+	}
+	if _, _, pools := l.usage(); pools[0].InUse != 1 {
+		t.Errorf("Expected one slot in use, got %+v", pools)
+	}
+	releaseSecond()
+	if _, active, pools := l.usage(); active != 0 || pools[0].InUse != 0 {
+		t.Errorf("Expected all slots free, got %d active and %+v", active, pools)
+	}
+}
+
+func TestRunLimiter_CancelAndClose(t *testing.T) {
+	l := newRunLimiter(&ServiceConfig{MaxConcurrentRuns: 1})
+
+	release, err := l.acquire(context.Background(), WaitingRun{RunID: "1", Script: "a"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release()
+
+	waited := false
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := l.acquire(ctx, WaitingRun{RunID: "2", Script: "a"}, func() { waited = true }); !errors.Is(err, context.DeadlineExceeded) || !waited {
+		t.Errorf("Expected the run to wait until its context ended, got %v (waited %v)", err, waited)
+	}
+	if runs := l.waitingRuns(); len(runs) != 0 {
+		t.Errorf("Expected a cancelled run to stop waiting, got %+v", runs)
+	}
+
+	closed := make(chan error, 1)
+	go func() {
+		_, err := l.acquire(context.Background(), WaitingRun{RunID: "3", Script: "a"}, nil)
+		closed <- err
+	}()
+	waitForWaiting(t, l, 1)
+	l.close()
+	if err := <-closed; !errors.Is(err, ErrShuttingDown) {
+		t.Errorf("Expected the waiting run to be refused, got %v", err)
+	}
+	if _, err := l.acquire(context.Background(), WaitingRun{RunID: "4", Script: "a"}, nil); !errors.Is(err, ErrShuttingDown) {
+		t.Errorf("Expected new runs to be refused, got %v", err)
+	}
+	if refused := l.refusedRuns(); refused != 2 {
+		t.Errorf("Expected 2 refused runs, got %d", refused)
+	}
+}
+
+func TestRunLimiter_Configure(t *testing.T) {
+	l := newRunLimiter(&ServiceConfig{MaxConcurrentRuns: 1})
+	release, _ := l.acquire(context.Background(), WaitingRun{RunID: "1", Script: "a"}, nil)
+	defer release()
+	waiting := acquireAsync(l, context.Background(), WaitingRun{RunID: "2", Script: "a"})
+	waitForWaiting(t, l, 1)
+
+	l.configure(&ServiceConfig{MaxConcurrentRuns: 2})
+	if releaseWaiting := <-waiting; releaseWaiting == nil {
+		t.Error("Expected a raised limit to start the waiting run")
+	} else {
+		releaseWaiting()
+	}
+}
+
+func TestScriptManager_RunLimits(t *testing.T) {
+	tmpDir := t.TempDir()
+	scriptPath := filepath.Join(tmpDir, "slow.sh")
+	if err := os.WriteFile(scriptPath, []byte("#!/bin/bash\nsleep 0.3\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	manager := NewScriptManager(&ServiceConfig{
+		Scripts: []ScriptConfig{
+			{Name: "slow", Path: scriptPath, Interval: 60, MaxLogLines: 10},
+			{Name: "urgent", Path: scriptPath, Interval: 60, MaxLogLines: 10, Priority: 10},
+		},
+		MaxConcurrentRuns: 1,
+	})
+	manager.SetLogDir(filepath.Join(tmpDir, "logs"))
+
+	first, _ := manager.EnqueueRun("slow")
+	for deadline := time.Now().Add(5 * time.Second); manager.QueueStatus().Active == 0; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the first run to start")
+		}
+	}
+	second, _ := manager.EnqueueRun("slow")
+	urgent, _ := manager.EnqueueRun("urgent")
+	for deadline := time.Now().Add(5 * time.Second); len(manager.QueueStatus().Waiting) < 2; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected two runs waiting for a slot, got %+v", manager.QueueStatus())
+		}
+	}
+
+	status := manager.QueueStatus()
+	if status.MaxConcurrentRuns != 1 || status.Active != 1 || status.Waiting[0].RunID != urgent.ID {
+		t.Errorf("Expected the urgent run to start next, got %+v", status)
+	}
+	if run, _ := manager.GetRun(second.ID); run.State != RunWaiting || run.Position != 2 || run.StartedAt != nil {
+		t.Errorf("Expected the second run waiting at position 2, got %+v", run)
+	}
+	if run, _ := manager.GetRun(first.ID); run.State != RunRunning {
+		t.Errorf("Expected the first run running, got %+v", run)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	urgentRun, _ := manager.WaitRun(ctx, urgent.ID)
+	secondRun, _ := manager.WaitRun(ctx, second.ID)
+	if urgentRun.State != RunSucceeded || secondRun.State != RunSucceeded {
+		t.Fatalf("Expected both runs to succeed, got %+v and %+v", urgentRun, secondRun)
+	}
+	if !urgentRun.FinishedAt.Before(*secondRun.FinishedAt) {
+		t.Errorf("Expected the urgent run to finish first, got %s and %s", urgentRun.FinishedAt, secondRun.FinishedAt)
+	}
+}
@@ -0,0 +1,127 @@
+// Package service provides core functionality for the run-script-service daemon.
+package service
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// MetricsContentType is the content type of the Prometheus text exposition format
+const MetricsContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// RunMetrics aggregates the runs recorded since the daemon started
+type RunMetrics struct {
+	scripts map[string]*scriptMetrics
+	mutex   sync.RWMutex
+}
+
+// scriptMetrics holds the metrics of one script
+type scriptMetrics struct {
+	runs         map[string]int64 // by outcome
+	lastDuration int64
+	lastExitCode int
+	lastRun      float64            // unix seconds
+	results      map[string]float64 // result fields configured as metrics, from the last run reporting them
+}
+
+// NewRunMetrics creates an empty metrics registry
+func NewRunMetrics() *RunMetrics {
+	return &RunMetrics{scripts: make(map[string]*scriptMetrics)}
+}
+
+// Record adds a finished run and the result fields exported as metrics
+func (m *RunMetrics) Record(entry *LogEntry, results map[string]float64) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	script, ok := m.scripts[entry.ScriptName]
+	if !ok {
+		script = &scriptMetrics{runs: make(map[string]int64), results: make(map[string]float64)}
+		m.scripts[entry.ScriptName] = script
+	}
+
+	script.runs[entry.RunOutcome()]++
+	script.lastDuration = entry.Duration
+	script.lastExitCode = entry.ExitCode
+	script.lastRun = float64(entry.Timestamp.UnixMilli()) / 1000
+	for name, value := range results {
+		script.results[name] = value
+	}
+}
+
+// metricFamily is one metric name with its samples
+type metricFamily struct {
+	name    string
+	help    string
+	kind    string
+	samples []string
+}
+
+// WritePrometheus writes all metrics in the Prometheus text exposition format
+func (m *RunMetrics) WritePrometheus(w io.Writer) error {
+	m.mutex.RLock()
+	names := make([]string, 0, len(m.scripts))
+	for name := range m.scripts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	families := []*metricFamily{
+		{name: "run_script_runs_total", help: "Runs recorded since the daemon started, by outcome.", kind: "counter"},
+		{name: "run_script_last_duration_milliseconds", help: "Duration of the last run.", kind: "gauge"},
+		{name: "run_script_last_exit_code", help: "Exit code of the last run.", kind: "gauge"},
+		{name: "run_script_last_run_timestamp_seconds", help: "Start time of the last run.", kind: "gauge"},
+		{name: "run_script_result", help: "Numeric result fields of the last run, as configured in output.metrics.", kind: "gauge"},
+	}
+	runs, duration, exitCode, lastRun, results := families[0], families[1], families[2], families[3], families[4]
+
+	for _, name := range names {
+		script := m.scripts[name]
+		label := `script="` + escapeLabelValue(name) + `"`
+		for _, outcome := range sortedKeys(script.runs) {
+			runs.samples = append(runs.samples, fmt.Sprintf(`{%s,outcome="%s"} %d`, label, outcome, script.runs[outcome]))
+		}
+		duration.samples = append(duration.samples, fmt.Sprintf("{%s} %d", label, script.lastDuration))
+		exitCode.samples = append(exitCode.samples, fmt.Sprintf("{%s} %d", label, script.lastExitCode))
+		lastRun.samples = append(lastRun.samples, fmt.Sprintf("{%s} %s", label, formatMetricValue(script.lastRun)))
+		for _, field := range sortedKeys(script.results) {
+			results.samples = append(results.samples, fmt.Sprintf(`{%s,field="%s"} %s`,
+				label, escapeLabelValue(field), formatMetricValue(script.results[field])))
+		}
+	}
+	m.mutex.RUnlock()
+
+	out := bufio.NewWriter(w)
+	for _, family := range families {
+		fmt.Fprintf(out, "# HELP %s %s\n# TYPE %s %s\n", family.name, family.help, family.name, family.kind)
+		for _, sample := range family.samples {
+			fmt.Fprintf(out, "%s%s\n", family.name, sample)
+		}
+	}
+	return out.Flush()
+}
+
+// sortedKeys returns the keys of a map in order
+func sortedKeys[V any](values map[string]V) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// escapeLabelValue escapes a Prometheus label value
+func escapeLabelValue(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+// formatMetricValue formats a sample value
+func formatMetricValue(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
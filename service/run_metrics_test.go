@@ -0,0 +1,40 @@
+package service
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunMetrics_WritePrometheus(t *testing.T) {
+	metrics := NewRunMetrics()
+	start := time.Unix(1740830400, 0)
+	metrics.Record(&LogEntry{ScriptName: "load", Timestamp: start, Duration: 20, Outcome: OutcomeSuccess},
+		map[string]float64{"rows": 1200})
+	metrics.Record(&LogEntry{ScriptName: "load", Timestamp: start.Add(time.Minute), ExitCode: 2, Duration: 35},
+		map[string]float64{"bytes": 1.5e9})
+	metrics.Record(&LogEntry{ScriptName: `odd"name`, Timestamp: start}, nil)
+
+	var buf bytes.Buffer
+	if err := metrics.WritePrometheus(&buf); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	output := buf.String()
+
+	for _, line := range []string{
+		"# TYPE run_script_runs_total counter",
+		`run_script_runs_total{script="load",outcome="failure"} 1`,
+		`run_script_runs_total{script="load",outcome="success"} 1`,
+		`run_script_last_duration_milliseconds{script="load"} 35`,
+		`run_script_last_exit_code{script="load"} 2`,
+		`run_script_last_run_timestamp_seconds{script="load"} 1740830460`,
+		`run_script_result{script="load",field="bytes"} 1500000000`,
+		`run_script_result{script="load",field="rows"} 1200`,
+		`run_script_runs_total{script="odd\"name",outcome="success"} 1`,
+	} {
+		if !strings.Contains(output, line+"\n") {
+			t.Errorf("Expected line %q in:\n%s", line, output)
+		}
+	}
+}
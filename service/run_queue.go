@@ -0,0 +1,271 @@
+// Package service provides core functionality for the run-script-service daemon.
+package service
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Defaults of the manual run queue, used when run_workers or run_queue_size is 0
+const (
+	DefaultRunWorkers   = 4
+	DefaultRunQueueSize = 100
+)
+
+// finishedRunsKept is how many finished runs the queue remembers for status requests
+const finishedRunsKept = 200
+
+// States of a queued run
+const (
+	RunQueued    = "queued"
+	RunRunning   = "running"
+	RunSucceeded = "succeeded"
+	RunFailed    = "failed"
+)
+
+// ErrQueueFull is returned for runs requested while run_queue_size runs are waiting
+var ErrQueueFull = errors.New("run queue is full")
+
+// RunWorkerCount returns how many manual runs may execute at once
+func (c *ServiceConfig) RunWorkerCount() int {
+	if c.RunWorkers <= 0 {
+		return DefaultRunWorkers
+	}
+	return c.RunWorkers
+}
+
+// RunQueueCapacity returns how many manual runs may wait for a worker
+func (c *ServiceConfig) RunQueueCapacity() int {
+	if c.RunQueueSize <= 0 {
+		return DefaultRunQueueSize
+	}
+	return c.RunQueueSize
+}
+
+// RunStatus describes a manual run from being queued until it finished
+type RunStatus struct {
+	ID         string     `json:"id"` // run ID of the log entry and artifacts
+	Script     string     `json:"script"`
+	State      string     `json:"state"`              // queued, waiting, running, succeeded or failed
+	Priority   int        `json:"priority,omitempty"` // higher priority runs leave the queue first
+	Position   int        `json:"position,omitempty"` // place among the queued or waiting runs, 1 runs next
+	QueuedAt   time.Time  `json:"queued_at"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	Error      string     `json:"error,omitempty"` // why a failed run failed
+}
+
+// Finished reports whether the run succeeded or failed
+func (s RunStatus) Finished() bool {
+	return s.State == RunSucceeded || s.State == RunFailed
+}
+
+// QueueStatus describes the manual run queue and, through ScriptManager.QueueStatus, the
+// runs of all scripts waiting for a slot
+type QueueStatus struct {
+	Workers  int         `json:"workers"`
+	Capacity int         `json:"capacity"`
+	Depth    int         `json:"depth"`   // runs waiting for a worker
+	Running  []RunStatus `json:"running"` // oldest first
+	Pending  []RunStatus `json:"pending"` // in the order they will run
+
+	MaxConcurrentRuns int          `json:"max_concurrent_runs"` // 0 means no limit
+	Active            int          `json:"active"`              // runs of all scripts holding a slot
+	Pools             []PoolStatus `json:"pools"`               // by name
+	Waiting           []WaitingRun `json:"waiting"`             // scheduled and manual runs waiting for a slot, in the order they start
+}
+
+// queuedRun is a run tracked by the queue, done is closed when it finished
+type queuedRun struct {
+	status RunStatus
+	done   chan struct{}
+}
+
+// RunQueue executes manual runs on a bounded pool of workers. Runs wait in a bounded queue,
+// highest priority first and otherwise in order, and finished runs are remembered for a while so their callers can poll them.
+type RunQueue struct {
+	mutex    sync.Mutex
+	wake     *sync.Cond
+	run      func(ctx context.Context, script, runID string) error
+	workers  int
+	capacity int
+	started  bool
+	closed   bool
+	pending  []*queuedRun
+	running  []*queuedRun
+	runs     map[string]*queuedRun
+	finished []string // IDs of finished runs, oldest first
+}
+
+// NewRunQueue creates a queue executing runs with run on up to workers goroutines, started
+// with the first run, and holding up to capacity waiting runs
+func NewRunQueue(run func(ctx context.Context, script, runID string) error, workers, capacity int) *RunQueue {
+	q := &RunQueue{
+		run:      run,
+		workers:  workers,
+		capacity: capacity,
+		runs:     make(map[string]*queuedRun),
+	}
+	q.wake = sync.NewCond(&q.mutex)
+	return q
+}
+
+// Enqueue queues a run of script behind the runs of the same or higher priority and returns
+// its status
+func (q *RunQueue) Enqueue(script string, priority int) (RunStatus, error) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	if q.closed {
+		return RunStatus{}, ErrShuttingDown
+	}
+	if len(q.pending) >= q.capacity {
+		return RunStatus{}, ErrQueueFull
+	}
+	if !q.started {
+		q.started = true
+		for i := 0; i < q.workers; i++ {
+			go q.work()
+		}
+	}
+
+	r := &queuedRun{
+		status: RunStatus{ID: NewRunID(), Script: script, State: RunQueued, Priority: priority, QueuedAt: time.Now()},
+		done:   make(chan struct{}),
+	}
+	i := sort.Search(len(q.pending), func(i int) bool { return q.pending[i].status.Priority < priority })
+	q.pending = append(q.pending, nil)
+	copy(q.pending[i+1:], q.pending[i:])
+	q.pending[i] = r
+	q.runs[r.status.ID] = r
+	q.wake.Signal()
+	return q.statusOf(r), nil
+}
+
+// work executes queued runs until the queue is closed
+func (q *RunQueue) work() {
+	for {
+		q.mutex.Lock()
+		for len(q.pending) == 0 && !q.closed {
+			q.wake.Wait()
+		}
+		if q.closed {
+			q.mutex.Unlock()
+			return
+		}
+		r := q.pending[0]
+		q.pending = q.pending[1:]
+		now := time.Now()
+		r.status.State, r.status.StartedAt = RunRunning, &now
+		q.running = append(q.running, r)
+		q.mutex.Unlock()
+
+		err := q.run(context.Background(), r.status.Script, r.status.ID)
+
+		q.mutex.Lock()
+		for i, active := range q.running {
+			if active == r {
+				q.running = append(q.running[:i], q.running[i+1:]...)
+				break
+			}
+		}
+		q.finish(r, err)
+		q.mutex.Unlock()
+	}
+}
+
+// finish records the outcome of r and forgets the oldest finished runs, q.mutex must be held
+func (q *RunQueue) finish(r *queuedRun, err error) {
+	now := time.Now()
+	r.status.State, r.status.FinishedAt = RunSucceeded, &now
+	if err != nil {
+		r.status.State, r.status.Error = RunFailed, err.Error()
+	}
+	close(r.done)
+
+	q.finished = append(q.finished, r.status.ID)
+	for len(q.finished) > finishedRunsKept {
+		delete(q.runs, q.finished[0])
+		q.finished = q.finished[1:]
+	}
+}
+
+// statusOf returns the status of r with its queue position, q.mutex must be held
+func (q *RunQueue) statusOf(r *queuedRun) RunStatus {
+	status := r.status
+	for i, waiting := range q.pending {
+		if waiting == r {
+			status.Position = i + 1
+			break
+		}
+	}
+	return status
+}
+
+// Get returns the status of run id, false when the queue does not know the run
+func (q *RunQueue) Get(id string) (RunStatus, bool) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	r, ok := q.runs[id]
+	if !ok {
+		return RunStatus{}, false
+	}
+	return q.statusOf(r), true
+}
+
+// Wait blocks until run id finished or ctx is done, and returns its status then
+func (q *RunQueue) Wait(ctx context.Context, id string) (RunStatus, bool) {
+	q.mutex.Lock()
+	r, ok := q.runs[id]
+	q.mutex.Unlock()
+	if !ok {
+		return RunStatus{}, false
+	}
+	select {
+	case <-r.done:
+	case <-ctx.Done():
+	}
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	return q.statusOf(r), true
+}
+
+// Status returns the workers, capacity and the runs in flight and waiting
+func (q *RunQueue) Status() QueueStatus {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	status := QueueStatus{
+		Workers:  q.workers,
+		Capacity: q.capacity,
+		Depth:    len(q.pending),
+		Running:  make([]RunStatus, 0, len(q.running)),
+		Pending:  make([]RunStatus, 0, len(q.pending)),
+	}
+	for _, r := range q.running {
+		status.Running = append(status.Running, q.statusOf(r))
+	}
+	for _, r := range q.pending {
+		status.Pending = append(status.Pending, q.statusOf(r))
+	}
+	return status
+}
+
+// Close refuses new runs, fails the waiting ones with ErrShuttingDown and returns how many
+// it failed. Runs in flight complete, their workers exit afterwards.
+func (q *RunQueue) Close() int {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	if q.closed {
+		return 0
+	}
+	q.closed = true
+	dropped := len(q.pending)
+	for _, r := range q.pending {
+		q.finish(r, ErrShuttingDown)
+	}
+	q.pending = nil
+	q.wake.Broadcast()
+	return dropped
+}
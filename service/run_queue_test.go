@@ -0,0 +1,214 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// blockingRuns returns a run function that blocks each run until release is closed,
+// and a channel receiving the script of every run as it starts
+func blockingRuns() (func(ctx context.Context, script, runID string) error, chan string, chan struct{}) {
+	started := make(chan string, 10)
+	release := make(chan struct{})
+	return func(ctx context.Context, script, runID string) error {
+		started <- script
+		<-release
+		if script == "broken" {
+			return errors.New("script exited with code 1")
+		}
+		return nil
+	}, started, release
+}
+
+// waitForQueuedRun waits up to 5 seconds for run id to finish
+func waitForQueuedRun(t *testing.T, q *RunQueue, id string) RunStatus {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	status, ok := q.Wait(ctx, id)
+	if !ok || !status.Finished() {
+		t.Fatalf("Expected run %s to finish, got %+v", id, status)
+	}
+	return status
+}
+
+func TestRunQueue_RunsInOrder(t *testing.T) {
+	run, started, release := blockingRuns()
+	q := NewRunQueue(run, 1, 10)
+
+	first, err := q.Enqueue("a", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.ID == "" || first.State != RunQueued || first.Script != "a" {
+		t.Errorf("Unexpected status %+v", first)
+	}
+	<-started
+	second, _ := q.Enqueue("b", 0)
+	third, _ := q.Enqueue("broken", 0)
+	if second.Position != 1 || third.Position != 2 {
+		t.Errorf("Expected positions 1 and 2, got %d and %d", second.Position, third.Position)
+	}
+
+	status := q.Status()
+	if status.Workers != 1 || status.Capacity != 10 || status.Depth != 2 {
+		t.Errorf("Unexpected queue status %+v", status)
+	}
+	if len(status.Running) != 1 || status.Running[0].ID != first.ID || status.Running[0].StartedAt == nil {
+		t.Errorf("Expected the first run in flight, got %+v", status.Running)
+	}
+	if len(status.Pending) != 2 || status.Pending[0].ID != second.ID || status.Pending[1].Position != 2 {
+		t.Errorf("Expected the other runs pending in order, got %+v", status.Pending)
+	}
+
+	close(release)
+	if s := waitForQueuedRun(t, q, first.ID); s.State != RunSucceeded || s.FinishedAt == nil {
+		t.Errorf("Expected the first run to succeed, got %+v", s)
+	}
+	if s := waitForQueuedRun(t, q, third.ID); s.State != RunFailed || s.Error != "script exited with code 1" {
+		t.Errorf("Expected the broken run to fail, got %+v", s)
+	}
+	if got := []string{<-started, <-started}; got[0] != "b" || got[1] != "broken" {
+		t.Errorf("Expected runs in queue order, got %v", got)
+	}
+}
+
+func TestRunQueue_Full(t *testing.T) {
+	run, started, release := blockingRuns()
+	defer close(release)
+	q := NewRunQueue(run, 1, 1)
+
+	if _, err := q.Enqueue("a", 0); err != nil {
+		t.Fatal(err)
+	}
+	<-started
+	if _, err := q.Enqueue("b", 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := q.Enqueue("c", 0); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("Expected ErrQueueFull, got %v", err)
+	}
+}
+
+func TestRunQueue_WaitTimeout(t *testing.T) {
+	run, started, release := blockingRuns()
+	defer close(release)
+	q := NewRunQueue(run, 1, 1)
+
+	queued, _ := q.Enqueue("a", 0)
+	<-started
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	status, ok := q.Wait(ctx, queued.ID)
+	if !ok || status.State != RunRunning {
+		t.Errorf("Expected the run still in flight, got %+v", status)
+	}
+	if _, ok := q.Get("unknown"); ok {
+		t.Error("Expected an unknown run not to be found")
+	}
+}
+
+func TestRunQueue_Close(t *testing.T) {
+	run, started, release := blockingRuns()
+	q := NewRunQueue(run, 1, 10)
+
+	inFlight, _ := q.Enqueue("a", 0)
+	<-started
+	waiting, _ := q.Enqueue("b", 0)
+
+	if dropped := q.Close(); dropped != 1 {
+		t.Errorf("Expected one dropped run, got %d", dropped)
+	}
+	if s, _ := q.Get(waiting.ID); s.State != RunFailed || s.Error != ErrShuttingDown.Error() {
+		t.Errorf("Expected the waiting run to fail, got %+v", s)
+	}
+	if _, err := q.Enqueue("c", 0); !errors.Is(err, ErrShuttingDown) {
+		t.Errorf("Expected new runs to be refused, got %v", err)
+	}
+
+	close(release)
+	if s := waitForQueuedRun(t, q, inFlight.ID); s.State != RunSucceeded {
+		t.Errorf("Expected the run in flight to complete, got %+v", s)
+	}
+}
+
+func TestRunQueue_ForgetsOldRuns(t *testing.T) {
+	q := NewRunQueue(func(ctx context.Context, script, runID string) error { return nil }, 2, finishedRunsKept+10)
+
+	var ids []string
+	for i := 0; i <= finishedRunsKept; i++ {
+		status, err := q.Enqueue("a", 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, status.ID)
+	}
+	for _, id := range ids {
+		q.Wait(context.Background(), id)
+	}
+	known := 0
+	for _, id := range ids {
+		if _, ok := q.Get(id); ok {
+			known++
+		}
+	}
+	if known != finishedRunsKept {
+		t.Errorf("Expected %d finished runs remembered, got %d", finishedRunsKept, known)
+	}
+}
+
+func TestScriptManager_EnqueueRun(t *testing.T) {
+	tmpDir := t.TempDir()
+	scriptPath := filepath.Join(tmpDir, "hello.sh")
+	if err := os.WriteFile(scriptPath, []byte("#!/bin/bash\necho hello\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	manager := NewScriptManager(&ServiceConfig{
+		Scripts:    []ScriptConfig{{Name: "hello", Path: scriptPath, Interval: 60, MaxLogLines: 10}},
+		RunWorkers: 2,
+	})
+	manager.SetLogDir(filepath.Join(tmpDir, "logs"))
+
+	queued, err := manager.EnqueueRun("hello")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := waitForQueuedRun(t, manager.GetRunQueue(), queued.ID); s.State != RunSucceeded {
+		t.Fatalf("Expected the run to succeed, got %+v", s)
+	}
+	entries := manager.GetLogManager().GetLogger("hello").GetEntries()
+	if len(entries) != 1 || entries[0].RunID != queued.ID || entries[0].Trigger != TriggerManual {
+		t.Errorf("Expected a manual run recorded as %s, got %+v", queued.ID, entries)
+	}
+	if status := manager.GetRunQueue().Status(); status.Workers != 2 || status.Capacity != DefaultRunQueueSize {
+		t.Errorf("Expected the configured queue, got %+v", status)
+	}
+
+	if _, err := manager.EnqueueRun("missing"); !errors.Is(err, ErrScriptNotFound) {
+		t.Errorf("Expected ErrScriptNotFound, got %v", err)
+	}
+}
+
+func TestRunQueue_Priority(t *testing.T) {
+	run, started, release := blockingRuns()
+	defer close(release)
+	q := NewRunQueue(run, 1, 10)
+
+	q.Enqueue("a", 0)
+	<-started
+	low, _ := q.Enqueue("low", 0)
+	high, _ := q.Enqueue("high", 5)
+	later, _ := q.Enqueue("later", 5)
+
+	pending := q.Status().Pending
+	if len(pending) != 3 || pending[0].ID != high.ID || pending[1].ID != later.ID || pending[2].ID != low.ID {
+		t.Errorf("Expected higher priority runs first in order, got %+v", pending)
+	}
+	if s, _ := q.Get(low.ID); s.Position != 3 || s.Priority != 0 {
+		t.Errorf("Expected the low priority run last, got %+v", s)
+	}
+}
@@ -3,33 +3,115 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log"
+	"os"
+	"reflect"
+	"strings"
 	"sync"
+	"time"
 )
 
+// Errors wrapped by ScriptManager methods, their messages read "script <name> ..."
+var (
+	ErrScriptNotFound = errors.New("not found in configuration")
+	ErrScriptExists   = errors.New("already exists")
+)
+
+// ReloadHook is called after a reload with the configuration it replaced and the new one
+type ReloadHook func(previous, current *ServiceConfig)
+
 // ScriptManager manages multiple script runners
 type ScriptManager struct {
-	scripts    map[string]*ScriptRunner
-	config     *ServiceConfig
-	configPath string
-	mutex      sync.RWMutex
+	scripts          map[string]*ScriptRunner
+	config           *ServiceConfig
+	configPath       string
+	logManager       *LogManager       // structured run logs, rooted at the log directory
+	eventBroadcaster *EventBroadcaster // script status events for the web interface
+	forwarder        *LogForwarder     // log sinks of the current configuration
+	metrics          *RunMetrics       // runs recorded since start, exported at /api/v1/metrics
+	artifacts        *ArtifactStore    // files kept with runs, nil until SetArtifactDir is called
+	ctx              context.Context   // context scheduled scripts were started with
+	gate             *runGate          // runs in flight, closed by Shutdown
+	limiter          *runLimiter       // max_concurrent_runs and resource pools, closed by Shutdown
+	queue            *RunQueue         // manual runs requested through the API
+	reloadHooks      []ReloadHook      // called after a reload, see OnReload
+	mutex            sync.RWMutex
 }
 
 // NewScriptManager creates a new script manager with the given configuration
 func NewScriptManager(config *ServiceConfig) *ScriptManager {
-	return &ScriptManager{
-		scripts: make(map[string]*ScriptRunner),
-		config:  config,
-	}
+	return NewScriptManagerWithPath(config, "")
 }
 
-// NewScriptManagerWithPath creates a new script manager with configuration and config path
+// NewScriptManagerWithPath creates a new script manager with configuration and config path.
+// Script logs go to the working directory until SetLogDir is called.
 func NewScriptManagerWithPath(config *ServiceConfig, configPath string) *ScriptManager {
-	return &ScriptManager{
-		scripts:    make(map[string]*ScriptRunner),
-		config:     config,
-		configPath: configPath,
+	sm := &ScriptManager{
+		scripts:          make(map[string]*ScriptRunner),
+		config:           config,
+		configPath:       configPath,
+		logManager:       NewLogManager(""),
+		eventBroadcaster: NewEventBroadcaster(),
+		forwarder:        NewLogForwarder(config),
+		metrics:          NewRunMetrics(),
+		gate:             newRunGate(),
+		limiter:          newRunLimiter(config),
 	}
+	sm.queue = NewRunQueue(sm.runScript, config.RunWorkerCount(), config.RunQueueCapacity())
+	return sm
+}
+
+// SetLogDir roots the script manager's LogManager at dir; runners started afterwards log there
+func (sm *ScriptManager) SetLogDir(dir string) {
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+	sm.logManager = NewLogManager(dir)
+}
+
+// SetArtifactDir keeps the artifacts of runs started afterwards in dir
+func (sm *ScriptManager) SetArtifactDir(dir string) {
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+	sm.artifacts = NewArtifactStore(dir)
+}
+
+// GetArtifactStore returns the store run artifacts are kept in, or nil when artifacts are disabled
+func (sm *ScriptManager) GetArtifactStore() *ArtifactStore {
+	sm.mutex.RLock()
+	defer sm.mutex.RUnlock()
+	return sm.artifacts
+}
+
+// GetLogManager returns the LogManager script runs are recorded in
+func (sm *ScriptManager) GetLogManager() *LogManager {
+	sm.mutex.RLock()
+	defer sm.mutex.RUnlock()
+	return sm.logManager
+}
+
+// GetEventBroadcaster returns the broadcaster script status events are published on
+func (sm *ScriptManager) GetEventBroadcaster() *EventBroadcaster {
+	return sm.eventBroadcaster
+}
+
+// newRunner creates a runner that records to the LogManager and publishes status events
+func (sm *ScriptManager) newRunner(config ScriptConfig) *ScriptRunner {
+	runner := NewManagedScriptRunner(config, sm.logManager, sm.eventBroadcaster)
+	runner.SetLogForwarder(sm.forwarder)
+	runner.SetRunMetrics(sm.metrics)
+	runner.gate = sm.gate
+	runner.limiter = sm.limiter
+	if sm.artifacts != nil {
+		runner.SetArtifactStore(sm.artifacts, sm.config.ArtifactPolicyFor(&config))
+	}
+	return runner
+}
+
+// GetRunMetrics returns the metrics of the runs recorded since the manager was created
+func (sm *ScriptManager) GetRunMetrics() *RunMetrics {
+	return sm.metrics
 }
 
 // StartScript starts a script by name
@@ -52,12 +134,11 @@ func (sm *ScriptManager) StartScript(ctx context.Context, name string) error {
 	}
 
 	if scriptConfig == nil {
-		return fmt.Errorf("script %s not found in configuration", name)
+		return fmt.Errorf("script %s %w", name, ErrScriptNotFound)
 	}
 
 	// Create and start the script runner
-	logPath := fmt.Sprintf("%s.log", name) // Simple log path for now
-	runner := NewScriptRunner(*scriptConfig, logPath)
+	runner := sm.newRunner(*scriptConfig)
 	sm.scripts[name] = runner
 
 	// Start the runner in a goroutine
@@ -89,6 +170,10 @@ func (sm *ScriptManager) StopScript(name string) error {
 
 // StartAllEnabled starts all enabled scripts
 func (sm *ScriptManager) StartAllEnabled(ctx context.Context) error {
+	sm.mutex.Lock()
+	sm.ctx = ctx
+	sm.mutex.Unlock()
+
 	for _, scriptConfig := range sm.config.Scripts {
 		if scriptConfig.Enabled {
 			if err := sm.StartScript(ctx, scriptConfig.Name); err != nil {
@@ -136,12 +221,211 @@ func (sm *ScriptManager) GetConfig() *ServiceConfig {
 	return sm.config
 }
 
+// Context returns the context scheduled scripts run under, or a background context if none were started
+func (sm *ScriptManager) Context() context.Context {
+	sm.mutex.RLock()
+	defer sm.mutex.RUnlock()
+
+	if sm.ctx == nil {
+		return context.Background()
+	}
+	return sm.ctx
+}
+
+// CompactLogs applies each script's retention policy to its log files. Logs of scripts
+// that are no longer configured follow the global log_retention.
+func (sm *ScriptManager) CompactLogs() []CompactionResult {
+	sm.mutex.RLock()
+	logManager := sm.logManager
+	policies := make(map[string]RetentionPolicy)
+	for i := range sm.config.Scripts {
+		policies[sm.config.Scripts[i].Name] = sm.config.RetentionFor(&sm.config.Scripts[i])
+	}
+	global := sm.config.RetentionFor(&ScriptConfig{})
+	sm.mutex.RUnlock()
+
+	names, err := ListLogScripts(logManager.GetBaseDir())
+	if err != nil {
+		Warnf("Log compaction skipped: %v", err)
+		return nil
+	}
+
+	var results []CompactionResult
+	for _, name := range names {
+		policy, configured := policies[name]
+		if !configured {
+			policy = global
+		}
+		if policy.IsZero() {
+			continue
+		}
+		result, err := logManager.Compact(name, policy)
+		if err != nil {
+			Warnf("Log compaction of %s failed: %v", name, err)
+			continue
+		}
+		if result.Rotated || result.Removed > 0 {
+			Debugf("Compacted logs of %s: rotated=%v removed=%d bytes=%d", name, result.Rotated, result.Removed, result.Bytes)
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// StartLogCompactor runs CompactLogs now and then every interval until ctx is done
+func (sm *ScriptManager) StartLogCompactor(ctx context.Context, interval time.Duration) {
+	go func() {
+		sm.CompactLogs()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				sm.CompactLogs()
+			}
+		}
+	}()
+}
+
+// GetConfigPath returns the path the configuration is saved to
+func (sm *ScriptManager) GetConfigPath() string {
+	return sm.configPath
+}
+
 // SaveConfig saves the current configuration to file
 func (sm *ScriptManager) SaveConfig() error {
 	if sm.configPath == "" {
 		return fmt.Errorf("config path not set - cannot save configuration")
 	}
-	return SaveServiceConfig(sm.configPath, sm.config)
+	return SaveServiceConfigWithOrigin(sm.configPath, sm.config, OriginAPI)
+}
+
+// GetConfigHistory returns the version history of the configuration file
+func (sm *ScriptManager) GetConfigHistory() (*ConfigHistory, error) {
+	if sm.configPath == "" {
+		return nil, fmt.Errorf("config path not set - no configuration history")
+	}
+	return NewConfigHistory(sm.configPath, sm.config.ConfigHistoryLimit), nil
+}
+
+// ReloadConfig re-reads the configuration file, records it in the history
+// and restarts the enabled scripts with the new settings if scheduling was active.
+// Changed settings that are only read at startup are logged as needing a restart.
+func (sm *ScriptManager) ReloadConfig(ctx context.Context) error {
+	if sm.configPath == "" {
+		return fmt.Errorf("config path not set - cannot reload configuration")
+	}
+
+	// A broken file must not replace a working configuration
+	issues, err := ValidateServiceConfigFile(sm.configPath, ValidationOptions{})
+	if err != nil {
+		return err
+	}
+	if len(issues) > 0 {
+		return &ConfigValidationError{ConfigPath: sm.configPath, Issues: issues}
+	}
+
+	var newConfig ServiceConfig
+	if err := LoadServiceConfig(sm.configPath, &newConfig); err != nil {
+		return err
+	}
+
+	if data, err := os.ReadFile(sm.configPath); err == nil {
+		history := NewConfigHistory(sm.configPath, newConfig.ConfigHistoryLimit)
+		if _, recordErr := history.Record(data, OriginReload); recordErr != nil {
+			log.Printf("Failed to record config history: %v", recordErr)
+		}
+	}
+
+	sm.StopAll()
+
+	sm.mutex.Lock()
+	if newConfig.WebPort == 0 {
+		newConfig.WebPort = sm.config.WebPort
+	}
+	previous := *sm.config
+	if changed := restartSettings(&previous, &newConfig); len(changed) > 0 {
+		Warnf("Changed settings take effect after a restart: %s", strings.Join(changed, ", "))
+	}
+	*sm.config = newConfig
+	sm.forwarder.Close()
+	sm.forwarder = NewLogForwarder(sm.config)
+	sm.limiter.configure(sm.config)
+	started := sm.ctx != nil
+	hooks := sm.reloadHooks
+	sm.mutex.Unlock()
+
+	for _, hook := range hooks {
+		hook(&previous, &newConfig)
+	}
+
+	// Only restart scheduling if the manager was running scripts before
+	if !started {
+		return nil
+	}
+	return sm.StartAllEnabled(ctx)
+}
+
+// OnReload calls hook after ReloadConfig applies a new configuration, so settings held
+// outside the manager, such as the web server's, follow reloads
+func (sm *ScriptManager) OnReload(hook ReloadHook) {
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+	sm.reloadHooks = append(sm.reloadHooks, hook)
+}
+
+// restartSettings returns the settings that differ between two configurations but are only
+// read at startup: listening, directories, TLS and the manual run queue
+func restartSettings(previous, current *ServiceConfig) []string {
+	var changed []string
+	if previous.WebPort != current.WebPort {
+		changed = append(changed, "web_port")
+	}
+	if previous.BindAddress != current.BindAddress {
+		changed = append(changed, "bind_address")
+	}
+	if previous.LogDir != current.LogDir {
+		changed = append(changed, "log_dir")
+	}
+	if previous.DataDir != current.DataDir {
+		changed = append(changed, "data_dir")
+	}
+	if !reflect.DeepEqual(previous.TLS, current.TLS) {
+		changed = append(changed, "tls")
+	}
+	if previous.RunWorkerCount() != current.RunWorkerCount() {
+		changed = append(changed, "run_workers")
+	}
+	if previous.RunQueueCapacity() != current.RunQueueCapacity() {
+		changed = append(changed, "run_queue_size")
+	}
+	return changed
+}
+
+// ExportBundle bundles the named scripts (all when names is empty) with their script files
+func (sm *ScriptManager) ExportBundle(names []string) (*Bundle, error) {
+	sm.mutex.RLock()
+	defer sm.mutex.RUnlock()
+	return ExportBundle(sm.config, "", names)
+}
+
+// ImportBundle merges a bundle into the configuration and saves it unless opts.DryRun is set.
+// Imported scripts are not started; overwritten running scripts keep their old settings until restarted.
+func (sm *ScriptManager) ImportBundle(bundle *Bundle, opts ImportOptions) (*ImportResult, error) {
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+
+	result, err := ImportBundle(sm.config, bundle, opts)
+	if err != nil || opts.DryRun || !result.Changed() || sm.configPath == "" {
+		return result, err
+	}
+	if err := SaveServiceConfigWithOrigin(sm.configPath, sm.config, OriginAPI); err != nil {
+		return nil, fmt.Errorf("failed to save config: %v", err)
+	}
+	return result, nil
 }
 
 // AddScript adds a new script configuration
@@ -152,7 +436,7 @@ func (sm *ScriptManager) AddScript(scriptConfig ScriptConfig) error {
 	// Check if script with same name already exists
 	for _, existing := range sm.config.Scripts {
 		if existing.Name == scriptConfig.Name {
-			return fmt.Errorf("script with name %s already exists", scriptConfig.Name)
+			return fmt.Errorf("script with name %s %w", scriptConfig.Name, ErrScriptExists)
 		}
 	}
 
@@ -163,27 +447,98 @@ func (sm *ScriptManager) AddScript(scriptConfig ScriptConfig) error {
 
 // RunScriptOnce executes a script once by name
 func (sm *ScriptManager) RunScriptOnce(ctx context.Context, name string) error {
+	return sm.runScript(ctx, name, NewRunID())
+}
+
+// EnqueueRun queues a manual run of a script at its priority and returns its status,
+// including the run ID the run will be recorded under
+func (sm *ScriptManager) EnqueueRun(name string) (RunStatus, error) {
 	sm.mutex.RLock()
-	defer sm.mutex.RUnlock()
+	index := indexOfScript(sm.config.Scripts, name)
+	priority := 0
+	if index >= 0 {
+		priority = sm.config.Scripts[index].Priority
+	}
+	sm.mutex.RUnlock()
+	if index < 0 {
+		return RunStatus{}, fmt.Errorf("script %s %w", name, ErrScriptNotFound)
+	}
+	return sm.queue.Enqueue(name, priority)
+}
 
-	// Find the script config
-	var scriptConfig *ScriptConfig
-	for i, sc := range sm.config.Scripts {
-		if sc.Name == name {
-			scriptConfig = &sm.config.Scripts[i]
+// GetRunQueue returns the queue of manual runs
+func (sm *ScriptManager) GetRunQueue() *RunQueue {
+	return sm.queue
+}
+
+// GetRun returns the status of a manual run, which is waiting while it has a worker but
+// no slot under max_concurrent_runs or its resource pools
+func (sm *ScriptManager) GetRun(id string) (RunStatus, bool) {
+	status, ok := sm.queue.Get(id)
+	if !ok {
+		return status, false
+	}
+	return withSlotWait(status, sm.limiter.waitingRuns()), true
+}
+
+// WaitRun blocks until manual run id finished or ctx is done, and returns its status then
+func (sm *ScriptManager) WaitRun(ctx context.Context, id string) (RunStatus, bool) {
+	status, ok := sm.queue.Wait(ctx, id)
+	if !ok {
+		return status, false
+	}
+	return withSlotWait(status, sm.limiter.waitingRuns()), true
+}
+
+// QueueStatus returns the manual run queue together with the run limits, the resource pools
+// and the runs of all scripts waiting for a slot
+func (sm *ScriptManager) QueueStatus() QueueStatus {
+	status := sm.queue.Status()
+	waiting := sm.limiter.waitingRuns()
+	for i := range status.Running {
+		status.Running[i] = withSlotWait(status.Running[i], waiting)
+	}
+	status.MaxConcurrentRuns, status.Active, status.Pools = sm.limiter.usage()
+	status.Waiting = waiting
+	return status
+}
+
+// withSlotWait marks a running manual run found among the waiting runs as waiting, it has
+// a worker but has not started
+func withSlotWait(status RunStatus, waiting []WaitingRun) RunStatus {
+	if status.State != RunRunning {
+		return status
+	}
+	for _, run := range waiting {
+		if run.RunID == status.ID {
+			status.State, status.Position, status.StartedAt = RunWaiting, run.Position, nil
 			break
 		}
 	}
+	return status
+}
 
-	if scriptConfig == nil {
-		return fmt.Errorf("script %s not found in configuration", name)
+// runScript executes a script once by name, recorded under runID. The configuration is
+// not locked during the run, so long runs do not hold up changes to it.
+func (sm *ScriptManager) runScript(ctx context.Context, name, runID string) error {
+	runner, err := sm.oneTimeRunner(name)
+	if err != nil {
+		return err
 	}
+	return runner.RunOnceWithID(ctx, runID)
+}
 
-	// Create a temporary script runner for one-time execution
-	logPath := fmt.Sprintf("%s.log", name)
-	runner := NewScriptRunner(*scriptConfig, logPath)
+// oneTimeRunner creates a temporary script runner for one-time execution of a script
+func (sm *ScriptManager) oneTimeRunner(name string) (*ScriptRunner, error) {
+	sm.mutex.RLock()
+	defer sm.mutex.RUnlock()
 
-	return runner.RunOnce(ctx)
+	for i, sc := range sm.config.Scripts {
+		if sc.Name == name {
+			return sm.newRunner(sm.config.Scripts[i]), nil
+		}
+	}
+	return nil, fmt.Errorf("script %s %w", name, ErrScriptNotFound)
 }
 
 // EnableScript enables a script by name
@@ -199,7 +554,7 @@ func (sm *ScriptManager) EnableScript(name string) error {
 		}
 	}
 
-	return fmt.Errorf("script %s not found in configuration", name)
+	return fmt.Errorf("script %s %w", name, ErrScriptNotFound)
 }
 
 // DisableScript disables a script by name
@@ -215,7 +570,49 @@ func (sm *ScriptManager) DisableScript(name string) error {
 		}
 	}
 
-	return fmt.Errorf("script %s not found in configuration", name)
+	return fmt.Errorf("script %s %w", name, ErrScriptNotFound)
+}
+
+// ResolveScriptName picks the script a single-script command acts on.
+// An explicit name must exist; otherwise the only script, or the default script, is chosen.
+func (sm *ScriptManager) ResolveScriptName(name string) (string, error) {
+	sm.mutex.RLock()
+	defer sm.mutex.RUnlock()
+
+	if name != "" {
+		if indexOfScript(sm.config.Scripts, name) < 0 {
+			return "", fmt.Errorf("script %s %w", name, ErrScriptNotFound)
+		}
+		return name, nil
+	}
+
+	switch {
+	case len(sm.config.Scripts) == 0:
+		return "", fmt.Errorf("no scripts configured")
+	case len(sm.config.Scripts) == 1:
+		return sm.config.Scripts[0].Name, nil
+	case indexOfScript(sm.config.Scripts, DefaultScriptName) >= 0:
+		return DefaultScriptName, nil
+	default:
+		return "", fmt.Errorf("multiple scripts configured, choose one with --script=<name>")
+	}
+}
+
+// SetScriptInterval changes how often a script runs
+func (sm *ScriptManager) SetScriptInterval(name string, interval int) error {
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+
+	if interval < 0 {
+		return fmt.Errorf("interval cannot be negative")
+	}
+
+	i := indexOfScript(sm.config.Scripts, name)
+	if i < 0 {
+		return fmt.Errorf("script %s %w", name, ErrScriptNotFound)
+	}
+	sm.config.Scripts[i].Interval = interval
+	return nil
 }
 
 // UpdateScript updates an existing script configuration
@@ -233,7 +630,7 @@ func (sm *ScriptManager) UpdateScript(name string, updatedConfig ScriptConfig) e
 		}
 	}
 
-	return fmt.Errorf("script %s not found in configuration", name)
+	return fmt.Errorf("script %s %w", name, ErrScriptNotFound)
 }
 
 // RemoveScript removes a script from configuration and stops it if running
@@ -259,7 +656,7 @@ func (sm *ScriptManager) RemoveScript(name string) error {
 	}
 
 	if !found {
-		return fmt.Errorf("script %s not found in configuration", name)
+		return fmt.Errorf("script %s %w", name, ErrScriptNotFound)
 	}
 
 	sm.config.Scripts = newScripts
@@ -2,6 +2,9 @@ package service
 
 import (
 	"context"
+	"os"
+	"path/filepath"
+	"reflect"
 	"testing"
 )
 
@@ -414,3 +417,183 @@ func TestScriptManager_RemoveScript_StopsRunningScript(t *testing.T) {
 		t.Errorf("Expected 0 scripts in config after removal, got %d", len(manager.config.Scripts))
 	}
 }
+
+func TestScriptManager_ReloadConfig(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.json")
+
+	config := &ServiceConfig{
+		Scripts: []ScriptConfig{{Name: testScriptName, Path: "./test1.sh", Interval: 60, MaxLogLines: 100}},
+		WebPort: 8080,
+	}
+	manager := NewScriptManagerWithPath(config, configPath)
+	if err := manager.SaveConfig(); err != nil {
+		t.Fatalf("Failed to save config: %v", err)
+	}
+
+	// Edit the file behind the manager's back
+	updated := `{"scripts": [{"name": "test1", "path": "./test1.sh", "interval": 300, "max_log_lines": 100}], "web_port": 8080}`
+	if err := os.WriteFile(configPath, []byte(updated), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := manager.ReloadConfig(context.Background()); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if manager.GetConfig().Scripts[0].Interval != 300 {
+		t.Errorf("Expected reloaded interval 300, got %d", manager.GetConfig().Scripts[0].Interval)
+	}
+
+	history, err := manager.GetConfigHistory()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	versions, _ := history.List()
+	if len(versions) != 2 || versions[1].Origin != OriginReload {
+		t.Errorf("Expected reload to be recorded in history, got %+v", versions)
+	}
+
+	// An invalid file is rejected and the current config kept
+	if err := os.WriteFile(configPath, []byte(`{"scripts": [{"name": ""}]}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := manager.ReloadConfig(context.Background()); err == nil {
+		t.Error("Expected error reloading invalid config")
+	}
+	if manager.GetConfig().Scripts[0].Interval != 300 {
+		t.Error("Expected current config to be kept after failed reload")
+	}
+}
+
+func TestScriptManager_ReloadConfig_Hooks(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	manager := NewScriptManagerWithPath(&ServiceConfig{WebPort: 8080}, configPath)
+	if err := manager.SaveConfig(); err != nil {
+		t.Fatal(err)
+	}
+
+	var previous, current *ServiceConfig
+	manager.OnReload(func(p, c *ServiceConfig) { previous, current = p, c })
+
+	updated := `{"scripts": [], "web_port": 8080, "rate_limit": {"requests_per_minute": 30}}`
+	if err := os.WriteFile(configPath, []byte(updated), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := manager.ReloadConfig(context.Background()); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if previous == nil || previous.RateLimit != nil {
+		t.Errorf("Expected the hook to get the replaced configuration, got %+v", previous)
+	}
+	if current == nil || current.RateLimit == nil || current.RateLimit.RequestsPerMinute != 30 {
+		t.Errorf("Expected the hook to get the reloaded configuration, got %+v", current)
+	}
+}
+
+func TestRestartSettings(t *testing.T) {
+	previous := &ServiceConfig{WebPort: 8080, RunWorkers: 4, RateLimit: &RateLimitConfig{RequestsPerMinute: 10}}
+	current := &ServiceConfig{
+		WebPort:   8081,
+		TLS:       &TLSConfig{SelfSigned: true},
+		RateLimit: &RateLimitConfig{RequestsPerMinute: 20},
+	}
+	// run_workers 4 is the default, rate limits apply without a restart
+	if changed := restartSettings(previous, current); !reflect.DeepEqual(changed, []string{"web_port", "tls"}) {
+		t.Errorf("Expected web_port and tls to need a restart, got %v", changed)
+	}
+	if changed := restartSettings(previous, previous); len(changed) != 0 {
+		t.Errorf("Expected no changes, got %v", changed)
+	}
+}
+
+func TestScriptManager_ResolveScriptName(t *testing.T) {
+	single := NewScriptManager(&ServiceConfig{Scripts: []ScriptConfig{{Name: "only", Path: "./only.sh"}}})
+	if name, err := single.ResolveScriptName(""); err != nil || name != "only" {
+		t.Errorf("Expected the only script, got %q (%v)", name, err)
+	}
+
+	multi := NewScriptManager(&ServiceConfig{Scripts: []ScriptConfig{
+		{Name: "backup", Path: "./backup.sh"},
+		{Name: DefaultScriptName, Path: DefaultScriptPath},
+	}})
+	if name, err := multi.ResolveScriptName(""); err != nil || name != DefaultScriptName {
+		t.Errorf("Expected the default script, got %q (%v)", name, err)
+	}
+	if name, err := multi.ResolveScriptName("backup"); err != nil || name != "backup" {
+		t.Errorf("Expected the named script, got %q (%v)", name, err)
+	}
+	if _, err := multi.ResolveScriptName("missing"); err == nil {
+		t.Error("Expected error for unknown script")
+	}
+
+	ambiguous := NewScriptManager(&ServiceConfig{Scripts: []ScriptConfig{
+		{Name: "a", Path: "./a.sh"},
+		{Name: "b", Path: "./b.sh"},
+	}})
+	if _, err := ambiguous.ResolveScriptName(""); err == nil {
+		t.Error("Expected error when no script can be chosen")
+	}
+	if _, err := NewScriptManager(&ServiceConfig{}).ResolveScriptName(""); err == nil {
+		t.Error("Expected error without scripts")
+	}
+}
+
+func TestScriptManager_SetScriptInterval(t *testing.T) {
+	manager := NewScriptManager(&ServiceConfig{Scripts: []ScriptConfig{{Name: "a", Path: "./a.sh", Interval: 60}}})
+
+	if err := manager.SetScriptInterval("a", 120); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if manager.GetConfig().Scripts[0].Interval != 120 {
+		t.Errorf("Expected interval 120, got %d", manager.GetConfig().Scripts[0].Interval)
+	}
+	if err := manager.SetScriptInterval("missing", 120); err == nil {
+		t.Error("Expected error for unknown script")
+	}
+	if err := manager.SetScriptInterval("a", -1); err == nil {
+		t.Error("Expected error for negative interval")
+	}
+}
+
+func TestScriptManager_RunScriptOnce_RecordsLogsAndEvents(t *testing.T) {
+	tmpDir := t.TempDir()
+	scriptPath := filepath.Join(tmpDir, "hello.sh")
+	if err := os.WriteFile(scriptPath, []byte("#!/bin/bash\necho hello\n"), 0755); err != nil {
+		t.Fatalf("Failed to write script: %v", err)
+	}
+
+	logDir := filepath.Join(tmpDir, "logs")
+	manager := NewScriptManager(&ServiceConfig{
+		Scripts: []ScriptConfig{{Name: "hello", Path: scriptPath, Interval: 60, Enabled: true, MaxLogLines: 100}},
+	})
+	manager.SetLogDir(logDir)
+
+	events := make(chan *ScriptStatusEvent, 10)
+	unsubscribe := manager.GetEventBroadcaster().Subscribe(events)
+	defer unsubscribe()
+
+	if err := manager.RunScriptOnce(context.Background(), "hello"); err != nil {
+		t.Fatalf("Expected no error running script, got: %v", err)
+	}
+
+	if manager.GetLogManager().GetBaseDir() != logDir {
+		t.Errorf("Expected log dir %s, got %s", logDir, manager.GetLogManager().GetBaseDir())
+	}
+	entries := manager.GetLogManager().GetLogger("hello").GetEntries()
+	if len(entries) != 1 || entries[0].Stdout != "hello" {
+		t.Fatalf("Expected one log entry with stdout 'hello', got %+v", entries)
+	}
+
+	// A fresh logger reads the JSON entry back from the file in the log dir
+	reloaded := NewScriptLogger("hello", logDir, 100).GetEntries()
+	if len(reloaded) != 1 || reloaded[0].ExitCode != 0 {
+		t.Errorf("Expected one entry reloaded from %s, got %+v", logDir, reloaded)
+	}
+
+	var statuses []string
+	for len(events) > 0 {
+		statuses = append(statuses, (<-events).Status)
+	}
+	if len(statuses) != 3 || statuses[0] != "starting" || statuses[1] != StatusOutput || statuses[2] != "completed" {
+		t.Errorf("Expected [starting output completed] events, got %v", statuses)
+	}
+}
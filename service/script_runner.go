@@ -3,7 +3,9 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"path/filepath"
 	"sync"
 	"time"
 )
@@ -27,6 +29,11 @@ func NewScriptExecutorWithoutLogging(scriptPath string) *ScriptExecutor {
 	}
 }
 
+// SetOutputHandler sets a handler called with every output line of a run
+func (se *ScriptExecutor) SetOutputHandler(handler OutputHandler) {
+	se.executor.SetOutputHandler(handler)
+}
+
 // Execute executes the script with context support and optional arguments
 func (se *ScriptExecutor) Execute(ctx context.Context, args ...string) error {
 	result, err := se.ExecuteWithResult(ctx, args...)
@@ -39,7 +46,12 @@ func (se *ScriptExecutor) Execute(ctx context.Context, args ...string) error {
 	return nil
 }
 
-// ExecuteWithResult executes the script and returns detailed execution result
+// killedOutputWait is how long a cancelled run may take to hand over the output it wrote
+const killedOutputWait = 2 * time.Second
+
+// ExecuteWithResult executes the script and returns detailed execution result. When ctx ends
+// first the script is killed and ctx's error is returned with the result of the killed run,
+// or a nil result when the script was not started or did not end in time.
 func (se *ScriptExecutor) ExecuteWithResult(ctx context.Context, args ...string) (*ExecutionResult, error) {
 	// Check if context is already canceled
 	select {
@@ -60,7 +72,12 @@ func (se *ScriptExecutor) ExecuteWithResult(ctx context.Context, args ...string)
 	// Wait for either completion or cancellation
 	select {
 	case <-ctx.Done():
-		return nil, ctx.Err()
+		select {
+		case result := <-resultChan:
+			return result, ctx.Err()
+		case <-time.After(killedOutputWait):
+			return nil, ctx.Err()
+		}
 	case result := <-resultChan:
 		return result, nil
 	}
@@ -74,6 +91,13 @@ type ScriptRunner struct {
 	executor         *ScriptExecutor
 	logManager       *LogManager
 	eventBroadcaster *EventBroadcaster
+	forwarder        *LogForwarder // log sinks runs are sent to after they are recorded
+	metrics          *RunMetrics   // run counters and result metrics
+	artifacts        *ArtifactStore
+	artifactPolicy   ArtifactPolicy
+	gate             *runGate      // admits runs while the service is not shutting down
+	limiter          *runLimiter   // max_concurrent_runs and resource pools shared by all scripts
+	halt             chan struct{} // closed by Halt to end scheduling after the current run
 	running          bool
 	mutex            sync.RWMutex
 }
@@ -102,13 +126,124 @@ func NewScriptRunnerWithLogManager(config ScriptConfig, logManager *LogManager)
 
 // NewScriptRunnerWithEventBroadcaster creates a new script runner with event broadcasting
 func NewScriptRunnerWithEventBroadcaster(config ScriptConfig, logPath string, broadcaster *EventBroadcaster) *ScriptRunner {
-	return &ScriptRunner{
+	runner := &ScriptRunner{
 		config:           config,
 		executor:         NewScriptExecutor(config.Path, logPath, config.MaxLogLines),
 		logManager:       nil,
 		eventBroadcaster: broadcaster,
 		running:          false,
 	}
+	runner.broadcastOutput()
+	return runner
+}
+
+// NewManagedScriptRunner creates a script runner that records runs in a LogManager and broadcasts status events
+func NewManagedScriptRunner(config ScriptConfig, logManager *LogManager, broadcaster *EventBroadcaster) *ScriptRunner {
+	runner := &ScriptRunner{
+		config:           config,
+		executor:         NewScriptExecutorWithoutLogging(config.Path), // No file logging since we use LogManager
+		logManager:       logManager,
+		eventBroadcaster: broadcaster,
+		running:          false,
+	}
+	runner.broadcastOutput()
+	return runner
+}
+
+// SetLogForwarder sends every recorded run to the log sinks of the forwarder
+func (sr *ScriptRunner) SetLogForwarder(forwarder *LogForwarder) {
+	sr.mutex.Lock()
+	defer sr.mutex.Unlock()
+	sr.forwarder = forwarder
+}
+
+// SetRunMetrics records every run in metrics
+func (sr *ScriptRunner) SetRunMetrics(metrics *RunMetrics) {
+	sr.mutex.Lock()
+	defer sr.mutex.Unlock()
+	sr.metrics = metrics
+}
+
+// SetArtifactStore keeps the files matching the script's artifact patterns after every run
+func (sr *ScriptRunner) SetArtifactStore(store *ArtifactStore, policy ArtifactPolicy) {
+	sr.mutex.Lock()
+	defer sr.mutex.Unlock()
+	sr.artifacts = store
+	sr.artifactPolicy = policy
+}
+
+// collectArtifacts copies the run's artifacts into the store and prunes old ones.
+// Problems are logged and never fail the run.
+func (sr *ScriptRunner) collectArtifacts(store *ArtifactStore, policy ArtifactPolicy, entry *LogEntry) {
+	workDir := filepath.Dir(sr.config.Path)
+	manifest, err := store.Collect(entry, workDir, sr.config.Artifacts, policy)
+	if err != nil {
+		Warnf("Artifacts of %s not collected: %v", sr.config.Name, err)
+		return
+	}
+	if manifest != nil {
+		entry.Artifacts = len(manifest.Files)
+		for _, skipped := range manifest.Skipped {
+			Warnf("Artifact %s of %s skipped: %s", skipped.Path, sr.config.Name, skipped.Reason)
+		}
+	}
+	if removed, err := store.Prune(sr.config.Name, policy, time.Now()); err != nil {
+		Warnf("Artifact retention of %s failed: %v", sr.config.Name, err)
+	} else if removed > 0 {
+		Debugf("Removed artifacts of %d old runs of %s", removed, sr.config.Name)
+	}
+}
+
+// evaluate parses the run's output into its result and decides the outcome.
+// It returns the result fields exported as metrics.
+func (sr *ScriptRunner) evaluate(entry *LogEntry) map[string]float64 {
+	resultOK := true
+	var metrics map[string]float64
+
+	if sr.config.Output != nil {
+		parser, err := NewOutputParser(*sr.config.Output)
+		if err != nil {
+			Warnf("Output parser of %s disabled: %v", sr.config.Name, err)
+		} else {
+			result, parseErr := parser.Parse(entry.Stdout)
+			if parseErr != nil {
+				Debugf("No result parsed from run of %s: %v", sr.config.Name, parseErr)
+			}
+			entry.Result = result
+			metrics = parser.Metrics(result)
+			resultOK = parser.Succeeded(result)
+		}
+	}
+
+	evaluator, err := NewOutcomeEvaluator(sr.config)
+	if err != nil {
+		Warnf("Success criteria of %s ignored: %v", sr.config.Name, err)
+		evaluator, _ = NewOutcomeEvaluator(ScriptConfig{})
+	}
+	entry.Outcome, entry.OutcomeReason = evaluator.Evaluate(entry, resultOK)
+	return metrics
+}
+
+// outcomeStatus returns the event status of a finished run
+func outcomeStatus(outcome string) string {
+	switch outcome {
+	case OutcomeSuccess:
+		return "completed"
+	case OutcomeWarning:
+		return StatusWarning
+	default:
+		return "failed"
+	}
+}
+
+// broadcastOutput publishes every output line of the script as an output event
+func (sr *ScriptRunner) broadcastOutput() {
+	if sr.eventBroadcaster == nil {
+		return
+	}
+	sr.executor.SetOutputHandler(func(stream, line string) {
+		sr.eventBroadcaster.Broadcast(NewScriptOutputEvent(sr.config.Name, stream, line))
+	})
 }
 
 // Start begins running the script at the configured interval
@@ -126,17 +261,21 @@ func (sr *ScriptRunner) Start(ctx context.Context) {
 
 	// Create ticker for interval execution
 	sr.ticker = time.NewTicker(time.Duration(sr.config.Interval) * time.Second)
+	sr.halt = make(chan struct{})
+	halt := sr.halt
 	sr.mutex.Unlock()
 
 	defer func() {
 		sr.mutex.Lock()
 		sr.running = false
 		sr.ticker.Stop()
+		sr.halt = nil
 		sr.mutex.Unlock()
+		cancel()
 	}()
 
 	// Run script immediately on start
-	if err := sr.RunOnce(runCtx); err != nil {
+	if err := sr.run(runCtx, TriggerSchedule, NewRunID()); err != nil {
 		// Log error but continue running - this is expected behavior
 		_ = err
 	}
@@ -146,8 +285,10 @@ func (sr *ScriptRunner) Start(ctx context.Context) {
 		select {
 		case <-runCtx.Done():
 			return
+		case <-halt:
+			return
 		case <-sr.ticker.C:
-			if err := sr.RunOnce(runCtx); err != nil {
+			if err := sr.run(runCtx, TriggerSchedule, NewRunID()); err != nil {
 				// Log error but continue running - this is expected behavior
 				_ = err
 			}
@@ -165,8 +306,49 @@ func (sr *ScriptRunner) Stop() {
 	}
 }
 
-// RunOnce executes the script once with optional arguments
+// Halt ends scheduling without interrupting a run in flight, which still completes
+func (sr *ScriptRunner) Halt() {
+	sr.mutex.Lock()
+	defer sr.mutex.Unlock()
+
+	if sr.halt != nil {
+		close(sr.halt)
+		sr.halt = nil
+	}
+}
+
+// RunOnce executes the script once with optional arguments, recorded as a manual run
 func (sr *ScriptRunner) RunOnce(ctx context.Context, args ...string) error {
+	return sr.run(ctx, TriggerManual, NewRunID(), args...)
+}
+
+// RunOnceWithID is RunOnce recording the run under runID, e.g. one handed out when it was queued
+func (sr *ScriptRunner) RunOnceWithID(ctx context.Context, runID string, args ...string) error {
+	return sr.run(ctx, TriggerManual, runID, args...)
+}
+
+// run executes the script once, recording what triggered the run under runID
+func (sr *ScriptRunner) run(ctx context.Context, trigger, runID string, args ...string) error {
+	if sr.limiter != nil {
+		waiting := WaitingRun{RunID: runID, Script: sr.config.Name, Trigger: trigger, Priority: sr.config.Priority, Pools: sr.config.Pools}
+		release, err := sr.limiter.acquire(ctx, waiting, func() {
+			if sr.eventBroadcaster != nil {
+				sr.eventBroadcaster.Broadcast(NewScriptStatusEvent(sr.config.Name, RunWaiting, 0, 0))
+			}
+		})
+		if err != nil {
+			return err
+		}
+		defer release()
+	}
+	if sr.gate != nil {
+		gateCtx, leave, err := sr.gate.enter(ctx, sr.config.Name)
+		if err != nil {
+			return err
+		}
+		defer leave()
+		ctx = gateCtx
+	}
 	startTime := time.Now()
 
 	// Broadcast starting event
@@ -176,35 +358,44 @@ func (sr *ScriptRunner) RunOnce(ctx context.Context, args ...string) error {
 	}
 
 	// Create timeout context if timeout is specified
+	runCtx := ctx
 	if sr.config.Timeout > 0 {
 		timeoutCtx, cancel := context.WithTimeout(ctx, time.Duration(sr.config.Timeout)*time.Second)
 		defer cancel()
-		ctx = timeoutCtx
+		runCtx = timeoutCtx
 	}
 
 	// Execute the script
-	result, err := sr.executor.ExecuteWithResult(ctx, args...)
+	result, err := sr.executor.ExecuteWithResult(runCtx, args...)
 	duration := time.Since(startTime).Milliseconds()
+	if err != nil && result == nil {
+		result = &ExecutionResult{Timestamp: startTime}
+	}
+
+	logEntry := &LogEntry{
+		Timestamp:  result.Timestamp,
+		ScriptName: sr.config.Name,
+		ExitCode:   result.ExitCode,
+		Stdout:     result.Stdout,
+		Stderr:     result.Stderr,
+		Duration:   duration,
+		Trigger:    trigger,
+		RunID:      runID,
+	}
+	if err != nil {
+		// Killed runs are recorded like any other, with the output they wrote
+		logEntry.ExitCode = -1
+		logEntry.Interrupted = sr.interruption(ctx, err)
+	}
+	resultMetrics := sr.evaluate(logEntry)
 
 	// If LogManager is available, use it for structured logging
 	if sr.logManager != nil {
-		if err != nil {
-			// Broadcast failed event if there was an execution error
-			if sr.eventBroadcaster != nil {
-				failedEvent := NewScriptStatusEvent(sr.config.Name, "failed", -1, duration)
-				sr.eventBroadcaster.Broadcast(failedEvent)
-			}
-			return err
-		}
-
-		// Create log entry
-		logEntry := &LogEntry{
-			Timestamp:  result.Timestamp,
-			ScriptName: sr.config.Name,
-			ExitCode:   result.ExitCode,
-			Stdout:     result.Stdout,
-			Stderr:     result.Stderr,
-			Duration:   duration,
+		sr.mutex.RLock()
+		forwarder, metrics, artifacts, artifactPolicy := sr.forwarder, sr.metrics, sr.artifacts, sr.artifactPolicy
+		sr.mutex.RUnlock()
+		if artifacts != nil && len(sr.config.Artifacts) > 0 {
+			sr.collectArtifacts(artifacts, artifactPolicy, logEntry)
 		}
 
 		// Add to log manager
@@ -214,49 +405,39 @@ func (sr *ScriptRunner) RunOnce(ctx context.Context, args ...string) error {
 			fmt.Printf("Failed to add log entry: %v\n", addErr)
 		}
 
-		// Broadcast completion or failure event
-		if sr.eventBroadcaster != nil {
-			if result.ExitCode == 0 {
-				completedEvent := NewScriptStatusEvent(sr.config.Name, "completed", result.ExitCode, duration)
-				sr.eventBroadcaster.Broadcast(completedEvent)
-			} else {
-				failedEvent := NewScriptStatusEvent(sr.config.Name, "failed", result.ExitCode, duration)
-				sr.eventBroadcaster.Broadcast(failedEvent)
-			}
+		if forwarder != nil {
+			forwarder.Forward(logEntry)
 		}
-
-		if result.ExitCode != 0 {
-			return fmt.Errorf("script exited with code %d", result.ExitCode)
+		if metrics != nil {
+			metrics.Record(logEntry, resultMetrics)
 		}
-		return nil
 	}
 
-	// Handle case with event broadcaster but no log manager
+	// Broadcast completion, warning or failure event
+	if sr.eventBroadcaster != nil {
+		status := outcomeStatus(logEntry.Outcome)
+		sr.eventBroadcaster.Broadcast(NewScriptStatusEvent(sr.config.Name, status, logEntry.ExitCode, duration))
+	}
+
 	if err != nil {
-		// Broadcast failed event if there was an execution error
-		if sr.eventBroadcaster != nil {
-			failedEvent := NewScriptStatusEvent(sr.config.Name, "failed", -1, duration)
-			sr.eventBroadcaster.Broadcast(failedEvent)
-		}
 		return err
 	}
-
-	// Broadcast completion or failure event
-	if sr.eventBroadcaster != nil {
-		if result.ExitCode == 0 {
-			completedEvent := NewScriptStatusEvent(sr.config.Name, "completed", result.ExitCode, duration)
-			sr.eventBroadcaster.Broadcast(completedEvent)
-		} else {
-			failedEvent := NewScriptStatusEvent(sr.config.Name, "failed", result.ExitCode, duration)
-			sr.eventBroadcaster.Broadcast(failedEvent)
-		}
+	if logEntry.Failed() {
+		return fmt.Errorf("script %s", logEntry.OutcomeReason)
 	}
+	return nil
+}
 
-	// Fallback to old executor method behavior
-	if result.ExitCode != 0 {
-		return fmt.Errorf("script exited with code %d", result.ExitCode)
+// interruption explains why a run was stopped before its script exited; ctx is the run's
+// context without the script's timeout
+func (sr *ScriptRunner) interruption(ctx context.Context, err error) string {
+	switch {
+	case sr.gate != nil && sr.gate.kill.Err() != nil:
+		return "killed when the service shut down"
+	case ctx.Err() == nil && errors.Is(err, context.DeadlineExceeded):
+		return fmt.Sprintf("timed out after %ds", sr.config.Timeout)
 	}
-	return nil
+	return "interrupted: " + err.Error()
 }
 
 // IsRunning returns whether the script runner is currently running
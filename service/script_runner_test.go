@@ -2,7 +2,9 @@ package service
 
 import (
 	"context"
+	"errors"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 )
@@ -158,6 +160,45 @@ func TestScriptRunner_IsRunning(t *testing.T) {
 	}
 }
 
+func TestScriptRunner_TimeoutRecordedAsFailure(t *testing.T) {
+	logManager := NewLogManager(filepath.Join(t.TempDir(), "logs"))
+	runner := NewScriptRunnerWithLogManager(ScriptConfig{Name: "slow", Path: "sleep", Interval: 60, MaxLogLines: 10, Timeout: 1}, logManager)
+	metrics := NewRunMetrics()
+	runner.SetRunMetrics(metrics)
+	broadcaster := NewEventBroadcaster()
+	events := make(chan *ScriptStatusEvent, 10)
+	defer broadcaster.Subscribe(events)()
+	runner.eventBroadcaster = broadcaster
+
+	start := time.Now()
+	if err := runner.RunOnce(context.Background(), "10"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the run to time out, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected the run killed at its timeout, took %s", elapsed)
+	}
+
+	entries := logManager.GetLogger("slow").GetEntries()
+	if len(entries) != 1 || entries[0].Outcome != OutcomeFailure || entries[0].OutcomeReason != "timed out after 1s" {
+		t.Fatalf("Expected a failed run timed out after 1s, got %+v", entries)
+	}
+	var buf strings.Builder
+	if err := metrics.WritePrometheus(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `run_script_runs_total{script="slow",outcome="failure"} 1`) {
+		t.Errorf("Expected the timeout counted as a failure, got:\n%s", buf.String())
+	}
+
+	var statuses []string
+	for len(events) > 0 {
+		statuses = append(statuses, (<-events).Status)
+	}
+	if len(statuses) != 2 || statuses[1] != "failed" {
+		t.Errorf("Expected starting and failed events, got %v", statuses)
+	}
+}
+
 func TestScriptRunner_WithLogManager(t *testing.T) {
 	tempDir := t.TempDir()
 	logsDir := filepath.Join(tempDir, "logs")
@@ -203,6 +244,29 @@ func TestScriptRunner_WithLogManager(t *testing.T) {
 		if entry.Stdout != "test output" {
 			t.Errorf("Expected stdout 'test output', got '%s'", entry.Stdout)
 		}
+		if entry.Trigger != TriggerManual {
+			t.Errorf("Expected trigger '%s', got '%s'", TriggerManual, entry.Trigger)
+		}
+	}
+}
+
+func TestScriptRunner_ScheduledRunTrigger(t *testing.T) {
+	logManager := NewLogManager(t.TempDir())
+	runner := NewScriptRunnerWithLogManager(ScriptConfig{Name: "tick", Path: "true", Interval: 60}, logManager)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go runner.Start(ctx)
+	defer cancel()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for len(logManager.GetLogger("tick").GetEntries()) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the scheduled run to be logged")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if trigger := logManager.GetLogger("tick").GetEntries()[0].Trigger; trigger != TriggerSchedule {
+		t.Errorf("Expected trigger '%s', got '%s'", TriggerSchedule, trigger)
 	}
 }
 
@@ -234,11 +298,11 @@ func TestScriptRunner_WithEventBroadcaster(t *testing.T) {
 		t.Errorf("Expected no error running script once, got: %v", err)
 	}
 
-	// Should receive two events: starting and completed
-	receivedEvents := make([]*ScriptStatusEvent, 0, 2)
+	// Should receive three events: starting, the output line and completed
+	receivedEvents := make([]*ScriptStatusEvent, 0, 3)
 
 	// Collect events with timeout
-	for i := 0; i < 2; i++ {
+	for i := 0; i < 3; i++ {
 		select {
 		case event := <-events:
 			receivedEvents = append(receivedEvents, event)
@@ -247,8 +311,8 @@ func TestScriptRunner_WithEventBroadcaster(t *testing.T) {
 		}
 	}
 
-	if len(receivedEvents) != 2 {
-		t.Errorf("Expected 2 events, got %d", len(receivedEvents))
+	if len(receivedEvents) != 3 {
+		t.Errorf("Expected 3 events, got %d", len(receivedEvents))
 	}
 
 	// First event should be "starting"
@@ -260,8 +324,14 @@ func TestScriptRunner_WithEventBroadcaster(t *testing.T) {
 		t.Errorf("Expected status 'starting', got %s", startEvent.Status)
 	}
 
-	// Second event should be "completed"
-	completeEvent := receivedEvents[1]
+	// Second event carries the output line
+	outputEvent := receivedEvents[1]
+	if outputEvent.Status != StatusOutput || outputEvent.Stream != StreamStdout || outputEvent.Line != "test output" {
+		t.Errorf("Expected stdout line 'test output', got %+v", outputEvent)
+	}
+
+	// Third event should be "completed"
+	completeEvent := receivedEvents[2]
 	if completeEvent.ScriptName != "test1" {
 		t.Errorf("Expected script name 'test1', got %s", completeEvent.ScriptName)
 	}
@@ -0,0 +1,322 @@
+// Package service provides core functionality for the run-script-service daemon.
+package service
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// SettingSource identifies which layer provided a setting's value
+type SettingSource string
+
+// Setting layers, from lowest to highest precedence
+const (
+	SourceDefault SettingSource = "default"
+	SourceConfig  SettingSource = "config"
+	SourceEnv     SettingSource = "env"
+	SourceFlag    SettingSource = "flag"
+)
+
+// Setting keys shared by sources, environment variables and flags
+const (
+	SettingConfig   = "config"
+	SettingLogDir   = "log_dir"
+	SettingDataDir  = "data_dir"
+	SettingBind     = "bind_address"
+	SettingPort     = "port"
+	SettingLogLevel = "log_level"
+)
+
+// DefaultWebPort is the port the web server listens on when nothing else is configured
+const DefaultWebPort = 8080
+
+// settingDefinition describes how a setting is named in each layer
+type settingDefinition struct {
+	key  string
+	env  string
+	flag string
+}
+
+// settingDefinitions lists all layered settings in display order
+var settingDefinitions = []settingDefinition{
+	{key: SettingConfig, env: "RSS_CONFIG", flag: "config"},
+	{key: SettingLogDir, env: "RSS_LOG_DIR", flag: "log-dir"},
+	{key: SettingDataDir, env: "RSS_DATA_DIR", flag: "data-dir"},
+	{key: SettingBind, env: "RSS_BIND_ADDRESS", flag: "bind"},
+	{key: SettingPort, env: "RSS_PORT", flag: "port"},
+	{key: SettingLogLevel, env: "RSS_LOG_LEVEL", flag: "log-level"},
+}
+
+// Settings holds the effective service settings after applying every layer:
+// built-in defaults, config file, RSS_* environment variables and command-line flags
+type Settings struct {
+	ConfigPath  string
+	LogDir      string
+	DataDir     string
+	BindAddress string
+	Port        int
+	LogLevel    string
+	TLS         *TLSConfig // only set in the config file, nil serves plain HTTP
+	Sources     map[string]SettingSource
+	Flags       []string // global flags given on the command line, for passing on to child processes
+}
+
+// SettingValue is a single effective setting with the layer it came from
+type SettingValue struct {
+	Key    string        `json:"key"`
+	Value  string        `json:"value"`
+	Source SettingSource `json:"source"`
+	Env    string        `json:"env"`
+	Flag   string        `json:"flag"`
+}
+
+// DefaultSettings returns the built-in defaults rooted at baseDir
+func DefaultSettings(baseDir string) *Settings {
+	settings := &Settings{
+		ConfigPath:  filepath.Join(baseDir, "service_config.json"),
+		LogDir:      filepath.Join(baseDir, "logs"),
+		DataDir:     baseDir,
+		BindAddress: "",
+		Port:        DefaultWebPort,
+		LogLevel:    "info",
+		Sources:     make(map[string]SettingSource),
+	}
+	for _, def := range settingDefinitions {
+		settings.Sources[def.key] = SourceDefault
+	}
+	return settings
+}
+
+// ResolveSettings computes the effective settings and returns args with global flags removed.
+// getenv is usually os.Getenv; it is a parameter so tests can supply their own environment.
+func ResolveSettings(baseDir string, args []string, getenv func(string) string) (*Settings, []string, error) {
+	settings := DefaultSettings(baseDir)
+
+	flags, remaining, err := extractGlobalFlags(args)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// The config path must be known before the config file layer can be applied
+	if value := getenv("RSS_CONFIG"); value != "" {
+		if err := settings.set(SettingConfig, value, SourceEnv); err != nil {
+			return nil, nil, err
+		}
+	}
+	if value, ok := flags["config"]; ok {
+		if err := settings.set(SettingConfig, value, SourceFlag); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if err := settings.applyConfigFile(); err != nil {
+		return nil, nil, err
+	}
+
+	for _, def := range settingDefinitions {
+		if def.key == SettingConfig {
+			continue
+		}
+		if value := getenv(def.env); value != "" {
+			if err := settings.set(def.key, value, SourceEnv); err != nil {
+				return nil, nil, fmt.Errorf("invalid %s: %v", def.env, err)
+			}
+		}
+		if value, ok := flags[def.flag]; ok {
+			if err := settings.set(def.key, value, SourceFlag); err != nil {
+				return nil, nil, fmt.Errorf("invalid --%s: %v", def.flag, err)
+			}
+		}
+	}
+
+	// Record resolved values so child processes started from another directory see the same paths
+	for _, def := range settingDefinitions {
+		if _, ok := flags[def.flag]; ok {
+			settings.Flags = append(settings.Flags, fmt.Sprintf("--%s=%s", def.flag, settings.valueOf(def.key)))
+		}
+	}
+
+	return settings, remaining, nil
+}
+
+// applyConfigFile applies settings stored in the config file
+func (s *Settings) applyConfigFile() error {
+	var config ServiceConfig
+	if err := LoadServiceConfig(s.ConfigPath, &config); err != nil {
+		return err
+	}
+
+	// Relative directories in the config file are relative to the file itself
+	configDir := filepath.Dir(s.ConfigPath)
+	resolve := func(path string) string {
+		if filepath.IsAbs(path) {
+			return path
+		}
+		return filepath.Join(configDir, path)
+	}
+
+	if config.LogDir != "" {
+		s.LogDir = resolve(config.LogDir)
+		s.Sources[SettingLogDir] = SourceConfig
+	}
+	if config.DataDir != "" {
+		s.DataDir = resolve(config.DataDir)
+		s.Sources[SettingDataDir] = SourceConfig
+	}
+	if config.BindAddress != "" {
+		s.BindAddress = config.BindAddress
+		s.Sources[SettingBind] = SourceConfig
+	}
+	if config.WebPort != 0 {
+		s.Port = config.WebPort
+		s.Sources[SettingPort] = SourceConfig
+	}
+	s.TLS = config.TLS
+	if config.LogLevel != "" {
+		// An invalid level is reported by validate-config; keep the default so the CLI stays usable
+		if _, err := ParseLogLevel(config.LogLevel); err != nil {
+			Warnf("ignoring log_level in %s: %v", s.ConfigPath, err)
+		} else {
+			s.LogLevel = strings.ToLower(config.LogLevel)
+			s.Sources[SettingLogLevel] = SourceConfig
+		}
+	}
+	return nil
+}
+
+// set assigns a setting from an environment variable or flag value
+func (s *Settings) set(key, value string, source SettingSource) error {
+	switch key {
+	case SettingConfig, SettingLogDir, SettingDataDir:
+		abs, err := filepath.Abs(value)
+		if err != nil {
+			return err
+		}
+		switch key {
+		case SettingConfig:
+			s.ConfigPath = abs
+		case SettingLogDir:
+			s.LogDir = abs
+		default:
+			s.DataDir = abs
+		}
+	case SettingBind:
+		s.BindAddress = value
+	case SettingPort:
+		port, err := strconv.Atoi(value)
+		if err != nil || port < 1 || port > 65535 {
+			return fmt.Errorf("port must be between 1 and 65535, got %q", value)
+		}
+		s.Port = port
+	case SettingLogLevel:
+		if _, err := ParseLogLevel(value); err != nil {
+			return err
+		}
+		s.LogLevel = strings.ToLower(value)
+	default:
+		return fmt.Errorf("unknown setting %s", key)
+	}
+	s.Sources[key] = source
+	return nil
+}
+
+// Values returns the effective settings in display order
+func (s *Settings) Values() []SettingValue {
+	values := make([]SettingValue, 0, len(settingDefinitions))
+	for _, def := range settingDefinitions {
+		values = append(values, SettingValue{
+			Key:    def.key,
+			Value:  s.valueOf(def.key),
+			Source: s.Sources[def.key],
+			Env:    def.env,
+			Flag:   "--" + def.flag,
+		})
+	}
+	return values
+}
+
+// valueOf returns a setting's value formatted for display
+func (s *Settings) valueOf(key string) string {
+	switch key {
+	case SettingConfig:
+		return s.ConfigPath
+	case SettingLogDir:
+		return s.LogDir
+	case SettingDataDir:
+		return s.DataDir
+	case SettingBind:
+		return s.BindAddress
+	case SettingPort:
+		return strconv.Itoa(s.Port)
+	case SettingLogLevel:
+		return s.LogLevel
+	}
+	return ""
+}
+
+// PidFilePath returns the location of the daemon PID file
+func (s *Settings) PidFilePath() string {
+	return filepath.Join(s.DataDir, "run-script-service.pid")
+}
+
+// DaemonLogPath returns the location of the daemon's stdout/stderr log
+func (s *Settings) DaemonLogPath() string {
+	return filepath.Join(s.DataDir, "daemon.log")
+}
+
+// SecretPaths returns what the file API must never serve from servedDir: the whole data
+// directory when it is a separate directory, and always the credentials, TLS keys and the
+// audit log, which must stay append-only. Temporary files written while saving them are
+// protected with them; auth.json.tmp is what earlier versions saved credentials through.
+func (s *Settings) SecretPaths(servedDir string) []string {
+	authPath := AuthStorePath(s.DataDir)
+	paths := []string{authPath, authPath + ".tmp", AuditLogPath(s.DataDir), filepath.Join(s.DataDir, "tls")}
+	if filepath.Clean(s.DataDir) != filepath.Clean(servedDir) {
+		paths = append(paths, s.DataDir)
+	}
+	if s.TLS != nil {
+		certFile, keyFile, clientCAFile := s.TLS.Files(s.DataDir)
+		paths = append(paths, certFile, keyFile, clientCAFile)
+	}
+	return paths
+}
+
+// extractGlobalFlags removes known --key=value global flags from args
+func extractGlobalFlags(args []string) (map[string]string, []string, error) {
+	known := make(map[string]bool)
+	for _, def := range settingDefinitions {
+		known[def.flag] = true
+	}
+
+	flags := make(map[string]string)
+	remaining := make([]string, 0, len(args))
+	for i, arg := range args {
+		if i == 0 || !strings.HasPrefix(arg, "--") {
+			remaining = append(remaining, arg)
+			continue
+		}
+		parts := strings.SplitN(arg[2:], "=", 2)
+		if !known[parts[0]] {
+			remaining = append(remaining, arg)
+			continue
+		}
+		if len(parts) != 2 || parts[1] == "" {
+			return nil, nil, fmt.Errorf("invalid flag format: %s (expected --%s=value)", arg, parts[0])
+		}
+		flags[parts[0]] = parts[1]
+	}
+	return flags, remaining, nil
+}
+
+// ExecutableDir returns the directory containing the running executable, or the working directory
+func ExecutableDir() string {
+	dir, err := os.Executable()
+	if err != nil {
+		dir, _ = os.Getwd()
+		return dir
+	}
+	return filepath.Dir(dir)
+}
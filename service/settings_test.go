@@ -0,0 +1,155 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+// envFrom returns a getenv function backed by a map
+func envFrom(env map[string]string) func(string) string {
+	return func(key string) string { return env[key] }
+}
+
+func TestResolveSettings_Defaults(t *testing.T) {
+	baseDir := t.TempDir()
+
+	settings, args, err := ResolveSettings(baseDir, []string{"rss", "list-scripts"}, envFrom(nil))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if settings.ConfigPath != filepath.Join(baseDir, "service_config.json") {
+		t.Errorf("unexpected config path: %s", settings.ConfigPath)
+	}
+	if settings.LogDir != filepath.Join(baseDir, "logs") || settings.DataDir != baseDir {
+		t.Errorf("unexpected directories: log=%s data=%s", settings.LogDir, settings.DataDir)
+	}
+	if settings.Port != DefaultWebPort || settings.LogLevel != "info" {
+		t.Errorf("unexpected port/log level: %d/%s", settings.Port, settings.LogLevel)
+	}
+	for _, v := range settings.Values() {
+		if v.Source != SourceDefault {
+			t.Errorf("expected %s to come from defaults, got %s", v.Key, v.Source)
+		}
+	}
+	if !reflect.DeepEqual(args, []string{"rss", "list-scripts"}) {
+		t.Errorf("unexpected remaining args: %v", args)
+	}
+	if settings.PidFilePath() != filepath.Join(baseDir, "run-script-service.pid") {
+		t.Errorf("unexpected PID file path: %s", settings.PidFilePath())
+	}
+}
+
+func TestResolveSettings_Layering(t *testing.T) {
+	baseDir := t.TempDir()
+	configDir := t.TempDir()
+	configPath := filepath.Join(configDir, "custom.json")
+
+	config := `{"scripts": [], "web_port": 9000, "log_dir": "var/logs", "bind_address": "127.0.0.1", "log_level": "warn",
+		"tls": {"self_signed": true}}`
+	if err := os.WriteFile(configPath, []byte(config), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	env := map[string]string{
+		"RSS_CONFIG": configPath,
+		"RSS_PORT":   "9100",
+	}
+	args := []string{"rss", "--port=9200", "daemon", "start", "--log-level=debug", "--strict"}
+
+	settings, remaining, err := ResolveSettings(baseDir, args, envFrom(env))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := map[string]struct {
+		value  string
+		source SettingSource
+	}{
+		SettingConfig:   {configPath, SourceEnv},
+		SettingLogDir:   {filepath.Join(configDir, "var", "logs"), SourceConfig},
+		SettingDataDir:  {baseDir, SourceDefault},
+		SettingBind:     {"127.0.0.1", SourceConfig},
+		SettingPort:     {"9200", SourceFlag},
+		SettingLogLevel: {"debug", SourceFlag},
+	}
+	for _, v := range settings.Values() {
+		want := expected[v.Key]
+		if v.Value != want.value || v.Source != want.source {
+			t.Errorf("%s: expected %q from %s, got %q from %s", v.Key, want.value, want.source, v.Value, v.Source)
+		}
+	}
+
+	if settings.TLS == nil || !settings.TLS.SelfSigned {
+		t.Errorf("Expected tls from the config file, got %+v", settings.TLS)
+	}
+
+	if !reflect.DeepEqual(remaining, []string{"rss", "daemon", "start", "--strict"}) {
+		t.Errorf("unexpected remaining args: %v", remaining)
+	}
+	if !reflect.DeepEqual(settings.Flags, []string{"--port=9200", "--log-level=debug"}) {
+		t.Errorf("unexpected pass-through flags: %v", settings.Flags)
+	}
+}
+
+func TestResolveSettings_RelativeFlagPathsAreAbsolute(t *testing.T) {
+	settings, _, err := ResolveSettings(t.TempDir(), []string{"rss", "--data-dir=state"}, envFrom(nil))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !filepath.IsAbs(settings.DataDir) {
+		t.Errorf("expected absolute data dir, got %s", settings.DataDir)
+	}
+	if settings.Flags[0] != "--data-dir="+settings.DataDir {
+		t.Errorf("expected pass-through flag to use the absolute path, got %s", settings.Flags[0])
+	}
+}
+
+func TestResolveSettings_InvalidValues(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		env  map[string]string
+	}{
+		{name: "port out of range", args: []string{"rss", "--port=70000"}},
+		{name: "port not a number", env: map[string]string{"RSS_PORT": "http"}},
+		{name: "unknown log level", env: map[string]string{"RSS_LOG_LEVEL": "verbose"}},
+		{name: "flag without value", args: []string{"rss", "--bind"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := tt.args
+			if args == nil {
+				args = []string{"rss"}
+			}
+			if _, _, err := ResolveSettings(t.TempDir(), args, envFrom(tt.env)); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestSettings_SecretPaths(t *testing.T) {
+	baseDir := t.TempDir()
+	settings := DefaultSettings(baseDir)
+	settings.TLS = &TLSConfig{KeyFile: "/etc/ssl/private/rss.key"}
+
+	paths := settings.SecretPaths(baseDir)
+	for _, expected := range []string{filepath.Join(baseDir, AuthFileName), filepath.Join(baseDir, AuthFileName+".tmp"), filepath.Join(baseDir, AuditFileName), filepath.Join(baseDir, "tls"), "/etc/ssl/private/rss.key"} {
+		if !slices.Contains(paths, expected) {
+			t.Errorf("Expected %s in %v", expected, paths)
+		}
+	}
+	if slices.Contains(paths, baseDir) {
+		t.Errorf("Expected a data dir shared with the served directory not to be protected as a whole, got %v", paths)
+	}
+
+	settings.DataDir = filepath.Join(baseDir, "data")
+	if paths := settings.SecretPaths(baseDir); !slices.Contains(paths, settings.DataDir) {
+		t.Errorf("Expected a separate data dir to be protected, got %v", paths)
+	}
+}
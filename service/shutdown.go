@@ -0,0 +1,194 @@
+// Package service provides core functionality for the run-script-service daemon.
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultShutdownTimeout is how long runs in flight may finish on shutdown without shutdown_timeout
+const DefaultShutdownTimeout = 30 * time.Second
+
+// killedRunGrace is how long killed runs get to record their outcome
+const killedRunGrace = 5 * time.Second
+
+// ErrShuttingDown is returned for runs requested after shutdown began
+var ErrShuttingDown = errors.New("service is shutting down")
+
+// ShutdownDuration returns how long runs in flight may finish on shutdown
+func (c *ServiceConfig) ShutdownDuration() time.Duration {
+	if c.ShutdownTimeout <= 0 {
+		return DefaultShutdownTimeout
+	}
+	return time.Duration(c.ShutdownTimeout) * time.Second
+}
+
+// ShutdownSummary describes what happened to the runs in flight when the service stopped
+type ShutdownSummary struct {
+	Finished []string      // scripts whose runs completed during the drain
+	Killed   []string      // scripts whose runs were still going at the deadline
+	Refused  int           // runs requested after shutdown began, or still queued or waiting for a slot when it began
+	Duration time.Duration // time spent draining
+}
+
+// String formats the summary for the daemon log
+func (s ShutdownSummary) String() string {
+	summary := fmt.Sprintf("%d run(s) finished, %d killed at the deadline, %d refused, drained in %s",
+		len(s.Finished), len(s.Killed), s.Refused, s.Duration.Round(time.Millisecond))
+	if len(s.Killed) > 0 {
+		summary += " (killed: " + strings.Join(s.Killed, ", ") + ")"
+	}
+	return summary
+}
+
+// runGate counts the runs in flight per script, refuses new runs once closed and can
+// kill the runs still going
+type runGate struct {
+	mutex   sync.Mutex
+	closed  bool
+	active  map[string]int
+	refused int
+	idle    chan struct{} // closed when the gate is closed and no run is in flight
+	kill    context.Context
+	killAll context.CancelFunc
+}
+
+// newRunGate creates an open gate
+func newRunGate() *runGate {
+	kill, killAll := context.WithCancel(context.Background())
+	return &runGate{active: make(map[string]int), idle: make(chan struct{}), kill: kill, killAll: killAll}
+}
+
+// enter admits a run of script. The returned context is also cancelled when the gate kills
+// its runs, and leave must be called when the run is over.
+func (g *runGate) enter(ctx context.Context, script string) (context.Context, func(), error) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	if g.closed {
+		g.refused++
+		return nil, nil, ErrShuttingDown
+	}
+	g.active[script]++
+
+	runCtx, cancel := context.WithCancel(ctx)
+	stop := context.AfterFunc(g.kill, cancel)
+	return runCtx, func() {
+		stop()
+		cancel()
+		g.leave(script)
+	}, nil
+}
+
+// leave ends a run of script
+func (g *runGate) leave(script string) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	if g.active[script]--; g.active[script] <= 0 {
+		delete(g.active, script)
+	}
+	if g.closed && len(g.active) == 0 {
+		close(g.idle)
+	}
+}
+
+// close refuses new runs and returns the scripts with runs in flight
+func (g *runGate) close() []string {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	if !g.closed {
+		g.closed = true
+		if len(g.active) == 0 {
+			close(g.idle)
+		}
+	}
+	return g.running()
+}
+
+// running returns the scripts with runs in flight, one entry per run
+func (g *runGate) running() []string {
+	var scripts []string
+	for script, count := range g.active {
+		for i := 0; i < count; i++ {
+			scripts = append(scripts, script)
+		}
+	}
+	sort.Strings(scripts)
+	return scripts
+}
+
+// wait blocks until no run is in flight after close, or ctx is done
+func (g *runGate) wait(ctx context.Context) bool {
+	select {
+	case <-g.idle:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// Shutdown drains the script manager: new runs are refused, scripts stop being scheduled
+// and runs in flight may finish until ctx is done, after which they are killed
+func (sm *ScriptManager) Shutdown(ctx context.Context) ShutdownSummary {
+	start := time.Now()
+	dropped := sm.queue.Close()
+	sm.limiter.close()
+	inFlight := sm.gate.close()
+
+	sm.mutex.RLock()
+	for _, runner := range sm.scripts {
+		runner.Halt()
+	}
+	sm.mutex.RUnlock()
+
+	var killed []string
+	if !sm.gate.wait(ctx) {
+		sm.gate.mutex.Lock()
+		killed = sm.gate.running()
+		sm.gate.mutex.Unlock()
+		sm.gate.killAll()
+
+		grace, cancel := context.WithTimeout(context.Background(), killedRunGrace)
+		sm.gate.wait(grace)
+		cancel()
+	}
+	sm.StopAll()
+
+	sm.gate.mutex.Lock()
+	refused := sm.gate.refused
+	sm.gate.mutex.Unlock()
+	return ShutdownSummary{
+		Finished: subtractScripts(inFlight, killed),
+		Killed:   killed,
+		Refused:  refused + dropped + sm.limiter.refusedRuns(),
+		Duration: time.Since(start),
+	}
+}
+
+// CloseLogSinks flushes and closes the log sinks, the last step of shutting down
+func (sm *ScriptManager) CloseLogSinks() {
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+	sm.forwarder.Close()
+}
+
+// subtractScripts removes one occurrence of each of remove from scripts
+func subtractScripts(scripts, remove []string) []string {
+	counts := make(map[string]int)
+	for _, script := range remove {
+		counts[script]++
+	}
+	var result []string
+	for _, script := range scripts {
+		if counts[script] > 0 {
+			counts[script]--
+			continue
+		}
+		result = append(result, script)
+	}
+	return result
+}
@@ -0,0 +1,170 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newShutdownTestManager returns a manager with a script that sleeps for the given seconds
+func newShutdownTestManager(t *testing.T, seconds string) *ScriptManager {
+	t.Helper()
+	dir := t.TempDir()
+	scriptPath := filepath.Join(dir, "slow.sh")
+	if err := os.WriteFile(scriptPath, []byte("#!/bin/bash\nsleep "+seconds+"\necho done\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	config := &ServiceConfig{Scripts: []ScriptConfig{
+		{Name: "slow", Path: scriptPath, Interval: 3600, Enabled: true, MaxLogLines: 10},
+	}}
+	manager := NewScriptManager(config)
+	manager.SetLogDir(filepath.Join(dir, "logs"))
+	return manager
+}
+
+// waitForRun blocks until the manager has a run in flight
+func waitForRun(t *testing.T, manager *ScriptManager) {
+	t.Helper()
+	for i := 0; i < 100; i++ {
+		manager.gate.mutex.Lock()
+		active := len(manager.gate.active)
+		manager.gate.mutex.Unlock()
+		if active > 0 {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("Expected a run to start")
+}
+
+func TestScriptManager_Shutdown_DrainsRuns(t *testing.T) {
+	manager := newShutdownTestManager(t, "0.3")
+	if err := manager.StartAllEnabled(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	waitForRun(t, manager)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	summary := manager.Shutdown(ctx)
+
+	if len(summary.Finished) != 1 || summary.Finished[0] != "slow" || len(summary.Killed) != 0 {
+		t.Errorf("Expected the run to finish, got %+v", summary)
+	}
+	entries := manager.GetLogManager().GetLogger("slow").GetEntries()
+	if len(entries) != 1 || entries[0].ExitCode != 0 || !strings.Contains(entries[0].Stdout, "done") {
+		t.Errorf("Expected the drained run to be recorded, got %+v", entries)
+	}
+	if len(manager.GetRunningScripts()) != 0 {
+		t.Errorf("Expected no scheduled scripts, got %v", manager.GetRunningScripts())
+	}
+
+	err := manager.RunScriptOnce(context.Background(), "slow")
+	if !errors.Is(err, ErrShuttingDown) {
+		t.Errorf("Expected new runs to be refused, got %v", err)
+	}
+	if summary := manager.Shutdown(ctx); summary.Refused != 1 {
+		t.Errorf("Expected one refused run, got %+v", summary)
+	}
+}
+
+func TestScriptManager_Shutdown_KillsAtDeadline(t *testing.T) {
+	manager := newShutdownTestManager(t, "30")
+	done := make(chan error, 1)
+	go func() { done <- manager.RunScriptOnce(context.Background(), "slow") }()
+	waitForRun(t, manager)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	summary := manager.Shutdown(ctx)
+
+	if len(summary.Killed) != 1 || summary.Killed[0] != "slow" || len(summary.Finished) != 0 {
+		t.Errorf("Expected the run to be killed, got %+v", summary)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected shutdown shortly after the deadline, took %s", elapsed)
+	}
+	if err := <-done; err == nil {
+		t.Error("Expected the killed run to fail")
+	}
+	if !strings.Contains(summary.String(), "1 killed at the deadline") || !strings.Contains(summary.String(), "(killed: slow)") {
+		t.Errorf("Unexpected summary %q", summary.String())
+	}
+}
+
+func TestScriptManager_Shutdown_RecordsKilledRuns(t *testing.T) {
+	dir := t.TempDir()
+	scriptPath := filepath.Join(dir, "slow.sh")
+	if err := os.WriteFile(scriptPath, []byte("#!/bin/bash\necho started\nsleep 30\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	manager := NewScriptManager(&ServiceConfig{Scripts: []ScriptConfig{
+		{Name: "slow", Path: scriptPath, Interval: 3600, MaxLogLines: 10},
+	}})
+	manager.SetLogDir(filepath.Join(dir, "logs"))
+
+	done := make(chan error, 1)
+	go func() { done <- manager.RunScriptOnce(context.Background(), "slow") }()
+	waitForRun(t, manager)
+	time.Sleep(100 * time.Millisecond) // let the script write its first line
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	manager.Shutdown(ctx)
+	<-done
+
+	entries := manager.GetLogManager().GetLogger("slow").GetEntries()
+	if len(entries) != 1 {
+		t.Fatalf("Expected the killed run to be recorded, got %+v", entries)
+	}
+	entry := entries[0]
+	if entry.ExitCode != -1 || entry.Stdout != "started" || entry.Outcome != OutcomeFailure || entry.OutcomeReason != "killed when the service shut down" {
+		t.Errorf("Expected a failed run with its output, got %+v", entry)
+	}
+
+	var buf strings.Builder
+	if err := manager.GetRunMetrics().WritePrometheus(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `run_script_runs_total{script="slow",outcome="failure"} 1`) {
+		t.Errorf("Expected the killed run counted as a failure, got:\n%s", buf.String())
+	}
+}
+
+func TestScriptRunner_Halt(t *testing.T) {
+	manager := newShutdownTestManager(t, "0.2")
+	runner := manager.newRunner(manager.config.Scripts[0])
+	runner.config.Interval = 1
+
+	stopped := make(chan struct{})
+	go func() {
+		runner.Start(context.Background())
+		close(stopped)
+	}()
+	waitForRun(t, manager)
+	runner.Halt()
+
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected scheduling to end after the run in flight")
+	}
+	entries := manager.GetLogManager().GetLogger("slow").GetEntries()
+	if len(entries) != 1 || entries[0].ExitCode != 0 {
+		t.Errorf("Expected the run in flight to complete, got %+v", entries)
+	}
+}
+
+func TestServiceConfig_ShutdownDuration(t *testing.T) {
+	if got := (&ServiceConfig{}).ShutdownDuration(); got != DefaultShutdownTimeout {
+		t.Errorf("Expected the default timeout, got %s", got)
+	}
+	if got := (&ServiceConfig{ShutdownTimeout: 5}).ShutdownDuration(); got != 5*time.Second {
+		t.Errorf("Expected 5s, got %s", got)
+	}
+}
@@ -0,0 +1,111 @@
+// Package service provides core functionality for the run-script-service daemon.
+package service
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DiffLine is a single line of a line-based diff
+type DiffLine struct {
+	Op   string `json:"op"` // " " unchanged, "-" removed, "+" added
+	Text string `json:"text"`
+}
+
+// DiffLines computes a line-based diff between two texts using a longest common subsequence
+func DiffLines(before, after string) []DiffLine {
+	a := splitLines(before)
+	b := splitLines(after)
+
+	// lcs[i][j] is the LCS length of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	diff := make([]DiffLine, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			diff = append(diff, DiffLine{Op: " ", Text: a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			diff = append(diff, DiffLine{Op: "-", Text: a[i]})
+			i++
+		default:
+			diff = append(diff, DiffLine{Op: "+", Text: b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		diff = append(diff, DiffLine{Op: "-", Text: a[i]})
+	}
+	for ; j < len(b); j++ {
+		diff = append(diff, DiffLine{Op: "+", Text: b[j]})
+	}
+	return diff
+}
+
+// HasChanges reports whether a diff contains any added or removed lines
+func HasChanges(diff []DiffLine) bool {
+	for _, line := range diff {
+		if line.Op != " " {
+			return true
+		}
+	}
+	return false
+}
+
+// FormatDiff renders a diff with unified-style headers, showing context lines around changes
+func FormatDiff(fromLabel, toLabel string, diff []DiffLine, context int) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", fromLabel, toLabel)
+
+	// Mark lines that are within context distance of a change
+	show := make([]bool, len(diff))
+	for i, line := range diff {
+		if line.Op == " " {
+			continue
+		}
+		for k := i - context; k <= i+context; k++ {
+			if k >= 0 && k < len(diff) {
+				show[k] = true
+			}
+		}
+	}
+
+	skipped := false
+	for i, line := range diff {
+		if !show[i] {
+			skipped = true
+			continue
+		}
+		if skipped {
+			sb.WriteString("@@\n")
+			skipped = false
+		}
+		sb.WriteString(line.Op + line.Text + "\n")
+	}
+	return sb.String()
+}
+
+// splitLines splits text into lines without a trailing empty element
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
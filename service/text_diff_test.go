@@ -0,0 +1,53 @@
+package service
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDiffLines(t *testing.T) {
+	before := "a\nb\nc\nd\n"
+	after := "a\nc\nd\ne\n"
+
+	diff := DiffLines(before, after)
+
+	expected := []DiffLine{
+		{Op: " ", Text: "a"},
+		{Op: "-", Text: "b"},
+		{Op: " ", Text: "c"},
+		{Op: " ", Text: "d"},
+		{Op: "+", Text: "e"},
+	}
+	if len(diff) != len(expected) {
+		t.Fatalf("expected %d lines, got %d: %+v", len(expected), len(diff), diff)
+	}
+	for i := range expected {
+		if diff[i] != expected[i] {
+			t.Errorf("line %d: expected %+v, got %+v", i, expected[i], diff[i])
+		}
+	}
+
+	if !HasChanges(diff) {
+		t.Error("expected diff to have changes")
+	}
+	if HasChanges(DiffLines(before, before)) {
+		t.Error("expected identical texts to have no changes")
+	}
+}
+
+func TestFormatDiff(t *testing.T) {
+	before := "1\n2\n3\n4\n5\n6\n7\n8\n"
+	after := "1\n2\n3\n4\n5\n6\n7\nX\n"
+
+	out := FormatDiff("v1", "v2", DiffLines(before, after), 1)
+
+	if !strings.HasPrefix(out, "--- v1\n+++ v2\n") {
+		t.Errorf("expected unified headers, got %q", out)
+	}
+	if !strings.Contains(out, "@@\n 7\n-8\n+X\n") {
+		t.Errorf("expected context around change, got %q", out)
+	}
+	if strings.Contains(out, " 1\n") {
+		t.Errorf("expected distant lines to be omitted, got %q", out)
+	}
+}
@@ -0,0 +1,282 @@
+// Package service provides core functionality for the run-script-service daemon.
+package service
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Default certificate files in the data directory, used when tls.cert_file and tls.key_file are empty
+const (
+	DefaultTLSCertFile = "tls/cert.pem"
+	DefaultTLSKeyFile  = "tls/key.pem"
+)
+
+// Client certificate modes of mutual TLS
+const (
+	ClientAuthRequire  = "require"  // connections without a valid client certificate are refused
+	ClientAuthOptional = "optional" // a client certificate is verified when one is presented
+)
+
+// unixSocketPrefix marks a bind address that is a unix domain socket path
+const unixSocketPrefix = "unix:"
+
+// selfSignedValidity is how long a generated certificate is valid
+const selfSignedValidity = 5 * 365 * 24 * time.Hour
+
+// TLSConfig serves the web interface over HTTPS. Relative paths are relative to the data directory.
+type TLSConfig struct {
+	CertFile     string `json:"cert_file,omitempty"`      // PEM certificate chain, default tls/cert.pem
+	KeyFile      string `json:"key_file,omitempty"`       // PEM private key, default tls/key.pem
+	SelfSigned   bool   `json:"self_signed,omitempty"`    // generate a certificate on first start when the files are missing
+	ClientCAFile string `json:"client_ca_file,omitempty"` // PEM CAs that sign client certificates, enables mutual TLS
+	ClientAuth   string `json:"client_auth,omitempty"`    // require (default) or optional
+}
+
+// UnixSocketPath returns the socket path of a "unix:<path>" bind address
+func UnixSocketPath(bindAddress string) (string, bool) {
+	path, ok := strings.CutPrefix(bindAddress, unixSocketPrefix)
+	if !ok || path == "" {
+		return "", false
+	}
+	return path, true
+}
+
+// Files returns the certificate, key and client CA paths resolved against dataDir.
+// The client CA path is empty without mutual TLS.
+func (c *TLSConfig) Files(dataDir string) (certFile, keyFile, clientCAFile string) {
+	resolve := func(path, fallback string) string {
+		if path == "" {
+			path = fallback
+		}
+		if path == "" || filepath.IsAbs(path) {
+			return path
+		}
+		return filepath.Join(dataDir, path)
+	}
+	return resolve(c.CertFile, DefaultTLSCertFile), resolve(c.KeyFile, DefaultTLSKeyFile), resolve(c.ClientCAFile, "")
+}
+
+// clientAuthType returns how client certificates are checked
+func (c *TLSConfig) clientAuthType() tls.ClientAuthType {
+	if c.ClientCAFile == "" {
+		return tls.NoClientCert
+	}
+	if c.ClientAuth == ClientAuthOptional {
+		return tls.VerifyClientCertIfGiven
+	}
+	return tls.RequireAndVerifyClientCert
+}
+
+// TLSReloader builds the server TLS configuration and reloads the certificate, key and
+// client CAs whenever one of the files changes, so renewed certificates apply without a restart
+type TLSReloader struct {
+	certFile     string
+	keyFile      string
+	clientCAFile string
+	clientAuth   tls.ClientAuthType
+
+	mutex   sync.Mutex
+	current *tls.Config
+	stamp   string // modification times and sizes of the files current was loaded from
+}
+
+// NewTLSReloader loads the configured files, generating a self-signed certificate for hosts
+// first when enabled and the files are missing
+func NewTLSReloader(config *TLSConfig, dataDir string, hosts []string) (*TLSReloader, error) {
+	certFile, keyFile, clientCAFile := config.Files(dataDir)
+	if config.SelfSigned {
+		_, certErr := os.Stat(certFile)
+		_, keyErr := os.Stat(keyFile)
+		if os.IsNotExist(certErr) && os.IsNotExist(keyErr) {
+			if err := GenerateSelfSignedCert(certFile, keyFile, hosts); err != nil {
+				return nil, err
+			}
+			Warnf("Generated a self-signed certificate at %s; clients must trust it explicitly", certFile)
+		}
+	}
+
+	reloader := &TLSReloader{
+		certFile:     certFile,
+		keyFile:      keyFile,
+		clientCAFile: clientCAFile,
+		clientAuth:   config.clientAuthType(),
+	}
+	if _, err := reloader.load(); err != nil {
+		return nil, err
+	}
+	return reloader, nil
+}
+
+// ServerConfig returns the TLS configuration to serve with
+func (r *TLSReloader) ServerConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		GetConfigForClient: r.configForClient,
+	}
+}
+
+// configForClient returns the configuration of a new connection, reloading changed files.
+// A failed reload keeps serving the previous files.
+func (r *TLSReloader) configForClient(*tls.ClientHelloInfo) (*tls.Config, error) {
+	config, err := r.load()
+	if err != nil {
+		Warnf("Keeping the previous TLS certificate: %v", err)
+	}
+	return config, nil
+}
+
+// load returns the configuration for the current files, reading them again when they changed
+func (r *TLSReloader) load() (*tls.Config, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	stamp := fileStamp(r.certFile, r.keyFile, r.clientCAFile)
+	if r.current != nil && stamp == r.stamp {
+		return r.current, nil
+	}
+
+	config, err := r.read()
+	if err != nil {
+		if r.current == nil {
+			return nil, err
+		}
+		// Retry on the next connection, the files may be in the middle of being replaced
+		return r.current, err
+	}
+	r.current, r.stamp = config, stamp
+	return config, nil
+}
+
+// read loads the certificate, key and client CAs from disk
+func (r *TLSReloader) read() (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load TLS certificate: %v", err)
+	}
+	config := &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{cert},
+		ClientAuth:   r.clientAuth,
+		NextProtos:   []string{"h2", "http/1.1"},
+	}
+	if r.clientCAFile != "" {
+		pool, err := LoadCertPool(r.clientCAFile)
+		if err != nil {
+			return nil, err
+		}
+		config.ClientCAs = pool
+	}
+	return config, nil
+}
+
+// fileStamp summarises the modification times and sizes of files, missing files included
+func fileStamp(paths ...string) string {
+	var stamp strings.Builder
+	for _, path := range paths {
+		if path == "" {
+			continue
+		}
+		if info, err := os.Stat(path); err == nil {
+			fmt.Fprintf(&stamp, "%s:%d:%d;", path, info.ModTime().UnixNano(), info.Size())
+		} else {
+			fmt.Fprintf(&stamp, "%s:missing;", path)
+		}
+	}
+	return stamp.String()
+}
+
+// LoadCertPool reads PEM certificates from path into a pool
+func LoadCertPool(path string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA certificates: %v", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no PEM certificates found in %s", path)
+	}
+	return pool, nil
+}
+
+// GenerateSelfSignedCert writes a new ECDSA certificate for hosts, which may be names or
+// IP addresses, and its private key. localhost and the loopback addresses are always included.
+func GenerateSelfSignedCert(certFile, keyFile string, hosts []string) error {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return fmt.Errorf("failed to generate key: %v", err)
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return fmt.Errorf("failed to generate serial number: %v", err)
+	}
+
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: "run-script-service", Organization: []string{"run-script-service"}},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(selfSignedValidity),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	seen := make(map[string]bool)
+	for _, host := range append([]string{"localhost", "127.0.0.1", "::1"}, hosts...) {
+		if host == "" || seen[host] {
+			continue
+		}
+		seen[host] = true
+		if ip := net.ParseIP(host); ip != nil {
+			if !ip.IsUnspecified() {
+				template.IPAddresses = append(template.IPAddresses, ip)
+			}
+		} else {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return fmt.Errorf("failed to create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return fmt.Errorf("failed to encode key: %v", err)
+	}
+
+	if err := writePEM(keyFile, "EC PRIVATE KEY", keyDER, 0600); err != nil {
+		return err
+	}
+	return writePEM(certFile, "CERTIFICATE", der, 0644)
+}
+
+// writePEM writes one PEM block to path, creating its directory
+func writePEM(path, blockType string, der []byte, mode os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create certificate directory: %v", err)
+	}
+	var data bytes.Buffer
+	if err := pem.Encode(&data, &pem.Block{Type: blockType, Bytes: der}); err != nil {
+		return fmt.Errorf("failed to encode %s: %v", path, err)
+	}
+	if err := os.WriteFile(path, data.Bytes(), mode); err != nil {
+		return fmt.Errorf("failed to write %s: %v", path, err)
+	}
+	return nil
+}
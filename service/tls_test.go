@@ -0,0 +1,170 @@
+package service
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestUnixSocketPath(t *testing.T) {
+	tests := []struct {
+		bind string
+		path string
+		ok   bool
+	}{
+		{"unix:/run/rss.sock", "/run/rss.sock", true},
+		{"unix:", "", false},
+		{"127.0.0.1", "", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		path, ok := UnixSocketPath(tt.bind)
+		if path != tt.path || ok != tt.ok {
+			t.Errorf("%q: expected (%q, %v), got (%q, %v)", tt.bind, tt.path, tt.ok, path, ok)
+		}
+	}
+}
+
+func TestTLSConfig_Files(t *testing.T) {
+	cert, key, ca := (&TLSConfig{}).Files("/data")
+	if cert != "/data/tls/cert.pem" || key != "/data/tls/key.pem" || ca != "" {
+		t.Errorf("Expected default files in the data directory, got %s %s %q", cert, key, ca)
+	}
+
+	cert, key, ca = (&TLSConfig{CertFile: "/etc/rss/cert.pem", KeyFile: "key.pem", ClientCAFile: "clients.pem"}).Files("/data")
+	if cert != "/etc/rss/cert.pem" || key != "/data/key.pem" || ca != "/data/clients.pem" {
+		t.Errorf("Expected configured files, got %s %s %s", cert, key, ca)
+	}
+}
+
+func TestGenerateSelfSignedCert(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "tls", "cert.pem"), filepath.Join(dir, "tls", "key.pem")
+	if err := GenerateSelfSignedCert(certFile, keyFile, []string{"rss.example.com", "10.0.0.5", "0.0.0.0"}); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Stat(keyFile)
+	if err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("Expected a private key file, got %v %v", info, err)
+	}
+	pair, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, host := range []string{"localhost", "rss.example.com", "127.0.0.1", "10.0.0.5"} {
+		if err := cert.VerifyHostname(host); err != nil {
+			t.Errorf("Expected the certificate to cover %s: %v", host, err)
+		}
+	}
+	if len(cert.IPAddresses) != 3 {
+		t.Errorf("Expected the unspecified address to be left out, got %v", cert.IPAddresses)
+	}
+}
+
+func TestNewTLSReloader(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := NewTLSReloader(&TLSConfig{}, dir, nil); err == nil {
+		t.Error("Expected an error without certificate files")
+	}
+
+	reloader, err := NewTLSReloader(&TLSConfig{SelfSigned: true}, dir, []string{"rss.example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, err := reloader.ServerConfig().GetConfigForClient(nil)
+	if err != nil || len(first.Certificates) != 1 || first.ClientAuth != tls.NoClientCert {
+		t.Fatalf("Expected a certificate without client auth, got %+v, %v", first, err)
+	}
+
+	// A second start keeps the generated certificate
+	again, err := NewTLSReloader(&TLSConfig{SelfSigned: true}, dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second, _ := again.configForClient(nil); !bytes.Equal(second.Certificates[0].Certificate[0], first.Certificates[0].Certificate[0]) {
+		t.Error("Expected the existing self-signed certificate to be reused")
+	}
+}
+
+func TestTLSReloader_Reload(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile, _ := (&TLSConfig{}).Files(dir)
+	if err := GenerateSelfSignedCert(certFile, keyFile, nil); err != nil {
+		t.Fatal(err)
+	}
+	reloader, err := NewTLSReloader(&TLSConfig{}, dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, _ := reloader.configForClient(nil)
+	if same, _ := reloader.configForClient(nil); same != first {
+		t.Error("Expected unchanged files not to be read again")
+	}
+
+	// A renewed certificate applies to the next connection
+	if err := GenerateSelfSignedCert(certFile, keyFile, nil); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Minute)
+	for _, path := range []string{certFile, keyFile} {
+		if err := os.Chtimes(path, later, later); err != nil {
+			t.Fatal(err)
+		}
+	}
+	renewed, _ := reloader.configForClient(nil)
+	if bytes.Equal(renewed.Certificates[0].Certificate[0], first.Certificates[0].Certificate[0]) {
+		t.Error("Expected the renewed certificate to be loaded")
+	}
+
+	// A broken file keeps the previous certificate
+	if err := os.WriteFile(certFile, []byte("not a certificate"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	kept, err := reloader.configForClient(nil)
+	if err != nil || kept != renewed {
+		t.Errorf("Expected the previous configuration to be kept, got %v", err)
+	}
+}
+
+func TestTLSReloader_ClientCAs(t *testing.T) {
+	dir := t.TempDir()
+	caFile := filepath.Join(dir, "clients.pem")
+	if err := GenerateSelfSignedCert(caFile, filepath.Join(dir, "clients.key"), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		clientAuth string
+		expected   tls.ClientAuthType
+	}{
+		{"", tls.RequireAndVerifyClientCert},
+		{ClientAuthRequire, tls.RequireAndVerifyClientCert},
+		{ClientAuthOptional, tls.VerifyClientCertIfGiven},
+	}
+	for _, tt := range tests {
+		reloader, err := NewTLSReloader(&TLSConfig{SelfSigned: true, ClientCAFile: "clients.pem", ClientAuth: tt.clientAuth}, dir, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		config, _ := reloader.configForClient(nil)
+		if config.ClientAuth != tt.expected || config.ClientCAs == nil {
+			t.Errorf("%q: expected client auth %v with CAs, got %v", tt.clientAuth, tt.expected, config.ClientAuth)
+		}
+	}
+
+	if err := os.WriteFile(caFile, []byte("none"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewTLSReloader(&TLSConfig{SelfSigned: true, ClientCAFile: "clients.pem"}, dir, nil); err == nil {
+		t.Error("Expected an error for a client CA file without certificates")
+	}
+}
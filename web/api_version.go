@@ -0,0 +1,87 @@
+// Package web provides versioning and request IDs of the HTTP API
+package web
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APIPrefix is the base path of the current version of the HTTP API
+const APIPrefix = "/api/v1"
+
+// legacyAPIPrefix serves the routes of APIPrefix as deprecated aliases
+const legacyAPIPrefix = "/api"
+
+// legacyAPIDeprecated is when the unversioned routes were deprecated, sent in the Deprecation header
+var legacyAPIDeprecated = time.Date(2026, time.October, 18, 0, 0, 0, 0, time.UTC)
+
+// RequestIDHeader carries the ID of a request in both directions. Clients may choose the ID,
+// otherwise the server generates one.
+const RequestIDHeader = "X-Request-ID"
+
+// requestIDKey is the gin context key of the request ID
+const requestIDKey = "request_id"
+
+// requestIDPattern accepts client request IDs that are safe to log and echo
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// apiGroups returns the router groups API routes are registered on: the current version
+// and its deprecated unversioned alias
+func (ws *WebServer) apiGroups() []*gin.RouterGroup {
+	return []*gin.RouterGroup{
+		ws.router.Group(APIPrefix),
+		ws.router.Group(legacyAPIPrefix, deprecatedAPI),
+	}
+}
+
+// deprecatedAPI marks responses of the unversioned routes as deprecated (RFC 9745) and links
+// the route of the current version
+func deprecatedAPI(c *gin.Context) {
+	c.Header("Deprecation", fmt.Sprintf("@%d", legacyAPIDeprecated.Unix()))
+	successor := APIPrefix + strings.TrimPrefix(c.Request.URL.Path, legacyAPIPrefix)
+	c.Header("Link", fmt.Sprintf("<%s>; rel=\"successor-version\"", successor))
+	c.Next()
+}
+
+// apiRoute returns the path of an API request below the prefix of its version, such as /scripts
+func apiRoute(path string) (string, bool) {
+	if rest, ok := strings.CutPrefix(path, APIPrefix); ok && (rest == "" || rest[0] == '/') {
+		return rest, true
+	}
+	if rest, ok := strings.CutPrefix(path, legacyAPIPrefix); ok && strings.HasPrefix(rest, "/") {
+		return rest, true
+	}
+	return "", false
+}
+
+// requestIDMiddleware assigns every request an ID, echoed in the X-Request-ID response header
+// and in error responses
+func requestIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !requestIDPattern.MatchString(id) {
+			id = newRequestID()
+		}
+		c.Set(requestIDKey, id)
+		c.Header(RequestIDHeader, id)
+		c.Next()
+	}
+}
+
+// newRequestID returns a random request ID
+func newRequestID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// requestID returns the ID of the request, empty outside of requestIDMiddleware
+func requestID(c *gin.Context) string {
+	return c.GetString(requestIDKey)
+}
@@ -0,0 +1,121 @@
+package web
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"run-script-service/service"
+)
+
+func TestAPIRoute(t *testing.T) {
+	tests := []struct {
+		path     string
+		expected string
+		ok       bool
+	}{
+		{"/api/v1/scripts", "/scripts", true},
+		{"/api/v1", "", true},
+		{"/api/scripts", "/scripts", true},
+		{"/api/v1/auth/login", "/auth/login", true},
+		{"/api/auth/login", "/auth/login", true},
+		{"/api/v10/scripts", "/v10/scripts", true}, // an unversioned route, 404 in the router
+		{"/api", "", false},
+		{"/apiary", "", false},
+		{"/ws", "", false},
+	}
+	for _, tt := range tests {
+		route, ok := apiRoute(tt.path)
+		if route != tt.expected || ok != tt.ok {
+			t.Errorf("apiRoute(%q) = %q, %v, expected %q, %v", tt.path, route, ok, tt.expected, tt.ok)
+		}
+	}
+}
+
+func TestWebServer_DeprecatedAliases(t *testing.T) {
+	server := createTestServerWithScripts([]service.ScriptConfig{
+		{Name: "backup", Path: "./backup.sh", Interval: 60},
+	})
+
+	for _, path := range []string{"/api/scripts", "/api/scripts/backup", "/api/status"} {
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		if w.Code != http.StatusOK {
+			t.Errorf("Expected the alias %s to be served, got %d", path, w.Code)
+		}
+		if w.Header().Get("Deprecation") != fmt.Sprintf("@%d", legacyAPIDeprecated.Unix()) {
+			t.Errorf("Expected a Deprecation header on %s, got %q", path, w.Header().Get("Deprecation"))
+		}
+		successor := fmt.Sprintf("<%s>; rel=\"successor-version\"", strings.Replace(path, "/api", APIPrefix, 1))
+		if w.Header().Get("Link") != successor {
+			t.Errorf("Expected Link %s on %s, got %q", successor, path, w.Header().Get("Link"))
+		}
+
+		v1 := strings.Replace(path, "/api", APIPrefix, 1)
+		w = httptest.NewRecorder()
+		server.router.ServeHTTP(w, httptest.NewRequest("GET", v1, nil))
+		if w.Code != http.StatusOK || w.Header().Get("Deprecation") != "" || w.Header().Get("Link") != "" {
+			t.Errorf("Expected %s without deprecation headers, got %d %v", v1, w.Code, w.Header())
+		}
+	}
+}
+
+func TestWebServer_RequestID(t *testing.T) {
+	server := createTestServerWithScripts(nil)
+
+	tests := []struct {
+		name   string
+		header string
+		echoed bool
+	}{
+		{"generated", "", false},
+		{"client chosen", "deploy-42:retry.1", true},
+		{"unsafe", "bad id\r\nX-Injected: 1", false},
+		{"too long", strings.Repeat("a", 129), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/v1/scripts/missing", nil)
+			if tt.header != "" {
+				req.Header.Set(RequestIDHeader, tt.header)
+			}
+			w := httptest.NewRecorder()
+			server.router.ServeHTTP(w, req)
+
+			id := w.Header().Get(RequestIDHeader)
+			if !requestIDPattern.MatchString(id) {
+				t.Fatalf("Expected a request ID, got %q", id)
+			}
+			if echoed := id == tt.header; echoed != tt.echoed {
+				t.Errorf("Expected echoed %v, got %q for %q", tt.echoed, id, tt.header)
+			}
+			if response := decodeError(t, w); response.RequestID != id {
+				t.Errorf("Expected request ID %s in the body, got %q", id, response.RequestID)
+			}
+		})
+	}
+}
+
+func TestWebServer_PublicRoutesBothVersions(t *testing.T) {
+	server, _ := createTestServerWithAuth(t, nil)
+
+	for _, path := range []string{"/api/v1/openapi.json", "/api/openapi.json"} {
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		if w.Code != http.StatusOK {
+			t.Errorf("Expected %s without credentials, got %d", path, w.Code)
+		}
+	}
+	for _, path := range []string{"/api/v1/scripts", "/api/scripts"} {
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("Expected %s to require credentials, got %d", path, w.Code)
+		}
+		if response := decodeError(t, w); response.Code != ErrorUnauthorized {
+			t.Errorf("Expected code %s on %s, got %q", ErrorUnauthorized, path, response.Code)
+		}
+	}
+}
@@ -0,0 +1,405 @@
+// Package web provides authentication of the HTTP API and WebSocket
+package web
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"run-script-service/service"
+)
+
+// sessionCookieName is the cookie holding a web interface session
+const sessionCookieName = "rss_session"
+
+// principalKey is the gin context key of the authenticated Principal
+const principalKey = "principal"
+
+// Principal is the authenticated caller of a request
+type Principal struct {
+	Name   string `json:"name"`   // user name, or the token name for API tokens
+	Method string `json:"method"` // service.AuthMethodToken, AuthMethodBasic, AuthMethodSession or AuthMethodCertificate
+	Role   string `json:"role"`   // role outside of per-script grants
+}
+
+// Authenticator identifies the caller of a request. It returns nil when the request
+// carries no credentials it understands, and an error when they are invalid.
+type Authenticator interface {
+	Method() string
+	Authenticate(r *http.Request) (*Principal, error)
+}
+
+// errInvalidCredentials rejects a request whose credentials do not match
+type errInvalidCredentials struct{}
+
+func (errInvalidCredentials) Error() string { return "invalid credentials" }
+
+// tokenAuthenticator accepts API tokens as bearer tokens
+type tokenAuthenticator struct {
+	store *service.AuthStore
+}
+
+// Method implements Authenticator
+func (a *tokenAuthenticator) Method() string { return service.AuthMethodToken }
+
+// Authenticate implements Authenticator
+func (a *tokenAuthenticator) Authenticate(r *http.Request) (*Principal, error) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return nil, nil
+	}
+	stored, valid := a.store.VerifyToken(strings.TrimSpace(token))
+	if !valid {
+		return nil, errInvalidCredentials{}
+	}
+	return &Principal{Name: stored.Name, Method: service.AuthMethodToken, Role: stored.Role}, nil
+}
+
+// basicAuthenticator accepts HTTP basic credentials checked against bcrypt password hashes
+type basicAuthenticator struct {
+	store *service.AuthStore
+}
+
+// Method implements Authenticator
+func (a *basicAuthenticator) Method() string { return service.AuthMethodBasic }
+
+// Authenticate implements Authenticator
+func (a *basicAuthenticator) Authenticate(r *http.Request) (*Principal, error) {
+	name, password, ok := r.BasicAuth()
+	if !ok {
+		return nil, nil
+	}
+	if !a.store.CheckPassword(name, password) {
+		return nil, errInvalidCredentials{}
+	}
+	role, ok := a.store.UserRole(name)
+	if !ok {
+		return nil, errInvalidCredentials{}
+	}
+	return &Principal{Name: name, Method: service.AuthMethodBasic, Role: role}, nil
+}
+
+// certificateAuthenticator accepts verified TLS client certificates whose common name is a user
+type certificateAuthenticator struct {
+	store *service.AuthStore
+}
+
+// Method implements Authenticator
+func (a *certificateAuthenticator) Method() string { return service.AuthMethodCertificate }
+
+// Authenticate implements Authenticator. Only certificates verified against the client CAs count.
+func (a *certificateAuthenticator) Authenticate(r *http.Request) (*Principal, error) {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return nil, nil
+	}
+	name := r.TLS.VerifiedChains[0][0].Subject.CommonName
+	role, ok := a.store.UserRole(name)
+	if !ok {
+		return nil, errInvalidCredentials{}
+	}
+	return &Principal{Name: name, Method: service.AuthMethodCertificate, Role: role}, nil
+}
+
+// session is a logged-in web interface user
+type session struct {
+	user    string
+	expires time.Time
+}
+
+// sessionAuthenticator accepts the session cookie set by POST /api/auth/login
+type sessionAuthenticator struct {
+	store    *service.AuthStore
+	ttl      time.Duration
+	sessions map[string]session
+	mutex    sync.Mutex
+}
+
+// newSessionAuthenticator creates an authenticator without sessions
+func newSessionAuthenticator(store *service.AuthStore, ttl time.Duration) *sessionAuthenticator {
+	return &sessionAuthenticator{store: store, ttl: ttl, sessions: make(map[string]session)}
+}
+
+// Method implements Authenticator
+func (a *sessionAuthenticator) Method() string { return service.AuthMethodSession }
+
+// Authenticate implements Authenticator. Sessions of removed users end immediately
+// and role changes apply to existing sessions.
+func (a *sessionAuthenticator) Authenticate(r *http.Request) (*Principal, error) {
+	cookie, err := r.Cookie(sessionCookieName)
+	if err != nil {
+		return nil, nil
+	}
+
+	a.mutex.Lock()
+	s, ok := a.sessions[cookie.Value]
+	if ok && time.Now().After(s.expires) {
+		delete(a.sessions, cookie.Value)
+		ok = false
+	}
+	a.mutex.Unlock()
+
+	if !ok {
+		return nil, errInvalidCredentials{}
+	}
+	role, ok := a.store.UserRole(s.user)
+	if !ok {
+		return nil, errInvalidCredentials{}
+	}
+	return &Principal{Name: s.user, Method: service.AuthMethodSession, Role: role}, nil
+}
+
+// create starts a session for user and returns its ID
+func (a *sessionAuthenticator) create(user string) (string, error) {
+	id, err := service.NewSessionID()
+	if err != nil {
+		return "", err
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	now := time.Now()
+	for key, s := range a.sessions {
+		if now.After(s.expires) {
+			delete(a.sessions, key)
+		}
+	}
+	a.sessions[id] = session{user: user, expires: now.Add(a.ttl)}
+	return id, nil
+}
+
+// end removes a session
+func (a *sessionAuthenticator) end(id string) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	delete(a.sessions, id)
+}
+
+// webAuth holds the credential store and the authenticators of the enabled methods
+type webAuth struct {
+	store          *service.AuthStore
+	authenticators []Authenticator
+	sessions       *sessionAuthenticator // nil when sessions are disabled
+	allowedOrigins map[string]bool
+}
+
+// SetAuth requires authentication on /api and /ws whenever store holds a user or token.
+// config selects the accepted methods; nil accepts all of them.
+func (ws *WebServer) SetAuth(store *service.AuthStore, config *service.AuthConfig) {
+	auth := &webAuth{store: store, allowedOrigins: make(map[string]bool)}
+	if config.MethodEnabled(service.AuthMethodCertificate) {
+		auth.authenticators = append(auth.authenticators, &certificateAuthenticator{store: store})
+	}
+	if config.MethodEnabled(service.AuthMethodToken) {
+		auth.authenticators = append(auth.authenticators, &tokenAuthenticator{store: store})
+	}
+	if config.MethodEnabled(service.AuthMethodBasic) {
+		auth.authenticators = append(auth.authenticators, &basicAuthenticator{store: store})
+	}
+	if config.MethodEnabled(service.AuthMethodSession) {
+		auth.sessions = newSessionAuthenticator(store, config.SessionDuration())
+		auth.authenticators = append(auth.authenticators, auth.sessions)
+	}
+	if config != nil {
+		for _, origin := range config.AllowedOrigins {
+			auth.allowedOrigins[strings.TrimSuffix(origin, "/")] = true
+		}
+	}
+
+	ws.authMutex.Lock()
+	defer ws.authMutex.Unlock()
+	ws.auth = auth
+}
+
+// getAuth returns the authentication settings, nil when none were set
+func (ws *WebServer) getAuth() *webAuth {
+	ws.authMutex.RLock()
+	defer ws.authMutex.RUnlock()
+	return ws.auth
+}
+
+// publicAPIRoutes can be requested without credentials, in every API version
+var publicAPIRoutes = map[string]bool{
+	"/auth/login":   true,
+	"/auth/logout":  true,
+	"/openapi.json": true,
+	"/docs":         true,
+}
+
+// authMiddleware rejects unauthenticated requests to /api and /ws once credentials exist,
+// and stores the Principal of authenticated ones in the context
+func (ws *WebServer) authMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		path := c.Request.URL.Path
+		if !strings.HasPrefix(path, "/api/") && path != "/ws" {
+			c.Next()
+			return
+		}
+		auth := ws.getAuth()
+		if auth == nil {
+			c.Next()
+			return
+		}
+
+		principal, err := auth.authenticate(c.Request)
+		if principal != nil {
+			// Browsers send the session cookie with requests other sites make, so its writes must come from a trusted page
+			if principal.Method == service.AuthMethodSession && !ws.trustedWrite(c.Request) {
+				respondError(c, ErrorForbidden, "Cross-site request refused: the Origin is not this server or an allowed origin")
+				return
+			}
+			c.Set(principalKey, principal)
+			c.Next()
+			return
+		}
+		if route, ok := apiRoute(path); ok && publicAPIRoutes[route] {
+			c.Next()
+			return
+		}
+
+		required, storeErr := auth.store.HasCredentials()
+		if storeErr != nil {
+			service.Warnf("Rejecting request, credential store unavailable: %v", storeErr)
+			respondError(c, ErrorInternal, "Credential store unavailable")
+			return
+		}
+		if !required {
+			c.Next()
+			return
+		}
+
+		message := "Authentication required"
+		if err != nil {
+			message = "Invalid credentials"
+		}
+		// The web interface marks its requests so browsers do not show their own login prompt
+		if auth.methodEnabled(service.AuthMethodBasic) && c.GetHeader("X-Requested-With") == "" {
+			c.Header("WWW-Authenticate", `Basic realm="run-script-service"`)
+		}
+		respondError(c, ErrorUnauthorized, message)
+	}
+}
+
+// authenticate tries each enabled method in turn. Invalid credentials of one method
+// are reported only when no other method accepts the request.
+func (a *webAuth) authenticate(r *http.Request) (*Principal, error) {
+	var firstErr error
+	for _, authenticator := range a.authenticators {
+		principal, err := authenticator.Authenticate(r)
+		if principal != nil {
+			return principal, nil
+		}
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return nil, firstErr
+}
+
+// methodEnabled reports whether an authentication method is accepted
+func (a *webAuth) methodEnabled(method string) bool {
+	for _, authenticator := range a.authenticators {
+		if authenticator.Method() == method {
+			return true
+		}
+	}
+	return false
+}
+
+// trustedWrite reports whether a request may change state with a session cookie: reads always
+// may, writes need an Origin that checkOrigin allows or, without one, the X-Requested-With
+// header of the web interface, which other sites cannot set without a CORS preflight
+func (ws *WebServer) trustedWrite(r *http.Request) bool {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	if r.Header.Get("Origin") == "" {
+		return r.Header.Get("X-Requested-With") != ""
+	}
+	return ws.checkOrigin(r)
+}
+
+// allowedOrigin reports whether a cross-origin page may call the API: only the configured
+// allowed origins may
+func (ws *WebServer) allowedOrigin(origin string) bool {
+	auth := ws.getAuth()
+	return auth != nil && auth.allowedOrigins[strings.TrimSuffix(origin, "/")]
+}
+
+// checkOrigin allows WebSocket connections without an Origin header (non-browser clients),
+// from the server's own origin and from the configured allowed origins
+func (ws *WebServer) checkOrigin(r *http.Request) bool {
+	return sameOrigin(r) || ws.allowedOrigin(r.Header.Get("Origin"))
+}
+
+// PrincipalFrom returns the authenticated caller of a request, nil when authentication is off
+func PrincipalFrom(c *gin.Context) *Principal {
+	value, ok := c.Get(principalKey)
+	if !ok {
+		return nil
+	}
+	principal, _ := value.(*Principal)
+	return principal
+}
+
+// authorize reports whether the caller may perform action, which needs role globally or,
+// when scripts are given, on each of them. Otherwise it responds 403 with the reason.
+// Without authentication every request is allowed.
+func (ws *WebServer) authorize(c *gin.Context, role, action string, scripts ...service.ScriptConfig) bool {
+	principal := PrincipalFrom(c)
+	if principal == nil {
+		return true
+	}
+
+	effective := principal.Role
+	if len(scripts) == 0 {
+		if service.RoleAllows(effective, role) {
+			return true
+		}
+	} else {
+		var config *service.AuthConfig
+		if ws.scriptManager != nil {
+			config = ws.scriptManager.GetConfig().Auth
+		}
+		token := principal.Method == service.AuthMethodToken
+		allowed := true
+		for _, script := range scripts {
+			scriptRole := config.ScriptRole(principal.Name, token, principal.Role, script)
+			if !service.RoleAllows(scriptRole, role) {
+				effective, allowed = scriptRole, false
+				break
+			}
+		}
+		if allowed {
+			return true
+		}
+	}
+
+	respondError(c, ErrorForbidden, fmt.Sprintf("Forbidden: %s requires the %s role, %s has the %s role", action, role, principal.Name, effective))
+	return false
+}
+
+// scriptConfig returns the configuration of a script for authorization. Unknown scripts
+// only carry their name, so grants by name still apply and the handler reports them missing.
+func (ws *WebServer) scriptConfig(name string) service.ScriptConfig {
+	if script, ok := ws.findScript(name); ok {
+		return script
+	}
+	return service.ScriptConfig{Name: name}
+}
+
+// findScript returns a copy of the configuration of a script
+func (ws *WebServer) findScript(name string) (service.ScriptConfig, bool) {
+	if ws.scriptManager != nil {
+		for _, script := range ws.scriptManager.GetConfig().Scripts {
+			if script.Name == name {
+				return script, true
+			}
+		}
+	}
+	return service.ScriptConfig{}, false
+}
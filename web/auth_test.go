@@ -0,0 +1,340 @@
+package web
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"run-script-service/service"
+)
+
+// createTestServerWithAuth returns a server whose credential store holds a user and a token
+func createTestServerWithAuth(t *testing.T, config *service.AuthConfig) (*WebServer, string) {
+	t.Helper()
+	store := service.NewAuthStore(filepath.Join(t.TempDir(), service.AuthFileName))
+	if _, err := store.AddUser("alice", "password1", ""); err != nil {
+		t.Fatal(err)
+	}
+	token, _, err := store.CreateToken("ci", service.RoleAdmin)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := createTestServerWithScripts(nil)
+	server.SetAuth(store, config)
+	return server, token
+}
+
+func TestAuthMiddleware_OpenWithoutCredentials(t *testing.T) {
+	server := createTestServerWithScripts(nil)
+	server.SetAuth(service.NewAuthStore(filepath.Join(t.TempDir(), service.AuthFileName)), nil)
+
+	req := httptest.NewRequest("GET", "/api/scripts", nil)
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected an open API without credentials, got %d", w.Code)
+	}
+}
+
+func TestAuthMiddleware(t *testing.T) {
+	server, token := createTestServerWithAuth(t, nil)
+
+	tests := []struct {
+		name      string
+		path      string
+		setup     func(r *http.Request)
+		status    int
+		challenge bool
+	}{
+		{"no credentials", "/api/scripts", func(r *http.Request) {}, http.StatusUnauthorized, true},
+		{"web interface request", "/api/scripts", func(r *http.Request) { r.Header.Set("X-Requested-With", "XMLHttpRequest") }, http.StatusUnauthorized, false},
+		{"bearer token", "/api/scripts", func(r *http.Request) { r.Header.Set("Authorization", "Bearer "+token) }, http.StatusOK, false},
+		{"wrong token", "/api/scripts", func(r *http.Request) { r.Header.Set("Authorization", "Bearer rss_0000_1111") }, http.StatusUnauthorized, true},
+		{"basic", "/api/scripts", func(r *http.Request) { r.SetBasicAuth("alice", "password1") }, http.StatusOK, false},
+		{"wrong password", "/api/scripts", func(r *http.Request) { r.SetBasicAuth("alice", "password2") }, http.StatusUnauthorized, true},
+		{"unknown session", "/api/scripts", func(r *http.Request) { r.AddCookie(&http.Cookie{Name: sessionCookieName, Value: "x"}) }, http.StatusUnauthorized, true},
+		{"websocket", "/ws", func(r *http.Request) {}, http.StatusUnauthorized, true},
+		{"login is public", "/api/auth/logout", func(r *http.Request) {}, http.StatusOK, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			method := "GET"
+			if tt.path == "/api/auth/logout" {
+				method = "POST"
+			}
+			req := httptest.NewRequest(method, tt.path, nil)
+			tt.setup(req)
+			w := httptest.NewRecorder()
+			server.router.ServeHTTP(w, req)
+
+			if w.Code != tt.status {
+				t.Fatalf("Expected status %d, got %d: %s", tt.status, w.Code, w.Body.String())
+			}
+			if got := w.Header().Get("WWW-Authenticate") != ""; got != tt.challenge {
+				t.Errorf("Expected a basic challenge %v, got %v", tt.challenge, got)
+			}
+		})
+	}
+}
+
+func TestAuthMiddleware_InvalidCredentialsMessage(t *testing.T) {
+	server, _ := createTestServerWithAuth(t, nil)
+
+	req := httptest.NewRequest("GET", "/api/scripts", nil)
+	req.SetBasicAuth("alice", "wrong-password")
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+
+	var response APIResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if response.Success || response.Error != "Invalid credentials" {
+		t.Errorf("Expected 'Invalid credentials', got %+v", response)
+	}
+}
+
+func TestAuthMiddleware_MethodsRestricted(t *testing.T) {
+	server, token := createTestServerWithAuth(t, &service.AuthConfig{Methods: []string{service.AuthMethodToken}})
+
+	req := httptest.NewRequest("GET", "/api/scripts", nil)
+	req.SetBasicAuth("alice", "password1")
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected basic authentication to be disabled, got %d", w.Code)
+	}
+	if w.Header().Get("WWW-Authenticate") != "" {
+		t.Error("Expected no basic challenge when basic authentication is disabled")
+	}
+
+	req = httptest.NewRequest("GET", "/api/scripts", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected the token to be accepted, got %d", w.Code)
+	}
+}
+
+func TestWebServer_ApplyConfig(t *testing.T) {
+	server, _ := createTestServerWithAuth(t, nil)
+	cookie := sessionCookie(t, login(server, "alice", "password1"))
+
+	send := func(setup func(r *http.Request)) int {
+		req := httptest.NewRequest("GET", "/api/scripts", nil)
+		setup(req)
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		return w.Code
+	}
+	withCookie := func(r *http.Request) { r.AddCookie(cookie) }
+	withPassword := func(r *http.Request) { r.SetBasicAuth("alice", "password1") }
+
+	// New rate limits apply; unchanged authentication keeps the session
+	limited := &service.ServiceConfig{RateLimit: &service.RateLimitConfig{RequestsPerMinute: 1}}
+	server.ApplyConfig(&service.ServiceConfig{}, limited)
+	if code := send(withCookie); code != http.StatusOK {
+		t.Fatalf("Expected the session to survive the reload, got %d", code)
+	}
+	if code := send(withCookie); code != http.StatusTooManyRequests {
+		t.Errorf("Expected the reloaded rate limit to apply, got %d", code)
+	}
+
+	tokensOnly := &service.ServiceConfig{
+		RateLimit: &service.RateLimitConfig{RequestsPerMinute: 1},
+		Auth:      &service.AuthConfig{Methods: []string{service.AuthMethodToken}},
+	}
+	server.ApplyConfig(limited, tokensOnly)
+	if code := send(withPassword); code != http.StatusUnauthorized {
+		t.Errorf("Expected basic authentication to be disabled by the reload, got %d", code)
+	}
+}
+
+// withClientCertificate marks a request as made over TLS with a verified client certificate
+func withClientCertificate(req *http.Request, commonName string) *http.Request {
+	cert := &x509.Certificate{Subject: pkix.Name{CommonName: commonName}}
+	req.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}, VerifiedChains: [][]*x509.Certificate{{cert}}}
+	return req
+}
+
+func TestAuthMiddleware_ClientCertificate(t *testing.T) {
+	server, _ := createTestServerWithAuth(t, nil)
+
+	tests := []struct {
+		name     string
+		req      *http.Request
+		expected int
+	}{
+		{"known user", withClientCertificate(httptest.NewRequest("GET", "/api/auth/me", nil), "alice"), http.StatusOK},
+		{"unknown user", withClientCertificate(httptest.NewRequest("GET", "/api/auth/me", nil), "mallory"), http.StatusUnauthorized},
+		{"unverified", httptest.NewRequest("GET", "/api/auth/me", nil), http.StatusUnauthorized},
+	}
+	tests[2].req.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{{Subject: pkix.Name{CommonName: "alice"}}}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			server.router.ServeHTTP(w, tt.req)
+			if w.Code != tt.expected {
+				t.Errorf("Expected %d, got %d: %s", tt.expected, w.Code, w.Body.String())
+			}
+			if tt.expected == http.StatusOK && !strings.Contains(w.Body.String(), `"method":"certificate"`) {
+				t.Errorf("Expected a certificate principal, got %s", w.Body.String())
+			}
+		})
+	}
+
+	server, _ = createTestServerWithAuth(t, &service.AuthConfig{Methods: []string{service.AuthMethodToken}})
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, withClientCertificate(httptest.NewRequest("GET", "/api/auth/me", nil), "alice"))
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected certificate authentication to be disabled, got %d", w.Code)
+	}
+}
+
+func TestCheckOrigin(t *testing.T) {
+	server, _ := createTestServerWithAuth(t, &service.AuthConfig{AllowedOrigins: []string{"https://ops.example.com/"}})
+
+	tests := []struct {
+		origin  string
+		allowed bool
+	}{
+		{"", true},
+		{"http://example.com:8080", true},
+		{"https://ops.example.com", true},
+		{"https://evil.example.com", false},
+		{"http://example.com:9090", false},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "http://example.com:8080/ws", nil)
+		if tt.origin != "" {
+			req.Header.Set("Origin", tt.origin)
+		}
+		if got := server.checkOrigin(req); got != tt.allowed {
+			t.Errorf("Origin %q: expected %v, got %v", tt.origin, tt.allowed, got)
+		}
+	}
+}
+
+// createRoleTokens returns a credential store with a viewer, an operator, an editor and
+// an admin token, and the tokens by name
+func createRoleTokens(t *testing.T) (*service.AuthStore, map[string]string) {
+	t.Helper()
+	store := service.NewAuthStore(filepath.Join(t.TempDir(), service.AuthFileName))
+	tokens := make(map[string]string)
+	for name, role := range map[string]string{
+		"carol": service.RoleViewer, "olive": service.RoleOperator, "eddie": service.RoleEditor, "alice": service.RoleAdmin,
+	} {
+		token, _, err := store.CreateToken(name, role)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tokens[name] = token
+	}
+	return store, tokens
+}
+
+// createTestServerWithRoles returns a server using store where the viewer carol can edit scripts tagged reports
+func createTestServerWithRoles(t *testing.T, store *service.AuthStore) *WebServer {
+	t.Helper()
+	server := createTestServerWithScripts([]service.ScriptConfig{
+		{Name: "backup", Path: "./backup.sh", Interval: 60},
+		{Name: "daily", Path: "./daily.sh", Interval: 60, Tags: []string{"reports"}},
+	})
+	server.scriptManager.GetConfig().Auth = &service.AuthConfig{Grants: []service.AuthGrant{
+		{Token: "carol", Role: service.RoleEditor, Tags: []string{"reports"}},
+	}}
+	server.SetAuth(store, nil)
+	server.SetFileManager(service.NewFileManager(t.TempDir()))
+	return server
+}
+
+func TestAuthorize(t *testing.T) {
+	store, tokens := createRoleTokens(t)
+	tests := []struct {
+		user      string
+		method    string
+		path      string
+		body      string
+		forbidden bool
+	}{
+		{"carol", "GET", "/api/scripts", "", false},
+		{"carol", "GET", "/api/logs/backup", "", false},
+		{"carol", "POST", "/api/scripts/backup/disable", "", true},
+		{"carol", "DELETE", "/api/logs/backup", "", true},
+		{"carol", "DELETE", "/api/logs/daily", "", false},
+		{"carol", "PUT", "/api/scripts/daily", `{"name": "daily", "path": "./daily.sh", "tags": ["reports"]}`, false},
+		{"carol", "PUT", "/api/scripts/daily", `{"name": "daily", "path": "./daily.sh"}`, true},
+		{"carol", "POST", "/api/scripts", `{"name": "weekly", "path": "./weekly.sh", "tags": ["reports"]}`, false},
+		{"olive", "POST", "/api/scripts/backup/disable", "", false},
+		{"olive", "POST", "/api/scripts/backup/enable", "", false},
+		{"olive", "DELETE", "/api/scripts/backup", "", true},
+		{"olive", "POST", "/api/scripts", `{"name": "hourly", "path": "./hourly.sh"}`, true},
+		{"olive", "GET", "/api/files-list/", "", true},
+		{"eddie", "DELETE", "/api/scripts/backup", "", false},
+		{"eddie", "GET", "/api/files-list/", "", false},
+		{"eddie", "PUT", "/api/files/run.sh", `{"content": "echo hi"}`, true},
+		{"eddie", "PUT", "/api/config", `{"webPort": 8081}`, true},
+		{"eddie", "GET", "/api/export", "", true},
+		{"alice", "PUT", "/api/files/run.sh", `{"content": "echo hi"}`, false},
+		{"alice", "GET", "/api/export", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.user+" "+tt.method+" "+tt.path, func(t *testing.T) {
+			server := createTestServerWithRoles(t, store)
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Authorization", "Bearer "+tokens[tt.user])
+			w := httptest.NewRecorder()
+			server.router.ServeHTTP(w, req)
+
+			if forbidden := w.Code == http.StatusForbidden; forbidden != tt.forbidden {
+				t.Errorf("Expected forbidden %v, got %d: %s", tt.forbidden, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestAuthorize_Reason(t *testing.T) {
+	store, tokens := createRoleTokens(t)
+	server := createTestServerWithRoles(t, store)
+
+	req := httptest.NewRequest("POST", "/api/scripts/backup/run", nil)
+	req.Header.Set("Authorization", "Bearer "+tokens["carol"])
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+
+	var response APIResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	expected := "Forbidden: running script 'backup' requires the operator role, carol has the viewer role"
+	if w.Code != http.StatusForbidden || response.Error != expected {
+		t.Errorf("Expected 403 %q, got %d %q", expected, w.Code, response.Error)
+	}
+}
+
+func TestAuthorize_UserRole(t *testing.T) {
+	server, _ := createTestServerWithAuth(t, nil)
+	if err := server.getAuth().store.SetUserRole("alice", service.RoleViewer); err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest("DELETE", "/api/logs/backup", nil)
+	req.SetBasicAuth("alice", "password1")
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Expected the user's role to apply, got %d", w.Code)
+	}
+}
@@ -0,0 +1,152 @@
+// Package web provides the error model of the HTTP API
+package web
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"run-script-service/service"
+)
+
+// ErrorCode classifies a failed request, so clients need not parse messages
+type ErrorCode string
+
+// Error codes of failed requests, each answered with the status of errorStatus
+const (
+	ErrorBadRequest       ErrorCode = "bad_request"       // malformed body or parameters
+	ErrorValidationFailed ErrorCode = "validation_failed" // well-formed but invalid, details name the fields
+	ErrorUnauthorized     ErrorCode = "unauthorized"      // missing or invalid credentials
+	ErrorForbidden        ErrorCode = "forbidden"         // the caller's role or the path is not allowed
+	ErrorNotFound         ErrorCode = "not_found"
+	ErrorConflict         ErrorCode = "conflict" // e.g. a script name that is taken
+	ErrorScriptFailed     ErrorCode = "script_failed"
+	ErrorTooLarge         ErrorCode = "payload_too_large" // the request body exceeds max_body_bytes or max_upload_bytes
+	ErrorRateLimited      ErrorCode = "rate_limited"      // the client exceeded its rate, retry after Retry-After seconds
+	ErrorUnavailable      ErrorCode = "unavailable"       // the service is shutting down or the run queue is full
+	ErrorInternal         ErrorCode = "internal"
+)
+
+// errorStatus is the HTTP status of each error code
+var errorStatus = map[ErrorCode]int{
+	ErrorBadRequest:       http.StatusBadRequest,
+	ErrorValidationFailed: http.StatusBadRequest,
+	ErrorUnauthorized:     http.StatusUnauthorized,
+	ErrorForbidden:        http.StatusForbidden,
+	ErrorNotFound:         http.StatusNotFound,
+	ErrorConflict:         http.StatusConflict,
+	ErrorScriptFailed:     http.StatusUnprocessableEntity,
+	ErrorTooLarge:         http.StatusRequestEntityTooLarge,
+	ErrorRateLimited:      http.StatusTooManyRequests,
+	ErrorUnavailable:      http.StatusServiceUnavailable,
+	ErrorInternal:         http.StatusInternalServerError,
+}
+
+// errorCodes lists the codes in documentation order
+var errorCodes = []ErrorCode{
+	ErrorBadRequest, ErrorValidationFailed, ErrorUnauthorized, ErrorForbidden, ErrorNotFound,
+	ErrorConflict, ErrorScriptFailed, ErrorTooLarge, ErrorRateLimited, ErrorUnavailable, ErrorInternal,
+}
+
+// Status returns the HTTP status of the code
+func (code ErrorCode) Status() int {
+	if status, ok := errorStatus[code]; ok {
+		return status
+	}
+	return http.StatusInternalServerError
+}
+
+// respondError aborts the request with a failed APIResponse carrying code, the request ID
+// and, for validation failures, the problems of the request fields
+func respondError(c *gin.Context, code ErrorCode, message string, details ...service.ConfigIssue) {
+	c.AbortWithStatusJSON(code.Status(), APIResponse{
+		Success:   false,
+		Error:     message,
+		Code:      code,
+		Details:   details,
+		RequestID: requestID(c),
+	})
+}
+
+// respondServiceError aborts the request with the message of err, classified by errorCode
+func respondServiceError(c *gin.Context, err error, fallback ErrorCode) {
+	var validationErr *service.ConfigValidationError
+	if errors.As(err, &validationErr) {
+		respondError(c, ErrorValidationFailed, err.Error(), validationErr.Issues...)
+		return
+	}
+	respondError(c, errorCode(err, fallback), err.Error())
+}
+
+// errorCode classifies the sentinel errors of the service package, and returns fallback
+// for errors it does not recognise
+func errorCode(err error, fallback ErrorCode) ErrorCode {
+	var validationErr *service.ConfigValidationError
+	switch {
+	case errors.Is(err, service.ErrScriptNotFound),
+		errors.Is(err, service.ErrConfigVersionNotFound),
+		errors.Is(err, fs.ErrNotExist):
+		return ErrorNotFound
+	case errors.Is(err, service.ErrScriptExists):
+		return ErrorConflict
+	case errors.Is(err, service.ErrPathNotAllowed):
+		return ErrorForbidden
+	case errors.Is(err, service.ErrShuttingDown), errors.Is(err, service.ErrQueueFull):
+		return ErrorUnavailable
+	case errors.As(err, &validationErr):
+		return ErrorValidationFailed
+	}
+	return fallback
+}
+
+// bindJSON decodes the JSON request body into obj. Otherwise it responds 400, naming the
+// field for type mismatches, or 413 for a body over the limit, and returns false.
+func bindJSON(c *gin.Context, obj interface{}) bool {
+	err := c.ShouldBindJSON(obj)
+	if err == nil {
+		return true
+	}
+	if respondTooLarge(c, err) {
+		return false
+	}
+	message := fmt.Sprintf("Invalid request body: %v", err)
+	if issue := service.DecodeIssue(err); issue.Path != "$" {
+		respondError(c, ErrorValidationFailed, message, issue)
+	} else {
+		respondError(c, ErrorBadRequest, message)
+	}
+	return false
+}
+
+// readBody reads the request body. Otherwise it responds 400, or 413 for a body over the
+// limit, and returns false.
+func readBody(c *gin.Context) ([]byte, bool) {
+	body, err := io.ReadAll(c.Request.Body)
+	if err == nil {
+		return body, true
+	}
+	if !respondTooLarge(c, err) {
+		respondError(c, ErrorBadRequest, fmt.Sprintf("Failed to read request body: %v", err))
+	}
+	return nil, false
+}
+
+// respondTooLarge responds 413 and returns true when err reports a body over the limit
+func respondTooLarge(c *gin.Context, err error) bool {
+	var tooLarge *http.MaxBytesError
+	if !errors.As(err, &tooLarge) {
+		return false
+	}
+	respondError(c, ErrorTooLarge, fmt.Sprintf("Request body exceeds the limit of %d bytes", tooLarge.Limit))
+	return true
+}
+
+// requiredField returns the issue of a missing request field, path being its JSON path
+func requiredField(path string) service.ConfigIssue {
+	return service.ConfigIssue{Path: path, Message: strings.TrimPrefix(path, "$.") + " is required"}
+}
@@ -0,0 +1,183 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"run-script-service/service"
+)
+
+// decodeError returns the error response of w
+func decodeError(t *testing.T, w *httptest.ResponseRecorder) APIResponse {
+	t.Helper()
+	var response APIResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if response.Success {
+		t.Errorf("Expected a failed response, got %s", w.Body.String())
+	}
+	return response
+}
+
+func TestErrorCode_Status(t *testing.T) {
+	for _, code := range errorCodes {
+		if _, ok := errorStatus[code]; !ok {
+			t.Errorf("Error code %s has no status", code)
+		}
+	}
+	if len(errorCodes) != len(errorStatus) {
+		t.Errorf("Expected %d documented codes, got %d", len(errorStatus), len(errorCodes))
+	}
+	if status := ErrorCode("unknown").Status(); status != http.StatusInternalServerError {
+		t.Errorf("Expected 500 for an unknown code, got %d", status)
+	}
+}
+
+func TestErrorCode_ServiceErrors(t *testing.T) {
+	tests := []struct {
+		err      error
+		expected ErrorCode
+	}{
+		{fmt.Errorf("script x %w", service.ErrScriptNotFound), ErrorNotFound},
+		{fmt.Errorf("config version 3 %w", service.ErrConfigVersionNotFound), ErrorNotFound},
+		{fmt.Errorf("failed to read: %w", os.ErrNotExist), ErrorNotFound},
+		{fmt.Errorf("script with name x %w", service.ErrScriptExists), ErrorConflict},
+		{service.ErrPathNotAllowed, ErrorForbidden},
+		{service.ErrShuttingDown, ErrorUnavailable},
+		{service.ErrQueueFull, ErrorUnavailable},
+		{&service.ConfigValidationError{Issues: []service.ConfigIssue{{Path: "$.web_port", Message: "bad"}}}, ErrorValidationFailed},
+		{errors.New("disk full"), ErrorInternal},
+	}
+	for _, tt := range tests {
+		if got := errorCode(tt.err, ErrorInternal); got != tt.expected {
+			t.Errorf("errorCode(%v) = %s, expected %s", tt.err, got, tt.expected)
+		}
+	}
+}
+
+func TestRespondError_RequestID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(requestIDMiddleware())
+	router.GET("/", func(c *gin.Context) {
+		respondError(c, ErrorConflict, "taken", service.ConfigIssue{Path: "$.name", Message: "taken"})
+	})
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(RequestIDHeader, "trace-1")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusConflict {
+		t.Errorf("Expected status 409, got %d", w.Code)
+	}
+	response := decodeError(t, w)
+	if response.Code != ErrorConflict || response.Error != "taken" || response.RequestID != "trace-1" {
+		t.Errorf("Unexpected response %+v", response)
+	}
+	if len(response.Details) != 1 || response.Details[0].Path != "$.name" {
+		t.Errorf("Expected the details, got %+v", response.Details)
+	}
+}
+
+func TestWebServer_ValidationErrors(t *testing.T) {
+	server := createTestServerWithScripts([]service.ScriptConfig{
+		{Name: "backup", Path: "./backup.sh", Interval: 60},
+	})
+
+	tests := []struct {
+		name    string
+		method  string
+		path    string
+		body    string
+		status  int
+		code    ErrorCode
+		details []string
+	}{
+		{"malformed body", "POST", "/api/v1/scripts", `{"name":`, http.StatusBadRequest, ErrorBadRequest, nil},
+		{"wrong type", "POST", "/api/v1/scripts", `{"name":"x","path":"./x.sh","interval":"often"}`,
+			http.StatusBadRequest, ErrorValidationFailed, []string{"$.interval"}},
+		{"missing fields", "POST", "/api/v1/scripts", `{}`,
+			http.StatusBadRequest, ErrorValidationFailed, []string{"$.name", "$.path"}},
+		{"invalid field", "POST", "/api/v1/scripts", `{"name":"x","path":"./x.sh","timeout":-1}`,
+			http.StatusBadRequest, ErrorValidationFailed, []string{"$.timeout"}},
+		{"duplicate name", "POST", "/api/v1/scripts", `{"name":"backup","path":"./other.sh"}`,
+			http.StatusConflict, ErrorConflict, nil},
+		{"invalid update", "PUT", "/api/v1/scripts/backup", `{"path":"./backup.sh","timeout":-5}`,
+			http.StatusBadRequest, ErrorValidationFailed, []string{"$.timeout"}},
+		{"unknown script", "PUT", "/api/v1/scripts/missing", `{"path":"./x.sh"}`,
+			http.StatusNotFound, ErrorNotFound, nil},
+		{"invalid port", "PUT", "/api/v1/config", `{"web_port":70000}`,
+			http.StatusBadRequest, ErrorValidationFailed, []string{"$.web_port"}},
+		{"port type", "PUT", "/api/v1/config", `{"webPort":"http"}`,
+			http.StatusBadRequest, ErrorValidationFailed, []string{"$.webPort"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			server.router.ServeHTTP(w, req)
+
+			if w.Code != tt.status {
+				t.Fatalf("Expected status %d, got %d: %s", tt.status, w.Code, w.Body.String())
+			}
+			response := decodeError(t, w)
+			if response.Code != tt.code {
+				t.Errorf("Expected code %s, got %q", tt.code, response.Code)
+			}
+			if response.RequestID == "" || response.RequestID != w.Header().Get(RequestIDHeader) {
+				t.Errorf("Expected the request ID of the header, got %q", response.RequestID)
+			}
+			var paths []string
+			for _, issue := range response.Details {
+				paths = append(paths, issue.Path)
+			}
+			if strings.Join(paths, ",") != strings.Join(tt.details, ",") {
+				t.Errorf("Expected details %v, got %+v", tt.details, response.Details)
+			}
+		})
+	}
+}
+
+func TestWebServer_FileErrorCodes(t *testing.T) {
+	server := createTestServerWithScripts(nil)
+	server.SetFileManager(service.NewFileManager(t.TempDir()))
+
+	tests := []struct {
+		method string
+		path   string
+		body   string
+		status int
+		code   ErrorCode
+	}{
+		{"GET", "/api/v1/files/missing.sh", "", http.StatusNotFound, ErrorNotFound},
+		{"GET", "/api/v1/files-list/missing", "", http.StatusNotFound, ErrorNotFound},
+		{"GET", "/api/v1/files/../../etc/passwd", "", http.StatusForbidden, ErrorForbidden},
+		{"POST", "/api/v1/files/validate", `{"content":""}`, http.StatusBadRequest, ErrorValidationFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			server.router.ServeHTTP(w, req)
+
+			if w.Code != tt.status {
+				t.Fatalf("Expected status %d, got %d: %s", tt.status, w.Code, w.Body.String())
+			}
+			if response := decodeError(t, w); response.Code != tt.code {
+				t.Errorf("Expected code %s, got %q", tt.code, response.Code)
+			}
+		})
+	}
+}
@@ -14,7 +14,8 @@ type EventBridge struct {
 
 // NewEventBridge creates a bridge between service events and WebSocket hub
 func NewEventBridge(wsHub *WebSocketHub, eventBroadcaster *service.EventBroadcaster) *EventBridge {
-	events := make(chan *service.ScriptStatusEvent, 100)
+	// Room for bursts of output lines, so status events are not dropped behind them
+	events := make(chan *service.ScriptStatusEvent, 1000)
 	unsubscribe := eventBroadcaster.Subscribe(events)
 
 	bridge := &EventBridge{
@@ -33,6 +34,17 @@ func NewEventBridge(wsHub *WebSocketHub, eventBroadcaster *service.EventBroadcas
 // processEvents processes script status events and broadcasts them via WebSocket
 func (eb *EventBridge) processEvents() {
 	for event := range eb.events {
+		if event.Status == service.StatusOutput {
+			data := map[string]interface{}{
+				"script_name": event.ScriptName,
+				"stream":      event.Stream,
+				"line":        event.Line,
+				"timestamp":   event.Timestamp.Format("2006-01-02T15:04:05Z07:00"),
+			}
+			_ = eb.wsHub.BroadcastMessage("script_output", data)
+			continue
+		}
+
 		// Convert service event to WebSocket message format
 		data := map[string]interface{}{
 			"script_name": event.ScriptName,
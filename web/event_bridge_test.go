@@ -135,3 +135,26 @@ func TestEventBridge_Close(t *testing.T) {
 		// Expected - no message received
 	}
 }
+
+func TestEventBridge_OutputEvents(t *testing.T) {
+	wsHub := NewWebSocketHub()
+	eventBroadcaster := service.NewEventBroadcaster()
+
+	bridge := NewEventBridge(wsHub, eventBroadcaster)
+	defer bridge.Close()
+
+	eventBroadcaster.Broadcast(service.NewScriptOutputEvent("test.sh", service.StreamStderr, "disk almost full"))
+
+	select {
+	case message := <-wsHub.broadcast:
+		var wsMessage WebSocketMessage
+		require.NoError(t, json.Unmarshal(message, &wsMessage))
+
+		assert.Equal(t, "script_output", wsMessage.Type)
+		assert.Equal(t, "test.sh", wsMessage.Data["script_name"])
+		assert.Equal(t, "stderr", wsMessage.Data["stream"])
+		assert.Equal(t, "disk almost full", wsMessage.Data["line"])
+	case <-time.After(200 * time.Millisecond):
+		t.Fatal("Expected WebSocket message to be received")
+	}
+}
@@ -0,0 +1,44 @@
+import { ref, type Ref } from 'vue'
+import { ApiService } from '@/services/api'
+import type { QueueStatus } from '@/types/api'
+
+export function useRunQueue() {
+  const queue: Ref<QueueStatus | null> = ref(null)
+  const error = ref<string | null>(null)
+
+  let intervalId: number | null = null
+
+  const fetchQueue = async (): Promise<void> => {
+    error.value = null
+
+    try {
+      queue.value = await ApiService.getQueue()
+    } catch (err) {
+      error.value = err instanceof Error ? err.message : 'Failed to fetch run queue'
+      console.error('Failed to fetch run queue:', err)
+    }
+  }
+
+  const startAutoRefresh = (intervalMs: number = 5000): void => {
+    stopAutoRefresh()
+    fetchQueue()
+    intervalId = window.setInterval(() => {
+      fetchQueue()
+    }, intervalMs)
+  }
+
+  const stopAutoRefresh = (): void => {
+    if (intervalId !== null) {
+      clearInterval(intervalId)
+      intervalId = null
+    }
+  }
+
+  return {
+    queue: queue as Readonly<Ref<QueueStatus | null>>,
+    error: error as Readonly<Ref<string | null>>,
+    fetchQueue,
+    startAutoRefresh,
+    cleanup: stopAutoRefresh
+  }
+}
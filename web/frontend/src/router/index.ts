@@ -34,6 +34,14 @@ const routes: RouteRecordRaw[] = [
       title: 'Settings'
     }
   },
+  {
+    path: '/login',
+    name: 'Login',
+    component: () => import('@/views/Login.vue'),
+    meta: {
+      title: 'Sign in'
+    }
+  },
   {
     path: '/:pathMatch(.*)*',
     redirect: '/'
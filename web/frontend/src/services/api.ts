@@ -1,18 +1,42 @@
-import type { ScriptConfig, LogEntry, SystemMetrics, ServiceConfig, ApiResponse } from '@/types/api'
+import type { ScriptConfig, LogEntry, SystemMetrics, ServiceConfig, ApiResponse, Principal, ErrorCode, FieldIssue, RunStatus, QueueStatus } from '@/types/api'
+
+// ApiError carries the error code, invalid fields and request ID of a failed request
+export class ApiError extends Error {
+  constructor(
+    message: string,
+    readonly status: number,
+    readonly code?: ErrorCode,
+    readonly details: FieldIssue[] = [],
+    readonly requestId?: string,
+  ) {
+    super(message)
+    this.name = 'ApiError'
+  }
+}
 
 export class ApiService {
-  private static readonly BASE_URL = '/api'
+  private static readonly BASE_URL = '/api/v1'
 
   private static async request<T>(endpoint: string, options?: RequestInit): Promise<T> {
     const response = await fetch(`${this.BASE_URL}${endpoint}`, {
+      ...options,
       headers: {
         'Content-Type': 'application/json',
+        // Marks requests from the web interface so the browser shows the login page, not its own prompt
+        'X-Requested-With': 'XMLHttpRequest',
         ...options?.headers,
       },
-      ...options,
     })
 
+    if (response.status === 401 && !endpoint.startsWith('/auth/') && window.location.pathname !== '/login') {
+      window.location.assign(`/login?redirect=${encodeURIComponent(window.location.pathname)}`)
+    }
+
     if (!response.ok) {
+      const body = await response.json().catch(() => null) as ApiResponse | null
+      if (body?.error) {
+        throw new ApiError(body.error, response.status, body.code, body.details, body.request_id)
+      }
       throw new Error(`API request failed: ${response.status} ${response.statusText}`)
     }
 
@@ -25,6 +49,21 @@ export class ApiService {
     return result.data as T
   }
 
+  static async login(username: string, password: string): Promise<Principal> {
+    return this.request<Principal>('/auth/login', {
+      method: 'POST',
+      body: JSON.stringify({ username, password }),
+    })
+  }
+
+  static async logout(): Promise<void> {
+    await this.request('/auth/logout', { method: 'POST' })
+  }
+
+  static async getPrincipal(): Promise<Principal | null> {
+    return this.request<Principal | null>('/auth/me')
+  }
+
   static async getScripts(): Promise<ScriptConfig[]> {
     return this.request<ScriptConfig[]>('/scripts')
   }
@@ -49,12 +88,20 @@ export class ApiService {
     })
   }
 
-  static async runScript(name: string): Promise<void> {
-    await this.request(`/scripts/${encodeURIComponent(name)}/run`, {
+  static async runScript(name: string): Promise<RunStatus> {
+    return this.request<RunStatus>(`/scripts/${encodeURIComponent(name)}/run`, {
       method: 'POST',
     })
   }
 
+  static async getRun(id: string): Promise<RunStatus> {
+    return this.request<RunStatus>(`/runs/${encodeURIComponent(id)}`)
+  }
+
+  static async getQueue(): Promise<QueueStatus> {
+    return this.request<QueueStatus>('/queue')
+  }
+
   static async getLogs(scriptName?: string, limit: number = 50): Promise<LogEntry[]> {
     const params = new URLSearchParams()
     if (scriptName) params.set('script', scriptName)
@@ -4,7 +4,10 @@ export interface ScriptConfig {
   interval: number
   enabled: boolean
   timeout?: number
-  status?: 'running' | 'completed' | 'failed' | 'idle'
+  tags?: string[]
+  pools?: string[]
+  priority?: number
+  status?: 'waiting' | 'running' | 'completed' | 'warning' | 'failed' | 'idle'
 }
 
 export interface LogEntry {
@@ -12,6 +15,16 @@ export interface LogEntry {
   message: string
   level: 'info' | 'warning' | 'error'
   script?: string
+  exit_code?: number
+  duration_ms?: number
+  stdout?: string
+  stderr?: string
+  trigger?: string
+  run_id?: string
+  result?: Record<string, unknown>
+  outcome?: 'success' | 'warning' | 'failure'
+  outcome_reason?: string
+  artifacts?: number
 }
 
 export interface SystemMetrics {
@@ -31,8 +44,75 @@ export interface ServiceConfig {
   autoRefresh: boolean
 }
 
+export interface Principal {
+  name: string
+  method: 'token' | 'basic' | 'session' | 'certificate'
+  role: 'viewer' | 'operator' | 'editor' | 'admin'
+}
+
+export interface RunStatus {
+  id: string
+  script: string
+  state: 'queued' | 'waiting' | 'running' | 'succeeded' | 'failed'
+  priority?: number
+  position?: number
+  queued_at: string
+  started_at?: string
+  finished_at?: string
+  error?: string
+}
+
+export interface QueueStatus {
+  workers: number
+  capacity: number
+  depth: number
+  running: RunStatus[]
+  pending: RunStatus[]
+  max_concurrent_runs: number
+  active: number
+  pools: PoolStatus[]
+  waiting: WaitingRun[]
+}
+
+export interface PoolStatus {
+  name: string
+  size: number
+  in_use: number
+}
+
+export interface WaitingRun {
+  run_id: string
+  script: string
+  trigger: 'schedule' | 'manual'
+  priority?: number
+  pools?: string[]
+  since: string
+  position: number
+}
+
+export type ErrorCode =
+  | 'bad_request'
+  | 'validation_failed'
+  | 'unauthorized'
+  | 'forbidden'
+  | 'not_found'
+  | 'conflict'
+  | 'script_failed'
+  | 'payload_too_large'
+  | 'rate_limited'
+  | 'unavailable'
+  | 'internal'
+
+export interface FieldIssue {
+  path: string
+  message: string
+}
+
 export interface ApiResponse<T = any> {
   success: boolean
   data?: T
   error?: string
+  code?: ErrorCode
+  details?: FieldIssue[]
+  request_id?: string
 }
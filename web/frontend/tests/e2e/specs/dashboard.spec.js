@@ -61,11 +61,11 @@ test.describe('Dashboard Page', () => {
     let scriptsApiCalled = false;
 
     page.on('response', response => {
-      if (response.url().includes('/api/status')) {
+      if (response.url().includes('/api/v1/status')) {
         statusApiCalled = true;
         expect(response.status()).toBe(200);
       }
-      if (response.url().includes('/api/scripts')) {
+      if (response.url().includes('/api/v1/scripts')) {
         scriptsApiCalled = true;
         expect(response.status()).toBe(200);
       }
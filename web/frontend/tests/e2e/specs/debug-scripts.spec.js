@@ -18,7 +18,7 @@ test.describe('Scripts Page - Debug', () => {
     // Check for scripts API call
     let apiResponse = null;
     page.on('response', async response => {
-      if (response.url().includes('/api/scripts')) {
+      if (response.url().includes('/api/v1/scripts')) {
         apiResponse = await response.json();
         console.log('API Response:', JSON.stringify(apiResponse, null, 2));
       }
@@ -71,7 +71,7 @@ test.describe('Scripts Page', () => {
     let apiResponseData = null;
 
     page.on('response', async response => {
-      if (response.url().includes('/api/scripts')) {
+      if (response.url().includes('/api/v1/scripts')) {
         apiCallSuccessful = true;
         expect(response.status()).toBe(200);
         try {
@@ -35,7 +35,7 @@ test.describe('Settings Page', () => {
     let configData = null;
 
     page.on('response', async response => {
-      if (response.url().includes('/api/config')) {
+      if (response.url().includes('/api/v1/config')) {
         configApiCalled = true;
         expect(response.status()).toBe(200);
         try {
@@ -58,7 +58,7 @@ test.describe('Settings Page', () => {
     // Wait for configuration to load - be more flexible with response waiting
     try {
       await page.waitForResponse(response =>
-        response.url().includes('/api/config') && response.status() === 200,
+        response.url().includes('/api/v1/config') && response.status() === 200,
         { timeout: 5000 }
       );
     } catch (error) {
@@ -157,7 +157,7 @@ test.describe('Settings Page', () => {
     let saveApiCalled = false;
 
     page.on('response', response => {
-      if (response.url().includes('/api/config') && response.request().method() === 'PUT') {
+      if (response.url().includes('/api/v1/config') && response.request().method() === 'PUT') {
         saveApiCalled = true;
         expect(response.status()).toBe(200);
       }
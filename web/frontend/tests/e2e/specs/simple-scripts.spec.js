@@ -23,14 +23,14 @@ test.describe('Scripts Page - Simple Tests', () => {
 
     // Listen for API requests
     page.on('request', request => {
-      if (request.url().includes('/api/scripts')) {
+      if (request.url().includes('/api/v1/scripts')) {
         console.log('API request detected:', request.url());
         apiCallMade = true;
       }
     });
 
     page.on('response', response => {
-      if (response.url().includes('/api/scripts')) {
+      if (response.url().includes('/api/v1/scripts')) {
         console.log('API response received:', response.status(), response.url());
       }
     });
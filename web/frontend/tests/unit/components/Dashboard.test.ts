@@ -7,6 +7,7 @@ import { ref } from 'vue'
 // Mock composables with proper reactive refs
 const mockSystemMetrics = vi.fn()
 const mockScripts = vi.fn()
+const mockRunQueue = vi.fn()
 
 vi.mock('@/composables/useSystemMetrics', () => ({
   useSystemMetrics: () => mockSystemMetrics()
@@ -16,6 +17,10 @@ vi.mock('@/composables/useScripts', () => ({
   useScripts: () => mockScripts()
 }))
 
+vi.mock('@/composables/useRunQueue', () => ({
+  useRunQueue: () => mockRunQueue()
+}))
+
 describe('Dashboard Component', () => {
   let router: any
 
@@ -46,6 +51,13 @@ describe('Dashboard Component', () => {
       fetchScripts: vi.fn(),
       runScript: vi.fn()
     })
+    mockRunQueue.mockReturnValue({
+      queue: ref(null),
+      error: ref(null),
+      fetchQueue: vi.fn(),
+      startAutoRefresh: vi.fn(),
+      cleanup: vi.fn()
+    })
   })
 
   afterEach(() => {
@@ -211,4 +223,48 @@ describe('Dashboard Component', () => {
     expect(scriptsSection.find('.error').exists()).toBe(true)
     expect(scriptsSection.text()).toContain('Failed to load scripts')
   })
+
+  it('should show runs waiting for a slot with their position', () => {
+    mockRunQueue.mockReturnValue({
+      queue: ref({
+        workers: 4, capacity: 100, depth: 0, running: [], pending: [],
+        max_concurrent_runs: 2, active: 2,
+        pools: [{ name: 'db', size: 1, in_use: 1 }],
+        waiting: [
+          { run_id: 'a', script: 'urgent', trigger: 'manual', priority: 10, since: '', position: 1 },
+          { run_id: 'b', script: 'backup', trigger: 'schedule', pools: ['db'], since: '', position: 2 }
+        ]
+      }),
+      error: ref(null),
+      fetchQueue: vi.fn(),
+      startAutoRefresh: vi.fn(),
+      cleanup: vi.fn()
+    })
+
+    const wrapper = mount(Dashboard, {
+      global: {
+        plugins: [router]
+      }
+    })
+
+    expect(wrapper.find('[data-testid="run-slots"]').text()).toBe('2 running of 2')
+    expect(wrapper.find('.pool.full').text()).toContain('db: 1/1')
+    const runs = wrapper.findAll('[data-testid="waiting-run"]')
+    expect(runs).toHaveLength(2)
+    expect(runs[0].text()).toContain('#1')
+    expect(runs[0].text()).toContain('urgent')
+    expect(runs[0].text()).toContain('priority 10')
+    expect(runs[1].text()).toContain('#2')
+    expect(runs[1].text()).toContain('db')
+  })
+
+  it('should hide the run queue without limits or waiting runs', () => {
+    const wrapper = mount(Dashboard, {
+      global: {
+        plugins: [router]
+      }
+    })
+
+    expect(wrapper.find('[data-testid="run-queue"]').exists()).toBe(false)
+  })
 })
@@ -1,5 +1,5 @@
 import { describe, it, expect, vi, beforeEach } from 'vitest'
-import { ApiService } from '@/services/api'
+import { ApiService, ApiError } from '@/services/api'
 
 // Mock fetch globally
 const mockFetch = vi.fn()
@@ -111,4 +111,26 @@ describe('ApiService - Real Backend Data', () => {
 
     await expect(ApiService.getScripts()).rejects.toThrow('API request failed: 500 Internal Server Error')
   })
+
+  it('should expose the code and details of API errors', async () => {
+    mockFetch.mockResolvedValueOnce({
+      ok: false,
+      status: 400,
+      statusText: 'Bad Request',
+      json: async () => ({
+        success: false,
+        error: 'Script name is required',
+        code: 'validation_failed',
+        details: [{ path: '$.name', message: 'name is required' }],
+        request_id: 'abc123'
+      })
+    })
+
+    const error = await ApiService.getScripts().catch((e) => e)
+    expect(error).toBeInstanceOf(ApiError)
+    expect(error.message).toBe('Script name is required')
+    expect(error.code).toBe('validation_failed')
+    expect(error.details).toEqual([{ path: '$.name', message: 'name is required' }])
+    expect(error.requestId).toBe('abc123')
+  })
 })
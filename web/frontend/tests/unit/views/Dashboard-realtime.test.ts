@@ -55,6 +55,7 @@ class MockWebSocket {
 const mockWebSocket = vi.fn()
 const mockSystemMetrics = vi.fn()
 const mockScripts = vi.fn()
+const mockRunQueue = vi.fn()
 
 vi.mock('@/composables/useWebSocket', () => ({
   useWebSocket: () => mockWebSocket()
@@ -68,6 +69,10 @@ vi.mock('@/composables/useScripts', () => ({
   useScripts: () => mockScripts()
 }))
 
+vi.mock('@/composables/useRunQueue', () => ({
+  useRunQueue: () => mockRunQueue()
+}))
+
 describe('Dashboard Real-time Updates', () => {
   let router: any
   let mockWS: MockWebSocket
@@ -130,6 +135,13 @@ describe('Dashboard Real-time Updates', () => {
       fetchScripts: vi.fn(),
       runScript: vi.fn()
     })
+    mockRunQueue.mockReturnValue({
+      queue: ref(null),
+      error: ref(null),
+      fetchQueue: vi.fn(),
+      startAutoRefresh: vi.fn(),
+      cleanup: vi.fn()
+    })
   })
 
   afterEach(() => {
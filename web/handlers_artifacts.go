@@ -0,0 +1,73 @@
+// Package web provides the run artifact handlers for the HTTP API server
+package web
+
+import (
+	"net/http"
+	"os"
+	"path"
+
+	"github.com/gin-gonic/gin"
+
+	"run-script-service/service"
+)
+
+// artifactStore returns the store of run artifacts, writing an error response when there is none
+func (ws *WebServer) artifactStore(c *gin.Context) *service.ArtifactStore {
+	if ws.scriptManager == nil {
+		respondError(c, ErrorInternal, "Script manager not initialized")
+		return nil
+	}
+	store := ws.scriptManager.GetArtifactStore()
+	if store == nil {
+		respondError(c, ErrorNotFound, "Artifacts are not enabled")
+	}
+	return store
+}
+
+// artifactError writes the response for a failed artifact lookup
+func artifactError(c *gin.Context, err error) {
+	if os.IsNotExist(err) {
+		respondError(c, ErrorNotFound, "Artifact not found")
+		return
+	}
+	respondError(c, ErrorBadRequest, err.Error())
+}
+
+// handleListArtifacts returns the manifest of the files kept with a run
+func (ws *WebServer) handleListArtifacts(c *gin.Context) {
+	store := ws.artifactStore(c)
+	if store == nil {
+		return
+	}
+
+	manifest, err := store.Manifest(c.Param("id"))
+	if os.IsNotExist(err) {
+		respondError(c, ErrorNotFound, "Run has no artifacts")
+		return
+	}
+	if err != nil {
+		artifactError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, APIResponse{
+		Success: true,
+		Data:    manifest,
+	})
+}
+
+// handleDownloadArtifact serves one artifact of a run as an attachment
+func (ws *WebServer) handleDownloadArtifact(c *gin.Context) {
+	store := ws.artifactStore(c)
+	if store == nil {
+		return
+	}
+
+	artifactPath := c.Param("path")
+	file, err := store.Path(c.Param("id"), artifactPath)
+	if err != nil {
+		artifactError(c, err)
+		return
+	}
+	c.FileAttachment(file, path.Base(artifactPath))
+}
@@ -0,0 +1,106 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"run-script-service/service"
+)
+
+// createArtifactServer returns a server whose store holds report.html and data/rows.csv for one run
+func createArtifactServer(t *testing.T) (*WebServer, string) {
+	t.Helper()
+	workDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(workDir, "data"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(workDir, "report.html"), []byte("<html/>"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(workDir, "data", "rows.csv"), []byte("a,b\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	server := createTestServerWithScripts([]service.ScriptConfig{{Name: "report", Path: "./report.sh", Interval: 60}})
+	server.scriptManager.SetArtifactDir(t.TempDir())
+	entry := &service.LogEntry{RunID: "0123456789abcdef", ScriptName: "report", Timestamp: time.Now()}
+	if _, err := server.scriptManager.GetArtifactStore().Collect(entry, workDir, []string{"*.html", "data/*"}, service.ArtifactPolicy{}); err != nil {
+		t.Fatal(err)
+	}
+	return server, entry.RunID
+}
+
+func TestWebServer_ListArtifacts(t *testing.T) {
+	server, runID := createArtifactServer(t)
+
+	req := httptest.NewRequest("GET", "/api/runs/"+runID+"/artifacts", nil)
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var response struct {
+		Success bool                     `json:"success"`
+		Data    service.ArtifactManifest `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if len(response.Data.Files) != 2 || response.Data.Files[0].Path != "data/rows.csv" || response.Data.ScriptName != "report" {
+		t.Errorf("Unexpected manifest: %+v", response.Data)
+	}
+
+	for path, status := range map[string]int{
+		"/api/runs/fedcba9876543210/artifacts": http.StatusNotFound,
+		"/api/runs/not-a-run/artifacts":        http.StatusBadRequest,
+	} {
+		req := httptest.NewRequest("GET", path, nil)
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		if w.Code != status {
+			t.Errorf("%s: expected status %d, got %d", path, status, w.Code)
+		}
+	}
+}
+
+func TestWebServer_DownloadArtifact(t *testing.T) {
+	server, runID := createArtifactServer(t)
+
+	req := httptest.NewRequest("GET", "/api/runs/"+runID+"/artifacts/data/rows.csv", nil)
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if w.Body.String() != "a,b\n" {
+		t.Errorf("Expected the artifact contents, got %q", w.Body.String())
+	}
+	if got := w.Header().Get("Content-Disposition"); got != `attachment; filename="rows.csv"` {
+		t.Errorf("Expected an attachment, got %q", got)
+	}
+
+	for _, path := range []string{"/missing.txt", "/../manifest.json", "/data"} {
+		req := httptest.NewRequest("GET", "/api/runs/"+runID+"/artifacts"+path, nil)
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		if w.Code != http.StatusNotFound {
+			t.Errorf("%s: expected status 404, got %d", path, w.Code)
+		}
+	}
+}
+
+func TestWebServer_Artifacts_NotEnabled(t *testing.T) {
+	server := createTestServerWithScripts(nil)
+
+	req := httptest.NewRequest("GET", "/api/runs/0123456789abcdef/artifacts", nil)
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	assertNotFoundResponse(t, w)
+}
@@ -0,0 +1,109 @@
+// Package web provides the audit log handlers for the HTTP API server
+package web
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"run-script-service/service"
+)
+
+// anonymousActor is recorded for changes made while authentication is off
+const anonymousActor = "anonymous"
+
+// SetAuditLog records every change made through the API in log
+func (ws *WebServer) SetAuditLog(log *service.AuditLog) {
+	ws.auditLog = log
+}
+
+// recordAudit records a change by the caller of a request. before and after are the
+// states compared for the diff, nil when the target did not exist before or after.
+// A failure to record is logged; the change itself has already been made.
+func (ws *WebServer) recordAudit(c *gin.Context, action, target, detail string, before, after interface{}) {
+	if ws.auditLog == nil {
+		return
+	}
+	actor := anonymousActor
+	if principal := PrincipalFrom(c); principal != nil {
+		actor = principal.Name
+	}
+	err := ws.auditLog.Record(service.AuditEntry{
+		Actor:    actor,
+		Source:   service.AuditSourceAPI,
+		RemoteIP: c.RemoteIP(), // forwarded headers are not trusted, any client can set them
+		Action:   action,
+		Target:   target,
+		Detail:   detail,
+		Diff:     service.AuditDiff(before, after),
+	})
+	if err != nil {
+		service.Warnf("Failed to record %s of %s by %s: %v", action, target, actor, err)
+	}
+}
+
+// configSnapshot returns a copy of the current configuration for recordAudit
+func (ws *WebServer) configSnapshot() []byte {
+	return service.AuditSnapshot(ws.scriptManager.GetConfig())
+}
+
+// handleGetAudit returns audit entries, newest first.
+// Query parameters: actor, action (or a prefix such as "script."), target, since, until and limit (default 100).
+func (ws *WebServer) handleGetAudit(c *gin.Context) {
+	if !ws.authorize(c, service.RoleAdmin, "reading the audit log") {
+		return
+	}
+	if ws.auditLog == nil {
+		respondError(c, ErrorInternal, "Audit log not initialized")
+		return
+	}
+
+	filter, err := parseAuditFilter(c, time.Now())
+	if err != nil {
+		respondError(c, ErrorBadRequest, err.Error())
+		return
+	}
+
+	entries, err := ws.auditLog.Query(filter)
+	if err != nil {
+		respondError(c, ErrorInternal, err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, APIResponse{
+		Success: true,
+		Data:    entries,
+	})
+}
+
+// parseAuditFilter reads the audit query parameters
+func parseAuditFilter(c *gin.Context, now time.Time) (service.AuditFilter, error) {
+	filter := service.AuditFilter{
+		Actor:  c.Query("actor"),
+		Action: c.Query("action"),
+		Target: c.Query("target"),
+		Limit:  100,
+	}
+	if value := c.Query("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 0 {
+			return filter, fmt.Errorf("invalid limit: %s", value)
+		}
+		filter.Limit = limit
+	}
+
+	var err error
+	if value := c.Query("since"); value != "" {
+		if filter.Since, err = service.ParseLogTime(value, now); err != nil {
+			return filter, err
+		}
+	}
+	if value := c.Query("until"); value != "" {
+		if filter.Until, err = service.ParseLogTime(value, now); err != nil {
+			return filter, err
+		}
+	}
+	return filter, nil
+}
@@ -0,0 +1,135 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"run-script-service/service"
+)
+
+// getAudit requests /api/audit and decodes the entries
+func getAudit(t *testing.T, server *WebServer, query, token string) ([]service.AuditEntry, *httptest.ResponseRecorder) {
+	t.Helper()
+	req := httptest.NewRequest("GET", "/api/audit"+query, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+
+	var response struct {
+		Data []service.AuditEntry `json:"data"`
+	}
+	if w.Code == http.StatusOK {
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Failed to unmarshal response: %v", err)
+		}
+	}
+	return response.Data, w
+}
+
+func TestHandleGetAudit_RecordsChanges(t *testing.T) {
+	server := createTestServerWithScripts([]service.ScriptConfig{
+		{Name: "backup", Path: "./backup.sh", Interval: 60, Enabled: true},
+	})
+	server.SetAuditLog(service.NewAuditLog(filepath.Join(t.TempDir(), service.AuditFileName)))
+	server.SetFileManager(service.NewFileManager(t.TempDir()))
+
+	requests := []struct {
+		method, path, body string
+	}{
+		{"POST", "/api/scripts/backup/disable", ""},
+		{"PUT", "/api/scripts/backup", `{"path": "./backup.sh", "interval": 120}`},
+		{"PUT", "/api/files/backup.sh", `{"content": "echo hi"}`},
+		{"DELETE", "/api/scripts/backup", ""},
+	}
+	for _, r := range requests {
+		req := httptest.NewRequest(r.method, r.path, strings.NewReader(r.body))
+		req.Header.Set("Content-Type", "application/json")
+		req.RemoteAddr = "192.0.2.10:4711"
+		// Forwarded headers come from the client and must not be recorded
+		req.Header.Set("X-Forwarded-For", "203.0.113.99")
+		req.Header.Set("X-Real-IP", "203.0.113.98")
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s %s: expected status 200, got %d: %s", r.method, r.path, w.Code, w.Body.String())
+		}
+	}
+
+	entries, w := getAudit(t, server, "", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	expected := []string{service.AuditScriptDelete, service.AuditFileWrite, service.AuditScriptUpdate, service.AuditScriptDisable}
+	if len(entries) != len(expected) {
+		t.Fatalf("Expected %d entries, got %+v", len(expected), entries)
+	}
+	for i, action := range expected {
+		entry := entries[i]
+		if entry.Action != action || entry.Actor != anonymousActor || entry.Source != service.AuditSourceAPI || entry.RemoteIP != "192.0.2.10" {
+			t.Errorf("Entry %d: unexpected %+v", i, entry)
+		}
+	}
+
+	update := entries[2]
+	if update.Target != "backup" || !containsDiffLine(update.Diff, "+", `"interval": 120`) || !containsDiffLine(update.Diff, "-", `"interval": 60`) {
+		t.Errorf("Expected the interval change in the diff, got %+v", update.Diff)
+	}
+	if deleted := entries[0]; !containsDiffLine(deleted.Diff, "-", `"name": "backup"`) {
+		t.Errorf("Expected the deleted configuration in the diff, got %+v", deleted.Diff)
+	}
+	if write := entries[1]; write.Target != "backup.sh" || !containsDiffLine(write.Diff, "+", "echo hi") {
+		t.Errorf("Expected the file content in the diff, got %+v", write)
+	}
+
+	if entries, _ := getAudit(t, server, "?action=script.&limit=2", ""); len(entries) != 2 || entries[1].Action != service.AuditScriptUpdate {
+		t.Errorf("Expected the newest script change, got %+v", entries)
+	}
+	if _, w := getAudit(t, server, "?limit=x", ""); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected an invalid limit to be rejected, got %d", w.Code)
+	}
+}
+
+func TestHandleGetAudit_Actor(t *testing.T) {
+	store, tokens := createRoleTokens(t)
+	server := createTestServerWithRoles(t, store)
+	server.SetAuditLog(service.NewAuditLog(filepath.Join(t.TempDir(), service.AuditFileName)))
+
+	req := httptest.NewRequest("POST", "/api/scripts/backup/disable", nil)
+	req.Header.Set("Authorization", "Bearer "+tokens["olive"])
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	if _, w := getAudit(t, server, "", tokens["eddie"]); w.Code != http.StatusForbidden {
+		t.Errorf("Expected the audit log to be for admins only, got %d", w.Code)
+	}
+	entries, w := getAudit(t, server, "?actor=olive", tokens["alice"])
+	if w.Code != http.StatusOK || len(entries) != 1 || entries[0].Action != service.AuditScriptDisable {
+		t.Errorf("Expected olive's change, got %d %+v", w.Code, entries)
+	}
+}
+
+func TestHandleGetAudit_NotInitialized(t *testing.T) {
+	server := createTestServerWithScripts(nil)
+	if _, w := getAudit(t, server, "", ""); w.Code != http.StatusInternalServerError {
+		t.Errorf("Expected status 500, got %d", w.Code)
+	}
+}
+
+// containsDiffLine reports whether diff has a line with op containing text
+func containsDiffLine(diff []service.DiffLine, op, text string) bool {
+	for _, line := range diff {
+		if line.Op == op && strings.Contains(line.Text, text) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,77 @@
+// Package web provides the login handlers for the HTTP API server
+package web
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LoginRequest is the body of POST /api/auth/login
+type LoginRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// setupAuthRoutes configures the session endpoints of the web interface
+func (ws *WebServer) setupAuthRoutes(api *gin.RouterGroup) {
+	api.POST("/auth/login", ws.handleLogin)
+	api.POST("/auth/logout", ws.handleLogout)
+	api.GET("/auth/me", ws.handleGetPrincipal)
+}
+
+// handleLogin checks a user's password and starts a session with an HttpOnly cookie
+func (ws *WebServer) handleLogin(c *gin.Context) {
+	auth := ws.getAuth()
+	if auth == nil || auth.sessions == nil {
+		respondError(c, ErrorNotFound, "Session login is not enabled")
+		return
+	}
+
+	var req LoginRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	if req.Username == "" {
+		respondError(c, ErrorValidationFailed, "Username and password are required", requiredField("$.username"))
+		return
+	}
+	if !auth.store.CheckPassword(req.Username, req.Password) {
+		respondError(c, ErrorUnauthorized, "Invalid username or password")
+		return
+	}
+
+	id, err := auth.sessions.create(req.Username)
+	if err != nil {
+		respondError(c, ErrorInternal, err.Error())
+		return
+	}
+	c.SetSameSite(http.SameSiteStrictMode)
+	c.SetCookie(sessionCookieName, id, int(auth.sessions.ttl.Seconds()), "/", "", c.Request.TLS != nil, true)
+	c.JSON(http.StatusOK, APIResponse{
+		Success: true,
+		Data:    Principal{Name: req.Username, Method: auth.sessions.Method()},
+	})
+}
+
+// handleLogout ends the session of the request, if any, and clears the cookie
+func (ws *WebServer) handleLogout(c *gin.Context) {
+	if auth := ws.getAuth(); auth != nil && auth.sessions != nil {
+		if cookie, err := c.Cookie(sessionCookieName); err == nil {
+			auth.sessions.end(cookie)
+		}
+	}
+	c.SetSameSite(http.SameSiteStrictMode)
+	c.SetCookie(sessionCookieName, "", -1, "/", "", c.Request.TLS != nil, true)
+	c.JSON(http.StatusOK, APIResponse{
+		Success: true,
+	})
+}
+
+// handleGetPrincipal returns the authenticated caller, or null when authentication is off
+func (ws *WebServer) handleGetPrincipal(c *gin.Context) {
+	c.JSON(http.StatusOK, APIResponse{
+		Success: true,
+		Data:    PrincipalFrom(c),
+	})
+}
@@ -0,0 +1,185 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"run-script-service/service"
+)
+
+// login posts credentials to /api/auth/login
+func login(server *WebServer, username, password string) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(LoginRequest{Username: username, Password: password})
+	req := httptest.NewRequest("POST", "/api/auth/login", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	return w
+}
+
+// sessionCookie returns the session cookie set by a response
+func sessionCookie(t *testing.T, w *httptest.ResponseRecorder) *http.Cookie {
+	t.Helper()
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.Name == sessionCookieName {
+			return cookie
+		}
+	}
+	t.Fatal("Expected a session cookie")
+	return nil
+}
+
+func TestHandleLogin(t *testing.T) {
+	server, _ := createTestServerWithAuth(t, nil)
+
+	if w := login(server, "alice", "wrong-password"); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected a wrong password to be rejected, got %d", w.Code)
+	}
+	if w := login(server, "", ""); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected missing credentials to be rejected, got %d", w.Code)
+	}
+
+	w := login(server, "alice", "password1")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	cookie := sessionCookie(t, w)
+	if !cookie.HttpOnly || cookie.SameSite != http.SameSiteStrictMode {
+		t.Errorf("Expected an HttpOnly SameSite=Strict cookie, got %+v", cookie)
+	}
+
+	req := httptest.NewRequest("GET", "/api/auth/me", nil)
+	req.AddCookie(cookie)
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected the session to authenticate, got %d", w.Code)
+	}
+	var response struct {
+		Data Principal `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if response.Data.Name != "alice" || response.Data.Method != service.AuthMethodSession || response.Data.Role != service.RoleAdmin {
+		t.Errorf("Expected alice as admin by session, got %+v", response.Data)
+	}
+
+	req = httptest.NewRequest("POST", "/api/auth/logout", nil)
+	req.Header.Set("X-Requested-With", "XMLHttpRequest")
+	req.AddCookie(cookie)
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected logout to succeed, got %d", w.Code)
+	}
+
+	req = httptest.NewRequest("GET", "/api/auth/me", nil)
+	req.AddCookie(cookie)
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected the session to end at logout, got %d", w.Code)
+	}
+}
+
+func TestSessionWritesRequireTrustedOrigin(t *testing.T) {
+	server, token := createTestServerWithAuth(t, &service.AuthConfig{AllowedOrigins: []string{"https://ops.example.com"}})
+	cookie := sessionCookie(t, login(server, "alice", "password1"))
+
+	tests := []struct {
+		name   string
+		method string
+		setup  func(r *http.Request)
+		status int
+	}{
+		{"read without origin", "GET", func(r *http.Request) {}, http.StatusOK},
+		{"read from an allowed origin", "GET", func(r *http.Request) { r.Header.Set("Origin", "https://ops.example.com") }, http.StatusOK},
+		{"write from another site", "POST", func(r *http.Request) { r.Header.Set("Origin", "https://evil.example") }, http.StatusForbidden},
+		{"write without origin", "POST", func(r *http.Request) {}, http.StatusForbidden},
+		{"write from the web interface", "POST", func(r *http.Request) { r.Header.Set("X-Requested-With", "XMLHttpRequest") }, http.StatusNotFound},
+		{"write from the same origin", "POST", func(r *http.Request) { r.Header.Set("Origin", "http://example.com") }, http.StatusNotFound},
+		{"write from an allowed origin", "POST", func(r *http.Request) { r.Header.Set("Origin", "https://ops.example.com") }, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := "/api/v1/scripts"
+			if tt.method == "POST" {
+				path = "/api/v1/scripts/missing/enable"
+			}
+			req := httptest.NewRequest(tt.method, path, nil)
+			req.AddCookie(cookie)
+			tt.setup(req)
+			w := httptest.NewRecorder()
+			server.router.ServeHTTP(w, req)
+			if w.Code != tt.status {
+				t.Errorf("Expected status %d, got %d: %s", tt.status, w.Code, w.Body.String())
+			}
+		})
+	}
+
+	// Tokens are not sent by browsers on their own, so they need no origin
+	req := httptest.NewRequest("POST", "/api/v1/scripts/missing/enable", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected a token write without origin to reach the handler, got %d", w.Code)
+	}
+}
+
+func TestCORS_AllowedOrigins(t *testing.T) {
+	server, _ := createTestServerWithAuth(t, &service.AuthConfig{AllowedOrigins: []string{"https://ops.example.com/"}})
+
+	preflight := func(origin string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("OPTIONS", "/api/v1/scripts", nil)
+		req.Header.Set("Origin", origin)
+		req.Header.Set("Access-Control-Request-Method", "POST")
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		return w
+	}
+	if w := preflight("https://ops.example.com"); w.Header().Get("Access-Control-Allow-Origin") != "https://ops.example.com" {
+		t.Errorf("Expected an allowed origin to pass the preflight, got %d %v", w.Code, w.Header())
+	}
+	if w := preflight("https://evil.example"); w.Code != http.StatusForbidden || w.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Errorf("Expected other origins to be refused, got %d %v", w.Code, w.Header())
+	}
+}
+
+func TestHandleLogin_SessionEndsWhenUserRemoved(t *testing.T) {
+	server, _ := createTestServerWithAuth(t, nil)
+	cookie := sessionCookie(t, login(server, "alice", "password1"))
+
+	if err := server.getAuth().store.RemoveUser("alice"); err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest("GET", "/api/scripts", nil)
+	req.AddCookie(cookie)
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected the session of a removed user to be rejected, got %d", w.Code)
+	}
+}
+
+func TestHandleLogin_SessionsDisabled(t *testing.T) {
+	server, _ := createTestServerWithAuth(t, &service.AuthConfig{Methods: []string{service.AuthMethodToken}})
+	assertNotFoundResponse(t, login(server, "alice", "password1"))
+}
+
+func TestHandleGetPrincipal_AuthenticationOff(t *testing.T) {
+	server := createTestServerWithScripts(nil)
+
+	req := httptest.NewRequest("GET", "/api/auth/me", nil)
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK || !bytes.Contains(w.Body.Bytes(), []byte(`"data":null`)) {
+		t.Errorf("Expected a null principal, got %d: %s", w.Code, w.Body.String())
+	}
+}
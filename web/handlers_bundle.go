@@ -0,0 +1,108 @@
+// Package web provides script import/export handlers for the HTTP API server
+package web
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"run-script-service/service"
+)
+
+// handleExport bundles scripts and their files for download.
+// Query parameters: scripts (comma separated, default all) and format (json or tar.gz).
+func (ws *WebServer) handleExport(c *gin.Context) {
+	if ws.scriptManager == nil {
+		respondError(c, ErrorInternal, "Script manager not initialized")
+		return
+	}
+	if !ws.authorize(c, service.RoleAdmin, "exporting scripts") {
+		return
+	}
+
+	format, err := service.ParseBundleFormat(c.DefaultQuery("format", "json"))
+	if err != nil {
+		respondError(c, ErrorBadRequest, err.Error())
+		return
+	}
+
+	var names []string
+	if scripts := c.Query("scripts"); scripts != "" {
+		for _, name := range strings.Split(scripts, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, name)
+			}
+		}
+	}
+
+	bundle, err := ws.scriptManager.ExportBundle(names)
+	if err != nil {
+		respondServiceError(c, err, ErrorInternal)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := service.EncodeBundle(&buf, bundle, format); err != nil {
+		respondError(c, ErrorInternal, fmt.Sprintf("Failed to encode bundle: %v", err))
+		return
+	}
+
+	contentType := "application/json"
+	if format == service.BundleTarGz {
+		contentType = "application/gzip"
+	}
+	fileName := fmt.Sprintf("scripts-%s.%s", bundle.CreatedAt.Format("20060102-150405"), format)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fileName))
+	c.Data(http.StatusOK, contentType, buf.Bytes())
+}
+
+// handleImport imports a JSON or tar.gz bundle from the request body.
+// Query parameters: conflict (skip, rename or overwrite) and dry_run.
+func (ws *WebServer) handleImport(c *gin.Context) {
+	if ws.scriptManager == nil {
+		respondError(c, ErrorInternal, "Script manager not initialized")
+		return
+	}
+	if !ws.authorize(c, service.RoleAdmin, "importing scripts") {
+		return
+	}
+
+	policy, err := service.ParseConflictPolicy(c.DefaultQuery("conflict", string(service.ConflictSkip)))
+	if err != nil {
+		respondError(c, ErrorBadRequest, err.Error())
+		return
+	}
+
+	body, ok := readBody(c)
+	if !ok {
+		return
+	}
+
+	bundle, err := service.DecodeBundle(body)
+	if err != nil {
+		respondError(c, ErrorBadRequest, err.Error())
+		return
+	}
+
+	opts := service.ImportOptions{
+		Conflict: policy,
+		DryRun:   c.Query("dry_run") == "true",
+	}
+	before := ws.configSnapshot()
+	result, err := ws.scriptManager.ImportBundle(bundle, opts)
+	if err != nil {
+		respondError(c, ErrorValidationFailed, err.Error())
+		return
+	}
+	if !opts.DryRun && result.Changed() {
+		ws.recordAudit(c, service.AuditConfigImport, "bundle", result.Summary(), before, ws.configSnapshot())
+	}
+
+	c.JSON(http.StatusOK, APIResponse{
+		Success: true,
+		Data:    result,
+	})
+}
@@ -0,0 +1,118 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"run-script-service/service"
+)
+
+// createBundleTestServer returns a server with one script backed by a real file
+func createBundleTestServer(t *testing.T) *WebServer {
+	t.Helper()
+	scriptPath := filepath.Join(t.TempDir(), "backup.sh")
+	if err := os.WriteFile(scriptPath, []byte("#!/bin/bash\necho backup\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return createTestServerWithScripts([]service.ScriptConfig{
+		{Name: "backup", Path: scriptPath, Interval: 60, Enabled: true, MaxLogLines: 100},
+	})
+}
+
+func TestWebServer_Export(t *testing.T) {
+	server := createBundleTestServer(t)
+
+	for _, format := range []string{"json", "tar.gz"} {
+		req := httptest.NewRequest("GET", "/api/export?scripts=backup&format="+format, nil)
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: expected status 200, got %d: %s", format, w.Code, w.Body.String())
+		}
+		if !strings.Contains(w.Header().Get("Content-Disposition"), "attachment") {
+			t.Errorf("%s: expected attachment disposition", format)
+		}
+
+		bundle, err := service.DecodeBundle(w.Body.Bytes())
+		if err != nil {
+			t.Fatalf("%s: failed to decode exported bundle: %v", format, err)
+		}
+		if len(bundle.Scripts) != 1 || !strings.Contains(bundle.Scripts[0].Content, "echo backup") {
+			t.Errorf("%s: unexpected bundle: %+v", format, bundle)
+		}
+	}
+
+	req := httptest.NewRequest("GET", "/api/export?scripts=missing", nil)
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	assertNotFoundResponse(t, w)
+
+	req = httptest.NewRequest("GET", "/api/export?format=zip", nil)
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for unknown format, got %d", w.Code)
+	}
+}
+
+func TestWebServer_ImportDryRun(t *testing.T) {
+	server := createBundleTestServer(t)
+
+	bundle, err := server.scriptManager.ExportBundle(nil)
+	if err != nil {
+		t.Fatalf("Failed to export: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := service.EncodeBundle(&buf, bundle, service.BundleJSON); err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest("POST", "/api/import?conflict=rename&dry_run=true", &buf)
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+
+	assertSuccessResponse(t, w)
+
+	var response struct {
+		Data service.ImportResult `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if !response.Data.DryRun || len(response.Data.Actions) != 1 {
+		t.Fatalf("Unexpected result: %+v", response.Data)
+	}
+	if action := response.Data.Actions[0]; action.Action != service.ImportRename || action.Name != "backup-2" {
+		t.Errorf("Expected rename to backup-2, got %+v", action)
+	}
+	if len(server.scriptManager.GetConfig().Scripts) != 1 {
+		t.Error("Dry run must not change the configuration")
+	}
+}
+
+func TestWebServer_ImportInvalid(t *testing.T) {
+	server := createTestServerWithScripts(nil)
+
+	tests := []struct {
+		url  string
+		body string
+	}{
+		{"/api/import?conflict=merge", `{"version": 1, "scripts": []}`},
+		{"/api/import", `not a bundle`},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("POST", tt.url, strings.NewReader(tt.body))
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", tt.url, w.Code)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+// Package web provides configuration handlers for the HTTP API server
+package web
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"run-script-service/service"
+)
+
+// ConfigValidationResponse represents the result of validating a configuration
+type ConfigValidationResponse struct {
+	Valid  bool                  `json:"valid"`
+	Issues []service.ConfigIssue `json:"issues"`
+}
+
+// handleValidateConfig validates a configuration document.
+// The request body is validated when present, otherwise the config file on disk is checked.
+func (ws *WebServer) handleValidateConfig(c *gin.Context) {
+	body, ok := readBody(c)
+	if !ok {
+		return
+	}
+
+	opts := service.ValidationOptions{CheckFiles: c.DefaultQuery("check_files", "true") != "false"}
+
+	var issues []service.ConfigIssue
+	if len(body) > 0 {
+		issues = service.ValidateServiceConfigData(body, opts)
+	} else {
+		if ws.scriptManager == nil || ws.scriptManager.GetConfigPath() == "" {
+			respondError(c, ErrorBadRequest, "No configuration provided and no config file available")
+			return
+		}
+		var err error
+		issues, err = service.ValidateServiceConfigFile(ws.scriptManager.GetConfigPath(), opts)
+		if err != nil {
+			respondError(c, ErrorInternal, err.Error())
+			return
+		}
+	}
+
+	if issues == nil {
+		issues = make([]service.ConfigIssue, 0)
+	}
+
+	c.JSON(http.StatusOK, APIResponse{
+		Success: true,
+		Data: ConfigValidationResponse{
+			Valid:  len(issues) == 0,
+			Issues: issues,
+		},
+	})
+}
+
+// handleGetConfigSchema publishes the JSON Schema for service_config.json
+func (ws *WebServer) handleGetConfigSchema(c *gin.Context) {
+	c.Header("Content-Type", "application/schema+json")
+	c.JSON(http.StatusOK, service.ConfigSchema())
+}
+
+// configHistory returns the script manager's config history or writes an error response
+func (ws *WebServer) configHistory(c *gin.Context) *service.ConfigHistory {
+	if ws.scriptManager == nil {
+		respondError(c, ErrorInternal, "Script manager not initialized")
+		return nil
+	}
+
+	history, err := ws.scriptManager.GetConfigHistory()
+	if err != nil {
+		respondError(c, ErrorInternal, err.Error())
+		return nil
+	}
+	return history
+}
+
+// versionParam parses a positive version number from a path or query value
+func versionParam(c *gin.Context, value, name string) (int, bool) {
+	version, err := strconv.Atoi(value)
+	if err != nil || version < 1 {
+		respondError(c, ErrorBadRequest, fmt.Sprintf("Invalid %s: %q", name, value))
+		return 0, false
+	}
+	return version, true
+}
+
+// handleGetConfigHistory lists recorded configuration versions
+func (ws *WebServer) handleGetConfigHistory(c *gin.Context) {
+	history := ws.configHistory(c)
+	if history == nil {
+		return
+	}
+
+	versions, err := history.List()
+	if err != nil {
+		respondError(c, ErrorInternal, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, APIResponse{
+		Success: true,
+		Data:    versions,
+	})
+}
+
+// handleGetConfigVersion returns the content of a recorded configuration version
+func (ws *WebServer) handleGetConfigVersion(c *gin.Context) {
+	history := ws.configHistory(c)
+	if history == nil {
+		return
+	}
+
+	version, ok := versionParam(c, c.Param("version"), "version")
+	if !ok {
+		return
+	}
+
+	data, meta, err := history.Get(version)
+	if err != nil {
+		respondServiceError(c, err, ErrorInternal)
+		return
+	}
+
+	c.JSON(http.StatusOK, APIResponse{
+		Success: true,
+		Data: map[string]interface{}{
+			"version": meta,
+			"content": string(data),
+		},
+	})
+}
+
+// handleDiffConfigVersions returns a line diff between two recorded versions
+func (ws *WebServer) handleDiffConfigVersions(c *gin.Context) {
+	history := ws.configHistory(c)
+	if history == nil {
+		return
+	}
+
+	from, ok := versionParam(c, c.Query("from"), "from")
+	if !ok {
+		return
+	}
+	to, ok := versionParam(c, c.Query("to"), "to")
+	if !ok {
+		return
+	}
+
+	diff, err := history.Diff(from, to)
+	if err != nil {
+		respondServiceError(c, err, ErrorInternal)
+		return
+	}
+
+	c.JSON(http.StatusOK, APIResponse{
+		Success: true,
+		Data: map[string]interface{}{
+			"from":    from,
+			"to":      to,
+			"changed": service.HasChanges(diff),
+			"lines":   diff,
+			"unified": service.FormatDiff(fmt.Sprintf("v%d", from), fmt.Sprintf("v%d", to), diff, 3),
+		},
+	})
+}
+
+// handleRollbackConfig restores a recorded version and reloads it into the running service
+func (ws *WebServer) handleRollbackConfig(c *gin.Context) {
+	if !ws.authorize(c, service.RoleAdmin, "rolling back the configuration") {
+		return
+	}
+	history := ws.configHistory(c)
+	if history == nil {
+		return
+	}
+
+	version, ok := versionParam(c, c.Param("version"), "version")
+	if !ok {
+		return
+	}
+
+	before := ws.configSnapshot()
+	restored, err := history.Rollback(version)
+	if err != nil {
+		respondServiceError(c, err, ErrorInternal)
+		return
+	}
+
+	if err := ws.scriptManager.ReloadConfig(ws.scriptManager.Context()); err != nil {
+		respondError(c, ErrorInternal, fmt.Sprintf("Configuration restored but reload failed: %v", err))
+		return
+	}
+	ws.recordAudit(c, service.AuditConfigRollback, fmt.Sprintf("version %d", version), "", before, ws.configSnapshot())
+
+	c.JSON(http.StatusOK, APIResponse{
+		Success: true,
+		Data: map[string]interface{}{
+			"message":  fmt.Sprintf("Configuration rolled back to version %d", version),
+			"restored": restored,
+		},
+	})
+}
@@ -0,0 +1,172 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"run-script-service/service"
+)
+
+func TestWebServer_ValidateConfig(t *testing.T) {
+	server := createTestServerWithScripts(nil)
+
+	body := `{"scripts": [{"name": "a", "path": "./a.sh"}, {"name": "a", "path": "./a.sh"}], "web_port": 0, "extra": 1}`
+	req := httptest.NewRequest("POST", "/api/config/validate?check_files=false", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+
+	assertSuccessResponse(t, w)
+
+	var response struct {
+		Data ConfigValidationResponse `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+
+	if response.Data.Valid {
+		t.Error("Expected config to be invalid")
+	}
+	if len(response.Data.Issues) != 2 {
+		t.Fatalf("Expected 2 issues, got %+v", response.Data.Issues)
+	}
+	if response.Data.Issues[0].Path != "$.extra" || response.Data.Issues[1].Path != "$.scripts[1].name" {
+		t.Errorf("Unexpected issues: %+v", response.Data.Issues)
+	}
+}
+
+func TestWebServer_ValidateConfig_FromDisk(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(configPath, []byte(`{"scripts": [], "web_port": 8080}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	config := &service.ServiceConfig{}
+	server := NewWebServer(8080)
+	server.SetScriptManager(service.NewScriptManagerWithPath(config, configPath))
+
+	req := httptest.NewRequest("POST", "/api/config/validate", nil)
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+
+	assertSuccessResponse(t, w)
+	if !strings.Contains(w.Body.String(), `"valid":true`) {
+		t.Errorf("Expected valid config, got %s", w.Body.String())
+	}
+}
+
+func TestWebServer_ConfigSchema(t *testing.T) {
+	server := createTestServerWithScripts(nil)
+
+	req := httptest.NewRequest("GET", "/api/config/schema", nil)
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/schema+json") {
+		t.Errorf("Expected schema content type, got %s", ct)
+	}
+
+	var schema map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &schema); err != nil {
+		t.Fatalf("Failed to unmarshal schema: %v", err)
+	}
+	if schema["$id"] != service.ConfigSchemaID {
+		t.Errorf("Unexpected schema id: %v", schema["$id"])
+	}
+}
+
+func TestWebServer_ConfigHistory(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.json")
+
+	config := &service.ServiceConfig{
+		Scripts: []service.ScriptConfig{createTestScript("a", false)},
+		WebPort: 8080,
+	}
+	scriptManager := service.NewScriptManagerWithPath(config, configPath)
+	if err := scriptManager.SaveConfig(); err != nil {
+		t.Fatalf("Failed to save config: %v", err)
+	}
+	config.WebPort = 9090
+	if err := scriptManager.SaveConfig(); err != nil {
+		t.Fatalf("Failed to save config: %v", err)
+	}
+
+	server := NewWebServer(8080)
+	server.SetScriptManager(scriptManager)
+
+	t.Run("list", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/config/history", nil)
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+
+		assertSuccessResponse(t, w)
+		var response struct {
+			Data []service.ConfigVersion `json:"data"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Failed to unmarshal response: %v", err)
+		}
+		if len(response.Data) != 2 || response.Data[1].Origin != service.OriginAPI {
+			t.Errorf("Unexpected history: %+v", response.Data)
+		}
+	})
+
+	t.Run("get version", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/config/history/1", nil)
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+
+		assertSuccessResponse(t, w)
+		if !strings.Contains(w.Body.String(), `\"web_port\": 8080`) {
+			t.Errorf("Expected version 1 content, got %s", w.Body.String())
+		}
+	})
+
+	t.Run("diff", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/config/history/diff?from=1&to=2", nil)
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+
+		assertSuccessResponse(t, w)
+		if !strings.Contains(w.Body.String(), `"changed":true`) {
+			t.Errorf("Expected changed diff, got %s", w.Body.String())
+		}
+	})
+
+	t.Run("diff invalid version", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/config/history/diff?from=a&to=2", nil)
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400, got %d", w.Code)
+		}
+	})
+
+	t.Run("rollback", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/api/config/history/1/rollback", nil)
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+
+		assertSuccessResponse(t, w)
+		if scriptManager.GetConfig().WebPort != 8080 {
+			t.Errorf("Expected reloaded web port 8080, got %d", scriptManager.GetConfig().WebPort)
+		}
+	})
+
+	t.Run("rollback unknown version", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/api/config/history/99/rollback", nil)
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+
+		assertNotFoundResponse(t, w)
+	})
+}
@@ -4,7 +4,6 @@ package web
 import (
 	"fmt"
 	"net/http"
-	"strings"
 
 	"github.com/gin-gonic/gin"
 
@@ -19,7 +18,7 @@ type FileOperationRequest struct {
 
 // ValidationRequest represents a script validation request
 type ValidationRequest struct {
-	Content string `json:"content" binding:"required"`
+	Content string `json:"content"`
 }
 
 // ValidationResponse represents validation results
@@ -33,8 +32,9 @@ func (ws *WebServer) SetFileManager(fm *service.FileManager) {
 	ws.fileManager = fm
 
 	// Setup file routes now that file manager is available
-	api := ws.router.Group("/api")
-	ws.setupFileRoutes(api)
+	for _, api := range ws.apiGroups() {
+		ws.setupFileRoutes(api)
+	}
 }
 
 // setupFileRoutes configures file operation API routes
@@ -53,20 +53,14 @@ func (ws *WebServer) setupFileRoutes(api *gin.RouterGroup) {
 // handleGetFile reads and returns a file's content
 func (ws *WebServer) handleGetFile(c *gin.Context) {
 	if ws.fileManager == nil {
-		c.JSON(http.StatusInternalServerError, APIResponse{
-			Success: false,
-			Error:   "File manager not initialized",
-		})
+		respondError(c, ErrorInternal, "File manager not initialized")
 		return
 	}
 
 	// Extract path from URL parameter
 	filePath := c.Param("path")
 	if filePath == "" {
-		c.JSON(http.StatusBadRequest, APIResponse{
-			Success: false,
-			Error:   "File path is required",
-		})
+		respondError(c, ErrorBadRequest, "File path is required")
 		return
 	}
 
@@ -75,19 +69,13 @@ func (ws *WebServer) handleGetFile(c *gin.Context) {
 		filePath = filePath[1:]
 	}
 
+	if !ws.authorize(c, service.RoleEditor, fmt.Sprintf("reading file '%s'", filePath)) {
+		return
+	}
+
 	fileContent, err := ws.fileManager.ReadFile(filePath)
 	if err != nil {
-		statusCode := http.StatusInternalServerError
-		if err.Error() == "access denied: path not allowed" {
-			statusCode = http.StatusForbidden
-		} else if strings.Contains(err.Error(), "no such file or directory") {
-			statusCode = http.StatusNotFound
-		}
-
-		c.JSON(statusCode, APIResponse{
-			Success: false,
-			Error:   err.Error(),
-		})
+		respondServiceError(c, err, ErrorInternal)
 		return
 	}
 
@@ -100,20 +88,14 @@ func (ws *WebServer) handleGetFile(c *gin.Context) {
 // handlePutFile writes content to a file
 func (ws *WebServer) handlePutFile(c *gin.Context) {
 	if ws.fileManager == nil {
-		c.JSON(http.StatusInternalServerError, APIResponse{
-			Success: false,
-			Error:   "File manager not initialized",
-		})
+		respondError(c, ErrorInternal, "File manager not initialized")
 		return
 	}
 
 	// Extract path from URL parameter
 	filePath := c.Param("path")
 	if filePath == "" {
-		c.JSON(http.StatusBadRequest, APIResponse{
-			Success: false,
-			Error:   "File path is required",
-		})
+		respondError(c, ErrorBadRequest, "File path is required")
 		return
 	}
 
@@ -122,32 +104,30 @@ func (ws *WebServer) handlePutFile(c *gin.Context) {
 		filePath = filePath[1:]
 	}
 
+	if !ws.authorize(c, service.RoleAdmin, fmt.Sprintf("writing file '%s'", filePath)) {
+		return
+	}
+
 	var request FileOperationRequest
-	if err := c.ShouldBindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, APIResponse{
-			Success: false,
-			Error:   fmt.Sprintf("Invalid request body: %v", err),
-		})
+	if !bindJSON(c, &request) {
 		return
 	}
 
 	// Use content from request body
 	content := request.Content
 
+	var before interface{}
+	if existing, readErr := ws.fileManager.ReadFile(filePath); readErr == nil {
+		before = existing.Content
+	}
 	err := ws.fileManager.WriteFile(filePath, content)
 	if err != nil {
-		statusCode := http.StatusInternalServerError
-		if err.Error() == "access denied: path not allowed" {
-			statusCode = http.StatusForbidden
-		}
-
-		c.JSON(statusCode, APIResponse{
-			Success: false,
-			Error:   err.Error(),
-		})
+		respondServiceError(c, err, ErrorInternal)
 		return
 	}
 
+	ws.recordAudit(c, service.AuditFileWrite, filePath, "", before, content)
+
 	c.JSON(http.StatusOK, APIResponse{
 		Success: true,
 		Data: map[string]interface{}{
@@ -160,19 +140,19 @@ func (ws *WebServer) handlePutFile(c *gin.Context) {
 // handleValidateFile validates script syntax
 func (ws *WebServer) handleValidateFile(c *gin.Context) {
 	if ws.fileManager == nil {
-		c.JSON(http.StatusInternalServerError, APIResponse{
-			Success: false,
-			Error:   "File manager not initialized",
-		})
+		respondError(c, ErrorInternal, "File manager not initialized")
+		return
+	}
+	if !ws.authorize(c, service.RoleEditor, "validating scripts") {
 		return
 	}
 
 	var request ValidationRequest
-	if err := c.ShouldBindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, APIResponse{
-			Success: false,
-			Error:   fmt.Sprintf("Invalid request body: %v", err),
-		})
+	if !bindJSON(c, &request) {
+		return
+	}
+	if request.Content == "" {
+		respondError(c, ErrorValidationFailed, "Script content is required", requiredField("$.content"))
 		return
 	}
 
@@ -192,10 +172,7 @@ func (ws *WebServer) handleValidateFile(c *gin.Context) {
 // handleListFiles lists files in a directory
 func (ws *WebServer) handleListFiles(c *gin.Context) {
 	if ws.fileManager == nil {
-		c.JSON(http.StatusInternalServerError, APIResponse{
-			Success: false,
-			Error:   "File manager not initialized",
-		})
+		respondError(c, ErrorInternal, "File manager not initialized")
 		return
 	}
 
@@ -210,19 +187,13 @@ func (ws *WebServer) handleListFiles(c *gin.Context) {
 		dirPath = dirPath[1:]
 	}
 
+	if !ws.authorize(c, service.RoleEditor, fmt.Sprintf("listing directory '%s'", dirPath)) {
+		return
+	}
+
 	files, err := ws.fileManager.ListFiles(dirPath)
 	if err != nil {
-		statusCode := http.StatusInternalServerError
-		if err.Error() == "access denied: path not allowed" {
-			statusCode = http.StatusForbidden
-		} else if strings.Contains(err.Error(), "no such file or directory") {
-			statusCode = http.StatusNotFound
-		}
-
-		c.JSON(statusCode, APIResponse{
-			Success: false,
-			Error:   err.Error(),
-		})
+		respondServiceError(c, err, ErrorInternal)
 		return
 	}
 
@@ -28,6 +28,9 @@ func assertAccessDeniedResponse(t *testing.T, w *httptest.ResponseRecorder) {
 	if response.Success {
 		t.Error("Expected failed response for denied access")
 	}
+	if response.Code != ErrorForbidden {
+		t.Errorf("Expected code %s, got %q", ErrorForbidden, response.Code)
+	}
 }
 
 func TestWebServer_FileOperations(t *testing.T) {
@@ -41,7 +44,7 @@ func TestWebServer_FileOperations(t *testing.T) {
 	// Create test dependencies
 	fileManager := service.NewFileManager(tempDir)
 
-	server := NewWebServer(nil, 8080)
+	server := NewWebServer(8080)
 	server.SetFileManager(fileManager)
 
 	// Create test file
@@ -308,7 +311,7 @@ func TestWebServer_FileOperations(t *testing.T) {
 
 func TestWebServer_FileOperations_NoFileManager(t *testing.T) {
 	// Create web server without file manager
-	server := NewWebServer(nil, 8080)
+	server := NewWebServer(8080)
 	// Note: not setting file manager
 
 	t.Run("get file without file manager", func(t *testing.T) {
@@ -323,3 +326,80 @@ func TestWebServer_FileOperations_NoFileManager(t *testing.T) {
 		}
 	})
 }
+
+func TestWebServer_FilesProtectSecrets(t *testing.T) {
+	dataDir := t.TempDir()
+	for _, name := range []string{"run.sh", service.AuthFileName, "." + service.AuthFileName + ".tmp-1234", service.AuthFileName + ".tmp", "tls/key.pem"} {
+		path := filepath.Join(dataDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("secret"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	settings := service.DefaultSettings(dataDir)
+	fileManager := service.NewFileManager(dataDir)
+	fileManager.Protect(settings.SecretPaths(dataDir)...)
+
+	store, tokens := createRoleTokens(t)
+	server := createTestServerWithScripts(nil)
+	server.SetAuth(store, nil)
+	server.SetFileManager(fileManager)
+
+	send := func(method, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(`{"content": "forged"}`))
+		req.Header.Set("Authorization", "Bearer "+tokens["eddie"])
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		return w
+	}
+	for _, path := range []string{"/api/v1/files/auth.json", "/api/v1/files/.auth.json.tmp-1234", "/api/v1/files/auth.json.tmp", "/api/v1/files/tls/key.pem", "/api/v1/files-list/tls"} {
+		t.Run(path, func(t *testing.T) {
+			assertAccessDeniedResponse(t, send("GET", path))
+		})
+	}
+
+	w := send("GET", "/api/v1/files-list/")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected the listing to succeed, got %d", w.Code)
+	}
+	if strings.Contains(w.Body.String(), service.AuthFileName) || strings.Contains(w.Body.String(), `"tls"`) {
+		t.Errorf("Expected secrets hidden from the listing, got %s", w.Body.String())
+	}
+	if w := send("GET", "/api/v1/files/run.sh"); w.Code != http.StatusOK {
+		t.Errorf("Expected other files to stay readable, got %d", w.Code)
+	}
+}
+
+func TestWebServer_FilesProtectAuditLog(t *testing.T) {
+	dataDir := t.TempDir()
+	auditLog := service.NewAuditLog(service.AuditLogPath(dataDir))
+	if err := auditLog.Record(service.AuditEntry{Actor: "alice", Action: service.AuditScriptAdd, Target: "backup"}); err != nil {
+		t.Fatal(err)
+	}
+	fileManager := service.NewFileManager(dataDir)
+	fileManager.Protect(service.DefaultSettings(dataDir).SecretPaths(dataDir)...)
+
+	store, tokens := createRoleTokens(t)
+	server := createTestServerWithScripts(nil)
+	server.SetAuth(store, nil)
+	server.SetAuditLog(auditLog)
+	server.SetFileManager(fileManager)
+
+	for _, method := range []string{"GET", "PUT"} {
+		req := httptest.NewRequest(method, "/api/v1/files/"+service.AuditFileName, strings.NewReader(`{"content": ""}`))
+		req.Header.Set("Authorization", "Bearer "+tokens["alice"])
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		assertAccessDeniedResponse(t, w)
+	}
+
+	entries, err := auditLog.Query(service.AuditFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Action != service.AuditScriptAdd {
+		t.Errorf("Expected the audit log untouched, got %+v", entries)
+	}
+}
@@ -0,0 +1,152 @@
+// Package web provides log search handlers for the HTTP API server
+package web
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"run-script-service/service"
+)
+
+// LogSearchResponse is one page of search results, newest run first
+type LogSearchResponse struct {
+	Entries    []LogEntry `json:"entries"`
+	NextCursor string     `json:"next_cursor,omitempty"`
+}
+
+// handleSearchLogs searches run output across the whole log history.
+// Query parameters: q (substring), regex, ignore_case, stream (stdout or stderr), script,
+// exit_code, trigger, min_duration and max_duration (ms), since, until, limit and cursor.
+func (ws *WebServer) handleSearchLogs(c *gin.Context) {
+	if ws.scriptManager == nil {
+		respondError(c, ErrorInternal, "Script manager not initialized")
+		return
+	}
+
+	query, err := parseLogSearchQuery(c, time.Now())
+	if err != nil {
+		respondError(c, ErrorBadRequest, err.Error())
+		return
+	}
+
+	result, err := ws.scriptManager.GetLogManager().Search(query)
+	if err != nil {
+		respondError(c, ErrorBadRequest, err.Error())
+		return
+	}
+
+	response := LogSearchResponse{
+		Entries:    make([]LogEntry, 0, len(result.Entries)),
+		NextCursor: result.NextCursor,
+	}
+	for i := range result.Entries {
+		response.Entries = append(response.Entries, toWebLogEntry(&result.Entries[i]))
+	}
+	c.JSON(http.StatusOK, APIResponse{
+		Success: true,
+		Data:    response,
+	})
+}
+
+// handleExportLogs downloads the runs matching the search filters as a file.
+// format is csv, ndjson or junit; the other query parameters are those of handleSearchLogs,
+// except that every match is exported and limit keeps the most recent runs.
+func (ws *WebServer) handleExportLogs(c *gin.Context) {
+	if ws.scriptManager == nil {
+		respondError(c, ErrorInternal, "Script manager not initialized")
+		return
+	}
+
+	format := c.DefaultQuery("format", service.ExportCSV)
+	contentType, ext, err := service.ExportContentType(format)
+	if err != nil {
+		respondError(c, ErrorBadRequest, err.Error())
+		return
+	}
+
+	now := time.Now()
+	query, err := parseLogSearchQuery(c, now)
+	if err != nil {
+		respondError(c, ErrorBadRequest, err.Error())
+		return
+	}
+
+	entries, err := ws.scriptManager.GetLogManager().Export(query)
+	if err != nil {
+		respondError(c, ErrorBadRequest, err.Error())
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := service.WriteLogExport(&buf, format, entries); err != nil {
+		respondError(c, ErrorInternal, err.Error())
+		return
+	}
+
+	filename := "logs-" + now.Format("20060102-150405") + ext
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Data(http.StatusOK, contentType, buf.Bytes())
+}
+
+// parseLogSearchQuery builds a LogQuery from the search query parameters
+func parseLogSearchQuery(c *gin.Context, now time.Time) (*service.LogQuery, error) {
+	query := &service.LogQuery{
+		ScriptName: c.Query("script"),
+		Text:       c.Query("q"),
+		Regex:      c.Query("regex"),
+		IgnoreCase: c.Query("ignore_case") == "true",
+		Stream:     c.Query("stream"),
+		Trigger:    c.Query("trigger"),
+		Cursor:     c.Query("cursor"),
+	}
+
+	integers := []struct {
+		name   string
+		target *int64
+	}{
+		{"min_duration", &query.MinDuration},
+		{"max_duration", &query.MaxDuration},
+	}
+	for _, param := range integers {
+		if value := c.Query(param.name); value != "" {
+			parsed, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s: %s", param.name, value)
+			}
+			*param.target = parsed
+		}
+	}
+
+	if value := c.Query("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 0 {
+			return nil, fmt.Errorf("invalid limit: %s", value)
+		}
+		query.Limit = limit
+	}
+	if value := c.Query("exit_code"); value != "" {
+		code, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exit_code: %s", value)
+		}
+		query.ExitCode = &code
+	}
+
+	var err error
+	if value := c.Query("since"); value != "" {
+		if query.StartTime, err = service.ParseLogTime(value, now); err != nil {
+			return nil, err
+		}
+	}
+	if value := c.Query("until"); value != "" {
+		if query.EndTime, err = service.ParseLogTime(value, now); err != nil {
+			return nil, err
+		}
+	}
+	return query, nil
+}
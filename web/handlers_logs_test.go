@@ -0,0 +1,164 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"run-script-service/service"
+)
+
+// createSearchTestServer returns a server whose log directory holds runs of one script
+func createSearchTestServer(t *testing.T) *WebServer {
+	t.Helper()
+	server := createTestServerWithScripts([]service.ScriptConfig{{Name: "fetch", Path: "./fetch.sh", Interval: 60}})
+	logDir := t.TempDir()
+	server.scriptManager.SetLogDir(logDir)
+
+	base := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	var entries []service.LogEntry
+	for i := 0; i < 3; i++ {
+		entries = append(entries, service.LogEntry{
+			Timestamp:  base.Add(time.Duration(i) * time.Hour),
+			ScriptName: "fetch",
+			ExitCode:   1,
+			Stderr:     "connection refused",
+			Duration:   int64(100 * (i + 1)),
+			Trigger:    service.TriggerSchedule,
+		})
+	}
+	entries = append(entries, service.LogEntry{Timestamp: base.Add(5 * time.Hour), ScriptName: "fetch", Stdout: "ok"})
+	if err := service.WriteLogFile(service.LogFilePath(logDir, "fetch"), entries); err != nil {
+		t.Fatal(err)
+	}
+	return server
+}
+
+// searchLogs performs a search request and decodes the page
+func searchLogs(t *testing.T, server *WebServer, params string) (int, LogSearchResponse) {
+	t.Helper()
+	req := httptest.NewRequest("GET", "/api/logs/search?"+params, nil)
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+
+	var response struct {
+		Success bool              `json:"success"`
+		Data    LogSearchResponse `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	return w.Code, response.Data
+}
+
+func TestWebServer_SearchLogs(t *testing.T) {
+	server := createSearchTestServer(t)
+
+	code, page := searchLogs(t, server, "q=refused&limit=2")
+	if code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", code)
+	}
+	if len(page.Entries) != 2 || page.NextCursor == "" {
+		t.Fatalf("Expected a first page of 2 with a cursor, got %+v", page)
+	}
+	if page.Entries[0].Timestamp != "2025-03-01T14:00:00Z" || page.Entries[0].Trigger != service.TriggerSchedule {
+		t.Errorf("Expected newest match first, got %+v", page.Entries[0])
+	}
+
+	_, page = searchLogs(t, server, "q=refused&limit=2&cursor="+page.NextCursor)
+	if len(page.Entries) != 1 || page.NextCursor != "" {
+		t.Errorf("Expected a last page of 1, got %+v", page)
+	}
+
+	_, page = searchLogs(t, server, "regex=^ok$&stream=stdout")
+	if len(page.Entries) != 1 || page.Entries[0].Message != "ok" {
+		t.Errorf("Expected the ok run, got %+v", page.Entries)
+	}
+
+	_, page = searchLogs(t, server, "min_duration=150&max_duration=250&exit_code=1")
+	if len(page.Entries) != 1 || page.Entries[0].Duration != 200 {
+		t.Errorf("Expected the 200ms run, got %+v", page.Entries)
+	}
+
+	_, page = searchLogs(t, server, "q=timeout")
+	if page.Entries == nil || len(page.Entries) != 0 {
+		t.Errorf("Expected an empty list, got %+v", page.Entries)
+	}
+}
+
+func TestWebServer_SearchLogs_BadRequest(t *testing.T) {
+	server := createSearchTestServer(t)
+
+	for _, params := range []string{"regex=(", "stream=both", "limit=x", "min_duration=slow", "since=yesterday", "cursor=%21%21"} {
+		if code, _ := searchLogs(t, server, params); code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", params, code)
+		}
+	}
+}
+
+func TestWebServer_SearchLogs_NoScriptManager(t *testing.T) {
+	server := NewWebServer(8080)
+
+	req := httptest.NewRequest("GET", "/api/logs/search?q=x", nil)
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("Expected status 500, got %d", w.Code)
+	}
+}
+
+func TestWebServer_ExportLogs(t *testing.T) {
+	server := createSearchTestServer(t)
+
+	tests := []struct {
+		format      string
+		contentType string
+		contains    string
+	}{
+		{"csv", "text/csv; charset=utf-8", "fetch,schedule,1,300,,connection refused,"},
+		{"ndjson", "application/x-ndjson", `"stderr":"connection refused"`},
+		{"junit", "application/xml; charset=utf-8", `<failure message="exit code 1" type="ExitCode">connection refused</failure>`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/logs/export?q=refused&format="+tt.format, nil)
+			w := httptest.NewRecorder()
+			server.router.ServeHTTP(w, req)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+			}
+			if got := w.Header().Get("Content-Type"); got != tt.contentType {
+				t.Errorf("Expected content type %s, got %s", tt.contentType, got)
+			}
+			if !strings.HasPrefix(w.Header().Get("Content-Disposition"), "attachment; filename=") {
+				t.Errorf("Expected an attachment, got %q", w.Header().Get("Content-Disposition"))
+			}
+			if !strings.Contains(w.Body.String(), tt.contains) {
+				t.Errorf("Expected export to contain %q, got:\n%s", tt.contains, w.Body.String())
+			}
+			if strings.Contains(w.Body.String(), `"ok"`) || strings.Contains(w.Body.String(), ">ok<") {
+				t.Error("Expected the filter to exclude the successful run")
+			}
+		})
+	}
+}
+
+func TestWebServer_ExportLogs_BadRequest(t *testing.T) {
+	server := createSearchTestServer(t)
+
+	for _, params := range []string{"format=xlsx", "format=csv&regex=("} {
+		req := httptest.NewRequest("GET", "/api/logs/export?"+params, nil)
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", params, w.Code)
+		}
+	}
+}
@@ -0,0 +1,28 @@
+// Package web provides the metrics handler for the HTTP API server
+package web
+
+import (
+	"bytes"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"run-script-service/service"
+)
+
+// handleGetMetrics exposes run counters, last-run gauges, result metrics and the counters of
+// requests refused by the API limits in the Prometheus text exposition format
+func (ws *WebServer) handleGetMetrics(c *gin.Context) {
+	if ws.scriptManager == nil {
+		respondError(c, ErrorInternal, "Script manager not initialized")
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := ws.scriptManager.GetRunMetrics().WritePrometheus(&buf); err != nil {
+		respondError(c, ErrorInternal, err.Error())
+		return
+	}
+	ws.limitCounters.writePrometheus(&buf)
+	c.Data(http.StatusOK, service.MetricsContentType, buf.Bytes())
+}
@@ -0,0 +1,44 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"run-script-service/service"
+)
+
+func TestWebServer_GetMetrics(t *testing.T) {
+	server := createTestServerWithScripts([]service.ScriptConfig{{Name: "load", Path: "./load.sh", Interval: 60}})
+	server.scriptManager.GetRunMetrics().Record(&service.LogEntry{
+		ScriptName: "load", Timestamp: time.Now(), Outcome: service.OutcomeSuccess,
+	}, map[string]float64{"rows": 42})
+
+	req := httptest.NewRequest("GET", "/api/metrics", nil)
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	if got := w.Header().Get("Content-Type"); got != service.MetricsContentType {
+		t.Errorf("Expected Prometheus content type, got %s", got)
+	}
+	if !strings.Contains(w.Body.String(), `run_script_result{script="load",field="rows"} 42`) {
+		t.Errorf("Expected the result metric, got:\n%s", w.Body.String())
+	}
+}
+
+func TestWebServer_GetMetrics_NoScriptManager(t *testing.T) {
+	server := NewWebServer(8080)
+
+	req := httptest.NewRequest("GET", "/api/metrics", nil)
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("Expected status 500, got %d", w.Code)
+	}
+}
@@ -0,0 +1,30 @@
+// Package web provides the API documentation handlers for the HTTP API server
+package web
+
+import (
+	_ "embed"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// apiDocsPage renders /api/v1/openapi.json in the browser without external assets
+//
+//go:embed openapi_docs.html
+var apiDocsPage []byte
+
+// setupDocsRoutes configures the OpenAPI document and its docs page
+func (ws *WebServer) setupDocsRoutes(api *gin.RouterGroup) {
+	api.GET("/openapi.json", ws.handleOpenAPI)
+	api.GET("/docs", ws.handleAPIDocs)
+}
+
+// handleOpenAPI returns the OpenAPI document describing the HTTP API
+func (ws *WebServer) handleOpenAPI(c *gin.Context) {
+	c.JSON(http.StatusOK, openAPIDocument())
+}
+
+// handleAPIDocs serves the HTML page documenting the HTTP API
+func (ws *WebServer) handleAPIDocs(c *gin.Context) {
+	c.Data(http.StatusOK, "text/html; charset=utf-8", apiDocsPage)
+}
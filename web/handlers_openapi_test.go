@@ -0,0 +1,110 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"run-script-service/client"
+	"run-script-service/service"
+)
+
+func TestWebServer_OpenAPI(t *testing.T) {
+	// The document is readable without credentials
+	server, _ := createTestServerWithAuth(t, nil)
+
+	req := httptest.NewRequest("GET", "/api/v1/openapi.json", nil)
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("Failed to unmarshal document: %v", err)
+	}
+	if doc["openapi"] != OpenAPIVersion {
+		t.Errorf("Unexpected version %v", doc["openapi"])
+	}
+	paths := doc["paths"].(map[string]interface{})
+	if paths["/api/v1/scripts/{name}/run"] == nil || paths["/api/v1/files/{path}"] == nil {
+		t.Errorf("Expected script and file routes, got %d paths", len(paths))
+	}
+
+	req = httptest.NewRequest("GET", "/api/v1/scripts", nil)
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected the rest of the API to stay protected, got %d", w.Code)
+	}
+}
+
+func TestWebServer_APIDocs(t *testing.T) {
+	server := createTestServerWithScripts(nil)
+
+	req := httptest.NewRequest("GET", "/api/v1/docs", nil)
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	if !strings.HasPrefix(w.Header().Get("Content-Type"), "text/html") {
+		t.Errorf("Expected an HTML page, got %s", w.Header().Get("Content-Type"))
+	}
+	if !strings.Contains(w.Body.String(), "fetch('openapi.json')") || strings.Contains(w.Body.String(), "https://") {
+		t.Error("Expected a self-contained page loading openapi.json")
+	}
+}
+
+func TestWebServer_TypedClient(t *testing.T) {
+	server, token := createTestServerWithAuth(t, nil)
+	httpServer := httptest.NewServer(server.router)
+	defer httpServer.Close()
+	ctx := context.Background()
+
+	c := client.New(httpServer.URL, client.WithToken(token))
+	if _, err := c.CreateScript(ctx, service.ScriptConfig{Name: "backup", Path: "./backup.sh", Interval: 60}); err != nil {
+		t.Fatalf("CreateScript failed: %v", err)
+	}
+	script, err := c.GetScript(ctx, "backup")
+	if err != nil {
+		t.Fatalf("GetScript failed: %v", err)
+	}
+	if script.Path != "./backup.sh" || script.Interval != 60 {
+		t.Errorf("Unexpected script %+v", script)
+	}
+	status, err := c.Status(ctx)
+	if err != nil || status.TotalScripts != 1 {
+		t.Errorf("Unexpected status %+v: %v", status, err)
+	}
+
+	// Failures surface the status and message of the API error
+	_, err = c.GetScript(ctx, "missing")
+	if !client.IsStatus(err, http.StatusNotFound) {
+		t.Errorf("Expected a 404 error, got %v", err)
+	}
+	_, err = client.New(httpServer.URL).ListScripts(ctx)
+	if !client.IsStatus(err, http.StatusUnauthorized) {
+		t.Errorf("Expected a 401 error without credentials, got %v", err)
+	}
+	principal, err := client.New(httpServer.URL, client.WithBasicAuth("alice", "password1")).Me(ctx)
+	if err != nil || principal == nil || principal.Name != "alice" {
+		t.Errorf("Unexpected principal %+v: %v", principal, err)
+	}
+
+	// ./backup.sh does not exist, so waiting for a run reports it failed
+	_, err = c.RunScript(ctx, "backup", 5*time.Second)
+	if !client.IsCode(err, string(ErrorScriptFailed)) {
+		t.Errorf("Expected a script_failed error, got %v", err)
+	}
+	queue, err := c.Queue(ctx)
+	if err != nil || queue.Workers != service.DefaultRunWorkers {
+		t.Errorf("Unexpected queue %+v: %v", queue, err)
+	}
+}
@@ -0,0 +1,119 @@
+// Package web provides the manual run and run queue handlers for the HTTP API server
+package web
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"run-script-service/service"
+)
+
+// maxRunWait caps the wait parameter of a run request; longer runs are polled at their Location
+const maxRunWait = 5 * time.Minute
+
+// parseRunWait parses the wait parameter of a run request, such as 30s; empty means no wait
+func parseRunWait(value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+	wait, err := time.ParseDuration(value)
+	if err != nil || wait < 0 {
+		return 0, fmt.Errorf("invalid wait '%s' (expected a duration such as 30s)", value)
+	}
+	if wait > maxRunWait {
+		return 0, fmt.Errorf("wait %s is longer than %s", value, maxRunWait)
+	}
+	return wait, nil
+}
+
+// handleRunScript queues a run of a script and answers 202 with the run and its Location.
+// With ?wait the request waits up to that long for the run: a finished run is answered with
+// 200, or with script_failed when it failed, and a run still going with 202.
+func (ws *WebServer) handleRunScript(c *gin.Context) {
+	if ws.scriptManager == nil {
+		respondError(c, ErrorInternal, "Script manager not initialized")
+		return
+	}
+
+	scriptName := c.Param("name")
+	if scriptName == "" {
+		respondError(c, ErrorBadRequest, "Script name is required")
+		return
+	}
+
+	if !ws.authorize(c, service.RoleOperator, fmt.Sprintf("running script '%s'", scriptName), ws.scriptConfig(scriptName)) {
+		return
+	}
+
+	wait, err := parseRunWait(c.Query("wait"))
+	if err != nil {
+		respondError(c, ErrorBadRequest, err.Error())
+		return
+	}
+
+	run, err := ws.scriptManager.EnqueueRun(scriptName)
+	if errors.Is(err, service.ErrShuttingDown) {
+		respondError(c, ErrorUnavailable, "Service is shutting down, the run was not started")
+		return
+	}
+	if err != nil {
+		respondServiceError(c, err, ErrorInternal)
+		return
+	}
+	ws.recordAudit(c, service.AuditScriptRun, scriptName, "queued as run "+run.ID, nil, nil)
+
+	c.Header("Location", APIPrefix+"/runs/"+run.ID)
+	if wait > 0 {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), wait)
+		defer cancel()
+		run, _ = ws.scriptManager.WaitRun(ctx, run.ID)
+	}
+
+	switch run.State {
+	case service.RunFailed:
+		respondError(c, ErrorScriptFailed, fmt.Sprintf("Run %s of script %s failed: %s", run.ID, scriptName, run.Error))
+	case service.RunSucceeded:
+		c.JSON(http.StatusOK, APIResponse{Success: true, Data: run})
+	default:
+		c.JSON(http.StatusAccepted, APIResponse{Success: true, Data: run})
+	}
+}
+
+// handleGetRun returns the state of a manual run, with its position while it waits for a
+// slot; finished runs are kept for a while
+func (ws *WebServer) handleGetRun(c *gin.Context) {
+	if ws.scriptManager == nil {
+		respondError(c, ErrorInternal, "Script manager not initialized")
+		return
+	}
+
+	run, ok := ws.scriptManager.GetRun(c.Param("id"))
+	if !ok {
+		respondError(c, ErrorNotFound, fmt.Sprintf("Run '%s' not found", c.Param("id")))
+		return
+	}
+
+	c.JSON(http.StatusOK, APIResponse{
+		Success: true,
+		Data:    run,
+	})
+}
+
+// handleGetQueue returns the queue depth, the manual runs in flight and waiting, and the
+// runs of all scripts waiting for a slot under the run limits
+func (ws *WebServer) handleGetQueue(c *gin.Context) {
+	if ws.scriptManager == nil {
+		respondError(c, ErrorInternal, "Script manager not initialized")
+		return
+	}
+
+	c.JSON(http.StatusOK, APIResponse{
+		Success: true,
+		Data:    ws.scriptManager.QueueStatus(),
+	})
+}
@@ -0,0 +1,238 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"run-script-service/service"
+)
+
+// createTestServerWithRunnableScripts returns a server whose scripts run the given shell
+// commands, executed by a single worker with room for two waiting runs
+func createTestServerWithRunnableScripts(t *testing.T, commands map[string]string) *WebServer {
+	t.Helper()
+	return createTestServerWithRunConfig(t, &service.ServiceConfig{RunWorkers: 1, RunQueueSize: 2}, commands)
+}
+
+// createTestServerWithRunConfig returns a server with config whose scripts run the given
+// shell commands
+func createTestServerWithRunConfig(t *testing.T, config *service.ServiceConfig, commands map[string]string) *WebServer {
+	t.Helper()
+	dir := t.TempDir()
+	for name, command := range commands {
+		path := filepath.Join(dir, name+".sh")
+		if err := os.WriteFile(path, []byte("#!/bin/bash\n"+command+"\n"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		config.Scripts = append(config.Scripts, service.ScriptConfig{Name: name, Path: path, Interval: 60, MaxLogLines: 10})
+	}
+	scriptManager := service.NewScriptManager(config)
+	scriptManager.SetLogDir(filepath.Join(dir, "logs"))
+	t.Cleanup(func() {
+		// Kill the runs still going before their directory is removed
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		scriptManager.Shutdown(ctx)
+	})
+	server := NewWebServer(8080)
+	server.SetScriptManager(scriptManager)
+	return server
+}
+
+// decodeRun returns the run in the data of a successful response
+func decodeRun(t *testing.T, w *httptest.ResponseRecorder) service.RunStatus {
+	t.Helper()
+	var response struct {
+		Success bool              `json:"success"`
+		Data    service.RunStatus `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil || !response.Success {
+		t.Fatalf("Expected a run, got %s", w.Body.String())
+	}
+	return response.Data
+}
+
+func TestWebServer_RunScriptAsync(t *testing.T) {
+	server := createTestServerWithRunnableScripts(t, map[string]string{"hello": "echo hello"})
+
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, httptest.NewRequest("POST", "/api/v1/scripts/hello/run", nil))
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("Expected status 202, got %d: %s", w.Code, w.Body.String())
+	}
+	run := decodeRun(t, w)
+	if run.ID == "" || run.Script != "hello" || w.Header().Get("Location") != "/api/v1/runs/"+run.ID {
+		t.Errorf("Unexpected run %+v at %q", run, w.Header().Get("Location"))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	server.scriptManager.GetRunQueue().Wait(ctx, run.ID)
+
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/runs/"+run.ID, nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if run = decodeRun(t, w); run.State != service.RunSucceeded || run.FinishedAt == nil {
+		t.Errorf("Expected the run to have succeeded, got %+v", run)
+	}
+	entries := server.scriptManager.GetLogManager().GetLogger("hello").GetEntries()
+	if len(entries) != 1 || entries[0].RunID != run.ID {
+		t.Errorf("Expected the run recorded as %s, got %+v", run.ID, entries)
+	}
+
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/runs/unknown", nil))
+	assertNotFoundResponse(t, w)
+}
+
+func TestWebServer_RunScriptWait(t *testing.T) {
+	server := createTestServerWithRunnableScripts(t, map[string]string{
+		"hello":  "echo hello",
+		"broken": "exit 3",
+		"slow":   "sleep 2",
+	})
+
+	tests := []struct {
+		path   string
+		status int
+		state  string
+	}{
+		{"/api/v1/scripts/hello/run?wait=5s", http.StatusOK, service.RunSucceeded},
+		{"/api/v1/scripts/broken/run?wait=5s", http.StatusUnprocessableEntity, ""},
+		{"/api/v1/scripts/slow/run?wait=50ms", http.StatusAccepted, service.RunRunning},
+		{"/api/v1/scripts/hello/run?wait=soon", http.StatusBadRequest, ""},
+		{"/api/v1/scripts/hello/run?wait=1h", http.StatusBadRequest, ""},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, httptest.NewRequest("POST", tt.path, nil))
+		if w.Code != tt.status {
+			t.Errorf("%s: expected status %d, got %d: %s", tt.path, tt.status, w.Code, w.Body.String())
+			continue
+		}
+		if tt.state != "" {
+			if run := decodeRun(t, w); run.State != tt.state {
+				t.Errorf("%s: expected state %s, got %+v", tt.path, tt.state, run)
+			}
+		} else if response := decodeError(t, w); tt.status == http.StatusUnprocessableEntity &&
+			(response.Code != ErrorScriptFailed || !strings.Contains(response.Error, "code 3")) {
+			t.Errorf("%s: expected script_failed with the exit code, got %+v", tt.path, response)
+		}
+	}
+}
+
+func TestWebServer_Queue(t *testing.T) {
+	server := createTestServerWithRunnableScripts(t, map[string]string{"slow": "sleep 1"})
+
+	var runs []service.RunStatus
+	for i := 0; i < 3; i++ {
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, httptest.NewRequest("POST", "/api/v1/scripts/slow/run", nil))
+		if w.Code != http.StatusAccepted {
+			t.Fatalf("Expected run %d to be queued, got %d: %s", i, w.Code, w.Body.String())
+		}
+		runs = append(runs, decodeRun(t, w))
+		for deadline := time.Now().Add(5 * time.Second); i == 0 && server.scriptManager.GetRunQueue().Status().Depth > 0; {
+			if time.Now().After(deadline) {
+				t.Fatal("Expected the first run to start")
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+
+	// One worker and room for two waiting runs
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, httptest.NewRequest("POST", "/api/v1/scripts/slow/run", nil))
+	if w.Code != http.StatusServiceUnavailable || decodeError(t, w).Code != ErrorUnavailable {
+		t.Errorf("Expected a full queue to answer 503, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/queue", nil))
+	var response struct {
+		Data service.QueueStatus `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	queue := response.Data
+	if queue.Workers != 1 || queue.Capacity != 2 || queue.Depth != 2 {
+		t.Errorf("Unexpected queue %+v", queue)
+	}
+	if len(queue.Running) != 1 || queue.Running[0].ID != runs[0].ID {
+		t.Errorf("Expected the first run in flight, got %+v", queue.Running)
+	}
+	if len(queue.Pending) != 2 || queue.Pending[0].ID != runs[1].ID || queue.Pending[1].Position != 2 {
+		t.Errorf("Expected the other runs waiting in order, got %+v", queue.Pending)
+	}
+}
+
+func TestWebServer_QueueWaitingForSlot(t *testing.T) {
+	server := createTestServerWithRunConfig(t, &service.ServiceConfig{
+		RunWorkers:        2,
+		MaxConcurrentRuns: 1,
+		ResourcePools:     map[string]int{"db": 1},
+	}, map[string]string{"slow": "sleep 1"})
+
+	var runs []service.RunStatus
+	for i := 0; i < 2; i++ {
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, httptest.NewRequest("POST", "/api/v1/scripts/slow/run", nil))
+		runs = append(runs, decodeRun(t, w))
+	}
+	for deadline := time.Now().Add(5 * time.Second); len(server.scriptManager.QueueStatus().Waiting) == 0; {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected a run to wait for a slot")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/queue", nil))
+	var response struct {
+		Data service.QueueStatus `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	queue := response.Data
+	if queue.MaxConcurrentRuns != 1 || queue.Active != 1 || len(queue.Pools) != 1 || queue.Pools[0].Name != "db" {
+		t.Errorf("Expected the run limits, got %+v", queue)
+	}
+	if len(queue.Waiting) != 1 || queue.Waiting[0].Position != 1 || queue.Waiting[0].Trigger != service.TriggerManual {
+		t.Fatalf("Expected one manual run waiting, got %+v", queue.Waiting)
+	}
+	waitingID := queue.Waiting[0].RunID
+
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/runs/"+waitingID, nil))
+	if run := decodeRun(t, w); run.State != service.RunWaiting || run.Position != 1 {
+		t.Errorf("Expected the run waiting at position 1, got %+v", run)
+	}
+	if waitingID != runs[0].ID && waitingID != runs[1].ID {
+		t.Errorf("Expected one of the queued runs to wait, got %s", waitingID)
+	}
+}
+
+func TestParseRunWait(t *testing.T) {
+	tests := map[string]time.Duration{"": 0, "30s": 30 * time.Second, "5m": 5 * time.Minute}
+	for value, expected := range tests {
+		if wait, err := parseRunWait(value); err != nil || wait != expected {
+			t.Errorf("parseRunWait(%q) = %s, %v, expected %s", value, wait, err, expected)
+		}
+	}
+	for _, value := range []string{"30", "-1s", "6m"} {
+		if _, err := parseRunWait(value); err == nil {
+			t.Errorf("Expected parseRunWait(%q) to fail", value)
+		}
+	}
+}
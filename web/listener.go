@@ -0,0 +1,74 @@
+// Package web provides the listeners of the HTTP API server
+package web
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"run-script-service/service"
+)
+
+// unixSocketMode lets the owner and group of the daemon connect to a unix socket
+const unixSocketMode = 0660
+
+// listen opens the TCP port on the bind address, or the unix socket of a unix:<path> bind address
+func (ws *WebServer) listen() (net.Listener, error) {
+	if path, ok := service.UnixSocketPath(ws.bindAddress); ok {
+		return listenUnix(path)
+	}
+	listener, err := net.Listen("tcp", net.JoinHostPort(ws.bindAddress, strconv.Itoa(ws.port)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen: %v", err)
+	}
+	return listener, nil
+}
+
+// serve handles requests from listener, over TLS when configured and the listener is not a unix socket
+func (ws *WebServer) serve(listener net.Listener) error {
+	server := &http.Server{
+		Handler:           ws.router,
+		ReadHeaderTimeout: 30 * time.Second,
+	}
+	ws.serverMutex.Lock()
+	ws.httpServer = server
+	ws.serverMutex.Unlock()
+	if _, unix := listener.Addr().(*net.UnixAddr); ws.tlsConfig == nil || unix {
+		return server.Serve(listener)
+	}
+	server.TLSConfig = ws.tlsConfig
+	return server.ServeTLS(listener, "", "")
+}
+
+// listenUnix listens on a socket at path, replacing a stale socket left behind by a previous run
+func listenUnix(path string) (net.Listener, error) {
+	if info, err := os.Lstat(path); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("failed to listen: %s exists and is not a socket", path)
+		}
+		if conn, err := net.Dial("unix", path); err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("failed to listen: %s is in use by another process", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("failed to remove stale socket: %v", err)
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create socket directory: %v", err)
+	}
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen: %v", err)
+	}
+	if err := os.Chmod(path, unixSocketMode); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("failed to set socket permissions: %v", err)
+	}
+	return listener, nil
+}
@@ -0,0 +1,183 @@
+package web
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"run-script-service/service"
+)
+
+// socketDir returns a short temporary directory, socket paths are limited to about 100 bytes
+func socketDir(t *testing.T) string {
+	t.Helper()
+	dir, err := os.MkdirTemp("", "rss")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return dir
+}
+
+// startListening serves the server in the background until the test ends
+func startListening(t *testing.T, server *WebServer) net.Listener {
+	t.Helper()
+	listener, err := server.listen()
+	if err != nil {
+		t.Fatal(err)
+	}
+	go server.serve(listener)
+	t.Cleanup(func() { listener.Close() })
+	return listener
+}
+
+func TestWebServer_UnixSocket(t *testing.T) {
+	path := filepath.Join(socketDir(t), "rss.sock")
+	server := createTestServerWithScripts(nil)
+	server.SetBindAddress("unix:" + path)
+	startListening(t, server)
+
+	info, err := os.Stat(path)
+	if err != nil || info.Mode().Perm() != unixSocketMode {
+		t.Fatalf("Expected a socket with mode %o, got %v %v", unixSocketMode, info, err)
+	}
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", path)
+		},
+	}}
+	resp, err := client.Get("http://localhost/api/scripts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected 200 over the socket, got %d", resp.StatusCode)
+	}
+
+	second := createTestServerWithScripts(nil)
+	second.SetBindAddress("unix:" + path)
+	if _, err := second.listen(); err == nil || !strings.Contains(err.Error(), "in use") {
+		t.Errorf("Expected a socket in use to be kept, got %v", err)
+	}
+}
+
+func TestListenUnix_StaleSocket(t *testing.T) {
+	dir := socketDir(t)
+	path := filepath.Join(dir, "rss.sock")
+	stale, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	listener, err := listenUnix(path)
+	if err != nil {
+		t.Fatalf("Expected the stale socket to be replaced, got %v", err)
+	}
+	listener.Close()
+
+	file := filepath.Join(dir, "config.json")
+	if err := os.WriteFile(file, []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := listenUnix(file); err == nil || !strings.Contains(err.Error(), "not a socket") {
+		t.Errorf("Expected a regular file to be kept, got %v", err)
+	}
+}
+
+// writeClientCertificate writes a CA and a client certificate for commonName signed by it
+func writeClientCertificate(t *testing.T, dir, commonName string) (caFile string, cert tls.Certificate) {
+	t.Helper()
+	caKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "clients"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caFile = filepath.Join(dir, "clients.pem")
+	if err := os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER}), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, caTemplate, &key.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return caFile, tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func TestWebServer_MutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	caFile, clientCert := writeClientCertificate(t, dir, "alice")
+	reloader, err := service.NewTLSReloader(&service.TLSConfig{SelfSigned: true, ClientCAFile: caFile}, dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	roots, err := service.LoadCertPool(filepath.Join(dir, service.DefaultTLSCertFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server, _ := createTestServerWithAuth(t, nil)
+	server.SetBindAddress("127.0.0.1")
+	server.port = 0
+	server.SetTLSConfig(reloader.ServerConfig())
+	listener := startListening(t, server)
+	url := "https://localhost:" + strings.TrimPrefix(listener.Addr().String(), "127.0.0.1:") + "/api/auth/me"
+
+	get := func(certs ...tls.Certificate) (*http.Response, error) {
+		client := &http.Client{Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{RootCAs: roots, Certificates: certs},
+		}}
+		resp, err := client.Get(url)
+		if err == nil {
+			resp.Body.Close()
+		}
+		return resp, err
+	}
+
+	if _, err := get(); err == nil {
+		t.Error("Expected a connection without a client certificate to be refused")
+	}
+	resp, err := get(clientCert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected the client certificate to authenticate alice, got %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,521 @@
+// Package web provides the OpenAPI description of the HTTP API server
+package web
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"run-script-service/client"
+	"run-script-service/service"
+)
+
+// OpenAPIVersion is the version of the OpenAPI specification the document follows
+const OpenAPIVersion = "3.1.0"
+
+// apiParam documents a query parameter of an operation
+type apiParam struct {
+	name        string
+	kind        string // string, integer or boolean
+	description string
+	enum        []string
+}
+
+// apiOperation documents one route of the HTTP API
+type apiOperation struct {
+	method      string
+	path        string // gin syntax: :name for a segment, *name for the rest of the path
+	id          string // operationId, the name of the client method where there is one
+	tag         string
+	summary     string
+	role        string // minimum role once roles are configured, empty for any caller
+	query       []apiParam
+	request     interface{} // JSON request body, nil for none
+	requestType string      // media type of a request body that is not JSON
+	response    interface{} // data of the success response, nil for none
+	status      int         // success status, 0 means 200
+	media       []string    // media types of a success response that is not JSON
+}
+
+// pathParamDescriptions documents the path parameters of the routes
+var pathParamDescriptions = map[string]string{
+	"name":    "Script name",
+	"script":  "Script name",
+	"id":      "Run ID",
+	"path":    "Slash-separated path",
+	"version": "Configuration version",
+}
+
+// logSearchParams are the query parameters of GET /logs/search and /logs/export
+var logSearchParams = []apiParam{
+	{name: "q", kind: "string", description: "Substring of the output"},
+	{name: "regex", kind: "string", description: "Regular expression the output must match"},
+	{name: "ignore_case", kind: "boolean", description: "Match q and regex case-insensitively"},
+	{name: "stream", kind: "string", description: "Output stream to search", enum: []string{"stdout", "stderr"}},
+	{name: "script", kind: "string", description: "Script name"},
+	{name: "exit_code", kind: "integer", description: "Exit code of the run"},
+	{name: "trigger", kind: "string", description: "What started the run, e.g. schedule or manual"},
+	{name: "min_duration", kind: "integer", description: "Minimum run duration in milliseconds"},
+	{name: "max_duration", kind: "integer", description: "Maximum run duration in milliseconds"},
+	{name: "since", kind: "string", description: "RFC 3339 time, date (2006-01-02) or age such as 12h or 7d"},
+	{name: "until", kind: "string", description: "RFC 3339 time, date (2006-01-02) or age such as 12h or 7d"},
+	{name: "limit", kind: "integer", description: "Maximum number of runs"},
+	{name: "cursor", kind: "string", description: "next_cursor of the previous page"},
+}
+
+// apiOperations documents every route registered by setupRoutes and setupFileRoutes under
+// APIPrefix; the deprecated aliases under /api are not described.
+// TestOpenAPI_MatchesRoutes fails when a route is added without an entry here.
+var apiOperations = []apiOperation{
+	{method: "GET", path: "/ws", id: "events", tag: "events",
+		summary: "Stream script status and log events over a WebSocket connection", status: http.StatusSwitchingProtocols},
+
+	{method: "GET", path: APIPrefix + "/status", id: "status", tag: "status",
+		summary: "Daemon status and script counts", response: client.Status{}},
+	{method: "GET", path: APIPrefix + "/openapi.json", id: "openAPI", tag: "status",
+		summary: "This OpenAPI document", media: []string{"application/json"}},
+	{method: "GET", path: APIPrefix + "/docs", id: "docs", tag: "status",
+		summary: "HTML documentation of the API", media: []string{"text/html"}},
+
+	{method: "GET", path: APIPrefix + "/scripts", id: "listScripts", tag: "scripts",
+		summary: "List the configured scripts", response: []client.Script{}},
+	{method: "POST", path: APIPrefix + "/scripts", id: "createScript", tag: "scripts", role: service.RoleEditor,
+		summary: "Add a script", request: service.ScriptConfig{}, response: client.Script{}, status: http.StatusCreated},
+	{method: "GET", path: APIPrefix + "/scripts/:name", id: "getScript", tag: "scripts",
+		summary: "Get a script", response: client.Script{}},
+	{method: "PUT", path: APIPrefix + "/scripts/:name", id: "updateScript", tag: "scripts", role: service.RoleEditor,
+		summary: "Replace the configuration of a script", request: service.ScriptConfig{}, response: client.ScriptUpdate{}},
+	{method: "DELETE", path: APIPrefix + "/scripts/:name", id: "deleteScript", tag: "scripts", role: service.RoleEditor,
+		summary: "Remove a script", response: client.ScriptAction{}},
+	{method: "POST", path: APIPrefix + "/scripts/:name/run", id: "runScript", tag: "scripts", role: service.RoleOperator,
+		summary: "Queue a run of a script; the Location header points at the run. " +
+			"With wait, a run that finished in time is answered with 200, or script_failed when it failed.",
+		response: service.RunStatus{}, status: http.StatusAccepted,
+		query: []apiParam{{name: "wait", kind: "string", description: "How long to wait for the run, such as 30s, at most 5m"}}},
+	{method: "POST", path: APIPrefix + "/scripts/:name/enable", id: "enableScript", tag: "scripts", role: service.RoleOperator,
+		summary: "Enable a script and start scheduling it", response: client.ScriptToggle{}},
+	{method: "POST", path: APIPrefix + "/scripts/:name/disable", id: "disableScript", tag: "scripts", role: service.RoleOperator,
+		summary: "Disable a script and stop scheduling it", response: client.ScriptToggle{}},
+
+	{method: "GET", path: APIPrefix + "/logs", id: "logs", tag: "logs",
+		summary: "Most recent runs of one or all scripts, oldest first", response: []client.LogEntry{},
+		query: []apiParam{
+			{name: "script", kind: "string", description: "Script name, all scripts when empty"},
+			{name: "limit", kind: "integer", description: "Maximum number of runs, default 50"},
+		}},
+	{method: "GET", path: APIPrefix + "/logs/search", id: "searchLogs", tag: "logs",
+		summary: "Search run output across the whole log history, newest first", query: logSearchParams,
+		response: client.LogSearchResult{}},
+	{method: "GET", path: APIPrefix + "/logs/export", id: "exportLogs", tag: "logs",
+		summary: "Download the matching runs", media: []string{"text/csv", "application/x-ndjson", "application/xml"},
+		query: append([]apiParam{{name: "format", kind: "string", description: "Export format, default csv",
+			enum: []string{service.ExportCSV, service.ExportNDJSON, service.ExportJUnit}}}, logSearchParams...)},
+	{method: "GET", path: APIPrefix + "/logs/:script", id: "scriptLog", tag: "logs",
+		summary: "Raw log file of a script", response: client.ScriptLog{}},
+	{method: "GET", path: APIPrefix + "/logs/raw/:script", id: "rawScriptLog", tag: "logs",
+		summary: "Raw log file of a script", response: client.ScriptLog{}},
+	{method: "DELETE", path: APIPrefix + "/logs/:script", id: "clearLogs", tag: "logs", role: service.RoleEditor,
+		summary: "Remove the recorded runs of a script", response: client.ScriptAction{}},
+	{method: "GET", path: APIPrefix + "/metrics", id: "metrics", tag: "logs",
+		summary: "Run metrics in the Prometheus text format", media: []string{service.MetricsContentType}},
+
+	{method: "POST", path: APIPrefix + "/auth/login", id: "login", tag: "auth",
+		summary: "Check a password and start a session cookie", request: client.LoginRequest{}, response: client.Principal{}},
+	{method: "POST", path: APIPrefix + "/auth/logout", id: "logout", tag: "auth",
+		summary: "End the session of the request"},
+	{method: "GET", path: APIPrefix + "/auth/me", id: "me", tag: "auth",
+		summary: "The authenticated caller, null when authentication is off", response: (*client.Principal)(nil)},
+
+	{method: "GET", path: APIPrefix + "/audit", id: "audit", tag: "audit", role: service.RoleAdmin,
+		summary: "Audit log entries, newest first", response: []service.AuditEntry{},
+		query: []apiParam{
+			{name: "actor", kind: "string", description: "User or token name"},
+			{name: "action", kind: "string", description: "Action, or a prefix such as script."},
+			{name: "target", kind: "string", description: "Script name, file path or version"},
+			{name: "since", kind: "string", description: "RFC 3339 time, date (2006-01-02) or age such as 12h or 7d"},
+			{name: "until", kind: "string", description: "RFC 3339 time, date (2006-01-02) or age such as 12h or 7d"},
+			{name: "limit", kind: "integer", description: "Maximum number of entries, default 100"},
+		}},
+
+	{method: "GET", path: APIPrefix + "/runs/:id", id: "run", tag: "runs",
+		summary: "State and queue position of a manual run; the last 200 finished runs are kept", response: service.RunStatus{}},
+	{method: "GET", path: APIPrefix + "/queue", id: "queue", tag: "runs",
+		summary: "Queue depth, the manual runs in flight and waiting, and the runs waiting for a slot", response: service.QueueStatus{}},
+
+	{method: "GET", path: APIPrefix + "/runs/:id/artifacts", id: "artifacts", tag: "artifacts",
+		summary: "Manifest of the files kept with a run", response: service.ArtifactManifest{}},
+	{method: "GET", path: APIPrefix + "/runs/:id/artifacts/*path", id: "downloadArtifact", tag: "artifacts",
+		summary: "Download one artifact of a run", media: []string{"*/*"}},
+
+	{method: "GET", path: APIPrefix + "/config", id: "config", tag: "config",
+		summary: "Settings shown by the web interface; only webPort comes from the configuration file", response: client.Config{}},
+	{method: "PUT", path: APIPrefix + "/config", id: "setWebPort", tag: "config", role: service.RoleAdmin,
+		summary: "Change the web port in the configuration file; web_port is accepted too",
+		request: client.ConfigUpdateRequest{}, response: client.ConfigUpdate{}},
+	{method: "POST", path: APIPrefix + "/config/validate", id: "validateConfig", tag: "config",
+		summary:     "Validate a configuration document, or the config file when the body is empty",
+		requestType: "application/json", response: client.ConfigValidation{},
+		query: []apiParam{{name: "check_files", kind: "boolean", description: "Check that script files exist and are executable, default true"}}},
+	{method: "GET", path: APIPrefix + "/config/schema", id: "configSchema", tag: "config",
+		summary: "JSON Schema of the configuration file", media: []string{"application/schema+json"}},
+	{method: "GET", path: APIPrefix + "/config/history", id: "configHistory", tag: "config",
+		summary: "Recorded configuration versions, oldest first", response: []service.ConfigVersion{}},
+	{method: "GET", path: APIPrefix + "/config/history/diff", id: "diffConfigVersions", tag: "config",
+		summary: "Line diff between two recorded versions", response: client.ConfigDiff{},
+		query: []apiParam{
+			{name: "from", kind: "integer", description: "Older version"},
+			{name: "to", kind: "integer", description: "Newer version"},
+		}},
+	{method: "GET", path: APIPrefix + "/config/history/:version", id: "configVersion", tag: "config",
+		summary: "Content of a recorded version", response: client.ConfigVersionContent{}},
+	{method: "POST", path: APIPrefix + "/config/history/:version/rollback", id: "rollbackConfig", tag: "config", role: service.RoleAdmin,
+		summary: "Restore a recorded version and reload it", response: client.ConfigRollback{}},
+
+	{method: "GET", path: APIPrefix + "/export", id: "export", tag: "bundles", role: service.RoleAdmin,
+		summary: "Download a bundle of scripts", media: []string{"application/json", "application/gzip"},
+		query: []apiParam{
+			{name: "format", kind: "string", description: "Bundle format, default json", enum: []string{string(service.BundleJSON), string(service.BundleTarGz)}},
+			{name: "scripts", kind: "string", description: "Comma-separated script names, all scripts when empty"},
+		}},
+	{method: "POST", path: APIPrefix + "/import", id: "import", tag: "bundles", role: service.RoleAdmin,
+		summary: "Add the scripts of a json or tar.gz bundle", requestType: "application/octet-stream",
+		response: service.ImportResult{},
+		query: []apiParam{
+			{name: "conflict", kind: "string", description: "How to treat existing script names, default skip",
+				enum: []string{string(service.ConflictSkip), string(service.ConflictRename), string(service.ConflictOverwrite)}},
+			{name: "dry_run", kind: "boolean", description: "Report the actions without changing anything"},
+		}},
+
+	{method: "GET", path: APIPrefix + "/files/*path", id: "readFile", tag: "files", role: service.RoleEditor,
+		summary: "Read a file", response: service.FileContent{}},
+	{method: "PUT", path: APIPrefix + "/files/*path", id: "writeFile", tag: "files", role: service.RoleAdmin,
+		summary: "Replace the content of a file", request: client.FileWriteRequest{}, response: client.FileWrite{}},
+	{method: "POST", path: APIPrefix + "/files/validate", id: "validateScript", tag: "files", role: service.RoleEditor,
+		summary: "Check shell script content for unmatched quotes and dangerous commands",
+		request: client.ScriptValidationRequest{}, response: client.ScriptValidation{}},
+	{method: "GET", path: APIPrefix + "/files-list/*path", id: "listFiles", tag: "files", role: service.RoleEditor,
+		summary: "List a directory", response: []client.FileInfo{}},
+}
+
+// ginParamPattern matches the :name and *name parameters of a gin path
+var ginParamPattern = regexp.MustCompile(`[:*]([A-Za-z_]+)`)
+
+// openAPIPath converts a gin path to the OpenAPI path template syntax
+func openAPIPath(ginPath string) string {
+	return ginParamPattern.ReplaceAllString(ginPath, "{$1}")
+}
+
+// openAPIDocument returns the OpenAPI document of the HTTP API, built once
+var openAPIDocument = sync.OnceValue(func() map[string]interface{} {
+	return buildOpenAPIDocument(apiOperations)
+})
+
+// buildOpenAPIDocument describes operations as an OpenAPI document
+func buildOpenAPIDocument(operations []apiOperation) map[string]interface{} {
+	schemas := newSchemaRegistry()
+	codes := make([]string, len(errorCodes))
+	for i, code := range errorCodes {
+		codes[i] = string(code)
+	}
+	schemas.components["ErrorResponse"] = map[string]interface{}{
+		"type":     "object",
+		"required": []string{"code", "error", "request_id", "success"},
+		"properties": map[string]interface{}{
+			"success":    map[string]interface{}{"type": "boolean", "const": false},
+			"error":      map[string]interface{}{"type": "string", "description": "Human-readable message"},
+			"code":       map[string]interface{}{"type": "string", "enum": codes},
+			"details":    schemas.schema(reflect.TypeOf([]service.ConfigIssue{})),
+			"request_id": map[string]interface{}{"type": "string", "description": "Also sent in the " + RequestIDHeader + " header"},
+		},
+	}
+
+	paths := make(map[string]interface{})
+	for _, op := range operations {
+		path := openAPIPath(op.path)
+		item, ok := paths[path].(map[string]interface{})
+		if !ok {
+			item = make(map[string]interface{})
+			paths[path] = item
+		}
+		item[strings.ToLower(op.method)] = op.document(schemas)
+	}
+
+	var tags []interface{}
+	for _, tag := range []struct{ name, description string }{
+		{"status", "The daemon and this documentation"},
+		{"scripts", "Configured scripts and manual runs"},
+		{"runs", "Queued manual runs and runs waiting for a slot"},
+		{"logs", "Recorded runs and metrics"},
+		{"auth", "Web interface sessions"},
+		{"audit", "Audit log of changes"},
+		{"artifacts", "Files kept with runs"},
+		{"config", "Configuration, validation and history"},
+		{"bundles", "Script import and export"},
+		{"files", "Script files, available when the daemon serves them"},
+		{"events", "Live updates"},
+	} {
+		tags = append(tags, map[string]interface{}{"name": tag.name, "description": tag.description})
+	}
+
+	return map[string]interface{}{
+		"openapi": OpenAPIVersion,
+		"info": map[string]interface{}{
+			"title":   "run-script-service API",
+			"version": "1",
+			"description": "JSON endpoints answer with {\"success\": true, \"data\": ...} or {\"success\": false, \"error\": \"...\", \"code\": \"...\"}; " +
+				"validation failures list the invalid fields in details as JSON paths. Every response carries an " + RequestIDHeader + " header. " +
+				"The same routes without the /v1 segment are deprecated aliases. " +
+				"Each client is rate limited per minute, separately for reads, writes and manual runs; refused requests are answered " +
+				"with 429 rate_limited and a Retry-After header, and request bodies over the limit with 413 payload_too_large. " +
+				"Authentication is only required once users or API tokens exist; roles are only checked once grants are configured.",
+		},
+		"servers": []interface{}{map[string]interface{}{"url": "/"}},
+		"tags":    tags,
+		"paths":   paths,
+		"components": map[string]interface{}{
+			"schemas": schemas.components,
+			"securitySchemes": map[string]interface{}{
+				"bearerAuth":    map[string]interface{}{"type": "http", "scheme": "bearer", "description": "API token"},
+				"basicAuth":     map[string]interface{}{"type": "http", "scheme": "basic"},
+				"sessionCookie": map[string]interface{}{"type": "apiKey", "in": "cookie", "name": sessionCookieName},
+			},
+			"responses": map[string]interface{}{
+				"Error": map[string]interface{}{
+					"description": "The request failed",
+					"content": map[string]interface{}{
+						"application/json": map[string]interface{}{"schema": schemaRef("ErrorResponse")},
+					},
+				},
+				"RateLimited": map[string]interface{}{
+					"description": "The client exceeded its rate",
+					"headers": map[string]interface{}{
+						"Retry-After": map[string]interface{}{
+							"description": "Seconds until the request may be retried",
+							"schema":      map[string]interface{}{"type": "integer"},
+						},
+					},
+					"content": map[string]interface{}{
+						"application/json": map[string]interface{}{"schema": schemaRef("ErrorResponse")},
+					},
+				},
+			},
+		},
+		"security": []interface{}{
+			map[string]interface{}{"bearerAuth": []string{}},
+			map[string]interface{}{"basicAuth": []string{}},
+			map[string]interface{}{"sessionCookie": []string{}},
+			map[string]interface{}{},
+		},
+	}
+}
+
+// document returns the OpenAPI operation object
+func (op apiOperation) document(schemas *schemaRegistry) map[string]interface{} {
+	doc := map[string]interface{}{
+		"operationId": op.id,
+		"summary":     op.summary,
+		"tags":        []string{op.tag},
+	}
+	if op.role != "" {
+		doc["description"] = fmt.Sprintf("Requires the %s role.", op.role)
+	}
+
+	var params []interface{}
+	for _, match := range ginParamPattern.FindAllStringSubmatch(op.path, -1) {
+		params = append(params, map[string]interface{}{
+			"name":        match[1],
+			"in":          "path",
+			"required":    true,
+			"description": pathParamDescriptions[match[1]],
+			"schema":      map[string]interface{}{"type": "string"},
+		})
+	}
+	for _, param := range op.query {
+		schema := map[string]interface{}{"type": param.kind}
+		if len(param.enum) > 0 {
+			schema["enum"] = param.enum
+		}
+		params = append(params, map[string]interface{}{
+			"name":        param.name,
+			"in":          "query",
+			"description": param.description,
+			"schema":      schema,
+		})
+	}
+	if len(params) > 0 {
+		doc["parameters"] = params
+	}
+
+	switch {
+	case op.request != nil:
+		doc["requestBody"] = map[string]interface{}{
+			"required": true,
+			"content": map[string]interface{}{
+				"application/json": map[string]interface{}{"schema": schemas.schema(reflect.TypeOf(op.request))},
+			},
+		}
+	case op.requestType != "":
+		doc["requestBody"] = map[string]interface{}{
+			"content": map[string]interface{}{op.requestType: map[string]interface{}{}},
+		}
+	}
+
+	status := op.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	success := map[string]interface{}{"description": http.StatusText(status)}
+	switch {
+	case len(op.media) > 0:
+		content := make(map[string]interface{})
+		for _, mediaType := range op.media {
+			content[mediaType] = map[string]interface{}{}
+		}
+		success["content"] = content
+	case status != http.StatusSwitchingProtocols:
+		envelope := map[string]interface{}{
+			"type":     "object",
+			"required": []string{"success"},
+			"properties": map[string]interface{}{
+				"success": map[string]interface{}{"type": "boolean"},
+			},
+		}
+		if op.response != nil {
+			envelope["properties"].(map[string]interface{})["data"] = schemas.schema(reflect.TypeOf(op.response))
+		}
+		success["content"] = map[string]interface{}{"application/json": map[string]interface{}{"schema": envelope}}
+	}
+	doc["responses"] = map[string]interface{}{
+		fmt.Sprint(status): success,
+		"429":              map[string]interface{}{"$ref": "#/components/responses/RateLimited"},
+		"default":          map[string]interface{}{"$ref": "#/components/responses/Error"},
+	}
+	return doc
+}
+
+// schemaRef returns a reference to a component schema
+func schemaRef(name string) map[string]interface{} {
+	return map[string]interface{}{"$ref": "#/components/schemas/" + name}
+}
+
+// schemaRegistry derives JSON Schemas from Go types, keeping named structs as components
+type schemaRegistry struct {
+	components map[string]interface{}
+	types      map[reflect.Type]string
+	names      map[string]bool
+}
+
+// newSchemaRegistry creates an empty registry
+func newSchemaRegistry() *schemaRegistry {
+	return &schemaRegistry{
+		components: make(map[string]interface{}),
+		types:      make(map[reflect.Type]string),
+		names:      make(map[string]bool),
+	}
+}
+
+// timeType is encoded as an RFC 3339 string
+var timeType = reflect.TypeOf(time.Time{})
+
+// schema returns the schema of t, a reference for named structs. Pointers, slices and maps
+// may be null, as encoding/json writes nil values.
+func (r *schemaRegistry) schema(t reflect.Type) map[string]interface{} {
+	if t.Kind() == reflect.Ptr {
+		return map[string]interface{}{"anyOf": []interface{}{r.schema(t.Elem()), map[string]interface{}{"type": "null"}}}
+	}
+	if t == timeType {
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if t.Name() == "" {
+			return r.structSchema(t)
+		}
+		if name, ok := r.types[t]; ok {
+			return schemaRef(name)
+		}
+		name := t.Name()
+		if r.names[name] {
+			// Types of different packages share the name, e.g. service.LogEntry and client.LogEntry
+			pkg := t.PkgPath()[strings.LastIndex(t.PkgPath(), "/")+1:]
+			name = strings.ToUpper(pkg[:1]) + pkg[1:] + name
+		}
+		r.types[t], r.names[name] = name, true
+		if schema := configComponent(t); schema != nil {
+			r.components[name] = schema
+		} else {
+			r.components[name] = r.structSchema(t)
+		}
+		return schemaRef(name)
+	case reflect.Slice:
+		return map[string]interface{}{"type": []string{"array", "null"}, "items": r.schema(t.Elem())}
+	case reflect.Array:
+		return map[string]interface{}{"type": "array", "items": r.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": []string{"object", "null"}, "additionalProperties": r.schema(t.Elem())}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	default:
+		return map[string]interface{}{}
+	}
+}
+
+// structSchema returns the object schema of a struct; fields without omitempty are required
+func (r *schemaRegistry) structSchema(t reflect.Type) map[string]interface{} {
+	properties := make(map[string]interface{})
+	var required []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = field.Name
+		}
+		properties[name] = r.schema(field.Type)
+		if !strings.Contains(options, "omitempty") {
+			required = append(required, name)
+		}
+	}
+	schema := map[string]interface{}{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		sort.Strings(required)
+		schema["required"] = required
+	}
+	return schema
+}
+
+// configComponent returns the configuration file schema of ServiceConfig and ScriptConfig,
+// which carries their constraints, and nil for other types
+func configComponent(t reflect.Type) map[string]interface{} {
+	schema := service.ConfigSchema()
+	switch t {
+	case reflect.TypeOf(service.ServiceConfig{}):
+		delete(schema, "$schema")
+		delete(schema, "$id")
+		return schema
+	case reflect.TypeOf(service.ScriptConfig{}):
+		scripts := schema["properties"].(map[string]interface{})["scripts"].(map[string]interface{})
+		return scripts["items"].(map[string]interface{})
+	default:
+		return nil
+	}
+}
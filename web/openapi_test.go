@@ -0,0 +1,394 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"run-script-service/service"
+)
+
+// specOperations returns "METHOD /path" for every operation of the OpenAPI document
+func specOperations(doc map[string]interface{}) map[string]map[string]interface{} {
+	operations := make(map[string]map[string]interface{})
+	for path, item := range doc["paths"].(map[string]interface{}) {
+		for method, op := range item.(map[string]interface{}) {
+			operations[strings.ToUpper(method)+" "+path] = op.(map[string]interface{})
+		}
+	}
+	return operations
+}
+
+func TestOpenAPI_MatchesRoutes(t *testing.T) {
+	server := createTestServerWithScripts(nil)
+	server.SetFileManager(service.NewFileManager(t.TempDir()))
+	operations := specOperations(openAPIDocument())
+
+	routes := make(map[string]bool)
+	for _, route := range server.router.Routes() {
+		// The web interface itself is not part of the API
+		if route.Path == "/" || strings.HasPrefix(route.Path, "/static/") {
+			continue
+		}
+		key := route.Method + " " + openAPIPath(route.Path)
+		routes[key] = true
+		if rest, ok := apiRoute(route.Path); ok && !strings.HasPrefix(route.Path, APIPrefix) {
+			// Deprecated aliases are not described, they must alias a route of the current version
+			if operations[route.Method+" "+openAPIPath(APIPrefix+rest)] == nil {
+				t.Errorf("Route %s %s has no counterpart under %s", route.Method, route.Path, APIPrefix)
+			}
+			continue
+		}
+		if operations[key] == nil {
+			t.Errorf("Route %s %s is not described in the OpenAPI document, add it to apiOperations", route.Method, route.Path)
+		}
+	}
+	for key := range operations {
+		if !routes[key] {
+			t.Errorf("The OpenAPI document describes %s, which is not a route", key)
+		}
+	}
+}
+
+func TestOpenAPI_Document(t *testing.T) {
+	doc := openAPIDocument()
+	if doc["openapi"] != OpenAPIVersion {
+		t.Errorf("Unexpected version %v", doc["openapi"])
+	}
+
+	// Every reference resolves and every operation has a unique id
+	data, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	schemas := doc["components"].(map[string]interface{})["schemas"].(map[string]interface{})
+	for _, ref := range strings.Split(string(data), `"$ref":"#/components/schemas/`)[1:] {
+		name := ref[:strings.Index(ref, `"`)]
+		if schemas[name] == nil {
+			t.Errorf("Unresolved schema reference %s", name)
+		}
+	}
+	ids := make(map[string]string)
+	for key, op := range specOperations(doc) {
+		id := op["operationId"].(string)
+		if other, ok := ids[id]; ok {
+			t.Errorf("%s and %s share the operationId %s", key, other, id)
+		}
+		ids[id] = key
+	}
+
+	// Config types carry the constraints of the configuration schema
+	script := schemas["ScriptConfig"].(map[string]interface{})
+	if required, _ := script["required"].([]string); len(required) != 2 {
+		t.Errorf("Expected name and path to be required, got %v", script["required"])
+	}
+}
+
+// conformanceRequest is a request of TestOpenAPI_ResponsesMatchSchemas
+type conformanceRequest struct {
+	method string
+	path   string
+	route  string // gin path of the operation
+	body   string
+}
+
+func TestOpenAPI_ResponsesMatchSchemas(t *testing.T) {
+	dir := t.TempDir()
+	scriptPath := filepath.Join(dir, "hello.sh")
+	if err := os.WriteFile(scriptPath, []byte("#!/bin/bash\necho hello\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "report.html"), []byte("<html/>"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	config := &service.ServiceConfig{
+		Scripts: []service.ScriptConfig{{Name: "hello", Path: scriptPath, Interval: 3600, MaxLogLines: 10}},
+		WebPort: 8080,
+	}
+	scriptManager := service.NewScriptManagerWithPath(config, filepath.Join(dir, "service_config.json"))
+	scriptManager.SetLogDir(filepath.Join(dir, "logs"))
+	scriptManager.SetArtifactDir(filepath.Join(dir, "artifacts"))
+	if err := scriptManager.SaveConfig(); err != nil {
+		t.Fatal(err)
+	}
+	entry := &service.LogEntry{RunID: "0123456789abcdef", ScriptName: "hello", Timestamp: time.Now()}
+	if _, err := scriptManager.GetArtifactStore().Collect(entry, dir, []string{"*.html"}, service.ArtifactPolicy{}); err != nil {
+		t.Fatal(err)
+	}
+
+	store := service.NewAuthStore(filepath.Join(dir, service.AuthFileName))
+	if _, err := store.AddUser("alice", "password1", service.RoleAdmin); err != nil {
+		t.Fatal(err)
+	}
+	token, _, err := store.CreateToken("ci", service.RoleAdmin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := NewWebServer(8080)
+	server.SetScriptManager(scriptManager)
+	server.SetAuth(store, nil)
+	server.SetAuditLog(service.NewAuditLog(filepath.Join(dir, service.AuditFileName)))
+	server.SetFileManager(service.NewFileManager(dir))
+
+	queued, err := scriptManager.EnqueueRun("hello")
+	if err != nil {
+		t.Fatal(err)
+	}
+	scriptManager.GetRunQueue().Wait(context.Background(), queued.ID)
+
+	other := fmt.Sprintf(`{"name":"other","path":%q,"interval":60}`, scriptPath)
+	requests := []conformanceRequest{
+		{"GET", "/api/v1/status", "/api/v1/status", ""},
+		{"GET", "/api/v1/openapi.json", "/api/v1/openapi.json", ""},
+		{"GET", "/api/v1/docs", "/api/v1/docs", ""},
+		{"GET", "/api/v1/scripts", "/api/v1/scripts", ""},
+		{"POST", "/api/v1/scripts", "/api/v1/scripts", other},
+		{"GET", "/api/v1/scripts/hello", "/api/v1/scripts/:name", ""},
+		{"PUT", "/api/v1/scripts/other", "/api/v1/scripts/:name", other},
+		{"POST", "/api/v1/scripts/hello/run", "/api/v1/scripts/:name/run", ""},
+		{"POST", "/api/v1/scripts/other/enable", "/api/v1/scripts/:name/enable", ""},
+		{"POST", "/api/v1/scripts/other/disable", "/api/v1/scripts/:name/disable", ""},
+		{"GET", "/api/v1/logs", "/api/v1/logs", ""},
+		{"GET", "/api/v1/logs/search?q=hello", "/api/v1/logs/search", ""},
+		{"GET", "/api/v1/logs/export?format=ndjson", "/api/v1/logs/export", ""},
+		{"GET", "/api/v1/logs/hello", "/api/v1/logs/:script", ""},
+		{"GET", "/api/v1/logs/raw/hello", "/api/v1/logs/raw/:script", ""},
+		{"GET", "/api/v1/metrics", "/api/v1/metrics", ""},
+		{"DELETE", "/api/v1/logs/hello", "/api/v1/logs/:script", ""},
+		{"DELETE", "/api/v1/scripts/other", "/api/v1/scripts/:name", ""},
+		{"POST", "/api/v1/auth/login", "/api/v1/auth/login", `{"username":"alice","password":"password1"}`},
+		{"GET", "/api/v1/auth/me", "/api/v1/auth/me", ""},
+		{"POST", "/api/v1/auth/logout", "/api/v1/auth/logout", ""},
+		{"GET", "/api/v1/audit", "/api/v1/audit", ""},
+		{"GET", "/api/v1/runs/" + queued.ID, "/api/v1/runs/:id", ""},
+		{"GET", "/api/v1/queue", "/api/v1/queue", ""},
+		{"GET", "/api/v1/runs/0123456789abcdef/artifacts", "/api/v1/runs/:id/artifacts", ""},
+		{"GET", "/api/v1/runs/0123456789abcdef/artifacts/report.html", "/api/v1/runs/:id/artifacts/*path", ""},
+		{"GET", "/api/v1/config", "/api/v1/config", ""},
+		{"PUT", "/api/v1/config", "/api/v1/config", `{"webPort":9090}`},
+		{"POST", "/api/v1/config/validate?check_files=false", "/api/v1/config/validate", `{"scripts":[]}`},
+		{"GET", "/api/v1/config/schema", "/api/v1/config/schema", ""},
+		{"GET", "/api/v1/config/history", "/api/v1/config/history", ""},
+		{"GET", "/api/v1/config/history/1", "/api/v1/config/history/:version", ""},
+		{"GET", "/api/v1/config/history/diff?from=1&to=2", "/api/v1/config/history/diff", ""},
+		{"POST", "/api/v1/config/history/1/rollback", "/api/v1/config/history/:version/rollback", ""},
+		{"GET", "/api/v1/export", "/api/v1/export", ""},
+		{"POST", "/api/v1/import?dry_run=true&conflict=rename", "/api/v1/import", `{"version":1,"scripts":[]}`},
+		{"GET", "/api/v1/files/hello.sh", "/api/v1/files/*path", ""},
+		{"PUT", "/api/v1/files/new.sh", "/api/v1/files/*path", `{"content":"echo new"}`},
+		{"POST", "/api/v1/files/validate", "/api/v1/files/validate", `{"content":"sudo ls"}`},
+		{"GET", "/api/v1/files-list/.", "/api/v1/files-list/*path", ""},
+	}
+
+	doc := openAPIDocument()
+	operations := specOperations(doc)
+	covered := make(map[string]bool)
+	for _, r := range requests {
+		key := r.method + " " + openAPIPath(r.route)
+		op := operations[key]
+		if op == nil {
+			t.Fatalf("%s is not in the OpenAPI document", key)
+		}
+		covered[key] = true
+
+		req := httptest.NewRequest(r.method, r.path, strings.NewReader(r.body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		if r.body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		if location := w.Header().Get("Location"); strings.HasPrefix(location, APIPrefix+"/runs/") {
+			// Let queued runs finish before the next request reads the logs
+			scriptManager.GetRunQueue().Wait(context.Background(), strings.TrimPrefix(location, APIPrefix+"/runs/"))
+		}
+
+		var status string
+		var response map[string]interface{}
+		for code, value := range op["responses"].(map[string]interface{}) {
+			if code != "default" && code != "429" {
+				status, response = code, value.(map[string]interface{})
+			}
+		}
+		if fmt.Sprint(w.Code) != status {
+			t.Errorf("%s %s: expected status %s, got %d: %s", r.method, r.path, status, w.Code, w.Body.String())
+			continue
+		}
+		content, _ := response["content"].(map[string]interface{})
+		if media, ok := content["application/json"].(map[string]interface{}); ok && media["schema"] != nil {
+			var body interface{}
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Errorf("%s %s: invalid JSON: %v", r.method, r.path, err)
+				continue
+			}
+			for _, problem := range checkSchema(doc, media["schema"].(map[string]interface{}), body, "$") {
+				t.Errorf("%s %s: %s", r.method, r.path, problem)
+			}
+			continue
+		}
+		contentType := strings.Split(w.Header().Get("Content-Type"), ";")[0]
+		documented := false
+		for mediaType := range content {
+			documented = documented || mediaType == "*/*" || strings.Split(mediaType, ";")[0] == contentType
+		}
+		if !documented {
+			t.Errorf("%s %s: %s is not one of the documented media types", r.method, r.path, contentType)
+		}
+	}
+
+	for key := range operations {
+		if !covered[key] && key != "GET /ws" {
+			t.Errorf("%s is not exercised, add a request for it", key)
+		}
+	}
+}
+
+func TestOpenAPI_ErrorsMatchSchema(t *testing.T) {
+	server, _ := createTestServerWithAuth(t, nil)
+	server.SetFileManager(service.NewFileManager(t.TempDir()))
+	doc := openAPIDocument()
+
+	requests := []struct {
+		method, path, body string
+		auth               bool
+	}{
+		{"GET", "/api/v1/scripts", "", false},
+		{"GET", "/api/v1/scripts/missing", "", true},
+		{"POST", "/api/v1/scripts", `{}`, true},
+		{"POST", "/api/v1/scripts", `{"name":`, true},
+		{"GET", "/api/v1/files/../../etc/passwd", "", true},
+		{"GET", "/api/v1/unknown", "", true},
+	}
+	for _, r := range requests {
+		req := httptest.NewRequest(r.method, r.path, strings.NewReader(r.body))
+		if r.auth {
+			req.SetBasicAuth("alice", "password1")
+		}
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+
+		if w.Code < 400 {
+			t.Errorf("%s %s: expected an error, got %d", r.method, r.path, w.Code)
+			continue
+		}
+		var body interface{}
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Errorf("%s %s: invalid JSON: %v", r.method, r.path, err)
+			continue
+		}
+		for _, problem := range checkSchema(doc, schemaRef("ErrorResponse"), body, "$") {
+			t.Errorf("%s %s: %s", r.method, r.path, problem)
+		}
+	}
+}
+
+// checkSchema returns where value does not match schema. Objects with properties are closed:
+// a field the document does not describe is reported, so handlers and the document cannot drift.
+func checkSchema(doc, schema map[string]interface{}, value interface{}, at string) []string {
+	if ref, ok := schema["$ref"].(string); ok {
+		name := strings.TrimPrefix(ref, "#/components/schemas/")
+		components := doc["components"].(map[string]interface{})["schemas"].(map[string]interface{})
+		return checkSchema(doc, components[name].(map[string]interface{}), value, at)
+	}
+	if anyOf, ok := schema["anyOf"].([]interface{}); ok {
+		var problems []string
+		for _, option := range anyOf {
+			optionProblems := checkSchema(doc, option.(map[string]interface{}), value, at)
+			if len(optionProblems) == 0 {
+				return nil
+			}
+			problems = append(problems, optionProblems...)
+		}
+		return problems
+	}
+
+	var types []string
+	switch kind := schema["type"].(type) {
+	case string:
+		types = []string{kind}
+	case []string:
+		types = kind
+	case []interface{}:
+		for _, k := range kind {
+			types = append(types, k.(string))
+		}
+	}
+	if len(types) == 0 {
+		return nil
+	}
+	actual := jsonType(value)
+	matched := false
+	for _, kind := range types {
+		if kind == actual || (kind == "number" && actual == "integer") {
+			matched = true
+		}
+	}
+	if !matched {
+		return []string{fmt.Sprintf("%s is %s, expected %v", at, actual, types)}
+	}
+
+	var problems []string
+	switch v := value.(type) {
+	case map[string]interface{}:
+		properties, _ := schema["properties"].(map[string]interface{})
+		additional, _ := schema["additionalProperties"].(map[string]interface{})
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			switch {
+			case properties[name] != nil:
+				problems = append(problems, checkSchema(doc, properties[name].(map[string]interface{}), v[name], at+"."+name)...)
+			case additional != nil:
+				problems = append(problems, checkSchema(doc, additional, v[name], at+"."+name)...)
+			case properties != nil:
+				problems = append(problems, fmt.Sprintf("%s.%s is not in the document", at, name))
+			}
+		}
+		required, _ := schema["required"].([]string)
+		for _, name := range required {
+			if _, ok := v[name]; !ok {
+				problems = append(problems, fmt.Sprintf("%s.%s is required but missing", at, name))
+			}
+		}
+	case []interface{}:
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range v {
+				problems = append(problems, checkSchema(doc, items, item, fmt.Sprintf("%s[%d]", at, i))...)
+			}
+		}
+	}
+	return problems
+}
+
+// jsonType returns the JSON Schema type of a decoded JSON value
+func jsonType(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if v == math.Trunc(v) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	default:
+		return "object"
+	}
+}
@@ -0,0 +1,210 @@
+// Package web provides rate limiting and request body limits of the HTTP API
+package web
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"run-script-service/service"
+)
+
+// requestLimits holds the limiters of one rate_limit configuration
+type requestLimits struct {
+	config   *service.RateLimitConfig
+	limiters map[string]*service.RateLimiter // by request class, empty when rate limiting is disabled
+}
+
+// requestClasses lists the request classes in metrics order
+var requestClasses = []string{service.RequestClassRead, service.RequestClassWrite, service.RequestClassRun, service.RequestClassAuth}
+
+// limitCounters counts the requests refused by the limits, kept across reconfiguration
+type limitCounters struct {
+	reads, writes, runs, auths atomic.Int64 // refused with 429, by request class
+	tooLarge                   atomic.Int64 // refused with 413
+}
+
+// SetRateLimits limits the API requests of each client and the size of request bodies as
+// config says; nil applies the defaults. Clients start with full buckets.
+func (ws *WebServer) SetRateLimits(config *service.RateLimitConfig) {
+	limits := &requestLimits{config: config, limiters: make(map[string]*service.RateLimiter)}
+	for _, class := range requestClasses {
+		if perMinute := config.PerMinute(class); perMinute > 0 {
+			limits.limiters[class] = service.NewRateLimiter(perMinute)
+		}
+	}
+
+	ws.limitsMutex.Lock()
+	defer ws.limitsMutex.Unlock()
+	ws.limits = limits
+}
+
+// getLimits returns the request limits
+func (ws *WebServer) getLimits() *requestLimits {
+	ws.limitsMutex.RLock()
+	defer ws.limitsMutex.RUnlock()
+	return ws.limits
+}
+
+// limitedRoute returns the route of an API or WebSocket request below the version prefix
+func limitedRoute(path string) (string, bool) {
+	if path == "/ws" {
+		return path, true
+	}
+	return apiRoute(path)
+}
+
+// requestClass classifies an API request for rate limiting; route is its path below the
+// version prefix
+func requestClass(method, route string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return service.RequestClassRead
+	}
+	if method == http.MethodPost && strings.HasPrefix(route, "/scripts/") && strings.HasSuffix(route, "/run") {
+		return service.RequestClassRun
+	}
+	return service.RequestClassWrite
+}
+
+// isUpload reports whether an API request carries a file or bundle, which may be larger
+// than other request bodies
+func isUpload(method, route string) bool {
+	return (method == http.MethodPut && strings.HasPrefix(route, "/files/")) ||
+		(method == http.MethodPost && route == "/import")
+}
+
+// rateLimitClient identifies the caller of a request for its token bucket: the user or
+// token once authenticated, the remote address otherwise
+func rateLimitClient(c *gin.Context) string {
+	if principal := PrincipalFrom(c); principal != nil {
+		if principal.Method == service.AuthMethodToken {
+			return "token:" + principal.Name
+		}
+		return "user:" + principal.Name
+	}
+	return "addr:" + c.RemoteIP()
+}
+
+// presentsCredentials reports whether a request carries a password or token to check,
+// as opposed to a session cookie or none at all
+func presentsCredentials(c *gin.Context, route string) bool {
+	return c.GetHeader("Authorization") != "" || route == "/auth/login"
+}
+
+// authFailureMiddleware limits the failed authentications of each remote address. A request
+// presenting credentials takes a token before they are checked, so guessing passwords costs
+// no password hashes once the address is over its rate, and gets it back unless it is answered
+// with 401. It runs before authMiddleware.
+func (ws *WebServer) authFailureMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		route, ok := limitedRoute(c.Request.URL.Path)
+		if !ok || !presentsCredentials(c, route) {
+			c.Next()
+			return
+		}
+		limits := ws.getLimits()
+		if limits == nil || limits.limiters[service.RequestClassAuth] == nil {
+			c.Next()
+			return
+		}
+
+		limiter := limits.limiters[service.RequestClassAuth]
+		client := "addr:" + c.RemoteIP()
+		if allowed, retry := limiter.Allow(client); !allowed {
+			ws.respondRateLimited(c, service.RequestClassAuth, retry)
+			return
+		}
+		c.Next()
+		if c.Writer.Status() != http.StatusUnauthorized {
+			limiter.Return(client)
+		}
+	}
+}
+
+// limitMiddleware answers API and WebSocket requests over their client's rate with 429 and a
+// Retry-After header, and caps request bodies, answering larger ones with 413. It runs after
+// authMiddleware so authenticated callers are limited by name.
+func (ws *WebServer) limitMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		route, ok := limitedRoute(c.Request.URL.Path)
+		if !ok {
+			c.Next()
+			return
+		}
+		limits := ws.getLimits()
+		if limits == nil {
+			c.Next()
+			return
+		}
+
+		class := requestClass(c.Request.Method, route)
+		if limiter := limits.limiters[class]; limiter != nil {
+			if allowed, retry := limiter.Allow(rateLimitClient(c)); !allowed {
+				ws.respondRateLimited(c, class, retry)
+				return
+			}
+		}
+
+		if c.Request.Body != nil && c.Request.Body != http.NoBody {
+			limit := limits.config.BodyLimit(isUpload(c.Request.Method, route))
+			if c.Request.ContentLength > limit {
+				ws.limitCounters.tooLarge.Add(1)
+				respondError(c, ErrorTooLarge, fmt.Sprintf("Request body of %d bytes exceeds the limit of %d bytes", c.Request.ContentLength, limit))
+				return
+			}
+			c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit)
+		}
+		c.Next()
+
+		// Bodies without a Content-Length are cut off while handlers read them
+		if c.Writer.Status() == http.StatusRequestEntityTooLarge {
+			ws.limitCounters.tooLarge.Add(1)
+		}
+	}
+}
+
+// respondRateLimited refuses a request of class with 429, telling the client when to retry
+func (ws *WebServer) respondRateLimited(c *gin.Context, class string, retry time.Duration) {
+	ws.limitCounters.rateLimited(class).Add(1)
+	seconds := int(math.Ceil(retry.Seconds()))
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	message := fmt.Sprintf("Too many %s requests, retry in %d second(s)", class, seconds)
+	if class == service.RequestClassAuth {
+		message = fmt.Sprintf("Too many failed authentications, retry in %d second(s)", seconds)
+	}
+	respondError(c, ErrorRateLimited, message)
+}
+
+// rateLimited returns the counter of requests of class refused with 429
+func (lc *limitCounters) rateLimited(class string) *atomic.Int64 {
+	switch class {
+	case service.RequestClassWrite:
+		return &lc.writes
+	case service.RequestClassRun:
+		return &lc.runs
+	case service.RequestClassAuth:
+		return &lc.auths
+	default:
+		return &lc.reads
+	}
+}
+
+// writePrometheus writes the counters in the Prometheus text exposition format
+func (lc *limitCounters) writePrometheus(w io.Writer) {
+	fmt.Fprintf(w, "# HELP run_script_http_rate_limited_total API requests refused with 429, by request class.\n")
+	fmt.Fprintf(w, "# TYPE run_script_http_rate_limited_total counter\n")
+	for _, class := range requestClasses {
+		fmt.Fprintf(w, "run_script_http_rate_limited_total{class=\"%s\"} %d\n", class, lc.rateLimited(class).Load())
+	}
+	fmt.Fprintf(w, "# HELP run_script_http_body_too_large_total API requests refused with 413.\n")
+	fmt.Fprintf(w, "# TYPE run_script_http_body_too_large_total counter\n")
+	fmt.Fprintf(w, "run_script_http_body_too_large_total %d\n", lc.tooLarge.Load())
+}
@@ -0,0 +1,232 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"run-script-service/service"
+)
+
+func TestRequestClass(t *testing.T) {
+	tests := []struct {
+		method, route, expected string
+		upload                  bool
+	}{
+		{"GET", "/scripts", service.RequestClassRead, false},
+		{"HEAD", "/status", service.RequestClassRead, false},
+		{"POST", "/scripts/backup/run", service.RequestClassRun, false},
+		{"POST", "/scripts", service.RequestClassWrite, false},
+		{"POST", "/scripts/backup/enable", service.RequestClassWrite, false},
+		{"DELETE", "/logs/backup", service.RequestClassWrite, false},
+		{"PUT", "/files/backup.sh", service.RequestClassWrite, true},
+		{"POST", "/import", service.RequestClassWrite, true},
+		{"POST", "/files/validate", service.RequestClassWrite, false},
+	}
+	for _, tt := range tests {
+		if class := requestClass(tt.method, tt.route); class != tt.expected {
+			t.Errorf("requestClass(%s %s) = %s, expected %s", tt.method, tt.route, class, tt.expected)
+		}
+		if upload := isUpload(tt.method, tt.route); upload != tt.upload {
+			t.Errorf("isUpload(%s %s) = %v, expected %v", tt.method, tt.route, upload, tt.upload)
+		}
+	}
+}
+
+func TestWebServer_RateLimit(t *testing.T) {
+	server := createTestServerWithScripts([]service.ScriptConfig{{Name: "backup", Path: "./backup.sh", Interval: 60}})
+	server.SetRateLimits(&service.RateLimitConfig{RequestsPerMinute: 3, RunsPerMinute: 2})
+
+	send := func(method, path, remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.RemoteAddr = remoteAddr
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		return w
+	}
+
+	for i := 0; i < 3; i++ {
+		if w := send("GET", "/api/v1/scripts", "192.0.2.1:1234"); w.Code != http.StatusOK {
+			t.Fatalf("Expected read %d to pass, got %d", i+1, w.Code)
+		}
+	}
+	w := send("GET", "/api/scripts", "192.0.2.1:5678")
+	if w.Code != http.StatusTooManyRequests || decodeError(t, w).Code != ErrorRateLimited {
+		t.Fatalf("Expected the 4th read refused with rate_limited, got %d: %s", w.Code, w.Body.String())
+	}
+	if retry := w.Header().Get("Retry-After"); retry != "20" {
+		t.Errorf("Expected Retry-After 20, got %q", retry)
+	}
+	if w := send("GET", "/api/v1/scripts", "198.51.100.7:1234"); w.Code != http.StatusOK {
+		t.Errorf("Expected another client to pass, got %d", w.Code)
+	}
+
+	// Runs have their own, stricter bucket
+	for i := 0; i < 2; i++ {
+		if w := send("POST", "/api/v1/scripts/missing/run", "192.0.2.1:1234"); w.Code != http.StatusNotFound {
+			t.Fatalf("Expected run %d to reach the handler, got %d", i+1, w.Code)
+		}
+	}
+	if w := send("POST", "/api/v1/scripts/missing/run", "192.0.2.1:1234"); w.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected the 3rd run refused, got %d", w.Code)
+	}
+	if w := send("GET", "/ws", "192.0.2.1:1234"); w.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected WebSocket connections to be rate limited, got %d", w.Code)
+	}
+	if w := send("GET", "/", "192.0.2.1:1234"); w.Code == http.StatusTooManyRequests {
+		t.Error("Expected routes outside the API not to be rate limited")
+	}
+
+	server.SetRateLimits(&service.RateLimitConfig{Disabled: true})
+	for i := 0; i < 10; i++ {
+		if w := send("GET", "/api/v1/scripts", "192.0.2.1:1234"); w.Code != http.StatusOK {
+			t.Fatalf("Expected no rate limiting when disabled, got %d", w.Code)
+		}
+	}
+}
+
+func TestWebServer_RateLimitByPrincipal(t *testing.T) {
+	server, token := createTestServerWithAuth(t, nil)
+	server.SetRateLimits(&service.RateLimitConfig{RequestsPerMinute: 2})
+
+	var codes []int
+	for _, remoteAddr := range []string{"192.0.2.1:1", "192.0.2.2:1", "192.0.2.3:1"} {
+		req := httptest.NewRequest("GET", "/api/v1/scripts", nil)
+		req.RemoteAddr = remoteAddr
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		codes = append(codes, w.Code)
+	}
+	if codes[0] != http.StatusOK || codes[1] != http.StatusOK || codes[2] != http.StatusTooManyRequests {
+		t.Errorf("Expected a token limited across addresses, got %v", codes)
+	}
+
+	req := httptest.NewRequest("GET", "/api/v1/scripts", nil)
+	req.SetBasicAuth("alice", "password1")
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected a user to have its own bucket, got %d", w.Code)
+	}
+}
+
+func TestWebServer_AuthFailuresThrottled(t *testing.T) {
+	server, token := createTestServerWithAuth(t, nil)
+	server.SetRateLimits(&service.RateLimitConfig{AuthFailuresPerMinute: 3})
+
+	send := func(remoteAddr string, setup func(r *http.Request)) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/api/v1/scripts", nil)
+		req.RemoteAddr = remoteAddr
+		setup(req)
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		return w
+	}
+	badPassword := func(r *http.Request) { r.SetBasicAuth("alice", "guess") }
+	goodPassword := func(r *http.Request) { r.SetBasicAuth("alice", "password1") }
+
+	// Successful authentications do not count
+	for i := 0; i < 5; i++ {
+		if w := send("192.0.2.1:1", goodPassword); w.Code != http.StatusOK {
+			t.Fatalf("Expected request %d to pass, got %d", i+1, w.Code)
+		}
+	}
+
+	for i := 0; i < 2; i++ {
+		if w := send("192.0.2.1:1", badPassword); w.Code != http.StatusUnauthorized {
+			t.Fatalf("Expected guess %d to be checked, got %d", i+1, w.Code)
+		}
+	}
+	login := httptest.NewRequest("POST", "/api/v1/auth/login", strings.NewReader(`{"username": "alice", "password": "guess"}`))
+	login.RemoteAddr = "192.0.2.1:2"
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, login)
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("Expected the failed login to be checked, got %d", w.Code)
+	}
+
+	w = send("192.0.2.1:3", badPassword)
+	if w.Code != http.StatusTooManyRequests || decodeError(t, w).Code != ErrorRateLimited {
+		t.Fatalf("Expected further guesses refused with rate_limited, got %d: %s", w.Code, w.Body.String())
+	}
+	// The bucket refills while the passwords are checked, so the wait may be shorter than a token's 20s
+	if retry, err := strconv.Atoi(w.Header().Get("Retry-After")); err != nil || retry < 1 || retry > 20 {
+		t.Errorf("Expected Retry-After between 1 and 20, got %q", w.Header().Get("Retry-After"))
+	}
+	if w := send("192.0.2.1:3", goodPassword); w.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected the address to be refused until it recovers, got %d", w.Code)
+	}
+	if w := send("198.51.100.7:1", func(r *http.Request) { r.Header.Set("Authorization", "Bearer "+token) }); w.Code != http.StatusOK {
+		t.Errorf("Expected other addresses to pass, got %d", w.Code)
+	}
+	if count := server.limitCounters.auths.Load(); count != 2 {
+		t.Errorf("Expected 2 requests refused for failed authentications, got %d", count)
+	}
+}
+
+func TestWebServer_BodyLimits(t *testing.T) {
+	server := createTestServerWithScripts(nil)
+	server.SetFileManager(service.NewFileManager(t.TempDir()))
+	server.SetRateLimits(&service.RateLimitConfig{MaxBodyBytes: 64, MaxUploadBytes: 256})
+
+	large := `{"name": "backup", "path": "./backup.sh", "tags": ["` + strings.Repeat("a", 100) + `"]}`
+	upload := `{"content": "` + strings.Repeat("echo ok; ", 20) + `"}`
+	tests := []struct {
+		name, method, path, body string
+		chunked                  bool
+		status                   int
+	}{
+		{"declared length", "POST", "/api/v1/scripts", large, false, http.StatusRequestEntityTooLarge},
+		{"chunked", "POST", "/api/v1/scripts", large, true, http.StatusRequestEntityTooLarge},
+		{"raw body", "POST", "/api/v1/config/validate", large, true, http.StatusRequestEntityTooLarge},
+		{"upload", "PUT", "/api/v1/files/ok.sh", upload, false, http.StatusOK},
+		{"large upload", "PUT", "/api/v1/files/big.sh", `{"content": "` + strings.Repeat("echo ok; ", 40) + `"}`, true, http.StatusRequestEntityTooLarge},
+		{"small body", "POST", "/api/v1/scripts", `{"name": "a", "path": "./a.sh"}`, false, http.StatusCreated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			if tt.chunked {
+				req.ContentLength = -1
+			}
+			w := httptest.NewRecorder()
+			server.router.ServeHTTP(w, req)
+			if w.Code != tt.status {
+				t.Fatalf("Expected status %d, got %d: %s", tt.status, w.Code, w.Body.String())
+			}
+			if tt.status == http.StatusRequestEntityTooLarge && decodeError(t, w).Code != ErrorTooLarge {
+				t.Errorf("Expected payload_too_large, got %s", w.Body.String())
+			}
+		})
+	}
+	if count := server.limitCounters.tooLarge.Load(); count != 4 {
+		t.Errorf("Expected 4 requests counted as too large, got %d", count)
+	}
+}
+
+func TestWebServer_LimitMetrics(t *testing.T) {
+	server := createTestServerWithScripts(nil)
+	server.SetRateLimits(&service.RateLimitConfig{RunsPerMinute: 1, MaxBodyBytes: 8})
+
+	for i := 0; i < 2; i++ {
+		server.router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/api/v1/scripts/missing/run", nil))
+	}
+	server.router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/api/v1/scripts", strings.NewReader(`{"name": "toolong"}`)))
+
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/metrics", nil))
+	for _, sample := range []string{
+		`run_script_http_rate_limited_total{class="run"} 1`,
+		`run_script_http_rate_limited_total{class="read"} 0`,
+		`run_script_http_rate_limited_total{class="auth"} 0`,
+		"run_script_http_body_too_large_total 1",
+	} {
+		if !strings.Contains(w.Body.String(), sample) {
+			t.Errorf("Expected %s in:\n%s", sample, w.Body.String())
+		}
+	}
+}
@@ -3,19 +3,23 @@ package web
 
 import (
 	"context"
+	"crypto/tls"
 	"embed"
 	"fmt"
 	"io"
 	"io/fs"
 	"net/http"
 	"os"
-	"path/filepath"
+	"reflect"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
 
 	"run-script-service/service"
 )
@@ -26,51 +30,88 @@ var frontendFS embed.FS
 // WebServer represents the HTTP API server
 type WebServer struct {
 	router        *gin.Engine
-	service       *service.Service
 	scriptManager *service.ScriptManager
 	fileManager   *service.FileManager
 	wsHub         *WebSocketHub
 	systemMonitor *service.SystemMonitor
+	eventBridge   *EventBridge
+	bindAddress   string
 	port          int
+	auth          *webAuth // nil until SetAuth is called
+	authMutex     sync.RWMutex
+	limits        *requestLimits // rate and body size limits of API requests
+	limitsMutex   sync.RWMutex
+	limitCounters limitCounters
+	auditLog      *service.AuditLog // nil records nothing
+	tlsConfig     *tls.Config       // nil serves plain HTTP
+	httpServer    *http.Server      // set once serving, for Shutdown
+	serverMutex   sync.Mutex
 }
 
 // APIResponse represents the standard API response format
 type APIResponse struct {
-	Success bool        `json:"success"`
-	Data    interface{} `json:"data,omitempty"`
-	Error   string      `json:"error,omitempty"`
+	Success   bool                  `json:"success"`
+	Data      interface{}           `json:"data,omitempty"`
+	Error     string                `json:"error,omitempty"`
+	Code      ErrorCode             `json:"code,omitempty"`       // set on failures
+	Details   []service.ConfigIssue `json:"details,omitempty"`    // invalid fields of validation failures
+	RequestID string                `json:"request_id,omitempty"` // set on failures, also in X-Request-ID
 }
 
-// LogEntry represents a structured log entry for the frontend
+// LogEntry represents one script run for the frontend
 type LogEntry struct {
 	Timestamp string `json:"timestamp"`
 	Message   string `json:"message"`
 	Level     string `json:"level"` // "info", "warning", "error"
 	Script    string `json:"script,omitempty"`
+	ExitCode  int    `json:"exit_code"`
+	Duration  int64  `json:"duration_ms"`
+	Stdout    string `json:"stdout,omitempty"`
+	Stderr    string `json:"stderr,omitempty"`
+	Trigger   string `json:"trigger,omitempty"`
+	RunID     string `json:"run_id,omitempty"`
+
+	Result        map[string]interface{} `json:"result,omitempty"`
+	Outcome       string                 `json:"outcome,omitempty"`
+	OutcomeReason string                 `json:"outcome_reason,omitempty"`
+	Artifacts     int                    `json:"artifacts,omitempty"`
 }
 
 // NewWebServer creates a new web server instance
-func NewWebServer(svc *service.Service, port int) *WebServer {
+func NewWebServer(port int) *WebServer {
 	// Set Gin to release mode for production
 	gin.SetMode(gin.ReleaseMode)
 
 	router := gin.New()
 
-	// Add middleware
-	router.Use(gin.Logger())
+	// Add middleware, request logging is noise above info level
+	if service.GetLogLevel() <= service.LevelInfo {
+		router.Use(gin.Logger())
+	}
 	router.Use(gin.Recovery())
-	router.Use(cors.Default())
 
 	// Create WebSocket hub
 	wsHub := NewWebSocketHub()
 	go wsHub.Run()
 
 	server := &WebServer{
-		router:  router,
-		service: svc,
-		wsHub:   wsHub,
-		port:    port,
+		router: router,
+		wsHub:  wsHub,
+		port:   port,
 	}
+	// Pages on other origins may only call the API when allowed in auth.allowed_origins
+	router.Use(cors.New(cors.Config{
+		AllowOriginFunc: server.allowedOrigin,
+		AllowMethods:    []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS"},
+		AllowHeaders:    []string{"Origin", "Content-Type", "Authorization", "X-Requested-With", RequestIDHeader},
+		ExposeHeaders:   []string{"Location", "Retry-After", RequestIDHeader},
+		MaxAge:          12 * time.Hour,
+	}))
+	router.Use(requestIDMiddleware())
+	router.Use(server.authFailureMiddleware())
+	router.Use(server.authMiddleware())
+	router.Use(server.limitMiddleware())
+	server.SetRateLimits(nil)
 
 	// Setup routes
 	server.setupRoutes()
@@ -78,9 +119,49 @@ func NewWebServer(svc *service.Service, port int) *WebServer {
 	return server
 }
 
-// SetScriptManager sets the script manager for the web server
+// SetScriptManager sets the script manager for the web server and forwards its
+// script status events to WebSocket clients
 func (ws *WebServer) SetScriptManager(sm *service.ScriptManager) {
+	if ws.eventBridge != nil {
+		ws.eventBridge.Close()
+		ws.eventBridge = nil
+	}
 	ws.scriptManager = sm
+	if sm != nil {
+		ws.eventBridge = NewEventBridge(ws.wsHub, sm.GetEventBroadcaster())
+	}
+}
+
+// ApplyConfig follows a configuration reload: changed rate limits and authentication
+// settings replace the current ones. Authentication is kept when unchanged, so sessions
+// survive unrelated reloads.
+func (ws *WebServer) ApplyConfig(previous, current *service.ServiceConfig) {
+	if !reflect.DeepEqual(previous.RateLimit, current.RateLimit) {
+		ws.SetRateLimits(current.RateLimit)
+	}
+	if auth := ws.getAuth(); auth != nil && !reflect.DeepEqual(previous.Auth, current.Auth) {
+		ws.SetAuth(auth.store, current.Auth)
+	}
+}
+
+// SetBindAddress sets the interface address the server listens on. Empty means all
+// interfaces, unix:<path> listens on a unix domain socket instead of the port.
+func (ws *WebServer) SetBindAddress(address string) {
+	ws.bindAddress = address
+}
+
+// SetTLSConfig serves HTTPS with config, nil serves plain HTTP. TLS is not used on unix sockets.
+func (ws *WebServer) SetTLSConfig(config *tls.Config) {
+	ws.tlsConfig = config
+}
+
+// scriptLogPath returns the log file path for a script in the script manager's log directory
+func (ws *WebServer) scriptLogPath(scriptName string) string {
+	dir := service.ExecutableDir()
+	if ws.scriptManager != nil {
+		dir = ws.scriptManager.GetLogManager().GetBaseDir()
+	}
+	return service.LogFilePath(dir, scriptName)
 }
 
 // GetWebSocketHub returns the WebSocket hub for broadcasting messages
@@ -118,14 +199,14 @@ func (ws *WebServer) setupRoutes() {
 	// Create a sub filesystem for the dist directory
 	distFS, err := fs.Sub(frontendFS, "frontend/dist")
 	if err != nil {
-		fmt.Printf("DEBUG: embed fs.Sub failed: %v, using fallback\n", err)
+		service.Debugf("embed fs.Sub failed: %v, using fallback", err)
 		// Fallback to file system if embed fails (development mode)
 		ws.router.Static("/static", "./web/frontend/dist")
 		ws.router.GET("/", func(c *gin.Context) {
 			c.File("./web/frontend/dist/index.html")
 		})
 	} else {
-		fmt.Println("DEBUG: Using embedded filesystem")
+		service.Debugf("Using embedded filesystem")
 		// Use embedded filesystem for static files
 		ws.router.StaticFS("/static", http.FS(distFS))
 
@@ -133,7 +214,7 @@ func (ws *WebServer) setupRoutes() {
 		ws.router.GET("/", func(c *gin.Context) {
 			indexFile, err := distFS.Open("index.html")
 			if err != nil {
-				fmt.Printf("DEBUG: Failed to open embedded index.html: %v\n", err)
+				service.Debugf("Failed to open embedded index.html: %v", err)
 				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load frontend"})
 				return
 			}
@@ -149,14 +230,14 @@ func (ws *WebServer) setupRoutes() {
 
 			// If it's an API route, let it 404
 			if strings.HasPrefix(path, "/api/") || strings.HasPrefix(path, "/ws") {
-				c.JSON(http.StatusNotFound, gin.H{"error": "Not found"})
+				respondError(c, ErrorNotFound, "Not found")
 				return
 			}
 
 			// For all other routes, serve index.html (Vue.js SPA)
 			indexFile, err := distFS.Open("index.html")
 			if err != nil {
-				fmt.Printf("DEBUG: Failed to open embedded index.html in NoRoute: %v\n", err)
+				service.Debugf("Failed to open embedded index.html in NoRoute: %v", err)
 				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load frontend"})
 				return
 			}
@@ -168,15 +249,25 @@ func (ws *WebServer) setupRoutes() {
 	}
 
 	// WebSocket endpoint
+	wsUpgrader := &websocket.Upgrader{CheckOrigin: ws.checkOrigin}
 	ws.router.GET("/ws", func(c *gin.Context) {
-		HandleWebSocket(ws.wsHub, c)
+		serveWebSocket(ws.wsHub, wsUpgrader, c)
 	})
 
-	api := ws.router.Group("/api")
+	// API routes of the current version and their deprecated aliases
+	for _, api := range ws.apiGroups() {
+		ws.setupAPIRoutes(api)
+	}
+}
 
+// setupAPIRoutes registers the API routes on a version group
+func (ws *WebServer) setupAPIRoutes(api *gin.RouterGroup) {
 	// System status endpoint
 	api.GET("/status", ws.handleStatus)
 
+	// OpenAPI document and its docs page
+	ws.setupDocsRoutes(api)
+
 	// Script management endpoints
 	api.GET("/scripts", ws.handleGetScripts)
 	api.POST("/scripts", ws.handlePostScript)
@@ -189,13 +280,42 @@ func (ws *WebServer) setupRoutes() {
 
 	// Log management endpoints
 	api.GET("/logs", ws.handleGetLogs)
+	api.GET("/logs/search", ws.handleSearchLogs)
+	api.GET("/logs/export", ws.handleExportLogs)
 	api.GET("/logs/:script", ws.handleGetScriptLogs)
 	api.GET("/logs/raw/:script", ws.handleGetRawLogs) // New simple endpoint
 	api.DELETE("/logs/:script", ws.handleClearScriptLogs)
 
+	// Run metrics
+	api.GET("/metrics", ws.handleGetMetrics)
+
+	// Web interface sessions
+	ws.setupAuthRoutes(api)
+
+	// Audit log of changes
+	api.GET("/audit", ws.handleGetAudit)
+
+	// Manual runs and their queue
+	api.GET("/runs/:id", ws.handleGetRun)
+	api.GET("/queue", ws.handleGetQueue)
+
+	// Run artifacts
+	api.GET("/runs/:id/artifacts", ws.handleListArtifacts)
+	api.GET("/runs/:id/artifacts/*path", ws.handleDownloadArtifact)
+
 	// Configuration endpoints
 	api.GET("/config", ws.handleGetConfig)
 	api.PUT("/config", ws.handleUpdateConfig)
+	api.POST("/config/validate", ws.handleValidateConfig)
+	api.GET("/config/schema", ws.handleGetConfigSchema)
+	api.GET("/config/history", ws.handleGetConfigHistory)
+	api.GET("/config/history/diff", ws.handleDiffConfigVersions)
+	api.GET("/config/history/:version", ws.handleGetConfigVersion)
+	api.POST("/config/history/:version/rollback", ws.handleRollbackConfig)
+
+	// Script import/export
+	api.GET("/export", ws.handleExport)
+	api.POST("/import", ws.handleImport)
 }
 
 // handleStatus returns system status information
@@ -241,10 +361,7 @@ func (ws *WebServer) handleStatus(c *gin.Context) {
 // handleGetScripts returns all scripts
 func (ws *WebServer) handleGetScripts(c *gin.Context) {
 	if ws.scriptManager == nil {
-		c.JSON(http.StatusInternalServerError, APIResponse{
-			Success: false,
-			Error:   "Script manager not initialized",
-		})
+		respondError(c, ErrorInternal, "Script manager not initialized")
 		return
 	}
 
@@ -260,6 +377,9 @@ func (ws *WebServer) handleGetScripts(c *gin.Context) {
 			"enabled":       scriptConfig.Enabled,
 			"max_log_lines": scriptConfig.MaxLogLines,
 			"timeout":       scriptConfig.Timeout,
+			"tags":          scriptConfig.Tags,
+			"pools":         scriptConfig.Pools,
+			"priority":      scriptConfig.Priority,
 			"running":       running,
 		})
 	}
@@ -273,36 +393,32 @@ func (ws *WebServer) handleGetScripts(c *gin.Context) {
 // handlePostScript creates a new script
 func (ws *WebServer) handlePostScript(c *gin.Context) {
 	if ws.scriptManager == nil {
-		c.JSON(http.StatusInternalServerError, APIResponse{
-			Success: false,
-			Error:   "Script manager not initialized",
-		})
+		respondError(c, ErrorInternal, "Script manager not initialized")
 		return
 	}
 
 	var scriptConfig service.ScriptConfig
-	if err := c.ShouldBindJSON(&scriptConfig); err != nil {
-		c.JSON(http.StatusBadRequest, APIResponse{
-			Success: false,
-			Error:   fmt.Sprintf("Invalid request body: %v", err),
-		})
+	if !bindJSON(c, &scriptConfig) {
 		return
 	}
 
 	// Validate required fields
+	var missing []service.ConfigIssue
 	if scriptConfig.Name == "" {
-		c.JSON(http.StatusBadRequest, APIResponse{
-			Success: false,
-			Error:   "Script name is required",
-		})
-		return
+		missing = append(missing, requiredField("$.name"))
 	}
-
 	if scriptConfig.Path == "" {
-		c.JSON(http.StatusBadRequest, APIResponse{
-			Success: false,
-			Error:   "Script path is required",
-		})
+		missing = append(missing, requiredField("$.path"))
+	}
+	if len(missing) > 0 {
+		message := "Script path is required"
+		if scriptConfig.Name == "" {
+			message = "Script name is required"
+		}
+		respondError(c, ErrorValidationFailed, message, missing...)
+		return
+	}
+	if !ws.authorize(c, service.RoleEditor, fmt.Sprintf("adding script '%s'", scriptConfig.Name), scriptConfig) {
 		return
 	}
 
@@ -314,14 +430,16 @@ func (ws *WebServer) handlePostScript(c *gin.Context) {
 		scriptConfig.MaxLogLines = 100 // Default to 100 lines
 	}
 
+	if !ws.validateScript(c, scriptConfig) {
+		return
+	}
+
 	// Add the script
 	if err := ws.scriptManager.AddScript(scriptConfig); err != nil {
-		c.JSON(http.StatusConflict, APIResponse{
-			Success: false,
-			Error:   err.Error(),
-		})
+		respondServiceError(c, err, ErrorInternal)
 		return
 	}
+	ws.recordAudit(c, service.AuditScriptAdd, scriptConfig.Name, "", nil, scriptConfig)
 
 	c.JSON(http.StatusCreated, APIResponse{
 		Success: true,
@@ -332,48 +450,10 @@ func (ws *WebServer) handlePostScript(c *gin.Context) {
 			"enabled":       scriptConfig.Enabled,
 			"max_log_lines": scriptConfig.MaxLogLines,
 			"timeout":       scriptConfig.Timeout,
-		},
-	})
-}
-
-// handleRunScript executes a script once
-func (ws *WebServer) handleRunScript(c *gin.Context) {
-	if ws.scriptManager == nil {
-		c.JSON(http.StatusInternalServerError, APIResponse{
-			Success: false,
-			Error:   "Script manager not initialized",
-		})
-		return
-	}
-
-	scriptName := c.Param("name")
-	if scriptName == "" {
-		c.JSON(http.StatusBadRequest, APIResponse{
-			Success: false,
-			Error:   "Script name is required",
-		})
-		return
-	}
-
-	// Run the script with a timeout context
-	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
-	defer cancel()
-
-	if err := ws.scriptManager.RunScriptOnce(ctx, scriptName); err != nil {
-		c.JSON(http.StatusNotFound, APIResponse{
-			Success: false,
-			Error:   err.Error(),
-		})
-		return
-	}
-
-	// Note: Logs are now handled via raw file access in /logs/raw/:script endpoint
-
-	c.JSON(http.StatusOK, APIResponse{
-		Success: true,
-		Data: map[string]interface{}{
-			"message": fmt.Sprintf("Script %s executed successfully", scriptName),
-			"script":  scriptName,
+			"tags":          scriptConfig.Tags,
+			"pools":         scriptConfig.Pools,
+			"priority":      scriptConfig.Priority,
+			"running":       ws.scriptManager.IsScriptRunning(scriptConfig.Name),
 		},
 	})
 }
@@ -381,19 +461,13 @@ func (ws *WebServer) handleRunScript(c *gin.Context) {
 // handleGetScript returns information about a specific script
 func (ws *WebServer) handleGetScript(c *gin.Context) {
 	if ws.scriptManager == nil {
-		c.JSON(http.StatusInternalServerError, APIResponse{
-			Success: false,
-			Error:   "Script manager not initialized",
-		})
+		respondError(c, ErrorInternal, "Script manager not initialized")
 		return
 	}
 
 	scriptName := c.Param("name")
 	if scriptName == "" {
-		c.JSON(http.StatusBadRequest, APIResponse{
-			Success: false,
-			Error:   "Script name is required",
-		})
+		respondError(c, ErrorBadRequest, "Script name is required")
 		return
 	}
 
@@ -410,6 +484,9 @@ func (ws *WebServer) handleGetScript(c *gin.Context) {
 				"enabled":       scriptConfig.Enabled,
 				"max_log_lines": scriptConfig.MaxLogLines,
 				"timeout":       scriptConfig.Timeout,
+				"tags":          scriptConfig.Tags,
+				"pools":         scriptConfig.Pools,
+				"priority":      scriptConfig.Priority,
 				"running":       running,
 			}
 
@@ -421,37 +498,29 @@ func (ws *WebServer) handleGetScript(c *gin.Context) {
 		}
 	}
 
-	c.JSON(http.StatusNotFound, APIResponse{
-		Success: false,
-		Error:   fmt.Sprintf("Script '%s' not found", scriptName),
-	})
+	respondError(c, ErrorNotFound, fmt.Sprintf("Script '%s' not found", scriptName))
 }
 
 // handleUpdateScript updates a script configuration
 func (ws *WebServer) handleUpdateScript(c *gin.Context) {
 	if ws.scriptManager == nil {
-		c.JSON(http.StatusInternalServerError, APIResponse{
-			Success: false,
-			Error:   "Script manager not initialized",
-		})
+		respondError(c, ErrorInternal, "Script manager not initialized")
 		return
 	}
 
 	scriptName := c.Param("name")
 	if scriptName == "" {
-		c.JSON(http.StatusBadRequest, APIResponse{
-			Success: false,
-			Error:   "Script name is required",
-		})
+		respondError(c, ErrorBadRequest, "Script name is required")
 		return
 	}
 
 	var updateData service.ScriptConfig
-	if err := c.ShouldBindJSON(&updateData); err != nil {
-		c.JSON(http.StatusBadRequest, APIResponse{
-			Success: false,
-			Error:   fmt.Sprintf("Invalid request body: %v", err),
-		})
+	if !bindJSON(c, &updateData) {
+		return
+	}
+	// Check the new tags too, so a script cannot be moved out of reach of its grants
+	updateData.Name = scriptName
+	if !ws.authorize(c, service.RoleEditor, fmt.Sprintf("changing script '%s'", scriptName), ws.scriptConfig(scriptName), updateData) {
 		return
 	}
 
@@ -463,14 +532,22 @@ func (ws *WebServer) handleUpdateScript(c *gin.Context) {
 		updateData.MaxLogLines = 100 // Default to 100 lines
 	}
 
+	before, exists := ws.findScript(scriptName)
+	if !exists {
+		respondError(c, ErrorNotFound, fmt.Sprintf("Script '%s' not found", scriptName))
+		return
+	}
+	if !ws.validateScript(c, updateData) {
+		return
+	}
+
 	// Update the script
 	if err := ws.scriptManager.UpdateScript(scriptName, updateData); err != nil {
-		c.JSON(http.StatusNotFound, APIResponse{
-			Success: false,
-			Error:   err.Error(),
-		})
+		respondServiceError(c, err, ErrorInternal)
 		return
 	}
+	after, _ := ws.findScript(scriptName)
+	ws.recordAudit(c, service.AuditScriptUpdate, scriptName, "", before, after)
 
 	c.JSON(http.StatusOK, APIResponse{
 		Success: true,
@@ -487,33 +564,40 @@ func (ws *WebServer) handleUpdateScript(c *gin.Context) {
 	})
 }
 
+// validateScript checks a script as it would be stored, responding 400 with the invalid fields
+func (ws *WebServer) validateScript(c *gin.Context, script service.ScriptConfig) bool {
+	issues := service.ValidateScript(ws.scriptManager.GetConfig(), script)
+	if len(issues) == 0 {
+		return true
+	}
+	respondError(c, ErrorValidationFailed, fmt.Sprintf("Invalid script '%s': %s", script.Name, issues[0].Message), issues...)
+	return false
+}
+
 // handleDeleteScript removes a script
 func (ws *WebServer) handleDeleteScript(c *gin.Context) {
 	if ws.scriptManager == nil {
-		c.JSON(http.StatusInternalServerError, APIResponse{
-			Success: false,
-			Error:   "Script manager not initialized",
-		})
+		respondError(c, ErrorInternal, "Script manager not initialized")
 		return
 	}
 
 	scriptName := c.Param("name")
 	if scriptName == "" {
-		c.JSON(http.StatusBadRequest, APIResponse{
-			Success: false,
-			Error:   "Script name is required",
-		})
+		respondError(c, ErrorBadRequest, "Script name is required")
+		return
+	}
+
+	if !ws.authorize(c, service.RoleEditor, fmt.Sprintf("deleting script '%s'", scriptName), ws.scriptConfig(scriptName)) {
 		return
 	}
 
 	// Remove the script
+	before, _ := ws.findScript(scriptName)
 	if err := ws.scriptManager.RemoveScript(scriptName); err != nil {
-		c.JSON(http.StatusNotFound, APIResponse{
-			Success: false,
-			Error:   err.Error(),
-		})
+		respondServiceError(c, err, ErrorInternal)
 		return
 	}
+	ws.recordAudit(c, service.AuditScriptDelete, scriptName, "", before, nil)
 
 	c.JSON(http.StatusOK, APIResponse{
 		Success: true,
@@ -527,39 +611,43 @@ func (ws *WebServer) handleDeleteScript(c *gin.Context) {
 // handleScriptToggle handles both enable and disable script operations
 func (ws *WebServer) handleScriptToggle(c *gin.Context, enable bool) {
 	if ws.scriptManager == nil {
-		c.JSON(http.StatusInternalServerError, APIResponse{
-			Success: false,
-			Error:   "Script manager not initialized",
-		})
+		respondError(c, ErrorInternal, "Script manager not initialized")
 		return
 	}
 
 	scriptName := c.Param("name")
 	if scriptName == "" {
-		c.JSON(http.StatusBadRequest, APIResponse{
-			Success: false,
-			Error:   "Script name is required",
-		})
+		respondError(c, ErrorBadRequest, "Script name is required")
+		return
+	}
+
+	verb := "disabling"
+	if enable {
+		verb = "enabling"
+	}
+	if !ws.authorize(c, service.RoleOperator, fmt.Sprintf("%s script '%s'", verb, scriptName), ws.scriptConfig(scriptName)) {
 		return
 	}
 
 	var err error
 	var action string
+	auditAction := service.AuditScriptDisable
+	before, _ := ws.findScript(scriptName)
 	if enable {
 		err = ws.scriptManager.EnableScript(scriptName)
 		action = "enabled"
+		auditAction = service.AuditScriptEnable
 	} else {
 		err = ws.scriptManager.DisableScript(scriptName)
 		action = "disabled"
 	}
 
 	if err != nil {
-		c.JSON(http.StatusNotFound, APIResponse{
-			Success: false,
-			Error:   err.Error(),
-		})
+		respondServiceError(c, err, ErrorInternal)
 		return
 	}
+	after, _ := ws.findScript(scriptName)
+	ws.recordAudit(c, auditAction, scriptName, "", before, after)
 
 	c.JSON(http.StatusOK, APIResponse{
 		Success: true,
@@ -614,22 +702,11 @@ func (ws *WebServer) handleGetLogs(c *gin.Context) {
 func (ws *WebServer) handleGetScriptLogs(c *gin.Context) {
 	scriptName := c.Param("script")
 	if scriptName == "" {
-		c.JSON(http.StatusBadRequest, APIResponse{
-			Success: false,
-			Error:   "Script name is required",
-		})
+		respondError(c, ErrorBadRequest, "Script name is required")
 		return
 	}
 
-	// Get log file path
-	dir, err := os.Executable()
-	if err != nil {
-		dir, _ = os.Getwd()
-	} else {
-		dir = filepath.Dir(dir)
-	}
-
-	logFile := filepath.Join(dir, fmt.Sprintf("%s.log", scriptName))
+	logFile := ws.scriptLogPath(scriptName)
 
 	// Check if log file exists
 	if _, err := os.Stat(logFile); os.IsNotExist(err) {
@@ -647,10 +724,7 @@ func (ws *WebServer) handleGetScriptLogs(c *gin.Context) {
 	// Read raw log file content
 	content, err := os.ReadFile(logFile)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, APIResponse{
-			Success: false,
-			Error:   fmt.Sprintf("Failed to read log file: %v", err),
-		})
+		respondError(c, ErrorInternal, fmt.Sprintf("Failed to read log file: %v", err))
 		return
 	}
 
@@ -667,31 +741,26 @@ func (ws *WebServer) handleGetScriptLogs(c *gin.Context) {
 func (ws *WebServer) handleClearScriptLogs(c *gin.Context) {
 	scriptName := c.Param("script")
 	if scriptName == "" {
-		c.JSON(http.StatusBadRequest, APIResponse{
-			Success: false,
-			Error:   "Script name is required",
-		})
+		respondError(c, ErrorBadRequest, "Script name is required")
 		return
 	}
 
-	// Get log file path
-	dir, err := os.Executable()
-	if err != nil {
-		dir, _ = os.Getwd()
-	} else {
-		dir = filepath.Dir(dir)
+	if !ws.authorize(c, service.RoleEditor, fmt.Sprintf("clearing the logs of script '%s'", scriptName), ws.scriptConfig(scriptName)) {
+		return
 	}
 
-	logFile := filepath.Join(dir, fmt.Sprintf("%s.log", scriptName))
-
-	// Clear the log file by truncating it
-	if err := os.Truncate(logFile, 0); err != nil {
-		c.JSON(http.StatusInternalServerError, APIResponse{
-			Success: false,
-			Error:   fmt.Sprintf("Failed to clear log file: %v", err),
-		})
+	// Clear through the LogManager so entries it already loaded go too
+	var err error
+	if ws.scriptManager != nil {
+		err = ws.scriptManager.GetLogManager().ClearLogs(scriptName)
+	} else {
+		err = os.Truncate(ws.scriptLogPath(scriptName), 0)
+	}
+	if err != nil {
+		respondError(c, ErrorInternal, fmt.Sprintf("Failed to clear log file: %v", err))
 		return
 	}
+	ws.recordAudit(c, service.AuditLogsClear, scriptName, "", nil, nil)
 
 	c.JSON(http.StatusOK, APIResponse{
 		Success: true,
@@ -706,22 +775,11 @@ func (ws *WebServer) handleClearScriptLogs(c *gin.Context) {
 func (ws *WebServer) handleGetRawLogs(c *gin.Context) {
 	scriptName := c.Param("script")
 	if scriptName == "" {
-		c.JSON(http.StatusBadRequest, APIResponse{
-			Success: false,
-			Error:   "Script name is required",
-		})
+		respondError(c, ErrorBadRequest, "Script name is required")
 		return
 	}
 
-	// Get log file path
-	dir, err := os.Executable()
-	if err != nil {
-		dir, _ = os.Getwd()
-	} else {
-		dir = filepath.Dir(dir)
-	}
-
-	logFile := filepath.Join(dir, fmt.Sprintf("%s.log", scriptName))
+	logFile := ws.scriptLogPath(scriptName)
 
 	// Check if log file exists
 	if _, err := os.Stat(logFile); os.IsNotExist(err) {
@@ -739,10 +797,7 @@ func (ws *WebServer) handleGetRawLogs(c *gin.Context) {
 	// Read log file content
 	content, err := os.ReadFile(logFile)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, APIResponse{
-			Success: false,
-			Error:   fmt.Sprintf("Failed to read log file: %v", err),
-		})
+		respondError(c, ErrorInternal, fmt.Sprintf("Failed to read log file: %v", err))
 		return
 	}
 
@@ -766,10 +821,7 @@ type ConfigResponse struct {
 // handleGetConfig returns system configuration
 func (ws *WebServer) handleGetConfig(c *gin.Context) {
 	if ws.scriptManager == nil {
-		c.JSON(http.StatusInternalServerError, APIResponse{
-			Success: false,
-			Error:   "Script manager not initialized",
-		})
+		respondError(c, ErrorInternal, "Script manager not initialized")
 		return
 	}
 
@@ -792,72 +844,49 @@ func (ws *WebServer) handleGetConfig(c *gin.Context) {
 // handleUpdateConfig updates system configuration
 func (ws *WebServer) handleUpdateConfig(c *gin.Context) {
 	if ws.scriptManager == nil {
-		c.JSON(http.StatusInternalServerError, APIResponse{
-			Success: false,
-			Error:   "Script manager not initialized",
-		})
+		respondError(c, ErrorInternal, "Script manager not initialized")
+		return
+	}
+	if !ws.authorize(c, service.RoleAdmin, "changing the configuration") {
 		return
 	}
 
 	var updateData map[string]interface{}
-	if err := c.ShouldBindJSON(&updateData); err != nil {
-		c.JSON(http.StatusBadRequest, APIResponse{
-			Success: false,
-			Error:   fmt.Sprintf("Invalid request body: %v", err),
-		})
+	if !bindJSON(c, &updateData) {
 		return
 	}
 
 	// Get current configuration
 	config := ws.scriptManager.GetConfig()
+	before := ws.configSnapshot()
 
 	// Update web port if provided (handle both camelCase and snake_case)
-	if webPort, ok := updateData["webPort"]; ok {
-		if port, isFloat := webPort.(float64); isFloat {
-			if port >= 1 && port <= 65535 {
-				config.WebPort = int(port)
-			} else {
-				c.JSON(http.StatusBadRequest, APIResponse{
-					Success: false,
-					Error:   "Web port must be between 1 and 65535",
-				})
-				return
-			}
-		} else {
-			c.JSON(http.StatusBadRequest, APIResponse{
-				Success: false,
-				Error:   "Web port must be a number",
-			})
+	for _, key := range []string{"webPort", "web_port"} {
+		webPort, ok := updateData[key]
+		if !ok {
+			continue
+		}
+		port, isFloat := webPort.(float64)
+		if !isFloat {
+			respondError(c, ErrorValidationFailed, "Web port must be a number",
+				service.ConfigIssue{Path: "$." + key, Message: "must be a number"})
 			return
 		}
-	} else if webPort, ok := updateData["web_port"]; ok {
-		if port, isFloat := webPort.(float64); isFloat {
-			if port >= 1 && port <= 65535 {
-				config.WebPort = int(port)
-			} else {
-				c.JSON(http.StatusBadRequest, APIResponse{
-					Success: false,
-					Error:   "Web port must be between 1 and 65535",
-				})
-				return
-			}
-		} else {
-			c.JSON(http.StatusBadRequest, APIResponse{
-				Success: false,
-				Error:   "Web port must be a number",
-			})
+		if port < 1 || port > 65535 {
+			respondError(c, ErrorValidationFailed, "Web port must be between 1 and 65535",
+				service.ConfigIssue{Path: "$." + key, Message: "must be between 1 and 65535"})
 			return
 		}
+		config.WebPort = int(port)
+		break
 	}
 
 	// Save updated configuration
 	if err := ws.scriptManager.SaveConfig(); err != nil {
-		c.JSON(http.StatusInternalServerError, APIResponse{
-			Success: false,
-			Error:   fmt.Sprintf("Failed to save configuration: %v", err),
-		})
+		respondError(c, ErrorInternal, fmt.Sprintf("Failed to save configuration: %v", err))
 		return
 	}
+	ws.recordAudit(c, service.AuditConfigUpdate, "configuration", "", before, ws.configSnapshot())
 
 	c.JSON(http.StatusOK, APIResponse{
 		Success: true,
@@ -868,13 +897,36 @@ func (ws *WebServer) handleUpdateConfig(c *gin.Context) {
 	})
 }
 
-// Start starts the web server
+// Start listens on the bind address and serves until the listener fails or Shutdown is called
 func (ws *WebServer) Start() error {
-	addr := fmt.Sprintf(":%d", ws.port)
-	return ws.router.Run(addr)
+	listener, err := ws.listen()
+	if err != nil {
+		return err
+	}
+	if err := ws.serve(listener); err != http.ErrServerClosed {
+		return err
+	}
+	return nil
 }
 
-// getAggregatedLogs returns logs from all scripts in LogEntry format
+// Shutdown sends close frames to WebSocket clients, then stops the HTTP server once the
+// requests in flight are answered or ctx is done
+func (ws *WebServer) Shutdown(ctx context.Context) error {
+	ws.wsHub.Close(ctx)
+
+	ws.serverMutex.Lock()
+	server := ws.httpServer
+	ws.serverMutex.Unlock()
+	if server == nil {
+		return nil
+	}
+	if err := server.Shutdown(ctx); err != nil {
+		return fmt.Errorf("failed to shut down web server: %v", err)
+	}
+	return nil
+}
+
+// getAggregatedLogs returns the most recent runs of all configured scripts, oldest first
 func (ws *WebServer) getAggregatedLogs(maxEntries int) []LogEntry {
 	// Initialize with non-nil slice to ensure JSON serializes as [] not null
 	allLogs := make([]LogEntry, 0)
@@ -883,88 +935,90 @@ func (ws *WebServer) getAggregatedLogs(maxEntries int) []LogEntry {
 		return allLogs
 	}
 
-	// Get all configured scripts
+	var entries []service.LogEntry
 	config := ws.scriptManager.GetConfig()
 	for _, script := range config.Scripts {
-		scriptLogs := ws.getScriptLogs(script.Name, maxEntries)
-		allLogs = append(allLogs, scriptLogs...)
+		entries = append(entries, ws.queryScriptLogs(script.Name, maxEntries)...)
 	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Timestamp.Before(entries[j].Timestamp)
+	})
 
 	// If we have more logs than requested, truncate to most recent
-	if len(allLogs) > maxEntries {
-		allLogs = allLogs[len(allLogs)-maxEntries:]
+	if len(entries) > maxEntries {
+		entries = entries[len(entries)-maxEntries:]
 	}
 
+	for i := range entries {
+		allLogs = append(allLogs, toWebLogEntry(&entries[i]))
+	}
 	return allLogs
 }
 
-// getScriptLogs returns logs for a specific script in LogEntry format
+// getScriptLogs returns the most recent runs of a script, oldest first
 func (ws *WebServer) getScriptLogs(scriptName string, maxEntries int) []LogEntry {
 	// Initialize with non-nil slice to ensure JSON serializes as [] not null
 	logs := make([]LogEntry, 0)
 
-	// Get log file path
-	dir, err := os.Executable()
-	if err != nil {
-		dir, _ = os.Getwd()
-	} else {
-		dir = filepath.Dir(dir)
+	entries := ws.queryScriptLogs(scriptName, maxEntries)
+	for i := range entries {
+		logs = append(logs, toWebLogEntry(&entries[i]))
 	}
+	return logs
+}
 
-	logFile := filepath.Join(dir, fmt.Sprintf("%s.log", scriptName))
-
-	// Check if log file exists
-	if _, err := os.Stat(logFile); os.IsNotExist(err) {
-		return logs // Return empty array
+// queryScriptLogs reads a script's runs from the script manager's LogManager
+func (ws *WebServer) queryScriptLogs(scriptName string, maxEntries int) []service.LogEntry {
+	if ws.scriptManager == nil {
+		return nil
 	}
 
-	// Read log file content
-	content, err := os.ReadFile(logFile)
+	entries, err := ws.scriptManager.GetLogManager().QueryLogs(&service.LogQuery{
+		ScriptName: scriptName,
+		Limit:      maxEntries,
+	})
 	if err != nil {
-		return logs // Return empty array on error
+		service.Warnf("Failed to query logs for %s: %v", scriptName, err)
+		return nil
 	}
+	return entries
+}
 
-	// Parse log content into LogEntry objects
-	lines := strings.Split(string(content), "\n")
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line == "" {
-			continue
-		}
-
-		// Simple log parsing - assume format: timestamp level message
-		// For now, create basic LogEntry objects from raw log lines
-		logEntry := LogEntry{
-			Timestamp: time.Now().Format(time.RFC3339), // Default timestamp
-			Message:   line,
-			Level:     "info", // Default level
-			Script:    scriptName,
-		}
-
-		// Try to extract timestamp and level from line if possible
-		if len(line) > 19 && line[10] == 'T' { // ISO timestamp format
-			if timestampEnd := strings.Index(line[20:], " "); timestampEnd > 0 {
-				logEntry.Timestamp = line[:20+timestampEnd]
-				remaining := strings.TrimSpace(line[20+timestampEnd:])
-
-				// Check for level indicators
-				if strings.Contains(strings.ToLower(remaining), "error") {
-					logEntry.Level = "error"
-				} else if strings.Contains(strings.ToLower(remaining), "warn") {
-					logEntry.Level = "warning"
-				}
-
-				logEntry.Message = remaining
-			}
-		}
-
-		logs = append(logs, logEntry)
+// toWebLogEntry converts a run record to the frontend format
+func toWebLogEntry(entry *service.LogEntry) LogEntry {
+	level := "info"
+	switch {
+	case entry.Failed():
+		level = "error"
+	case entry.RunOutcome() == service.OutcomeWarning, entry.Stderr != "":
+		level = "warning"
 	}
 
-	// Limit to maxEntries (most recent)
-	if len(logs) > maxEntries {
-		logs = logs[len(logs)-maxEntries:]
+	message := entry.Stdout
+	switch {
+	case entry.Error != "":
+		message = entry.Error
+	case message == "" && entry.Stderr != "":
+		message = entry.Stderr
+	case message == "":
+		message = fmt.Sprintf("Exited with code %d", entry.ExitCode)
 	}
 
-	return logs
+	return LogEntry{
+		Timestamp: entry.Timestamp.Format(time.RFC3339),
+		Message:   message,
+		Level:     level,
+		Script:    entry.ScriptName,
+		ExitCode:  entry.ExitCode,
+		Duration:  entry.Duration,
+		Stdout:    entry.Stdout,
+		Stderr:    entry.Stderr,
+		Trigger:   entry.Trigger,
+		RunID:     entry.RunID,
+		Result:    entry.Result,
+		Outcome:   entry.Outcome,
+
+		OutcomeReason: entry.OutcomeReason,
+		Artifacts:     entry.Artifacts,
+	}
 }
@@ -10,6 +10,8 @@ import (
 	"testing"
 	"time"
 
+	"github.com/gorilla/websocket"
+
 	"run-script-service/service"
 )
 
@@ -17,7 +19,7 @@ import (
 func createTestServerWithScripts(scripts []service.ScriptConfig) *WebServer {
 	config := &service.ServiceConfig{Scripts: scripts}
 	scriptManager := service.NewScriptManager(config)
-	server := NewWebServer(nil, 8080)
+	server := NewWebServer(8080)
 	server.SetScriptManager(scriptManager)
 	return server
 }
@@ -38,6 +40,9 @@ func assertNotFoundResponse(t *testing.T, w *httptest.ResponseRecorder) {
 	if response.Success {
 		t.Error("Expected failed response for non-existent resource")
 	}
+	if response.Code != ErrorNotFound {
+		t.Errorf("Expected the not_found code, got %q", response.Code)
+	}
 }
 
 // Helper function to test a successful response
@@ -73,7 +78,7 @@ func createTestScript(name string, enabled bool) service.ScriptConfig {
 func TestWebServer_New(t *testing.T) {
 	// Create test service and log manager
 
-	server := NewWebServer(nil, 8080)
+	server := NewWebServer(8080)
 
 	if server == nil {
 		t.Fatal("NewWebServer should not return nil")
@@ -126,7 +131,7 @@ func TestAPIResponse_JSON(t *testing.T) {
 func TestWebServer_StatusEndpoint(t *testing.T) {
 	// Create test dependencies
 
-	server := NewWebServer(nil, 8080)
+	server := NewWebServer(8080)
 
 	// Create test request
 	req := httptest.NewRequest("GET", "/api/status", nil)
@@ -168,7 +173,7 @@ func TestWebServer_ScriptsEndpoint(t *testing.T) {
 
 	scriptManager := service.NewScriptManager(config)
 
-	server := NewWebServer(nil, 8080)
+	server := NewWebServer(8080)
 	server.SetScriptManager(scriptManager)
 
 	// Create test request
@@ -212,7 +217,7 @@ func TestWebServer_PostScript(t *testing.T) {
 
 	scriptManager := service.NewScriptManager(config)
 
-	server := NewWebServer(nil, 8080)
+	server := NewWebServer(8080)
 	server.SetScriptManager(scriptManager)
 
 	// Create test request with script data
@@ -265,7 +270,7 @@ func TestWebServer_RunScript(t *testing.T) {
 
 	scriptManager := service.NewScriptManager(config)
 
-	server := NewWebServer(nil, 8080)
+	server := NewWebServer(8080)
 	server.SetScriptManager(scriptManager)
 
 	// Create test request
@@ -275,9 +280,18 @@ func TestWebServer_RunScript(t *testing.T) {
 	// Call the run script handler
 	server.router.ServeHTTP(w, req)
 
-	// Since the script doesn't exist, we expect a 404
-	if w.Code != http.StatusNotFound {
-		t.Errorf("Expected status 404, got %d", w.Code)
+	// The run is queued
+	if w.Code != http.StatusAccepted || !strings.HasPrefix(w.Header().Get("Location"), "/api/v1/runs/") {
+		t.Errorf("Expected status 202 with a Location, got %d %q", w.Code, w.Header().Get("Location"))
+	}
+
+	// The script is configured but its file does not exist, so waiting for the run reports the failure
+	req = httptest.NewRequest("POST", "/api/scripts/test-script/run?wait=5s", nil)
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected status 422, got %d", w.Code)
 	}
 
 	var response APIResponse
@@ -286,8 +300,8 @@ func TestWebServer_RunScript(t *testing.T) {
 		t.Fatalf("Failed to unmarshal response: %v", err)
 	}
 
-	if response.Success {
-		t.Error("Expected failed response for non-existent script")
+	if response.Success || response.Code != ErrorScriptFailed {
+		t.Errorf("Expected a script_failed response, got %+v", response)
 	}
 }
 
@@ -302,8 +316,56 @@ func TestWebServer_RunScript_NotFound(t *testing.T) {
 	assertNotFoundResponse(t, w)
 }
 
+func TestWebServer_RunScript_ShuttingDown(t *testing.T) {
+	server := createTestServerWithScripts([]service.ScriptConfig{createTestScript("backup", false)})
+	server.scriptManager.Shutdown(context.Background())
+
+	req := httptest.NewRequest("POST", "/api/scripts/backup/run", nil)
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusServiceUnavailable || !strings.Contains(w.Body.String(), "shutting down") {
+		t.Errorf("Expected 503 while shutting down, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestWebServer_Shutdown(t *testing.T) {
+	server := createTestServerWithScripts(nil)
+	server.SetBindAddress("127.0.0.1")
+	server.port = 0
+	listener, err := server.listen()
+	if err != nil {
+		t.Fatal(err)
+	}
+	served := make(chan error, 1)
+	go func() { served <- server.serve(listener) }()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws://"+listener.Addr().String()+"/ws", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, _, err := conn.ReadMessage(); !websocket.IsCloseError(err, websocket.CloseGoingAway) {
+		t.Errorf("Expected a going-away close frame, got %v", err)
+	}
+	if err := <-served; err != http.ErrServerClosed {
+		t.Errorf("Expected the server to be closed, got %v", err)
+	}
+	if _, err := http.Get("http://" + listener.Addr().String() + "/api/status"); err == nil {
+		t.Error("Expected no new connections after shutdown")
+	}
+}
+
 func TestWebServer_LogsEndpoint(t *testing.T) {
-	server := NewWebServer(nil, 8080)
+	server := NewWebServer(8080)
 
 	// Create test request
 	req := httptest.NewRequest("GET", "/api/logs", nil)
@@ -341,7 +403,7 @@ func TestWebServer_LogsEndpoint(t *testing.T) {
 
 // TDD Test: Red Phase - Test for LogEntry array format expected by frontend
 func TestWebServer_LogsEndpoint_ExpectedFormat(t *testing.T) {
-	server := NewWebServer(nil, 8080)
+	server := NewWebServer(8080)
 
 	// Create test request
 	req := httptest.NewRequest("GET", "/api/logs", nil)
@@ -405,7 +467,7 @@ func TestWebServer_LogsEndpoint_ExpectedFormat(t *testing.T) {
 }
 
 func TestWebServer_GetScriptLogs(t *testing.T) {
-	server := NewWebServer(nil, 8080)
+	server := NewWebServer(8080)
 
 	// Create test request for specific script
 	req := httptest.NewRequest("GET", "/api/logs/test-script", nil)
@@ -497,7 +559,7 @@ func TestWebServer_DisableScript(t *testing.T) {
 
 func TestWebServer_StaticFiles(t *testing.T) {
 	// Create test dependencies
-	server := NewWebServer(nil, 8080)
+	server := NewWebServer(8080)
 
 	// Test that static route returns 404 when files don't exist
 	req := httptest.NewRequest("GET", "/", nil)
@@ -517,7 +579,7 @@ func TestWebServer_StaticFiles(t *testing.T) {
 
 func TestWebServer_StaticFileRouting(t *testing.T) {
 	// Create test dependencies
-	server := NewWebServer(nil, 8080)
+	server := NewWebServer(8080)
 
 	// Test static file routing
 	req := httptest.NewRequest("GET", "/static/css/main.css", nil)
@@ -562,7 +624,7 @@ func TestWebServer_UpdateConfig(t *testing.T) {
 
 	scriptManager := service.NewScriptManagerWithPath(config, configPath)
 
-	server := NewWebServer(nil, 8080)
+	server := NewWebServer(8080)
 	server.SetScriptManager(scriptManager)
 
 	// Create test request with updated config data
@@ -606,7 +668,7 @@ func TestWebServer_UpdateConfig_InvalidJSON(t *testing.T) {
 
 	scriptManager := service.NewScriptManagerWithPath(config, configPath)
 
-	server := NewWebServer(nil, 8080)
+	server := NewWebServer(8080)
 	server.SetScriptManager(scriptManager)
 
 	// Create test request with invalid JSON
@@ -650,7 +712,7 @@ func TestWebServer_UpdateScript(t *testing.T) {
 
 	scriptManager := service.NewScriptManager(config)
 
-	server := NewWebServer(nil, 8080)
+	server := NewWebServer(8080)
 	server.SetScriptManager(scriptManager)
 
 	// Create test request with updated script data
@@ -731,7 +793,7 @@ func TestWebServer_DeleteScript_NotFound(t *testing.T) {
 
 func TestWebServer_WebSocketRouteSetup(t *testing.T) {
 	// Create test dependencies
-	server := NewWebServer(nil, 8080)
+	server := NewWebServer(8080)
 
 	// Test that WebSocket route is configured but returns 404 since we haven't implemented the handler yet
 	req := httptest.NewRequest("GET", "/ws", nil)
@@ -748,7 +810,7 @@ func TestWebServer_WebSocketRouteSetup(t *testing.T) {
 }
 
 func TestWebServer_SystemMonitorIntegration(t *testing.T) {
-	server := NewWebServer(nil, 8080)
+	server := NewWebServer(8080)
 
 	// Test that web server can be configured with system monitor
 	if server.systemMonitor != nil {
@@ -765,7 +827,7 @@ func TestWebServer_SystemMonitorIntegration(t *testing.T) {
 }
 
 func TestWebServer_StartSystemMetricsBroadcasting(t *testing.T) {
-	server := NewWebServer(nil, 8080)
+	server := NewWebServer(8080)
 	monitor := service.NewSystemMonitor()
 	server.SetSystemMonitor(monitor)
 
@@ -776,3 +838,112 @@ func TestWebServer_StartSystemMetricsBroadcasting(t *testing.T) {
 		t.Errorf("Expected no error starting system metrics broadcasting, got: %v", err)
 	}
 }
+
+func TestWebServer_SetScriptManager_BridgesEvents(t *testing.T) {
+	server := NewWebServer(8080)
+	first := service.NewScriptManager(&service.ServiceConfig{})
+	server.SetScriptManager(first)
+
+	client := &WebSocketClient{hub: server.wsHub, send: make(chan []byte, 10)}
+	server.wsHub.register <- client
+
+	first.GetEventBroadcaster().Broadcast(service.NewScriptStatusEvent("backup", "completed", 0, 10))
+
+	select {
+	case message := <-client.send:
+		var wsMessage WebSocketMessage
+		if err := json.Unmarshal(message, &wsMessage); err != nil {
+			t.Fatalf("Failed to parse message: %v", err)
+		}
+		if wsMessage.Type != "script_status" || wsMessage.Data["script_name"] != "backup" {
+			t.Errorf("Expected script_status event for backup, got %+v", wsMessage)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected script status event to reach the WebSocket client")
+	}
+
+	// Replacing the script manager detaches the previous broadcaster
+	second := service.NewScriptManager(&service.ServiceConfig{})
+	server.SetScriptManager(second)
+	first.GetEventBroadcaster().Broadcast(service.NewScriptStatusEvent("backup", "failed", 1, 10))
+
+	select {
+	case message := <-client.send:
+		t.Errorf("Expected no event from the replaced script manager, got %s", message)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestWebServer_LogsEndpoint_ReturnsRunRecords(t *testing.T) {
+	server := createTestServerWithScripts([]service.ScriptConfig{
+		{Name: "backup", Path: "./backup.sh", Interval: 60},
+		{Name: "cleanup", Path: "./cleanup.sh", Interval: 60},
+	})
+	logDir := t.TempDir()
+	server.scriptManager.SetLogDir(logDir)
+
+	ran := time.Date(2025, 8, 2, 11, 26, 16, 0, time.UTC)
+	if err := service.WriteLogFile(service.LogFilePath(logDir, "backup"), []service.LogEntry{
+		{Timestamp: ran, ScriptName: "backup", ExitCode: 0, Stdout: "done", Duration: 120},
+		{Timestamp: ran.Add(time.Minute), ScriptName: "backup", ExitCode: 3, Stderr: "disk full", Duration: 80},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := service.WriteLogFile(service.LogFilePath(logDir, "cleanup"), []service.LogEntry{
+		{Timestamp: ran.Add(30 * time.Second), ScriptName: "cleanup", Stdout: "cleaned"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest("GET", "/api/logs?script=backup", nil)
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+
+	var response struct {
+		Success bool       `json:"success"`
+		Data    []LogEntry `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if len(response.Data) != 2 {
+		t.Fatalf("Expected 2 runs, got %+v", response.Data)
+	}
+	first, second := response.Data[0], response.Data[1]
+	if first.Timestamp != "2025-08-02T11:26:16Z" || first.Message != "done" || first.Level != "info" || first.Duration != 120 {
+		t.Errorf("Unexpected first run: %+v", first)
+	}
+	if second.ExitCode != 3 || second.Level != "error" || second.Message != "disk full" {
+		t.Errorf("Unexpected second run: %+v", second)
+	}
+
+	// Aggregated logs interleave scripts by time and honour the limit
+	req = httptest.NewRequest("GET", "/api/logs?limit=2", nil)
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if len(response.Data) != 2 || response.Data[0].Script != "cleanup" || response.Data[1].ExitCode != 3 {
+		t.Errorf("Expected the two most recent runs, got %+v", response.Data)
+	}
+}
+
+func TestToWebLogEntry_Outcome(t *testing.T) {
+	tests := []struct {
+		entry service.LogEntry
+		level string
+	}{
+		{service.LogEntry{Stdout: "done"}, "info"},
+		{service.LogEntry{ExitCode: 1, Outcome: service.OutcomeSuccess}, "info"},
+		{service.LogEntry{ExitCode: 1, Outcome: service.OutcomeWarning, OutcomeReason: "exited with warning code 1"}, "warning"},
+		{service.LogEntry{Stderr: "FATAL", Outcome: service.OutcomeFailure}, "error"},
+		{service.LogEntry{ExitCode: 2}, "error"},
+	}
+	for _, tt := range tests {
+		got := toWebLogEntry(&tt.entry)
+		if got.Level != tt.level || got.OutcomeReason != tt.entry.OutcomeReason {
+			t.Errorf("%+v: expected level %s, got %+v", tt.entry, tt.level, got)
+		}
+	}
+}
@@ -1,9 +1,14 @@
 package web
 
 import (
+	"context"
 	"encoding/json"
 	"log"
 	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -17,10 +22,19 @@ type WebSocketMessage struct {
 	Data      map[string]interface{} `json:"data"`
 }
 
+// upgrader accepts WebSocket connections from the server's own origin and from non-browser clients
 var upgrader = websocket.Upgrader{
-	CheckOrigin: func(r *http.Request) bool {
-		return true // Allow connections from any origin
-	},
+	CheckOrigin: sameOrigin,
+}
+
+// sameOrigin allows requests without an Origin header and those whose origin is the requested host
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Host, r.Host)
 }
 
 // WebSocketClient represents a connected WebSocket client
@@ -46,6 +60,14 @@ type WebSocketHub struct {
 
 	// Maximum number of concurrent connections
 	maxConnections int
+
+	// Closed by Close to disconnect every client with a close frame
+	shutdown  chan struct{}
+	closeOnce sync.Once
+	closing   atomic.Bool
+
+	// Write pumps still running, so Close can wait for the close frames to be sent
+	pumps sync.WaitGroup
 }
 
 const (
@@ -61,16 +83,28 @@ func NewWebSocketHub() *WebSocketHub {
 		unregister:     make(chan *WebSocketClient),
 		clients:        make(map[*WebSocketClient]bool),
 		maxConnections: MaxWebSocketConnections,
+		shutdown:       make(chan struct{}),
 	}
 }
 
 // Run starts the WebSocket hub
 func (h *WebSocketHub) Run() {
+	shutdown := h.shutdown
 	for {
 		select {
+		case <-shutdown:
+			// Each write pump sends a close frame once its send channel is closed
+			for client := range h.clients {
+				close(client.send)
+				delete(h.clients, client)
+			}
+			shutdown = nil
+
 		case client := <-h.register:
-			// Check connection limit
-			if len(h.clients) >= h.maxConnections {
+			// Check connection limit, and refuse clients that connect while shutting down
+			if h.closing.Load() {
+				close(client.send)
+			} else if len(h.clients) >= h.maxConnections {
 				log.Printf("WebSocket connection limit reached (%d), rejecting new connection", h.maxConnections)
 				close(client.send)
 				client.conn.Close()
@@ -101,6 +135,25 @@ func (h *WebSocketHub) Run() {
 	}
 }
 
+// Close sends a going-away close frame to every client and refuses new ones. It waits
+// until the frames are written or ctx is done.
+func (h *WebSocketHub) Close(ctx context.Context) {
+	h.closeOnce.Do(func() {
+		h.closing.Store(true)
+		close(h.shutdown)
+	})
+
+	done := make(chan struct{})
+	go func() {
+		h.pumps.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+}
+
 // BroadcastMessage sends a message to all connected clients
 func (h *WebSocketHub) BroadcastMessage(msgType string, data map[string]interface{}) error {
 	message := WebSocketMessage{
@@ -130,6 +183,11 @@ func (h *WebSocketHub) GetConnectionCount() int {
 
 // HandleWebSocket handles WebSocket connections
 func HandleWebSocket(hub *WebSocketHub, c *gin.Context) {
+	serveWebSocket(hub, &upgrader, c)
+}
+
+// serveWebSocket upgrades the request with the given upgrader and registers the client
+func serveWebSocket(hub *WebSocketHub, upgrader *websocket.Upgrader, c *gin.Context) {
 	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
 	if err != nil {
 		log.Printf("WebSocket upgrade error: %v", err)
@@ -142,6 +200,7 @@ func HandleWebSocket(hub *WebSocketHub, c *gin.Context) {
 		send: make(chan []byte, 256),
 	}
 
+	hub.pumps.Add(1)
 	client.hub.register <- client
 
 	// Start goroutines for reading and writing
@@ -180,6 +239,7 @@ func (c *WebSocketClient) writePump() {
 	defer func() {
 		ticker.Stop()
 		c.conn.Close()
+		c.hub.pumps.Done()
 	}()
 
 	for {
@@ -187,7 +247,11 @@ func (c *WebSocketClient) writePump() {
 		case message, ok := <-c.send:
 			c.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
 			if !ok {
-				c.conn.WriteMessage(websocket.CloseMessage, []byte{})
+				payload := []byte{}
+				if c.hub.closing.Load() {
+					payload = websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down")
+				}
+				c.conn.WriteMessage(websocket.CloseMessage, payload)
 				return
 			}
 